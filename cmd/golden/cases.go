@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+)
+
+// goldenCase is a named analytics query whose JSON output is compared
+// against testdata/golden/<name>.json
+type goldenCase struct {
+	name string
+	run  func(ctx context.Context, repo database.Repository) (interface{}, error)
+}
+
+func strPtr(s string) *string { return &s }
+
+var cases = []goldenCase{
+	{
+		name: "total_production",
+		run: func(ctx context.Context, repo database.Repository) (interface{}, error) {
+			return repo.GetTotalProductionByDate(ctx, nil, nil, nil)
+		},
+	},
+	{
+		name: "total_production_range",
+		run: func(ctx context.Context, repo database.Repository) (interface{}, error) {
+			return repo.GetTotalProductionByDate(ctx, strPtr("2025-09-01"), strPtr("2025-09-02"), nil)
+		},
+	},
+	{
+		name: "total_production_as_of",
+		run: func(ctx context.Context, repo database.Repository) (interface{}, error) {
+			asOf := time.Date(2025, 9, 3, 12, 0, 0, 0, time.UTC)
+			return repo.GetTotalProductionByDate(ctx, nil, nil, &asOf)
+		},
+	},
+	{
+		name: "market_share",
+		run: func(ctx context.Context, repo database.Repository) (interface{}, error) {
+			return repo.GetMarketShareByOperator(ctx, nil, nil, nil)
+		},
+	},
+	{
+		name: "market_share_range",
+		run: func(ctx context.Context, repo database.Repository) (interface{}, error) {
+			return repo.GetMarketShareByOperator(ctx, strPtr("2025-09-01"), strPtr("2025-09-02"), nil)
+		},
+	},
+	{
+		name: "crosstab_type_day",
+		run: func(ctx context.Context, repo database.Repository) (interface{}, error) {
+			return repo.GetCrosstab(ctx, &models.CrosstabQuery{Rows: "type", Columns: "day", Value: "sum"})
+		},
+	},
+	{
+		name: "crosstab_operator_utilisation",
+		run: func(ctx context.Context, repo database.Repository) (interface{}, error) {
+			return repo.GetCrosstab(ctx, &models.CrosstabQuery{Rows: "operator", Columns: "month", Metric: "sum(productionMw) / sum(capacity)"})
+		},
+	},
+	{
+		name: "production_by_generator",
+		run: func(ctx context.Context, repo database.Repository) (interface{}, error) {
+			return repo.GetProductionByGenerator(ctx, nil, nil)
+		},
+	},
+	{
+		name: "generator_efficiency",
+		run: func(ctx context.Context, repo database.Repository) (interface{}, error) {
+			return repo.GetGeneratorEfficiency(ctx, nil, nil)
+		},
+	},
+	{
+		name: "renewable_summary",
+		run: func(ctx context.Context, repo database.Repository) (interface{}, error) {
+			return repo.GetRenewableSummary(ctx, nil, nil)
+		},
+	},
+	{
+		name: "latest_production_dates",
+		run: func(ctx context.Context, repo database.Repository) (interface{}, error) {
+			return repo.GetLatestProductionDates(ctx)
+		},
+	},
+}
@@ -0,0 +1,150 @@
+package main
+
+// golden seeds a fixed dataset into a scratch schema, runs the analytics
+// queries of the repository against it and compares their JSON output with
+// the expected files in testdata/golden. Run with -update to rewrite the
+// expected files after an intentional change.
+//
+//	go run ./cmd/golden [-dir testdata/golden] [-update] [-keep]
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+)
+
+const goldenSchema = "golden"
+
+func main() {
+	dir := flag.String("dir", "testdata/golden", "Directory with seed.sql and expected <case>.json files")
+	update := flag.Bool("update", false, "Rewrite the expected files with the current output")
+	keep := flag.Bool("keep", false, "Keep the golden schema after the run for inspection")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	// Expected values are computed with a fixed policy, independent of the environment
+	numeric.SetPolicy(numeric.Policy{Decimals: 3, Mode: numeric.RoundHalfEven})
+
+	// Point the repository at the scratch schema
+	os.Setenv("DB_SEARCH_PATH", goldenSchema)
+	db, err := database.NewConnection(ctx)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	if err := setupSchema(ctx, db, filepath.Join(*dir, "seed.sql")); err != nil {
+		log.Fatalf("Failed to seed golden dataset: %v", err)
+	}
+	if !*keep {
+		defer func() {
+			if _, err := db.Pool.Exec(ctx, "DROP SCHEMA IF EXISTS "+goldenSchema+" CASCADE"); err != nil {
+				log.Printf("Failed to drop golden schema: %v", err)
+			}
+		}()
+	}
+
+	repo := database.NewRepository(db.Pool)
+	failed := 0
+	for _, tc := range cases {
+		ok, err := runCase(ctx, repo, tc, *dir, *update)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Printf("FAIL %s: %v\n", tc.name, err)
+		case !ok:
+			failed++
+		default:
+			fmt.Printf("ok   %s\n", tc.name)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d of %d golden cases failed\n", failed, len(cases))
+		db.Close()
+		os.Exit(1)
+	}
+	fmt.Printf("all %d golden cases passed\n", len(cases))
+}
+
+// setupSchema recreates the scratch schema with the shape of the core tables and loads the seed
+func setupSchema(ctx context.Context, db *database.DB, seedPath string) error {
+	seed, err := os.ReadFile(seedPath)
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		"DROP SCHEMA IF EXISTS " + goldenSchema + " CASCADE",
+		"CREATE SCHEMA " + goldenSchema,
+	}
+	for _, table := range []string{"types", "operators", "generators", "productions", "production_corrections", "submission_calendar"} {
+		statements = append(statements, fmt.Sprintf(
+			"CREATE TABLE %s.%s (LIKE core.%s INCLUDING ALL)", goldenSchema, table, table))
+	}
+	for _, stmt := range statements {
+		if _, err := db.Pool.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+
+	_, err = db.Pool.Exec(ctx, string(seed))
+	return err
+}
+
+func runCase(ctx context.Context, repo database.Repository, tc goldenCase, dir string, update bool) (bool, error) {
+	result, err := tc.run(ctx, repo)
+	if err != nil {
+		return false, err
+	}
+	actual, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	actual = append(actual, '\n')
+
+	path := filepath.Join(dir, tc.name+".json")
+	if update {
+		return true, os.WriteFile(path, actual, 0o644)
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("missing golden file (run with -update to create it): %w", err)
+	}
+	if bytes.Equal(bytes.TrimSpace(expected), bytes.TrimSpace(actual)) {
+		return true, nil
+	}
+
+	fmt.Printf("FAIL %s: output differs from %s\n", tc.name, path)
+	printDiff(string(expected), string(actual))
+	return false, nil
+}
+
+// printDiff prints the lines that differ between expected and actual output
+func printDiff(expected, actual string) {
+	exp := strings.Split(strings.TrimSpace(expected), "\n")
+	act := strings.Split(strings.TrimSpace(actual), "\n")
+	for i := 0; i < len(exp) || i < len(act); i++ {
+		var e, a string
+		if i < len(exp) {
+			e = exp[i]
+		}
+		if i < len(act) {
+			a = act[i]
+		}
+		if e != a {
+			fmt.Printf("  line %d\n    - %s\n    + %s\n", i+1, e, a)
+		}
+	}
+}
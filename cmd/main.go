@@ -11,6 +11,11 @@ package main
 //  @license.url     https://opensource.org/licenses/MIT
 //  @schemes         http https
 //  @BasePath        /api/v1
+//
+//  @securityDefinitions.apikey  BearerAuth
+//  @in                          header
+//  @name                        Authorization
+//  @description                 Bearer token ("Bearer <token>") from POST /auth/login. Writes need one and are checked against the operators the user is granted; users scoped to operators get 403 outside them.
 
 import (
     "context"
@@ -18,9 +23,38 @@ import (
     "net/http"
     "os"
 
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/attachments"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/cache"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/calendar"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/catalog"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/connectors"
     "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/demo"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/events"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/freshness"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/geo"
     "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/handlers"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpclient"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/imports"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/jobs"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/live"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/middleware"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/notify"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/projections"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/provenance"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/quality"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/redact"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/reports"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/scrub"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/slo"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/storage"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/telemetry"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/trash"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
     "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
 
     // Swagger UI
     swaggerFiles "github.com/swaggo/files"
@@ -30,25 +64,284 @@ import (
 )
 
 func main() {
-	// Initialize database connection
 	ctx := context.Background()
-	db, err := database.NewConnection(ctx)
+
+	// Demo mode runs on the in-memory repository instead of the database
+	demoConfig := demo.LoadConfig()
+	var store database.Repository
+	// Tenants of TENANTS_FILE are served from databases of their own
+	tenantConfig := database.LoadTenantConfig()
+	var tenants *database.Tenants
+	// Reads of GET requests go to DB_REPLICA_URI unless they ask for consistency
+	replicaConfig := database.LoadReplicaConfig()
+	var replica *database.DB
+	if demoConfig.Enabled {
+		log.Println("DEMO_MODE is enabled: serving synthetic data from memory")
+		store = database.NewMemoryRepository()
+	} else {
+		// Initialize database connection
+		db, err := database.NewConnection(ctx)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer db.Close()
+		store = database.NewRepository(db.Pool)
+
+		tenantList, err := database.LoadTenants(tenantConfig.File)
+		if err != nil {
+			log.Fatalf("Failed to load tenants: %v", err)
+		}
+		if len(tenantList) > 0 {
+			if replicaConfig.URI != "" {
+				log.Println("Warning: DB_REPLICA_URI is ignored when TENANTS_FILE is set")
+			}
+			if tenants, err = database.NewTenants(ctx, db.Pool, tenantList); err != nil {
+				log.Fatalf("Failed to connect to tenant databases: %v", err)
+			}
+			defer tenants.Close()
+			store = database.NewTenantRepository(tenants)
+		} else if replicaConfig.URI != "" {
+			if replica, err = database.NewReplicaConnection(ctx, replicaConfig.URI); err != nil {
+				log.Fatalf("Failed to connect to read replica: %v", err)
+			}
+			defer replica.Close()
+			store = database.NewReplicaRepository(db.Pool, replica.Pool)
+		}
+	}
+
+	// Apply the configured decimal precision to energy values
+	numeric.SetPolicy(numeric.LoadPolicy())
+	utils.RegisterValidators()
+	provenance.SetSigningKey(provenance.LoadSigningKey())
+
+	// Fields hidden from roles in responses
+	redactionPolicy, err := redact.LoadPolicy(redact.LoadConfig().File)
+	if err != nil {
+		log.Fatalf("Failed to load redaction policy: %v", err)
+	}
+	redact.SetPolicy(redactionPolicy)
+
+	// Proxy, CA bundle and timeouts of every outbound integration
+	if err := httpclient.Configure(httpclient.LoadConfig()); err != nil {
+		log.Fatalf("Failed to configure outbound HTTP: %v", err)
+	}
+
+	// Create repository; writes are checked against the operator grants of the request principal
+	// and recorded in the audit log
+	repo := database.NewAuthorizedRepository(database.NewAuditedRepository(store))
+
+	// Analytics responses are cached in memory or in Redis (REDIS_URI) until the data they
+	// aggregate changes
+	cacheStore, err := cache.New(cache.LoadConfig())
+	if err != nil {
+		log.Fatalf("Failed to open cache: %v", err)
+	}
+	defer cacheStore.Close()
+	analyticsCache := middleware.NewAnalyticsCache(middleware.LoadAnalyticsCacheConfig(), cacheStore)
+
+	// Changes are published as domain events; their side effects subscribe to them
+	bus := events.NewBus()
+	repo = database.NewPublishingRepository(repo, bus)
+	bus.Subscribe(analyticsCache.OnEvents)
+
+	// Synthetic fleet, history and live production for demos
+	if demoConfig.Enabled {
+		demoGenerator := demo.NewGenerator(repo, demoConfig)
+		if err := demoGenerator.Seed(ctx); err != nil {
+			log.Fatalf("Failed to seed demo data: %v", err)
+		}
+		go demoGenerator.Run(ctx)
+	}
+
+	// Background jobs and file imports
+	jobManager := jobs.NewManager()
+	importConfig := imports.LoadConfig()
+	importer := imports.NewImporter(repo, jobManager, importConfig)
+	uploadStore, err := imports.NewUploadStore(importConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize upload store: %v", err)
+	}
+	objectStorage := storage.NewClient(storage.LoadConfig())
+
+	// CSV exports dropped in IMPORT_DROP_DIR (e.g. by SCADA systems) are imported as they arrive
+	dropFolder, err := imports.NewDropFolder(importer, importConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize drop folder: %v", err)
+	}
+	go dropFolder.Run(ctx)
+
+	// Partner SFTP feeds are pulled on their schedule and staged in the drop folder
+	connectorConfig := connectors.LoadConfig(importConfig.DropDir)
+	sftpConnector, err := connectors.NewConnector(repo, connectorConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize SFTP connector: %v", err)
+	}
+	go sftpConnector.Run(ctx)
+
+	// Spreadsheets emailed by operators are taken from the IMAP mailboxes and staged in the drop folder
+	reportConfig := reports.LoadConfig()
+	mailboxPoller, err := connectors.NewMailboxPoller(repo, connectorConfig, reports.NewMailer(reportConfig.SMTP))
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatalf("Failed to initialize IMAP poller: %v", err)
 	}
-	defer db.Close()
+	go mailboxPoller.Run(ctx)
+
+	// Alerts are logged, and emailed to ALERT_EMAIL_TO and to users as their
+	// notification preferences say, with the stored email templates
+	emailTemplates := notify.NewTemplates(repo)
+	alertConfig := notify.LoadConfig()
+	alertMailer := reports.NewMailer(reportConfig.SMTP)
+	alertDispatcher := notify.NewDispatcher(repo, emailTemplates, alertMailer, alertConfig)
+
+	// Alerts of users on the digest delivery are batched into one email a day
+	digestCompiler := notify.NewDigestCompiler(repo, emailTemplates, alertMailer, alertConfig)
+	go digestCompiler.Run(ctx)
 
-	// Create repository
-	repo := database.NewRepository(db.Pool)
+	// Inbox notifications are kept for NOTIFICATION_RETENTION_DAYS
+	inboxPurger := notify.NewInboxPurger(repo, alertConfig)
+	go inboxPurger.Run(ctx)
+
+	// Per-route latency and error budgets; alerts are checked in the background
+	sloTracker := slo.NewTracker(slo.LoadConfig(), notify.NewSLONotifier(alertDispatcher))
+	go sloTracker.Run(ctx)
+
+	// Holiday calendars from HOLIDAY_CALENDARS_FILE and the holidays managed
+	// through the API, for business-day lags, schedules and comparisons
+	calendars, err := calendar.NewService(calendar.LoadConfig(), repo)
+	if err != nil {
+		log.Fatalf("Failed to load holiday calendars: %v", err)
+	}
+
+	// Production data lagging behind today raises staleness alerts
+	freshnessMonitor := freshness.NewMonitor(repo, freshness.LoadConfig(), calendars, notify.NewFreshnessNotifier(alertDispatcher))
+	go freshnessMonitor.Run(ctx)
+
+	// Orphaned and inconsistent rows are scanned for in the background; new findings raise alerts
+	qualityMonitor := quality.NewMonitor(repo, quality.LoadConfig(), notify.NewDataQualityNotifier(alertDispatcher))
+	go qualityMonitor.Run(ctx)
+
+	// Saved reports are run on their schedule and emailed or posted to webhooks
+	reportScheduler := reports.NewScheduler(repo, reportConfig, calendars, nil)
+	go reportScheduler.Run(ctx)
+
+	// Projections of the domain event log catch up with new events in the background
+	projector := projections.NewProjector(repo, projections.LoadConfig())
+	go projector.Run(ctx)
+
+	// Deleted resources stay in the trash until they are past the retention period
+	trashPurger := trash.NewPurger(repo, trash.LoadConfig())
+	go trashPurger.Run(ctx)
+
+	// Plant meters push production through the MQTT bridge
+	telemetryBridge, err := telemetry.NewBridge(repo, telemetry.LoadConfig())
+	if err != nil {
+		log.Fatalf("Failed to initialize telemetry bridge: %v", err)
+	}
+	go telemetryBridge.Run(ctx)
+
+	// Documents attached to types and generators; objects of deleted attachments are swept from the bucket
+	attachmentService := attachments.NewService(repo, objectStorage, attachments.LoadConfig())
+	go attachmentService.Run(ctx)
+
+	// Region polygons for choropleth analytics
+	regions, err := geo.LoadRegions(geo.LoadConfig())
+	if err != nil {
+		log.Fatalf("Failed to load regions: %v", err)
+	}
 
 	// Create a Gin router with default middleware (logger and recovery)
 	r := gin.Default()
+	// Client IPs (login throttling, audit log) come from X-Forwarded-For only behind TRUSTED_PROXIES
+	if err := middleware.ConfigureProxies(r, middleware.LoadProxyConfig()); err != nil {
+		log.Fatalf("Failed to configure proxies: %v", err)
+	}
+	r.Use(middleware.ClientIP())
+	if tenants != nil {
+		r.Use(middleware.Tenant(tenants, tenantConfig.Header))
+	} else if replica != nil {
+		// Writes answer with X-Consistency-Token; reads sending it back within DB_REPLICA_MAX_LAG use the primary
+		r.Use(middleware.ReadConsistency(replicaConfig.MaxLag))
+	}
+	r.Use(sloTracker.Middleware())
+	// Query count, rows and time of GETs sent with X-Query-Stats: true
+	r.Use(middleware.QueryStats(middleware.LoadQueryStatsConfig()))
+	// Browser calls from the origins of CORS_ALLOWED_ORIGINS, preflights included
+	r.Use(middleware.NewCORS(middleware.LoadCORSConfig(), "/api/v1").Middleware())
+	// Security headers on every response; bodies of JSON endpoints must be JSON in UTF-8
+	security := middleware.NewSecurity(middleware.LoadSecurityConfig())
+	security.AnyContentType(
+		"/api/v1/imports/productions",
+		"/api/v1/imports/uploads/:id/chunks/:index",
+		"/api/v1/types/:id/attachments",
+		"/api/v1/generators/:id/attachments",
+	)
+	r.Use(security.Middleware())
+	if demoConfig.Enabled {
+		r.Use(demo.Middleware())
+	}
+
+	// Access tokens for writes; reads stay public
+	authConfig := auth.LoadConfig()
+	tokens := auth.NewTokens(authConfig)
+	authenticator := middleware.NewAuthenticator(authConfig, tokens, repo)
+	authenticator.Public("/api/v1/auth/login", "/api/v1/auth/register", "/api/v1/analytics/query")
+	// Users whose role requires a second factor enroll without one
+	authenticator.TwoFactorExempt("/api/v1/users/me/2fa", "/api/v1/users/me/2fa/confirm")
 
 	// Initialize handlers
+	authHandler := handlers.NewAuthHandler(repo, authConfig, tokens, notify.NewLockoutNotifier(alertDispatcher))
+	impersonationHandler := handlers.NewImpersonationHandler(repo, authConfig, tokens)
+	twoFactorHandler := handlers.NewTwoFactorHandler(repo, authConfig, tokens)
 	userHandler := handlers.NewUserHandler(repo)
-	typeHandler := handlers.NewTypeHandler(repo)
+	typeHandler := handlers.NewTypeHandler(repo, catalog.LoadConfig())
 	generatorHandler := handlers.NewGeneratorHandler(repo)
-	productionHandler := handlers.NewProductionHandler(repo)
+	operatorHandler := handlers.NewOperatorHandler(repo)
+	plantHandler := handlers.NewPlantHandler(repo)
+	regionHandler := handlers.NewRegionHandler(repo)
+	// Production records written are pushed to the live feeds
+	liveConfig := live.LoadConfig()
+	liveHub := live.NewHub(liveConfig, func(ctx context.Context, generatorID uuid.UUID) (uuid.UUID, error) {
+		g, err := repo.GetGeneratorByID(ctx, generatorID)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		return g.TypeID, nil
+	})
+	bus.Subscribe(liveHub.OnEvents)
+	liveHandler := handlers.NewLiveHandler(liveHub, liveConfig)
+	productionHandler := handlers.NewProductionHandler(repo, handlers.LoadResultLimitConfig())
+	importHandler := handlers.NewImportHandler(importer, uploadStore, objectStorage)
+	importProfileHandler := handlers.NewImportProfileHandler(repo)
+	deadLetterHandler := handlers.NewDeadLetterHandler(repo, importer)
+	jobHandler := handlers.NewJobHandler(jobManager)
+	analyticsHandler := handlers.NewAnalyticsHandler(repo, regions, calendars)
+	freshnessHandler := handlers.NewFreshnessHandler(freshnessMonitor)
+	dataQualityHandler := handlers.NewDataQualityHandler(qualityMonitor)
+	submissionCalendarHandler := handlers.NewSubmissionCalendarHandler(repo)
+	calendarHandler := handlers.NewCalendarHandler(repo, calendars)
+	catalogHandler := handlers.NewCatalogHandler()
+	metadataHandler := handlers.NewMetadataHandler()
+	snapshotHandler := handlers.NewSnapshotHandler(repo)
+	datasetHandler := handlers.NewDatasetHandler(repo, scrub.LoadConfig(), handlers.LoadResultLimitConfig())
+	sloHandler := handlers.NewSLOHandler(sloTracker)
+	schemaHandler := handlers.NewSchemaHandler(repo)
+	eventHandler := handlers.NewEventHandler(repo)
+	trashHandler := handlers.NewTrashHandler(repo, trashPurger)
+	reportHandler := handlers.NewReportHandler(repo, reportScheduler)
+	reportTemplateHandler := handlers.NewReportTemplateHandler(repo)
+	telemetryHandler := handlers.NewTelemetryHandler(telemetryBridge)
+	dropFolderHandler := handlers.NewDropFolderHandler(dropFolder)
+	connectorHandler := handlers.NewConnectorHandler(repo, sftpConnector, mailboxPoller)
+	mapHandler := handlers.NewMapHandler(repo)
+	attachmentHandler := handlers.NewAttachmentHandler(repo, attachmentService)
+	annotationHandler := handlers.NewAnnotationHandler(repo)
+	customFieldHandler := handlers.NewCustomFieldHandler(repo)
+	recalculationHandler := handlers.NewRecalculationHandler(repo)
+	emailTemplateHandler := handlers.NewEmailTemplateHandler(repo, emailTemplates)
+	notificationPreferenceHandler := handlers.NewNotificationPreferenceHandler(repo, alertDispatcher)
+	notificationHandler := handlers.NewNotificationHandler(repo)
+	auditHandler := handlers.NewAuditHandler(repo)
+	lockoutHandler := handlers.NewLockoutHandler(repo)
 
 	// Define basic routes
 	r.GET("/", func(c *gin.Context) {
@@ -62,43 +355,339 @@ func main() {
 	// Health check endpoint
 	r.GET("/health", userHandler.HealthCheck)
 
-	// API v1 routes
-	v1 := r.Group("/api/v1")
+	// API v1 routes; each group has its own in-flight request limit and queue
+	concurrencyLimits := middleware.LoadConcurrencyConfig()
+	// Reference data (types, catalog) is served with Cache-Control/ETag headers
+	referenceCache := middleware.NewReferenceCache(middleware.LoadCacheConfig(), cacheStore)
+	// Writes of reference and production data need an admin or operator; accounts are managed by admins.
+	// The role checks cover reads as well, except on the groups opted out with the ToWrite variants.
+	writers := authenticator.RequireRole(auth.RoleAdmin, auth.RoleOperator)
+	admins := authenticator.RequireRole(auth.RoleAdmin)
+	publicWriters := authenticator.RequireRoleToWrite(auth.RoleAdmin, auth.RoleOperator)
+	publicAdmins := authenticator.RequireRoleToWrite(auth.RoleAdmin)
+	// Groups served by routes registered apart share one limiter, as every For call creates its own
+	usersLimit := concurrencyLimits.For("users")
+	generatorsLimit := concurrencyLimits.For("generators")
+	exportsLimit := concurrencyLimits.For("exports")
+	// POSTs repeated with the same body within REQUEST_DEDUP_WINDOW get the first response again
+	deduplicator := middleware.NewDeduplicator(middleware.LoadDedupConfig())
+	// Requests per client and RATE_LIMIT_WINDOW, counted in the cache store shared by replicas
+	rateLimiter := middleware.NewRateLimiter(middleware.LoadRateLimitConfig(), cacheStore)
+	v1 := r.Group("/api/v1", authenticator.Middleware(), rateLimiter.Middleware(), deduplicator.Middleware())
 	{
+		// Auth routes
+		authRoutes := v1.Group("/auth", concurrencyLimits.For("auth"))
+		{
+			authRoutes.POST("/register", authHandler.Register)
+			authRoutes.POST("/login", authHandler.Login)
+		}
+
 		// Type routes
-		types := v1.Group("/types")
+		types := v1.Group("/types", concurrencyLimits.For("types"), publicWriters)
 		{
-			types.GET("", typeHandler.GetAllTypes)
-			types.GET("/:id", typeHandler.GetTypeByID)
+			types.GET("", referenceCache.Middleware(), typeHandler.GetAllTypes)
+			types.GET("/:id", referenceCache.Middleware(), typeHandler.GetTypeByID)
 			types.POST("", typeHandler.CreateType)
 			types.PUT("/:id", typeHandler.UpdateType)
+			types.PATCH("/:id", typeHandler.PatchType)
 			types.DELETE("/:id", typeHandler.DeleteType)
+			types.POST("/:id/merge-into/:targetId", typeHandler.MergeType)
+			types.GET("/:id/translations", typeHandler.GetTypeTranslations)
+			types.PUT("/:id/translations/:lang", typeHandler.SetTypeTranslation)
+			types.DELETE("/:id/translations/:lang", typeHandler.DeleteTypeTranslation)
+			types.PUT("/:id/submission-cadence", submissionCalendarHandler.SetTypeCadence)
+			types.DELETE("/:id/submission-cadence", submissionCalendarHandler.DeleteTypeCadence)
+			types.GET("/:id/attachments", attachmentHandler.GetTypeAttachments)
+			types.POST("/:id/attachments", attachmentHandler.UploadTypeAttachment)
+			types.GET("/:id/attachments/:attachmentId", attachmentHandler.DownloadTypeAttachment)
+			types.DELETE("/:id/attachments/:attachmentId", attachmentHandler.DeleteTypeAttachment)
 		}
 
-		// User routes (placeholder)
-		users := v1.Group("/users")
+		// User routes
+		v1.GET("/users/profile", usersLimit, userHandler.GetUserProfile)
+		users := v1.Group("/users", usersLimit, admins)
 		{
-			users.GET("/profile", userHandler.GetUserProfile)
+			users.PUT("/:id/roles", userHandler.SetUserRoles)
+			users.GET("/:id/operator-grants", userHandler.GetOperatorGrants)
+			users.POST("/:id/operator-grants", userHandler.GrantOperator)
+			users.DELETE("/:id/operator-grants/:operatorId", userHandler.RevokeOperator)
+			users.DELETE("/:id/2fa", twoFactorHandler.ResetTwoFactor)
+		}
+
+		// Routes of the current user, open to every role
+		me := v1.Group("/users/me", usersLimit)
+		{
+			me.GET("/notification-preferences", notificationPreferenceHandler.GetNotificationPreferences)
+			me.PUT("/notification-preferences/:alertType", notificationPreferenceHandler.SetNotificationPreference)
+			me.DELETE("/notification-preferences/:alertType", notificationPreferenceHandler.DeleteNotificationPreference)
+			me.GET("/notifications", notificationHandler.GetNotifications)
+			me.GET("/notifications/unread", notificationHandler.GetUnreadNotifications)
+			me.POST("/notifications/read", notificationHandler.MarkNotificationsRead)
+			me.POST("/notifications/:id/read", notificationHandler.MarkNotificationRead)
+			me.GET("/2fa", twoFactorHandler.GetTwoFactor)
+			me.POST("/2fa", twoFactorHandler.EnrollTwoFactor)
+			me.POST("/2fa/confirm", twoFactorHandler.ConfirmTwoFactor)
+			me.POST("/2fa/backup-codes", twoFactorHandler.RegenerateBackupCodes)
+			me.POST("/2fa/disable", twoFactorHandler.DisableTwoFactor)
 		}
 
 		// Generators routes
-		generators := v1.Group("/generators")
+		generators := v1.Group("/generators", generatorsLimit, publicWriters)
 		{
 			generators.GET("", generatorHandler.GetAllGenerators)
 			generators.GET("/:id", generatorHandler.GetGeneratorByID)
+			generators.GET("/:id/capacity-factor", generatorHandler.GetCapacityFactor)
 			generators.POST("", generatorHandler.CreateGenerator)
 			generators.PUT("/:id", generatorHandler.UpdateGenerator)
+			generators.PATCH("/:id", generatorHandler.PatchGenerator)
 			generators.DELETE("/:id", generatorHandler.DeleteGenerator)
+			generators.PUT("/:id/submission-cadence", submissionCalendarHandler.SetGeneratorCadence)
+			generators.DELETE("/:id/submission-cadence", submissionCalendarHandler.DeleteGeneratorCadence)
+			generators.GET("/:id/attachments", attachmentHandler.GetGeneratorAttachments)
+			generators.POST("/:id/attachments", attachmentHandler.UploadGeneratorAttachment)
+			generators.GET("/:id/attachments/:attachmentId", attachmentHandler.DownloadGeneratorAttachment)
+			generators.DELETE("/:id/attachments/:attachmentId", attachmentHandler.DeleteGeneratorAttachment)
+		}
+
+		// The fleet as GeoJSON points for maps
+		v1.GET("/generators.geojson", generatorsLimit, generatorHandler.GetGeneratorsGeoJSON)
+
+		// Map routes (clustered points for the dashboard map)
+		mapRoutes := v1.Group("/map", concurrencyLimits.For("map"))
+		{
+			mapRoutes.GET("/generators", mapHandler.GetMapGenerators)
+		}
+
+		// Operator routes (companies owning generators)
+		operators := v1.Group("/operators", concurrencyLimits.For("operators"), publicWriters)
+		{
+			operators.GET("", operatorHandler.GetAllOperators)
+			operators.GET("/:id", operatorHandler.GetOperatorByID)
+			operators.POST("", operatorHandler.CreateOperator)
+			operators.PUT("/:id", operatorHandler.UpdateOperator)
+			operators.DELETE("/:id", operatorHandler.DeleteOperator)
+		}
+
+		// Plant routes (facilities grouping generators)
+		plants := v1.Group("/plants", concurrencyLimits.For("plants"), publicWriters)
+		{
+			plants.GET("", plantHandler.GetAllPlants)
+			plants.GET("/:id", plantHandler.GetPlantByID)
+			plants.POST("", plantHandler.CreatePlant)
+			plants.PUT("/:id", plantHandler.UpdatePlant)
+			plants.DELETE("/:id", plantHandler.DeletePlant)
+		}
+
+		// Region routes (geographic regions of generators)
+		regions := v1.Group("/regions", concurrencyLimits.For("regions"), publicWriters)
+		{
+			regions.GET("", regionHandler.GetAllRegions)
+			regions.GET("/:id", regionHandler.GetRegionByID)
+			regions.POST("", regionHandler.CreateRegion)
+			regions.PUT("/:id", regionHandler.UpdateRegion)
+			regions.DELETE("/:id", regionHandler.DeleteRegion)
 		}
 
 		// Productions routes (with mixed search via query params)
-		productions := v1.Group("/productions")
+		productions := v1.Group("/productions", concurrencyLimits.For("productions"), publicWriters)
 		{
 			productions.GET("", productionHandler.GetAllProductions)
+			productions.GET("/facets", productionHandler.GetProductionFacets)
+			productions.POST("/read-snapshots", productionHandler.OpenReadSnapshot)
+			productions.DELETE("/read-snapshots/:id", productionHandler.CloseReadSnapshot)
+			productions.GET("/export", exportsLimit, productionHandler.ExportProductions)
 			productions.GET("/:id", productionHandler.GetProductionByID)
 			productions.POST("", productionHandler.CreateProduction)
+			productions.POST("/bulk", productionHandler.CreateProductions)
+			productions.PATCH("/bulk", productionHandler.UpdateProductions)
 			productions.PUT("/:id", productionHandler.UpdateProduction)
+			productions.PATCH("/:id", productionHandler.PatchProduction)
 			productions.DELETE("/:id", productionHandler.DeleteProduction)
+			productions.GET("/:id/corrections", snapshotHandler.GetCorrections)
+			productions.POST("/:id/corrections", snapshotHandler.CreateCorrection)
+			productions.GET("/:id/annotations", annotationHandler.GetProductionAnnotations)
+		}
+
+		// Live feeds; long-lived, so outside the concurrency limits
+		v1.GET("/ws/productions", liveHandler.ProductionsSocket)
+		v1.GET("/stream/productions", liveHandler.ProductionsStream)
+
+		// Annotation routes (notes on production records and date ranges)
+		annotations := v1.Group("/annotations", concurrencyLimits.For("annotations"), publicWriters)
+		{
+			annotations.GET("", annotationHandler.GetAnnotations)
+			annotations.POST("", annotationHandler.CreateAnnotation)
+			annotations.GET("/:id", annotationHandler.GetAnnotationByID)
+			annotations.PUT("/:id", annotationHandler.UpdateAnnotation)
+			annotations.DELETE("/:id", annotationHandler.DeleteAnnotation)
+		}
+
+		// Custom field routes (attributes deployments add to types, operators, generators and productions)
+		customFields := v1.Group("/custom-fields", concurrencyLimits.For("custom-fields"), publicAdmins)
+		{
+			customFields.GET("", customFieldHandler.GetCustomFields)
+			customFields.POST("", customFieldHandler.CreateCustomField)
+			customFields.GET("/:id", customFieldHandler.GetCustomFieldByID)
+			customFields.PUT("/:id", customFieldHandler.UpdateCustomField)
+			customFields.DELETE("/:id", customFieldHandler.DeleteCustomField)
+		}
+
+		// Snapshot routes (published months are immutable)
+		snapshots := v1.Group("/snapshots", concurrencyLimits.For("snapshots"), publicWriters)
+		{
+			snapshots.GET("", snapshotHandler.GetSnapshots)
+			snapshots.POST("", snapshotHandler.PublishSnapshot)
+			snapshots.GET("/:month", snapshotHandler.GetSnapshot)
+			snapshots.GET("/:month/verify", snapshotHandler.VerifySnapshot)
+		}
+
+		// Whole-dataset export, scrubbed for sharing with scrub=true
+		v1.GET("/dataset", exportsLimit, datasetHandler.GetDataset)
+
+		// Import routes (streamed file uploads)
+		importRoutes := v1.Group("/imports", concurrencyLimits.For("imports"), writers)
+		{
+			importRoutes.POST("/productions", importHandler.ImportProductions)
+			importRoutes.POST("/uploads", importHandler.InitUpload)
+			importRoutes.GET("/uploads/:id", importHandler.GetUpload)
+			importRoutes.PUT("/uploads/:id/chunks/:index", importHandler.PutUploadChunk)
+			importRoutes.POST("/uploads/:id/complete", importHandler.CompleteUpload)
+			importRoutes.DELETE("/uploads/:id", importHandler.DeleteUpload)
+			importRoutes.POST("/presigned", importHandler.CreatePresignedUpload)
+			importRoutes.POST("/presigned/:jobId/confirm", importHandler.ConfirmPresignedUpload)
+			importRoutes.GET("/profiles", importProfileHandler.GetImportProfiles)
+			importRoutes.POST("/profiles", importProfileHandler.CreateImportProfile)
+			importRoutes.GET("/profiles/:id", importProfileHandler.GetImportProfileByID)
+			importRoutes.PUT("/profiles/:id", importProfileHandler.UpdateImportProfile)
+			importRoutes.DELETE("/profiles/:id", importProfileHandler.DeleteImportProfile)
+			importRoutes.GET("/dead-letters", deadLetterHandler.GetDeadLetters)
+			importRoutes.POST("/dead-letters/resubmit", deadLetterHandler.ResubmitDeadLetters)
+			importRoutes.GET("/dead-letters/:id", deadLetterHandler.GetDeadLetterByID)
+			importRoutes.PUT("/dead-letters/:id", deadLetterHandler.UpdateDeadLetter)
+			importRoutes.POST("/dead-letters/:id/resubmit", deadLetterHandler.ResubmitDeadLetter)
+			importRoutes.DELETE("/dead-letters/:id", deadLetterHandler.DeleteDeadLetter)
+			importRoutes.GET("/drop-folder", dropFolderHandler.GetDropFiles)
+			importRoutes.GET("/drop-folder/:id", dropFolderHandler.GetDropFile)
+			importRoutes.GET("/sftp/sources", connectorHandler.GetSFTPSources)
+			importRoutes.GET("/sftp/files", connectorHandler.GetSFTPFiles)
+			importRoutes.GET("/email/mailboxes", connectorHandler.GetMailboxes)
+			importRoutes.GET("/email/attachments", connectorHandler.GetEmailAttachments)
+		}
+
+		// Analytics routes
+		analytics := v1.Group("/analytics", analyticsCache.Middleware(), concurrencyLimits.For("analytics"))
+		{
+			analytics.GET("/total-production", analyticsHandler.GetTotalProduction)
+			analytics.GET("/market-share", analyticsHandler.GetMarketShare)
+			analytics.GET("/generator-efficiency", analyticsHandler.GetGeneratorEfficiency)
+			analytics.GET("/renewable-summary", analyticsHandler.GetRenewableSummary)
+			analytics.GET("/crosstab", analyticsHandler.GetCrosstab)
+			analytics.GET("/timeseries", analyticsHandler.GetTimeSeries)
+			analytics.GET("/daily-summary", analyticsHandler.GetDailySummary)
+			analytics.GET("/mix", analyticsHandler.GetMix)
+			analytics.GET("/mix/compare", analyticsHandler.GetMixComparison)
+			analytics.GET("/mix-by-region", analyticsHandler.GetMixByRegion)
+			analytics.GET("/regions.geojson", analyticsHandler.GetRegionsGeoJSON)
+			analytics.GET("/freshness", freshnessHandler.GetFreshness)
+			analytics.POST("/query", analyticsHandler.QueryAnalytics)
+		}
+
+		// Expected submission cadence per type and generator, used by the freshness check
+		v1.GET("/submission-calendar", concurrencyLimits.For("submission-calendar"), submissionCalendarHandler.GetSubmissionCalendar)
+
+		// Saved and scheduled report routes
+		reportRoutes := v1.Group("/reports", concurrencyLimits.For("reports"), writers)
+		{
+			reportRoutes.GET("", reportHandler.GetReports)
+			reportRoutes.POST("", reportHandler.CreateReport)
+			reportRoutes.GET("/:id", reportHandler.GetReportByID)
+			reportRoutes.PUT("/:id", reportHandler.UpdateReport)
+			reportRoutes.DELETE("/:id", reportHandler.DeleteReport)
+			reportRoutes.POST("/:id/run", reportHandler.RunReport)
+			reportRoutes.GET("/:id/runs", reportHandler.GetReportRuns)
+			reportRoutes.GET("/:id/download", reportHandler.DownloadReport)
+			reportRoutes.GET("/:id/deliveries", reportHandler.GetWebhookDeliveries)
+			reportRoutes.POST("/:id/deliveries/:deliveryId/redeliver", reportHandler.RedeliverWebhook)
+			reportRoutes.POST("/:id/webhook-secret", reportHandler.RotateWebhookSecret)
+			reportRoutes.GET("/templates", reportTemplateHandler.GetReportTemplates)
+			reportRoutes.POST("/templates", reportTemplateHandler.CreateReportTemplate)
+			reportRoutes.GET("/templates/:templateId", reportTemplateHandler.GetReportTemplateByID)
+			reportRoutes.PUT("/templates/:templateId", reportTemplateHandler.UpdateReportTemplate)
+			reportRoutes.DELETE("/templates/:templateId", reportTemplateHandler.DeleteReportTemplate)
+		}
+
+		// Catalog routes (canonical technology codes)
+		catalogRoutes := v1.Group("/catalog", concurrencyLimits.For("catalog"))
+		{
+			catalogRoutes.GET("/technologies", referenceCache.Middleware(), catalogHandler.GetTechnologies)
+		}
+
+		// Metadata routes (data dictionary)
+		metadataRoutes := v1.Group("/metadata", concurrencyLimits.For("metadata"))
+		{
+			metadataRoutes.GET("/fields", referenceCache.Middleware(), metadataHandler.GetFields)
+		}
+
+		// Trash routes
+		trashRoutes := v1.Group("/trash", concurrencyLimits.For("trash"), writers)
+		{
+			trashRoutes.GET("", trashHandler.GetTrash)
+			trashRoutes.GET("/:id", trashHandler.GetTrashItem)
+			trashRoutes.POST("/:id/restore", trashHandler.RestoreTrashItem)
+			trashRoutes.DELETE("/:id", admins, trashHandler.PurgeTrashItem)
+		}
+
+		// Admin routes
+		admin := v1.Group("/admin", concurrencyLimits.For("admin"), admins)
+		{
+			admin.GET("/slo", sloHandler.GetSLO)
+			admin.GET("/schema", schemaHandler.GetSchema)
+			admin.GET("/data-quality", dataQualityHandler.GetDataQuality)
+			admin.GET("/events", eventHandler.GetEvents)
+			admin.GET("/projections", eventHandler.GetProjections)
+			admin.POST("/projections/:name/rebuild", eventHandler.RebuildProjection)
+			admin.GET("/telemetry", telemetryHandler.GetTelemetryStatus)
+			admin.GET("/recalculations", recalculationHandler.GetRecalculations)
+			admin.POST("/recalculations", recalculationHandler.ApplyRecalculation)
+			admin.POST("/recalculations/preview", recalculationHandler.PreviewRecalculation)
+			admin.GET("/email-templates", emailTemplateHandler.GetEmailTemplates)
+			admin.GET("/email-templates/:key", emailTemplateHandler.GetEmailTemplate)
+			admin.PUT("/email-templates/:key", emailTemplateHandler.UpdateEmailTemplate)
+			admin.POST("/email-templates/:key/preview", emailTemplateHandler.PreviewEmailTemplate)
+			admin.GET("/email-templates/:key/versions", emailTemplateHandler.GetEmailTemplateVersions)
+			admin.GET("/email-templates/:key/versions/:version", emailTemplateHandler.GetEmailTemplateVersion)
+			admin.POST("/email-templates/:key/versions/:version/restore", emailTemplateHandler.RestoreEmailTemplateVersion)
+			admin.GET("/impersonations", impersonationHandler.GetImpersonations)
+			admin.POST("/impersonations", impersonationHandler.StartImpersonation)
+			admin.GET("/impersonations/:id", impersonationHandler.GetImpersonation)
+			admin.POST("/impersonations/:id/end", impersonationHandler.EndImpersonation)
+			admin.GET("/impersonations/:id/events", impersonationHandler.GetImpersonationEvents)
+			admin.GET("/login-lockouts", lockoutHandler.GetLoginLockouts)
+			admin.POST("/login-lockouts/:username/unlock", lockoutHandler.UnlockLogin)
+			admin.GET("/holidays", calendarHandler.GetHolidays)
+			admin.POST("/holidays", calendarHandler.CreateHoliday)
+			admin.DELETE("/holidays/:id", calendarHandler.DeleteHoliday)
+			admin.GET("/calendars/:code", calendarHandler.GetCalendar)
+		}
+
+		// Audit log routes
+		audit := v1.Group("/audit", concurrencyLimits.For("audit"), admins)
+		{
+			audit.GET("", auditHandler.GetAuditEntries)
+			audit.GET("/:id", auditHandler.GetAuditEntry)
+		}
+
+		// Telemetry routes (meter messages pushed over HTTP)
+		telemetryRoutes := v1.Group("/telemetry", concurrencyLimits.For("telemetry"), writers)
+		{
+			telemetryRoutes.POST("/*topic", telemetryHandler.PostTelemetry)
+		}
+
+		// Background job routes
+		jobRoutes := v1.Group("/jobs", concurrencyLimits.For("jobs"))
+		{
+			jobRoutes.GET("", jobHandler.GetAllJobs)
+			jobRoutes.GET("/:id", jobHandler.GetJobByID)
 		}
 	}
 
@@ -107,22 +696,198 @@ func main() {
 	log.Println("Available endpoints:")
 	log.Println("  GET  /")
 	log.Println("  GET  /health")
+	log.Println("  POST /api/v1/auth/register")
+	log.Println("  POST /api/v1/auth/login")
 	log.Println("  GET  /api/v1/types")
 	log.Println("  POST /api/v1/types")
 	log.Println("  GET  /api/v1/types/:id")
 	log.Println("  PUT  /api/v1/types/:id")
+	log.Println("  PATCH /api/v1/types/:id")
 	log.Println("  DELETE /api/v1/types/:id")
+	log.Println("  POST /api/v1/types/:id/merge-into/:targetId")
+	log.Println("  GET  /api/v1/types/:id/translations")
+	log.Println("  PUT  /api/v1/types/:id/translations/:lang")
+	log.Println("  DELETE /api/v1/types/:id/translations/:lang")
+	log.Println("  PUT  /api/v1/types/:id/submission-cadence")
+	log.Println("  DELETE /api/v1/types/:id/submission-cadence")
+	log.Println("  GET  /api/v1/types/:id/attachments")
+	log.Println("  POST /api/v1/types/:id/attachments")
+	log.Println("  GET  /api/v1/types/:id/attachments/:attachmentId")
+	log.Println("  DELETE /api/v1/types/:id/attachments/:attachmentId")
 	log.Println("  GET  /api/v1/users/profile")
+	log.Println("  PUT  /api/v1/users/:id/roles")
+	log.Println("  GET  /api/v1/users/:id/operator-grants")
+	log.Println("  POST /api/v1/users/:id/operator-grants")
+	log.Println("  DELETE /api/v1/users/:id/operator-grants/:operatorId")
+	log.Println("  DELETE /api/v1/users/:id/2fa")
+	log.Println("  GET  /api/v1/users/me/notification-preferences")
+	log.Println("  PUT  /api/v1/users/me/notification-preferences/:alertType")
+	log.Println("  DELETE /api/v1/users/me/notification-preferences/:alertType")
+	log.Println("  GET  /api/v1/users/me/notifications")
+	log.Println("  GET  /api/v1/users/me/notifications/unread")
+	log.Println("  POST /api/v1/users/me/notifications/read")
+	log.Println("  POST /api/v1/users/me/notifications/:id/read")
+	log.Println("  GET  /api/v1/users/me/2fa")
+	log.Println("  POST /api/v1/users/me/2fa")
+	log.Println("  POST /api/v1/users/me/2fa/confirm")
+	log.Println("  POST /api/v1/users/me/2fa/backup-codes")
+	log.Println("  POST /api/v1/users/me/2fa/disable")
 	log.Println("  GET  /api/v1/generators")
 	log.Println("  POST /api/v1/generators")
+	log.Println("  GET  /api/v1/generators.geojson")
+	log.Println("  GET  /api/v1/map/generators")
 	log.Println("  GET  /api/v1/generators/:id")
+	log.Println("  GET  /api/v1/generators/:id/capacity-factor")
 	log.Println("  PUT  /api/v1/generators/:id")
+	log.Println("  PATCH /api/v1/generators/:id")
 	log.Println("  DELETE /api/v1/generators/:id")
+	log.Println("  PUT  /api/v1/generators/:id/submission-cadence")
+	log.Println("  DELETE /api/v1/generators/:id/submission-cadence")
+	log.Println("  GET  /api/v1/generators/:id/attachments")
+	log.Println("  POST /api/v1/generators/:id/attachments")
+	log.Println("  GET  /api/v1/generators/:id/attachments/:attachmentId")
+	log.Println("  DELETE /api/v1/generators/:id/attachments/:attachmentId")
+	log.Println("  GET  /api/v1/operators")
+	log.Println("  POST /api/v1/operators")
+	log.Println("  GET  /api/v1/operators/:id")
+	log.Println("  PUT  /api/v1/operators/:id")
+	log.Println("  DELETE /api/v1/operators/:id")
+	log.Println("  GET  /api/v1/plants")
+	log.Println("  POST /api/v1/plants")
+	log.Println("  GET  /api/v1/plants/:id")
+	log.Println("  PUT  /api/v1/plants/:id")
+	log.Println("  DELETE /api/v1/plants/:id")
+	log.Println("  GET  /api/v1/regions")
+	log.Println("  POST /api/v1/regions")
+	log.Println("  GET  /api/v1/regions/:id")
+	log.Println("  PUT  /api/v1/regions/:id")
+	log.Println("  DELETE /api/v1/regions/:id")
 	log.Println("  GET  /api/v1/productions")
 	log.Println("  POST /api/v1/productions")
+	log.Println("  POST /api/v1/productions/bulk")
+	log.Println("  PATCH /api/v1/productions/bulk")
+	log.Println("  GET  /api/v1/productions/facets")
+	log.Println("  POST /api/v1/productions/read-snapshots")
+	log.Println("  DELETE /api/v1/productions/read-snapshots/:id")
+	log.Println("  GET  /api/v1/productions/export")
+	log.Println("  GET  /api/v1/ws/productions (WebSocket)")
+	log.Println("  GET  /api/v1/stream/productions (Server-Sent Events)")
 	log.Println("  GET  /api/v1/productions/:id")
 	log.Println("  PUT  /api/v1/productions/:id")
+	log.Println("  PATCH /api/v1/productions/:id")
 	log.Println("  DELETE /api/v1/productions/:id")
+	log.Println("  GET  /api/v1/productions/:id/corrections")
+	log.Println("  POST /api/v1/productions/:id/corrections")
+	log.Println("  GET  /api/v1/productions/:id/annotations")
+	log.Println("  GET  /api/v1/annotations")
+	log.Println("  POST /api/v1/annotations")
+	log.Println("  GET  /api/v1/annotations/:id")
+	log.Println("  PUT  /api/v1/annotations/:id")
+	log.Println("  DELETE /api/v1/annotations/:id")
+	log.Println("  GET  /api/v1/custom-fields")
+	log.Println("  POST /api/v1/custom-fields")
+	log.Println("  GET  /api/v1/custom-fields/:id")
+	log.Println("  PUT  /api/v1/custom-fields/:id")
+	log.Println("  DELETE /api/v1/custom-fields/:id")
+	log.Println("  GET  /api/v1/snapshots")
+	log.Println("  POST /api/v1/snapshots")
+	log.Println("  GET  /api/v1/snapshots/:month")
+	log.Println("  GET  /api/v1/snapshots/:month/verify")
+	log.Println("  GET  /api/v1/dataset")
+	log.Println("  POST /api/v1/imports/productions")
+	log.Println("  POST /api/v1/imports/uploads")
+	log.Println("  GET  /api/v1/imports/uploads/:id")
+	log.Println("  PUT  /api/v1/imports/uploads/:id/chunks/:index")
+	log.Println("  POST /api/v1/imports/uploads/:id/complete")
+	log.Println("  DELETE /api/v1/imports/uploads/:id")
+	log.Println("  POST /api/v1/imports/presigned")
+	log.Println("  POST /api/v1/imports/presigned/:jobId/confirm")
+	log.Println("  GET  /api/v1/imports/profiles")
+	log.Println("  POST /api/v1/imports/profiles")
+	log.Println("  GET  /api/v1/imports/profiles/:id")
+	log.Println("  PUT  /api/v1/imports/profiles/:id")
+	log.Println("  DELETE /api/v1/imports/profiles/:id")
+	log.Println("  GET  /api/v1/imports/dead-letters")
+	log.Println("  POST /api/v1/imports/dead-letters/resubmit")
+	log.Println("  GET  /api/v1/imports/dead-letters/:id")
+	log.Println("  PUT  /api/v1/imports/dead-letters/:id")
+	log.Println("  POST /api/v1/imports/dead-letters/:id/resubmit")
+	log.Println("  DELETE /api/v1/imports/dead-letters/:id")
+	log.Println("  GET  /api/v1/imports/drop-folder")
+	log.Println("  GET  /api/v1/imports/drop-folder/:id")
+	log.Println("  GET  /api/v1/imports/sftp/sources")
+	log.Println("  GET  /api/v1/imports/sftp/files")
+	log.Println("  GET  /api/v1/imports/email/mailboxes")
+	log.Println("  GET  /api/v1/imports/email/attachments")
+	log.Println("  GET  /api/v1/analytics/total-production")
+	log.Println("  GET  /api/v1/analytics/market-share")
+	log.Println("  GET  /api/v1/analytics/generator-efficiency")
+	log.Println("  GET  /api/v1/analytics/renewable-summary")
+	log.Println("  GET  /api/v1/analytics/crosstab")
+	log.Println("  GET  /api/v1/analytics/timeseries")
+	log.Println("  GET  /api/v1/analytics/daily-summary")
+	log.Println("  GET  /api/v1/analytics/mix")
+	log.Println("  GET  /api/v1/analytics/mix/compare")
+	log.Println("  GET  /api/v1/analytics/mix-by-region")
+	log.Println("  GET  /api/v1/analytics/regions.geojson")
+	log.Println("  GET  /api/v1/analytics/freshness")
+	log.Println("  POST /api/v1/analytics/query")
+	log.Println("  GET  /api/v1/submission-calendar")
+	log.Println("  GET  /api/v1/reports")
+	log.Println("  POST /api/v1/reports")
+	log.Println("  GET  /api/v1/reports/:id")
+	log.Println("  PUT  /api/v1/reports/:id")
+	log.Println("  DELETE /api/v1/reports/:id")
+	log.Println("  POST /api/v1/reports/:id/run")
+	log.Println("  GET  /api/v1/reports/:id/runs")
+	log.Println("  GET  /api/v1/reports/:id/download")
+	log.Println("  GET  /api/v1/reports/:id/deliveries")
+	log.Println("  POST /api/v1/reports/:id/deliveries/:deliveryId/redeliver")
+	log.Println("  POST /api/v1/reports/:id/webhook-secret")
+	log.Println("  GET  /api/v1/reports/templates")
+	log.Println("  POST /api/v1/reports/templates")
+	log.Println("  GET  /api/v1/reports/templates/:templateId")
+	log.Println("  PUT  /api/v1/reports/templates/:templateId")
+	log.Println("  DELETE /api/v1/reports/templates/:templateId")
+	log.Println("  GET  /api/v1/catalog/technologies")
+	log.Println("  GET  /api/v1/metadata/fields")
+	log.Println("  GET  /api/v1/jobs")
+	log.Println("  GET  /api/v1/jobs/:id")
+	log.Println("  GET  /api/v1/trash")
+	log.Println("  GET  /api/v1/trash/:id")
+	log.Println("  POST /api/v1/trash/:id/restore")
+	log.Println("  DELETE /api/v1/trash/:id")
+	log.Println("  GET  /api/v1/admin/slo")
+	log.Println("  GET  /api/v1/admin/schema")
+	log.Println("  GET  /api/v1/admin/data-quality")
+	log.Println("  GET  /api/v1/admin/events")
+	log.Println("  GET  /api/v1/admin/projections")
+	log.Println("  POST /api/v1/admin/projections/:name/rebuild")
+	log.Println("  GET  /api/v1/admin/telemetry")
+	log.Println("  GET  /api/v1/admin/recalculations")
+	log.Println("  POST /api/v1/admin/recalculations")
+	log.Println("  POST /api/v1/admin/recalculations/preview")
+	log.Println("  GET  /api/v1/admin/email-templates")
+	log.Println("  GET  /api/v1/admin/email-templates/:key")
+	log.Println("  PUT  /api/v1/admin/email-templates/:key")
+	log.Println("  POST /api/v1/admin/email-templates/:key/preview")
+	log.Println("  GET  /api/v1/admin/email-templates/:key/versions")
+	log.Println("  GET  /api/v1/admin/email-templates/:key/versions/:version")
+	log.Println("  POST /api/v1/admin/email-templates/:key/versions/:version/restore")
+	log.Println("  GET  /api/v1/admin/impersonations")
+	log.Println("  POST /api/v1/admin/impersonations")
+	log.Println("  GET  /api/v1/admin/impersonations/:id")
+	log.Println("  POST /api/v1/admin/impersonations/:id/end")
+	log.Println("  GET  /api/v1/admin/impersonations/:id/events")
+	log.Println("  GET  /api/v1/admin/login-lockouts")
+	log.Println("  POST /api/v1/admin/login-lockouts/:username/unlock")
+	log.Println("  GET  /api/v1/admin/holidays")
+	log.Println("  POST /api/v1/admin/holidays")
+	log.Println("  DELETE /api/v1/admin/holidays/:id")
+	log.Println("  GET  /api/v1/admin/calendars/:code")
+	log.Println("  GET  /api/v1/audit")
+	log.Println("  GET  /api/v1/audit/:id")
+	log.Println("  POST /api/v1/telemetry/*topic")
 
     // Swagger UI endpoint
     r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
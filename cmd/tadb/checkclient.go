@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	openapi3 "github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/client"
+)
+
+// runCheckClient implements `tadb check-client`: every operation of the
+// OpenAPI document must be implemented by pkg/client
+func runCheckClient(args []string) error {
+	fset := flag.NewFlagSet("check-client", flag.ContinueOnError)
+	spec := fset.String("spec", "docs/openapi.yaml", "Path to the OpenAPI 3 document")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromFile(*spec)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", *spec, err)
+	}
+
+	covered := make(map[string]bool, len(client.Routes))
+	for _, r := range client.Routes {
+		covered[r.Method+" "+r.Path] = true
+	}
+
+	documented := map[string]bool{}
+	var missing []string
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			key := strings.ToUpper(method) + " " + path
+			documented[key] = true
+			if !covered[key] {
+				missing = append(missing, key)
+			}
+		}
+	}
+
+	var undocumented []string
+	for key := range covered {
+		if !documented[key] {
+			undocumented = append(undocumented, key)
+		}
+	}
+	sort.Strings(undocumented)
+	for _, key := range undocumented {
+		log.Printf("not in %s (regenerate the docs?): %s", *spec, key)
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		for _, key := range missing {
+			log.Printf("missing in pkg/client: %s", key)
+		}
+		return fmt.Errorf("%d documented operations are not implemented by pkg/client", len(missing))
+	}
+	log.Printf("pkg/client covers all %d operations of %s", len(documented), *spec)
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+)
+
+// runCreateAdmin implements `tadb create-admin`: create an admin account
+// directly in the database, e.g. the first one of a deployment that runs
+// with AUTH_FIRST_USER_ADMIN=false. The password is read from the first line
+// of stdin, so it stays out of the shell history and the process list.
+func runCreateAdmin(args []string) error {
+	fset := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	username := fset.String("username", "", "Username of the admin")
+	email := fset.String("email", "", "Email of the admin")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" || *email == "" {
+		return fmt.Errorf("--username and --email are required")
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return fmt.Errorf("failed to read the password: %w", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if len(password) < 8 || len(password) > 72 {
+		return fmt.Errorf("the password must have 8 to 72 bytes")
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db, err := database.NewConnection(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+	repo := database.NewAuditedRepository(database.NewRepository(db.Pool))
+
+	req := &models.RegisterRequest{Username: *username, Email: *email, Password: password}
+	user, err := repo.CreateUser(ctx, req, hash, []string{auth.RoleAdmin})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created admin %s (%s)\n", user.Username, user.ID)
+	return nil
+}
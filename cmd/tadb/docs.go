@@ -0,0 +1,541 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// statusCodes maps the net/http status constants handlers answer with to
+// their code; validate rejects constants missing here so the map is kept
+// complete
+var statusCodes = map[string]int{
+	"StatusOK":                    200,
+	"StatusCreated":               201,
+	"StatusAccepted":              202,
+	"StatusNoContent":             204,
+	"StatusNotModified":           304,
+	"StatusBadRequest":            400,
+	"StatusUnauthorized":          401,
+	"StatusForbidden":             403,
+	"StatusNotFound":              404,
+	"StatusConflict":              409,
+	"StatusPreconditionFailed":    412,
+	"StatusRequestEntityTooLarge": 413,
+	"StatusUnprocessableEntity":   422,
+	"StatusPreconditionRequired":  428,
+	"StatusTooManyRequests":       429,
+	"StatusInternalServerError":   500,
+	"StatusNotImplemented":        501,
+	"StatusServiceUnavailable":    503,
+}
+
+// exampleFree are field types swag documents without an example
+var exampleFree = map[string]bool{
+	"time.Time":       true,
+	"json.RawMessage": true,
+	"any":             true,
+	"interface{}":     true,
+}
+
+// runDocs implements `tadb docs <subcommand>`
+func runDocs(args []string) error {
+	if len(args) == 0 || args[0] != "validate" {
+		return fmt.Errorf("usage: tadb docs validate [flags]")
+	}
+	return runDocsValidate(args[1:])
+}
+
+// route is an operation registered on the router
+type route struct {
+	method  string
+	path    string
+	handler string
+	pos     token.Position
+}
+
+// operation is a handler documented with swag annotations
+type operation struct {
+	fn       *ast.FuncDecl
+	pos      token.Position
+	method   string
+	path     string
+	summary  bool
+	security string
+	codes    map[int]bool
+	models   []string
+}
+
+// runDocsValidate implements `tadb docs validate`: every route registered in
+// the server must be documented, with a summary, every status code its
+// handler answers with, a security requirement when it can answer 403 and
+// examples for every field of the models it takes and returns
+func runDocsValidate(args []string) error {
+	fset := flag.NewFlagSet("docs validate", flag.ContinueOnError)
+	mainFile := fset.String("main", "cmd/main.go", "File registering the routes")
+	handlersDir := fset.String("handlers", "pkg/handlers", "Directory of the annotated handlers")
+	basePath := fset.String("base", "/api/v1", "Base path of the documented routes")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	files := token.NewFileSet()
+	routes, schemes, err := registeredRoutes(files, *mainFile, *basePath)
+	if err != nil {
+		return err
+	}
+	funcs, err := parseFuncs(files, *handlersDir)
+	if err != nil {
+		return err
+	}
+	ops, problems := documentedOperations(files, funcs)
+
+	byKey := map[string]*operation{}
+	for _, op := range ops {
+		key := op.method + " " + op.path
+		if prev, ok := byKey[key]; ok {
+			problems = append(problems, fmt.Sprintf("%s: %s is also documented at %s", op.pos, key, prev.pos))
+		}
+		byKey[key] = op
+	}
+
+	registered := map[string]bool{}
+	for _, r := range routes {
+		key := r.method + " " + r.path
+		registered[key] = true
+		op, ok := byKey[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: %s (%s) has no @Router annotation", r.pos, key, r.handler))
+			continue
+		}
+		if op.fn.Name.Name != r.handler {
+			problems = append(problems, fmt.Sprintf("%s: %s is served by %s but documented on %s", r.pos, key, r.handler, op.fn.Name.Name))
+		}
+	}
+
+	models := newModelIndex(files)
+	checkedModels := map[string]bool{}
+	for _, op := range ops {
+		key := op.method + " " + op.path
+		if !registered[key] {
+			problems = append(problems, fmt.Sprintf("%s: %s is documented but not registered", op.pos, key))
+		}
+		if !op.summary {
+			problems = append(problems, fmt.Sprintf("%s: %s has no @Summary", op.pos, key))
+		}
+		for _, code := range answeredCodes(op.fn, funcs, &problems, files) {
+			if !op.codes[code] {
+				problems = append(problems, fmt.Sprintf("%s: %s answers %d but does not document it", op.pos, key, code))
+			}
+		}
+		if op.codes[403] && op.security == "" {
+			problems = append(problems, fmt.Sprintf("%s: %s answers 403 but has no @Security requirement", op.pos, key))
+		}
+		if op.security != "" && !schemes[op.security] {
+			problems = append(problems, fmt.Sprintf("%s: %s requires security %s, which is not defined in %s", op.pos, key, op.security, *mainFile))
+		}
+		for _, m := range op.models {
+			if checkedModels[m] {
+				continue
+			}
+			checkedModels[m] = true
+			problems = append(problems, models.missingExamples(m)...)
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		for _, p := range problems {
+			log.Print(p)
+		}
+		return fmt.Errorf("%d documentation problems", len(problems))
+	}
+	log.Printf("all %d routes are documented", len(routes))
+	return nil
+}
+
+// registeredRoutes reads the routes registered in file, following the
+// prefixes of router groups, and the security schemes it defines. Routes
+// served by inline or generated handlers (the welcome page, the Swagger UI)
+// are skipped.
+func registeredRoutes(files *token.FileSet, file, basePath string) ([]route, map[string]bool, error) {
+	f, err := parser.ParseFile(files, file, nil, parser.ParseComments)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", file, err)
+	}
+
+	schemes := map[string]bool{}
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			fields := strings.Fields(strings.TrimPrefix(c.Text, "//"))
+			if len(fields) > 1 && strings.HasPrefix(fields[0], "@securityDefinitions.") {
+				schemes[fields[1]] = true
+			}
+		}
+	}
+
+	prefixes := map[string]string{}
+	var routes []route
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if len(n.Lhs) != 1 || len(n.Rhs) != 1 {
+				return true
+			}
+			target, ok := n.Lhs[0].(*ast.Ident)
+			call, isCall := n.Rhs[0].(*ast.CallExpr)
+			if !ok || !isCall {
+				return true
+			}
+			if recv, name, ok := methodCall(call); ok && name == "Group" {
+				if p, ok := stringArg(call, 0); ok {
+					prefixes[target.Name] = prefixes[recv] + p
+				}
+			}
+		case *ast.CallExpr:
+			recv, name, ok := methodCall(n)
+			if !ok || !isHTTPMethod(name) {
+				return true
+			}
+			p, ok := stringArg(n, 0)
+			if !ok {
+				return true
+			}
+			handler, ok := n.Args[len(n.Args)-1].(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			routes = append(routes, route{
+				method:  name,
+				path:    openAPIPath(strings.TrimPrefix(prefixes[recv]+p, basePath)),
+				handler: handler.Sel.Name,
+				pos:     files.Position(n.Pos()),
+			})
+		}
+		return true
+	})
+	return routes, schemes, nil
+}
+
+// methodCall splits a call of the form recv.name(...)
+func methodCall(call *ast.CallExpr) (recv, name string, ok bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", "", false
+	}
+	x, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return "", "", false
+	}
+	return x.Name, sel.Sel.Name, true
+}
+
+// stringArg returns argument i of call when it is a string literal
+func stringArg(call *ast.CallExpr, i int) (string, bool) {
+	if len(call.Args) <= i {
+		return "", false
+	}
+	lit, ok := call.Args[i].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+func isHTTPMethod(name string) bool {
+	switch name {
+	case "GET", "POST", "PUT", "PATCH", "DELETE":
+		return true
+	}
+	return false
+}
+
+// openAPIPath turns gin parameters (:id) and wildcards (*topic) into
+// OpenAPI parameters ({id}, {topic})
+func openAPIPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// parseFuncs parses the functions and methods of a package by name
+func parseFuncs(files *token.FileSet, dir string) (map[string][]*ast.FuncDecl, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	funcs := map[string][]*ast.FuncDecl{}
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(files, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+				funcs[fn.Name.Name] = append(funcs[fn.Name.Name], fn)
+			}
+		}
+	}
+	return funcs, nil
+}
+
+// documentedOperations reads the swag annotations of the handlers
+func documentedOperations(files *token.FileSet, funcs map[string][]*ast.FuncDecl) ([]*operation, []string) {
+	var (
+		ops      []*operation
+		problems []string
+	)
+	for _, list := range funcs {
+		for _, fn := range list {
+			if fn.Doc == nil {
+				continue
+			}
+			op := &operation{fn: fn, pos: files.Position(fn.Pos()), codes: map[int]bool{}}
+			for _, c := range fn.Doc.List {
+				fields := strings.Fields(strings.TrimPrefix(c.Text, "//"))
+				if len(fields) == 0 {
+					continue
+				}
+				switch fields[0] {
+				case "@Summary":
+					op.summary = len(fields) > 1
+				case "@Security":
+					if len(fields) > 1 {
+						op.security = fields[1]
+					}
+				case "@Router":
+					if len(fields) == 3 {
+						op.path = fields[1]
+						op.method = strings.ToUpper(strings.Trim(fields[2], "[]"))
+					}
+				case "@Success", "@Failure":
+					if len(fields) < 2 {
+						continue
+					}
+					code, err := strconv.Atoi(fields[1])
+					if err != nil {
+						problems = append(problems, fmt.Sprintf("%s: invalid status %q in %s", op.pos, fields[1], fields[0]))
+						continue
+					}
+					op.codes[code] = true
+					if len(fields) > 3 {
+						op.models = append(op.models, fields[3])
+					}
+				case "@Param":
+					if len(fields) > 3 && fields[2] == "body" {
+						op.models = append(op.models, fields[3])
+					}
+				}
+			}
+			if op.path != "" {
+				ops = append(ops, op)
+			}
+		}
+	}
+	return ops, problems
+}
+
+// answeredCodes returns the status codes fn answers with, following the
+// calls to package functions and to helpers that receive the gin context.
+// Params.Valid answers 400.
+func answeredCodes(fn *ast.FuncDecl, funcs map[string][]*ast.FuncDecl, problems *[]string, files *token.FileSet) []int {
+	seen := map[*ast.FuncDecl]bool{}
+	codes := map[int]bool{}
+	var visit func(fn *ast.FuncDecl)
+	visit = func(fn *ast.FuncDecl) {
+		if seen[fn] {
+			return
+		}
+		seen[fn] = true
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				if x, ok := n.X.(*ast.Ident); ok && x.Name == "http" && strings.HasPrefix(n.Sel.Name, "Status") && !strings.HasPrefix(n.Sel.Name, "StatusText") {
+					code, ok := statusCodes[n.Sel.Name]
+					if !ok {
+						*problems = append(*problems, fmt.Sprintf("%s: unknown status http.%s", files.Position(n.Pos()), n.Sel.Name))
+					}
+					codes[code] = true
+				}
+			case *ast.CallExpr:
+				switch f := n.Fun.(type) {
+				case *ast.Ident:
+					for _, callee := range funcs[f.Name] {
+						if callee.Recv == nil {
+							visit(callee)
+						}
+					}
+				case *ast.SelectorExpr:
+					if f.Sel.Name == "Valid" && len(n.Args) == 0 {
+						codes[400] = true
+					}
+					if passesContext(n) {
+						for _, callee := range funcs[f.Sel.Name] {
+							visit(callee)
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+	visit(fn)
+
+	list := make([]int, 0, len(codes))
+	for code := range codes {
+		if code != 0 {
+			list = append(list, code)
+		}
+	}
+	sort.Ints(list)
+	return list
+}
+
+// passesContext reports whether a call receives the gin context c
+func passesContext(call *ast.CallExpr) bool {
+	for _, arg := range call.Args {
+		if id, ok := arg.(*ast.Ident); ok && id.Name == "c" {
+			return true
+		}
+	}
+	return false
+}
+
+// modelIndex finds the struct types referenced by annotations, parsing the
+// package of each (models.X lives in pkg/models) on first use
+type modelIndex struct {
+	files    *token.FileSet
+	packages map[string]map[string]*ast.TypeSpec
+}
+
+func newModelIndex(files *token.FileSet) *modelIndex {
+	return &modelIndex{files: files, packages: map[string]map[string]*ast.TypeSpec{}}
+}
+
+// lookup returns the type spec of pkg.Name
+func (m *modelIndex) lookup(pkg, name string) *ast.TypeSpec {
+	types, ok := m.packages[pkg]
+	if !ok {
+		types = map[string]*ast.TypeSpec{}
+		paths, _ := filepath.Glob(filepath.Join("pkg", pkg, "*.go"))
+		for _, path := range paths {
+			if strings.HasSuffix(path, "_test.go") {
+				continue
+			}
+			f, err := parser.ParseFile(m.files, path, nil, 0)
+			if err != nil {
+				continue
+			}
+			for _, decl := range f.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					ts := spec.(*ast.TypeSpec)
+					types[ts.Name.Name] = ts
+				}
+			}
+		}
+		m.packages[pkg] = types
+	}
+	return types[name]
+}
+
+// missingExamples lists the scalar fields of a referenced model, and of the
+// structs of its package it contains, that have no example
+func (m *modelIndex) missingExamples(ref string) []string {
+	pkg, name, ok := strings.Cut(ref, ".")
+	if !ok {
+		return nil
+	}
+	var problems []string
+	seen := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		ts := m.lookup(pkg, name)
+		if ts == nil {
+			return
+		}
+		st, ok := ts.Type.(*ast.StructType)
+		if !ok {
+			return
+		}
+		for _, field := range st.Fields.List {
+			var tag reflect.StructTag
+			if field.Tag != nil {
+				s, _ := strconv.Unquote(field.Tag.Value)
+				tag = reflect.StructTag(s)
+			}
+			if tag.Get("json") == "-" || tag.Get("swaggerignore") == "true" {
+				continue
+			}
+			typ, local := fieldType(field.Type)
+			if local != "" {
+				visit(local)
+				continue
+			}
+			if len(field.Names) == 0 || !field.Names[0].IsExported() || exampleFree[typ] || typ == "" {
+				continue
+			}
+			if _, ok := tag.Lookup("example"); !ok {
+				problems = append(problems, fmt.Sprintf("%s: %s.%s.%s has no example", m.files.Position(field.Pos()), pkg, name, field.Names[0].Name))
+			}
+		}
+	}
+	visit(name)
+	return problems
+}
+
+// fieldType names the element type of a field. Scalars are returned as typ;
+// structs of the same package as local; other composite types as neither.
+func fieldType(expr ast.Expr) (typ, local string) {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return fieldType(t.X)
+	case *ast.ArrayType:
+		if id, ok := t.Elt.(*ast.Ident); ok && id.Name == "byte" {
+			return "[]byte", ""
+		}
+		_, local = fieldType(t.Elt)
+		return "", local
+	case *ast.MapType:
+		_, local = fieldType(t.Value)
+		return "", local
+	case *ast.Ident:
+		if ast.IsExported(t.Name) {
+			return "", t.Name
+		}
+		return t.Name, ""
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok {
+			return x.Name + "." + t.Sel.Name, ""
+		}
+	case *ast.InterfaceType:
+		return "interface{}", ""
+	}
+	return "", ""
+}
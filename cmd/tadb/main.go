@@ -0,0 +1,63 @@
+// Command tadb groups developer tooling around the TADB API.
+//
+//	tadb gen-client --lang go|ts|python   generate and package a client SDK from the OpenAPI doc
+//	tadb check-client                     check that pkg/client implements every documented operation
+//	tadb docs validate                    check that every route is documented with its errors and examples
+//	tadb mock                             serve example responses from the OpenAPI doc (no database)
+//	tadb rebuild-projections              recompute the projections from the domain event log
+//	tadb migrate [--check] [--tenant id]  apply pending migrations, or try them on a shadow copy first
+//	tadb create-admin --username --email  create an admin account, reading its password from stdin
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// command is a tadb subcommand; run receives the arguments after its name
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"gen-client":          {summary: "Generate and package a client SDK from the OpenAPI doc", run: runGenClient},
+	"check-client":        {summary: "Check that pkg/client implements every documented operation", run: runCheckClient},
+	"docs":                {summary: "Validate the route documentation (docs validate)", run: runDocs},
+	"mock":                {summary: "Serve example responses from the OpenAPI doc (no database needed)", run: runMock},
+	"rebuild-projections": {summary: "Recompute the projections from the domain event log", run: runRebuildProjections},
+	"migrate":             {summary: "Apply pending migrations (--check tries them on a shadow copy first)", run: runMigrate},
+	"create-admin":        {summary: "Create an admin account, reading its password from stdin", run: runCreateAdmin},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: tadb <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun 'tadb <command> -h' for the flags of a command.")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "tadb: unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "tadb %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
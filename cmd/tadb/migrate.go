@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// runMigrate implements `tadb migrate`: apply the migrations of sql/migrations
+// not recorded in core.schema_migrations yet. With --check they are applied
+// to a shadow copy of the database instead, and the repository queries the
+// API depends on are run against it, so a breaking migration shows up before
+// it touches the real database. The databases of the tenants of TENANTS_FILE
+// are migrated after the default one, each with its own history.
+func runMigrate(args []string) error {
+	fset := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dir := fset.String("dir", "sql/migrations", "Directory of the migrations")
+	check := fset.Bool("check", false, "Apply the pending migrations to a shadow copy of the database and run the smoke queries against it")
+	baseline := fset.Bool("baseline", false, "Record every migration as applied without running them (databases created from sql/create.sql or migrated by hand)")
+	template := fset.String("template", "", "Database to copy into the shadow database with --check, which nobody may be connected to (default: the one of DB_URI or DB_NAME, so stop the API first)")
+	maintenance := fset.String("maintenance-db", "postgres", "Database to connect to while creating and dropping the shadow database")
+	tenant := fset.String("tenant", "", "Only migrate the database of this tenant of TENANTS_FILE, or \"default\" for the default database")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if *check && *baseline {
+		return fmt.Errorf("--check and --baseline cannot be combined")
+	}
+	if *template != "" && *tenant == "" {
+		// A template is one database
+		*tenant = "default"
+	}
+
+	migrations, err := database.LoadMigrations(*dir)
+	if err != nil {
+		return err
+	}
+	tenants, err := database.LoadTenants(database.LoadTenantConfig().File)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	opts := migrateOptions{check: *check, baseline: *baseline, template: *template, maintenance: *maintenance}
+
+	if *tenant == "" || *tenant == "default" {
+		db, err := database.NewConnection(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		if err := migrateDatabase(ctx, db, migrations, opts); err != nil {
+			return err
+		}
+		if *tenant == "default" {
+			return nil
+		}
+	}
+	found := false
+	for _, t := range tenants {
+		if *tenant != "" && t.ID != *tenant {
+			continue
+		}
+		found = true
+		fmt.Printf("tenant %s:\n", t.ID)
+		db, err := database.NewTenantConnection(ctx, t)
+		if err != nil {
+			return err
+		}
+		if err := migrateDatabase(ctx, db, migrations, opts); err != nil {
+			return fmt.Errorf("tenant %s: %w", t.ID, err)
+		}
+	}
+	if *tenant != "" && !found {
+		return fmt.Errorf("%w: %s", database.ErrUnknownTenant, *tenant)
+	}
+	return nil
+}
+
+// migrateOptions are the flags of `tadb migrate` applying to every database
+type migrateOptions struct {
+	check       bool
+	baseline    bool
+	template    string
+	maintenance string
+}
+
+// migrateDatabase applies the pending migrations to db, or checks them with
+// opts.check, and closes it
+func migrateDatabase(ctx context.Context, db *database.DB, migrations []database.Migration, opts migrateOptions) error {
+	defer db.Close()
+
+	if opts.baseline {
+		for _, m := range migrations {
+			if err := database.RecordMigration(ctx, db.Pool, m); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("recorded %d migrations as applied\n", len(migrations))
+		return nil
+	}
+
+	applied, err := database.AppliedMigrations(ctx, db.Pool)
+	if err != nil {
+		return err
+	}
+	pending := database.PendingMigrations(migrations, applied)
+	if len(pending) == 0 {
+		fmt.Println("no pending migrations")
+		return nil
+	}
+	if len(applied) == 0 {
+		fmt.Println("warning: no migration history; run 'tadb migrate --baseline' first if the database is up to date")
+	}
+
+	if opts.check {
+		config := db.Pool.Config()
+		template := opts.template
+		if template == "" {
+			template = config.ConnConfig.Database
+		}
+		db.Pool.Close()
+		return checkMigrations(ctx, config, opts.maintenance, template, pending)
+	}
+
+	for _, m := range pending {
+		if err := database.ApplyMigration(ctx, db.Pool, m); err != nil {
+			return err
+		}
+		fmt.Printf("applied %s\n", m.Name)
+	}
+	return nil
+}
+
+// checkMigrations applies pending to a copy of template and runs the smoke
+// queries against it; the copy is dropped afterwards
+func checkMigrations(ctx context.Context, config *pgxpool.Config, maintenance, template string, pending []database.Migration) error {
+	name := fmt.Sprintf("%s_shadow_%d", template, time.Now().Unix())
+	shadow, err := database.CreateShadow(ctx, config, maintenance, template, name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		shadow.Close()
+		if err := database.DropShadow(ctx, config, maintenance, name); err != nil {
+			fmt.Printf("warning: %v; drop database %s by hand\n", err, name)
+		}
+	}()
+	fmt.Printf("shadow database %s copied from %s\n", name, template)
+
+	for _, m := range pending {
+		start := time.Now()
+		if err := database.ApplyMigration(ctx, shadow.Pool, m); err != nil {
+			return fmt.Errorf("migration failed on the shadow database: %w", err)
+		}
+		fmt.Printf("applied %s (%s)\n", m.Name, time.Since(start).Round(time.Millisecond))
+	}
+
+	failed := 0
+	repo := database.NewRepository(shadow.Pool)
+	for _, q := range smokeQueries {
+		if err := q.run(ctx, repo); err != nil {
+			fmt.Printf("FAIL %s: %v\n", q.name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("ok   %s\n", q.name)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d smoke queries failed after the pending migrations", failed, len(smokeQueries))
+	}
+	fmt.Printf("%d pending migrations apply cleanly; run 'tadb migrate' to apply them\n", len(pending))
+	return nil
+}
+
+// smokeQuery is a repository read the API depends on
+type smokeQuery struct {
+	name string
+	run  func(ctx context.Context, repo database.Repository) error
+}
+
+// smokeQueries read every main table through the repository, so a migration
+// that drops or renames a column the code still selects fails the check
+var smokeQueries = []smokeQuery{
+	{"types", func(ctx context.Context, repo database.Repository) error {
+		types, err := repo.GetAllTypes(ctx, nil)
+		if err == nil && len(types) > 0 {
+			_, err = repo.GetTypeByID(ctx, types[0].ID)
+		}
+		return err
+	}},
+	{"operators", func(ctx context.Context, repo database.Repository) error {
+		_, err := repo.GetAllOperators(ctx)
+		return err
+	}},
+	{"plants", func(ctx context.Context, repo database.Repository) error {
+		_, err := repo.GetAllPlants(ctx, &models.PlantFilter{})
+		return err
+	}},
+	{"regions", func(ctx context.Context, repo database.Repository) error {
+		_, err := repo.GetAllRegions(ctx, &models.RegionFilter{})
+		return err
+	}},
+	{"generators", func(ctx context.Context, repo database.Repository) error {
+		generators, err := repo.GetAllGenerators(ctx, models.GeneratorFilter{})
+		if err == nil && len(generators) > 0 {
+			_, err = repo.GetGeneratorByID(ctx, generators[0].ID)
+		}
+		return err
+	}},
+	{"productions", func(ctx context.Context, repo database.Repository) error {
+		productions, err := repo.GetAllProductions(ctx, &models.ProductionFilter{Limit: 10})
+		if err == nil && len(productions) > 0 {
+			_, err = repo.GetProductionByID(ctx, productions[0].ID)
+		}
+		return err
+	}},
+	{"analytics", func(ctx context.Context, repo database.Repository) error {
+		if _, err := repo.GetTotalProductionByDate(ctx, nil, nil, nil); err != nil {
+			return err
+		}
+		if _, err := repo.GetLatestProductionDates(ctx); err != nil {
+			return err
+		}
+		if _, err := repo.GetRenewableSummary(ctx, nil, nil); err != nil {
+			return err
+		}
+		_, err := repo.GetMixByRegion(ctx, nil, nil)
+		return err
+	}},
+	{"projections", func(ctx context.Context, repo database.Repository) error {
+		_, err := repo.GetProjections(ctx)
+		return err
+	}},
+}
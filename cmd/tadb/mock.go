@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	openapi3 "github.com/getkin/kin-openapi/openapi3"
+)
+
+// maxExampleDepth stops example generation on recursive schemas
+const maxExampleDepth = 8
+
+// mockRoute is one documented operation served by the mock server
+type mockRoute struct {
+	method   string
+	segments []string // path segments; "{name}" segments match anything
+	op       *openapi3.Operation
+}
+
+// runMock implements `tadb mock`: serve example responses for every operation
+// of the OpenAPI document, without database or backend
+func runMock(args []string) error {
+	fset := flag.NewFlagSet("mock", flag.ContinueOnError)
+	spec := fset.String("spec", "docs/openapi.yaml", "Path to the OpenAPI 3 document")
+	addr := fset.String("addr", ":4010", "Address to listen on")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromFile(*spec)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", *spec, err)
+	}
+
+	// Operations are served under the path of the first server, e.g. /api/v1
+	basePath := ""
+	if len(doc.Servers) > 0 {
+		if u, err := url.Parse(doc.Servers[0].URL); err == nil {
+			basePath = strings.TrimRight(u.Path, "/")
+		}
+	}
+
+	var routes []mockRoute
+	for path, item := range doc.Paths.Map() {
+		for method, op := range item.Operations() {
+			routes = append(routes, mockRoute{
+				method:   strings.ToUpper(method),
+				segments: splitPath(basePath + path),
+				op:       op,
+			})
+		}
+	}
+	// Literal segments win over parameters (/imports/presigned before /imports/{id})
+	sort.Slice(routes, func(i, j int) bool { return literalCount(routes[i]) > literalCount(routes[j]) })
+
+	log.Printf("Mocking %d operations of %s on %s%s", len(routes), *spec, *addr, basePath)
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           mockHandler(routes),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return srv.ListenAndServe()
+}
+
+func mockHandler(routes []mockRoute) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Frontends run on another origin during development
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Headers", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		route := matchRoute(routes, r.Method, splitPath(r.URL.Path))
+		if route == nil {
+			writeMockJSON(w, http.StatusNotFound, map[string]string{"status": "error", "error": "No documented operation for " + r.Method + " " + r.URL.Path})
+			return
+		}
+
+		code, resp := pickResponse(route.op, r.Header.Get("Prefer"))
+		if resp == nil {
+			w.WriteHeader(code)
+			return
+		}
+		media := resp.Content.Get("application/json")
+		if media == nil {
+			w.WriteHeader(code)
+			return
+		}
+		log.Printf("%s %s -> %d", r.Method, r.URL.Path, code)
+		writeMockJSON(w, code, mediaExample(media))
+	})
+}
+
+func matchRoute(routes []mockRoute, method string, segments []string) *mockRoute {
+	for i := range routes {
+		rt := &routes[i]
+		if rt.method != method || len(rt.segments) != len(segments) {
+			continue
+		}
+		ok := true
+		for k, s := range rt.segments {
+			if !strings.HasPrefix(s, "{") && s != segments[k] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return rt
+		}
+	}
+	return nil
+}
+
+// pickResponse returns the response requested with `Prefer: code=<status>`,
+// otherwise the lowest documented 2xx response
+func pickResponse(op *openapi3.Operation, prefer string) (int, *openapi3.Response) {
+	if op.Responses == nil {
+		return http.StatusOK, nil
+	}
+	if strings.HasPrefix(prefer, "code=") {
+		if ref := op.Responses.Value(strings.TrimPrefix(prefer, "code=")); ref != nil {
+			code, _ := strconv.Atoi(strings.TrimPrefix(prefer, "code="))
+			return code, ref.Value
+		}
+	}
+	best := 0
+	var resp *openapi3.Response
+	for status, ref := range op.Responses.Map() {
+		code, err := strconv.Atoi(status)
+		if err != nil || code < 200 || code >= 300 {
+			continue
+		}
+		if best == 0 || code < best {
+			best, resp = code, ref.Value
+		}
+	}
+	if best == 0 {
+		return http.StatusOK, nil
+	}
+	return best, resp
+}
+
+// mediaExample prefers the documented example and falls back to one built from the schema
+func mediaExample(media *openapi3.MediaType) any {
+	if media.Example != nil {
+		return media.Example
+	}
+	names := make([]string, 0, len(media.Examples))
+	for name := range media.Examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if ex := media.Examples[name]; ex != nil && ex.Value != nil {
+			return ex.Value.Value
+		}
+	}
+	if media.Schema == nil {
+		return nil
+	}
+	return schemaExample(media.Schema.Value, 0)
+}
+
+// schemaExample builds a value from the schema examples, enums, formats and types
+func schemaExample(s *openapi3.Schema, depth int) any {
+	if s == nil || depth > maxExampleDepth {
+		return nil
+	}
+	if s.Example != nil {
+		return s.Example
+	}
+	if s.Default != nil {
+		return s.Default
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[0]
+	}
+	for _, group := range [][]*openapi3.SchemaRef{s.AllOf, s.OneOf, s.AnyOf} {
+		if len(group) > 0 {
+			merged := map[string]any{}
+			for _, ref := range group {
+				v := schemaExample(ref.Value, depth+1)
+				obj, ok := v.(map[string]any)
+				if !ok {
+					return v
+				}
+				for k, val := range obj {
+					merged[k] = val
+				}
+			}
+			return merged
+		}
+	}
+
+	switch {
+	case s.Type.Is("array"):
+		if s.Items == nil {
+			return []any{}
+		}
+		return []any{schemaExample(s.Items.Value, depth+1)}
+	case s.Type.Is("string"):
+		switch s.Format {
+		case "uuid":
+			return "550e8400-e29b-41d4-a716-446655440000"
+		case "date":
+			return "2025-09-01"
+		case "date-time":
+			return "2025-09-01T00:00:00Z"
+		}
+		return "string"
+	case s.Type.Is("integer"):
+		if s.Min != nil {
+			return int64(*s.Min)
+		}
+		return 0
+	case s.Type.Is("number"):
+		if s.Min != nil {
+			return *s.Min
+		}
+		return 0
+	case s.Type.Is("boolean"):
+		return true
+	}
+
+	obj := map[string]any{}
+	for name, prop := range s.Properties {
+		v := schemaExample(prop.Value, depth+1)
+		// swag documents time.Time as a plain string; name the timestamps properly
+		if v == "string" {
+			switch {
+			case strings.HasSuffix(name, "At"):
+				v = "2025-09-01T00:00:00Z"
+			case strings.HasSuffix(strings.ToLower(name), "date"):
+				v = "2025-09-01"
+			}
+		}
+		obj[name] = v
+	}
+	return obj
+}
+
+func splitPath(p string) []string {
+	return strings.Split(strings.Trim(p, "/"), "/")
+}
+
+func literalCount(r mockRoute) int {
+	n := 0
+	for _, s := range r.segments {
+		if !strings.HasPrefix(s, "{") {
+			n++
+		}
+	}
+	return n
+}
+
+func writeMockJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("failed to write mock response: %v", err)
+	}
+}
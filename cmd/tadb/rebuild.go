@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+)
+
+// runRebuildProjections implements `tadb rebuild-projections`: recompute
+// projections from the domain event log, e.g. after fixing an aggregation bug
+func runRebuildProjections(args []string) error {
+	fset := flag.NewFlagSet("rebuild-projections", flag.ContinueOnError)
+	name := fset.String("projection", "", "Projection to rebuild (default: all)")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db, err := database.NewConnection(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+	repo := database.NewRepository(db.Pool)
+
+	names := []string{*name}
+	if *name == "" {
+		list, err := repo.GetProjections(ctx)
+		if err != nil {
+			return err
+		}
+		names = names[:0]
+		for _, p := range list {
+			names = append(names, p.Name)
+		}
+	}
+
+	for _, n := range names {
+		p, err := repo.RebuildProjection(ctx, n)
+		if err != nil {
+			return fmt.Errorf("%s: %w", n, err)
+		}
+		fmt.Printf("%s: rebuilt %d rows up to event %d\n", p.Name, p.Rows, p.LastSeq)
+	}
+	return nil
+}
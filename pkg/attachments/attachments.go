@@ -0,0 +1,210 @@
+// Package attachments stores the documents attached to types and
+// generators: permits, datasheets, bulletin PDFs. Contents go to object
+// storage when it is configured and to the database otherwise, and the
+// objects of attachments deleted with their entity are removed from the
+// bucket in the background.
+package attachments
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/storage"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// ErrTooLarge is returned for files over the size limit
+var ErrTooLarge = errors.New("attachment exceeds the maximum size")
+
+// ErrEmpty is returned for empty files
+var ErrEmpty = errors.New("attachment is empty")
+
+// ErrContentType is returned for files of a media type that is not allowed
+var ErrContentType = errors.New("attachment media type is not allowed")
+
+// sweepBatch is how many queued objects a sweep removes at most
+const sweepBatch = 100
+
+// Config represents the attachment limits
+type Config struct {
+	MaxBytes int64
+	// ContentTypes are the media types accepted
+	ContentTypes []string
+	// SweepInterval is how often the objects of deleted attachments are removed; 0 disables it
+	SweepInterval time.Duration
+}
+
+// LoadConfig loads attachment configuration from environment variables
+func LoadConfig() *Config {
+	return &Config{
+		MaxBytes: utils.GetEnvAsInt64("ATTACHMENT_MAX_BYTES", 20<<20), // 20 MiB
+		ContentTypes: utils.GetEnvAsList("ATTACHMENT_CONTENT_TYPES", []string{
+			"application/pdf",
+			"image/png",
+			"image/jpeg",
+			"text/plain",
+			"text/csv",
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		}),
+		SweepInterval: utils.GetEnvAsDuration("ATTACHMENT_SWEEP_INTERVAL", 5*time.Minute),
+	}
+}
+
+// Service stores and reads attachments
+type Service struct {
+	repo    database.Repository
+	storage *storage.Client
+	cfg     *Config
+}
+
+// NewService creates a new Service; contents are kept in the database when
+// the storage client is not configured
+func NewService(repo database.Repository, storageClient *storage.Client, cfg *Config) *Service {
+	return &Service{repo: repo, storage: storageClient, cfg: cfg}
+}
+
+// Config returns the attachment configuration
+func (s *Service) Config() *Config {
+	return s.cfg
+}
+
+// Upload validates the file read from r and attaches it to the type or
+// generator; contentType is the declared media type, sniffed when it is
+// missing or generic
+func (s *Service) Upload(ctx context.Context, scope string, entityID uuid.UUID, fileName, contentType string, r io.Reader) (*models.Attachment, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.cfg.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if int64(len(data)) > s.cfg.MaxBytes {
+		return nil, fmt.Errorf("%w of %d bytes", ErrTooLarge, s.cfg.MaxBytes)
+	}
+	if len(data) == 0 {
+		return nil, ErrEmpty
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	if !s.allowed(mediaType) {
+		return nil, fmt.Errorf("%w: %s (allowed: %s)", ErrContentType, mediaType, strings.Join(s.cfg.ContentTypes, ", "))
+	}
+
+	sum := sha256.Sum256(data)
+	a := &models.Attachment{
+		ID:          uuid.New(),
+		Scope:       scope,
+		EntityID:    entityID,
+		FileName:    path.Base(strings.ReplaceAll(fileName, "\\", "/")),
+		ContentType: mediaType,
+		SizeBytes:   int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+	if a.FileName == "." || a.FileName == "/" {
+		a.FileName = "attachment"
+	}
+	if s.storage.Enabled() {
+		a.StorageKey = "attachments/" + scope + "/" + entityID.String() + "/" + a.ID.String() + "/" + a.FileName
+		if err := s.storage.Put(ctx, a.StorageKey, data, mediaType); err != nil {
+			return nil, fmt.Errorf("failed to store attachment: %w", err)
+		}
+	}
+
+	out, err := s.repo.CreateAttachment(ctx, a, data)
+	if err != nil {
+		if a.StorageKey != "" {
+			if delErr := s.storage.Delete(context.WithoutCancel(ctx), a.StorageKey); delErr != nil {
+				utils.LogError("delete attachment object "+a.StorageKey, delErr)
+			}
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *Service) allowed(mediaType string) bool {
+	for _, t := range s.cfg.ContentTypes {
+		if strings.EqualFold(t, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Open returns an attachment of the type or generator with a reader over its
+// content, which the caller closes
+func (s *Service) Open(ctx context.Context, scope string, entityID, id uuid.UUID) (*models.Attachment, io.ReadCloser, error) {
+	a, content, err := s.repo.GetAttachment(ctx, scope, entityID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a.StorageKey == "" {
+		return a, io.NopCloser(bytes.NewReader(content)), nil
+	}
+	body, _, err := s.storage.Open(ctx, a.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return a, body, nil
+}
+
+// Sweep removes from the bucket the objects of deleted attachments and
+// returns how many were removed
+func (s *Service) Sweep(ctx context.Context) (int, error) {
+	if !s.storage.Enabled() {
+		return 0, nil
+	}
+	keys, err := s.repo.GetAttachmentObjectDeletions(ctx, sweepBatch)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, key := range keys {
+		if err := s.storage.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+			return removed, fmt.Errorf("failed to delete attachment object %s: %w", key, err)
+		}
+		if err := s.repo.ForgetAttachmentObject(ctx, key); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// Run sweeps deleted attachment objects every SweepInterval until ctx is cancelled
+func (s *Service) Run(ctx context.Context) {
+	if s.cfg.SweepInterval <= 0 || !s.storage.Enabled() {
+		return
+	}
+	ticker := time.NewTicker(s.cfg.SweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.Sweep(ctx)
+			if err != nil {
+				utils.LogError("attachment sweep", err)
+				continue
+			}
+			if n > 0 {
+				utils.LogInfo(fmt.Sprintf("Removed %d objects of deleted attachments", n))
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// ErrAccountLocked is returned for logins to usernames locked after too many
+// failed attempts
+var ErrAccountLocked = errors.New("too many failed logins")
+
+// LockoutConfig represents how failed logins are slowed down and locked out.
+// Failures are counted per username, existing or not, so lockouts do not
+// reveal which usernames exist.
+type LockoutConfig struct {
+	// Threshold is the failures within Window that lock the username; 0
+	// disables lockouts
+	Threshold int
+	Window    time.Duration
+	// Duration is how long the first lockout lasts; every further lockout
+	// before a successful login doubles it, up to MaxDuration
+	Duration    time.Duration
+	MaxDuration time.Duration
+	// Delay slows down the answer to a failed login, doubling with every
+	// failure in a row up to MaxDelay
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+func loadLockoutConfig() LockoutConfig {
+	cfg := LockoutConfig{
+		Threshold:   utils.GetEnvAsInt("LOGIN_LOCKOUT_THRESHOLD", 5),
+		Window:      utils.GetEnvAsDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		Duration:    utils.GetEnvAsDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		MaxDuration: utils.GetEnvAsDuration("LOGIN_LOCKOUT_MAX_DURATION", 24*time.Hour),
+		Delay:       utils.GetEnvAsDuration("LOGIN_FAILURE_DELAY", 250*time.Millisecond),
+		MaxDelay:    utils.GetEnvAsDuration("LOGIN_FAILURE_MAX_DELAY", 4*time.Second),
+	}
+	if cfg.Threshold < 0 {
+		cfg.Threshold = 0
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 15 * time.Minute
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = 15 * time.Minute
+	}
+	if cfg.MaxDuration < cfg.Duration {
+		cfg.MaxDuration = cfg.Duration
+	}
+	if cfg.MaxDelay < cfg.Delay {
+		cfg.MaxDelay = cfg.Delay
+	}
+	return cfg
+}
+
+// FailureDelay returns how long to hold the answer to a failed login that
+// is the nth failure in a row
+func (c LockoutConfig) FailureDelay(failures int) time.Duration {
+	return backoff(c.Delay, c.MaxDelay, failures-1)
+}
+
+// LockFor returns how long a username is locked that was already locked
+// the given number of times since its last successful login
+func (c LockoutConfig) LockFor(lockouts int) time.Duration {
+	return backoff(c.Duration, c.MaxDuration, lockouts)
+}
+
+// backoff doubles base n times, up to max
+func backoff(base, max time.Duration, n int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	d := base
+	for i := 0; i < n && d < max; i++ {
+		d *= 2
+	}
+	return min(d, max)
+}
+
+// LockoutAlert reports a username locked after too many failed logins
+type LockoutAlert struct {
+	Username string `json:"username"`
+	// UserID is the account of the username, nil when none exists
+	UserID      *uuid.UUID `json:"userId,omitempty"`
+	Failures    int        `json:"failures"`
+	Lockouts    int        `json:"lockouts"`
+	LastIP      string     `json:"lastIp"`
+	LockedUntil time.Time  `json:"lockedUntil"`
+	RaisedAt    time.Time  `json:"raisedAt"`
+}
+
+// Notifier delivers lockout alerts
+type Notifier interface {
+	Notify(ctx context.Context, alert LockoutAlert) error
+}
+
+// LogNotifier writes lockout alerts to the server log
+type LogNotifier struct{}
+
+// Notify implements Notifier
+func (LogNotifier) Notify(_ context.Context, a LockoutAlert) error {
+	utils.LogInfo(fmt.Sprintf("Login of %q locked until %s after %d failed attempts, the last from %s",
+		a.Username, a.LockedUntil.Format(time.RFC3339), a.Failures, a.LastIP))
+	return nil
+}
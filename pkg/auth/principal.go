@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrForbidden is returned when the principal may not perform a write
+var ErrForbidden = errors.New("no write access")
+
+// Roles of users. Admins and operators write types, generators and
+// productions; viewers only read. Managing accounts needs an admin.
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleViewer   = "viewer"
+)
+
+// Roles lists the valid roles
+var Roles = []string{RoleAdmin, RoleOperator, RoleViewer}
+
+// Principal is the identity a request acts on behalf of
+type Principal struct {
+	UserID uuid.UUID
+	Roles  []string
+	// OperatorIDs are the operators whose generators (and their productions)
+	// the principal may write; admins write every generator. Holding any also
+	// makes the other shared data read-only to the principal (see Scoped).
+	OperatorIDs []uuid.UUID
+	// ImpersonatorID is the admin acting as this principal during the
+	// impersonation session SessionID; both are uuid.Nil otherwise
+	ImpersonatorID uuid.UUID
+	SessionID      uuid.UUID
+	// TwoFactor reports whether the access token of the request was issued
+	// after a second factor
+	TwoFactor bool
+}
+
+// NewPrincipal builds a principal from the roles and operator grants of a user
+func NewPrincipal(userID uuid.UUID, roles []string, grants []uuid.UUID) *Principal {
+	return &Principal{
+		UserID:      userID,
+		Roles:       roles,
+		OperatorIDs: grants,
+	}
+}
+
+// HasRole reports whether the principal has any of roles
+func (p *Principal) HasRole(roles ...string) bool {
+	if p == nil {
+		return false
+	}
+	for _, have := range p.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Impersonated reports whether an admin acts as the principal
+func (p *Principal) Impersonated() bool {
+	return p != nil && p.SessionID != uuid.Nil
+}
+
+// Scoped reports whether the principal contributes the data of specific
+// operators only, so it may not change data shared by all of them
+func (p *Principal) Scoped() bool {
+	return p != nil && len(p.OperatorIDs) > 0 && !p.HasRole(RoleAdmin)
+}
+
+// CanWriteOperator reports whether the principal may write generators owned
+// by operatorID: admins may write all, everyone else those of the operators
+// granted to them, none without grants. Generators without operator are
+// written by admins only.
+func (p *Principal) CanWriteOperator(operatorID *uuid.UUID) bool {
+	if p.HasRole(RoleAdmin) {
+		return true
+	}
+	if operatorID == nil {
+		return false
+	}
+	for _, id := range p.OperatorIDs {
+		if id == *operatorID {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal attached to ctx, if any
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of ctx carrying the IP address of the client
+// of the request, as resolved through the trusted proxies
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIPFrom returns the client IP address attached to ctx, empty if none
+func ClientIPFrom(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx carrying the tenant of the request, whose
+// queries then run on the database of that tenant
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the tenant attached to ctx, empty for the default database
+func TenantFrom(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+type replicaReadsKey struct{}
+
+// WithReplicaReads returns a copy of ctx whose read-only queries may run on
+// the read replica, which can lag behind the writes of the primary
+func WithReplicaReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaReadsKey{}, true)
+}
+
+// ReplicaReadsFrom reports whether the queries of ctx may read from the replica
+func ReplicaReadsFrom(ctx context.Context) bool {
+	ok, _ := ctx.Value(replicaReadsKey{}).(bool)
+	return ok
+}
@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// ErrInvalidToken is returned for access tokens that are malformed, badly
+// signed, expired or issued by someone else
+var ErrInvalidToken = errors.New("invalid access token")
+
+// Config represents authentication of API requests
+type Config struct {
+	// Secret is the HMAC key access tokens are signed with
+	Secret string
+	TTL    time.Duration
+	Issuer string
+	// Required rejects writes (POST, PUT, PATCH, DELETE) without a valid
+	// access token, and reads of the routes that require a role
+	Required bool
+	// OpenRegistration lets anyone register an account. Otherwise only the
+	// first account registers without a token and the rest are created by
+	// unrestricted users.
+	OpenRegistration bool
+	// FirstUserAdmin lets the first account register without a token and
+	// become admin. Without it the first admin is created out of band with
+	// `tadb create-admin`, so nobody can claim a fresh deployment.
+	FirstUserAdmin bool
+	// ImpersonationTTL is the longest an impersonation session may last
+	ImpersonationTTL time.Duration
+	// Lockout guards logins against password guessing
+	Lockout LockoutConfig
+	// TwoFactor configures TOTP and the roles that need it
+	TwoFactor TwoFactorConfig
+}
+
+// LoadConfig loads authentication configuration from environment variables.
+// Without JWT_SECRET a random secret is used, so tokens do not survive a
+// restart and are not accepted by other instances.
+func LoadConfig() *Config {
+	cfg := &Config{
+		Secret:           utils.GetEnv("JWT_SECRET", ""),
+		TTL:              utils.GetEnvAsDuration("JWT_TTL", 24*time.Hour),
+		Issuer:           utils.GetEnv("JWT_ISSUER", "tadb"),
+		Required:         utils.GetEnvAsBool("AUTH_REQUIRED", true),
+		OpenRegistration: utils.GetEnvAsBool("AUTH_OPEN_REGISTRATION", false),
+		FirstUserAdmin:   utils.GetEnvAsBool("AUTH_FIRST_USER_ADMIN", true),
+		ImpersonationTTL: utils.GetEnvAsDuration("IMPERSONATION_MAX_TTL", time.Hour),
+		Lockout:          loadLockoutConfig(),
+		TwoFactor:        loadTwoFactorConfig(),
+	}
+	if cfg.Secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			panic("auth: no randomness: " + err.Error())
+		}
+		cfg.Secret = hex.EncodeToString(b)
+		utils.LogInfo("JWT_SECRET is not set: signing access tokens with a random secret")
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.ImpersonationTTL <= 0 {
+		cfg.ImpersonationTTL = time.Hour
+	}
+	return cfg
+}
+
+// Tokens issues and verifies access tokens: JWTs signed with HS256 whose
+// subject is the user ID
+type Tokens struct {
+	cfg *Config
+	now func() time.Time
+}
+
+// NewTokens creates a token issuer and verifier for cfg
+func NewTokens(cfg *Config) *Tokens {
+	return &Tokens{cfg: cfg, now: time.Now}
+}
+
+// tokenHeader is the only JOSE header tokens are issued and accepted with
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+type claims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	ID        string `json:"jti"`
+	// Session is the impersonation session of impersonation tokens, whose
+	// subject is the admin impersonating
+	Session string `json:"sid,omitempty"`
+	// Methods are the authentication methods (RFC 8176) of tokens issued
+	// after a second factor: pwd and otp
+	Methods []string `json:"amr,omitempty"`
+}
+
+// Identity is the bearer of a verified access token
+type Identity struct {
+	UserID uuid.UUID
+	// SessionID is the impersonation session of impersonation tokens, whose
+	// user is the admin impersonating
+	SessionID *uuid.UUID
+	// TwoFactor reports whether the token was issued after a second factor
+	TwoFactor bool
+}
+
+// twoFactorMethods are the methods of tokens issued after a second factor
+var twoFactorMethods = []string{"pwd", "otp"}
+
+// Issue returns a signed access token for a user and when it expires;
+// twoFactor marks tokens issued after a second factor
+func (t *Tokens) Issue(userID uuid.UUID, twoFactor bool) (string, time.Time, error) {
+	now := t.now()
+	exp := now.Add(t.cfg.TTL)
+	c := claims{
+		Issuer:    t.cfg.Issuer,
+		Subject:   userID.String(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: exp.Unix(),
+		ID:        uuid.NewString(),
+	}
+	if twoFactor {
+		c.Methods = twoFactorMethods
+	}
+	token, err := t.issue(c)
+	return token, exp, err
+}
+
+// IssueImpersonation returns a signed access token of an impersonation
+// session, issued to the admin impersonating and valid until expiresAt.
+// twoFactor carries over whether the admin had used a second factor.
+func (t *Tokens) IssueImpersonation(actorID, sessionID uuid.UUID, expiresAt time.Time, twoFactor bool) (string, error) {
+	c := claims{
+		Issuer:    t.cfg.Issuer,
+		Subject:   actorID.String(),
+		IssuedAt:  t.now().Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		ID:        uuid.NewString(),
+		Session:   sessionID.String(),
+	}
+	if twoFactor {
+		c.Methods = twoFactorMethods
+	}
+	return t.issue(c)
+}
+
+func (t *Tokens) issue(c claims) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token claims: %w", err)
+	}
+	signed := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + t.sign(signed), nil
+}
+
+// Verify checks an access token and returns the user it was issued to
+func (t *Tokens) Verify(token string) (uuid.UUID, error) {
+	id, err := t.VerifyIdentity(token)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return id.UserID, nil
+}
+
+// VerifyIdentity checks an access token and returns the user it was issued
+// to with, for impersonation tokens, the impersonation session
+func (t *Tokens) VerifyIdentity(token string) (*Identity, error) {
+	header, rest, ok := strings.Cut(token, ".")
+	if !ok || header != tokenHeader {
+		return nil, ErrInvalidToken
+	}
+	payload, signature, ok := strings.Cut(rest, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(t.sign(header+"."+payload))) {
+		return nil, ErrInvalidToken
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var c claims
+	if err := json.Unmarshal(raw, &c); err != nil || c.Issuer != t.cfg.Issuer {
+		return nil, ErrInvalidToken
+	}
+	if t.now().Unix() >= c.ExpiresAt {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	userID, err := uuid.Parse(c.Subject)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	id := &Identity{UserID: userID, TwoFactor: slices.Contains(c.Methods, "otp")}
+	if c.Session == "" {
+		return id, nil
+	}
+	sessionID, err := uuid.Parse(c.Session)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	id.SessionID = &sessionID
+	return id, nil
+}
+
+func (t *Tokens) sign(signed string) string {
+	mac := hmac.New(sha256.New, []byte(t.cfg.Secret))
+	mac.Write([]byte(signed))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
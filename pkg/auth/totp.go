@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// Two-factor errors
+var (
+	// ErrTwoFactorRequired is returned for logins to accounts with two-factor
+	// authentication that carry no code
+	ErrTwoFactorRequired = errors.New("two-factor code required")
+	// ErrInvalidTwoFactorCode is returned for wrong, expired or reused codes
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+)
+
+// TOTP parameters (RFC 6238), the defaults every authenticator app supports
+const (
+	TOTPAlgorithm = "SHA1"
+	TOTPDigits    = 6
+	TOTPPeriod    = 30
+)
+
+// BackupCodeCount is how many backup codes are issued at a time
+const BackupCodeCount = 10
+
+// TwoFactorConfig represents two-factor authentication with TOTP
+type TwoFactorConfig struct {
+	// Issuer names the service in authenticator apps
+	Issuer string
+	// RequiredRoles need a second factor for writes: users with any of them
+	// must enroll and log in with a code before writing
+	RequiredRoles []string
+	// Skew is how many time steps before and after the current one codes
+	// are accepted from, for clock drift
+	Skew int
+}
+
+func loadTwoFactorConfig() TwoFactorConfig {
+	cfg := TwoFactorConfig{
+		Issuer: utils.GetEnv("TOTP_ISSUER", "TADB"),
+		Skew:   utils.GetEnvAsInt("TOTP_SKEW", 1),
+	}
+	for _, role := range strings.Split(utils.GetEnv("TOTP_REQUIRED_ROLES", ""), ",") {
+		role = strings.TrimSpace(role)
+		if role == "" {
+			continue
+		}
+		if !slices.Contains(Roles, role) {
+			utils.LogError("auth: TOTP_REQUIRED_ROLES", fmt.Errorf("unknown role %q", role))
+			continue
+		}
+		cfg.RequiredRoles = append(cfg.RequiredRoles, role)
+	}
+	if cfg.Skew < 0 || cfg.Skew > 10 {
+		cfg.Skew = 1
+	}
+	return cfg
+}
+
+// RequiredFor returns the first role of roles that needs a second factor;
+// false when none does
+func (c TwoFactorConfig) RequiredFor(roles []string) (string, bool) {
+	for _, role := range roles {
+		if slices.Contains(c.RequiredRoles, role) {
+			return role, true
+		}
+	}
+	return "", false
+}
+
+// NewTOTPSecret returns a random 160-bit TOTP secret in base32
+func NewTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+// ProvisioningURI returns the otpauth:// URI of a secret, the content of the
+// QR code authenticator apps scan
+func ProvisioningURI(issuer, account, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", TOTPAlgorithm)
+	q.Set("digits", fmt.Sprint(TOTPDigits))
+	q.Set("period", fmt.Sprint(TOTPPeriod))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// TOTPStep returns the time step of t
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / TOTPPeriod
+}
+
+// TOTPCode returns the code of a secret at a time step
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", TOTPDigits, value%1000000), nil
+}
+
+// ValidateTOTP checks a code against a secret at now, accepting the steps
+// within skew of the current one that come after lastStep, and returns the
+// step it matched
+func ValidateTOTP(secret, code string, now time.Time, skew int, lastStep int64) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+	current := TOTPStep(now)
+	for step := current - int64(skew); step <= current+int64(skew); step++ {
+		if step <= lastStep {
+			continue
+		}
+		want, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// backupAlphabet leaves out look-alike characters; 32 of them, so every
+// random byte maps to one without bias
+const backupAlphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+
+// NewBackupCodes returns BackupCodeCount random single-use codes, formatted
+// as xxxx-xxxx
+func NewBackupCodes() ([]string, error) {
+	codes := make([]string, BackupCodeCount)
+	b := make([]byte, 8)
+	for i := range codes {
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("failed to generate backup codes: %w", err)
+		}
+		var sb strings.Builder
+		for j, c := range b {
+			if j == 4 {
+				sb.WriteByte('-')
+			}
+			sb.WriteByte(backupAlphabet[int(c)%len(backupAlphabet)])
+		}
+		codes[i] = sb.String()
+	}
+	return codes, nil
+}
+
+// HashBackupCode returns the stored form of a backup code; case, spaces and
+// dashes do not matter. Codes are random enough for an unsalted SHA-256.
+func HashBackupCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
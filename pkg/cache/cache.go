@@ -0,0 +1,52 @@
+// Package cache stores short-lived values such as cached responses and rate
+// limit counters, in memory or, when REDIS_URI is set, in Redis so every
+// replica of the API shares them.
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// Store keeps values by key until they expire
+type Store interface {
+	// Get returns the value of key; false when it is missing or expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl; 0 keeps it until it is deleted
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	// Incr adds one to the counter under key and returns the new value; a
+	// missing counter starts from 0 and expires after ttl
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	Close() error
+}
+
+// Config represents where cached values are kept
+type Config struct {
+	// RedisURI is redis://[user:password@]host:port[/db], or rediss:// for
+	// TLS; empty keeps the values in the memory of this process
+	RedisURI string
+	// MaxEntries bounds the values kept in memory
+	MaxEntries int
+	// Timeout bounds every Redis command
+	Timeout time.Duration
+}
+
+// LoadConfig loads cache configuration from environment variables
+func LoadConfig() *Config {
+	return &Config{
+		RedisURI:   utils.GetEnv("REDIS_URI", ""),
+		MaxEntries: utils.GetEnvAsInt("CACHE_MAX_ENTRIES", 4096),
+		Timeout:    utils.GetEnvAsDuration("REDIS_TIMEOUT", 2*time.Second),
+	}
+}
+
+// New returns the Redis store of cfg, or a memory store without RedisURI
+func New(cfg *Config) (Store, error) {
+	if cfg.RedisURI == "" {
+		return NewMemory(cfg.MaxEntries), nil
+	}
+	return NewRedis(cfg.RedisURI, cfg.Timeout)
+}
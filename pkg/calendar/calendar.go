@@ -0,0 +1,218 @@
+// Package calendar counts business days on per-country holiday calendars,
+// so jobs can be scheduled relative to publication dates ("the 5th business
+// day of the month") rather than at fixed intervals, and data lags and
+// comparisons can skip weekends and holidays.
+package calendar
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrUnknownCalendar is returned for a country without a holiday calendar
+var ErrUnknownCalendar = errors.New("unknown holiday calendar")
+
+// validCountry accepts ISO 3166-1 alpha-2 codes
+var validCountry = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// Holiday is a non-working day of a calendar
+type Holiday struct {
+	// Date is YYYY-MM-DD, or MM-DD for a holiday on the same date every year
+	Date string `json:"date"`
+	Name string `json:"name,omitempty"`
+}
+
+// Calendar is the weekend and the holidays of a country
+type Calendar struct {
+	// Country is the ISO 3166-1 alpha-2 code the calendar is looked up by
+	Country string `json:"country"`
+	// Weekend lists the non-working weekdays (0 = Sunday), default Saturday and Sunday
+	Weekend  []time.Weekday `json:"weekend,omitempty"`
+	Holidays []Holiday      `json:"holidays"`
+
+	weekend  map[time.Weekday]bool
+	holidays map[string]string
+}
+
+// Calendars are the holiday calendars by country code
+type Calendars map[string]*Calendar
+
+// calendarsFile is the layout of HOLIDAY_CALENDARS_FILE
+type calendarsFile struct {
+	Calendars []*Calendar `json:"calendars"`
+}
+
+// Load reads and checks a calendars file; no file name gives no calendars
+func Load(file string) (Calendars, error) {
+	cs := Calendars{}
+	if file == "" {
+		return cs, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read holiday calendars: %w", err)
+	}
+	var f calendarsFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to parse holiday calendars: %w", err)
+	}
+	for i, c := range f.Calendars {
+		c.Country = strings.ToUpper(c.Country)
+		if err := c.check(); err != nil {
+			return nil, fmt.Errorf("holiday calendar %d (%s): %w", i, c.Country, err)
+		}
+		if cs[c.Country] != nil {
+			return nil, fmt.Errorf("holiday calendar %d: duplicate country %s", i, c.Country)
+		}
+		cs[c.Country] = c
+	}
+	return cs, nil
+}
+
+func (c *Calendar) check() error {
+	if !validCountry.MatchString(c.Country) {
+		return errors.New("country must be an ISO 3166-1 alpha-2 code")
+	}
+	if c.Weekend == nil {
+		c.Weekend = []time.Weekday{time.Saturday, time.Sunday}
+	}
+	c.weekend = map[time.Weekday]bool{}
+	for _, d := range c.Weekend {
+		if d < time.Sunday || d > time.Saturday {
+			return errors.New("weekend days must be weekdays from 0 (Sunday) to 6")
+		}
+		c.weekend[d] = true
+	}
+	if len(c.weekend) == 7 {
+		return errors.New("weekend cannot cover the whole week")
+	}
+	c.holidays = map[string]string{}
+	for _, h := range c.Holidays {
+		if !ValidHolidayDate(h.Date) {
+			return fmt.Errorf("invalid holiday date %q: use YYYY-MM-DD or MM-DD", h.Date)
+		}
+		c.holidays[h.Date] = h.Name
+	}
+	return nil
+}
+
+// ValidHolidayDate reports whether date is YYYY-MM-DD, or MM-DD for a
+// holiday on the same date every year
+func ValidHolidayDate(date string) bool {
+	if _, err := time.Parse("2006-01-02", date); err == nil {
+		return true
+	}
+	_, err := time.Parse("01-02", date)
+	return err == nil && len(date) == 5
+}
+
+// weekends is the calendar of schedules that name no country
+var weekends = &Calendar{
+	Weekend: []time.Weekday{time.Saturday, time.Sunday},
+	weekend: map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+}
+
+// Get returns the calendar of a country; an empty country is Saturdays and
+// Sundays without holidays
+func (cs Calendars) Get(country string) (*Calendar, error) {
+	if country == "" {
+		return weekends, nil
+	}
+	c, ok := cs[strings.ToUpper(country)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCalendar, country)
+	}
+	return c, nil
+}
+
+// Holiday returns the name of the holiday on the date of day, if it is one
+func (c *Calendar) Holiday(day time.Time) (string, bool) {
+	if name, ok := c.holidays[day.Format("2006-01-02")]; ok {
+		return name, true
+	}
+	name, ok := c.holidays[day.Format("01-02")]
+	return name, ok
+}
+
+// IsBusinessDay reports whether the date of day is neither weekend nor holiday
+func (c *Calendar) IsBusinessDay(day time.Time) bool {
+	if c.weekend[day.Weekday()] {
+		return false
+	}
+	_, holiday := c.Holiday(day)
+	return !holiday
+}
+
+// AddBusinessDays returns the business day n business days after the date of
+// day, or before it when n is negative; 0 gives the date itself
+func (c *Calendar) AddBusinessDays(day time.Time, n int) time.Time {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		day = day.AddDate(0, 0, step)
+		if c.IsBusinessDay(day) {
+			n--
+		}
+	}
+	return day
+}
+
+// PreviousBusinessDay returns the last business day before the date of day
+func (c *Calendar) PreviousBusinessDay(day time.Time) time.Time {
+	return c.AddBusinessDays(day, -1)
+}
+
+// NextBusinessDay returns the first business day after the date of day
+func (c *Calendar) NextBusinessDay(day time.Time) time.Time {
+	return c.AddBusinessDays(day, 1)
+}
+
+// BusinessDaysBetween counts the business days after the date of from up to
+// and including the date of to, negative when to is before from
+func (c *Calendar) BusinessDaysBetween(from, to time.Time) int {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	sign := 1
+	if to.Before(from) {
+		from, to, sign = to, from, -1
+	}
+	n := 0
+	for day := from.AddDate(0, 0, 1); !day.After(to); day = day.AddDate(0, 0, 1) {
+		if c.IsBusinessDay(day) {
+			n++
+		}
+	}
+	return sign * n
+}
+
+// BusinessDay returns the nth business day of a month, counted from the end
+// of the month when n is negative (-1 is the last); false when the month has
+// fewer business days
+func (c *Calendar) BusinessDay(year int, month time.Month, n int) (time.Time, bool) {
+	if n == 0 {
+		return time.Time{}, false
+	}
+	day, step := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), 1
+	if n < 0 {
+		day, step, n = day.AddDate(0, 1, -1), -1, -n
+	}
+	for ; day.Month() == month; day = day.AddDate(0, 0, step) {
+		if c.IsBusinessDay(day) {
+			if n--; n == 0 {
+				return day, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,96 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	// Embedded zone database so schedule timezones resolve in minimal containers
+	_ "time/tzdata"
+)
+
+// maxBusinessDay bounds the business day of a rule; no month has more
+const maxBusinessDay = 23
+
+// Rule is a time of the month relative to the business days of a calendar
+type Rule struct {
+	// BusinessDay is the nth business day of the month, counted from the end
+	// when negative (-1 is the last)
+	BusinessDay int `json:"businessDay"`
+	// At is the time of day (HH:MM), default 00:00
+	At string `json:"at,omitempty"`
+	// Calendar is the country whose holidays are skipped; weekends only when empty
+	Calendar string `json:"calendar,omitempty"`
+	// Timezone of At and of the dates, default UTC
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Schedule is a Rule resolved against its calendar and timezone
+type Schedule struct {
+	rule     Rule
+	calendar *Calendar
+	loc      *time.Location
+	at       time.Duration
+}
+
+// Schedule checks a rule and resolves its calendar and timezone
+func (cs Calendars) Schedule(r *Rule) (*Schedule, error) {
+	if r.BusinessDay == 0 || r.BusinessDay > maxBusinessDay || r.BusinessDay < -maxBusinessDay {
+		return nil, fmt.Errorf("businessDay must be from 1 to %d, or from -1 to -%d counting from the end of the month", maxBusinessDay, maxBusinessDay)
+	}
+	s := &Schedule{rule: *r, loc: time.UTC}
+	if r.At != "" {
+		at, err := time.Parse("15:04", r.At)
+		if err != nil {
+			return nil, errors.New("at must be a time of day (HH:MM)")
+		}
+		s.at = time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+	}
+	if r.Timezone != "" {
+		loc, err := time.LoadLocation(r.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", r.Timezone)
+		}
+		s.loc = loc
+	}
+	cal, err := cs.Get(r.Calendar)
+	if err != nil {
+		return nil, err
+	}
+	s.calendar = cal
+	return s, nil
+}
+
+// Next returns the first time of the schedule strictly after after. Months
+// with fewer business days than the rule asks for are skipped.
+func (s *Schedule) Next(after time.Time) time.Time {
+	t := after.In(s.loc)
+	first := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 12; i++ {
+		month := first.AddDate(0, i, 0)
+		day, ok := s.calendar.BusinessDay(month.Year(), month.Month(), s.rule.BusinessDay)
+		if !ok {
+			continue
+		}
+		run := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, s.loc).Add(s.at)
+		if run.After(after) {
+			return run.UTC()
+		}
+	}
+	// No month of the coming year has that many business days: look again in a month
+	return after.AddDate(0, 1, 0).UTC()
+}
+
+// String describes the schedule, e.g. "business day 5 at 09:00 America/Bogota (CO)"
+func (s *Schedule) String() string {
+	day := fmt.Sprintf("business day %d", s.rule.BusinessDay)
+	if s.rule.BusinessDay < 0 {
+		day = fmt.Sprintf("business day %d from the end", -s.rule.BusinessDay)
+	}
+	at := fmt.Sprintf("%02d:%02d", int(s.at.Hours()), int(s.at.Minutes())%60)
+	out := day + " at " + at + " " + s.loc.String()
+	if s.calendar.Country != "" {
+		out += " (" + s.calendar.Country + ")"
+	}
+	return out
+}
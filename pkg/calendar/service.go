@@ -0,0 +1,189 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// validCode accepts a country code, optionally followed by the code of one
+// of its regions: CO or CO-ANT
+var validCode = regexp.MustCompile(`^([A-Z]{2})(?:-([A-Z0-9]{1,10}))?$`)
+
+// Config represents the holiday calendars and how long resolved ones are kept
+type Config struct {
+	// File is the JSON file with the base calendars, see Load
+	File string
+	// CacheTTL is how long a resolved calendar is used before the stored
+	// holidays are read again; changes made through the service apply at once
+	CacheTTL time.Duration
+}
+
+// LoadConfig loads calendar configuration from environment variables
+func LoadConfig() *Config {
+	return &Config{
+		File:     utils.GetEnv("HOLIDAY_CALENDARS_FILE", ""),
+		CacheTTL: utils.GetEnvAsDuration("HOLIDAY_CACHE_TTL", 5*time.Minute),
+	}
+}
+
+// Store lists the holidays managed through the API
+type Store interface {
+	ListHolidays(ctx context.Context, country string) ([]*models.Holiday, error)
+}
+
+// Service resolves calendars by code from the calendars file and the stored
+// holidays: a country calendar has the weekend and holidays of the file plus
+// the stored holidays of the country, and a region calendar (CO-ANT) adds
+// the stored holidays of that region.
+type Service struct {
+	cfg   *Config
+	files Calendars
+	store Store
+	now   func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedCalendar
+}
+
+type cachedCalendar struct {
+	calendar *Calendar
+	// stored are the dates of the holidays that come from the store
+	stored   map[string]bool
+	loadedAt time.Time
+}
+
+// NewService creates a calendar service, reading the calendars file
+func NewService(cfg *Config, store Store) (*Service, error) {
+	files, err := Load(cfg.File)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{cfg: cfg, files: files, store: store, now: time.Now, cache: map[string]cachedCalendar{}}, nil
+}
+
+// ParseCode splits a calendar code into its country and region, upper-case
+func ParseCode(code string) (country, region string, err error) {
+	m := validCode.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(code)))
+	if m == nil {
+		return "", "", fmt.Errorf("%w: %q is not a country code (CO) or a country and region code (CO-ANT)", ErrUnknownCalendar, code)
+	}
+	return m[1], m[2], nil
+}
+
+// Invalidate drops the resolved calendars, after the stored holidays changed
+func (s *Service) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache = map[string]cachedCalendar{}
+}
+
+// Calendar returns the calendar of a code; an empty code is Saturdays and
+// Sundays without holidays. A country without calendar in the file nor
+// stored holidays is unknown.
+func (s *Service) Calendar(ctx context.Context, code string) (*Calendar, error) {
+	c, err := s.resolve(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	return c.calendar, nil
+}
+
+func (s *Service) resolve(ctx context.Context, code string) (cachedCalendar, error) {
+	if code == "" {
+		return cachedCalendar{calendar: weekends}, nil
+	}
+	country, region, err := ParseCode(code)
+	if err != nil {
+		return cachedCalendar{}, err
+	}
+	code = country
+	if region != "" {
+		code += "-" + region
+	}
+
+	// Tenants with databases of their own store holidays of their own
+	key := auth.TenantFrom(ctx) + "/" + code
+	s.mu.Lock()
+	c, ok := s.cache[key]
+	s.mu.Unlock()
+	if ok && s.now().Sub(c.loadedAt) < s.cfg.CacheTTL {
+		return c, nil
+	}
+
+	holidays, err := s.store.ListHolidays(ctx, country)
+	if err != nil {
+		return cachedCalendar{}, err
+	}
+	base := s.files[country]
+	if base == nil && len(holidays) == 0 {
+		return cachedCalendar{}, fmt.Errorf("%w: %s", ErrUnknownCalendar, code)
+	}
+	cal := &Calendar{Country: country}
+	if base != nil {
+		cal.Weekend = base.Weekend
+		cal.Holidays = append(cal.Holidays, base.Holidays...)
+	}
+	c = cachedCalendar{calendar: cal, stored: map[string]bool{}, loadedAt: s.now()}
+	for _, h := range holidays {
+		if h.Region == "" || h.Region == region {
+			cal.Holidays = append(cal.Holidays, Holiday{Date: h.Date, Name: h.Name})
+			c.stored[h.Date] = true
+		}
+	}
+	if err := cal.check(); err != nil {
+		return cachedCalendar{}, fmt.Errorf("holiday calendar %s: %w", code, err)
+	}
+	cal.Country = code
+
+	s.mu.Lock()
+	s.cache[key] = c
+	s.mu.Unlock()
+	return c, nil
+}
+
+// Range resolves the calendar of a code over the inclusive date range from
+// start to end: its business days, holidays and the business days around it
+func (s *Service) Range(ctx context.Context, code string, start, end time.Time) (*models.BusinessCalendar, error) {
+	c, err := s.resolve(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	cal := c.calendar
+	const layout = "2006-01-02"
+	out := &models.BusinessCalendar{
+		Code:                cal.Country,
+		Weekend:             cal.Weekend,
+		StartDate:           start.Format(layout),
+		EndDate:             end.Format(layout),
+		Holidays:            []*models.CalendarDay{},
+		PreviousBusinessDay: cal.PreviousBusinessDay(start).Format(layout),
+		NextBusinessDay:     cal.NextBusinessDay(end).Format(layout),
+	}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		out.Days++
+		if name, ok := cal.Holiday(day); ok {
+			// Holiday prefers the holiday of the exact date over a yearly one
+			date := day.Format(layout)
+			if _, exact := cal.holidays[date]; !exact {
+				date = day.Format("01-02")
+			}
+			source := "file"
+			if c.stored[date] {
+				source = "api"
+			}
+			out.Holidays = append(out.Holidays, &models.CalendarDay{Date: day.Format(layout), Name: name, Source: source})
+		}
+		if cal.IsBusinessDay(day) {
+			out.BusinessDays++
+		}
+	}
+	return out, nil
+}
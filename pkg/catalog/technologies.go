@@ -0,0 +1,124 @@
+package catalog
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// ErrUnknownTechnology is returned when a name or code is not in the catalog
+var ErrUnknownTechnology = errors.New("unknown technology")
+
+// Technology is a canonical generation technology used to compare datasets
+// @Description Canonical generation technology
+type Technology struct {
+	Code        string   `json:"code" example:"SOLAR"`
+	Name        string   `json:"name" example:"Solar"`
+	NameEn      string   `json:"nameEn" example:"Solar"`
+	IsRenewable bool     `json:"isRenewable" example:"true"`
+	Aliases     []string `json:"aliases" example:"solar fotovoltaica,fotovoltaica,pv"`
+}
+
+// technologies is the built-in catalog of well-known technologies
+var technologies = []Technology{
+	{Code: "SOLAR", Name: "Solar", NameEn: "Solar", IsRenewable: true, Aliases: []string{"solar fotovoltaica", "fotovoltaica", "photovoltaic", "pv"}},
+	{Code: "WIND", Name: "Eólica", NameEn: "Wind", IsRenewable: true, Aliases: []string{"eolica", "wind", "viento"}},
+	{Code: "HYDRO", Name: "Hidráulica", NameEn: "Hydro", IsRenewable: true, Aliases: []string{"hidraulica", "hidro", "hidroelectrica", "hydro", "hydroelectric", "agua"}},
+	{Code: "BIOMASS", Name: "Biomasa", NameEn: "Biomass", IsRenewable: true, Aliases: []string{"biomass", "biogas", "cogeneracion"}},
+	{Code: "GEOTHERMAL", Name: "Geotérmica", NameEn: "Geothermal", IsRenewable: true, Aliases: []string{"geotermica", "geothermal"}},
+	{Code: "THERMAL", Name: "Térmica", NameEn: "Thermal", IsRenewable: false, Aliases: []string{"termica", "thermal", "termoelectrica"}},
+	{Code: "COAL", Name: "Carbón", NameEn: "Coal", IsRenewable: false, Aliases: []string{"carbon", "coal"}},
+	{Code: "GAS", Name: "Gas natural", NameEn: "Natural gas", IsRenewable: false, Aliases: []string{"gas", "natural gas"}},
+	{Code: "OIL", Name: "Combustibles líquidos", NameEn: "Oil", IsRenewable: false, Aliases: []string{"combustibles liquidos", "diesel", "fuel oil", "oil", "acpm"}},
+	{Code: "NUCLEAR", Name: "Nuclear", NameEn: "Nuclear", IsRenewable: false, Aliases: []string{"nuclear"}},
+}
+
+// byKey indexes codes, names and aliases (normalized) to catalog entries
+var byKey = func() map[string]*Technology {
+	index := make(map[string]*Technology)
+	for i := range technologies {
+		t := &technologies[i]
+		index[normalize(t.Code)] = t
+		index[normalize(t.Name)] = t
+		index[normalize(t.NameEn)] = t
+		for _, alias := range t.Aliases {
+			index[normalize(alias)] = t
+		}
+	}
+	return index
+}()
+
+// Config represents catalog validation settings
+type Config struct {
+	// Enforce rejects types whose name does not map to a canonical technology
+	Enforce bool
+}
+
+// LoadConfig loads catalog configuration from environment variables
+func LoadConfig() *Config {
+	return &Config{
+		Enforce: utils.GetEnvAsBool("CATALOG_ENFORCE", false),
+	}
+}
+
+// All returns the catalog sorted by code
+func All() []Technology {
+	list := make([]Technology, len(technologies))
+	copy(list, technologies)
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list
+}
+
+// ByCode returns the technology with the given code
+func ByCode(code string) (*Technology, bool) {
+	for i := range technologies {
+		if strings.EqualFold(technologies[i].Code, strings.TrimSpace(code)) {
+			t := technologies[i]
+			return &t, true
+		}
+	}
+	return nil, false
+}
+
+// Lookup maps a free-form type name ("solar", "Eólica", "HIDRO") to a technology
+func Lookup(name string) (*Technology, bool) {
+	t, ok := byKey[normalize(name)]
+	if !ok {
+		return nil, false
+	}
+	cp := *t
+	return &cp, true
+}
+
+// Resolve returns the canonical code for a type. An explicit code must exist
+// in the catalog; otherwise the code is inferred from the name. When enforce
+// is set, names that cannot be mapped are rejected.
+func Resolve(name, code string, enforce bool) (string, error) {
+	if code != "" {
+		t, ok := ByCode(code)
+		if !ok {
+			return "", ErrUnknownTechnology
+		}
+		return t.Code, nil
+	}
+	if t, ok := Lookup(name); ok {
+		return t.Code, nil
+	}
+	if enforce {
+		return "", ErrUnknownTechnology
+	}
+	return "", nil
+}
+
+// normalize lowercases, trims and strips Spanish accents so "Eólica" matches "eolica"
+func normalize(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return accentReplacer.Replace(s)
+}
+
+var accentReplacer = strings.NewReplacer(
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+	"_", " ", "-", " ",
+)
@@ -0,0 +1,293 @@
+// Package client is the Go client of the TADB API. It covers every endpoint
+// with typed methods on the server models, sends the bearer token, retries
+// transient failures and iterates paginated lists.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// Config represents the client configuration
+type Config struct {
+	// BaseURL of the API including the version prefix, e.g. http://localhost:8080/api/v1
+	BaseURL string
+	// Token is sent as "Authorization: Bearer <token>" when set
+	Token      string
+	Timeout    time.Duration
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry; it doubles on each attempt
+	RetryBackoff time.Duration
+	UserAgent    string
+	// HTTPClient overrides the default http.Client (Timeout is then ignored)
+	HTTPClient *http.Client
+}
+
+// LoadConfig loads client configuration from environment variables
+func LoadConfig() *Config {
+	return &Config{
+		BaseURL:      utils.GetEnv("TADB_API_URL", "http://localhost:8080/api/v1"),
+		Token:        utils.GetEnv("TADB_API_TOKEN", ""),
+		Timeout:      utils.GetEnvAsDuration("TADB_API_TIMEOUT", 30*time.Second),
+		MaxRetries:   utils.GetEnvAsInt("TADB_API_MAX_RETRIES", 3),
+		RetryBackoff: utils.GetEnvAsDuration("TADB_API_RETRY_BACKOFF", 200*time.Millisecond),
+		UserAgent:    "tadb-go-client",
+	}
+}
+
+// APIError is returned for non-2xx responses
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("tadb api: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsNotFound reports whether err is a 404 response
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Client calls the TADB API
+type Client struct {
+	cfg     *Config
+	baseURL *url.URL
+	http    *http.Client
+	// consistencyToken is the X-Consistency-Token of the last write, sent
+	// with later requests so they read that write even behind a read replica
+	consistencyToken atomic.Value
+}
+
+// New creates a new API client
+func New(cfg *Config) (*Client, error) {
+	base, err := url.Parse(strings.TrimRight(cfg.BaseURL, "/"))
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", cfg.BaseURL)
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: cfg.Timeout}
+	}
+	return &Client{cfg: cfg, baseURL: base, http: httpClient}, nil
+}
+
+// request describes one API call
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	// url, when set, is an absolute URL (e.g. a next-page link) used instead of path and query
+	url string
+	// body is JSON-encoded unless it is []byte (sent as is) or an io.Reader (streamed)
+	body        any
+	contentType string
+	header      http.Header
+}
+
+// retryable reports whether a failed request may be sent again. Only requests
+// that are safe to repeat are retried, and never with a streamed body.
+func (r *request) retryable() bool {
+	if _, streamed := r.body.(io.Reader); streamed {
+		return false
+	}
+	switch r.method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusBadGateway ||
+		code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+// do sends req and decodes a JSON response into out (when non-nil). The
+// response is returned with its body closed so callers can read headers.
+func (c *Client) do(ctx context.Context, req *request, out any) (*http.Response, error) {
+	var payload []byte
+	if raw, ok := req.body.([]byte); ok {
+		payload = raw
+	} else if req.body != nil {
+		if _, ok := req.body.(io.Reader); !ok {
+			b, err := json.Marshal(req.body)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode request: %w", err)
+			}
+			payload = b
+			if req.contentType == "" {
+				req.contentType = "application/json"
+			}
+		}
+	}
+
+	attempts := 1
+	if req.retryable() {
+		attempts += c.cfg.MaxRetries
+	}
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := c.wait(ctx, attempt, lastErr); err != nil {
+				return nil, err
+			}
+		}
+
+		httpReq, err := c.newHTTPRequest(ctx, req, payload)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.http.Do(httpReq)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+
+		if resp.StatusCode >= 300 {
+			apiErr := readAPIError(resp)
+			if retryableStatus(resp.StatusCode) {
+				lastErr = &retryAfterError{APIError: apiErr, after: retryAfter(resp)}
+				continue
+			}
+			return resp, apiErr
+		}
+
+		if token := resp.Header.Get("X-Consistency-Token"); token != "" {
+			c.consistencyToken.Store(token)
+		}
+		err = decodeBody(resp, out)
+		return resp, err
+	}
+	var ra *retryAfterError
+	if errors.As(lastErr, &ra) {
+		return nil, ra.APIError
+	}
+	return nil, fmt.Errorf("request failed after %d attempts: %w", attempts, lastErr)
+}
+
+func (c *Client) newHTTPRequest(ctx context.Context, req *request, payload []byte) (*http.Request, error) {
+	u := *c.baseURL
+	u.Path += req.path
+	if len(req.query) > 0 {
+		u.RawQuery = req.query.Encode()
+	}
+	if req.url != "" {
+		next, err := url.Parse(req.url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid page link %q: %w", req.url, err)
+		}
+		u = *c.baseURL.ResolveReference(next)
+	}
+
+	var body io.Reader
+	if r, ok := req.body.(io.Reader); ok {
+		body = r
+	} else if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	for k, v := range req.header {
+		httpReq.Header[k] = v
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	if c.cfg.Token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	}
+	if c.cfg.UserAgent != "" {
+		httpReq.Header.Set("User-Agent", c.cfg.UserAgent)
+	}
+	if token, _ := c.consistencyToken.Load().(string); token != "" {
+		httpReq.Header.Set("X-Consistency-Token", token)
+	}
+	return httpReq, nil
+}
+
+// wait sleeps before a retry: Retry-After when the server sent one, otherwise
+// exponential backoff with jitter
+func (c *Client) wait(ctx context.Context, attempt int, lastErr error) error {
+	delay := c.cfg.RetryBackoff << (attempt - 1)
+	delay += time.Duration(rand.Int63n(int64(delay)/2 + 1))
+	var ra *retryAfterError
+	if errors.As(lastErr, &ra) && ra.after > 0 {
+		delay = ra.after
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryAfterError carries the server's Retry-After hint between attempts
+type retryAfterError struct {
+	*APIError
+	after time.Duration
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return 0
+}
+
+// readAPIError builds an APIError from the standard error body and closes it
+func readAPIError(resp *http.Response) *APIError {
+	defer resp.Body.Close()
+	var body utils.Response
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	msg := strings.TrimSpace(string(data))
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		msg = body.Error
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: msg}
+}
+
+func decodeBody(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	// Files (e.g. report downloads) are returned as is
+	if raw, ok := out.(*[]byte); ok {
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		*raw = b
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,2055 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/catalog"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/geo"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/imports"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/jobs"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/metadata"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+)
+
+// Route is an API operation covered by the client, in OpenAPI path notation
+type Route struct {
+	Method string
+	Path   string
+}
+
+// Routes lists every operation the client implements. `tadb check-client`
+// compares it with the OpenAPI document so new endpoints are not forgotten.
+var Routes = []Route{
+	{http.MethodGet, "/health"},
+	{http.MethodPost, "/auth/register"},
+	{http.MethodPost, "/auth/login"},
+	{http.MethodGet, "/types"},
+	{http.MethodPost, "/types"},
+	{http.MethodGet, "/types/{id}"},
+	{http.MethodPut, "/types/{id}"},
+	{http.MethodPatch, "/types/{id}"},
+	{http.MethodDelete, "/types/{id}"},
+	{http.MethodPost, "/types/{id}/merge-into/{targetId}"},
+	{http.MethodGet, "/types/{id}/translations"},
+	{http.MethodPut, "/types/{id}/translations/{lang}"},
+	{http.MethodDelete, "/types/{id}/translations/{lang}"},
+	{http.MethodPut, "/types/{id}/submission-cadence"},
+	{http.MethodDelete, "/types/{id}/submission-cadence"},
+	{http.MethodGet, "/types/{id}/attachments"},
+	{http.MethodPost, "/types/{id}/attachments"},
+	{http.MethodGet, "/types/{id}/attachments/{attachmentId}"},
+	{http.MethodDelete, "/types/{id}/attachments/{attachmentId}"},
+	{http.MethodGet, "/users/profile"},
+	{http.MethodPut, "/users/{id}/roles"},
+	{http.MethodGet, "/users/{id}/operator-grants"},
+	{http.MethodPost, "/users/{id}/operator-grants"},
+	{http.MethodDelete, "/users/{id}/operator-grants/{operatorId}"},
+	{http.MethodDelete, "/users/{id}/2fa"},
+	{http.MethodGet, "/users/me/notification-preferences"},
+	{http.MethodPut, "/users/me/notification-preferences/{alertType}"},
+	{http.MethodDelete, "/users/me/notification-preferences/{alertType}"},
+	{http.MethodGet, "/users/me/notifications"},
+	{http.MethodGet, "/users/me/notifications/unread"},
+	{http.MethodPost, "/users/me/notifications/read"},
+	{http.MethodPost, "/users/me/notifications/{id}/read"},
+	{http.MethodGet, "/users/me/2fa"},
+	{http.MethodPost, "/users/me/2fa"},
+	{http.MethodPost, "/users/me/2fa/confirm"},
+	{http.MethodPost, "/users/me/2fa/backup-codes"},
+	{http.MethodPost, "/users/me/2fa/disable"},
+	{http.MethodGet, "/generators"},
+	{http.MethodGet, "/generators.geojson"},
+	{http.MethodGet, "/map/generators"},
+	{http.MethodPost, "/generators"},
+	{http.MethodGet, "/generators/{id}"},
+	{http.MethodGet, "/generators/{id}/capacity-factor"},
+	{http.MethodPut, "/generators/{id}"},
+	{http.MethodPatch, "/generators/{id}"},
+	{http.MethodDelete, "/generators/{id}"},
+	{http.MethodPut, "/generators/{id}/submission-cadence"},
+	{http.MethodDelete, "/generators/{id}/submission-cadence"},
+	{http.MethodGet, "/generators/{id}/attachments"},
+	{http.MethodPost, "/generators/{id}/attachments"},
+	{http.MethodGet, "/generators/{id}/attachments/{attachmentId}"},
+	{http.MethodDelete, "/generators/{id}/attachments/{attachmentId}"},
+	{http.MethodGet, "/operators"},
+	{http.MethodPost, "/operators"},
+	{http.MethodGet, "/operators/{id}"},
+	{http.MethodPut, "/operators/{id}"},
+	{http.MethodDelete, "/operators/{id}"},
+	{http.MethodGet, "/plants"},
+	{http.MethodPost, "/plants"},
+	{http.MethodGet, "/plants/{id}"},
+	{http.MethodPut, "/plants/{id}"},
+	{http.MethodDelete, "/plants/{id}"},
+	{http.MethodGet, "/regions"},
+	{http.MethodPost, "/regions"},
+	{http.MethodGet, "/regions/{id}"},
+	{http.MethodPut, "/regions/{id}"},
+	{http.MethodDelete, "/regions/{id}"},
+	{http.MethodGet, "/productions"},
+	{http.MethodPost, "/productions"},
+	{http.MethodPost, "/productions/bulk"},
+	{http.MethodPatch, "/productions/bulk"},
+	{http.MethodGet, "/productions/facets"},
+	{http.MethodPost, "/productions/read-snapshots"},
+	{http.MethodDelete, "/productions/read-snapshots/:id"},
+	{http.MethodGet, "/productions/export"},
+	{http.MethodGet, "/productions/{id}"},
+	{http.MethodPut, "/productions/{id}"},
+	{http.MethodPatch, "/productions/{id}"},
+	{http.MethodDelete, "/productions/{id}"},
+	{http.MethodGet, "/productions/{id}/corrections"},
+	{http.MethodPost, "/productions/{id}/corrections"},
+	{http.MethodGet, "/productions/{id}/annotations"},
+	{http.MethodGet, "/ws/productions"},
+	{http.MethodGet, "/stream/productions"},
+	{http.MethodGet, "/annotations"},
+	{http.MethodPost, "/annotations"},
+	{http.MethodGet, "/annotations/{id}"},
+	{http.MethodPut, "/annotations/{id}"},
+	{http.MethodDelete, "/annotations/{id}"},
+	{http.MethodGet, "/custom-fields"},
+	{http.MethodPost, "/custom-fields"},
+	{http.MethodGet, "/custom-fields/{id}"},
+	{http.MethodPut, "/custom-fields/{id}"},
+	{http.MethodDelete, "/custom-fields/{id}"},
+	{http.MethodGet, "/snapshots"},
+	{http.MethodPost, "/snapshots"},
+	{http.MethodGet, "/snapshots/{month}"},
+	{http.MethodGet, "/snapshots/{month}/verify"},
+	{http.MethodGet, "/dataset"},
+	{http.MethodPost, "/imports/productions"},
+	{http.MethodPost, "/imports/uploads"},
+	{http.MethodGet, "/imports/uploads/{id}"},
+	{http.MethodPut, "/imports/uploads/{id}/chunks/{index}"},
+	{http.MethodPost, "/imports/uploads/{id}/complete"},
+	{http.MethodDelete, "/imports/uploads/{id}"},
+	{http.MethodPost, "/imports/presigned"},
+	{http.MethodPost, "/imports/presigned/{jobId}/confirm"},
+	{http.MethodGet, "/imports/profiles"},
+	{http.MethodPost, "/imports/profiles"},
+	{http.MethodGet, "/imports/profiles/{id}"},
+	{http.MethodPut, "/imports/profiles/{id}"},
+	{http.MethodDelete, "/imports/profiles/{id}"},
+	{http.MethodGet, "/imports/dead-letters"},
+	{http.MethodPost, "/imports/dead-letters/resubmit"},
+	{http.MethodGet, "/imports/dead-letters/{id}"},
+	{http.MethodPut, "/imports/dead-letters/{id}"},
+	{http.MethodPost, "/imports/dead-letters/{id}/resubmit"},
+	{http.MethodDelete, "/imports/dead-letters/{id}"},
+	{http.MethodGet, "/imports/drop-folder"},
+	{http.MethodGet, "/imports/drop-folder/{id}"},
+	{http.MethodGet, "/imports/sftp/sources"},
+	{http.MethodGet, "/imports/sftp/files"},
+	{http.MethodGet, "/imports/email/mailboxes"},
+	{http.MethodGet, "/imports/email/attachments"},
+	{http.MethodGet, "/analytics/total-production"},
+	{http.MethodGet, "/analytics/market-share"},
+	{http.MethodGet, "/analytics/generator-efficiency"},
+	{http.MethodGet, "/analytics/renewable-summary"},
+	{http.MethodGet, "/analytics/crosstab"},
+	{http.MethodGet, "/analytics/timeseries"},
+	{http.MethodGet, "/analytics/daily-summary"},
+	{http.MethodGet, "/analytics/mix"},
+	{http.MethodGet, "/analytics/mix/compare"},
+	{http.MethodGet, "/analytics/mix-by-region"},
+	{http.MethodGet, "/analytics/regions.geojson"},
+	{http.MethodGet, "/analytics/freshness"},
+	{http.MethodPost, "/analytics/query"},
+	{http.MethodGet, "/submission-calendar"},
+	{http.MethodGet, "/reports"},
+	{http.MethodPost, "/reports"},
+	{http.MethodGet, "/reports/{id}"},
+	{http.MethodPut, "/reports/{id}"},
+	{http.MethodDelete, "/reports/{id}"},
+	{http.MethodPost, "/reports/{id}/run"},
+	{http.MethodGet, "/reports/{id}/runs"},
+	{http.MethodGet, "/reports/{id}/download"},
+	{http.MethodGet, "/reports/{id}/deliveries"},
+	{http.MethodPost, "/reports/{id}/deliveries/{deliveryId}/redeliver"},
+	{http.MethodPost, "/reports/{id}/webhook-secret"},
+	{http.MethodGet, "/reports/templates"},
+	{http.MethodPost, "/reports/templates"},
+	{http.MethodGet, "/reports/templates/{templateId}"},
+	{http.MethodPut, "/reports/templates/{templateId}"},
+	{http.MethodDelete, "/reports/templates/{templateId}"},
+	{http.MethodGet, "/catalog/technologies"},
+	{http.MethodGet, "/metadata/fields"},
+	{http.MethodGet, "/jobs"},
+	{http.MethodGet, "/jobs/{id}"},
+	{http.MethodGet, "/trash"},
+	{http.MethodGet, "/trash/{id}"},
+	{http.MethodPost, "/trash/{id}/restore"},
+	{http.MethodDelete, "/trash/{id}"},
+	{http.MethodGet, "/admin/schema"},
+	{http.MethodGet, "/admin/data-quality"},
+	{http.MethodGet, "/admin/events"},
+	{http.MethodGet, "/admin/projections"},
+	{http.MethodPost, "/admin/projections/{name}/rebuild"},
+	{http.MethodGet, "/admin/telemetry"},
+	{http.MethodGet, "/admin/recalculations"},
+	{http.MethodPost, "/admin/recalculations"},
+	{http.MethodPost, "/admin/recalculations/preview"},
+	{http.MethodGet, "/admin/email-templates"},
+	{http.MethodGet, "/admin/email-templates/{key}"},
+	{http.MethodPut, "/admin/email-templates/{key}"},
+	{http.MethodPost, "/admin/email-templates/{key}/preview"},
+	{http.MethodGet, "/admin/email-templates/{key}/versions"},
+	{http.MethodGet, "/admin/email-templates/{key}/versions/{version}"},
+	{http.MethodPost, "/admin/email-templates/{key}/versions/{version}/restore"},
+	{http.MethodGet, "/admin/impersonations"},
+	{http.MethodPost, "/admin/impersonations"},
+	{http.MethodGet, "/admin/impersonations/{id}"},
+	{http.MethodPost, "/admin/impersonations/{id}/end"},
+	{http.MethodGet, "/admin/impersonations/{id}/events"},
+	{http.MethodGet, "/admin/login-lockouts"},
+	{http.MethodPost, "/admin/login-lockouts/{username}/unlock"},
+	{http.MethodGet, "/admin/holidays"},
+	{http.MethodPost, "/admin/holidays"},
+	{http.MethodDelete, "/admin/holidays/{id}"},
+	{http.MethodGet, "/admin/calendars/{code}"},
+	{http.MethodGet, "/audit"},
+	{http.MethodGet, "/audit/{id}"},
+	{http.MethodPost, "/telemetry/{topic}"},
+}
+
+// GeneratorFilter narrows generator listings; nil fields are not applied
+type GeneratorFilter struct {
+	TypeID     *uuid.UUID
+	OperatorID *uuid.UUID
+	PlantID    *uuid.UUID
+	RegionID   *uuid.UUID
+	// Near keeps the generators within RadiusKm (server default 50) of the point
+	Near     *geo.Point
+	RadiusKm float64
+	Within   *geo.BBox
+	// Attributes keeps the generators whose custom attributes equal these
+	Attributes models.CustomAttributes
+}
+
+func (f *GeneratorFilter) query() url.Values {
+	q := url.Values{}
+	if f == nil {
+		return q
+	}
+	if f.TypeID != nil {
+		q.Set("typeId", f.TypeID.String())
+	}
+	if f.OperatorID != nil {
+		q.Set("operatorId", f.OperatorID.String())
+	}
+	if f.PlantID != nil {
+		q.Set("plantId", f.PlantID.String())
+	}
+	if f.RegionID != nil {
+		q.Set("regionId", f.RegionID.String())
+	}
+	if f.Near != nil {
+		q.Set("near", formatFloats(f.Near.Lat, f.Near.Lng))
+		if f.RadiusKm > 0 {
+			q.Set("radiusKm", formatFloats(f.RadiusKm))
+		}
+	}
+	if b := f.Within; b != nil {
+		q.Set("bbox", formatFloats(b.MinLng, b.MinLat, b.MaxLng, b.MaxLat))
+	}
+	setAttributes(q, f.Attributes)
+	return q
+}
+
+// setAttributes adds the attr.<name>=value filters of custom attributes to q
+func setAttributes(q url.Values, attrs models.CustomAttributes) {
+	for name, v := range attrs {
+		switch v := v.(type) {
+		case float64:
+			q.Set("attr."+name, formatFloats(v))
+		default:
+			q.Set("attr."+name, fmt.Sprint(v))
+		}
+	}
+}
+
+func formatFloats(v ...float64) string {
+	parts := make([]string, len(v))
+	for i, f := range v {
+		parts[i] = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return strings.Join(parts, ",")
+}
+
+// DateRange limits analytics to inclusive YYYY-MM-DD bounds; empty bounds are open
+type DateRange struct {
+	StartDate string
+	EndDate   string
+	// AsOf restates the data to a past moment; the zero value means now
+	AsOf time.Time
+}
+
+func (d DateRange) query() url.Values {
+	q := url.Values{}
+	if d.StartDate != "" {
+		q.Set("startDate", d.StartDate)
+	}
+	if d.EndDate != "" {
+		q.Set("endDate", d.EndDate)
+	}
+	if !d.AsOf.IsZero() {
+		q.Set("asOf", d.AsOf.Format(time.RFC3339Nano))
+	}
+	return q
+}
+
+func get(path string, query url.Values) *request {
+	return &request{method: http.MethodGet, path: path, query: query}
+}
+
+func send(method, path string, body any) *request {
+	return &request{method: method, path: path, body: body}
+}
+
+// ifMatch makes req apply only to the version of the record, failing with
+// 412 when it changed since it was read
+func ifMatch(req *request, version *int) *request {
+	if version != nil {
+		req.header = http.Header{"If-Match": {`"` + strconv.Itoa(*version) + `"`}}
+	}
+	return req
+}
+
+// deleteRequest deletes path, with the rows that depend on it when cascade is set
+func deleteRequest(path string, cascade bool) *request {
+	req := send(http.MethodDelete, path, nil)
+	if cascade {
+		req.query = url.Values{"cascade": {"true"}}
+	}
+	return req
+}
+
+// ===================== Health =====================
+
+// Health calls GET /health (outside the versioned prefix)
+func (c *Client) Health(ctx context.Context) (*models.SuccessResponse, error) {
+	var out models.SuccessResponse
+	u := *c.baseURL
+	u.Path = "/health"
+	_, err := c.do(ctx, &request{method: http.MethodGet, url: u.String()}, &out)
+	return &out, err
+}
+
+// ===================== Types =====================
+
+// Types iterates energy generator types, optionally filtered by renewable status
+func (c *Client) Types(ctx context.Context, renewable *bool) iter.Seq2[*models.Type, error] {
+	q := url.Values{}
+	if renewable != nil {
+		q.Set("renewable", strconv.FormatBool(*renewable))
+	}
+	return paginate[models.Type](ctx, c, get("/types", q))
+}
+
+// ListTypes returns all energy generator types
+func (c *Client) ListTypes(ctx context.Context, renewable *bool) ([]*models.Type, error) {
+	return collect(c.Types(ctx, renewable))
+}
+
+func (c *Client) GetType(ctx context.Context, id uuid.UUID) (*models.Type, error) {
+	var out models.Type
+	_, err := c.do(ctx, get("/types/"+id.String(), nil), &out)
+	return &out, err
+}
+
+func (c *Client) CreateType(ctx context.Context, req *models.CreateTypeRequest) (*models.Type, error) {
+	var out models.Type
+	_, err := c.do(ctx, send(http.MethodPost, "/types", req), &out)
+	return &out, err
+}
+
+func (c *Client) UpdateType(ctx context.Context, id uuid.UUID, req *models.UpdateTypeRequest) (*models.Type, error) {
+	var out models.Type
+	_, err := c.do(ctx, ifMatch(send(http.MethodPut, "/types/"+id.String(), req), req.IfMatch), &out)
+	return &out, err
+}
+
+// PatchType updates only the members of patch, keyed by their JSON names; a
+// nil value clears an optional field. With ifMatchVersion it applies only to
+// that version of the type.
+func (c *Client) PatchType(ctx context.Context, id uuid.UUID, patch map[string]any, ifMatchVersion *int) (*models.Type, error) {
+	var out models.Type
+	_, err := c.do(ctx, ifMatch(send(http.MethodPatch, "/types/"+id.String(), patch), ifMatchVersion), &out)
+	return &out, err
+}
+
+// DeleteType moves a type to the trash; with cascade its generators and
+// their production records go with it, otherwise they make it fail with 409
+func (c *Client) DeleteType(ctx context.Context, id uuid.UUID, cascade bool) error {
+	_, err := c.do(ctx, deleteRequest("/types/"+id.String(), cascade), nil)
+	return err
+}
+
+// MergeType merges the duplicate type id into targetID
+func (c *Client) MergeType(ctx context.Context, id, targetID uuid.UUID) (*models.TypeMergeResult, error) {
+	var out models.TypeMergeResult
+	_, err := c.do(ctx, send(http.MethodPost, "/types/"+id.String()+"/merge-into/"+targetID.String(), nil), &out)
+	return &out, err
+}
+
+// GetTypeTranslations lists the translations of a type by language
+func (c *Client) GetTypeTranslations(ctx context.Context, id uuid.UUID) ([]*models.TypeTranslation, error) {
+	var out []*models.TypeTranslation
+	_, err := c.do(ctx, get("/types/"+id.String()+"/translations", nil), &out)
+	return out, err
+}
+
+// SetTypeTranslation sets the name and description of a type in lang (es or en)
+func (c *Client) SetTypeTranslation(ctx context.Context, id uuid.UUID, lang string, req *models.TypeTranslationRequest) (*models.TypeTranslation, error) {
+	var out models.TypeTranslation
+	_, err := c.do(ctx, send(http.MethodPut, "/types/"+id.String()+"/translations/"+lang, req), &out)
+	return &out, err
+}
+
+func (c *Client) DeleteTypeTranslation(ctx context.Context, id uuid.UUID, lang string) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/types/"+id.String()+"/translations/"+lang, nil), nil)
+	return err
+}
+
+// SetTypeCadence sets the expected submission cadence of the generators of a type
+func (c *Client) SetTypeCadence(ctx context.Context, id uuid.UUID, cadence string) error {
+	_, err := c.do(ctx, send(http.MethodPut, "/types/"+id.String()+"/submission-cadence", &models.SubmissionCadenceRequest{Cadence: cadence}), nil)
+	return err
+}
+
+func (c *Client) DeleteTypeCadence(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/types/"+id.String()+"/submission-cadence", nil), nil)
+	return err
+}
+
+// TypeAttachments lists the documents attached to a type, newest first
+func (c *Client) TypeAttachments(ctx context.Context, id uuid.UUID) ([]*models.Attachment, error) {
+	var out []*models.Attachment
+	_, err := c.do(ctx, get("/types/"+id.String()+"/attachments", nil), &out)
+	return out, err
+}
+
+// UploadTypeAttachment attaches a document to a type; its media type is
+// sniffed by the server
+func (c *Client) UploadTypeAttachment(ctx context.Context, id uuid.UUID, fileName string, content []byte) (*models.Attachment, error) {
+	return c.uploadAttachment(ctx, "/types/"+id.String()+"/attachments", fileName, content)
+}
+
+func (c *Client) DownloadTypeAttachment(ctx context.Context, id, attachmentID uuid.UUID) ([]byte, error) {
+	var out []byte
+	_, err := c.do(ctx, get("/types/"+id.String()+"/attachments/"+attachmentID.String(), nil), &out)
+	return out, err
+}
+
+func (c *Client) DeleteTypeAttachment(ctx context.Context, id, attachmentID uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/types/"+id.String()+"/attachments/"+attachmentID.String(), nil), nil)
+	return err
+}
+
+// uploadAttachment posts content as the multipart "file" field
+func (c *Client) uploadAttachment(ctx context.Context, path, fileName string, content []byte) (*models.Attachment, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	part, err := w.CreateFormFile("file", fileName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(content); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	var out models.Attachment
+	req := &request{method: http.MethodPost, path: path, body: buf.Bytes(), contentType: w.FormDataContentType()}
+	_, err = c.do(ctx, req, &out)
+	return &out, err
+}
+
+// ===================== Auth =====================
+
+// Register creates an account and returns an access token for it. Set
+// Config.Token to the token for the writes of the account.
+func (c *Client) Register(ctx context.Context, req *models.RegisterRequest) (*models.TokenResponse, error) {
+	var out models.TokenResponse
+	_, err := c.do(ctx, send(http.MethodPost, "/auth/register", req), &out)
+	return &out, err
+}
+
+// Login exchanges a username and password for an access token
+func (c *Client) Login(ctx context.Context, username, password string) (*models.TokenResponse, error) {
+	return c.LoginWithCode(ctx, username, password, "")
+}
+
+// LoginWithCode logs in to an account with two-factor authentication, with a
+// code of its authenticator app or a backup code
+func (c *Client) LoginWithCode(ctx context.Context, username, password, code string) (*models.TokenResponse, error) {
+	var out models.TokenResponse
+	req := &models.LoginRequest{Username: username, Password: password, Code: code}
+	_, err := c.do(ctx, send(http.MethodPost, "/auth/login", req), &out)
+	return &out, err
+}
+
+// ===================== Users =====================
+
+func (c *Client) GetUserProfile(ctx context.Context) (*models.User, error) {
+	var out models.User
+	_, err := c.do(ctx, get("/users/profile", nil), &out)
+	return &out, err
+}
+
+func (c *Client) SetUserRoles(ctx context.Context, userID uuid.UUID, roles ...string) (*models.User, error) {
+	var out models.User
+	_, err := c.do(ctx, send(http.MethodPut, "/users/"+userID.String()+"/roles", &models.SetUserRolesRequest{Roles: roles}), &out)
+	return &out, err
+}
+
+func (c *Client) GetOperatorGrants(ctx context.Context, userID uuid.UUID) ([]*models.OperatorGrant, error) {
+	return collect(paginate[models.OperatorGrant](ctx, c, get("/users/"+userID.String()+"/operator-grants", nil)))
+}
+
+func (c *Client) GrantOperator(ctx context.Context, userID, operatorID uuid.UUID) error {
+	req := &models.GrantOperatorRequest{OperatorID: operatorID}
+	_, err := c.do(ctx, send(http.MethodPost, "/users/"+userID.String()+"/operator-grants", req), nil)
+	return err
+}
+
+func (c *Client) RevokeOperator(ctx context.Context, userID, operatorID uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/users/"+userID.String()+"/operator-grants/"+operatorID.String(), nil), nil)
+	return err
+}
+
+// ResetTwoFactor removes the two-factor enrollment of a user
+func (c *Client) ResetTwoFactor(ctx context.Context, userID uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/users/"+userID.String()+"/2fa", nil), nil)
+	return err
+}
+
+// GetTwoFactor returns the two-factor status of the current user
+func (c *Client) GetTwoFactor(ctx context.Context) (*models.TwoFactorStatus, error) {
+	var out models.TwoFactorStatus
+	_, err := c.do(ctx, get("/users/me/2fa", nil), &out)
+	return &out, err
+}
+
+// EnrollTwoFactor starts a two-factor enrollment of the current user; show
+// ProvisioningURI as a QR code and confirm with ConfirmTwoFactor
+func (c *Client) EnrollTwoFactor(ctx context.Context) (*models.TwoFactorEnrollment, error) {
+	var out models.TwoFactorEnrollment
+	_, err := c.do(ctx, send(http.MethodPost, "/users/me/2fa", nil), &out)
+	return &out, err
+}
+
+// ConfirmTwoFactor enables two-factor authentication of the current user
+// with a first code. Set Config.Token to the returned token, which carries
+// the second factor, and keep the backup codes.
+func (c *Client) ConfirmTwoFactor(ctx context.Context, code string) (*models.TwoFactorEnabled, error) {
+	var out models.TwoFactorEnabled
+	_, err := c.do(ctx, send(http.MethodPost, "/users/me/2fa/confirm", &models.TwoFactorCodeRequest{Code: code}), &out)
+	return &out, err
+}
+
+// RegenerateBackupCodes replaces the backup codes of the current user
+func (c *Client) RegenerateBackupCodes(ctx context.Context, code string) (*models.BackupCodes, error) {
+	var out models.BackupCodes
+	_, err := c.do(ctx, send(http.MethodPost, "/users/me/2fa/backup-codes", &models.TwoFactorCodeRequest{Code: code}), &out)
+	return &out, err
+}
+
+// DisableTwoFactor turns off two-factor authentication of the current user,
+// given a code or a backup code
+func (c *Client) DisableTwoFactor(ctx context.Context, code string) error {
+	_, err := c.do(ctx, send(http.MethodPost, "/users/me/2fa/disable", &models.TwoFactorCodeRequest{Code: code}), nil)
+	return err
+}
+
+// GetNotificationPreferences returns how the current user is told about every alert type
+func (c *Client) GetNotificationPreferences(ctx context.Context) ([]*models.NotificationPreference, error) {
+	var out []*models.NotificationPreference
+	_, err := c.do(ctx, get("/users/me/notification-preferences", nil), &out)
+	return out, err
+}
+
+// SetNotificationPreference chooses the delivery of an alert type for the current user
+func (c *Client) SetNotificationPreference(ctx context.Context, alertType string, req *models.NotificationPreferenceRequest) (*models.NotificationPreference, error) {
+	var out models.NotificationPreference
+	_, err := c.do(ctx, send(http.MethodPut, "/users/me/notification-preferences/"+url.PathEscape(alertType), req), &out)
+	return &out, err
+}
+
+// ResetNotificationPreference drops the choice of the current user for an
+// alert type, which falls back to the default
+func (c *Client) ResetNotificationPreference(ctx context.Context, alertType string) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/users/me/notification-preferences/"+url.PathEscape(alertType), nil), nil)
+	return err
+}
+
+// Notifications iterates the inbox of the current user, newest first, only
+// the unread notifications when unread is set
+func (c *Client) Notifications(ctx context.Context, unread bool) iter.Seq2[*models.Notification, error] {
+	var q url.Values
+	if unread {
+		q = url.Values{"unread": {"true"}}
+	}
+	return paginate[models.Notification](ctx, c, get("/users/me/notifications", q))
+}
+
+// ListNotifications returns the inbox of the current user, newest first
+func (c *Client) ListNotifications(ctx context.Context, unread bool) ([]*models.Notification, error) {
+	return collect(c.Notifications(ctx, unread))
+}
+
+// GetUnreadNotifications counts the unread notifications of the current user
+func (c *Client) GetUnreadNotifications(ctx context.Context) (*models.UnreadNotifications, error) {
+	var out models.UnreadNotifications
+	_, err := c.do(ctx, get("/users/me/notifications/unread", nil), &out)
+	return &out, err
+}
+
+// MarkNotificationRead marks a notification of the current user read
+func (c *Client) MarkNotificationRead(ctx context.Context, id uuid.UUID) (*models.Notification, error) {
+	var out models.Notification
+	_, err := c.do(ctx, send(http.MethodPost, "/users/me/notifications/"+id.String()+"/read", nil), &out)
+	return &out, err
+}
+
+// MarkNotificationsRead marks the unread notifications of the current user
+// read, of one alert type when alertType is not empty
+func (c *Client) MarkNotificationsRead(ctx context.Context, alertType string) (*models.MarkNotificationsReadResult, error) {
+	req := send(http.MethodPost, "/users/me/notifications/read", nil)
+	if alertType != "" {
+		req.query = url.Values{"alertType": {alertType}}
+	}
+	var out models.MarkNotificationsReadResult
+	_, err := c.do(ctx, req, &out)
+	return &out, err
+}
+
+// ===================== Generators =====================
+
+// Generators iterates generators matching filter (nil for all)
+func (c *Client) Generators(ctx context.Context, filter *GeneratorFilter) iter.Seq2[*models.Generator, error] {
+	return paginate[models.Generator](ctx, c, get("/generators", filter.query()))
+}
+
+// ListGenerators returns all generators matching filter (nil for all)
+func (c *Client) ListGenerators(ctx context.Context, filter *GeneratorFilter) ([]*models.Generator, error) {
+	return collect(c.Generators(ctx, filter))
+}
+
+// GeneratorsGeoJSON returns the generators with coordinates matching filter
+// (nil for all) as a GeoJSON feature collection
+func (c *Client) GeneratorsGeoJSON(ctx context.Context, filter *GeneratorFilter) (*geo.FeatureCollection, error) {
+	var out geo.FeatureCollection
+	_, err := c.do(ctx, get("/generators.geojson", filter.query()), &out)
+	return &out, err
+}
+
+// MapGenerators returns the generators inside bbox clustered for the map at
+// zoom; filter is optional and only its type, operator, plant and region are applied
+func (c *Client) MapGenerators(ctx context.Context, bbox geo.BBox, zoom int, filter *GeneratorFilter) (*geo.FeatureCollection, error) {
+	q := url.Values{}
+	if filter != nil {
+		q = (&GeneratorFilter{TypeID: filter.TypeID, OperatorID: filter.OperatorID, PlantID: filter.PlantID, RegionID: filter.RegionID}).query()
+	}
+	q.Set("bbox", formatFloats(bbox.MinLng, bbox.MinLat, bbox.MaxLng, bbox.MaxLat))
+	q.Set("zoom", strconv.Itoa(zoom))
+	var out geo.FeatureCollection
+	_, err := c.do(ctx, get("/map/generators", q), &out)
+	return &out, err
+}
+
+func (c *Client) GetGenerator(ctx context.Context, id uuid.UUID) (*models.Generator, error) {
+	var out models.Generator
+	_, err := c.do(ctx, get("/generators/"+id.String(), nil), &out)
+	return &out, err
+}
+
+// CapacityFactorOptions selects the periods and smoothing of a capacity
+// factor trend; empty fields fall back to the server defaults (day, none, 7)
+type CapacityFactorOptions struct {
+	// GroupBy is day, week, month or year
+	GroupBy string
+	// Smoothing is none, sma or ema
+	Smoothing string
+	Window    int
+	StartDate string
+	EndDate   string
+}
+
+// GetCapacityFactor returns the capacity factor of a generator per period
+func (c *Client) GetCapacityFactor(ctx context.Context, id uuid.UUID, opts CapacityFactorOptions) (*models.CapacityFactorTrend, error) {
+	q := DateRange{StartDate: opts.StartDate, EndDate: opts.EndDate}.query()
+	if opts.GroupBy != "" {
+		q.Set("groupBy", opts.GroupBy)
+	}
+	if opts.Smoothing != "" {
+		q.Set("smoothing", opts.Smoothing)
+	}
+	if opts.Window > 0 {
+		q.Set("window", strconv.Itoa(opts.Window))
+	}
+	var out models.CapacityFactorTrend
+	_, err := c.do(ctx, get("/generators/"+id.String()+"/capacity-factor", q), &out)
+	return &out, err
+}
+
+func (c *Client) CreateGenerator(ctx context.Context, req *models.CreateGeneratorRequest) (*models.Generator, error) {
+	var out models.Generator
+	_, err := c.do(ctx, send(http.MethodPost, "/generators", req), &out)
+	return &out, err
+}
+
+func (c *Client) UpdateGenerator(ctx context.Context, id uuid.UUID, req *models.UpdateGeneratorRequest) (*models.Generator, error) {
+	var out models.Generator
+	_, err := c.do(ctx, ifMatch(send(http.MethodPut, "/generators/"+id.String(), req), req.IfMatch), &out)
+	return &out, err
+}
+
+// PatchGenerator updates only the members of patch, keyed by their JSON
+// names; a nil value clears an optional field. With ifMatchVersion it applies
+// only to that version of the generator.
+func (c *Client) PatchGenerator(ctx context.Context, id uuid.UUID, patch map[string]any, ifMatchVersion *int) (*models.Generator, error) {
+	var out models.Generator
+	_, err := c.do(ctx, ifMatch(send(http.MethodPatch, "/generators/"+id.String(), patch), ifMatchVersion), &out)
+	return &out, err
+}
+
+// DeleteGenerator moves a generator to the trash; with cascade its
+// production records go with it, otherwise they make it fail with 409
+func (c *Client) DeleteGenerator(ctx context.Context, id uuid.UUID, cascade bool) error {
+	_, err := c.do(ctx, deleteRequest("/generators/"+id.String(), cascade), nil)
+	return err
+}
+
+// SetGeneratorCadence sets the expected submission cadence of a generator, overriding its type
+func (c *Client) SetGeneratorCadence(ctx context.Context, id uuid.UUID, cadence string) error {
+	_, err := c.do(ctx, send(http.MethodPut, "/generators/"+id.String()+"/submission-cadence", &models.SubmissionCadenceRequest{Cadence: cadence}), nil)
+	return err
+}
+
+func (c *Client) DeleteGeneratorCadence(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/generators/"+id.String()+"/submission-cadence", nil), nil)
+	return err
+}
+
+// SubmissionCalendar returns the cadences set per type and per generator
+func (c *Client) SubmissionCalendar(ctx context.Context) ([]*models.SubmissionCadence, error) {
+	return collect(paginate[models.SubmissionCadence](ctx, c, get("/submission-calendar", nil)))
+}
+
+// GeneratorAttachments lists the documents attached to a generator, newest first
+func (c *Client) GeneratorAttachments(ctx context.Context, id uuid.UUID) ([]*models.Attachment, error) {
+	var out []*models.Attachment
+	_, err := c.do(ctx, get("/generators/"+id.String()+"/attachments", nil), &out)
+	return out, err
+}
+
+// UploadGeneratorAttachment attaches a document (e.g. a permit) to a
+// generator; its media type is sniffed by the server
+func (c *Client) UploadGeneratorAttachment(ctx context.Context, id uuid.UUID, fileName string, content []byte) (*models.Attachment, error) {
+	return c.uploadAttachment(ctx, "/generators/"+id.String()+"/attachments", fileName, content)
+}
+
+func (c *Client) DownloadGeneratorAttachment(ctx context.Context, id, attachmentID uuid.UUID) ([]byte, error) {
+	var out []byte
+	_, err := c.do(ctx, get("/generators/"+id.String()+"/attachments/"+attachmentID.String(), nil), &out)
+	return out, err
+}
+
+func (c *Client) DeleteGeneratorAttachment(ctx context.Context, id, attachmentID uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/generators/"+id.String()+"/attachments/"+attachmentID.String(), nil), nil)
+	return err
+}
+
+// ===================== Operators =====================
+
+// Operators iterates operators
+func (c *Client) Operators(ctx context.Context) iter.Seq2[*models.Operator, error] {
+	return paginate[models.Operator](ctx, c, get("/operators", nil))
+}
+
+// ListOperators returns all operators
+func (c *Client) ListOperators(ctx context.Context) ([]*models.Operator, error) {
+	return collect(c.Operators(ctx))
+}
+
+func (c *Client) GetOperator(ctx context.Context, id uuid.UUID) (*models.Operator, error) {
+	var out models.Operator
+	_, err := c.do(ctx, get("/operators/"+id.String(), nil), &out)
+	return &out, err
+}
+
+func (c *Client) CreateOperator(ctx context.Context, req *models.CreateOperatorRequest) (*models.Operator, error) {
+	var out models.Operator
+	_, err := c.do(ctx, send(http.MethodPost, "/operators", req), &out)
+	return &out, err
+}
+
+func (c *Client) UpdateOperator(ctx context.Context, id uuid.UUID, req *models.UpdateOperatorRequest) (*models.Operator, error) {
+	var out models.Operator
+	_, err := c.do(ctx, send(http.MethodPut, "/operators/"+id.String(), req), &out)
+	return &out, err
+}
+
+func (c *Client) DeleteOperator(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/operators/"+id.String(), nil), nil)
+	return err
+}
+
+// ===================== Plants =====================
+
+// Plants iterates the plants of an owner, or all plants when ownerID is nil
+func (c *Client) Plants(ctx context.Context, ownerID *uuid.UUID) iter.Seq2[*models.Plant, error] {
+	q := url.Values{}
+	if ownerID != nil {
+		q.Set("ownerId", ownerID.String())
+	}
+	return paginate[models.Plant](ctx, c, get("/plants", q))
+}
+
+// ListPlants returns the plants of an owner, or all plants when ownerID is nil
+func (c *Client) ListPlants(ctx context.Context, ownerID *uuid.UUID) ([]*models.Plant, error) {
+	return collect(c.Plants(ctx, ownerID))
+}
+
+func (c *Client) GetPlant(ctx context.Context, id uuid.UUID) (*models.Plant, error) {
+	var out models.Plant
+	_, err := c.do(ctx, get("/plants/"+id.String(), nil), &out)
+	return &out, err
+}
+
+func (c *Client) CreatePlant(ctx context.Context, req *models.CreatePlantRequest) (*models.Plant, error) {
+	var out models.Plant
+	_, err := c.do(ctx, send(http.MethodPost, "/plants", req), &out)
+	return &out, err
+}
+
+func (c *Client) UpdatePlant(ctx context.Context, id uuid.UUID, req *models.UpdatePlantRequest) (*models.Plant, error) {
+	var out models.Plant
+	_, err := c.do(ctx, send(http.MethodPut, "/plants/"+id.String(), req), &out)
+	return &out, err
+}
+
+func (c *Client) DeletePlant(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/plants/"+id.String(), nil), nil)
+	return err
+}
+
+// ===================== Regions =====================
+
+// Regions iterates the regions by name
+func (c *Client) Regions(ctx context.Context) iter.Seq2[*models.Region, error] {
+	return paginate[models.Region](ctx, c, get("/regions", nil))
+}
+
+// ListRegions returns the regions by name
+func (c *Client) ListRegions(ctx context.Context) ([]*models.Region, error) {
+	return collect(c.Regions(ctx))
+}
+
+func (c *Client) GetRegion(ctx context.Context, id uuid.UUID) (*models.Region, error) {
+	var out models.Region
+	_, err := c.do(ctx, get("/regions/"+id.String(), nil), &out)
+	return &out, err
+}
+
+func (c *Client) CreateRegion(ctx context.Context, req *models.CreateRegionRequest) (*models.Region, error) {
+	var out models.Region
+	_, err := c.do(ctx, send(http.MethodPost, "/regions", req), &out)
+	return &out, err
+}
+
+func (c *Client) UpdateRegion(ctx context.Context, id uuid.UUID, req *models.UpdateRegionRequest) (*models.Region, error) {
+	var out models.Region
+	_, err := c.do(ctx, send(http.MethodPut, "/regions/"+id.String(), req), &out)
+	return &out, err
+}
+
+func (c *Client) DeleteRegion(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/regions/"+id.String(), nil), nil)
+	return err
+}
+
+// ===================== Productions =====================
+
+// Productions iterates production records matching filter (nil for all),
+// following the next links of truncated pages; Limit sets the page size
+func (c *Client) Productions(ctx context.Context, filter *models.ProductionFilter) iter.Seq2[*models.Production, error] {
+	return paginate[models.Production](ctx, c, get("/productions", productionQuery(filter)))
+}
+
+func productionQuery(filter *models.ProductionFilter) url.Values {
+	q := url.Values{}
+	if filter != nil {
+		if filter.GeneratorID != nil {
+			q.Set("generatorId", filter.GeneratorID.String())
+		}
+		if filter.PlantID != nil {
+			q.Set("plantId", filter.PlantID.String())
+		}
+		if filter.StartDate != nil {
+			q.Set("startDate", *filter.StartDate)
+		}
+		if filter.EndDate != nil {
+			q.Set("endDate", *filter.EndDate)
+		}
+		if filter.Source != nil {
+			q.Set("source", *filter.Source)
+		}
+		setAttributes(q, filter.Attributes)
+		if len(filter.Compute) > 0 {
+			q.Set("compute", strings.Join(filter.Compute, ","))
+		}
+		if filter.Limit > 0 {
+			q.Set("limit", strconv.Itoa(filter.Limit))
+		}
+		if filter.Offset > 0 {
+			q.Set("offset", strconv.Itoa(filter.Offset))
+		}
+	}
+	return q
+}
+
+// ListProductions returns all production records matching filter (nil for all)
+func (c *Client) ListProductions(ctx context.Context, filter *models.ProductionFilter) ([]*models.Production, error) {
+	return collect(c.Productions(ctx, filter))
+}
+
+// GetProductionFacets returns the generators, types, sources and date bounds of the matching productions
+func (c *Client) GetProductionFacets(ctx context.Context, filter *models.ProductionFilter) (*models.ProductionFacets, error) {
+	var out models.ProductionFacets
+	_, err := c.do(ctx, get("/productions/facets", productionQuery(filter)), &out)
+	return &out, err
+}
+
+// ExportProductions returns the productions matching filter (nil for all) as
+// a file; format is csv (the default when empty) or xlsx
+func (c *Client) ExportProductions(ctx context.Context, filter *models.ProductionFilter, format string) ([]byte, error) {
+	q := productionQuery(filter)
+	if format != "" {
+		q.Set("format", format)
+	}
+	var out []byte
+	_, err := c.do(ctx, get("/productions/export", q), &out)
+	return out, err
+}
+
+// OpenReadSnapshot pins the data read by ProductionsIn and ExportProductionsIn
+// to now, until CloseReadSnapshot or its expiry
+func (c *Client) OpenReadSnapshot(ctx context.Context) (*models.ReadSnapshot, error) {
+	var out models.ReadSnapshot
+	_, err := c.do(ctx, send(http.MethodPost, "/productions/read-snapshots", nil), &out)
+	return &out, err
+}
+
+func (c *Client) CloseReadSnapshot(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/productions/read-snapshots/"+id.String(), nil), nil)
+	return err
+}
+
+// ProductionsIn iterates like Productions, reading every page from the read
+// snapshot id
+func (c *Client) ProductionsIn(ctx context.Context, id uuid.UUID, filter *models.ProductionFilter) iter.Seq2[*models.Production, error] {
+	q := productionQuery(filter)
+	q.Set("readSnapshot", id.String())
+	return paginate[models.Production](ctx, c, get("/productions", q))
+}
+
+// ExportProductionsIn exports like ExportProductions from the read snapshot id
+func (c *Client) ExportProductionsIn(ctx context.Context, id uuid.UUID, filter *models.ProductionFilter, format string) ([]byte, error) {
+	q := productionQuery(filter)
+	q.Set("readSnapshot", id.String())
+	if format != "" {
+		q.Set("format", format)
+	}
+	var out []byte
+	_, err := c.do(ctx, get("/productions/export", q), &out)
+	return out, err
+}
+
+// GetProduction returns a production record with the computed fields
+// (models.Compute*) in compute, if any
+func (c *Client) GetProduction(ctx context.Context, id uuid.UUID, compute ...string) (*models.Production, error) {
+	var q url.Values
+	if len(compute) > 0 {
+		q = url.Values{"compute": {strings.Join(compute, ",")}}
+	}
+	var out models.Production
+	_, err := c.do(ctx, get("/productions/"+id.String(), q), &out)
+	return &out, err
+}
+
+func (c *Client) CreateProduction(ctx context.Context, req *models.CreateProductionRequest) (*models.Production, error) {
+	var out models.Production
+	_, err := c.do(ctx, send(http.MethodPost, "/productions", req), &out)
+	return &out, err
+}
+
+// UpsertProduction creates the production record, or updates the one its
+// generator already has on the date
+func (c *Client) UpsertProduction(ctx context.Context, req *models.CreateProductionRequest) (*models.Production, error) {
+	var out models.Production
+	r := send(http.MethodPost, "/productions", req)
+	r.query = url.Values{"upsert": {"true"}}
+	_, err := c.do(ctx, r, &out)
+	return &out, err
+}
+
+// CreateProductions creates many production records at once. Records that
+// fail do not return an error: check Failed and the items of the result.
+func (c *Client) CreateProductions(ctx context.Context, reqs []*models.CreateProductionRequest) (*models.BulkProductionResult, error) {
+	var out models.BulkProductionResult
+	_, err := c.do(ctx, send(http.MethodPost, "/productions/bulk", reqs), &out)
+	return &out, err
+}
+
+// UpdateProductions applies many production updates at once; with atomic
+// either all of them are applied or none. Records that fail do not return an
+// error: check Failed and the items of the result.
+func (c *Client) UpdateProductions(ctx context.Context, updates []*models.BulkProductionUpdate, atomic bool) (*models.BulkProductionUpdateResult, error) {
+	var out models.BulkProductionUpdateResult
+	req := send(http.MethodPatch, "/productions/bulk", updates)
+	if atomic {
+		req.query = url.Values{"atomic": {"true"}}
+	}
+	_, err := c.do(ctx, req, &out)
+	return &out, err
+}
+
+func (c *Client) UpdateProduction(ctx context.Context, id uuid.UUID, req *models.UpdateProductionRequest) (*models.Production, error) {
+	var out models.Production
+	_, err := c.do(ctx, ifMatch(send(http.MethodPut, "/productions/"+id.String(), req), req.IfMatch), &out)
+	return &out, err
+}
+
+// PatchProduction updates only the members of patch, keyed by their JSON
+// names; a nil value clears an optional field. With ifMatchVersion it applies
+// only to that version of the record.
+func (c *Client) PatchProduction(ctx context.Context, id uuid.UUID, patch map[string]any, ifMatchVersion *int) (*models.Production, error) {
+	var out models.Production
+	_, err := c.do(ctx, ifMatch(send(http.MethodPatch, "/productions/"+id.String(), patch), ifMatchVersion), &out)
+	return &out, err
+}
+
+func (c *Client) DeleteProduction(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/productions/"+id.String(), nil), nil)
+	return err
+}
+
+func (c *Client) GetCorrections(ctx context.Context, productionID uuid.UUID) ([]*models.ProductionCorrection, error) {
+	return collect(paginate[models.ProductionCorrection](ctx, c, get("/productions/"+productionID.String()+"/corrections", nil)))
+}
+
+// CreateCorrection corrects a production record, including records of published months
+func (c *Client) CreateCorrection(ctx context.Context, productionID uuid.UUID, req *models.CreateCorrectionRequest) (*models.ProductionCorrection, error) {
+	var out models.ProductionCorrection
+	_, err := c.do(ctx, send(http.MethodPost, "/productions/"+productionID.String()+"/corrections", req), &out)
+	return &out, err
+}
+
+// GetProductionAnnotations lists the annotations that apply to a production
+// record: its own and the ranges covering its date
+func (c *Client) GetProductionAnnotations(ctx context.Context, productionID uuid.UUID) ([]*models.Annotation, error) {
+	var out []*models.Annotation
+	_, err := c.do(ctx, get("/productions/"+productionID.String()+"/annotations", nil), &out)
+	return out, err
+}
+
+// ===================== Annotations =====================
+
+// ListAnnotations returns the annotations that apply to the production data
+// selected by filter (nil for all), in date order
+func (c *Client) ListAnnotations(ctx context.Context, filter *models.AnnotationFilter) ([]*models.Annotation, error) {
+	q := url.Values{}
+	if filter != nil {
+		if filter.ProductionID != nil {
+			q.Set("productionId", filter.ProductionID.String())
+		}
+		if filter.GeneratorID != nil {
+			q.Set("generatorId", filter.GeneratorID.String())
+		}
+		if filter.StartDate != nil {
+			q.Set("startDate", *filter.StartDate)
+		}
+		if filter.EndDate != nil {
+			q.Set("endDate", *filter.EndDate)
+		}
+	}
+	var out []*models.Annotation
+	_, err := c.do(ctx, get("/annotations", q), &out)
+	return out, err
+}
+
+// CreateAnnotation adds a note on a production record or a date range
+func (c *Client) CreateAnnotation(ctx context.Context, req *models.CreateAnnotationRequest) (*models.Annotation, error) {
+	var out models.Annotation
+	_, err := c.do(ctx, send(http.MethodPost, "/annotations", req), &out)
+	return &out, err
+}
+
+func (c *Client) GetAnnotation(ctx context.Context, id uuid.UUID) (*models.Annotation, error) {
+	var out models.Annotation
+	_, err := c.do(ctx, get("/annotations/"+id.String(), nil), &out)
+	return &out, err
+}
+
+func (c *Client) UpdateAnnotation(ctx context.Context, id uuid.UUID, req *models.UpdateAnnotationRequest) (*models.Annotation, error) {
+	var out models.Annotation
+	_, err := c.do(ctx, send(http.MethodPut, "/annotations/"+id.String(), req), &out)
+	return &out, err
+}
+
+func (c *Client) DeleteAnnotation(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/annotations/"+id.String(), nil), nil)
+	return err
+}
+
+// ===================== Custom fields =====================
+
+// ListCustomFields returns the custom fields of an entity (models.CustomEntity*),
+// or of every entity when entity is empty
+func (c *Client) ListCustomFields(ctx context.Context, entity string) ([]*models.CustomField, error) {
+	q := url.Values{}
+	if entity != "" {
+		q.Set("entity", entity)
+	}
+	var out []*models.CustomField
+	_, err := c.do(ctx, get("/custom-fields", q), &out)
+	return out, err
+}
+
+// CreateCustomField registers a custom field; records set it in their CustomAttributes
+func (c *Client) CreateCustomField(ctx context.Context, req *models.CreateCustomFieldRequest) (*models.CustomField, error) {
+	var out models.CustomField
+	_, err := c.do(ctx, send(http.MethodPost, "/custom-fields", req), &out)
+	return &out, err
+}
+
+func (c *Client) GetCustomField(ctx context.Context, id uuid.UUID) (*models.CustomField, error) {
+	var out models.CustomField
+	_, err := c.do(ctx, get("/custom-fields/"+id.String(), nil), &out)
+	return &out, err
+}
+
+func (c *Client) UpdateCustomField(ctx context.Context, id uuid.UUID, req *models.UpdateCustomFieldRequest) (*models.CustomField, error) {
+	var out models.CustomField
+	_, err := c.do(ctx, send(http.MethodPut, "/custom-fields/"+id.String(), req), &out)
+	return &out, err
+}
+
+// DeleteCustomField removes a custom field and its values from every record
+func (c *Client) DeleteCustomField(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/custom-fields/"+id.String(), nil), nil)
+	return err
+}
+
+// ===================== Snapshots =====================
+
+// Snapshots iterates published snapshots, newest month first
+func (c *Client) Snapshots(ctx context.Context) iter.Seq2[*models.Snapshot, error] {
+	return paginate[models.Snapshot](ctx, c, get("/snapshots", nil))
+}
+
+// ListSnapshots returns all published snapshots
+func (c *Client) ListSnapshots(ctx context.Context) ([]*models.Snapshot, error) {
+	return collect(c.Snapshots(ctx))
+}
+
+// PublishSnapshot publishes a month (YYYY-MM)
+func (c *Client) PublishSnapshot(ctx context.Context, month string) (*models.Snapshot, error) {
+	var out models.Snapshot
+	_, err := c.do(ctx, send(http.MethodPost, "/snapshots", &models.PublishSnapshotRequest{Month: month}), &out)
+	return &out, err
+}
+
+// GetSnapshot returns the snapshot of a month (YYYY-MM) with its frozen records
+func (c *Client) GetSnapshot(ctx context.Context, month string) (*models.SnapshotDetail, error) {
+	var out models.SnapshotDetail
+	_, err := c.do(ctx, get("/snapshots/"+url.PathEscape(month), nil), &out)
+	return &out, err
+}
+
+// VerifySnapshot recomputes the hash of the snapshot of a month (YYYY-MM)
+func (c *Client) VerifySnapshot(ctx context.Context, month string) (*models.SnapshotVerification, error) {
+	var out models.SnapshotVerification
+	_, err := c.do(ctx, get("/snapshots/"+url.PathEscape(month)+"/verify", nil), &out)
+	return &out, err
+}
+
+// GetDataset returns the reference data with the productions between
+// startDate and endDate (empty for no bound); scrub strips identifying
+// details by the policy of the server
+func (c *Client) GetDataset(ctx context.Context, startDate, endDate string, scrub bool) (*models.Dataset, error) {
+	q := url.Values{}
+	if startDate != "" {
+		q.Set("startDate", startDate)
+	}
+	if endDate != "" {
+		q.Set("endDate", endDate)
+	}
+	if scrub {
+		q.Set("scrub", "true")
+	}
+	var out models.Dataset
+	_, err := c.do(ctx, get("/dataset", q), &out)
+	return &out, err
+}
+
+// ===================== Imports =====================
+
+// ImportProductions streams a CSV file (generatorId,date,productionMw, or the
+// columns of the import profile when profileID is set) and returns the
+// queued import job; poll it with GetJob. The request is never retried.
+func (c *Client) ImportProductions(ctx context.Context, csv io.Reader, profileID *uuid.UUID) (*jobs.Job, error) {
+	var out jobs.Job
+	req := &request{method: http.MethodPost, path: "/imports/productions", query: profileQuery(profileID), body: csv, contentType: "text/csv"}
+	_, err := c.do(ctx, req, &out)
+	return &out, err
+}
+
+// InitUpload starts a resumable chunked upload
+func (c *Client) InitUpload(ctx context.Context, req *models.InitUploadRequest) (*imports.Upload, error) {
+	var out imports.Upload
+	_, err := c.do(ctx, send(http.MethodPost, "/imports/uploads", req), &out)
+	return &out, err
+}
+
+func (c *Client) GetUpload(ctx context.Context, id uuid.UUID) (*imports.Upload, error) {
+	var out imports.Upload
+	_, err := c.do(ctx, get("/imports/uploads/"+id.String(), nil), &out)
+	return &out, err
+}
+
+// PutUploadChunk uploads one chunk; its checksum is computed here
+func (c *Client) PutUploadChunk(ctx context.Context, id uuid.UUID, index int, chunk []byte) (*imports.Upload, error) {
+	var out imports.Upload
+	sum := sha256.Sum256(chunk)
+	req := &request{
+		method:      http.MethodPut,
+		path:        "/imports/uploads/" + id.String() + "/chunks/" + strconv.Itoa(index),
+		body:        chunk,
+		contentType: "application/octet-stream",
+		header:      http.Header{"X-Chunk-Checksum": {hex.EncodeToString(sum[:])}},
+	}
+	_, err := c.do(ctx, req, &out)
+	return &out, err
+}
+
+// CompleteUpload assembles the chunks and starts the import job, with the
+// columns of the import profile when profileID is set
+func (c *Client) CompleteUpload(ctx context.Context, id uuid.UUID, profileID *uuid.UUID) (*jobs.Job, error) {
+	var out jobs.Job
+	req := send(http.MethodPost, "/imports/uploads/"+id.String()+"/complete", nil)
+	req.query = profileQuery(profileID)
+	_, err := c.do(ctx, req, &out)
+	return &out, err
+}
+
+func (c *Client) DeleteUpload(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/imports/uploads/"+id.String(), nil), nil)
+	return err
+}
+
+// CreatePresignedUpload registers an import job and returns the URL to PUT the file to
+func (c *Client) CreatePresignedUpload(ctx context.Context, req *models.PresignedUploadRequest) (*models.PresignedUpload, error) {
+	var out models.PresignedUpload
+	_, err := c.do(ctx, send(http.MethodPost, "/imports/presigned", req), &out)
+	return &out, err
+}
+
+// ConfirmPresignedUpload starts the import once the file is in storage, with
+// the columns of the import profile when profileID is set
+func (c *Client) ConfirmPresignedUpload(ctx context.Context, jobID uuid.UUID, profileID *uuid.UUID) (*jobs.Job, error) {
+	var out jobs.Job
+	req := send(http.MethodPost, "/imports/presigned/"+jobID.String()+"/confirm", nil)
+	req.query = profileQuery(profileID)
+	_, err := c.do(ctx, req, &out)
+	return &out, err
+}
+
+// profileQuery names the import profile of an import, nil for none
+func profileQuery(profileID *uuid.UUID) url.Values {
+	if profileID == nil {
+		return nil
+	}
+	return url.Values{"profileId": {profileID.String()}}
+}
+
+// ListImportProfiles returns the import profiles of a source, or of every
+// source when source is empty
+func (c *Client) ListImportProfiles(ctx context.Context, source string) ([]*models.ImportProfile, error) {
+	q := url.Values{}
+	if source != "" {
+		q.Set("source", source)
+	}
+	var out []*models.ImportProfile
+	_, err := c.do(ctx, get("/imports/profiles", q), &out)
+	return out, err
+}
+
+// CreateImportProfile saves the column mapping of a source
+func (c *Client) CreateImportProfile(ctx context.Context, req *models.CreateImportProfileRequest) (*models.ImportProfile, error) {
+	var out models.ImportProfile
+	_, err := c.do(ctx, send(http.MethodPost, "/imports/profiles", req), &out)
+	return &out, err
+}
+
+func (c *Client) GetImportProfile(ctx context.Context, id uuid.UUID) (*models.ImportProfile, error) {
+	var out models.ImportProfile
+	_, err := c.do(ctx, get("/imports/profiles/"+id.String(), nil), &out)
+	return &out, err
+}
+
+func (c *Client) UpdateImportProfile(ctx context.Context, id uuid.UUID, req *models.UpdateImportProfileRequest) (*models.ImportProfile, error) {
+	var out models.ImportProfile
+	_, err := c.do(ctx, send(http.MethodPut, "/imports/profiles/"+id.String(), req), &out)
+	return &out, err
+}
+
+func (c *Client) DeleteImportProfile(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/imports/profiles/"+id.String(), nil), nil)
+	return err
+}
+
+// ListDeadLetters returns the rows rejected by imports, newest first,
+// selected by filter (nil for the first page of all)
+func (c *Client) ListDeadLetters(ctx context.Context, filter *models.DeadLetterFilter) ([]*models.DeadLetter, error) {
+	q := url.Values{}
+	if filter != nil {
+		if filter.JobID != nil {
+			q.Set("jobId", filter.JobID.String())
+		}
+		if filter.Status != nil {
+			q.Set("status", *filter.Status)
+		}
+		if filter.Limit > 0 {
+			q.Set("limit", strconv.Itoa(filter.Limit))
+		}
+		if filter.Offset > 0 {
+			q.Set("offset", strconv.Itoa(filter.Offset))
+		}
+	}
+	var out []*models.DeadLetter
+	_, err := c.do(ctx, get("/imports/dead-letters", q), &out)
+	return out, err
+}
+
+func (c *Client) GetDeadLetter(ctx context.Context, id uuid.UUID) (*models.DeadLetter, error) {
+	var out models.DeadLetter
+	_, err := c.do(ctx, get("/imports/dead-letters/"+id.String(), nil), &out)
+	return &out, err
+}
+
+// UpdateDeadLetter corrects the values of a pending dead letter
+func (c *Client) UpdateDeadLetter(ctx context.Context, id uuid.UUID, req *models.UpdateDeadLetterRequest) (*models.DeadLetter, error) {
+	var out models.DeadLetter
+	_, err := c.do(ctx, send(http.MethodPut, "/imports/dead-letters/"+id.String(), req), &out)
+	return &out, err
+}
+
+// ResubmitDeadLetter imports a pending dead letter again; a row that is still
+// rejected comes back as a 422 *APIError
+func (c *Client) ResubmitDeadLetter(ctx context.Context, id uuid.UUID) (*models.DeadLetter, error) {
+	var out models.DeadLetter
+	_, err := c.do(ctx, send(http.MethodPost, "/imports/dead-letters/"+id.String()+"/resubmit", nil), &out)
+	return &out, err
+}
+
+// ResubmitDeadLetters imports the pending dead letters again, those of one
+// job when jobID is set
+func (c *Client) ResubmitDeadLetters(ctx context.Context, jobID *uuid.UUID) (*models.DeadLetterResubmission, error) {
+	req := send(http.MethodPost, "/imports/dead-letters/resubmit", nil)
+	if jobID != nil {
+		req.query = url.Values{"jobId": {jobID.String()}}
+	}
+	var out models.DeadLetterResubmission
+	_, err := c.do(ctx, req, &out)
+	return &out, err
+}
+
+func (c *Client) DeleteDeadLetter(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/imports/dead-letters/"+id.String(), nil), nil)
+	return err
+}
+
+// GetDropFiles lists the drop folder processing log, most recent first;
+// status and limit are optional
+func (c *Client) GetDropFiles(ctx context.Context, status string, limit int) ([]*imports.DropFile, error) {
+	q := url.Values{}
+	if status != "" {
+		q.Set("status", status)
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var out []*imports.DropFile
+	_, err := c.do(ctx, get("/imports/drop-folder", q), &out)
+	return out, err
+}
+
+func (c *Client) GetDropFile(ctx context.Context, id uuid.UUID) (*imports.DropFile, error) {
+	var out imports.DropFile
+	_, err := c.do(ctx, get("/imports/drop-folder/"+id.String(), nil), &out)
+	return &out, err
+}
+
+// GetSFTPSources returns the SFTP partner feeds with the outcome of their last pull
+func (c *Client) GetSFTPSources(ctx context.Context) ([]*models.ConnectorSourceStatus, error) {
+	var out []*models.ConnectorSourceStatus
+	_, err := c.do(ctx, get("/imports/sftp/sources", nil), &out)
+	return out, err
+}
+
+// GetSFTPFiles lists the files pulled from SFTP partner feeds, newest
+// first; source and limit are optional
+func (c *Client) GetSFTPFiles(ctx context.Context, source string, limit int) ([]*models.ConnectorFile, error) {
+	q := url.Values{}
+	if source != "" {
+		q.Set("source", source)
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var out []*models.ConnectorFile
+	_, err := c.do(ctx, get("/imports/sftp/files", q), &out)
+	return out, err
+}
+
+// GetMailboxes returns the polled IMAP mailboxes with the outcome of their last poll
+func (c *Client) GetMailboxes(ctx context.Context) ([]*models.MailboxStatus, error) {
+	var out []*models.MailboxStatus
+	_, err := c.do(ctx, get("/imports/email/mailboxes", nil), &out)
+	return out, err
+}
+
+// GetEmailAttachments lists the attachments staged from polled mailboxes,
+// newest first; mailbox and limit are optional
+func (c *Client) GetEmailAttachments(ctx context.Context, mailbox string, limit int) ([]*models.ConnectorFile, error) {
+	q := url.Values{}
+	if mailbox != "" {
+		q.Set("mailbox", mailbox)
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var out []*models.ConnectorFile
+	_, err := c.do(ctx, get("/imports/email/attachments", q), &out)
+	return out, err
+}
+
+// ===================== Analytics =====================
+
+func (c *Client) GetTotalProduction(ctx context.Context, r DateRange) ([]*models.TotalProductionByDate, error) {
+	return collect(paginate[models.TotalProductionByDate](ctx, c, get("/analytics/total-production", r.query())))
+}
+
+func (c *Client) GetMarketShare(ctx context.Context, r DateRange) ([]*models.OperatorMarketShare, error) {
+	return collect(paginate[models.OperatorMarketShare](ctx, c, get("/analytics/market-share", r.query())))
+}
+
+// GetGeneratorEfficiency returns the production and capacity factor of every
+// generator over the range; AsOf is not applied
+func (c *Client) GetGeneratorEfficiency(ctx context.Context, r DateRange) ([]*models.GeneratorEfficiency, error) {
+	q := r.query()
+	q.Del("asOf")
+	return collect(paginate[models.GeneratorEfficiency](ctx, c, get("/analytics/generator-efficiency", q)))
+}
+
+// GetRenewableSummary returns capacity and production of the renewable and
+// non-renewable generators over the range; AsOf is not applied
+func (c *Client) GetRenewableSummary(ctx context.Context, r DateRange) ([]*models.RenewableSummary, error) {
+	q := r.query()
+	q.Del("asOf")
+	return collect(paginate[models.RenewableSummary](ctx, c, get("/analytics/renewable-summary", q)))
+}
+
+// GetMix returns the energy mix of date (YYYY-MM-DD); an empty date is today
+func (c *Client) GetMix(ctx context.Context, date string) (*models.EnergyMix, error) {
+	q := url.Values{}
+	if date != "" {
+		q.Set("date", date)
+	}
+	var out models.EnergyMix
+	_, err := c.do(ctx, get("/analytics/mix", q), &out)
+	return &out, err
+}
+
+// CompareMix returns the energy mix of date (today when empty) next to that
+// of the previous business day of calendar (weekends only when empty)
+func (c *Client) CompareMix(ctx context.Context, date, calendar string) (*models.EnergyMixComparison, error) {
+	q := url.Values{}
+	if date != "" {
+		q.Set("date", date)
+	}
+	if calendar != "" {
+		q.Set("calendar", calendar)
+	}
+	var out models.EnergyMixComparison
+	_, err := c.do(ctx, get("/analytics/mix/compare", q), &out)
+	return &out, err
+}
+
+// GetMixByRegion returns the renewable and non-renewable production of every
+// region over the range; AsOf is not applied
+func (c *Client) GetMixByRegion(ctx context.Context, r DateRange) ([]*models.RegionMix, error) {
+	q := r.query()
+	q.Del("asOf")
+	var out []*models.RegionMix
+	_, err := c.do(ctx, get("/analytics/mix-by-region", q), &out)
+	return out, err
+}
+
+// RegionsGeoJSON returns the configured regions with their production over
+// the range as GeoJSON; AsOf is not applied
+func (c *Client) RegionsGeoJSON(ctx context.Context, r DateRange) (*geo.FeatureCollection, error) {
+	q := r.query()
+	q.Del("asOf")
+	var out geo.FeatureCollection
+	_, err := c.do(ctx, get("/analytics/regions.geojson", q), &out)
+	return &out, err
+}
+
+// GetDailySummary returns production per day and generator type from the event log projection
+func (c *Client) GetDailySummary(ctx context.Context, r DateRange) ([]*models.DailyProductionSummary, error) {
+	return collect(paginate[models.DailyProductionSummary](ctx, c, get("/analytics/daily-summary", r.query())))
+}
+
+// CrosstabOptions selects the cross-tab dimensions and value; empty fields
+// fall back to the server defaults (type × month, sum)
+type CrosstabOptions struct {
+	Rows  string
+	Cols  string
+	Value string
+	// Metric is an expression such as sum(productionMw)/sum(capacity); it replaces Value
+	Metric string
+	DateRange
+}
+
+// GetCrosstab aggregates production by two dimensions
+func (c *Client) GetCrosstab(ctx context.Context, opts CrosstabOptions) (*models.Crosstab, error) {
+	q := opts.query()
+	for k, v := range map[string]string{"rows": opts.Rows, "cols": opts.Cols, "value": opts.Value, "metric": opts.Metric} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	var out models.Crosstab
+	_, err := c.do(ctx, get("/analytics/crosstab", q), &out)
+	return &out, err
+}
+
+// TimeSeriesOptions selects the buckets and series of a time series; empty
+// fields fall back to the server defaults (day, total)
+type TimeSeriesOptions struct {
+	// GroupBy is day, week, month or year
+	GroupBy string
+	// Metric is total, renewable or byType
+	Metric string
+	DateRange
+}
+
+// GetTimeSeries returns production summed per bucket for charting
+func (c *Client) GetTimeSeries(ctx context.Context, opts TimeSeriesOptions) (*models.TimeSeries, error) {
+	q := opts.query()
+	if opts.GroupBy != "" {
+		q.Set("groupBy", opts.GroupBy)
+	}
+	if opts.Metric != "" {
+		q.Set("metric", opts.Metric)
+	}
+	var out models.TimeSeries
+	_, err := c.do(ctx, get("/analytics/timeseries", q), &out)
+	return &out, err
+}
+
+// FreshnessOptions tunes GetFreshness; a nil MaxLagDays uses the server threshold
+type FreshnessOptions struct {
+	MaxLagDays *int
+	StaleOnly  bool
+}
+
+// GetFreshness returns the latest production date overall and per generator with its lag against today
+func (c *Client) GetFreshness(ctx context.Context, opts FreshnessOptions) (*models.Freshness, error) {
+	q := url.Values{}
+	if opts.MaxLagDays != nil {
+		q.Set("maxLagDays", strconv.Itoa(*opts.MaxLagDays))
+	}
+	if opts.StaleOnly {
+		q.Set("staleOnly", "true")
+	}
+	var out models.Freshness
+	_, err := c.do(ctx, get("/analytics/freshness", q), &out)
+	return &out, err
+}
+
+// QueryAnalytics runs several analytics queries in one request; failed
+// queries report their status and error in their result
+func (c *Client) QueryAnalytics(ctx context.Context, req *models.AnalyticsBatchRequest) (*models.AnalyticsBatch, error) {
+	var out models.AnalyticsBatch
+	_, err := c.do(ctx, send(http.MethodPost, "/analytics/query", req), &out)
+	return &out, err
+}
+
+// ===================== Reports =====================
+
+// Reports iterates saved reports ordered by name
+func (c *Client) Reports(ctx context.Context) iter.Seq2[*models.Report, error] {
+	return paginate[models.Report](ctx, c, get("/reports", nil))
+}
+
+func (c *Client) GetReport(ctx context.Context, id uuid.UUID) (*models.Report, error) {
+	var out models.Report
+	_, err := c.do(ctx, get("/reports/"+id.String(), nil), &out)
+	return &out, err
+}
+
+func (c *Client) CreateReport(ctx context.Context, req *models.ReportRequest) (*models.Report, error) {
+	var out models.Report
+	_, err := c.do(ctx, send(http.MethodPost, "/reports", req), &out)
+	return &out, err
+}
+
+func (c *Client) UpdateReport(ctx context.Context, id uuid.UUID, req *models.ReportRequest) (*models.Report, error) {
+	var out models.Report
+	_, err := c.do(ctx, send(http.MethodPut, "/reports/"+id.String(), req), &out)
+	return &out, err
+}
+
+func (c *Client) DeleteReport(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/reports/"+id.String(), nil), nil)
+	return err
+}
+
+// RunReport renders and delivers a report now; a failed run comes back with status failed
+func (c *Client) RunReport(ctx context.Context, id uuid.UUID) (*models.ReportRun, error) {
+	var out models.ReportRun
+	_, err := c.do(ctx, send(http.MethodPost, "/reports/"+id.String()+"/run", nil), &out)
+	return &out, err
+}
+
+// ReportRuns returns the latest runs of a report, newest first
+func (c *Client) ReportRuns(ctx context.Context, id uuid.UUID) ([]*models.ReportRun, error) {
+	return collect(paginate[models.ReportRun](ctx, c, get("/reports/"+id.String()+"/runs", nil)))
+}
+
+// DownloadReport renders a report without delivering it and returns the
+// file; format overrides the report format when not empty (csv, pdf)
+func (c *Client) DownloadReport(ctx context.Context, id uuid.UUID, format string) ([]byte, error) {
+	q := url.Values{}
+	if format != "" {
+		q.Set("format", format)
+	}
+	var out []byte
+	_, err := c.do(ctx, get("/reports/"+id.String()+"/download", q), &out)
+	return out, err
+}
+
+// WebhookDeliveries returns the latest webhook requests of a report, newest first
+func (c *Client) WebhookDeliveries(ctx context.Context, id uuid.UUID) ([]*models.WebhookDelivery, error) {
+	return collect(paginate[models.WebhookDelivery](ctx, c, get("/reports/"+id.String()+"/deliveries", nil)))
+}
+
+// RedeliverWebhook posts the period of a past delivery to the webhook again
+func (c *Client) RedeliverWebhook(ctx context.Context, id, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	var out models.WebhookDelivery
+	_, err := c.do(ctx, send(http.MethodPost, "/reports/"+id.String()+"/deliveries/"+deliveryID.String()+"/redeliver", nil), &out)
+	return &out, err
+}
+
+// RotateWebhookSecret replaces the webhook signing secret of a report and returns the new one
+func (c *Client) RotateWebhookSecret(ctx context.Context, id uuid.UUID) (*models.WebhookSecret, error) {
+	var out models.WebhookSecret
+	_, err := c.do(ctx, send(http.MethodPost, "/reports/"+id.String()+"/webhook-secret", nil), &out)
+	return &out, err
+}
+
+// ReportTemplates iterates report templates ordered by name
+func (c *Client) ReportTemplates(ctx context.Context) iter.Seq2[*models.ReportTemplate, error] {
+	return paginate[models.ReportTemplate](ctx, c, get("/reports/templates", nil))
+}
+
+func (c *Client) GetReportTemplate(ctx context.Context, id uuid.UUID) (*models.ReportTemplate, error) {
+	var out models.ReportTemplate
+	_, err := c.do(ctx, get("/reports/templates/"+id.String(), nil), &out)
+	return &out, err
+}
+
+func (c *Client) CreateReportTemplate(ctx context.Context, req *models.ReportTemplateRequest) (*models.ReportTemplate, error) {
+	var out models.ReportTemplate
+	_, err := c.do(ctx, send(http.MethodPost, "/reports/templates", req), &out)
+	return &out, err
+}
+
+func (c *Client) UpdateReportTemplate(ctx context.Context, id uuid.UUID, req *models.ReportTemplateRequest) (*models.ReportTemplate, error) {
+	var out models.ReportTemplate
+	_, err := c.do(ctx, send(http.MethodPut, "/reports/templates/"+id.String(), req), &out)
+	return &out, err
+}
+
+func (c *Client) DeleteReportTemplate(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/reports/templates/"+id.String(), nil), nil)
+	return err
+}
+
+// ===================== Catalog & jobs =====================
+
+func (c *Client) GetTechnologies(ctx context.Context) ([]*catalog.Technology, error) {
+	return collect(paginate[catalog.Technology](ctx, c, get("/catalog/technologies", nil)))
+}
+
+// GetFieldMetadata returns the data dictionary; a non-empty resource limits it to that resource
+func (c *Client) GetFieldMetadata(ctx context.Context, resource string) ([]*metadata.Resource, error) {
+	q := url.Values{}
+	if resource != "" {
+		q.Set("resource", resource)
+	}
+	return collect(paginate[metadata.Resource](ctx, c, get("/metadata/fields", q)))
+}
+
+// Jobs iterates background jobs
+func (c *Client) Jobs(ctx context.Context) iter.Seq2[*jobs.Job, error] {
+	return paginate[jobs.Job](ctx, c, get("/jobs", nil))
+}
+
+func (c *Client) GetJob(ctx context.Context, id uuid.UUID) (*jobs.Job, error) {
+	var out jobs.Job
+	_, err := c.do(ctx, get("/jobs/"+id.String(), nil), &out)
+	return &out, err
+}
+
+// ===================== Trash =====================
+
+// GetTrash lists deleted resources; a non-empty resource (type, operator,
+// generator, production, report, report_template) limits it to that kind
+func (c *Client) GetTrash(ctx context.Context, resource string) ([]*models.TrashItem, error) {
+	q := url.Values{}
+	if resource != "" {
+		q.Set("resource", resource)
+	}
+	return collect(paginate[models.TrashItem](ctx, c, get("/trash", q)))
+}
+
+func (c *Client) GetTrashItem(ctx context.Context, id uuid.UUID) (*models.TrashItem, error) {
+	var out models.TrashItem
+	_, err := c.do(ctx, get("/trash/"+id.String(), nil), &out)
+	return &out, err
+}
+
+// RestoreTrashItem puts a deleted resource back with the records deleted with it
+func (c *Client) RestoreTrashItem(ctx context.Context, id uuid.UUID) (*models.TrashItem, error) {
+	var out models.TrashItem
+	_, err := c.do(ctx, send(http.MethodPost, "/trash/"+id.String()+"/restore", nil), &out)
+	return &out, err
+}
+
+// PurgeTrashItem deletes a trash item for good
+func (c *Client) PurgeTrashItem(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/trash/"+id.String(), nil), nil)
+	return err
+}
+
+// ===================== Admin =====================
+
+// GetSchema describes the tables, columns and foreign keys of the data model
+func (c *Client) GetSchema(ctx context.Context) (*models.DatabaseSchema, error) {
+	var out models.DatabaseSchema
+	_, err := c.do(ctx, get("/admin/schema", nil), &out)
+	return &out, err
+}
+
+// GetSchemaDiagram returns the ER diagram of the data model in format (mermaid, dot)
+func (c *Client) GetSchemaDiagram(ctx context.Context, format string) ([]byte, error) {
+	var out []byte
+	_, err := c.do(ctx, get("/admin/schema", url.Values{"format": {format}}), &out)
+	return out, err
+}
+
+// ScanDataQuality runs the data quality checks (all when none are given),
+// listing at most limit findings of each (the server default when 0)
+func (c *Client) ScanDataQuality(ctx context.Context, limit int, checks ...string) (*models.DataQualityReport, error) {
+	q := url.Values{}
+	if len(checks) > 0 {
+		q.Set("check", strings.Join(checks, ","))
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var out models.DataQualityReport
+	_, err := c.do(ctx, get("/admin/data-quality", q), &out)
+	return &out, err
+}
+
+// EventOptions narrows GetEvents; zero fields are not applied
+type EventOptions struct {
+	Aggregate   string
+	AggregateID *uuid.UUID
+	// AfterSeq returns the events after this sequence number, for paging through the log
+	AfterSeq int64
+	Limit    int
+}
+
+// GetEvents returns a page of the domain event log in log order
+func (c *Client) GetEvents(ctx context.Context, opts EventOptions) ([]*models.DomainEvent, error) {
+	q := url.Values{}
+	if opts.Aggregate != "" {
+		q.Set("aggregate", opts.Aggregate)
+	}
+	if opts.AggregateID != nil {
+		q.Set("aggregateId", opts.AggregateID.String())
+	}
+	if opts.AfterSeq > 0 {
+		q.Set("afterSeq", strconv.FormatInt(opts.AfterSeq, 10))
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	var out []*models.DomainEvent
+	_, err := c.do(ctx, get("/admin/events", q), &out)
+	return out, err
+}
+
+func (c *Client) GetProjections(ctx context.Context) ([]*models.Projection, error) {
+	return collect(paginate[models.Projection](ctx, c, get("/admin/projections", nil)))
+}
+
+// RebuildProjection recomputes a projection from the event log
+func (c *Client) RebuildProjection(ctx context.Context, name string) (*models.Projection, error) {
+	var out models.Projection
+	_, err := c.do(ctx, send(http.MethodPost, "/admin/projections/"+url.PathEscape(name)+"/rebuild", nil), &out)
+	return &out, err
+}
+
+// GetTelemetryStatus returns the state of the MQTT telemetry bridge
+func (c *Client) GetTelemetryStatus(ctx context.Context) (*models.TelemetryStatus, error) {
+	var out models.TelemetryStatus
+	_, err := c.do(ctx, get("/admin/telemetry", nil), &out)
+	return &out, err
+}
+
+// GetRecalculations returns the applied recalculations, newest first
+func (c *Client) GetRecalculations(ctx context.Context) ([]*models.Recalculation, error) {
+	var out []*models.Recalculation
+	_, err := c.do(ctx, get("/admin/recalculations", nil), &out)
+	return out, err
+}
+
+// PreviewRecalculation reports what a recalculation would change without changing anything
+func (c *Client) PreviewRecalculation(ctx context.Context, req *models.RecalculationRequest) (*models.Recalculation, error) {
+	var out models.Recalculation
+	_, err := c.do(ctx, send(http.MethodPost, "/admin/recalculations/preview", req), &out)
+	return &out, err
+}
+
+// ApplyRecalculation rescales the matching production records
+func (c *Client) ApplyRecalculation(ctx context.Context, req *models.RecalculationRequest) (*models.Recalculation, error) {
+	var out models.Recalculation
+	_, err := c.do(ctx, send(http.MethodPost, "/admin/recalculations", req), &out)
+	return &out, err
+}
+
+// ListEmailTemplates returns the current template of every notification email
+func (c *Client) ListEmailTemplates(ctx context.Context) ([]*models.EmailTemplate, error) {
+	var out []*models.EmailTemplate
+	_, err := c.do(ctx, get("/admin/email-templates", nil), &out)
+	return out, err
+}
+
+// GetEmailTemplate returns the current template of a notification email;
+// version 0 is the built-in one
+func (c *Client) GetEmailTemplate(ctx context.Context, key string) (*models.EmailTemplate, error) {
+	var out models.EmailTemplate
+	_, err := c.do(ctx, get("/admin/email-templates/"+url.PathEscape(key), nil), &out)
+	return &out, err
+}
+
+// UpdateEmailTemplate saves a new version of a notification email
+func (c *Client) UpdateEmailTemplate(ctx context.Context, key string, req *models.EmailTemplateRequest) (*models.EmailTemplate, error) {
+	var out models.EmailTemplate
+	_, err := c.do(ctx, send(http.MethodPut, "/admin/email-templates/"+url.PathEscape(key), req), &out)
+	return &out, err
+}
+
+// PreviewEmailTemplate renders a notification email without sending it
+func (c *Client) PreviewEmailTemplate(ctx context.Context, key string, req *models.EmailTemplatePreviewRequest) (*models.EmailPreview, error) {
+	var out models.EmailPreview
+	_, err := c.do(ctx, send(http.MethodPost, "/admin/email-templates/"+url.PathEscape(key)+"/preview", req), &out)
+	return &out, err
+}
+
+// GetEmailTemplateVersions returns the saved versions of a notification email, newest first
+func (c *Client) GetEmailTemplateVersions(ctx context.Context, key string) ([]*models.EmailTemplate, error) {
+	var out []*models.EmailTemplate
+	_, err := c.do(ctx, get("/admin/email-templates/"+url.PathEscape(key)+"/versions", nil), &out)
+	return out, err
+}
+
+func (c *Client) GetEmailTemplateVersion(ctx context.Context, key string, version int) (*models.EmailTemplate, error) {
+	var out models.EmailTemplate
+	_, err := c.do(ctx, get("/admin/email-templates/"+url.PathEscape(key)+"/versions/"+strconv.Itoa(version), nil), &out)
+	return &out, err
+}
+
+// RestoreEmailTemplateVersion saves an earlier version as the current one
+func (c *Client) RestoreEmailTemplateVersion(ctx context.Context, key string, version int) (*models.EmailTemplate, error) {
+	var out models.EmailTemplate
+	_, err := c.do(ctx, send(http.MethodPost, "/admin/email-templates/"+url.PathEscape(key)+"/versions/"+strconv.Itoa(version)+"/restore", nil), &out)
+	return &out, err
+}
+
+// StartImpersonation starts an impersonation session. Act as the impersonated
+// user with a client whose Token is the AccessToken of the response.
+func (c *Client) StartImpersonation(ctx context.Context, req *models.ImpersonationRequest) (*models.ImpersonationResponse, error) {
+	var out models.ImpersonationResponse
+	_, err := c.do(ctx, send(http.MethodPost, "/admin/impersonations", req), &out)
+	return &out, err
+}
+
+// Impersonations iterates impersonation sessions, newest first
+func (c *Client) Impersonations(ctx context.Context) iter.Seq2[*models.Impersonation, error] {
+	return paginate[models.Impersonation](ctx, c, get("/admin/impersonations", nil))
+}
+
+// ListImpersonations returns the impersonation sessions, newest first
+func (c *Client) ListImpersonations(ctx context.Context) ([]*models.Impersonation, error) {
+	return collect(c.Impersonations(ctx))
+}
+
+// GetImpersonation returns an impersonation session
+func (c *Client) GetImpersonation(ctx context.Context, id uuid.UUID) (*models.Impersonation, error) {
+	var out models.Impersonation
+	_, err := c.do(ctx, get("/admin/impersonations/"+id.String(), nil), &out)
+	return &out, err
+}
+
+// EndImpersonation ends an impersonation session, rejecting its token from then on
+func (c *Client) EndImpersonation(ctx context.Context, id uuid.UUID) (*models.Impersonation, error) {
+	var out models.Impersonation
+	_, err := c.do(ctx, send(http.MethodPost, "/admin/impersonations/"+id.String()+"/end", nil), &out)
+	return &out, err
+}
+
+// ListImpersonationEvents returns the requests made during an impersonation session
+func (c *Client) ListImpersonationEvents(ctx context.Context, id uuid.UUID) ([]*models.ImpersonationEvent, error) {
+	return collect(paginate[models.ImpersonationEvent](ctx, c, get("/admin/impersonations/"+id.String()+"/events", nil)))
+}
+
+// LoginLockouts iterates the usernames with failed logins, most recent failure first
+func (c *Client) LoginLockouts(ctx context.Context) iter.Seq2[*models.LoginLockout, error] {
+	return paginate[models.LoginLockout](ctx, c, get("/admin/login-lockouts", nil))
+}
+
+// ListLoginLockouts returns the usernames with failed logins, most recent failure first
+func (c *Client) ListLoginLockouts(ctx context.Context) ([]*models.LoginLockout, error) {
+	return collect(c.LoginLockouts(ctx))
+}
+
+// UnlockLogin lifts the lock of a username locked after too many failed logins
+func (c *Client) UnlockLogin(ctx context.Context, username string) (*models.LoginLockout, error) {
+	var out models.LoginLockout
+	_, err := c.do(ctx, send(http.MethodPost, "/admin/login-lockouts/"+url.PathEscape(username)+"/unlock", nil), &out)
+	return &out, err
+}
+
+// Holidays returns the holidays managed through the API of a country, all
+// countries when empty
+func (c *Client) Holidays(ctx context.Context, country string) ([]*models.Holiday, error) {
+	q := url.Values{}
+	if country != "" {
+		q.Set("country", country)
+	}
+	var out []*models.Holiday
+	_, err := c.do(ctx, get("/admin/holidays", q), &out)
+	return out, err
+}
+
+func (c *Client) CreateHoliday(ctx context.Context, req *models.HolidayRequest) (*models.Holiday, error) {
+	var out models.Holiday
+	_, err := c.do(ctx, send(http.MethodPost, "/admin/holidays", req), &out)
+	return &out, err
+}
+
+func (c *Client) DeleteHoliday(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, send(http.MethodDelete, "/admin/holidays/"+id.String(), nil), nil)
+	return err
+}
+
+// BusinessCalendar resolves the calendar of a country (CO) or region (CO-ANT)
+// over the range; AsOf is not applied and an empty range is the current month
+func (c *Client) BusinessCalendar(ctx context.Context, code string, r DateRange) (*models.BusinessCalendar, error) {
+	q := r.query()
+	q.Del("asOf")
+	var out models.BusinessCalendar
+	_, err := c.do(ctx, get("/admin/calendars/"+url.PathEscape(code), q), &out)
+	return &out, err
+}
+
+// ===================== Audit log =====================
+
+// AuditEntries iterates the audit log entries selected by filter (nil for
+// all), newest first; Limit and Offset of filter are not used
+func (c *Client) AuditEntries(ctx context.Context, filter *models.AuditFilter) iter.Seq2[*models.AuditEntry, error] {
+	q := url.Values{}
+	if filter != nil {
+		if filter.EntityType != nil {
+			q.Set("entityType", *filter.EntityType)
+		}
+		if filter.EntityID != nil {
+			q.Set("entityId", filter.EntityID.String())
+		}
+		if filter.UserID != nil {
+			q.Set("userId", filter.UserID.String())
+		}
+		if filter.Action != nil {
+			q.Set("action", *filter.Action)
+		}
+		if filter.StartDate != nil {
+			q.Set("startDate", *filter.StartDate)
+		}
+		if filter.EndDate != nil {
+			q.Set("endDate", *filter.EndDate)
+		}
+	}
+	return paginate[models.AuditEntry](ctx, c, get("/audit", q))
+}
+
+// ListAuditEntries returns the audit log entries selected by filter, newest first
+func (c *Client) ListAuditEntries(ctx context.Context, filter *models.AuditFilter) ([]*models.AuditEntry, error) {
+	return collect(c.AuditEntries(ctx, filter))
+}
+
+// GetAuditEntry returns an audit log entry
+func (c *Client) GetAuditEntry(ctx context.Context, id int64) (*models.AuditEntry, error) {
+	var out models.AuditEntry
+	_, err := c.do(ctx, get("/audit/"+strconv.FormatInt(id, 10), nil), &out)
+	return &out, err
+}
+
+// ===================== Telemetry =====================
+
+// PushTelemetry records a meter message as if it was published on topic
+func (c *Client) PushTelemetry(ctx context.Context, topic string, message json.RawMessage) (*models.Production, error) {
+	levels := strings.Split(strings.Trim(topic, "/"), "/")
+	for i, l := range levels {
+		levels[i] = url.PathEscape(l)
+	}
+	var out models.Production
+	_, err := c.do(ctx, send(http.MethodPost, "/telemetry/"+strings.Join(levels, "/"), message), &out)
+	return &out, err
+}
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/live"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+)
+
+// ProductionFeed follows the live feed of production records (GET
+// /ws/productions), of one generator or of the generators of one type when
+// generatorID or typeID is set. It yields every record created or updated
+// from then on, until the loop breaks, ctx is cancelled or the server closes
+// the feed. A feed closed because the client fell behind yields an error.
+func (c *Client) ProductionFeed(ctx context.Context, generatorID, typeID *uuid.UUID) iter.Seq2[*models.ProductionEvent, error] {
+	return func(yield func(*models.ProductionEvent, error) bool) {
+		q := url.Values{}
+		if generatorID != nil {
+			q.Set("generatorId", generatorID.String())
+		}
+		if typeID != nil {
+			q.Set("typeId", typeID.String())
+		}
+		conn, err := c.dial(ctx, "/ws/productions", q)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer conn.Close(live.CloseNormal, "")
+		stop := context.AfterFunc(ctx, func() { conn.Close(live.CloseGoingAway, "") })
+		defer stop()
+
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				var closeErr *live.CloseError
+				switch {
+				case ctx.Err() != nil:
+					yield(nil, ctx.Err())
+				case errors.As(err, &closeErr) && (closeErr.Code == live.CloseNormal || closeErr.Code == live.CloseGoingAway):
+				default:
+					yield(nil, err)
+				}
+				return
+			}
+			var event models.ProductionEvent
+			if err := json.Unmarshal(msg, &event); err != nil {
+				yield(nil, fmt.Errorf("failed to decode event: %w", err))
+				return
+			}
+			if !yield(&event, nil) {
+				return
+			}
+		}
+	}
+}
+
+// ErrReplayIncomplete is yielded by ProductionStream when the server no
+// longer has some of the events missed while disconnected; re-read the
+// records to catch up, then keep iterating
+var ErrReplayIncomplete = errors.New("live feed: missed events are no longer kept")
+
+// ProductionStream follows the live feed of production records as
+// Server-Sent Events (GET /stream/productions), for networks that do not
+// pass WebSockets; generatorID and typeID filter as with ProductionFeed.
+// With lastEventID it starts after that event. When the stream drops it
+// reconnects, up to MaxRetries times in a row, and resumes after the last
+// event yielded, so none is missed; events may then repeat.
+func (c *Client) ProductionStream(ctx context.Context, generatorID, typeID *uuid.UUID, lastEventID string) iter.Seq2[*models.ProductionEvent, error] {
+	return func(yield func(*models.ProductionEvent, error) bool) {
+		q := url.Values{}
+		if generatorID != nil {
+			q.Set("generatorId", generatorID.String())
+		}
+		if typeID != nil {
+			q.Set("typeId", typeID.String())
+		}
+		for attempt := 0; ; attempt++ {
+			received, err := c.stream(ctx, q, &lastEventID, yield)
+			if errors.Is(err, errStopped) {
+				return
+			}
+			var apiErr *APIError
+			if ctx.Err() != nil || (errors.As(err, &apiErr) && !retryableStatus(apiErr.StatusCode)) {
+				if ctx.Err() != nil {
+					err = ctx.Err()
+				}
+				yield(nil, err)
+				return
+			}
+			if received {
+				attempt = 0
+			}
+			if attempt >= c.cfg.MaxRetries {
+				if err == nil {
+					err = errors.New("live feed: stream closed by the server")
+				}
+				yield(nil, err)
+				return
+			}
+			if err := c.wait(ctx, attempt+1, err); err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+	}
+}
+
+// errStopped ends a stream when the loop over its events breaks
+var errStopped = errors.New("stopped")
+
+// stream reads one connection of ProductionStream, yielding its events and
+// keeping lastEventID up to date. It reports whether an event arrived.
+func (c *Client) stream(ctx context.Context, query url.Values, lastEventID *string, yield func(*models.ProductionEvent, error) bool) (bool, error) {
+	req := get("/stream/productions", query)
+	req.header = http.Header{}
+	if *lastEventID != "" {
+		req.header.Set("Last-Event-ID", *lastEventID)
+	}
+	httpReq, err := c.newHTTPRequest(ctx, req, nil)
+	if err != nil {
+		return false, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpClient := *c.http
+	httpClient.Timeout = 0
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, readAPIError(resp)
+	}
+	defer resp.Body.Close()
+
+	received := false
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	var id, kind, data string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "id":
+				id = value
+			case "event":
+				kind = value
+			case "data":
+				data += value + "\n"
+			}
+			continue
+		}
+		// A blank line ends the event
+		switch {
+		case kind == "reset":
+			if !yield(nil, ErrReplayIncomplete) {
+				return received, errStopped
+			}
+		case data != "":
+			var event models.ProductionEvent
+			if err := json.Unmarshal([]byte(strings.TrimSuffix(data, "\n")), &event); err != nil {
+				return received, fmt.Errorf("failed to decode event: %w", err)
+			}
+			received = true
+			if id != "" {
+				*lastEventID = id
+			}
+			if !yield(&event, nil) {
+				return received, errStopped
+			}
+		}
+		id, kind, data = "", "", ""
+	}
+	return received, scanner.Err()
+}
+
+// dial opens a WebSocket on path. The connection outlives Config.Timeout,
+// which only bounds the handshake.
+func (c *Client) dial(ctx context.Context, path string, query url.Values) (*live.Conn, error) {
+	req := get(path, query)
+	req.header = http.Header{}
+	key := live.SetHandshake(req.header)
+	httpReq, err := c.newHTTPRequest(ctx, req, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := *c.http
+	httpClient.Timeout = 0
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, readAPIError(resp)
+	}
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok || resp.Header.Get("Sec-WebSocket-Accept") != live.AcceptKey(key) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("invalid WebSocket handshake response")
+	}
+	return live.NewClientConn(rwc), nil
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"strings"
+)
+
+// paginate iterates a list endpoint page by page. Each page is a JSON array;
+// the next page is taken from the `Link: <url>; rel="next"` response header,
+// so endpoints that return everything at once yield a single page.
+func paginate[T any](ctx context.Context, c *Client, req *request) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		for {
+			var page []*T
+			resp, err := c.do(ctx, req, &page)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			next := nextLink(resp)
+			if next == "" || len(page) == 0 {
+				return
+			}
+			req = &request{method: req.method, url: next, header: req.header}
+		}
+	}
+}
+
+// collect drains an iterator into a slice
+func collect[T any](seq iter.Seq2[*T, error]) ([]*T, error) {
+	list := []*T{}
+	for item, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+// nextLink returns the rel="next" target of the Link header, if any
+func nextLink(resp *http.Response) string {
+	for _, header := range resp.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			for _, param := range parts[1:] {
+				if strings.ReplaceAll(strings.TrimSpace(param), `"`, "") == "rel=next" {
+					return target
+				}
+			}
+		}
+	}
+	return ""
+}
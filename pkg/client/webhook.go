@@ -0,0 +1,42 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/webhook"
+)
+
+// WebhookVerifier checks the signature, age and nonce of the report webhooks
+// the API posts; one verifier per secret, shared by all requests so replayed
+// nonces are detected
+type WebhookVerifier = webhook.Verifier
+
+// Errors returned by WebhookVerifier.Verify
+var (
+	ErrWebhookUnsigned  = webhook.ErrMissingSignature
+	ErrWebhookSignature = webhook.ErrInvalidSignature
+	ErrWebhookExpired   = webhook.ErrExpired
+	ErrWebhookReplayed  = webhook.ErrReplayed
+)
+
+// NewWebhookVerifier creates a verifier for the secret of a report (see
+// RotateWebhookSecret); tolerance is how old a request may be, 0 for 5 minutes
+func NewWebhookVerifier(secret string, tolerance time.Duration) *WebhookVerifier {
+	return webhook.NewVerifier(secret, tolerance)
+}
+
+// VerifyWebhookRequest reads the body of a webhook request and verifies it,
+// returning the body (the report file) when the request is authentic
+func VerifyWebhookRequest(v *WebhookVerifier, r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook body: %w", err)
+	}
+	if err := v.Verify(r.Header, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
@@ -0,0 +1,348 @@
+// Package connectors pulls files from partner data feeds into the import
+// pipeline. Files are downloaded into the drop folder, which validates,
+// imports and archives them like any other drop; every fetched file is
+// recorded with its checksum so it is not imported twice.
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/calendar"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// Config represents the partner feed settings
+type Config struct {
+	// SourcesFile is the JSON file listing the SFTP sources; empty disables the connector
+	SourcesFile string
+	// DropDir is the import drop folder files are staged in
+	DropDir        string
+	ConnectTimeout time.Duration
+	// MailboxesFile is the JSON file listing the IMAP mailboxes; empty disables the poller
+	MailboxesFile string
+	// MailTimeout bounds connecting to the mail server and every IMAP command
+	MailTimeout     time.Duration
+	MaxMessageBytes int64
+	// CalendarsFile is the JSON file with the holiday calendars schedules refer to
+	CalendarsFile string
+}
+
+// LoadConfig loads connector configuration from environment variables;
+// dropDir is the import drop folder
+func LoadConfig(dropDir string) *Config {
+	return &Config{
+		SourcesFile:     utils.GetEnv("SFTP_SOURCES_FILE", ""),
+		DropDir:         dropDir,
+		ConnectTimeout:  utils.GetEnvAsDuration("SFTP_CONNECT_TIMEOUT", 30*time.Second),
+		MailboxesFile:   utils.GetEnv("IMAP_MAILBOXES_FILE", ""),
+		MailTimeout:     utils.GetEnvAsDuration("IMAP_TIMEOUT", time.Minute),
+		MaxMessageBytes: utils.GetEnvAsInt64("IMAP_MAX_MESSAGE_BYTES", 25<<20), // 25 MiB
+		CalendarsFile:   utils.GetEnv("HOLIDAY_CALENDARS_FILE", ""),
+	}
+}
+
+// validSourceName keeps source names usable as file name prefixes
+var validSourceName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,80}$`)
+
+// Source is a partner SFTP server and the folder its files are pulled from
+type Source struct {
+	Name string `json:"name"`
+	// Host is host or host:port (default port 22)
+	Host string `json:"host"`
+	User string `json:"user"`
+	// KeyFile is the private key file to authenticate with
+	KeyFile string `json:"keyFile,omitempty"`
+	// PasswordEnv names the environment variable holding the password, for
+	// servers without key authentication
+	PasswordEnv string `json:"passwordEnv,omitempty"`
+	// HostKey pins the server key (authorized_keys format); otherwise the
+	// server must be in KnownHostsFile
+	HostKey        string `json:"hostKey,omitempty"`
+	KnownHostsFile string `json:"knownHostsFile,omitempty"`
+	Dir            string `json:"dir"`
+	// Pattern selects the files of Dir (path.Match syntax), default *.csv
+	Pattern string `json:"pattern,omitempty"`
+	// Interval is how often the source is pulled, default 15m
+	Interval string `json:"interval,omitempty"`
+	// Schedule pulls the source once a month on a business day instead of every Interval
+	Schedule *calendar.Rule `json:"schedule,omitempty"`
+
+	interval time.Duration
+	schedule *calendar.Schedule
+}
+
+// sourcesFile is the layout of SFTP_SOURCES_FILE
+type sourcesFile struct {
+	Sources []*Source `json:"sources"`
+}
+
+// LoadSources reads and checks the sources file
+func LoadSources(file string) ([]*Source, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SFTP sources: %w", err)
+	}
+	var f sourcesFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to parse SFTP sources: %w", err)
+	}
+	names := map[string]bool{}
+	for i, s := range f.Sources {
+		if err := s.check(); err != nil {
+			return nil, fmt.Errorf("SFTP source %d (%s): %w", i, s.Name, err)
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("SFTP source %d: duplicate name %s", i, s.Name)
+		}
+		names[s.Name] = true
+	}
+	return f.Sources, nil
+}
+
+func (s *Source) check() error {
+	switch {
+	case !validSourceName.MatchString(s.Name):
+		return errors.New("name must be 1 to 80 letters, digits, - or _")
+	case s.Host == "" || s.User == "" || s.Dir == "":
+		return errors.New("host, user and dir are required")
+	case s.KeyFile == "" && s.PasswordEnv == "":
+		return errors.New("keyFile or passwordEnv is required")
+	case s.HostKey == "" && s.KnownHostsFile == "":
+		return errors.New("hostKey or knownHostsFile is required to verify the server")
+	}
+	if s.Pattern == "" {
+		s.Pattern = "*.csv"
+	}
+	if _, err := path.Match(s.Pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	interval, err := parseInterval(s.Interval, s.Schedule, 15*time.Minute)
+	if err != nil {
+		return err
+	}
+	s.interval = interval
+	// Fail at startup rather than on the first pull
+	if _, err := s.hostKeyCallback(); err != nil {
+		return err
+	}
+	_, err = s.auth()
+	return err
+}
+
+// Connector pulls the files of every source on its schedule
+type Connector struct {
+	repo    database.Repository
+	cfg     *Config
+	sources []*Source
+	now     func() time.Time
+
+	mu     sync.Mutex
+	status map[string]*models.ConnectorSourceStatus
+}
+
+// NewConnector creates a new Connector with the sources of cfg.SourcesFile
+func NewConnector(repo database.Repository, cfg *Config) (*Connector, error) {
+	c := &Connector{repo: repo, cfg: cfg, now: time.Now, status: map[string]*models.ConnectorSourceStatus{}}
+	if cfg.SourcesFile == "" {
+		return c, nil
+	}
+	if cfg.DropDir == "" {
+		return nil, errors.New("SFTP_SOURCES_FILE needs IMPORT_DROP_DIR to stage the files in")
+	}
+	sources, err := LoadSources(cfg.SourcesFile)
+	if err != nil {
+		return nil, err
+	}
+	calendars, err := calendar.Load(cfg.CalendarsFile)
+	if err != nil {
+		return nil, err
+	}
+	c.sources = sources
+	for _, s := range sources {
+		if s.schedule, err = resolveSchedule(calendars, s.Schedule); err != nil {
+			return nil, fmt.Errorf("SFTP source %s: %w", s.Name, err)
+		}
+		st := &models.ConnectorSourceStatus{
+			Name:     s.Name,
+			Host:     s.Host,
+			Dir:      s.Dir,
+			Pattern:  s.Pattern,
+			Interval: s.interval.String(),
+		}
+		if s.schedule != nil {
+			next := s.schedule.Next(c.now())
+			st.Interval, st.Schedule, st.NextRunAt = "", s.schedule.String(), &next
+		}
+		c.status[s.Name] = st
+	}
+	return c, nil
+}
+
+// Sources returns the schedule and last outcome of every source
+func (c *Connector) Sources() []*models.ConnectorSourceStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	list := make([]*models.ConnectorSourceStatus, 0, len(c.sources))
+	for _, s := range c.sources {
+		st := *c.status[s.Name]
+		list = append(list, &st)
+	}
+	return list
+}
+
+// Run pulls every source at once and then every Interval, or on the days of
+// its Schedule, until ctx is cancelled
+func (c *Connector) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, s := range c.sources {
+		wg.Add(1)
+		go func(s *Source) {
+			defer wg.Done()
+			c.runSource(ctx, s)
+		}(s)
+	}
+	wg.Wait()
+}
+
+func (c *Connector) runSource(ctx context.Context, s *Source) {
+	if s.schedule != nil && !sleepUntil(ctx, c.now, s.schedule.Next(c.now())) {
+		return
+	}
+	for {
+		staged, err := c.Pull(ctx, s)
+		now := c.now()
+		next := nextRun(now, s.interval, s.schedule)
+		c.mu.Lock()
+		st := c.status[s.Name]
+		st.LastRunAt, st.NextRunAt = &now, &next
+		st.FilesStaged += int64(staged)
+		st.LastError = ""
+		if err != nil {
+			st.LastError = err.Error()
+		}
+		c.mu.Unlock()
+		if err != nil && ctx.Err() == nil {
+			utils.LogError("SFTP source "+s.Name, err)
+		}
+
+		if !sleepUntil(ctx, c.now, next) {
+			return
+		}
+	}
+}
+
+// Pull downloads the new files of a source into the drop folder and
+// returns how many were staged. Files already fetched with the same size
+// and modification time are not downloaded again; downloads matching the
+// checksum of a file staged before are recorded as duplicates.
+func (c *Connector) Pull(ctx context.Context, s *Source) (int, error) {
+	client, err := dialSFTP(s, c.cfg.ConnectTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	files, err := client.ReadDir(s.Dir)
+	if err != nil {
+		return 0, err
+	}
+	staged := 0
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return staged, err
+		}
+		if !f.regular {
+			continue
+		}
+		if ok, _ := path.Match(s.Pattern, f.name); !ok {
+			continue
+		}
+		remote := path.Join(s.Dir, f.name)
+		seen, err := c.repo.ConnectorFileSeen(ctx, s.Name, remote, f.size, f.modTime)
+		if err != nil {
+			return staged, err
+		}
+		if seen {
+			continue
+		}
+		rec, err := c.fetch(ctx, client, s, remote, f)
+		if err != nil {
+			return staged, err
+		}
+		if rec != nil && rec.Status == models.ConnectorFileStaged {
+			staged++
+			utils.LogInfo(fmt.Sprintf("SFTP source %s: staged %s as %s", s.Name, remote, rec.StagedAs))
+		}
+	}
+	return staged, nil
+}
+
+// fetch downloads one file next to the drop folder files, moves it in
+// unless its checksum was staged before, and records it; it returns nil
+// for files that changed while downloading
+func (c *Connector) fetch(ctx context.Context, client *sftpClient, s *Source, remote string, f remoteFile) (*models.ConnectorFile, error) {
+	// Hidden files are skipped by the drop folder until renamed
+	tmp, err := os.CreateTemp(c.cfg.DropDir, ".sftp-*.part")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := client.Download(remote, io.MultiWriter(tmp, hash))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &models.ConnectorFile{
+		ID:         uuid.New(),
+		Connector:  models.ConnectorSFTP,
+		Source:     s.Name,
+		RemotePath: remote,
+		Size:       size,
+		ModifiedAt: f.modTime,
+		SHA256:     hex.EncodeToString(hash.Sum(nil)),
+		Status:     models.ConnectorFileDuplicate,
+		FetchedAt:  c.now().UTC(),
+	}
+	if size != f.size {
+		// Still being written on the server: left for the next pull
+		return nil, nil
+	}
+	dup, err := c.repo.ConnectorChecksumStaged(ctx, s.Name, rec.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	if !dup {
+		rec.Status = models.ConnectorFileStaged
+		rec.StagedAs = fmt.Sprintf("%s-%s-%s", s.Name, rec.SHA256[:8], f.name)
+		if err := os.Rename(tmp.Name(), filepath.Join(c.cfg.DropDir, rec.StagedAs)); err != nil {
+			return nil, fmt.Errorf("failed to stage %s: %w", remote, err)
+		}
+	}
+	if err := c.repo.CreateConnectorFile(ctx, rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
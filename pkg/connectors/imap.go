@@ -0,0 +1,238 @@
+package connectors
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultIMAPPort = "993"
+	// imapMaxLine bounds a response line without its literals
+	imapMaxLine = 64 << 10
+)
+
+// imapMessage is a message fetched from the mailbox
+type imapMessage struct {
+	uid          uint32
+	internalDate time.Time
+	raw          []byte
+}
+
+// imapClient is an IMAP4rev1 session over TLS (RFC 3501). Commands are
+// sent one at a time; only what reading unseen messages and marking them
+// seen needs is implemented.
+type imapClient struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	tag     int
+	maxSize int64
+}
+
+// dialIMAP connects to the mailbox server over TLS, logs in and selects folder
+func dialIMAP(m *Mailbox, timeout time.Duration, maxSize int64) (*imapClient, error) {
+	addr := m.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, defaultIMAPPort)
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, &tls.Config{ServerName: host})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	c := &imapClient{conn: conn, r: bufio.NewReader(conn), maxSize: maxSize}
+
+	// Server greeting
+	conn.SetDeadline(time.Now().Add(timeout))
+	greeting, _, err := c.readResponse()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected greeting: %s", greeting)
+	}
+	if !strings.HasPrefix(greeting, "* PREAUTH") {
+		if _, err := c.command(timeout, "LOGIN %s %s", quote(m.User), quote(m.password())); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to log in: %w", err)
+		}
+	}
+	if _, err := c.command(timeout, "SELECT %s", quote(m.Folder)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to select %s: %w", m.Folder, err)
+	}
+	return c, nil
+}
+
+// Close logs out and closes the connection
+func (c *imapClient) Close() error {
+	c.command(5*time.Second, "LOGOUT")
+	return c.conn.Close()
+}
+
+// SearchUnseen returns the UIDs of the messages without the \Seen flag
+func (c *imapClient) SearchUnseen(timeout time.Duration) ([]uint32, error) {
+	untagged, err := c.command(timeout, "UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	var uids []uint32
+	for _, u := range untagged {
+		if !strings.HasPrefix(u.line, "* SEARCH") {
+			continue
+		}
+		for _, f := range strings.Fields(u.line[len("* SEARCH"):]) {
+			uid, err := strconv.ParseUint(f, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("malformed search response: %s", u.line)
+			}
+			uids = append(uids, uint32(uid))
+		}
+	}
+	return uids, nil
+}
+
+// internalDatePattern finds the INTERNALDATE of a FETCH response
+var internalDatePattern = regexp.MustCompile(`INTERNALDATE "([^"]+)"`)
+
+// sizePattern finds the RFC822.SIZE of a FETCH response
+var sizePattern = regexp.MustCompile(`RFC822\.SIZE (\d+)`)
+
+// errMessageTooLarge is returned by Fetch for messages over the size limit
+var errMessageTooLarge = errors.New("message exceeds IMAP_MAX_MESSAGE_BYTES")
+
+// Fetch downloads a message without setting its \Seen flag
+func (c *imapClient) Fetch(timeout time.Duration, uid uint32) (*imapMessage, error) {
+	untagged, err := c.command(timeout, "UID FETCH %d (UID RFC822.SIZE)", uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch message %d: %w", uid, err)
+	}
+	for _, u := range untagged {
+		if m := sizePattern.FindStringSubmatch(u.line); m != nil {
+			if size, err := strconv.ParseInt(m[1], 10, 64); err != nil || size > c.maxSize {
+				return nil, errMessageTooLarge
+			}
+		}
+	}
+
+	untagged, err = c.command(timeout, "UID FETCH %d (UID INTERNALDATE BODY.PEEK[])", uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch message %d: %w", uid, err)
+	}
+	for _, u := range untagged {
+		if !strings.Contains(u.line, " FETCH ") || len(u.literals) == 0 {
+			continue
+		}
+		msg := &imapMessage{uid: uid, raw: u.literals[len(u.literals)-1]}
+		if m := internalDatePattern.FindStringSubmatch(u.line); m != nil {
+			if t, err := time.Parse("_2-Jan-2006 15:04:05 -0700", m[1]); err == nil {
+				msg.internalDate = t.UTC()
+			}
+		}
+		return msg, nil
+	}
+	return nil, fmt.Errorf("message %d not found", uid)
+}
+
+// MarkSeen sets the \Seen flag of a message so it is not fetched again
+func (c *imapClient) MarkSeen(timeout time.Duration, uid uint32) error {
+	if _, err := c.command(timeout, `UID STORE %d +FLAGS.SILENT (\Seen)`, uid); err != nil {
+		return fmt.Errorf("failed to mark message %d seen: %w", uid, err)
+	}
+	return nil
+}
+
+// imapResponse is an untagged response line with the literals it carried
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+// command sends a tagged command and returns the untagged responses that
+// preceded its completion; a NO or BAD completion is returned as error
+func (c *imapClient) command(timeout time.Duration, format string, args ...any) ([]imapResponse, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%d", c.tag)
+	c.conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, fmt.Errorf("failed to write imap command: %w", err)
+	}
+	var untagged []imapResponse
+	for {
+		line, literals, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, tag+" ") {
+			untagged = append(untagged, imapResponse{line: line, literals: literals})
+			continue
+		}
+		status := strings.TrimPrefix(line, tag+" ")
+		if !strings.HasPrefix(status, "OK") {
+			return nil, errors.New(status)
+		}
+		return untagged, nil
+	}
+}
+
+// literalPattern matches the {n} announcing a literal at the end of a line
+var literalPattern = regexp.MustCompile(`\{(\d+)\}$`)
+
+// readResponse reads a response line; literals are read into their own
+// slices and replaced by "{}" in the returned line
+func (c *imapClient) readResponse() (string, [][]byte, error) {
+	var line strings.Builder
+	var literals [][]byte
+	for {
+		part, err := c.readLine()
+		if err != nil {
+			return "", nil, err
+		}
+		m := literalPattern.FindStringSubmatch(part)
+		if m == nil {
+			line.WriteString(part)
+			return line.String(), literals, nil
+		}
+		n, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || n > c.maxSize {
+			return "", nil, fmt.Errorf("imap literal of %s bytes exceeds the %d byte limit", m[1], c.maxSize)
+		}
+		lit := make([]byte, n)
+		if _, err := io.ReadFull(c.r, lit); err != nil {
+			return "", nil, fmt.Errorf("failed to read imap response: %w", err)
+		}
+		literals = append(literals, lit)
+		line.WriteString(part[:len(part)-len(m[0])] + "{}")
+	}
+}
+
+func (c *imapClient) readLine() (string, error) {
+	var buf []byte
+	for {
+		chunk, isPrefix, err := c.r.ReadLine()
+		if err != nil {
+			return "", fmt.Errorf("failed to read imap response: %w", err)
+		}
+		buf = append(buf, chunk...)
+		if len(buf) > imapMaxLine {
+			return "", errors.New("imap response line too long")
+		}
+		if !isPrefix {
+			return string(buf), nil
+		}
+	}
+}
+
+// quote encodes s as an IMAP quoted string
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
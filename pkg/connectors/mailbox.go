@@ -0,0 +1,508 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/calendar"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/reports"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// Mailbox is an IMAP folder operators email their spreadsheets to
+type Mailbox struct {
+	Name string `json:"name"`
+	// Host is host or host:port (default port 993); connections always use TLS
+	Host string `json:"host"`
+	User string `json:"user"`
+	// PasswordEnv names the environment variable holding the password
+	PasswordEnv string `json:"passwordEnv"`
+	// Folder is polled for unseen messages, default INBOX
+	Folder string `json:"folder,omitempty"`
+	// Interval is how often the mailbox is polled, default 5m
+	Interval string `json:"interval,omitempty"`
+	// Schedule polls the mailbox once a month on a business day instead of every Interval
+	Schedule *calendar.Rule `json:"schedule,omitempty"`
+	// Rules select the attachments to stage; a message is taken if any rule matches
+	Rules []*AttachmentRule `json:"rules"`
+	// Reviewers are emailed the attachments staged from every message
+	Reviewers []string `json:"reviewers,omitempty"`
+
+	interval time.Duration
+	schedule *calendar.Schedule
+}
+
+// AttachmentRule selects attachments by sender, subject and file name
+type AttachmentRule struct {
+	// From matches the sender address (path.Match syntax, case-insensitive), default *
+	From string `json:"from,omitempty"`
+	// Subject must be contained in the subject (case-insensitive) when set
+	Subject string `json:"subject,omitempty"`
+	// Filename matches the attachment name (path.Match syntax, case-insensitive), default *.csv
+	Filename string `json:"filename,omitempty"`
+}
+
+// mailboxesFile is the layout of IMAP_MAILBOXES_FILE
+type mailboxesFile struct {
+	Mailboxes []*Mailbox `json:"mailboxes"`
+}
+
+// LoadMailboxes reads and checks the mailboxes file
+func LoadMailboxes(file string) ([]*Mailbox, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IMAP mailboxes: %w", err)
+	}
+	var f mailboxesFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to parse IMAP mailboxes: %w", err)
+	}
+	names := map[string]bool{}
+	for i, m := range f.Mailboxes {
+		if err := m.check(); err != nil {
+			return nil, fmt.Errorf("IMAP mailbox %d (%s): %w", i, m.Name, err)
+		}
+		if names[m.Name] {
+			return nil, fmt.Errorf("IMAP mailbox %d: duplicate name %s", i, m.Name)
+		}
+		names[m.Name] = true
+	}
+	return f.Mailboxes, nil
+}
+
+func (m *Mailbox) check() error {
+	switch {
+	case !validSourceName.MatchString(m.Name):
+		return errors.New("name must be 1 to 80 letters, digits, - or _")
+	case m.Host == "" || m.User == "" || m.PasswordEnv == "":
+		return errors.New("host, user and passwordEnv are required")
+	case len(m.Rules) == 0:
+		return errors.New("at least one rule is required")
+	}
+	if m.Folder == "" {
+		m.Folder = "INBOX"
+	}
+	for i, r := range m.Rules {
+		if r.From == "" {
+			r.From = "*"
+		}
+		if r.Filename == "" {
+			r.Filename = "*.csv"
+		}
+		r.From, r.Subject, r.Filename = strings.ToLower(r.From), strings.ToLower(r.Subject), strings.ToLower(r.Filename)
+		if _, err := path.Match(r.From, ""); err != nil {
+			return fmt.Errorf("rule %d: invalid from: %w", i, err)
+		}
+		if _, err := path.Match(r.Filename, ""); err != nil {
+			return fmt.Errorf("rule %d: invalid filename: %w", i, err)
+		}
+	}
+	for _, addr := range m.Reviewers {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("invalid reviewer %q", addr)
+		}
+	}
+	interval, err := parseInterval(m.Interval, m.Schedule, 5*time.Minute)
+	if err != nil {
+		return err
+	}
+	m.interval = interval
+	return nil
+}
+
+func (m *Mailbox) password() string {
+	return os.Getenv(m.PasswordEnv)
+}
+
+// rules returns the rules matching the sender and subject of a message
+func (m *Mailbox) rules(from, subject string) []*AttachmentRule {
+	from, subject = strings.ToLower(from), strings.ToLower(subject)
+	var matched []*AttachmentRule
+	for _, r := range m.Rules {
+		if ok, _ := path.Match(r.From, from); ok && strings.Contains(subject, r.Subject) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// MailboxPoller stages the attachments of the messages sent to every
+// mailbox in the drop folder and notifies its reviewers
+type MailboxPoller struct {
+	repo      database.Repository
+	cfg       *Config
+	mailboxes []*Mailbox
+	mailer    reports.Mailer
+	now       func() time.Time
+
+	mu     sync.Mutex
+	status map[string]*models.MailboxStatus
+}
+
+// NewMailboxPoller creates a new MailboxPoller with the mailboxes of
+// cfg.MailboxesFile; reviewers are notified through mailer
+func NewMailboxPoller(repo database.Repository, cfg *Config, mailer reports.Mailer) (*MailboxPoller, error) {
+	if mailer == nil {
+		mailer = reports.LogMailer{}
+	}
+	p := &MailboxPoller{repo: repo, cfg: cfg, mailer: mailer, now: time.Now, status: map[string]*models.MailboxStatus{}}
+	if cfg.MailboxesFile == "" {
+		return p, nil
+	}
+	if cfg.DropDir == "" {
+		return nil, errors.New("IMAP_MAILBOXES_FILE needs IMPORT_DROP_DIR to stage the attachments in")
+	}
+	mailboxes, err := LoadMailboxes(cfg.MailboxesFile)
+	if err != nil {
+		return nil, err
+	}
+	calendars, err := calendar.Load(cfg.CalendarsFile)
+	if err != nil {
+		return nil, err
+	}
+	p.mailboxes = mailboxes
+	for _, m := range mailboxes {
+		if m.schedule, err = resolveSchedule(calendars, m.Schedule); err != nil {
+			return nil, fmt.Errorf("IMAP mailbox %s: %w", m.Name, err)
+		}
+		st := &models.MailboxStatus{
+			Name:      m.Name,
+			Host:      m.Host,
+			Folder:    m.Folder,
+			Interval:  m.interval.String(),
+			Reviewers: len(m.Reviewers),
+		}
+		if m.schedule != nil {
+			next := m.schedule.Next(p.now())
+			st.Interval, st.Schedule, st.NextRunAt = "", m.schedule.String(), &next
+		}
+		p.status[m.Name] = st
+	}
+	return p, nil
+}
+
+// Mailboxes returns the schedule and last outcome of every mailbox
+func (p *MailboxPoller) Mailboxes() []*models.MailboxStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	list := make([]*models.MailboxStatus, 0, len(p.mailboxes))
+	for _, m := range p.mailboxes {
+		st := *p.status[m.Name]
+		list = append(list, &st)
+	}
+	return list
+}
+
+// Run polls every mailbox at once and then every Interval, or on the days of
+// its Schedule, until ctx is cancelled
+func (p *MailboxPoller) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, m := range p.mailboxes {
+		wg.Add(1)
+		go func(m *Mailbox) {
+			defer wg.Done()
+			p.runMailbox(ctx, m)
+		}(m)
+	}
+	wg.Wait()
+}
+
+func (p *MailboxPoller) runMailbox(ctx context.Context, m *Mailbox) {
+	if m.schedule != nil && !sleepUntil(ctx, p.now, m.schedule.Next(p.now())) {
+		return
+	}
+	for {
+		read, staged, err := p.Poll(ctx, m)
+		now := p.now()
+		next := nextRun(now, m.interval, m.schedule)
+		p.mu.Lock()
+		st := p.status[m.Name]
+		st.LastRunAt, st.NextRunAt = &now, &next
+		st.MessagesRead += int64(read)
+		st.AttachmentsStaged += int64(staged)
+		st.LastError = ""
+		if err != nil {
+			st.LastError = err.Error()
+		}
+		p.mu.Unlock()
+		if err != nil && ctx.Err() == nil {
+			utils.LogError("IMAP mailbox "+m.Name, err)
+		}
+
+		if !sleepUntil(ctx, p.now, next) {
+			return
+		}
+	}
+}
+
+// Poll reads the unseen messages of a mailbox, stages the attachments
+// matching its rules in the drop folder and marks the messages seen; it
+// returns how many messages were read and attachments staged. Messages
+// that fail to be staged stay unseen and are tried again on the next poll.
+func (p *MailboxPoller) Poll(ctx context.Context, m *Mailbox) (int, int, error) {
+	client, err := dialIMAP(m, p.cfg.MailTimeout, p.cfg.MaxMessageBytes)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer client.Close()
+
+	uids, err := client.SearchUnseen(p.cfg.MailTimeout)
+	if err != nil {
+		return 0, 0, err
+	}
+	read, staged := 0, 0
+	for _, uid := range uids {
+		if err := ctx.Err(); err != nil {
+			return read, staged, err
+		}
+		msg, err := client.Fetch(p.cfg.MailTimeout, uid)
+		switch {
+		case errors.Is(err, errMessageTooLarge):
+			// Marked seen and left for a human, so it does not block the mailbox
+			utils.LogInfo(fmt.Sprintf("IMAP mailbox %s: skipped message %d: %v", m.Name, uid, err))
+		case err != nil:
+			return read, staged, err
+		default:
+			n, err := p.stageMessage(ctx, m, msg)
+			staged += n
+			if err != nil {
+				return read, staged, err
+			}
+		}
+		if err := client.MarkSeen(p.cfg.MailTimeout, uid); err != nil {
+			return read, staged, err
+		}
+		read++
+	}
+	return read, staged, nil
+}
+
+// attachment is a file attached to a message
+type attachment struct {
+	filename string
+	body     []byte
+}
+
+// stageMessage stages the matching attachments of a message and notifies
+// the reviewers of the mailbox about them
+func (p *MailboxPoller) stageMessage(ctx context.Context, m *Mailbox, raw *imapMessage) (int, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw.raw))
+	if err != nil {
+		// Not worth retrying: marked seen and left for a human
+		utils.LogInfo(fmt.Sprintf("IMAP mailbox %s: skipped unreadable message %d: %v", m.Name, raw.uid, err))
+		return 0, nil
+	}
+	from := ""
+	if addr, err := mail.ParseAddress(msg.Header.Get("From")); err == nil {
+		from = addr.Address
+	}
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+	rules := m.rules(from, subject)
+	if len(rules) == 0 {
+		return 0, nil
+	}
+
+	messageID := strings.Trim(msg.Header.Get("Message-Id"), "<> ")
+	if messageID == "" {
+		messageID = fmt.Sprintf("uid-%d", raw.uid)
+	}
+	receivedAt := raw.internalDate
+	if receivedAt.IsZero() {
+		receivedAt, _ = msg.Header.Date()
+	}
+
+	var attachments []attachment
+	if err := walkParts(textproto.MIMEHeader(msg.Header), msg.Body, &attachments); err != nil {
+		utils.LogInfo(fmt.Sprintf("IMAP mailbox %s: message %s from %s is malformed: %v", m.Name, messageID, from, err))
+	}
+	var staged []*models.ConnectorFile
+	for _, a := range attachments {
+		if !matchesFilename(rules, a.filename) {
+			continue
+		}
+		rec, err := p.stageAttachment(ctx, m, messageID, receivedAt, a)
+		if err != nil {
+			return len(staged), err
+		}
+		if rec != nil && rec.Status == models.ConnectorFileStaged {
+			staged = append(staged, rec)
+			utils.LogInfo(fmt.Sprintf("IMAP mailbox %s: staged %s from %s as %s", m.Name, a.filename, from, rec.StagedAs))
+		}
+	}
+	if len(staged) > 0 && len(m.Reviewers) > 0 {
+		if err := p.mailer.Send(ctx, reviewMessage(m, from, subject, staged)); err != nil {
+			// The files are staged either way; a lost notification is only logged
+			utils.LogError("IMAP mailbox "+m.Name+" reviewer notification", err)
+		}
+	}
+	return len(staged), nil
+}
+
+// stageAttachment moves an attachment into the drop folder unless its
+// checksum was staged from the mailbox before, and records it; it returns
+// nil for attachments recorded on an earlier poll
+func (p *MailboxPoller) stageAttachment(ctx context.Context, m *Mailbox, messageID string, receivedAt time.Time, a attachment) (*models.ConnectorFile, error) {
+	remote := messageID + "/" + a.filename
+	size := int64(len(a.body))
+	seen, err := p.repo.ConnectorFileSeen(ctx, m.Name, remote, size, receivedAt)
+	if err != nil || seen {
+		return nil, err
+	}
+	sum := sha256.Sum256(a.body)
+	rec := &models.ConnectorFile{
+		ID:         uuid.New(),
+		Connector:  models.ConnectorEmail,
+		Source:     m.Name,
+		RemotePath: remote,
+		Size:       size,
+		ModifiedAt: receivedAt,
+		SHA256:     hex.EncodeToString(sum[:]),
+		Status:     models.ConnectorFileDuplicate,
+		FetchedAt:  p.now().UTC(),
+	}
+	dup, err := p.repo.ConnectorChecksumStaged(ctx, m.Name, rec.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	if !dup {
+		rec.Status = models.ConnectorFileStaged
+		rec.StagedAs = fmt.Sprintf("%s-%s-%s", m.Name, rec.SHA256[:8], a.filename)
+		if err := writeStaged(p.cfg.DropDir, rec.StagedAs, a.body); err != nil {
+			return nil, fmt.Errorf("failed to stage %s: %w", remote, err)
+		}
+	}
+	if err := p.repo.CreateConnectorFile(ctx, rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// writeStaged writes a file to the drop folder under a hidden name first,
+// so the drop folder never picks up a partial file
+func writeStaged(dir, name string, body []byte) error {
+	tmp, err := os.CreateTemp(dir, ".imap-*.part")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+func matchesFilename(rules []*AttachmentRule, filename string) bool {
+	name := strings.ToLower(filename)
+	for _, r := range rules {
+		if ok, _ := path.Match(r.Filename, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// unsafeFilename matches the characters not kept in staged file names
+var unsafeFilename = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// walkParts collects the attachments of a MIME entity, descending into
+// multipart bodies; parts are decoded according to their transfer encoding
+func walkParts(header textproto.MIMEHeader, body io.Reader, out *[]attachment) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := walkParts(part.Header, part, out); err != nil {
+				return err
+			}
+		}
+	}
+
+	filename := ""
+	if _, dparams, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		filename = dparams["filename"]
+	}
+	if filename == "" {
+		filename = params["name"]
+	}
+	if filename == "" {
+		return nil
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(filename); err == nil {
+		filename = decoded
+	}
+	filename = strings.Trim(unsafeFilename.ReplaceAllString(filepath.Base(filename), "_"), "._")
+	if filename == "" {
+		return nil
+	}
+
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", filename, err)
+	}
+	*out = append(*out, attachment{filename: filename, body: data})
+	return nil
+}
+
+// reviewMessage is the notification sent to the reviewers of a mailbox
+func reviewMessage(m *Mailbox, from, subject string, staged []*models.ConnectorFile) *reports.Message {
+	var body strings.Builder
+	fmt.Fprintf(&body, "%d attachment(s) emailed by %s to mailbox %s were staged for import.\n\n", len(staged), from, m.Name)
+	fmt.Fprintf(&body, "Subject: %s\n\n", subject)
+	for _, f := range staged {
+		fmt.Fprintf(&body, "- %s (%d bytes, SHA-256 %s) staged as %s\n", path.Base(f.RemotePath), f.Size, f.SHA256, f.StagedAs)
+	}
+	body.WriteString("\nThe import jobs and their outcome are listed in the drop folder processing log (GET /api/v1/imports/drop-folder).\n")
+	return &reports.Message{
+		To:      m.Reviewers,
+		Subject: fmt.Sprintf("[TADB] %d attachment(s) from %s staged for import", len(staged), from),
+		Body:    body.String(),
+	}
+}
@@ -0,0 +1,60 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/calendar"
+)
+
+// parseInterval checks the interval of a feed, def when unset; feeds on a
+// calendar schedule have none
+func parseInterval(interval string, schedule *calendar.Rule, def time.Duration) (time.Duration, error) {
+	if schedule != nil {
+		if interval != "" {
+			return 0, errors.New("set interval or schedule, not both")
+		}
+		return 0, nil
+	}
+	if interval == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(interval)
+	if err != nil || d < time.Minute {
+		return 0, errors.New("interval must be a duration of at least 1m")
+	}
+	return d, nil
+}
+
+// resolveSchedule resolves the calendar schedule of a feed, nil when it has none
+func resolveSchedule(calendars calendar.Calendars, rule *calendar.Rule) (*calendar.Schedule, error) {
+	if rule == nil {
+		return nil, nil
+	}
+	s, err := calendars.Schedule(rule)
+	if err != nil {
+		return nil, errors.New("invalid schedule: " + err.Error())
+	}
+	return s, nil
+}
+
+// nextRun returns when a feed run at now is due again
+func nextRun(now time.Time, interval time.Duration, schedule *calendar.Schedule) time.Time {
+	if schedule != nil {
+		return schedule.Next(now)
+	}
+	return now.Add(interval)
+}
+
+// sleepUntil waits until t; false when ctx was cancelled first
+func sleepUntil(ctx context.Context, now func() time.Time, t time.Time) bool {
+	timer := time.NewTimer(t.Sub(now()))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,407 @@
+package connectors
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SFTP version 3 packet types (draft-ietf-secsh-filexfer-02); only what
+// listing and downloading files needs is implemented
+const (
+	sshFxpInit     = 1
+	sshFxpVersion  = 2
+	sshFxpOpen     = 3
+	sshFxpClose    = 4
+	sshFxpRead     = 5
+	sshFxpOpendir  = 11
+	sshFxpReaddir  = 12
+	sshFxpStatus   = 101
+	sshFxpHandle   = 102
+	sshFxpData     = 103
+	sshFxpName     = 104
+	sftpVersion    = 3
+	sftpReadSize   = 32 << 10
+	sftpMaxPacket  = 256 << 10
+	sshFxOK        = 0
+	sshFxEOF       = 1
+	sshFxfRead     = 0x01
+	attrSize       = 0x01
+	attrUIDGID     = 0x02
+	attrPerms      = 0x04
+	attrACModTime  = 0x08
+	attrExtended   = 0x80000000
+	modeTypeMask   = 0o170000
+	modeRegular    = 0o100000
+	defaultSSHPort = "22"
+)
+
+// remoteFile is an entry of a remote directory
+type remoteFile struct {
+	name    string
+	size    int64
+	modTime time.Time
+	regular bool
+}
+
+// sftpClient is an SFTP session over an SSH connection. Requests are sent
+// one at a time, so request ids only guard against protocol errors.
+type sftpClient struct {
+	conn  *ssh.Client
+	in    io.WriteCloser
+	out   io.Reader
+	reqID uint32
+}
+
+// dialSFTP connects to the source over SSH and starts the sftp subsystem
+func dialSFTP(src *Source, timeout time.Duration) (*sftpClient, error) {
+	hostKey, err := src.hostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+	auth, err := src.auth()
+	if err != nil {
+		return nil, err
+	}
+	addr := src.Host
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, defaultSSHPort)
+	}
+
+	conn, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            src.User,
+		Auth:            auth,
+		HostKeyCallback: hostKey,
+		Timeout:         timeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	session, err := conn.NewSession()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open session: %w", err)
+	}
+	in, err := session.StdinPipe()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	out, err := session.StdoutPipe()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start sftp: %w", err)
+	}
+
+	c := &sftpClient{conn: conn, in: in, out: out}
+	if err := c.init(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// hostKeyCallback verifies the server against the pinned key or the known_hosts file
+func (s *Source) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	if s.HostKey != "" {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(s.HostKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hostKey: %w", err)
+		}
+		return ssh.FixedHostKey(key), nil
+	}
+	cb, err := knownhosts.New(s.KnownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read knownHostsFile: %w", err)
+	}
+	return cb, nil
+}
+
+// auth returns the private key and, when PasswordEnv is set, password authentication
+func (s *Source) auth() ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+	if s.KeyFile != "" {
+		pem, err := os.ReadFile(s.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keyFile: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(pem)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keyFile: %w", err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+	if s.PasswordEnv != "" {
+		methods = append(methods, ssh.Password(os.Getenv(s.PasswordEnv)))
+	}
+	return methods, nil
+}
+
+func (c *sftpClient) Close() error {
+	return c.conn.Close()
+}
+
+func (c *sftpClient) init() error {
+	if err := c.send(sshFxpInit, binary.BigEndian.AppendUint32(nil, sftpVersion)); err != nil {
+		return err
+	}
+	kind, body, err := c.recv()
+	if err != nil {
+		return fmt.Errorf("failed to start sftp: %w", err)
+	}
+	if kind != sshFxpVersion || len(body) < 4 {
+		return fmt.Errorf("failed to start sftp: unexpected packet type %d", kind)
+	}
+	return nil
+}
+
+// ReadDir lists the entries of a remote directory
+func (c *sftpClient) ReadDir(dir string) ([]remoteFile, error) {
+	handle, err := c.handle(sshFxpOpendir, appendString(nil, dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", dir, err)
+	}
+	defer c.closeHandle(handle)
+
+	var files []remoteFile
+	for {
+		kind, body, err := c.request(sshFxpReaddir, appendString(nil, handle))
+		if err != nil {
+			return nil, err
+		}
+		if kind == sshFxpStatus {
+			if err := statusError(body); err != nil && !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+			}
+			return files, nil
+		}
+		if kind != sshFxpName {
+			return nil, fmt.Errorf("failed to list %s: unexpected packet type %d", dir, kind)
+		}
+		entries, err := parseNames(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+		}
+		files = append(files, entries...)
+	}
+}
+
+// Download copies a remote file to w
+func (c *sftpClient) Download(file string, w io.Writer) (int64, error) {
+	body := appendString(nil, file)
+	body = binary.BigEndian.AppendUint32(body, sshFxfRead)
+	body = binary.BigEndian.AppendUint32(body, 0) // no attributes
+	handle, err := c.handle(sshFxpOpen, body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", file, err)
+	}
+	defer c.closeHandle(handle)
+
+	var offset int64
+	for {
+		req := appendString(nil, handle)
+		req = binary.BigEndian.AppendUint64(req, uint64(offset))
+		req = binary.BigEndian.AppendUint32(req, sftpReadSize)
+		kind, resp, err := c.request(sshFxpRead, req)
+		if err != nil {
+			return offset, err
+		}
+		if kind == sshFxpStatus {
+			if err := statusError(resp); err != nil && !errors.Is(err, io.EOF) {
+				return offset, fmt.Errorf("failed to read %s: %w", file, err)
+			}
+			return offset, nil
+		}
+		data, _, ok := readString(resp)
+		if kind != sshFxpData || !ok {
+			return offset, fmt.Errorf("failed to read %s: unexpected packet type %d", file, kind)
+		}
+		if _, err := w.Write(data); err != nil {
+			return offset, err
+		}
+		offset += int64(len(data))
+	}
+}
+
+// handle sends a request answered with a handle
+func (c *sftpClient) handle(kind byte, body []byte) (string, error) {
+	respKind, resp, err := c.request(kind, body)
+	if err != nil {
+		return "", err
+	}
+	if respKind == sshFxpStatus {
+		if err := statusError(resp); err != nil {
+			return "", err
+		}
+	}
+	handle, _, ok := readString(resp)
+	if respKind != sshFxpHandle || !ok {
+		return "", fmt.Errorf("unexpected packet type %d", respKind)
+	}
+	return string(handle), nil
+}
+
+func (c *sftpClient) closeHandle(handle string) {
+	c.request(sshFxpClose, appendString(nil, handle))
+}
+
+// request sends a packet with a new request id and reads its response
+func (c *sftpClient) request(kind byte, body []byte) (byte, []byte, error) {
+	c.reqID++
+	id := c.reqID
+	if err := c.send(kind, append(binary.BigEndian.AppendUint32(nil, id), body...)); err != nil {
+		return 0, nil, err
+	}
+	respKind, resp, err := c.recv()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(resp) < 4 || binary.BigEndian.Uint32(resp) != id {
+		return 0, nil, errors.New("sftp response for another request")
+	}
+	return respKind, resp[4:], nil
+}
+
+func (c *sftpClient) send(kind byte, body []byte) error {
+	pkt := binary.BigEndian.AppendUint32(nil, uint32(len(body)+1))
+	pkt = append(pkt, kind)
+	if _, err := c.in.Write(append(pkt, body...)); err != nil {
+		return fmt.Errorf("failed to write sftp request: %w", err)
+	}
+	return nil
+}
+
+func (c *sftpClient) recv() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.out, header[:]); err != nil {
+		return 0, nil, fmt.Errorf("failed to read sftp response: %w", err)
+	}
+	n := binary.BigEndian.Uint32(header[:4])
+	if n < 1 || n > sftpMaxPacket {
+		return 0, nil, fmt.Errorf("sftp packet of %d bytes", n)
+	}
+	body := make([]byte, n-1)
+	if _, err := io.ReadFull(c.out, body); err != nil {
+		return 0, nil, fmt.Errorf("failed to read sftp response: %w", err)
+	}
+	return header[4], body, nil
+}
+
+// statusError returns nil for SSH_FX_OK, io.EOF for SSH_FX_EOF and the
+// server message otherwise
+func statusError(body []byte) error {
+	if len(body) < 4 {
+		return errors.New("malformed sftp status")
+	}
+	code := binary.BigEndian.Uint32(body)
+	switch code {
+	case sshFxOK:
+		return nil
+	case sshFxEOF:
+		return io.EOF
+	}
+	msg, _, _ := readString(body[4:])
+	return fmt.Errorf("sftp error %d: %s", code, msg)
+}
+
+// parseNames decodes an SSH_FXP_NAME body
+func parseNames(body []byte) ([]remoteFile, error) {
+	if len(body) < 4 {
+		return nil, errors.New("malformed sftp name list")
+	}
+	count := binary.BigEndian.Uint32(body)
+	body = body[4:]
+	files := make([]remoteFile, 0, count)
+	for i := uint32(0); i < count; i++ {
+		name, rest, ok := readString(body)
+		if !ok {
+			return nil, errors.New("malformed sftp name list")
+		}
+		if _, rest, ok = readString(rest); !ok { // long name
+			return nil, errors.New("malformed sftp name list")
+		}
+		// Servers that send no permissions only list files worth trying
+		f := remoteFile{name: string(name), regular: true}
+		if body, ok = parseAttrs(rest, &f); !ok {
+			return nil, errors.New("malformed sftp attributes")
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// parseAttrs decodes the ATTRS of a name entry into f and returns the rest of b
+func parseAttrs(b []byte, f *remoteFile) ([]byte, bool) {
+	flags, b, ok := readUint32(b)
+	if !ok {
+		return nil, false
+	}
+	if flags&attrSize != 0 {
+		if len(b) < 8 {
+			return nil, false
+		}
+		f.size, b = int64(binary.BigEndian.Uint64(b)), b[8:]
+	}
+	if flags&attrUIDGID != 0 {
+		if len(b) < 8 {
+			return nil, false
+		}
+		b = b[8:]
+	}
+	if flags&attrPerms != 0 {
+		var perms uint32
+		if perms, b, ok = readUint32(b); !ok {
+			return nil, false
+		}
+		f.regular = perms&modeTypeMask == modeRegular
+	}
+	if flags&attrACModTime != 0 {
+		if len(b) < 8 {
+			return nil, false
+		}
+		f.modTime, b = time.Unix(int64(binary.BigEndian.Uint32(b[4:])), 0).UTC(), b[8:]
+	}
+	if flags&attrExtended != 0 {
+		var n uint32
+		if n, b, ok = readUint32(b); !ok {
+			return nil, false
+		}
+		for i := uint32(0); i < 2*n; i++ {
+			if _, b, ok = readString(b); !ok {
+				return nil, false
+			}
+		}
+	}
+	return b, true
+}
+
+func readUint32(b []byte) (uint32, []byte, bool) {
+	if len(b) < 4 {
+		return 0, nil, false
+	}
+	return binary.BigEndian.Uint32(b), b[4:], true
+}
+
+func readString(b []byte) ([]byte, []byte, bool) {
+	n, b, ok := readUint32(b)
+	if !ok || uint32(len(b)) < n {
+		return nil, nil, false
+	}
+	return b[:n], b[n:], true
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,176 @@
+// Package customfields checks custom attributes against the custom fields
+// registered for their entity, and parses attribute filters of listings.
+package customfields
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+)
+
+// ErrInvalid is returned for attributes or field definitions that do not validate
+var ErrInvalid = errors.New("invalid custom attributes")
+
+var namePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// CheckDefinition checks a field definition: the name is an identifier,
+// pattern compiles, min is not above max and options are only given for strings
+func CheckDefinition(name, typ, pattern string, min, max *float64, options []string) error {
+	if !namePattern.MatchString(name) {
+		return errors.New("name must start with a letter followed by letters, digits or underscores")
+	}
+	if pattern != "" {
+		if typ != models.CustomFieldString {
+			return errors.New("pattern only applies to string fields")
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("pattern: %v", err)
+		}
+	}
+	if len(options) > 0 && typ != models.CustomFieldString {
+		return errors.New("options only apply to string fields")
+	}
+	if (min != nil || max != nil) && typ != models.CustomFieldString && typ != models.CustomFieldNumber {
+		return errors.New("min and max only apply to number and string fields")
+	}
+	if min != nil && max != nil && *min > *max {
+		return errors.New("min must not be above max")
+	}
+	return nil
+}
+
+// Validate checks attrs against the fields of their entity: every attribute
+// is a registered field with a valid value and, unless partial, every
+// required field is present. A null value leaves the attribute unset (on
+// updates it removes it), which required fields do not allow. All problems
+// are reported.
+func Validate(fields []*models.CustomField, attrs models.CustomAttributes, partial bool) error {
+	byName := make(map[string]*models.CustomField, len(fields))
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+
+	var problems []string
+	for name, v := range attrs {
+		f, ok := byName[name]
+		if !ok {
+			problems = append(problems, name+": unknown custom field")
+			continue
+		}
+		if v == nil {
+			if f.Required {
+				problems = append(problems, name+": is required")
+			}
+			continue
+		}
+		if err := checkValue(f, v); err != nil {
+			problems = append(problems, name+": "+err.Error())
+		}
+	}
+	if !partial {
+		for _, f := range fields {
+			if _, ok := attrs[f.Name]; f.Required && !ok {
+				problems = append(problems, f.Name+": is required")
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("%w: %s", ErrInvalid, strings.Join(problems, "; "))
+}
+
+// checkValue checks a value decoded from JSON against its field
+func checkValue(f *models.CustomField, v any) error {
+	switch f.Type {
+	case models.CustomFieldNumber:
+		n, ok := v.(float64)
+		if !ok {
+			return errors.New("must be a number")
+		}
+		if f.Min != nil && n < *f.Min {
+			return fmt.Errorf("must be at least %g", *f.Min)
+		}
+		if f.Max != nil && n > *f.Max {
+			return fmt.Errorf("must be at most %g", *f.Max)
+		}
+	case models.CustomFieldBoolean:
+		if _, ok := v.(bool); !ok {
+			return errors.New("must be true or false")
+		}
+	case models.CustomFieldDate:
+		s, ok := v.(string)
+		if !ok {
+			return errors.New("must be a date (YYYY-MM-DD)")
+		}
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return errors.New("must be a date (YYYY-MM-DD)")
+		}
+	default:
+		s, ok := v.(string)
+		if !ok {
+			return errors.New("must be a string")
+		}
+		n := float64(utf8.RuneCountInString(s))
+		if f.Min != nil && n < *f.Min {
+			return fmt.Errorf("must be at least %g characters", *f.Min)
+		}
+		if f.Max != nil && n > *f.Max {
+			return fmt.Errorf("must be at most %g characters", *f.Max)
+		}
+		if len(f.Options) > 0 && !contains(f.Options, s) {
+			return errors.New("must be one of " + strings.Join(f.Options, ", "))
+		}
+		if f.Pattern != "" {
+			// Definitions are checked on registration; a pattern that no
+			// longer compiles rejects the value rather than the request
+			re, err := regexp.Compile(f.Pattern)
+			if err != nil || !re.MatchString(s) {
+				return errors.New("must match " + f.Pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseValue converts the query string value of an attribute filter to the
+// type of its field, so it compares equal to the stored JSON value
+func ParseValue(f *models.CustomField, raw string) (any, error) {
+	switch f.Type {
+	case models.CustomFieldNumber:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, errors.New("must be a number")
+		}
+		return n, nil
+	case models.CustomFieldBoolean:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return b, nil
+	case models.CustomFieldDate:
+		if _, err := time.Parse("2006-01-02", raw); err != nil {
+			return nil, errors.New("must be a date (YYYY-MM-DD)")
+		}
+	}
+	return raw, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,519 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/metric"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+	"github.com/shopspring/decimal"
+)
+
+// dateRangeConditions appends optional inclusive date bounds on column to conds/args
+func dateRangeConditions(column string, startDate, endDate *string, conds []string, args []any) ([]string, []any) {
+	if startDate != nil && *startDate != "" {
+		args = append(args, *startDate)
+		conds = append(conds, fmt.Sprintf("%s >= $%d", column, len(args)))
+	}
+	if endDate != nil && *endDate != "" {
+		args = append(args, *endDate)
+		conds = append(conds, fmt.Sprintf("%s <= $%d", column, len(args)))
+	}
+	return conds, args
+}
+
+// whereClause joins conditions into a WHERE clause (empty when there are none)
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// asOfRelations returns the relations analytics read productions and
+// generators from. Without asOf they are the tables themselves; with asOf
+// they are restated to that moment: records and generators created later are
+// left out and corrected records take the value they had then, the previous
+// value of their first correction after asOf. Deleted records and changes
+// outside the correction workflow are not tracked, so only published months
+// (which change through corrections alone) are reproduced exactly.
+func asOfRelations(asOf *time.Time, args []any) (productions, generators string, _ []any) {
+	if asOf == nil {
+		return "productions", "generators", args
+	}
+	args = append(args, *asOf)
+	n := len(args)
+	productions = fmt.Sprintf(`(
+			SELECT p.id, p.generator_id, p.date, p.source,
+			       COALESCE((
+			           SELECT c.previous_mw FROM production_corrections c
+			           WHERE c.production_id = p.id AND c.created_at > $%[1]d
+			           ORDER BY c.created_at LIMIT 1
+			       ), p.production_mw) AS production_mw
+			FROM productions p
+			WHERE p.created_at <= $%[1]d
+		)`, n)
+	generators = fmt.Sprintf(`(SELECT * FROM generators WHERE created_at <= $%d)`, n)
+	return productions, generators, args
+}
+
+// GetTotalProductionByDate returns daily production totals split by renewable
+// status, restated to asOf when it is set; wide ranges read the daily totals
+func (r *postgresRepository) GetTotalProductionByDate(ctx context.Context, startDate, endDate *string, asOf *time.Time) ([]*models.TotalProductionByDate, error) {
+	from, conds, args := r.productionRelation(startDate, endDate, asOf, nil)
+	conds, args = dateRangeConditions("p.date", startDate, endDate, conds, args)
+	query := `
+		SELECT p.date::text,
+		       SUM(p.production_mw),
+		       COALESCE(SUM(p.production_mw) FILTER (WHERE t.isrenuevable), 0),
+		       COALESCE(SUM(p.production_mw) FILTER (WHERE NOT t.isrenuevable), 0)
+		FROM ` + from + whereClause(conds) + `
+		GROUP BY p.date
+		ORDER BY p.date DESC`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query total production: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.TotalProductionByDate
+	for rows.Next() {
+		var t models.TotalProductionByDate
+		if err := rows.Scan(&t.Date, &t.TotalProduction, &t.RenewableProduction, &t.NonRenewableProduction); err != nil {
+			return nil, fmt.Errorf("failed to scan total production: %w", err)
+		}
+		t.TotalProduction = numeric.RoundDecimal(t.TotalProduction)
+		t.RenewableProduction = numeric.RoundDecimal(t.RenewableProduction)
+		t.NonRenewableProduction = numeric.RoundDecimal(t.NonRenewableProduction)
+		list = append(list, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// GetProductionByGenerator returns the production total of every generator
+// with records between the dates, ordered by generator id
+func (r *postgresRepository) GetProductionByGenerator(ctx context.Context, startDate, endDate *string) ([]*models.GeneratorProduction, error) {
+	conds, args := dateRangeConditions("p.date", startDate, endDate, nil, nil)
+	query := `
+		SELECT p.generator_id, SUM(p.production_mw)
+		FROM productions p` + whereClause(conds) + `
+		GROUP BY p.generator_id
+		ORDER BY p.generator_id`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query production by generator: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.GeneratorProduction
+	for rows.Next() {
+		var g models.GeneratorProduction
+		if err := rows.Scan(&g.GeneratorID, &g.TotalProduction); err != nil {
+			return nil, fmt.Errorf("failed to scan production by generator: %w", err)
+		}
+		g.TotalProduction = numeric.RoundDecimal(g.TotalProduction)
+		list = append(list, &g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// GetGeneratorEfficiency returns the production of every generator between
+// the dates with its average per day with records and its capacity factor,
+// that average as a percentage of the capacity. Generators without records
+// are included with zeros. Highest capacity factor first.
+func (r *postgresRepository) GetGeneratorEfficiency(ctx context.Context, startDate, endDate *string) ([]*models.GeneratorEfficiency, error) {
+	conds, args := dateRangeConditions("p.date", startDate, endDate, []string{"p.generator_id = g.id"}, nil)
+	query := `
+		WITH gen AS (
+			SELECT g.id, t.name AS type_name, g.capacity,
+			       COALESCE(SUM(p.production_mw), 0) AS production,
+			       COALESCE(SUM(p.production_mw) / NULLIF(COUNT(DISTINCT p.date), 0), 0) AS daily
+			FROM generators g
+			JOIN types t ON g.type = t.id
+			LEFT JOIN productions p ON ` + strings.Join(conds, " AND ") + `
+			GROUP BY g.id, t.name, g.capacity
+		)
+		SELECT id, type_name, capacity, production, daily,
+		       COALESCE(daily * 100 / NULLIF(capacity, 0), 0) AS efficiency
+		FROM gen
+		ORDER BY efficiency DESC, id`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query generator efficiency: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.GeneratorEfficiency
+	for rows.Next() {
+		var e models.GeneratorEfficiency
+		if err := rows.Scan(&e.GeneratorID, &e.TypeName, &e.Capacity, &e.TotalProduction,
+			&e.AvgDailyProduction, &e.EfficiencyPercentage); err != nil {
+			return nil, fmt.Errorf("failed to scan generator efficiency: %w", err)
+		}
+		e.Capacity = numeric.RoundDecimal(e.Capacity)
+		e.TotalProduction = numeric.RoundDecimal(e.TotalProduction)
+		e.AvgDailyProduction = numeric.RoundDecimal(e.AvgDailyProduction)
+		e.EfficiencyPercentage = numeric.RoundDecimal(e.EfficiencyPercentage)
+		list = append(list, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// GetRenewableSummary returns capacity, generator count and production of the
+// renewable and non-renewable generators, with the average production per
+// record and the share of the production of both. Dates limit production
+// only; both classes are always returned, renewable first.
+func (r *postgresRepository) GetRenewableSummary(ctx context.Context, startDate, endDate *string) ([]*models.RenewableSummary, error) {
+	conds, args := dateRangeConditions("p.date", startDate, endDate, []string{"p.generator_id = g.id"}, nil)
+	query := `
+		WITH gen AS (
+			SELECT g.id, t.isrenuevable AS renewable, g.capacity,
+			       COALESCE(SUM(p.production_mw), 0) AS production, COUNT(p.id) AS records
+			FROM generators g
+			JOIN types t ON g.type = t.id
+			LEFT JOIN productions p ON ` + strings.Join(conds, " AND ") + `
+			GROUP BY g.id, t.isrenuevable, g.capacity
+		)
+		SELECT CASE WHEN c.renewable THEN 'Renewable' ELSE 'Non-renewable' END,
+		       COALESCE(SUM(gen.capacity), 0),
+		       COUNT(gen.id),
+		       COALESCE(SUM(gen.production), 0),
+		       COALESCE(SUM(gen.production) / NULLIF(SUM(gen.records), 0), 0),
+		       COALESCE(SUM(gen.production) * 100 / NULLIF((SELECT SUM(production) FROM gen), 0), 0)
+		FROM (VALUES (true), (false)) AS c(renewable)
+		LEFT JOIN gen ON gen.renewable = c.renewable
+		GROUP BY c.renewable
+		ORDER BY c.renewable DESC`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query renewable summary: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.RenewableSummary
+	for rows.Next() {
+		var s models.RenewableSummary
+		if err := rows.Scan(&s.EnergyType, &s.TotalCapacity, &s.GeneratorCount, &s.TotalProduction,
+			&s.AvgProduction, &s.PercentageOfTotal); err != nil {
+			return nil, fmt.Errorf("failed to scan renewable summary: %w", err)
+		}
+		s.TotalCapacity = numeric.RoundDecimal(s.TotalCapacity)
+		s.TotalProduction = numeric.RoundDecimal(s.TotalProduction)
+		s.AvgProduction = numeric.RoundDecimal(s.AvgProduction)
+		s.PercentageOfTotal = numeric.RoundDecimal(s.PercentageOfTotal)
+		list = append(list, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// GetMarketShareByOperator returns capacity and production totals per operator with
+// their share of the whole fleet, restated to asOf when it is set. Generators
+// without operator are grouped as "Unassigned".
+func (r *postgresRepository) GetMarketShareByOperator(ctx context.Context, startDate, endDate *string, asOf *time.Time) ([]*models.OperatorMarketShare, error) {
+	productions, generators, args := asOfRelations(asOf, nil)
+	// Date bounds restrict the joined productions, not the generators, so
+	// capacity shares always cover the whole fleet
+	conds, args := dateRangeConditions("p.date", startDate, endDate, []string{"p.generator_id = g.id"}, args)
+	query := `
+		WITH gen AS (
+			SELECT g.id, g.operator_id, g.capacity, COALESCE(SUM(p.production_mw), 0) AS production
+			FROM ` + generators + ` g
+			LEFT JOIN ` + productions + ` p ON ` + strings.Join(conds, " AND ") + `
+			GROUP BY g.id, g.operator_id, g.capacity
+		)
+		SELECT o.id,
+		       COALESCE(o.name, 'Unassigned'),
+		       COUNT(*),
+		       SUM(gen.capacity),
+		       SUM(gen.production),
+		       COALESCE(SUM(gen.production) * 100 / NULLIF((SELECT SUM(production) FROM gen), 0), 0),
+		       COALESCE(SUM(gen.capacity) * 100 / NULLIF((SELECT SUM(capacity) FROM gen), 0), 0)
+		FROM gen
+		LEFT JOIN operators o ON gen.operator_id = o.id
+		GROUP BY o.id, o.name
+		ORDER BY SUM(gen.production) DESC, 2`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query market share: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.OperatorMarketShare
+	for rows.Next() {
+		var m models.OperatorMarketShare
+		if err := rows.Scan(&m.OperatorID, &m.OperatorName, &m.GeneratorCount, &m.TotalCapacity,
+			&m.TotalProduction, &m.ProductionShare, &m.CapacityShare); err != nil {
+			return nil, fmt.Errorf("failed to scan market share: %w", err)
+		}
+		m.TotalCapacity = numeric.RoundDecimal(m.TotalCapacity)
+		m.TotalProduction = numeric.RoundDecimal(m.TotalProduction)
+		m.ProductionShare = numeric.RoundDecimal(m.ProductionShare)
+		m.CapacityShare = numeric.RoundDecimal(m.CapacityShare)
+		list = append(list, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// ErrInvalidCrosstab is returned for cross-tab dimensions or aggregates outside the whitelist
+var ErrInvalidCrosstab = errors.New("invalid crosstab")
+
+// crosstabDimensions maps the allowed dimension names to their SQL expression
+var crosstabDimensions = map[string]string{
+	"type":       "t.name",
+	"technology": "COALESCE(t.technology_code, 'UNKNOWN')",
+	"renewable":  "CASE WHEN t.isrenuevable THEN 'renewable' ELSE 'non-renewable' END",
+	"operator":   "COALESCE(o.name, 'Unassigned')",
+	"generator":  "g.id::text",
+	"source":     "p.source",
+	"year":       "to_char(p.date, 'YYYY')",
+	"month":      "to_char(p.date, 'YYYY-MM')",
+	"day":        "p.date::text",
+}
+
+// crosstabValues maps the preset aggregates to their metric expression
+var crosstabValues = map[string]string{
+	"sum":   "sum(productionMw)",
+	"avg":   "avg(productionMw)",
+	"min":   "min(productionMw)",
+	"max":   "max(productionMw)",
+	"count": "count()",
+}
+
+// CrosstabDimensions returns the allowed dimension names, sorted
+func CrosstabDimensions() []string {
+	names := make([]string, 0, len(crosstabDimensions))
+	for name := range crosstabDimensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CrosstabValues returns the allowed aggregate names, sorted
+func CrosstabValues() []string {
+	names := make([]string, 0, len(crosstabValues))
+	for name := range crosstabValues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseCrosstab checks the dimensions and value of q against the whitelist and
+// returns the expression to aggregate with its canonical name
+func parseCrosstab(q *models.CrosstabQuery) (*metric.Expr, string, error) {
+	_, okRow := crosstabDimensions[q.Rows]
+	_, okCol := crosstabDimensions[q.Columns]
+	source, okValue := crosstabValues[q.Value]
+	value := q.Value
+	if q.Metric != "" {
+		source, okValue = q.Metric, true
+	}
+	switch {
+	case !okRow || !okCol:
+		return nil, "", fmt.Errorf("%w: rows and cols must be one of %s", ErrInvalidCrosstab, strings.Join(CrosstabDimensions(), ", "))
+	case q.Rows == q.Columns:
+		return nil, "", fmt.Errorf("%w: rows and cols must be different dimensions", ErrInvalidCrosstab)
+	case !okValue:
+		return nil, "", fmt.Errorf("%w: value must be one of %s", ErrInvalidCrosstab, strings.Join(CrosstabValues(), ", "))
+	}
+	expr, err := metric.Parse(source)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidCrosstab, err)
+	}
+	if q.Metric != "" {
+		value = expr.String()
+	}
+	return expr, value, nil
+}
+
+// GetCrosstab aggregates production over two whitelisted dimensions. Row,
+// column and grand totals come from the same query through GROUPING SETS, so
+// they are exact for every aggregate (an average of averages is not). The
+// value is a preset or, when q.Metric is set, a metric expression evaluated
+// per production record (capacity is that record's generator capacity).
+// q.AsOf restates the records as in GetTotalProductionByDate.
+func (r *postgresRepository) GetCrosstab(ctx context.Context, q *models.CrosstabQuery) (*models.Crosstab, error) {
+	expr, value, err := parseCrosstab(q)
+	if err != nil {
+		return nil, err
+	}
+	rowExpr, colExpr := crosstabDimensions[q.Rows], crosstabDimensions[q.Columns]
+
+	productions, generators, args := asOfRelations(q.AsOf, nil)
+	conds, args := dateRangeConditions("p.date", q.StartDate, q.EndDate, nil, args)
+	query := `
+		SELECT rk, ck, ` + expr.SQL() + `, GROUPING(rk), GROUPING(ck)
+		FROM (
+			SELECT ` + rowExpr + ` AS rk, ` + colExpr + ` AS ck, p.production_mw, g.capacity
+			FROM ` + productions + ` p
+			JOIN ` + generators + ` g ON p.generator_id = g.id
+			JOIN types t ON g.type = t.id
+			LEFT JOIN operators o ON g.operator_id = o.id` + whereClause(conds) + `
+		) s
+		GROUP BY GROUPING SETS ((rk, ck), (rk), (ck), ())`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crosstab: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		cells     []crosstabCell
+		rowTotals = map[string]*decimal.Decimal{}
+		colTotals = map[string]*decimal.Decimal{}
+		total     *decimal.Decimal
+	)
+	for rows.Next() {
+		var (
+			rk, ck         *string
+			scanned        decimal.NullDecimal
+			rowAll, colAll int
+		)
+		if err := rows.Scan(&rk, &ck, &scanned, &rowAll, &colAll); err != nil {
+			return nil, fmt.Errorf("failed to scan crosstab: %w", err)
+		}
+		// A metric is NULL when it divides by zero
+		var v *decimal.Decimal
+		if scanned.Valid {
+			rounded := numeric.RoundDecimal(scanned.Decimal)
+			v = &rounded
+		}
+		switch {
+		case rowAll == 1 && colAll == 1:
+			total = v
+		case colAll == 1:
+			rowTotals[deref(rk)] = v
+		case rowAll == 1:
+			colTotals[deref(ck)] = v
+		default:
+			cells = append(cells, crosstabCell{row: deref(rk), col: deref(ck), value: v})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return assembleCrosstab(q, value, cells, rowTotals, colTotals, total), nil
+}
+
+// crosstabCell is the aggregate of one row key × column key
+type crosstabCell struct {
+	row, col string
+	value    *decimal.Decimal
+}
+
+// assembleCrosstab lays out aggregated cells and totals as a matrix with sorted keys
+func assembleCrosstab(q *models.CrosstabQuery, value string, cells []crosstabCell, rowTotals, colTotals map[string]*decimal.Decimal, total *decimal.Decimal) *models.Crosstab {
+	ct := &models.Crosstab{
+		Rows:       q.Rows,
+		Columns:    q.Columns,
+		Value:      value,
+		RowKeys:    sortedKeys(rowTotals),
+		ColumnKeys: sortedKeys(colTotals),
+		Total:      total,
+	}
+	rowIndex := indexOf(ct.RowKeys)
+	colIndex := indexOf(ct.ColumnKeys)
+	ct.Cells = make([][]*decimal.Decimal, len(ct.RowKeys))
+	for i := range ct.Cells {
+		ct.Cells[i] = make([]*decimal.Decimal, len(ct.ColumnKeys))
+	}
+	for _, c := range cells {
+		ct.Cells[rowIndex[c.row]][colIndex[c.col]] = c.value
+	}
+	ct.RowTotals = make([]*decimal.Decimal, len(ct.RowKeys))
+	for i, k := range ct.RowKeys {
+		ct.RowTotals[i] = rowTotals[k]
+	}
+	ct.ColumnTotals = make([]*decimal.Decimal, len(ct.ColumnKeys))
+	for i, k := range ct.ColumnKeys {
+		ct.ColumnTotals[i] = colTotals[k]
+	}
+	return ct
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func sortedKeys(m map[string]*decimal.Decimal) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func indexOf(keys []string) map[string]int {
+	index := make(map[string]int, len(keys))
+	for i, k := range keys {
+		index[k] = i
+	}
+	return index
+}
+
+// GetLatestProductionDates returns the most recent production date of every
+// generator with its expected submission cadence (its own, else its type's,
+// else daily), those that never reported first and then the oldest
+func (r *postgresRepository) GetLatestProductionDates(ctx context.Context) ([]*models.GeneratorLatestProduction, error) {
+	query := `
+		SELECT g.id, t.name, COALESCE(o.name, ''), MAX(p.date)::text, (g.created_at AT TIME ZONE 'UTC')::date::text,
+		       COALESCE(gc.cadence, tc.cadence, 'daily')
+		FROM generators g
+		JOIN types t ON g.type = t.id
+		LEFT JOIN operators o ON g.operator_id = o.id
+		LEFT JOIN submission_calendar gc ON gc.generator_id = g.id
+		LEFT JOIN submission_calendar tc ON tc.type_id = t.id
+		LEFT JOIN productions p ON p.generator_id = g.id
+		GROUP BY g.id, t.name, o.name, gc.cadence, tc.cadence
+		ORDER BY MAX(p.date) NULLS FIRST, g.id`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latest production dates: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.GeneratorLatestProduction
+	for rows.Next() {
+		var l models.GeneratorLatestProduction
+		if err := rows.Scan(&l.GeneratorID, &l.TypeName, &l.OperatorName, &l.LatestDate, &l.CreatedOn, &l.Cadence); err != nil {
+			return nil, fmt.Errorf("failed to scan latest production date: %w", err)
+		}
+		list = append(list, &l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
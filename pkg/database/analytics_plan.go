@@ -0,0 +1,63 @@
+package database
+
+import (
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// AnalyticsPlanConfig represents when production analytics read the daily
+// totals instead of aggregating the production records
+type AnalyticsPlanConfig struct {
+	// SummaryThresholdDays is the widest date range, in days, aggregated from
+	// the production records; wider ranges read core.daily_totals. Negative
+	// always aggregates the records.
+	SummaryThresholdDays int
+}
+
+// LoadAnalyticsPlanConfig loads the analytics plan settings from environment variables
+func LoadAnalyticsPlanConfig() *AnalyticsPlanConfig {
+	return &AnalyticsPlanConfig{
+		SummaryThresholdDays: utils.GetEnvAsInt("ANALYTICS_SUMMARY_THRESHOLD_DAYS", 92),
+	}
+}
+
+// useSummary reports whether the range is wide enough to read the daily
+// totals. A range without start is as wide as the data and one without end
+// runs to today. The totals hold today's figures, so as-of queries always
+// read the records.
+func (cfg *AnalyticsPlanConfig) useSummary(startDate, endDate *string, asOf *time.Time) bool {
+	if cfg.SummaryThresholdDays < 0 || asOf != nil {
+		return false
+	}
+	if startDate == nil || *startDate == "" {
+		return true
+	}
+	start, err := time.Parse(time.DateOnly, *startDate)
+	if err != nil {
+		return false
+	}
+	end := time.Now().UTC()
+	if endDate != nil && *endDate != "" {
+		if end, err = time.Parse(time.DateOnly, *endDate); err != nil {
+			return false
+		}
+	}
+	return int(end.Sub(start).Hours()/24)+1 > cfg.SummaryThresholdDays
+}
+
+// productionRelation returns what production analytics over a date range
+// read: the productions p with their generator g and type t, restated to
+// asOf when it is set, or, for wide ranges, the daily totals per type as p
+// with their type t. Both have p.date and p.production_mw, so the queries
+// aggregate either alike; only days and types with records are kept.
+func (r *postgresRepository) productionRelation(startDate, endDate *string, asOf *time.Time, args []any) (string, []string, []any) {
+	if r.plan.useSummary(startDate, endDate, asOf) {
+		return `daily_totals p
+		JOIN types t ON p.type_id = t.id`, []string{"p.records > 0"}, args
+	}
+	productions, generators, args := asOfRelations(asOf, args)
+	return productions + ` p
+		JOIN ` + generators + ` g ON p.generator_id = g.id
+		JOIN types t ON g.type = t.id`, nil, args
+}
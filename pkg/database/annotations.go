@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const annotationColumns = `a.id, a.production_id, a.generator_id, a.start_date::text, a.end_date::text, a.note, a.created_at, a.updated_at`
+
+func scanAnnotation(row pgx.Row, a *models.Annotation) error {
+	return row.Scan(&a.ID, &a.ProductionID, &a.GeneratorID, &a.StartDate, &a.EndDate, &a.Note, &a.CreatedAt, &a.UpdatedAt)
+}
+
+// CreateAnnotation adds a note on a production record or a date range;
+// sql.ErrNoRows when the record or generator does not exist
+func (r *postgresRepository) CreateAnnotation(ctx context.Context, req *models.CreateAnnotationRequest) (*models.Annotation, error) {
+	endDate := req.EndDate
+	if endDate == nil {
+		endDate = req.StartDate
+	}
+	query := `
+		INSERT INTO annotations AS a (id, production_id, generator_id, start_date, end_date, note, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		RETURNING ` + annotationColumns
+
+	var a models.Annotation
+	err := scanAnnotation(r.db.QueryRow(ctx, query, uuid.New(), req.ProductionID, req.GeneratorID, req.StartDate, endDate, req.Note, time.Now()), &a)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to create annotation: %w", err)
+	}
+	return &a, nil
+}
+
+func (r *postgresRepository) GetAnnotationByID(ctx context.Context, id uuid.UUID) (*models.Annotation, error) {
+	var a models.Annotation
+	err := scanAnnotation(r.db.QueryRow(ctx, `SELECT `+annotationColumns+` FROM annotations a WHERE a.id = $1`, id), &a)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get annotation: %w", err)
+	}
+	return &a, nil
+}
+
+// GetAnnotations lists the annotations that apply to the production data of
+// filter, in date order. Annotations on a record take its generator and date;
+// fleet-wide ranges apply to every generator.
+func (r *postgresRepository) GetAnnotations(ctx context.Context, filter *models.AnnotationFilter) ([]*models.Annotation, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	if filter.ProductionID != nil {
+		args = append(args, *filter.ProductionID)
+		conds = append(conds, fmt.Sprintf(`(a.production_id = $%[1]d OR EXISTS (
+			SELECT 1 FROM productions x
+			WHERE x.id = $%[1]d AND x.date BETWEEN a.start_date AND a.end_date
+			  AND (a.generator_id IS NULL OR a.generator_id = x.generator_id)))`, len(args)))
+	}
+	if filter.GeneratorID != nil {
+		args = append(args, *filter.GeneratorID)
+		conds = append(conds, fmt.Sprintf("(COALESCE(p.generator_id, a.generator_id) = $%d OR (a.production_id IS NULL AND a.generator_id IS NULL))", len(args)))
+	}
+	if filter.StartDate != nil {
+		args = append(args, *filter.StartDate)
+		conds = append(conds, fmt.Sprintf("COALESCE(p.date, a.end_date) >= $%d", len(args)))
+	}
+	if filter.EndDate != nil {
+		args = append(args, *filter.EndDate)
+		conds = append(conds, fmt.Sprintf("COALESCE(p.date, a.start_date) <= $%d", len(args)))
+	}
+	query := `
+		SELECT ` + annotationColumns + `
+		FROM annotations a
+		LEFT JOIN productions p ON p.id = a.production_id` + whereClause(conds) + `
+		ORDER BY COALESCE(p.date, a.start_date), a.created_at, a.id`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query annotations: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.Annotation
+	for rows.Next() {
+		var a models.Annotation
+		if err := scanAnnotation(rows, &a); err != nil {
+			return nil, fmt.Errorf("failed to scan annotation: %w", err)
+		}
+		list = append(list, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+func (r *postgresRepository) UpdateAnnotation(ctx context.Context, id uuid.UUID, req *models.UpdateAnnotationRequest) (*models.Annotation, error) {
+	var a models.Annotation
+	err := scanAnnotation(r.db.QueryRow(ctx, `
+		UPDATE annotations AS a SET note = $2, updated_at = $3
+		WHERE a.id = $1
+		RETURNING `+annotationColumns, id, req.Note, time.Now()), &a)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to update annotation: %w", err)
+	}
+	return &a, nil
+}
+
+func (r *postgresRepository) DeleteAnnotation(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM annotations WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete annotation: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// attachmentTargets maps an attachment scope to its column and the table it refers to
+var attachmentTargets = map[string]struct{ column, table string }{
+	models.AttachmentScopeType:      {"type_id", "types"},
+	models.AttachmentScopeGenerator: {"generator_id", "generators"},
+}
+
+const attachmentColumns = `id, file_name, content_type, size_bytes, sha256, COALESCE(storage_key, ''), created_at`
+
+func scanAttachment(row pgx.Row, scope string, entityID uuid.UUID) (*models.Attachment, error) {
+	a := models.Attachment{Scope: scope, EntityID: entityID}
+	if err := row.Scan(&a.ID, &a.FileName, &a.ContentType, &a.SizeBytes, &a.SHA256, &a.StorageKey, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	a.Storage = models.AttachmentStorageDatabase
+	if a.StorageKey != "" {
+		a.Storage = models.AttachmentStorageObject
+	}
+	return &a, nil
+}
+
+// CreateAttachment records an attachment of a type or generator. content is
+// kept in the row when a.StorageKey is empty. It returns sql.ErrNoRows when
+// there is no such type or generator.
+func (r *postgresRepository) CreateAttachment(ctx context.Context, a *models.Attachment, content []byte) (*models.Attachment, error) {
+	target, ok := attachmentTargets[a.Scope]
+	if !ok {
+		return nil, fmt.Errorf("unknown attachment scope %q", a.Scope)
+	}
+	var key *string
+	if a.StorageKey != "" {
+		key, content = &a.StorageKey, nil
+	}
+	// Selecting from the target table turns an unknown id into no row instead of a foreign key error
+	query := fmt.Sprintf(`
+		INSERT INTO attachments (id, %[1]s, file_name, content_type, size_bytes, sha256, storage_key, content)
+		SELECT $2, id, $3, $4, $5, $6, $7, $8 FROM %[2]s WHERE id = $1
+		RETURNING `+attachmentColumns, target.column, target.table)
+
+	out, err := scanAttachment(r.db.QueryRow(ctx, query, a.EntityID, a.ID, a.FileName, a.ContentType,
+		a.SizeBytes, a.SHA256, key, content), a.Scope, a.EntityID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+	return out, nil
+}
+
+// GetAttachments lists the attachments of a type or generator, newest first;
+// sql.ErrNoRows when there is no such type or generator
+func (r *postgresRepository) GetAttachments(ctx context.Context, scope string, entityID uuid.UUID) ([]*models.Attachment, error) {
+	target, ok := attachmentTargets[scope]
+	if !ok {
+		return nil, fmt.Errorf("unknown attachment scope %q", scope)
+	}
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM `+target.table+` WHERE id = $1)`, entityID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %w", err)
+	}
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT `+attachmentColumns+`
+		FROM attachments
+		WHERE `+target.column+` = $1
+		ORDER BY created_at DESC, id`, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*models.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows, scope, entityID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		list = append(list, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// GetAttachment returns an attachment of a type or generator with the
+// content kept in its row (nil for contents in object storage)
+func (r *postgresRepository) GetAttachment(ctx context.Context, scope string, entityID, id uuid.UUID) (*models.Attachment, []byte, error) {
+	target, ok := attachmentTargets[scope]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown attachment scope %q", scope)
+	}
+	var (
+		a       models.Attachment
+		content []byte
+	)
+	err := r.db.QueryRow(ctx, `
+		SELECT `+attachmentColumns+`, content
+		FROM attachments
+		WHERE id = $1 AND `+target.column+` = $2`, id, entityID).
+		Scan(&a.ID, &a.FileName, &a.ContentType, &a.SizeBytes, &a.SHA256, &a.StorageKey, &a.CreatedAt, &content)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil, sql.ErrNoRows
+		}
+		return nil, nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	a.Scope, a.EntityID, a.Storage = scope, entityID, models.AttachmentStorageDatabase
+	if a.StorageKey != "" {
+		a.Storage = models.AttachmentStorageObject
+	}
+	return &a, content, nil
+}
+
+// DeleteAttachment deletes an attachment of a type or generator; its object,
+// if any, is queued for removal from the bucket
+func (r *postgresRepository) DeleteAttachment(ctx context.Context, scope string, entityID, id uuid.UUID) error {
+	target, ok := attachmentTargets[scope]
+	if !ok {
+		return fmt.Errorf("unknown attachment scope %q", scope)
+	}
+	result, err := r.db.Exec(ctx, `DELETE FROM attachments WHERE id = $1 AND `+target.column+` = $2`, id, entityID)
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetAttachmentObjectDeletions returns up to limit object keys left behind by
+// deleted attachments, oldest first
+func (r *postgresRepository) GetAttachmentObjectDeletions(ctx context.Context, limit int) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT storage_key FROM attachment_object_deletions
+		ORDER BY queued_at
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachment object deletions: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment object deletion: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return keys, nil
+}
+
+// ForgetAttachmentObject removes key from the deletion queue once the object is gone
+func (r *postgresRepository) ForgetAttachmentObject(ctx context.Context, key string) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM attachment_object_deletions WHERE storage_key = $1`, key); err != nil {
+		return fmt.Errorf("failed to forget attachment object: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const auditColumns = `id, entity_type, entity_id, action, before, after, user_id, impersonator_id, COALESCE(client_ip, ''), created_at`
+
+func scanAuditEntry(row pgx.Row, e *models.AuditEntry) error {
+	return row.Scan(&e.ID, &e.EntityType, &e.EntityID, &e.Action, &e.Before, &e.After, &e.UserID, &e.ImpersonatorID, &e.ClientIP, &e.CreatedAt)
+}
+
+// CreateAuditEntry records a change to an entity
+func (r *postgresRepository) CreateAuditEntry(ctx context.Context, e *models.AuditEntry) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO audit_log (entity_type, entity_id, action, before, after, user_id, impersonator_id, client_ip)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+		RETURNING id, created_at`,
+		e.EntityType, e.EntityID, e.Action, e.Before, e.After, e.UserID, e.ImpersonatorID, e.ClientIP).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create audit entry: %w", err)
+	}
+	return nil
+}
+
+// GetAuditEntry retrieves an audit entry by ID
+func (r *postgresRepository) GetAuditEntry(ctx context.Context, id int64) (*models.AuditEntry, error) {
+	var e models.AuditEntry
+	if err := scanAuditEntry(r.db.QueryRow(ctx, `SELECT `+auditColumns+` FROM audit_log WHERE id = $1`, id), &e); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get audit entry: %w", err)
+	}
+	return &e, nil
+}
+
+// GetAuditEntries lists audit entries, newest first
+func (r *postgresRepository) GetAuditEntries(ctx context.Context, filter *models.AuditFilter) ([]*models.AuditEntry, error) {
+	var conds []string
+	var args []any
+	if filter.EntityType != nil {
+		args = append(args, *filter.EntityType)
+		conds = append(conds, fmt.Sprintf("entity_type = $%d", len(args)))
+	}
+	if filter.EntityID != nil {
+		args = append(args, *filter.EntityID)
+		conds = append(conds, fmt.Sprintf("entity_id = $%d", len(args)))
+	}
+	if filter.UserID != nil {
+		args = append(args, *filter.UserID)
+		conds = append(conds, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if filter.Action != nil {
+		args = append(args, *filter.Action)
+		conds = append(conds, fmt.Sprintf("action = $%d", len(args)))
+	}
+	if filter.StartDate != nil {
+		args = append(args, *filter.StartDate)
+		conds = append(conds, fmt.Sprintf("created_at >= $%d::date", len(args)))
+	}
+	if filter.EndDate != nil {
+		args = append(args, *filter.EndDate)
+		conds = append(conds, fmt.Sprintf("created_at < $%d::date + 1", len(args)))
+	}
+	query := `SELECT ` + auditColumns + ` FROM audit_log` + whereClause(conds) + `
+		ORDER BY id DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := scanAuditEntry(rows, &e); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		list = append(list, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
@@ -0,0 +1,506 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// auditedRepository records every successful create, update and delete of
+// the audited entities in the audit log, with the entity before and after
+// the change and the principal that made it. Reads pass through.
+type auditedRepository struct {
+	Repository
+}
+
+// NewAuditedRepository wraps repo so changes are written to its audit log.
+// Failing to record an entry is logged and does not fail the change.
+func NewAuditedRepository(repo Repository) Repository {
+	return &auditedRepository{Repository: repo}
+}
+
+// audit records a change; before is nil for creates and after for deletes
+func (r *auditedRepository) audit(ctx context.Context, entityType string, id uuid.UUID, action string, before, after any) {
+	e := &models.AuditEntry{EntityType: entityType, EntityID: id, Action: action, ClientIP: auth.ClientIPFrom(ctx)}
+	if p, ok := auth.PrincipalFrom(ctx); ok {
+		userID := p.UserID
+		e.UserID = &userID
+		if p.Impersonated() {
+			impersonatorID := p.ImpersonatorID
+			e.ImpersonatorID = &impersonatorID
+		}
+	}
+	var err error
+	if e.Before, err = auditJSON(before); err == nil {
+		e.After, err = auditJSON(after)
+	}
+	if err == nil {
+		// The change is made; record it even if the request is gone
+		err = r.Repository.CreateAuditEntry(context.WithoutCancel(ctx), e)
+	}
+	if err != nil && !errors.Is(err, ErrNotSupported) {
+		utils.LogError(fmt.Sprintf("audit: %s %s %s", action, entityType, id), err)
+	}
+}
+
+// auditJSON encodes the state of an entity, nil for none
+func auditJSON(v any) (json.RawMessage, error) {
+	b, err := json.Marshal(v)
+	if err != nil || string(b) == "null" {
+		return nil, err
+	}
+	return b, nil
+}
+
+// before reads the state of an entity ahead of a change; entities that
+// cannot be read are audited without it, the change reports the error
+func before[T any](ctx context.Context, get func(context.Context, uuid.UUID) (*T, error), id uuid.UUID) *T {
+	v, err := get(ctx, id)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// ===================== Types =====================
+
+func (r *auditedRepository) CreateType(ctx context.Context, req *models.CreateTypeRequest) (*models.Type, error) {
+	t, err := r.Repository.CreateType(ctx, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityType, t.ID, models.AuditCreate, nil, t)
+	}
+	return t, err
+}
+
+func (r *auditedRepository) UpdateType(ctx context.Context, id uuid.UUID, req *models.UpdateTypeRequest) (*models.Type, error) {
+	old := before(ctx, r.Repository.GetTypeByID, id)
+	t, err := r.Repository.UpdateType(ctx, id, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityType, id, models.AuditUpdate, old, t)
+	}
+	return t, err
+}
+
+func (r *auditedRepository) DeleteType(ctx context.Context, id uuid.UUID, cascade bool) error {
+	old := before(ctx, r.Repository.GetTypeByID, id)
+	err := r.Repository.DeleteType(ctx, id, cascade)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityType, id, models.AuditDelete, old, nil)
+	}
+	return err
+}
+
+// ===================== Operators =====================
+
+func (r *auditedRepository) CreateOperator(ctx context.Context, req *models.CreateOperatorRequest) (*models.Operator, error) {
+	o, err := r.Repository.CreateOperator(ctx, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityOperator, o.ID, models.AuditCreate, nil, o)
+	}
+	return o, err
+}
+
+func (r *auditedRepository) UpdateOperator(ctx context.Context, id uuid.UUID, req *models.UpdateOperatorRequest) (*models.Operator, error) {
+	old := before(ctx, r.Repository.GetOperatorByID, id)
+	o, err := r.Repository.UpdateOperator(ctx, id, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityOperator, id, models.AuditUpdate, old, o)
+	}
+	return o, err
+}
+
+func (r *auditedRepository) DeleteOperator(ctx context.Context, id uuid.UUID) error {
+	old := before(ctx, r.Repository.GetOperatorByID, id)
+	err := r.Repository.DeleteOperator(ctx, id)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityOperator, id, models.AuditDelete, old, nil)
+	}
+	return err
+}
+
+// ===================== Plants =====================
+
+func (r *auditedRepository) CreatePlant(ctx context.Context, req *models.CreatePlantRequest) (*models.Plant, error) {
+	p, err := r.Repository.CreatePlant(ctx, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityPlant, p.ID, models.AuditCreate, nil, p)
+	}
+	return p, err
+}
+
+func (r *auditedRepository) UpdatePlant(ctx context.Context, id uuid.UUID, req *models.UpdatePlantRequest) (*models.Plant, error) {
+	old := before(ctx, r.Repository.GetPlantByID, id)
+	p, err := r.Repository.UpdatePlant(ctx, id, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityPlant, id, models.AuditUpdate, old, p)
+	}
+	return p, err
+}
+
+func (r *auditedRepository) DeletePlant(ctx context.Context, id uuid.UUID) error {
+	old := before(ctx, r.Repository.GetPlantByID, id)
+	err := r.Repository.DeletePlant(ctx, id)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityPlant, id, models.AuditDelete, old, nil)
+	}
+	return err
+}
+
+// ===================== Regions =====================
+
+func (r *auditedRepository) CreateRegion(ctx context.Context, req *models.CreateRegionRequest) (*models.Region, error) {
+	rg, err := r.Repository.CreateRegion(ctx, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityRegion, rg.ID, models.AuditCreate, nil, rg)
+	}
+	return rg, err
+}
+
+func (r *auditedRepository) UpdateRegion(ctx context.Context, id uuid.UUID, req *models.UpdateRegionRequest) (*models.Region, error) {
+	old := before(ctx, r.Repository.GetRegionByID, id)
+	rg, err := r.Repository.UpdateRegion(ctx, id, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityRegion, id, models.AuditUpdate, old, rg)
+	}
+	return rg, err
+}
+
+func (r *auditedRepository) DeleteRegion(ctx context.Context, id uuid.UUID) error {
+	old := before(ctx, r.Repository.GetRegionByID, id)
+	err := r.Repository.DeleteRegion(ctx, id)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityRegion, id, models.AuditDelete, old, nil)
+	}
+	return err
+}
+
+// ===================== Generators =====================
+
+func (r *auditedRepository) CreateGenerator(ctx context.Context, req *models.CreateGeneratorRequest) (*models.Generator, error) {
+	g, err := r.Repository.CreateGenerator(ctx, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityGenerator, g.ID, models.AuditCreate, nil, g)
+	}
+	return g, err
+}
+
+func (r *auditedRepository) UpdateGenerator(ctx context.Context, id uuid.UUID, req *models.UpdateGeneratorRequest) (*models.Generator, error) {
+	old := before(ctx, r.Repository.GetGeneratorByID, id)
+	g, err := r.Repository.UpdateGenerator(ctx, id, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityGenerator, id, models.AuditUpdate, old, g)
+	}
+	return g, err
+}
+
+func (r *auditedRepository) DeleteGenerator(ctx context.Context, id uuid.UUID, cascade bool) error {
+	old := before(ctx, r.Repository.GetGeneratorByID, id)
+	err := r.Repository.DeleteGenerator(ctx, id, cascade)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityGenerator, id, models.AuditDelete, old, nil)
+	}
+	return err
+}
+
+// ===================== Productions =====================
+
+func (r *auditedRepository) CreateProduction(ctx context.Context, req *models.CreateProductionRequest) (*models.Production, error) {
+	p, err := r.Repository.CreateProduction(ctx, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityProduction, p.ID, models.AuditCreate, nil, p)
+	}
+	return p, err
+}
+
+func (r *auditedRepository) UpsertProduction(ctx context.Context, req *models.CreateProductionRequest) (*models.Production, bool, error) {
+	var old *models.Production
+	if existing, err := r.Repository.GetAllProductions(ctx, &models.ProductionFilter{GeneratorID: &req.GeneratorID, StartDate: &req.Date, EndDate: &req.Date}); err == nil && len(existing) == 1 {
+		old = existing[0]
+	}
+	p, created, err := r.Repository.UpsertProduction(ctx, req)
+	if err == nil {
+		if created {
+			r.audit(ctx, models.AuditEntityProduction, p.ID, models.AuditCreate, nil, p)
+		} else {
+			r.audit(ctx, models.AuditEntityProduction, p.ID, models.AuditUpdate, old, p)
+		}
+	}
+	return p, created, err
+}
+
+func (r *auditedRepository) CreateProductions(ctx context.Context, reqs []*models.CreateProductionRequest) ([]*models.Production, []error, error) {
+	created, errs, err := r.Repository.CreateProductions(ctx, reqs)
+	if err == nil {
+		for i, p := range created {
+			if p != nil && errs[i] == nil {
+				r.audit(ctx, models.AuditEntityProduction, p.ID, models.AuditCreate, nil, p)
+			}
+		}
+	}
+	return created, errs, err
+}
+
+func (r *auditedRepository) UpdateProductions(ctx context.Context, updates []*models.BulkProductionUpdate, atomic bool) ([]*models.Production, []error, error) {
+	old := make([]*models.Production, len(updates))
+	for i, u := range updates {
+		old[i] = before(ctx, r.Repository.GetProductionByID, u.ID)
+	}
+	updated, errs, err := r.Repository.UpdateProductions(ctx, updates, atomic)
+	if err == nil {
+		for i, p := range updated {
+			if p != nil && errs[i] == nil {
+				r.audit(ctx, models.AuditEntityProduction, p.ID, models.AuditUpdate, old[i], p)
+			}
+		}
+	}
+	return updated, errs, err
+}
+
+func (r *auditedRepository) UpdateProduction(ctx context.Context, id uuid.UUID, req *models.UpdateProductionRequest) (*models.Production, error) {
+	old := before(ctx, r.Repository.GetProductionByID, id)
+	p, err := r.Repository.UpdateProduction(ctx, id, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityProduction, id, models.AuditUpdate, old, p)
+	}
+	return p, err
+}
+
+func (r *auditedRepository) DeleteProduction(ctx context.Context, id uuid.UUID) error {
+	old := before(ctx, r.Repository.GetProductionByID, id)
+	err := r.Repository.DeleteProduction(ctx, id)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityProduction, id, models.AuditDelete, old, nil)
+	}
+	return err
+}
+
+// ===================== Annotations =====================
+
+func (r *auditedRepository) CreateAnnotation(ctx context.Context, req *models.CreateAnnotationRequest) (*models.Annotation, error) {
+	a, err := r.Repository.CreateAnnotation(ctx, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityAnnotation, a.ID, models.AuditCreate, nil, a)
+	}
+	return a, err
+}
+
+func (r *auditedRepository) UpdateAnnotation(ctx context.Context, id uuid.UUID, req *models.UpdateAnnotationRequest) (*models.Annotation, error) {
+	old := before(ctx, r.Repository.GetAnnotationByID, id)
+	a, err := r.Repository.UpdateAnnotation(ctx, id, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityAnnotation, id, models.AuditUpdate, old, a)
+	}
+	return a, err
+}
+
+func (r *auditedRepository) DeleteAnnotation(ctx context.Context, id uuid.UUID) error {
+	old := before(ctx, r.Repository.GetAnnotationByID, id)
+	err := r.Repository.DeleteAnnotation(ctx, id)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityAnnotation, id, models.AuditDelete, old, nil)
+	}
+	return err
+}
+
+// ===================== Custom fields =====================
+
+func (r *auditedRepository) CreateCustomField(ctx context.Context, req *models.CreateCustomFieldRequest) (*models.CustomField, error) {
+	f, err := r.Repository.CreateCustomField(ctx, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityCustomField, f.ID, models.AuditCreate, nil, f)
+	}
+	return f, err
+}
+
+func (r *auditedRepository) UpdateCustomField(ctx context.Context, id uuid.UUID, req *models.UpdateCustomFieldRequest) (*models.CustomField, error) {
+	old := before(ctx, r.Repository.GetCustomFieldByID, id)
+	f, err := r.Repository.UpdateCustomField(ctx, id, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityCustomField, id, models.AuditUpdate, old, f)
+	}
+	return f, err
+}
+
+func (r *auditedRepository) DeleteCustomField(ctx context.Context, id uuid.UUID) error {
+	old := before(ctx, r.Repository.GetCustomFieldByID, id)
+	err := r.Repository.DeleteCustomField(ctx, id)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityCustomField, id, models.AuditDelete, old, nil)
+	}
+	return err
+}
+
+// ===================== Import profiles =====================
+
+func (r *auditedRepository) CreateImportProfile(ctx context.Context, req *models.CreateImportProfileRequest) (*models.ImportProfile, error) {
+	p, err := r.Repository.CreateImportProfile(ctx, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityImportProfile, p.ID, models.AuditCreate, nil, p)
+	}
+	return p, err
+}
+
+func (r *auditedRepository) UpdateImportProfile(ctx context.Context, id uuid.UUID, req *models.UpdateImportProfileRequest) (*models.ImportProfile, error) {
+	old := before(ctx, r.Repository.GetImportProfileByID, id)
+	p, err := r.Repository.UpdateImportProfile(ctx, id, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityImportProfile, id, models.AuditUpdate, old, p)
+	}
+	return p, err
+}
+
+func (r *auditedRepository) DeleteImportProfile(ctx context.Context, id uuid.UUID) error {
+	old := before(ctx, r.Repository.GetImportProfileByID, id)
+	err := r.Repository.DeleteImportProfile(ctx, id)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityImportProfile, id, models.AuditDelete, old, nil)
+	}
+	return err
+}
+
+// ===================== Reports =====================
+
+func (r *auditedRepository) CreateReport(ctx context.Context, req *models.ReportRequest, nextRunAt *time.Time) (*models.Report, error) {
+	rep, err := r.Repository.CreateReport(ctx, req, nextRunAt)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityReport, rep.ID, models.AuditCreate, nil, rep)
+	}
+	return rep, err
+}
+
+func (r *auditedRepository) UpdateReport(ctx context.Context, id uuid.UUID, req *models.ReportRequest, nextRunAt *time.Time) (*models.Report, error) {
+	old := before(ctx, r.Repository.GetReportByID, id)
+	rep, err := r.Repository.UpdateReport(ctx, id, req, nextRunAt)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityReport, id, models.AuditUpdate, old, rep)
+	}
+	return rep, err
+}
+
+func (r *auditedRepository) DeleteReport(ctx context.Context, id uuid.UUID) error {
+	old := before(ctx, r.Repository.GetReportByID, id)
+	err := r.Repository.DeleteReport(ctx, id)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityReport, id, models.AuditDelete, old, nil)
+	}
+	return err
+}
+
+func (r *auditedRepository) CreateReportTemplate(ctx context.Context, req *models.ReportTemplateRequest) (*models.ReportTemplate, error) {
+	t, err := r.Repository.CreateReportTemplate(ctx, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityReportTemplate, t.ID, models.AuditCreate, nil, t)
+	}
+	return t, err
+}
+
+func (r *auditedRepository) UpdateReportTemplate(ctx context.Context, id uuid.UUID, req *models.ReportTemplateRequest) (*models.ReportTemplate, error) {
+	old := before(ctx, r.Repository.GetReportTemplateByID, id)
+	t, err := r.Repository.UpdateReportTemplate(ctx, id, req)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityReportTemplate, id, models.AuditUpdate, old, t)
+	}
+	return t, err
+}
+
+func (r *auditedRepository) DeleteReportTemplate(ctx context.Context, id uuid.UUID) error {
+	old := before(ctx, r.Repository.GetReportTemplateByID, id)
+	err := r.Repository.DeleteReportTemplate(ctx, id)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityReportTemplate, id, models.AuditDelete, old, nil)
+	}
+	return err
+}
+
+// ===================== Users =====================
+
+// auditedUser is a user as the audit log records it: the roles only, as the
+// log is read by admins and kept longer than the accounts, so the email and
+// username are left out; the entity ID identifies the account
+type auditedUser struct {
+	Roles []string `json:"roles"`
+}
+
+func auditUser(u *models.User) *auditedUser {
+	if u == nil {
+		return nil
+	}
+	return &auditedUser{Roles: u.Roles}
+}
+
+func (r *auditedRepository) CreateUser(ctx context.Context, req *models.RegisterRequest, passwordHash string, roles []string) (*models.User, error) {
+	u, err := r.Repository.CreateUser(ctx, req, passwordHash, roles)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityUser, u.ID, models.AuditCreate, nil, auditUser(u))
+	}
+	return u, err
+}
+
+func (r *auditedRepository) CreateFirstUser(ctx context.Context, req *models.RegisterRequest, passwordHash string) (*models.User, error) {
+	u, err := r.Repository.CreateFirstUser(ctx, req, passwordHash)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityUser, u.ID, models.AuditCreate, nil, auditUser(u))
+	}
+	return u, err
+}
+
+func (r *auditedRepository) SetUserRoles(ctx context.Context, id uuid.UUID, roles []string) (*models.User, error) {
+	old := before(ctx, r.Repository.GetUserByID, id)
+	u, err := r.Repository.SetUserRoles(ctx, id, roles)
+	if err == nil {
+		r.audit(ctx, models.AuditEntityUser, id, models.AuditUpdate, auditUser(old), auditUser(u))
+	}
+	return u, err
+}
+
+// Enabling and removing two-factor authentication are audited as updates of
+// the user; the secret and backup codes are left out
+func (r *auditedRepository) EnableTwoFactor(ctx context.Context, userID uuid.UUID, step int64, codeHashes []string) error {
+	if err := r.Repository.EnableTwoFactor(ctx, userID, step, codeHashes); err != nil {
+		return err
+	}
+	r.audit(ctx, models.AuditEntityUser, userID, models.AuditUpdate, map[string]any{"twoFactor": false}, map[string]any{"twoFactor": true})
+	return nil
+}
+
+func (r *auditedRepository) DeleteTwoFactor(ctx context.Context, userID uuid.UUID) error {
+	old, err := r.Repository.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := r.Repository.DeleteTwoFactor(ctx, userID); err != nil {
+		return err
+	}
+	// Pending enrollments were never in force
+	if old.Enabled() {
+		r.audit(ctx, models.AuditEntityUser, userID, models.AuditUpdate, map[string]any{"twoFactor": true}, map[string]any{"twoFactor": false})
+	}
+	return nil
+}
+
+// GrantOperator and RevokeOperator are audited as updates of the user, with
+// its operator grants before and after
+func (r *auditedRepository) GrantOperator(ctx context.Context, userID, operatorID uuid.UUID) error {
+	return r.changeGrants(ctx, userID, func() error { return r.Repository.GrantOperator(ctx, userID, operatorID) })
+}
+
+func (r *auditedRepository) RevokeOperator(ctx context.Context, userID, operatorID uuid.UUID) error {
+	return r.changeGrants(ctx, userID, func() error { return r.Repository.RevokeOperator(ctx, userID, operatorID) })
+}
+
+func (r *auditedRepository) changeGrants(ctx context.Context, userID uuid.UUID, change func() error) error {
+	old, _ := r.Repository.GetOperatorGrants(ctx, userID)
+	if err := change(); err != nil {
+		return err
+	}
+	grants, _ := r.Repository.GetOperatorGrants(ctx, userID)
+	r.audit(ctx, models.AuditEntityUser, userID, models.AuditUpdate, map[string]any{"operatorGrants": old}, map[string]any{"operatorGrants": grants})
+	return nil
+}
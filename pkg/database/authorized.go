@@ -0,0 +1,586 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+)
+
+// authorizedRepository enforces per-operator write permissions of the request
+// principal before delegating to the wrapped repository. Reads pass through.
+type authorizedRepository struct {
+	Repository
+}
+
+// NewAuthorizedRepository wraps repo so every write is checked against the
+// principal attached to the context (see auth.WithPrincipal) by the
+// Authenticator. Requests without principal, which only reach the repository
+// when authentication is disabled or from the server itself, are not
+// restricted.
+func NewAuthorizedRepository(repo Repository) Repository {
+	return &authorizedRepository{Repository: repo}
+}
+
+// LoadPrincipal builds the principal of a user from its roles and operator
+// grants; sql.ErrNoRows when the user does not exist
+func LoadPrincipal(ctx context.Context, repo Repository, userID uuid.UUID) (*auth.Principal, error) {
+	user, err := repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	grants, err := repo.GetOperatorGrants(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(grants))
+	for _, g := range grants {
+		ids = append(ids, g.OperatorID)
+	}
+	return auth.NewPrincipal(userID, user.Roles, ids), nil
+}
+
+// requireUnscoped rejects scoped principals, which may only write their own generators
+func requireUnscoped(ctx context.Context, resource string) error {
+	if p, ok := auth.PrincipalFrom(ctx); ok && p.Scoped() {
+		return fmt.Errorf("%w to %s: only unrestricted users can change them", auth.ErrForbidden, resource)
+	}
+	return nil
+}
+
+// requireAdmin rejects principals without the admin role, for writes that
+// reach the generators and productions of every operator at once
+func requireAdmin(ctx context.Context, what string) error {
+	if p, ok := auth.PrincipalFrom(ctx); ok && !p.HasRole(auth.RoleAdmin) {
+		return fmt.Errorf("%w to %s: only admins can do it, as it changes generators of every operator", auth.ErrForbidden, what)
+	}
+	return nil
+}
+
+// requireOperator checks that the principal may write generators of operatorID
+func requireOperator(ctx context.Context, operatorID *uuid.UUID) error {
+	if p, ok := auth.PrincipalFrom(ctx); ok && !p.CanWriteOperator(operatorID) {
+		return fmt.Errorf("%w to generators of this operator", auth.ErrForbidden)
+	}
+	return nil
+}
+
+// requireGenerator checks write access to an existing generator
+func (r *authorizedRepository) requireGenerator(ctx context.Context, id uuid.UUID) error {
+	if p, ok := auth.PrincipalFrom(ctx); !ok || p.HasRole(auth.RoleAdmin) {
+		return nil
+	}
+	gen, err := r.Repository.GetGeneratorByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return requireOperator(ctx, gen.OperatorID)
+}
+
+// requireProduction checks write access to the generator of an existing production
+func (r *authorizedRepository) requireProduction(ctx context.Context, id uuid.UUID) error {
+	if p, ok := auth.PrincipalFrom(ctx); !ok || p.HasRole(auth.RoleAdmin) {
+		return nil
+	}
+	pr, err := r.Repository.GetProductionByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return r.requireGenerator(ctx, pr.GeneratorID)
+}
+
+func (r *authorizedRepository) CreateType(ctx context.Context, req *models.CreateTypeRequest) (*models.Type, error) {
+	if err := requireUnscoped(ctx, "types"); err != nil {
+		return nil, err
+	}
+	return r.Repository.CreateType(ctx, req)
+}
+
+func (r *authorizedRepository) UpdateType(ctx context.Context, id uuid.UUID, req *models.UpdateTypeRequest) (*models.Type, error) {
+	if err := requireUnscoped(ctx, "types"); err != nil {
+		return nil, err
+	}
+	return r.Repository.UpdateType(ctx, id, req)
+}
+
+func (r *authorizedRepository) DeleteType(ctx context.Context, id uuid.UUID, cascade bool) error {
+	if err := requireUnscoped(ctx, "types"); err != nil {
+		return err
+	}
+	if cascade {
+		if err := requireAdmin(ctx, "cascade delete types"); err != nil {
+			return err
+		}
+	}
+	return r.Repository.DeleteType(ctx, id, cascade)
+}
+
+func (r *authorizedRepository) SetTypeTranslation(ctx context.Context, typeID uuid.UUID, language string, req *models.TypeTranslationRequest) (*models.TypeTranslation, error) {
+	if err := requireUnscoped(ctx, "types"); err != nil {
+		return nil, err
+	}
+	return r.Repository.SetTypeTranslation(ctx, typeID, language, req)
+}
+
+func (r *authorizedRepository) DeleteTypeTranslation(ctx context.Context, typeID uuid.UUID, language string) error {
+	if err := requireUnscoped(ctx, "types"); err != nil {
+		return err
+	}
+	return r.Repository.DeleteTypeTranslation(ctx, typeID, language)
+}
+
+func (r *authorizedRepository) MergeType(ctx context.Context, sourceID, targetID uuid.UUID) (*models.TypeMergeResult, error) {
+	if err := requireAdmin(ctx, "merge types"); err != nil {
+		return nil, err
+	}
+	return r.Repository.MergeType(ctx, sourceID, targetID)
+}
+
+func (r *authorizedRepository) CreateOperator(ctx context.Context, req *models.CreateOperatorRequest) (*models.Operator, error) {
+	if err := requireUnscoped(ctx, "operators"); err != nil {
+		return nil, err
+	}
+	return r.Repository.CreateOperator(ctx, req)
+}
+
+func (r *authorizedRepository) UpdateOperator(ctx context.Context, id uuid.UUID, req *models.UpdateOperatorRequest) (*models.Operator, error) {
+	if err := requireUnscoped(ctx, "operators"); err != nil {
+		return nil, err
+	}
+	return r.Repository.UpdateOperator(ctx, id, req)
+}
+
+func (r *authorizedRepository) DeleteOperator(ctx context.Context, id uuid.UUID) error {
+	if err := requireUnscoped(ctx, "operators"); err != nil {
+		return err
+	}
+	return r.Repository.DeleteOperator(ctx, id)
+}
+
+func (r *authorizedRepository) CreatePlant(ctx context.Context, req *models.CreatePlantRequest) (*models.Plant, error) {
+	if err := requireUnscoped(ctx, "plants"); err != nil {
+		return nil, err
+	}
+	return r.Repository.CreatePlant(ctx, req)
+}
+
+func (r *authorizedRepository) UpdatePlant(ctx context.Context, id uuid.UUID, req *models.UpdatePlantRequest) (*models.Plant, error) {
+	if err := requireUnscoped(ctx, "plants"); err != nil {
+		return nil, err
+	}
+	return r.Repository.UpdatePlant(ctx, id, req)
+}
+
+func (r *authorizedRepository) DeletePlant(ctx context.Context, id uuid.UUID) error {
+	if err := requireUnscoped(ctx, "plants"); err != nil {
+		return err
+	}
+	return r.Repository.DeletePlant(ctx, id)
+}
+
+func (r *authorizedRepository) CreateRegion(ctx context.Context, req *models.CreateRegionRequest) (*models.Region, error) {
+	if err := requireUnscoped(ctx, "regions"); err != nil {
+		return nil, err
+	}
+	return r.Repository.CreateRegion(ctx, req)
+}
+
+func (r *authorizedRepository) UpdateRegion(ctx context.Context, id uuid.UUID, req *models.UpdateRegionRequest) (*models.Region, error) {
+	if err := requireUnscoped(ctx, "regions"); err != nil {
+		return nil, err
+	}
+	return r.Repository.UpdateRegion(ctx, id, req)
+}
+
+func (r *authorizedRepository) DeleteRegion(ctx context.Context, id uuid.UUID) error {
+	if err := requireUnscoped(ctx, "regions"); err != nil {
+		return err
+	}
+	return r.Repository.DeleteRegion(ctx, id)
+}
+
+func (r *authorizedRepository) CreateGenerator(ctx context.Context, req *models.CreateGeneratorRequest) (*models.Generator, error) {
+	if err := requireOperator(ctx, req.OperatorID); err != nil {
+		return nil, err
+	}
+	return r.Repository.CreateGenerator(ctx, req)
+}
+
+func (r *authorizedRepository) UpdateGenerator(ctx context.Context, id uuid.UUID, req *models.UpdateGeneratorRequest) (*models.Generator, error) {
+	if err := r.requireGenerator(ctx, id); err != nil {
+		return nil, err
+	}
+	// Moving a generator to another operator requires access to that operator
+	// too, and clearing it with a PATCH is left to unscoped users
+	if req.OperatorID != nil || req.Fields.Has("operatorId") {
+		if err := requireOperator(ctx, req.OperatorID); err != nil {
+			return nil, err
+		}
+	}
+	return r.Repository.UpdateGenerator(ctx, id, req)
+}
+
+func (r *authorizedRepository) DeleteGenerator(ctx context.Context, id uuid.UUID, cascade bool) error {
+	if err := r.requireGenerator(ctx, id); err != nil {
+		return err
+	}
+	return r.Repository.DeleteGenerator(ctx, id, cascade)
+}
+
+func (r *authorizedRepository) CreateProduction(ctx context.Context, req *models.CreateProductionRequest) (*models.Production, error) {
+	if err := r.requireGenerator(ctx, req.GeneratorID); err != nil {
+		return nil, err
+	}
+	return r.Repository.CreateProduction(ctx, req)
+}
+
+func (r *authorizedRepository) UpsertProduction(ctx context.Context, req *models.CreateProductionRequest) (*models.Production, bool, error) {
+	if err := r.requireGenerator(ctx, req.GeneratorID); err != nil {
+		return nil, false, err
+	}
+	return r.Repository.UpsertProduction(ctx, req)
+}
+
+// CreateProductions reports the records of generators the principal may not
+// write as forbidden and creates the others
+func (r *authorizedRepository) CreateProductions(ctx context.Context, reqs []*models.CreateProductionRequest) ([]*models.Production, []error, error) {
+	created := make([]*models.Production, len(reqs))
+	errs := make([]error, len(reqs))
+	checked := map[uuid.UUID]error{}
+	var allowed []*models.CreateProductionRequest
+	var at []int
+	for i, req := range reqs {
+		err, ok := checked[req.GeneratorID]
+		if !ok {
+			err = r.requireGenerator(ctx, req.GeneratorID)
+			checked[req.GeneratorID] = err
+		}
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		allowed = append(allowed, req)
+		at = append(at, i)
+	}
+
+	inner, innerErrs, err := r.Repository.CreateProductions(ctx, allowed)
+	if err != nil {
+		return nil, nil, err
+	}
+	for j, i := range at {
+		created[i], errs[i] = inner[j], innerErrs[j]
+	}
+	return created, errs, nil
+}
+
+// UpdateProductions reports the records the principal may not write, or
+// moved to generators it may not write, as forbidden. An atomic request with
+// such records is not applied.
+func (r *authorizedRepository) UpdateProductions(ctx context.Context, updates []*models.BulkProductionUpdate, atomic bool) ([]*models.Production, []error, error) {
+	updated := make([]*models.Production, len(updates))
+	errs := make([]error, len(updates))
+	var allowed []*models.BulkProductionUpdate
+	var at []int
+	for i, u := range updates {
+		err := r.requireProduction(ctx, u.ID)
+		if err == sql.ErrNoRows {
+			err = errUnknownProduction
+		}
+		if err == nil && u.Changes.GeneratorID != nil {
+			if err = r.requireGenerator(ctx, *u.Changes.GeneratorID); err == sql.ErrNoRows {
+				err = errUnknownGenerator
+			}
+		}
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		allowed = append(allowed, u)
+		at = append(at, i)
+	}
+	if atomic && len(allowed) < len(updates) {
+		rollBackBulk(updated, errs)
+		return updated, errs, nil
+	}
+
+	inner, innerErrs, err := r.Repository.UpdateProductions(ctx, allowed, atomic)
+	if err != nil {
+		return nil, nil, err
+	}
+	for j, i := range at {
+		updated[i], errs[i] = inner[j], innerErrs[j]
+	}
+	return updated, errs, nil
+}
+
+func (r *authorizedRepository) UpdateProduction(ctx context.Context, id uuid.UUID, req *models.UpdateProductionRequest) (*models.Production, error) {
+	if err := r.requireProduction(ctx, id); err != nil {
+		return nil, err
+	}
+	if req.GeneratorID != nil {
+		if err := r.requireGenerator(ctx, *req.GeneratorID); err != nil {
+			return nil, err
+		}
+	}
+	return r.Repository.UpdateProduction(ctx, id, req)
+}
+
+func (r *authorizedRepository) RecordTelemetry(ctx context.Context, reading *models.TelemetryReading) (*models.Production, error) {
+	if err := r.requireGenerator(ctx, reading.GeneratorID); err != nil {
+		return nil, err
+	}
+	return r.Repository.RecordTelemetry(ctx, reading)
+}
+
+func (r *authorizedRepository) DeleteProduction(ctx context.Context, id uuid.UUID) error {
+	if err := r.requireProduction(ctx, id); err != nil {
+		return err
+	}
+	return r.Repository.DeleteProduction(ctx, id)
+}
+
+func (r *authorizedRepository) PublishSnapshot(ctx context.Context, month time.Time) (*models.Snapshot, error) {
+	if err := requireUnscoped(ctx, "snapshots"); err != nil {
+		return nil, err
+	}
+	return r.Repository.PublishSnapshot(ctx, month)
+}
+
+func (r *authorizedRepository) ApplyCorrection(ctx context.Context, productionID uuid.UUID, req *models.CreateCorrectionRequest) (*models.ProductionCorrection, error) {
+	if err := r.requireProduction(ctx, productionID); err != nil {
+		return nil, err
+	}
+	return r.Repository.ApplyCorrection(ctx, productionID, req)
+}
+
+func (r *authorizedRepository) PreviewRecalculation(ctx context.Context, req *models.RecalculationRequest) (*models.Recalculation, error) {
+	if err := requireUnscoped(ctx, "recalculations"); err != nil {
+		return nil, err
+	}
+	return r.Repository.PreviewRecalculation(ctx, req)
+}
+
+func (r *authorizedRepository) ApplyRecalculation(ctx context.Context, req *models.RecalculationRequest, userID *uuid.UUID) (*models.Recalculation, error) {
+	if err := requireUnscoped(ctx, "recalculations"); err != nil {
+		return nil, err
+	}
+	return r.Repository.ApplyRecalculation(ctx, req, userID)
+}
+
+func (r *authorizedRepository) GrantOperator(ctx context.Context, userID, operatorID uuid.UUID) error {
+	if err := requireUnscoped(ctx, "operator grants"); err != nil {
+		return err
+	}
+	return r.Repository.GrantOperator(ctx, userID, operatorID)
+}
+
+func (r *authorizedRepository) RevokeOperator(ctx context.Context, userID, operatorID uuid.UUID) error {
+	if err := requireUnscoped(ctx, "operator grants"); err != nil {
+		return err
+	}
+	return r.Repository.RevokeOperator(ctx, userID, operatorID)
+}
+
+func (r *authorizedRepository) CreateReport(ctx context.Context, req *models.ReportRequest, nextRunAt *time.Time) (*models.Report, error) {
+	if err := requireUnscoped(ctx, "reports"); err != nil {
+		return nil, err
+	}
+	return r.Repository.CreateReport(ctx, req, nextRunAt)
+}
+
+func (r *authorizedRepository) UpdateReport(ctx context.Context, id uuid.UUID, req *models.ReportRequest, nextRunAt *time.Time) (*models.Report, error) {
+	if err := requireUnscoped(ctx, "reports"); err != nil {
+		return nil, err
+	}
+	return r.Repository.UpdateReport(ctx, id, req, nextRunAt)
+}
+
+func (r *authorizedRepository) DeleteReport(ctx context.Context, id uuid.UUID) error {
+	if err := requireUnscoped(ctx, "reports"); err != nil {
+		return err
+	}
+	return r.Repository.DeleteReport(ctx, id)
+}
+
+func (r *authorizedRepository) RotateReportWebhookSecret(ctx context.Context, reportID uuid.UUID) (*models.WebhookSecret, error) {
+	if err := requireUnscoped(ctx, "reports"); err != nil {
+		return nil, err
+	}
+	return r.Repository.RotateReportWebhookSecret(ctx, reportID)
+}
+
+func (r *authorizedRepository) CreateReportTemplate(ctx context.Context, req *models.ReportTemplateRequest) (*models.ReportTemplate, error) {
+	if err := requireUnscoped(ctx, "report templates"); err != nil {
+		return nil, err
+	}
+	return r.Repository.CreateReportTemplate(ctx, req)
+}
+
+func (r *authorizedRepository) UpdateReportTemplate(ctx context.Context, id uuid.UUID, req *models.ReportTemplateRequest) (*models.ReportTemplate, error) {
+	if err := requireUnscoped(ctx, "report templates"); err != nil {
+		return nil, err
+	}
+	return r.Repository.UpdateReportTemplate(ctx, id, req)
+}
+
+func (r *authorizedRepository) DeleteReportTemplate(ctx context.Context, id uuid.UUID) error {
+	if err := requireUnscoped(ctx, "report templates"); err != nil {
+		return err
+	}
+	return r.Repository.DeleteReportTemplate(ctx, id)
+}
+
+func (r *authorizedRepository) CreateEmailTemplateVersion(ctx context.Context, key string, req *models.EmailTemplateRequest, userID *uuid.UUID) (*models.EmailTemplate, error) {
+	if err := requireUnscoped(ctx, "email templates"); err != nil {
+		return nil, err
+	}
+	return r.Repository.CreateEmailTemplateVersion(ctx, key, req, userID)
+}
+
+func (r *authorizedRepository) SetSubmissionCadence(ctx context.Context, scope string, id uuid.UUID, cadence string) error {
+	if err := requireUnscoped(ctx, "submission calendar"); err != nil {
+		return err
+	}
+	return r.Repository.SetSubmissionCadence(ctx, scope, id, cadence)
+}
+
+func (r *authorizedRepository) DeleteSubmissionCadence(ctx context.Context, scope string, id uuid.UUID) error {
+	if err := requireUnscoped(ctx, "submission calendar"); err != nil {
+		return err
+	}
+	return r.Repository.DeleteSubmissionCadence(ctx, scope, id)
+}
+
+// requireAttachmentScope checks write access to the type or generator attachments belong to
+func (r *authorizedRepository) requireAttachmentScope(ctx context.Context, scope string, entityID uuid.UUID) error {
+	if scope == models.AttachmentScopeGenerator {
+		return r.requireGenerator(ctx, entityID)
+	}
+	return requireUnscoped(ctx, "types")
+}
+
+func (r *authorizedRepository) CreateAttachment(ctx context.Context, a *models.Attachment, content []byte) (*models.Attachment, error) {
+	if err := r.requireAttachmentScope(ctx, a.Scope, a.EntityID); err != nil {
+		return nil, err
+	}
+	return r.Repository.CreateAttachment(ctx, a, content)
+}
+
+func (r *authorizedRepository) DeleteAttachment(ctx context.Context, scope string, entityID, id uuid.UUID) error {
+	if err := r.requireAttachmentScope(ctx, scope, entityID); err != nil {
+		return err
+	}
+	return r.Repository.DeleteAttachment(ctx, scope, entityID, id)
+}
+
+// requireAnnotationTarget checks write access to what an annotation is on:
+// the generator of its record, its generator, or the whole fleet
+func (r *authorizedRepository) requireAnnotationTarget(ctx context.Context, productionID, generatorID *uuid.UUID) error {
+	switch {
+	case productionID != nil:
+		return r.requireProduction(ctx, *productionID)
+	case generatorID != nil:
+		return r.requireGenerator(ctx, *generatorID)
+	}
+	return requireUnscoped(ctx, "fleet-wide annotations")
+}
+
+// requireAnnotation checks write access to an existing annotation
+func (r *authorizedRepository) requireAnnotation(ctx context.Context, id uuid.UUID) error {
+	if p, ok := auth.PrincipalFrom(ctx); !ok || p.HasRole(auth.RoleAdmin) {
+		return nil
+	}
+	a, err := r.Repository.GetAnnotationByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return r.requireAnnotationTarget(ctx, a.ProductionID, a.GeneratorID)
+}
+
+func (r *authorizedRepository) CreateAnnotation(ctx context.Context, req *models.CreateAnnotationRequest) (*models.Annotation, error) {
+	if err := r.requireAnnotationTarget(ctx, req.ProductionID, req.GeneratorID); err != nil {
+		return nil, err
+	}
+	return r.Repository.CreateAnnotation(ctx, req)
+}
+
+func (r *authorizedRepository) UpdateAnnotation(ctx context.Context, id uuid.UUID, req *models.UpdateAnnotationRequest) (*models.Annotation, error) {
+	if err := r.requireAnnotation(ctx, id); err != nil {
+		return nil, err
+	}
+	return r.Repository.UpdateAnnotation(ctx, id, req)
+}
+
+func (r *authorizedRepository) DeleteAnnotation(ctx context.Context, id uuid.UUID) error {
+	if err := r.requireAnnotation(ctx, id); err != nil {
+		return err
+	}
+	return r.Repository.DeleteAnnotation(ctx, id)
+}
+
+func (r *authorizedRepository) RebuildProjection(ctx context.Context, name string) (*models.Projection, error) {
+	if err := requireUnscoped(ctx, "projections"); err != nil {
+		return nil, err
+	}
+	return r.Repository.RebuildProjection(ctx, name)
+}
+
+func (r *authorizedRepository) RestoreTrashItem(ctx context.Context, id uuid.UUID) (*models.TrashItem, error) {
+	if err := requireAdmin(ctx, "restore from the trash"); err != nil {
+		return nil, err
+	}
+	return r.Repository.RestoreTrashItem(ctx, id)
+}
+
+func (r *authorizedRepository) PurgeTrashItem(ctx context.Context, id uuid.UUID) error {
+	if err := requireUnscoped(ctx, "trash"); err != nil {
+		return err
+	}
+	return r.Repository.PurgeTrashItem(ctx, id)
+}
+
+func (r *authorizedRepository) CreateCustomField(ctx context.Context, req *models.CreateCustomFieldRequest) (*models.CustomField, error) {
+	if err := requireUnscoped(ctx, "custom fields"); err != nil {
+		return nil, err
+	}
+	return r.Repository.CreateCustomField(ctx, req)
+}
+
+func (r *authorizedRepository) UpdateCustomField(ctx context.Context, id uuid.UUID, req *models.UpdateCustomFieldRequest) (*models.CustomField, error) {
+	if err := requireUnscoped(ctx, "custom fields"); err != nil {
+		return nil, err
+	}
+	return r.Repository.UpdateCustomField(ctx, id, req)
+}
+
+func (r *authorizedRepository) DeleteCustomField(ctx context.Context, id uuid.UUID) error {
+	if err := requireUnscoped(ctx, "custom fields"); err != nil {
+		return err
+	}
+	return r.Repository.DeleteCustomField(ctx, id)
+}
+
+func (r *authorizedRepository) CreateImportProfile(ctx context.Context, req *models.CreateImportProfileRequest) (*models.ImportProfile, error) {
+	if err := requireUnscoped(ctx, "import profiles"); err != nil {
+		return nil, err
+	}
+	return r.Repository.CreateImportProfile(ctx, req)
+}
+
+func (r *authorizedRepository) UpdateImportProfile(ctx context.Context, id uuid.UUID, req *models.UpdateImportProfileRequest) (*models.ImportProfile, error) {
+	if err := requireUnscoped(ctx, "import profiles"); err != nil {
+		return nil, err
+	}
+	return r.Repository.UpdateImportProfile(ctx, id, req)
+}
+
+func (r *authorizedRepository) DeleteImportProfile(ctx context.Context, id uuid.UUID) error {
+	if err := requireUnscoped(ctx, "import profiles"); err != nil {
+		return err
+	}
+	return r.Repository.DeleteImportProfile(ctx, id)
+}
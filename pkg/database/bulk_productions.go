@@ -0,0 +1,267 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/provenance"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrDuplicateProduction is returned for a production of a generator on a
+// date that already has one
+var ErrDuplicateProduction = errors.New("generator already has a production on this date")
+
+// ErrInvalidDate is returned for a production date that is not YYYY-MM-DD
+var ErrInvalidDate = fmt.Errorf("invalid date: %w", utils.ErrInvalidDate)
+
+// productionDate parses the date of a production for the date column, so no
+// string reaches PostgreSQL, whose own parsing would take 03/09/2025 too
+func productionDate(date string) (time.Time, error) {
+	t, err := utils.ParseDate(date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w (%q)", ErrInvalidDate, date)
+	}
+	return t, nil
+}
+
+// optionalProductionDate is productionDate for the date of an update, nil
+// when it is left unchanged
+func optionalProductionDate(date *string) (*time.Time, error) {
+	if date == nil {
+		return nil, nil
+	}
+	t, err := productionDate(*date)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ErrBulkRolledBack is reported for the records of an atomic bulk request
+// that succeeded but were rolled back because another record failed
+var ErrBulkRolledBack = errors.New("not applied: another record of the atomic request failed")
+
+// Bulk record errors naming what does not exist; they match sql.ErrNoRows
+var (
+	errUnknownGenerator  = notFoundError("generator does not exist")
+	errUnknownProduction = notFoundError("production does not exist")
+)
+
+type notFoundError string
+
+func (e notFoundError) Error() string { return string(e) }
+
+func (e notFoundError) Is(target error) bool { return target == sql.ErrNoRows }
+
+// CreateProductions inserts many productions in one transaction. Each record
+// is written under its own savepoint, so a record that fails (unknown
+// generator, duplicate date, published month) is reported in errs at its
+// index and the others are still created. err is set only when the whole
+// batch failed and nothing was created.
+func (r *postgresRepository) CreateProductions(ctx context.Context, reqs []*models.CreateProductionRequest) ([]*models.Production, []error, error) {
+	created := make([]*models.Production, len(reqs))
+	errs := make([]error, len(reqs))
+	if len(reqs) == 0 {
+		return created, errs, nil
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO productions (id, generator_id, date, production_mw, source, source_ref, custom_attributes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5, ''), 'api'), NULLIF($6, ''), jsonb_strip_nulls(COALESCE($8::jsonb, '{}')), $7, $7)`
+	now := time.Now()
+	ids := make([]uuid.UUID, 0, len(reqs))
+	at := make(map[uuid.UUID]int, len(reqs))
+	for i, req := range reqs {
+		date, err := productionDate(req.Date)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		id := uuid.New()
+		// Begin on a transaction creates a savepoint
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create savepoint: %w", err)
+		}
+		_, err = sp.Exec(ctx, query, id, req.GeneratorID, date, req.ProductionMW, req.Source, req.SourceRef, now, attributesParam(req.CustomAttributes))
+		if err == nil && provenance.Enabled() {
+			err = signProduction(ctx, sp, id)
+		}
+		if err != nil {
+			if rbErr := sp.Rollback(ctx); rbErr != nil {
+				return nil, nil, fmt.Errorf("failed to roll back savepoint: %w", rbErr)
+			}
+			errs[i] = bulkProductionError(err)
+			continue
+		}
+		if err := sp.Commit(ctx); err != nil {
+			return nil, nil, fmt.Errorf("failed to release savepoint: %w", err)
+		}
+		ids = append(ids, id)
+		at[id] = i
+	}
+
+	written, err := productionsByID(ctx, tx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	for id, p := range written {
+		created[at[id]] = p
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit productions: %w", err)
+	}
+	return created, errs, nil
+}
+
+// UpdateProductions applies many updates in one transaction, each under its
+// own savepoint like CreateProductions. With atomic, the transaction is
+// rolled back when any update failed.
+func (r *postgresRepository) UpdateProductions(ctx context.Context, updates []*models.BulkProductionUpdate, atomic bool) ([]*models.Production, []error, error) {
+	updated := make([]*models.Production, len(updates))
+	errs := make([]error, len(updates))
+	if len(updates) == 0 {
+		return updated, errs, nil
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	var ids []uuid.UUID
+	failed := false
+	for i, u := range updates {
+		req := u.Changes
+		date, err := optionalProductionDate(req.Date)
+		if err != nil {
+			errs[i], failed = err, true
+			continue
+		}
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create savepoint: %w", err)
+		}
+		tag, err := sp.Exec(ctx, updateProductionQuery, u.ID, req.GeneratorID, date, req.ProductionMW, req.Source, req.SourceRef, now, attributesParam(req.CustomAttributes))
+		if err == nil && tag.RowsAffected() == 0 {
+			err = errUnknownProduction
+		}
+		if err == nil && provenance.Enabled() {
+			err = signProduction(ctx, sp, u.ID)
+		}
+		if err != nil {
+			if rbErr := sp.Rollback(ctx); rbErr != nil {
+				return nil, nil, fmt.Errorf("failed to roll back savepoint: %w", rbErr)
+			}
+			if !errors.Is(err, errUnknownProduction) {
+				err = bulkProductionError(err)
+			}
+			errs[i], failed = err, true
+			continue
+		}
+		if err := sp.Commit(ctx); err != nil {
+			return nil, nil, fmt.Errorf("failed to release savepoint: %w", err)
+		}
+		ids = append(ids, u.ID)
+	}
+	if atomic && failed {
+		rollBackBulk(updated, errs)
+		return updated, errs, nil
+	}
+
+	written, err := productionsByID(ctx, tx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, u := range updates {
+		if errs[i] == nil {
+			updated[i] = written[u.ID]
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit productions: %w", err)
+	}
+	return updated, errs, nil
+}
+
+// rollBackBulk marks the records of an atomic bulk request that succeeded as
+// rolled back
+func rollBackBulk(written []*models.Production, errs []error) {
+	for i := range errs {
+		if errs[i] == nil {
+			written[i], errs[i] = nil, ErrBulkRolledBack
+		}
+	}
+}
+
+// productionsByID reads the productions with the IDs within tx, keyed by ID
+func productionsByID(ctx context.Context, tx pgx.Tx, ids []uuid.UUID) (map[uuid.UUID]*models.Production, error) {
+	list := make(map[uuid.UUID]*models.Production, len(ids))
+	if len(ids) == 0 {
+		return list, nil
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT p.id, p.generator_id, g.capacity, t.name, t.isrenuevable, p.date, p.production_mw,
+		       p.source, COALESCE(p.source_ref, ''), COALESCE(p.signature, ''), p.custom_attributes, p.created_at, p.updated_at, p.version
+		FROM productions p
+		JOIN generators g ON p.generator_id = g.id
+		JOIN types t ON g.type = t.id
+		WHERE p.id = ANY($1)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query written productions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.Production
+		if err := scanProduction(rows, &p); err != nil {
+			return nil, fmt.Errorf("failed to scan production: %w", err)
+		}
+		list[p.ID] = &p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// bulkProductionError maps the database error of one bulk record
+func bulkProductionError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23503":
+			return errUnknownGenerator
+		case "23505":
+			return ErrDuplicateProduction
+		}
+	}
+	return fmt.Errorf("failed to create production: %w", publishedError(err))
+}
+
+// duplicateError maps the violation of the one record per generator and
+// date, and writes to published months
+func duplicateError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDuplicateProduction
+	}
+	return publishedError(err)
+}
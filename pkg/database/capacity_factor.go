@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// capacityFactorPeriod is the production of a generator in one bucket
+type capacityFactorPeriod struct {
+	bucket     time.Time
+	production decimal.Decimal
+	days       int64
+}
+
+// buildCapacityFactorTrend lays the periods out on their buckets, computes
+// the capacity factor of each and smooths it
+func buildCapacityFactorTrend(g *models.Generator, q *models.CapacityFactorQuery, periods []capacityFactorPeriod) (*models.CapacityFactorTrend, error) {
+	trend := &models.CapacityFactorTrend{
+		GeneratorID: g.ID,
+		Capacity:    numeric.RoundDecimal(g.Capacity),
+		GroupBy:     q.GroupBy,
+		Smoothing:   q.Smoothing,
+		Points:      []*models.CapacityFactorPoint{},
+	}
+	if q.Smoothing != models.SmoothingNone {
+		trend.Window = q.Window
+	}
+	data := make([]time.Time, len(periods))
+	byBucket := map[time.Time]capacityFactorPeriod{}
+	for i, p := range periods {
+		data[i] = p.bucket
+		byBucket[p.bucket] = p
+	}
+	buckets, err := timeBuckets(q.GroupBy, q.StartDate, q.EndDate, data)
+	if err != nil {
+		return nil, err
+	}
+
+	hundred := decimal.NewFromInt(100)
+	factors := make([]*decimal.Decimal, len(buckets))
+	for i, b := range buckets {
+		p := byBucket[b]
+		point := &models.CapacityFactorPoint{Bucket: b.Format(time.DateOnly), Days: p.days, Production: numeric.RoundDecimal(p.production)}
+		if p.days > 0 && g.Capacity.IsPositive() {
+			f := p.production.Mul(hundred).DivRound(g.Capacity.Mul(decimal.NewFromInt(p.days)), 16)
+			factors[i] = &f
+			rounded := numeric.RoundDecimal(f)
+			point.CapacityFactor = &rounded
+		}
+		trend.Points = append(trend.Points, point)
+	}
+	for i, s := range smooth(factors, q.Smoothing, q.Window) {
+		if s != nil {
+			rounded := numeric.RoundDecimal(*s)
+			trend.Points[i].Smoothed = &rounded
+		}
+	}
+	return trend, nil
+}
+
+// smooth returns the moving average of values; periods without a value are
+// skipped by the simple average and carry the exponential one over
+func smooth(values []*decimal.Decimal, smoothing string, window int) []*decimal.Decimal {
+	out := make([]*decimal.Decimal, len(values))
+	switch smoothing {
+	case models.SmoothingSMA:
+		for i := range values {
+			sum, n := decimal.Zero, int64(0)
+			for j := max(0, i-window+1); j <= i; j++ {
+				if values[j] != nil {
+					sum = sum.Add(*values[j])
+					n++
+				}
+			}
+			if n > 0 {
+				avg := sum.DivRound(decimal.NewFromInt(n), 16)
+				out[i] = &avg
+			}
+		}
+	case models.SmoothingEMA:
+		alpha := decimal.NewFromInt(2).DivRound(decimal.NewFromInt(int64(window)+1), 16)
+		var ema *decimal.Decimal
+		for i, v := range values {
+			if v != nil {
+				next := *v
+				if ema != nil {
+					next = alpha.Mul(*v).Add(decimal.NewFromInt(1).Sub(alpha).Mul(*ema))
+				}
+				ema = &next
+			}
+			out[i] = ema
+		}
+	}
+	return out
+}
+
+// GetCapacityFactorTrend returns the capacity factor of a generator per
+// bucket of q.GroupBy, smoothed as q says
+func (r *postgresRepository) GetCapacityFactorTrend(ctx context.Context, id uuid.UUID, q *models.CapacityFactorQuery) (*models.CapacityFactorTrend, error) {
+	g, err := r.GetGeneratorByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	conds, args := dateRangeConditions("p.date", q.StartDate, q.EndDate, []string{"p.generator_id = $2"}, []any{q.GroupBy, id})
+	query := `
+		SELECT date_trunc($1, p.date::timestamp)::date, SUM(p.production_mw), COUNT(DISTINCT p.date)
+		FROM productions p` + whereClause(conds) + `
+		GROUP BY 1
+		ORDER BY 1`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query capacity factor: %w", err)
+	}
+	defer rows.Close()
+
+	var periods []capacityFactorPeriod
+	for rows.Next() {
+		var p capacityFactorPeriod
+		if err := rows.Scan(&p.bucket, &p.production, &p.days); err != nil {
+			return nil, fmt.Errorf("failed to scan capacity factor: %w", err)
+		}
+		periods = append(periods, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return buildCapacityFactorTrend(g, q, periods)
+}
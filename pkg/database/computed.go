@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ComputeProductions sets the computed fields of productions in one query,
+// from the stored (unrounded) values; a field is left nil where it divides by zero
+func (r *postgresRepository) ComputeProductions(ctx context.Context, list []*models.Production, fields []string) error {
+	if len(list) == 0 || len(fields) == 0 {
+		return nil
+	}
+	capacityFactor, utilization := "NULL::numeric", "NULL::numeric"
+	for _, f := range fields {
+		switch f {
+		case models.ComputeCapacityFactor:
+			capacityFactor = "p.production_mw * 100 / NULLIF(g.capacity, 0)"
+		case models.ComputeUtilization:
+			utilization = `p.production_mw * 100 / NULLIF((
+			    SELECT MAX(x.production_mw) FROM productions x WHERE x.generator_id = p.generator_id), 0)`
+		}
+	}
+	ids := make([]uuid.UUID, len(list))
+	byID := make(map[uuid.UUID]*models.Production, len(list))
+	for i, p := range list {
+		ids[i], byID[p.ID] = p.ID, p
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT p.id, `+capacityFactor+`, `+utilization+`
+		FROM productions p
+		JOIN generators g ON p.generator_id = g.id
+		WHERE p.id = ANY($1)`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to compute production fields: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id     uuid.UUID
+			cf, ut decimal.NullDecimal
+		)
+		if err := rows.Scan(&id, &cf, &ut); err != nil {
+			return fmt.Errorf("failed to scan computed fields: %w", err)
+		}
+		p := byID[id]
+		p.CapacityFactor, p.Utilization = roundedOrNil(cf), roundedOrNil(ut)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %w", err)
+	}
+	return nil
+}
+
+// roundedOrNil applies the rounding policy to a nullable value
+func roundedOrNil(d decimal.NullDecimal) *decimal.Decimal {
+	if !d.Valid {
+		return nil
+	}
+	v := numeric.RoundDecimal(d.Decimal)
+	return &v
+}
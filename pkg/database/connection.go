@@ -139,26 +139,7 @@ func NewConnection(ctx context.Context) (*DB, error) {
 			config.User, config.Host, config.Port, config.Database)
 	}
 
-	// Set pool configuration with defaults
-	if poolConfig.MaxConns == 0 {
-		poolConfig.MaxConns = int32(getEnvAsIntWithDefault("DB_MAX_CONNECTIONS", 25))
-	}
-	if poolConfig.MinConns == 0 {
-		poolConfig.MinConns = int32(getEnvAsIntWithDefault("DB_MIN_CONNECTIONS", 5))
-	}
-	if poolConfig.MaxConnLifetime == 0 {
-		poolConfig.MaxConnLifetime = time.Duration(getEnvAsIntWithDefault("DB_MAX_CONN_LIFETIME", 60)) * time.Minute
-	}
-	if poolConfig.MaxConnIdleTime == 0 {
-		poolConfig.MaxConnIdleTime = time.Duration(getEnvAsIntWithDefault("DB_MAX_CONN_IDLE_TIME", 30)) * time.Minute
-	}
-
-	// Set connection parameters for better performance
-	if poolConfig.ConnConfig.RuntimeParams == nil {
-		poolConfig.ConnConfig.RuntimeParams = make(map[string]string)
-	}
-	poolConfig.ConnConfig.RuntimeParams["application_name"] = "tadb-api"
-	poolConfig.ConnConfig.RuntimeParams["search_path"] = "core,public"
+	configurePool(poolConfig)
 
 	// Create connection pool
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
@@ -181,6 +162,33 @@ func NewConnection(ctx context.Context) (*DB, error) {
 	return &DB{Pool: pool}, nil
 }
 
+// configurePool applies the pool defaults and connection parameters of the
+// API to poolConfig
+func configurePool(poolConfig *pgxpool.Config) {
+	// Set pool configuration with defaults
+	if poolConfig.MaxConns == 0 {
+		poolConfig.MaxConns = int32(getEnvAsIntWithDefault("DB_MAX_CONNECTIONS", 25))
+	}
+	if poolConfig.MinConns == 0 {
+		poolConfig.MinConns = int32(getEnvAsIntWithDefault("DB_MIN_CONNECTIONS", 5))
+	}
+	if poolConfig.MaxConnLifetime == 0 {
+		poolConfig.MaxConnLifetime = time.Duration(getEnvAsIntWithDefault("DB_MAX_CONN_LIFETIME", 60)) * time.Minute
+	}
+	if poolConfig.MaxConnIdleTime == 0 {
+		poolConfig.MaxConnIdleTime = time.Duration(getEnvAsIntWithDefault("DB_MAX_CONN_IDLE_TIME", 30)) * time.Minute
+	}
+
+	// Set connection parameters for better performance
+	if poolConfig.ConnConfig.RuntimeParams == nil {
+		poolConfig.ConnConfig.RuntimeParams = make(map[string]string)
+	}
+	poolConfig.ConnConfig.RuntimeParams["application_name"] = "tadb-api"
+	poolConfig.ConnConfig.RuntimeParams["search_path"] = getEnvWithDefault("DB_SEARCH_PATH", "core,public")
+	// Adds up the queries of requests asking for their query stats
+	poolConfig.ConnConfig.Tracer = queryTracer{}
+}
+
 // Close closes the database connection pool
 func (db *DB) Close() {
 	if db.Pool != nil {
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+)
+
+// ConnectorFileSeen reports whether the remote file was fetched before with the same size and modification time
+func (r *postgresRepository) ConnectorFileSeen(ctx context.Context, source, remotePath string, size int64, modifiedAt time.Time) (bool, error) {
+	var seen bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM connector_files
+			WHERE source = $1 AND remote_path = $2 AND size = $3 AND modified_at = $4
+		)`, source, remotePath, size, modifiedAt).Scan(&seen)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up connector file: %w", err)
+	}
+	return seen, nil
+}
+
+// ConnectorChecksumStaged reports whether a file with the checksum was staged from the source before
+func (r *postgresRepository) ConnectorChecksumStaged(ctx context.Context, source, sha256 string) (bool, error) {
+	var staged bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM connector_files
+			WHERE source = $1 AND sha256 = $2 AND status = 'staged'
+		)`, source, sha256).Scan(&staged)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up connector checksum: %w", err)
+	}
+	return staged, nil
+}
+
+// CreateConnectorFile records a fetched remote file
+func (r *postgresRepository) CreateConnectorFile(ctx context.Context, f *models.ConnectorFile) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO connector_files (id, connector, source, remote_path, size, modified_at, sha256, status, staged_as, fetched_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10)`,
+		f.ID, f.Connector, f.Source, f.RemotePath, f.Size, f.ModifiedAt, f.SHA256, f.Status, f.StagedAs, f.FetchedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record connector file: %w", err)
+	}
+	return nil
+}
+
+// GetConnectorFiles lists the latest files fetched by a connector, newest first; an empty source lists every source
+func (r *postgresRepository) GetConnectorFiles(ctx context.Context, connector, source string, limit int) ([]*models.ConnectorFile, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, connector, source, remote_path, size, modified_at, sha256, status, COALESCE(staged_as, ''), fetched_at
+		FROM connector_files
+		WHERE connector = $1 AND ($2 = '' OR source = $2)
+		ORDER BY fetched_at DESC
+		LIMIT $3`, connector, source, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query connector files: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*models.ConnectorFile{}
+	for rows.Next() {
+		var f models.ConnectorFile
+		if err := rows.Scan(&f.ID, &f.Connector, &f.Source, &f.RemotePath, &f.Size, &f.ModifiedAt, &f.SHA256, &f.Status, &f.StagedAs, &f.FetchedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan connector file: %w", err)
+		}
+		list = append(list, &f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrCustomFieldExists is returned when the entity already has a field with the name
+var ErrCustomFieldExists = errors.New("custom field already exists")
+
+// customEntityTables are the tables holding the custom attributes of each entity
+var customEntityTables = map[string]string{
+	models.CustomEntityType:       "types",
+	models.CustomEntityOperator:   "operators",
+	models.CustomEntityGenerator:  "generators",
+	models.CustomEntityProduction: "productions",
+}
+
+const customFieldColumns = `id, entity, name, type, required, COALESCE(description, ''), COALESCE(pattern, ''),
+	min_value, max_value, COALESCE(options, '{}'), created_at, updated_at`
+
+func scanCustomField(row pgx.Row, f *models.CustomField) error {
+	return row.Scan(&f.ID, &f.Entity, &f.Name, &f.Type, &f.Required, &f.Description, &f.Pattern,
+		&f.Min, &f.Max, &f.Options, &f.CreatedAt, &f.UpdatedAt)
+}
+
+// attributesParam passes custom attributes to a JSONB parameter; none is
+// NULL, which inserts keep as '{}' and updates leave unchanged
+func attributesParam(attrs models.CustomAttributes) any {
+	if len(attrs) == 0 {
+		return nil
+	}
+	return attrs
+}
+
+// GetCustomFields lists the custom fields of an entity, or of all entities when entity is empty
+func (r *postgresRepository) GetCustomFields(ctx context.Context, entity string) ([]*models.CustomField, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+customFieldColumns+`
+		FROM custom_fields
+		WHERE $1 = '' OR entity = $1
+		ORDER BY entity, name`, entity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query custom fields: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.CustomField
+	for rows.Next() {
+		var f models.CustomField
+		if err := scanCustomField(rows, &f); err != nil {
+			return nil, fmt.Errorf("failed to scan custom field: %w", err)
+		}
+		list = append(list, &f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// GetCustomFieldByID retrieves a custom field by its ID
+func (r *postgresRepository) GetCustomFieldByID(ctx context.Context, id uuid.UUID) (*models.CustomField, error) {
+	var f models.CustomField
+	err := scanCustomField(r.db.QueryRow(ctx, `SELECT `+customFieldColumns+` FROM custom_fields WHERE id = $1`, id), &f)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get custom field: %w", err)
+	}
+	return &f, nil
+}
+
+// CreateCustomField registers a custom field; ErrCustomFieldExists when the
+// entity already has one with the name
+func (r *postgresRepository) CreateCustomField(ctx context.Context, req *models.CreateCustomFieldRequest) (*models.CustomField, error) {
+	query := `
+		INSERT INTO custom_fields (id, entity, name, type, required, description, pattern, min_value, max_value, options, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8, $9, $10, $11, $11)
+		RETURNING ` + customFieldColumns
+
+	var f models.CustomField
+	err := scanCustomField(r.db.QueryRow(ctx, query, uuid.New(), req.Entity, req.Name, req.Type, req.Required,
+		req.Description, req.Pattern, req.Min, req.Max, req.Options, time.Now()), &f)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrCustomFieldExists
+		}
+		return nil, fmt.Errorf("failed to create custom field: %w", err)
+	}
+	return &f, nil
+}
+
+// UpdateCustomField replaces the description and validation of a custom
+// field. Stored values are not checked again; they are on their next write.
+func (r *postgresRepository) UpdateCustomField(ctx context.Context, id uuid.UUID, req *models.UpdateCustomFieldRequest) (*models.CustomField, error) {
+	query := `
+		UPDATE custom_fields
+		SET required = $2, description = NULLIF($3, ''), pattern = NULLIF($4, ''),
+		    min_value = $5, max_value = $6, options = $7, updated_at = $8
+		WHERE id = $1
+		RETURNING ` + customFieldColumns
+
+	var f models.CustomField
+	err := scanCustomField(r.db.QueryRow(ctx, query, id, req.Required, req.Description, req.Pattern,
+		req.Min, req.Max, req.Options, time.Now()), &f)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to update custom field: %w", err)
+	}
+	return &f, nil
+}
+
+// DeleteCustomField removes a custom field and its values from the records of its entity
+func (r *postgresRepository) DeleteCustomField(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var entity, name string
+	err = tx.QueryRow(ctx, `DELETE FROM custom_fields WHERE id = $1 RETURNING entity, name`, id).Scan(&entity, &name)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return sql.ErrNoRows
+		}
+		return fmt.Errorf("failed to delete custom field: %w", err)
+	}
+	// The values are not part of published snapshots, so removing them may
+	// touch productions of published months
+	if entity == models.CustomEntityProduction {
+		if _, err := tx.Exec(ctx, `SELECT set_config('tadb.allow_published_edit', 'on', true)`); err != nil {
+			return fmt.Errorf("failed to enable correction mode: %w", err)
+		}
+	}
+	table := customEntityTables[entity]
+	_, err = tx.Exec(ctx, `UPDATE `+table+` SET custom_attributes = custom_attributes - $1::text WHERE custom_attributes ? $1`, name)
+	if err != nil {
+		return fmt.Errorf("failed to remove custom field values: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit custom field deletion: %w", err)
+	}
+	return nil
+}
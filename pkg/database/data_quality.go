@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+)
+
+// qualityQueries select the rows flagged by each data quality check: the
+// entity ID, the generator ID and date of productions, the detail and the
+// count of all flagged rows; $1 is the limit
+var qualityQueries = map[string]struct{ entity, query string }{
+	models.QualityOrphanProduction: {models.QualityEntityProduction, `
+		SELECT p.id, p.generator_id, p.date::text, 'generator ' || p.generator_id || ' does not exist', COUNT(*) OVER ()
+		FROM productions p
+		WHERE NOT EXISTS (SELECT 1 FROM generators g WHERE g.id = p.generator_id)
+		ORDER BY p.date, p.id
+		LIMIT $1`},
+	models.QualityOrphanGrant: {models.QualityEntityUser, `
+		SELECT ug.user_id, NULL::uuid, NULL::text, 'user does not exist but has a grant on operator ' || COALESCE(o.name, ug.operator_id::text), COUNT(*) OVER ()
+		FROM user_operator_grants ug
+		LEFT JOIN operators o ON o.id = ug.operator_id
+		WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = ug.user_id)
+		ORDER BY ug.user_id, ug.operator_id
+		LIMIT $1`},
+	models.QualityDeletedType: {models.QualityEntityGenerator, `
+		SELECT g.id, g.id, NULL::text,
+		       'type ' || t.name || CASE WHEN t.merged_into IS NULL THEN ' is deleted' ELSE ' was merged into ' || t.merged_into END,
+		       COUNT(*) OVER ()
+		FROM generators g
+		JOIN types t ON g.type = t.id
+		WHERE t.deleted_at IS NOT NULL
+		ORDER BY t.name, g.id
+		LIMIT $1`},
+	models.QualityBeforeCommissioning: {models.QualityEntityProduction, `
+		SELECT p.id, p.generator_id, p.date::text, 'dated before the commissioning of the generator on ' || g.commissioned_on::text, COUNT(*) OVER ()
+		FROM productions p
+		JOIN generators g ON p.generator_id = g.id
+		WHERE p.date < g.commissioned_on
+		ORDER BY p.date, p.id
+		LIMIT $1`},
+	models.QualityOverCapacity: {models.QualityEntityProduction, `
+		SELECT p.id, p.generator_id, p.date::text,
+		       p.production_mw::float8::text || ' MW exceeds the capacity of ' || g.capacity::float8::text || ' MW',
+		       COUNT(*) OVER ()
+		FROM productions p
+		JOIN generators g ON p.generator_id = g.id
+		WHERE p.production_mw > g.capacity
+		ORDER BY p.date, p.id
+		LIMIT $1`},
+	models.QualityUnusedType: {models.QualityEntityType, `
+		SELECT t.id, NULL::uuid, NULL::text, 'type ' || t.name || ' has no generators', COUNT(*) OVER ()
+		FROM types t
+		WHERE t.deleted_at IS NULL AND NOT EXISTS (SELECT 1 FROM generators g WHERE g.type = t.id)
+		ORDER BY t.name
+		LIMIT $1`},
+}
+
+// newQualityReport returns a report of checks (all when empty), in the order
+// of models.QualityChecks
+func newQualityReport(checks []string) *models.DataQualityReport {
+	report := &models.DataQualityReport{Checks: []*models.DataQualityCheck{}, Findings: []*models.DataQualityFinding{}}
+	for _, check := range models.QualityChecks {
+		if len(checks) == 0 || slices.Contains(checks, check) {
+			report.Checks = append(report.Checks, &models.DataQualityCheck{Check: check})
+		}
+	}
+	return report
+}
+
+// ScanDataQuality runs the data quality checks (all when empty), listing at
+// most limit findings of each
+func (r *postgresRepository) ScanDataQuality(ctx context.Context, checks []string, limit int) (*models.DataQualityReport, error) {
+	report := newQualityReport(checks)
+	for _, c := range report.Checks {
+		q := qualityQueries[c.Check]
+		rows, err := r.db.Query(ctx, q.query, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run data quality check %s: %w", c.Check, err)
+		}
+		for rows.Next() {
+			f := &models.DataQualityFinding{Check: c.Check, Entity: q.entity}
+			if err := rows.Scan(&f.EntityID, &f.GeneratorID, &f.Date, &f.Detail, &c.Count); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan data quality finding: %w", err)
+			}
+			report.Findings = append(report.Findings, f)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("row iteration error: %w", err)
+		}
+		if c.Count > int64(limit) {
+			report.Truncated = true
+		}
+	}
+	return report, nil
+}
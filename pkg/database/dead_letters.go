@@ -0,0 +1,162 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrDeadLetterResolved is returned when changing a dead letter that was already resubmitted
+var ErrDeadLetterResolved = errors.New("dead letter was already resubmitted")
+
+const deadLetterColumns = `id, job_id, line, generator_id, date, production_mw, error, status, attempts, production_id, created_at, updated_at`
+
+func scanDeadLetter(row pgx.Row, d *models.DeadLetter) error {
+	return row.Scan(&d.ID, &d.JobID, &d.Line, &d.GeneratorID, &d.Date, &d.ProductionMW, &d.Error, &d.Status,
+		&d.Attempts, &d.ProductionID, &d.CreatedAt, &d.UpdatedAt)
+}
+
+// CreateDeadLetter keeps a rejected import row
+func (r *postgresRepository) CreateDeadLetter(ctx context.Context, d *models.DeadLetter) error {
+	now := time.Now()
+	d.ID, d.Status, d.CreatedAt, d.UpdatedAt = uuid.New(), models.DeadLetterPending, now, now
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO import_dead_letters (id, job_id, line, generator_id, date, production_mw, error, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)`,
+		d.ID, d.JobID, d.Line, d.GeneratorID, d.Date, d.ProductionMW, d.Error, d.Status, now)
+	if err != nil {
+		return fmt.Errorf("failed to create dead letter: %w", err)
+	}
+	return nil
+}
+
+// GetDeadLetters lists dead letters by job and line, newest jobs first
+func (r *postgresRepository) GetDeadLetters(ctx context.Context, filter *models.DeadLetterFilter) ([]*models.DeadLetter, error) {
+	var conds []string
+	var args []any
+	if filter.JobID != nil {
+		args = append(args, *filter.JobID)
+		conds = append(conds, fmt.Sprintf("job_id = $%d", len(args)))
+	}
+	if filter.Status != nil {
+		args = append(args, *filter.Status)
+		conds = append(conds, fmt.Sprintf("status = $%d", len(args)))
+	}
+	query := `SELECT ` + deadLetterColumns + ` FROM import_dead_letters` + whereClause(conds) + `
+		ORDER BY created_at DESC, job_id, line`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.DeadLetter
+	for rows.Next() {
+		var d models.DeadLetter
+		if err := scanDeadLetter(rows, &d); err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+		}
+		list = append(list, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// GetDeadLetterByID retrieves a dead letter by its ID
+func (r *postgresRepository) GetDeadLetterByID(ctx context.Context, id uuid.UUID) (*models.DeadLetter, error) {
+	var d models.DeadLetter
+	err := scanDeadLetter(r.db.QueryRow(ctx, `SELECT `+deadLetterColumns+` FROM import_dead_letters WHERE id = $1`, id), &d)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	return &d, nil
+}
+
+// UpdateDeadLetter corrects the values of a pending dead letter;
+// ErrDeadLetterResolved once it was resubmitted
+func (r *postgresRepository) UpdateDeadLetter(ctx context.Context, id uuid.UUID, req *models.UpdateDeadLetterRequest) (*models.DeadLetter, error) {
+	var d models.DeadLetter
+	err := scanDeadLetter(r.db.QueryRow(ctx, `
+		UPDATE import_dead_letters
+		SET generator_id = COALESCE($2, generator_id), date = COALESCE($3, date),
+		    production_mw = COALESCE($4, production_mw), updated_at = $5
+		WHERE id = $1 AND status = $6
+		RETURNING `+deadLetterColumns,
+		id, req.GeneratorID, req.Date, req.ProductionMW, time.Now(), models.DeadLetterPending), &d)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, r.deadLetterMissing(ctx, id)
+		}
+		return nil, fmt.Errorf("failed to update dead letter: %w", err)
+	}
+	return &d, nil
+}
+
+// RecordDeadLetterAttempt records a resubmission of a pending dead letter:
+// resubmitted with the created production when productionID is set, still
+// pending with the new error otherwise
+func (r *postgresRepository) RecordDeadLetterAttempt(ctx context.Context, id uuid.UUID, productionID *uuid.UUID, errMsg string) (*models.DeadLetter, error) {
+	status := models.DeadLetterPending
+	if productionID != nil {
+		status = models.DeadLetterResubmitted
+	}
+	var d models.DeadLetter
+	err := scanDeadLetter(r.db.QueryRow(ctx, `
+		UPDATE import_dead_letters
+		SET status = $2, production_id = $3, error = CASE WHEN $4 = '' THEN error ELSE $4 END,
+		    attempts = attempts + 1, updated_at = $5
+		WHERE id = $1 AND status = $6
+		RETURNING `+deadLetterColumns,
+		id, status, productionID, errMsg, time.Now(), models.DeadLetterPending), &d)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, r.deadLetterMissing(ctx, id)
+		}
+		return nil, fmt.Errorf("failed to record dead letter attempt: %w", err)
+	}
+	return &d, nil
+}
+
+// DeleteDeadLetter discards a dead letter
+func (r *postgresRepository) DeleteDeadLetter(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM import_dead_letters WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// deadLetterMissing tells why a pending dead letter was not found
+func (r *postgresRepository) deadLetterMissing(ctx context.Context, id uuid.UUID) error {
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM import_dead_letters WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	if exists {
+		return ErrDeadLetterResolved
+	}
+	return sql.ErrNoRows
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrEmailTemplateEdited is returned when another version of the template
+// was saved at the same time
+var ErrEmailTemplateEdited = errors.New("email template was edited at the same time; retry")
+
+const emailTemplateColumns = `key, version, subject, body, COALESCE(comment, ''), user_id, created_at`
+
+func scanEmailTemplate(row pgx.Row, t *models.EmailTemplate) error {
+	return row.Scan(&t.Key, &t.Version, &t.Subject, &t.Body, &t.Comment, &t.UserID, &t.CreatedAt)
+}
+
+func (r *postgresRepository) queryEmailTemplates(ctx context.Context, query string, args ...any) ([]*models.EmailTemplate, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query email templates: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.EmailTemplate
+	for rows.Next() {
+		var t models.EmailTemplate
+		if err := scanEmailTemplate(rows, &t); err != nil {
+			return nil, fmt.Errorf("failed to scan email template: %w", err)
+		}
+		list = append(list, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// GetEmailTemplate retrieves the latest version of an email template
+func (r *postgresRepository) GetEmailTemplate(ctx context.Context, key string) (*models.EmailTemplate, error) {
+	var t models.EmailTemplate
+	err := scanEmailTemplate(r.db.QueryRow(ctx, `
+		SELECT `+emailTemplateColumns+`
+		FROM email_templates
+		WHERE key = $1
+		ORDER BY version DESC
+		LIMIT 1`, key), &t)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get email template: %w", err)
+	}
+	return &t, nil
+}
+
+// GetEmailTemplateVersions lists the versions of an email template, newest first
+func (r *postgresRepository) GetEmailTemplateVersions(ctx context.Context, key string) ([]*models.EmailTemplate, error) {
+	return r.queryEmailTemplates(ctx, `
+		SELECT `+emailTemplateColumns+`
+		FROM email_templates
+		WHERE key = $1
+		ORDER BY version DESC`, key)
+}
+
+// GetEmailTemplateVersion retrieves a version of an email template
+func (r *postgresRepository) GetEmailTemplateVersion(ctx context.Context, key string, version int) (*models.EmailTemplate, error) {
+	var t models.EmailTemplate
+	err := scanEmailTemplate(r.db.QueryRow(ctx, `
+		SELECT `+emailTemplateColumns+` FROM email_templates WHERE key = $1 AND version = $2`, key, version), &t)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get email template version: %w", err)
+	}
+	return &t, nil
+}
+
+// CreateEmailTemplateVersion saves a new version of an email template, which
+// becomes the current one; earlier versions are kept
+func (r *postgresRepository) CreateEmailTemplateVersion(ctx context.Context, key string, req *models.EmailTemplateRequest, userID *uuid.UUID) (*models.EmailTemplate, error) {
+	query := `
+		INSERT INTO email_templates (key, version, subject, body, comment, user_id, created_at)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, NULLIF($4, ''), $5, $6
+		FROM email_templates
+		WHERE key = $1
+		RETURNING ` + emailTemplateColumns
+
+	var t models.EmailTemplate
+	err := scanEmailTemplate(r.db.QueryRow(ctx, query, key, req.Subject, req.Body, req.Comment, userID, time.Now()), &t)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrEmailTemplateEdited
+		}
+		return nil, fmt.Errorf("failed to create email template version: %w", err)
+	}
+	return &t, nil
+}
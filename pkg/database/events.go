@@ -0,0 +1,282 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrUnknownProjection is returned for a projection name that does not exist
+var ErrUnknownProjection = errors.New("unknown projection")
+
+// projectionTables maps projection names to the summary table they fill
+var projectionTables = map[string]string{
+	models.ProjectionDailyProduction: "daily_production_summary",
+}
+
+// defaultEventLimit is the page size of GetDomainEvents when the filter sets none
+const defaultEventLimit = 100
+
+// GetDomainEvents returns events of the log in log order
+func (r *postgresRepository) GetDomainEvents(ctx context.Context, filter *models.DomainEventFilter) ([]*models.DomainEvent, error) {
+	args := []any{filter.AfterSeq}
+	conds := []string{"seq > $1"}
+	if filter.Aggregate != nil {
+		args = append(args, *filter.Aggregate)
+		conds = append(conds, fmt.Sprintf("aggregate = $%d", len(args)))
+	}
+	if filter.AggregateID != nil {
+		args = append(args, *filter.AggregateID)
+		conds = append(conds, fmt.Sprintf("aggregate_id = $%d", len(args)))
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultEventLimit
+	}
+	args = append(args, limit)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT seq, aggregate, aggregate_id, event, old_state, new_state, occurred_at
+		FROM domain_events`+whereClause(conds)+fmt.Sprintf(`
+		ORDER BY seq
+		LIMIT $%d`, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query domain events: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.DomainEvent
+	for rows.Next() {
+		var e models.DomainEvent
+		if err := rows.Scan(&e.Seq, &e.Aggregate, &e.AggregateID, &e.Event, &e.Old, &e.New, &e.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan domain event: %w", err)
+		}
+		list = append(list, &e)
+	}
+	return list, rows.Err()
+}
+
+// GetProjections returns every projection with how far it is behind the log
+func (r *postgresRepository) GetProjections(ctx context.Context) ([]*models.Projection, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT c.name, c.last_seq, c.updated_at, c.rebuilt_at,
+		       COALESCE((SELECT MAX(seq) FROM domain_events), 0),
+		       (SELECT COUNT(*) FROM domain_events e WHERE e.seq > c.last_seq)
+		FROM projection_checkpoints c
+		ORDER BY c.name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query projections: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.Projection
+	for rows.Next() {
+		var p models.Projection
+		if err := rows.Scan(&p.Name, &p.LastSeq, &p.UpdatedAt, &p.RebuiltAt, &p.HeadSeq, &p.PendingEvents); err != nil {
+			return nil, fmt.Errorf("failed to scan projection: %w", err)
+		}
+		list = append(list, &p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, p := range list {
+		table, ok := projectionTables[p.Name]
+		if !ok {
+			continue
+		}
+		if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM `+table).Scan(&p.Rows); err != nil {
+			return nil, fmt.Errorf("failed to count projection rows: %w", err)
+		}
+	}
+	return list, nil
+}
+
+// lockCheckpoint locks the checkpoint of a projection for the transaction and
+// returns it with the id below which every transaction has finished, the
+// point the projection can be brought up to
+func lockCheckpoint(ctx context.Context, tx pgx.Tx, name string) (from, to string, err error) {
+	err = tx.QueryRow(ctx, `
+		SELECT last_txid::text, pg_snapshot_xmin(pg_current_snapshot())::text
+		FROM projection_checkpoints
+		WHERE name = $1
+		FOR UPDATE`, name).Scan(&from, &to)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", "", ErrUnknownProjection
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to lock projection checkpoint: %w", err)
+	}
+	return from, to, nil
+}
+
+// CatchUpProjections applies the events of the transactions finished since
+// the last call to the projections and returns how many were applied. The
+// days those events touch are recomputed from the current tables, so applying
+// an event twice is harmless; events are taken from the checkpoint on, which
+// can repeat some but never skips a transaction that committed late.
+func (r *postgresRepository) CatchUpProjections(ctx context.Context) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	from, to, err := lockCheckpoint(ctx, tx, models.ProjectionDailyProduction)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		applied int64
+		lastSeq int64
+		dates   []string
+	)
+	err = tx.QueryRow(ctx, `
+		WITH changed AS (
+			SELECT seq, aggregate, aggregate_id, old_state, new_state
+			FROM domain_events
+			WHERE txid >= $1::xid8 AND txid < $2::xid8
+		),
+		dates AS (
+			SELECT old_state->>'date' AS date FROM changed WHERE aggregate = 'production'
+			UNION
+			SELECT new_state->>'date' FROM changed WHERE aggregate = 'production'
+			UNION
+			SELECT p.date::text FROM productions p
+			WHERE p.generator_id IN (SELECT aggregate_id FROM changed WHERE aggregate = 'generator')
+		)
+		SELECT (SELECT COUNT(*) FROM changed),
+		       (SELECT COALESCE(MAX(seq), 0) FROM changed),
+		       COALESCE((SELECT array_agg(date) FROM dates WHERE date IS NOT NULL), '{}')`,
+		from, to).Scan(&applied, &lastSeq, &dates)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read domain events: %w", err)
+	}
+
+	if len(dates) > 0 {
+		if _, err := tx.Exec(ctx, `DELETE FROM daily_production_summary WHERE date = ANY($1::text[]::date[])`, dates); err != nil {
+			return 0, fmt.Errorf("failed to clear daily production summary: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO daily_production_summary (date, type_id, records, production_mw)
+			SELECT p.date, g.type, COUNT(*), SUM(p.production_mw)
+			FROM productions p
+			JOIN generators g ON p.generator_id = g.id
+			WHERE p.date = ANY($1::text[]::date[])
+			GROUP BY p.date, g.type`, dates); err != nil {
+			return 0, fmt.Errorf("failed to refresh daily production summary: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE projection_checkpoints
+		SET last_txid = $2::xid8, last_seq = GREATEST(last_seq, $3), updated_at = now()
+		WHERE name = $1`, models.ProjectionDailyProduction, to, lastSeq); err != nil {
+		return 0, fmt.Errorf("failed to update projection checkpoint: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return applied, nil
+}
+
+// RebuildProjection recomputes a projection from the event log alone,
+// folding the events of every record into its latest state, and moves its
+// checkpoint to the end of the log. The current tables are not read, so
+// the result only depends on the events.
+func (r *postgresRepository) RebuildProjection(ctx context.Context, name string) (*models.Projection, error) {
+	if _, ok := projectionTables[name]; !ok {
+		return nil, ErrUnknownProjection
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, to, err := lockCheckpoint(ctx, tx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM daily_production_summary`); err != nil {
+		return nil, fmt.Errorf("failed to clear daily production summary: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		WITH latest AS (
+			SELECT DISTINCT ON (aggregate, aggregate_id) aggregate, aggregate_id, new_state
+			FROM domain_events
+			WHERE aggregate IN ('generator', 'production') AND txid < $1::xid8
+			ORDER BY aggregate, aggregate_id, seq DESC
+		)
+		INSERT INTO daily_production_summary (date, type_id, records, production_mw)
+		SELECT (p.new_state->>'date')::date, (g.new_state->>'type')::uuid,
+		       COUNT(*), SUM((p.new_state->>'production_mw')::numeric)
+		FROM latest p
+		JOIN latest g ON g.aggregate = 'generator'
+		             AND g.aggregate_id = (p.new_state->>'generator_id')::uuid
+		             AND g.new_state IS NOT NULL
+		WHERE p.aggregate = 'production' AND p.new_state IS NOT NULL
+		GROUP BY 1, 2`, to); err != nil {
+		return nil, fmt.Errorf("failed to rebuild daily production summary: %w", err)
+	}
+
+	var p models.Projection
+	err = tx.QueryRow(ctx, `
+		UPDATE projection_checkpoints
+		SET last_txid = $2::xid8,
+		    last_seq = COALESCE((SELECT MAX(seq) FROM domain_events WHERE txid < $2::xid8), 0),
+		    updated_at = now(), rebuilt_at = now()
+		WHERE name = $1
+		RETURNING name, last_seq, updated_at, rebuilt_at`, name, to).Scan(&p.Name, &p.LastSeq, &p.UpdatedAt, &p.RebuiltAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update projection checkpoint: %w", err)
+	}
+	err = tx.QueryRow(ctx, `
+		SELECT (SELECT COUNT(*) FROM daily_production_summary),
+		       COALESCE((SELECT MAX(seq) FROM domain_events), 0)`).Scan(&p.Rows, &p.HeadSeq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read projection: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	p.PendingEvents = p.HeadSeq - p.LastSeq
+	if p.PendingEvents < 0 {
+		p.PendingEvents = 0
+	}
+	return &p, nil
+}
+
+// GetDailyProductionSummary returns the daily production projection, newest day first
+func (r *postgresRepository) GetDailyProductionSummary(ctx context.Context, startDate, endDate *string) ([]*models.DailyProductionSummary, error) {
+	conds, args := dateRangeConditions("s.date", startDate, endDate, nil, nil)
+	rows, err := r.db.Query(ctx, `
+		SELECT s.date::text, s.type_id, t.name, t.isrenuevable, s.records, s.production_mw
+		FROM daily_production_summary s
+		JOIN types t ON s.type_id = t.id`+whereClause(conds)+`
+		ORDER BY s.date DESC, t.name`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily production summary: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.DailyProductionSummary
+	for rows.Next() {
+		var s models.DailyProductionSummary
+		if err := rows.Scan(&s.Date, &s.TypeID, &s.TypeName, &s.IsRenewable, &s.Records, &s.ProductionMW); err != nil {
+			return nil, fmt.Errorf("failed to scan daily production summary: %w", err)
+		}
+		s.ProductionMW = numeric.RoundDecimal(s.ProductionMW)
+		list = append(list, &s)
+	}
+	return list, rows.Err()
+}
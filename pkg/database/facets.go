@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+)
+
+// productionConditions builds the WHERE conditions of a production filter on
+// the aliases p (productions), g (generators) and t (types)
+func productionConditions(filter *models.ProductionFilter) ([]string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+	if filter.GeneratorID != nil {
+		args = append(args, *filter.GeneratorID)
+		conds = append(conds, fmt.Sprintf("p.generator_id = $%d", len(args)))
+	}
+	if filter.PlantID != nil {
+		args = append(args, *filter.PlantID)
+		conds = append(conds, fmt.Sprintf("g.plant_id = $%d", len(args)))
+	}
+	conds, args = dateRangeConditions("p.date", filter.StartDate, filter.EndDate, conds, args)
+	if filter.Source != nil {
+		args = append(args, *filter.Source)
+		conds = append(conds, fmt.Sprintf("p.source = $%d", len(args)))
+	}
+	if len(filter.Attributes) > 0 {
+		args = append(args, filter.Attributes)
+		conds = append(conds, fmt.Sprintf("p.custom_attributes @> $%d", len(args)))
+	}
+	return conds, args
+}
+
+// GetProductionFacets returns the generators, types, sources and date bounds
+// of the productions matching filter, with record counts
+func (r *postgresRepository) GetProductionFacets(ctx context.Context, filter *models.ProductionFilter) (*models.ProductionFacets, error) {
+	conds, args := productionConditions(filter)
+	from := `
+		FROM productions p
+		JOIN generators g ON p.generator_id = g.id
+		JOIN types t ON g.type = t.id
+		LEFT JOIN operators o ON g.operator_id = o.id` + whereClause(conds)
+
+	facets := models.ProductionFacets{
+		Generators: []*models.GeneratorFacet{},
+		Types:      []*models.TypeFacet{},
+		Sources:    []*models.SourceFacet{},
+	}
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*), MIN(p.date)::text, MAX(p.date)::text`+from, args...).
+		Scan(&facets.Total, &facets.MinDate, &facets.MaxDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query production facets: %w", err)
+	}
+	if facets.Total == 0 {
+		return &facets, nil
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT g.id, t.name, g.capacity, COALESCE(o.name, ''), COUNT(*)`+from+`
+		GROUP BY g.id, t.name, g.capacity, o.name
+		ORDER BY t.name, g.capacity DESC, g.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query generator facets: %w", err)
+	}
+	for rows.Next() {
+		var f models.GeneratorFacet
+		if err := rows.Scan(&f.ID, &f.TypeName, &f.Capacity, &f.OperatorName, &f.Count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan generator facet: %w", err)
+		}
+		f.Capacity = numeric.RoundDecimal(f.Capacity)
+		facets.Generators = append(facets.Generators, &f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	rows, err = r.db.Query(ctx, `
+		SELECT t.id, t.name, t.isrenuevable, COUNT(*)`+from+`
+		GROUP BY t.id, t.name, t.isrenuevable
+		ORDER BY t.name`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query type facets: %w", err)
+	}
+	for rows.Next() {
+		var f models.TypeFacet
+		if err := rows.Scan(&f.ID, &f.Name, &f.IsRenewable, &f.Count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan type facet: %w", err)
+		}
+		facets.Types = append(facets.Types, &f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	rows, err = r.db.Query(ctx, `
+		SELECT p.source, COUNT(*)`+from+`
+		GROUP BY p.source
+		ORDER BY COUNT(*) DESC, p.source`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query source facets: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f models.SourceFacet
+		if err := rows.Scan(&f.Source, &f.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan source facet: %w", err)
+		}
+		facets.Sources = append(facets.Sources, &f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return &facets, nil
+}
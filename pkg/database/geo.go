@@ -0,0 +1,36 @@
+package database
+
+import (
+	"sort"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/geo"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+)
+
+// sortByDistance sets the distance of every generator to p and orders them nearest first
+func sortByDistance(list []*models.Generator, p geo.Point) {
+	for _, g := range list {
+		if loc, ok := g.Location(); ok {
+			d := geo.DistanceKm(p, loc)
+			g.DistanceKm = &d
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return *list[i].DistanceKm < *list[j].DistanceKm
+	})
+}
+
+// matchesGeoFilter reports whether g satisfies the location conditions of filter
+func matchesGeoFilter(g *models.Generator, filter models.GeneratorFilter) bool {
+	if filter.Near == nil && filter.Within == nil {
+		return true
+	}
+	loc, ok := g.Location()
+	if !ok {
+		return false
+	}
+	if filter.Within != nil && !filter.Within.Contains(loc) {
+		return false
+	}
+	return filter.Near == nil || geo.DistanceKm(*filter.Near, loc) <= filter.RadiusKm
+}
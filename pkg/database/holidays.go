@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrHolidayExists is returned when the country (or region) already has a
+// holiday on the date
+var ErrHolidayExists = errors.New("holiday already exists")
+
+// ListHolidays lists the holidays of a country, including those of its
+// regions, all countries when empty
+func (r *postgresRepository) ListHolidays(ctx context.Context, country string) ([]*models.Holiday, error) {
+	query := `SELECT id, country, region, date, name, created_at FROM holidays`
+	var args []any
+	if country != "" {
+		args = append(args, strings.ToUpper(country))
+		query += ` WHERE country = $1`
+	}
+	query += ` ORDER BY country, region, date`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query holidays: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.Holiday
+	for rows.Next() {
+		var h models.Holiday
+		if err := rows.Scan(&h.ID, &h.Country, &h.Region, &h.Date, &h.Name, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan holiday: %w", err)
+		}
+		list = append(list, &h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// CreateHoliday adds a holiday; country and region are stored upper-case
+func (r *postgresRepository) CreateHoliday(ctx context.Context, req *models.HolidayRequest) (*models.Holiday, error) {
+	h := &models.Holiday{
+		ID:        uuid.New(),
+		Country:   strings.ToUpper(req.Country),
+		Region:    regionCode(req.Region),
+		Date:      req.Date,
+		Name:      strings.TrimSpace(req.Name),
+		CreatedAt: time.Now(),
+	}
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO holidays (id, country, region, date, name, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		h.ID, h.Country, h.Region, h.Date, h.Name, h.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrHolidayExists
+		}
+		return nil, fmt.Errorf("failed to create holiday: %w", err)
+	}
+	return h, nil
+}
+
+// DeleteHoliday removes a holiday
+func (r *postgresRepository) DeleteHoliday(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM holidays WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete holiday: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const impersonationColumns = `id, actor_id, user_id, operator_id, reason, started_at, expires_at, ended_at,
+	ended_at IS NULL AND expires_at > now()`
+
+func scanImpersonation(row pgx.Row, s *models.Impersonation) error {
+	return row.Scan(&s.ID, &s.ActorID, &s.UserID, &s.OperatorID, &s.Reason, &s.StartedAt, &s.ExpiresAt, &s.EndedAt, &s.Active)
+}
+
+// CreateImpersonation records the start of an impersonation session
+func (r *postgresRepository) CreateImpersonation(ctx context.Context, s *models.Impersonation) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO impersonations (id, actor_id, user_id, operator_id, reason, started_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		s.ID, s.ActorID, s.UserID, s.OperatorID, s.Reason, s.StartedAt, s.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create impersonation: %w", err)
+	}
+	return nil
+}
+
+// GetImpersonation retrieves an impersonation session by ID
+func (r *postgresRepository) GetImpersonation(ctx context.Context, id uuid.UUID) (*models.Impersonation, error) {
+	var s models.Impersonation
+	err := scanImpersonation(r.db.QueryRow(ctx, `SELECT `+impersonationColumns+` FROM impersonations WHERE id = $1`, id), &s)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get impersonation: %w", err)
+	}
+	return &s, nil
+}
+
+// GetImpersonations lists impersonation sessions, newest first
+func (r *postgresRepository) GetImpersonations(ctx context.Context, filter *models.ImpersonationFilter) ([]*models.Impersonation, error) {
+	var conds []string
+	var args []any
+	if filter.ActorID != nil {
+		args = append(args, *filter.ActorID)
+		conds = append(conds, fmt.Sprintf("actor_id = $%d", len(args)))
+	}
+	if filter.UserID != nil {
+		args = append(args, *filter.UserID)
+		conds = append(conds, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		conds = append(conds, fmt.Sprintf("(ended_at IS NULL AND expires_at > now()) = $%d", len(args)))
+	}
+	query := `SELECT ` + impersonationColumns + ` FROM impersonations` + whereClause(conds) + `
+		ORDER BY started_at DESC, id`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query impersonations: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.Impersonation
+	for rows.Next() {
+		var s models.Impersonation
+		if err := scanImpersonation(rows, &s); err != nil {
+			return nil, fmt.Errorf("failed to scan impersonation: %w", err)
+		}
+		list = append(list, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// EndImpersonation ends an impersonation session, after which its token is
+// rejected; sessions that already ended keep their end time
+func (r *postgresRepository) EndImpersonation(ctx context.Context, id uuid.UUID) (*models.Impersonation, error) {
+	var s models.Impersonation
+	err := scanImpersonation(r.db.QueryRow(ctx, `
+		UPDATE impersonations SET ended_at = COALESCE(ended_at, LEAST($2, expires_at))
+		WHERE id = $1
+		RETURNING `+impersonationColumns, id, time.Now()), &s)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to end impersonation: %w", err)
+	}
+	return &s, nil
+}
+
+// CreateImpersonationEvent records a request made during an impersonation session
+func (r *postgresRepository) CreateImpersonationEvent(ctx context.Context, e *models.ImpersonationEvent) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO impersonation_events (session_id, method, path, status, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`, e.SessionID, e.Method, e.Path, e.Status, e.CreatedAt).Scan(&e.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create impersonation event: %w", err)
+	}
+	return nil
+}
+
+// GetImpersonationEvents lists the requests of an impersonation session in the order they were made
+func (r *postgresRepository) GetImpersonationEvents(ctx context.Context, sessionID uuid.UUID, limit, offset int) ([]*models.ImpersonationEvent, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, session_id, method, path, status, created_at
+		FROM impersonation_events
+		WHERE session_id = $1
+		ORDER BY id
+		LIMIT $2 OFFSET $3`, sessionID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query impersonation events: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.ImpersonationEvent
+	for rows.Next() {
+		var e models.ImpersonationEvent
+		if err := rows.Scan(&e.ID, &e.SessionID, &e.Method, &e.Path, &e.Status, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan impersonation event: %w", err)
+		}
+		list = append(list, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
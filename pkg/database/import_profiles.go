@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrImportProfileExists is returned when another import profile has the name
+var ErrImportProfileExists = errors.New("import profile already exists")
+
+const importProfileColumns = `id, name, source, COALESCE(description, ''), columns, COALESCE(delimiter, ''), created_at, updated_at`
+
+func scanImportProfile(row pgx.Row, p *models.ImportProfile) error {
+	return row.Scan(&p.ID, &p.Name, &p.Source, &p.Description, &p.Columns, &p.Delimiter, &p.CreatedAt, &p.UpdatedAt)
+}
+
+// importProfileError maps the errors of writing an import profile
+func importProfileError(action string, err error) error {
+	var pgErr *pgconn.PgError
+	switch {
+	case err == pgx.ErrNoRows:
+		return sql.ErrNoRows
+	case errors.As(err, &pgErr) && pgErr.Code == "23505":
+		return ErrImportProfileExists
+	}
+	return fmt.Errorf("failed to %s import profile: %w", action, err)
+}
+
+// GetImportProfiles lists the import profiles of a source, or of all sources when source is empty
+func (r *postgresRepository) GetImportProfiles(ctx context.Context, source string) ([]*models.ImportProfile, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+importProfileColumns+`
+		FROM import_profiles
+		WHERE $1 = '' OR lower(source) = lower($1)
+		ORDER BY source, name`, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query import profiles: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.ImportProfile
+	for rows.Next() {
+		var p models.ImportProfile
+		if err := scanImportProfile(rows, &p); err != nil {
+			return nil, fmt.Errorf("failed to scan import profile: %w", err)
+		}
+		list = append(list, &p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// GetImportProfileByID retrieves an import profile by its ID
+func (r *postgresRepository) GetImportProfileByID(ctx context.Context, id uuid.UUID) (*models.ImportProfile, error) {
+	var p models.ImportProfile
+	err := scanImportProfile(r.db.QueryRow(ctx, `SELECT `+importProfileColumns+` FROM import_profiles WHERE id = $1`, id), &p)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get import profile: %w", err)
+	}
+	return &p, nil
+}
+
+// CreateImportProfile saves an import profile; ErrImportProfileExists when
+// another one has the name
+func (r *postgresRepository) CreateImportProfile(ctx context.Context, req *models.CreateImportProfileRequest) (*models.ImportProfile, error) {
+	query := `
+		INSERT INTO import_profiles (id, name, source, description, columns, delimiter, created_at, updated_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, ''), $7, $7)
+		RETURNING ` + importProfileColumns
+
+	var p models.ImportProfile
+	err := scanImportProfile(r.db.QueryRow(ctx, query, uuid.New(), req.Name, req.Source, req.Description,
+		req.Columns, req.Delimiter, time.Now()), &p)
+	if err != nil {
+		return nil, importProfileError("create", err)
+	}
+	return &p, nil
+}
+
+// UpdateImportProfile replaces an import profile
+func (r *postgresRepository) UpdateImportProfile(ctx context.Context, id uuid.UUID, req *models.UpdateImportProfileRequest) (*models.ImportProfile, error) {
+	query := `
+		UPDATE import_profiles
+		SET name = $2, source = $3, description = NULLIF($4, ''), columns = $5, delimiter = NULLIF($6, ''), updated_at = $7
+		WHERE id = $1
+		RETURNING ` + importProfileColumns
+
+	var p models.ImportProfile
+	err := scanImportProfile(r.db.QueryRow(ctx, query, id, req.Name, req.Source, req.Description,
+		req.Columns, req.Delimiter, time.Now()), &p)
+	if err != nil {
+		return nil, importProfileError("update", err)
+	}
+	return &p, nil
+}
+
+// DeleteImportProfile removes an import profile
+func (r *postgresRepository) DeleteImportProfile(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM import_profiles WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete import profile: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
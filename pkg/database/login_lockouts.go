@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const lockoutColumns = `username, failures, lockouts, last_failure_at, last_ip, locked_until,
+	COALESCE(locked_until > now(), false)`
+
+func scanLoginLockout(row pgx.Row, l *models.LoginLockout) error {
+	return row.Scan(&l.Username, &l.Failures, &l.Lockouts, &l.LastFailureAt, &l.LastIP, &l.LockedUntil, &l.Locked)
+}
+
+// lockoutKey is the key of a username in core.login_lockouts
+func lockoutKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// GetLoginLockout retrieves the failed-login state of a username
+func (r *postgresRepository) GetLoginLockout(ctx context.Context, username string) (*models.LoginLockout, error) {
+	var l models.LoginLockout
+	err := scanLoginLockout(r.db.QueryRow(ctx, `SELECT `+lockoutColumns+` FROM login_lockouts WHERE username = $1`, lockoutKey(username)), &l)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get login lockout: %w", err)
+	}
+	return &l, nil
+}
+
+// GetLoginLockouts lists the usernames with failed logins, most recent failure first
+func (r *postgresRepository) GetLoginLockouts(ctx context.Context, filter *models.LoginLockoutFilter) ([]*models.LoginLockout, error) {
+	var conds []string
+	var args []any
+	if filter.Locked != nil {
+		args = append(args, *filter.Locked)
+		conds = append(conds, fmt.Sprintf("COALESCE(locked_until > now(), false) = $%d", len(args)))
+	}
+	query := `SELECT ` + lockoutColumns + ` FROM login_lockouts` + whereClause(conds) + `
+		ORDER BY last_failure_at DESC NULLS LAST, username`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query login lockouts: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.LoginLockout
+	for rows.Next() {
+		var l models.LoginLockout
+		if err := scanLoginLockout(rows, &l); err != nil {
+			return nil, fmt.Errorf("failed to scan login lockout: %w", err)
+		}
+		list = append(list, &l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// RecordLoginFailure counts a failed login of a username
+func (r *postgresRepository) RecordLoginFailure(ctx context.Context, username, ip string, window time.Duration) (*models.LoginLockout, error) {
+	now := time.Now()
+	var l models.LoginLockout
+	err := scanLoginLockout(r.db.QueryRow(ctx, `
+		INSERT INTO login_lockouts AS l (username, failures, last_failure_at, last_ip)
+		VALUES ($1, 1, $2, $3)
+		ON CONFLICT (username) DO UPDATE
+		SET failures = CASE WHEN l.last_failure_at IS NULL OR l.last_failure_at < $4 THEN 1 ELSE l.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at,
+			last_ip = EXCLUDED.last_ip
+		RETURNING `+lockoutColumns, lockoutKey(username), now, ip, now.Add(-window)), &l)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return &l, nil
+}
+
+// LockLogin locks a username until the given time, restarting its failure count
+func (r *postgresRepository) LockLogin(ctx context.Context, username string, until time.Time) (*models.LoginLockout, error) {
+	var l models.LoginLockout
+	err := scanLoginLockout(r.db.QueryRow(ctx, `
+		UPDATE login_lockouts SET failures = 0, lockouts = lockouts + 1, locked_until = $2
+		WHERE username = $1
+		RETURNING `+lockoutColumns, lockoutKey(username), until), &l)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to lock login: %w", err)
+	}
+	return &l, nil
+}
+
+// ResetLoginFailures forgets the failed logins and lockouts of a username
+// after a successful login
+func (r *postgresRepository) ResetLoginFailures(ctx context.Context, username string) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM login_lockouts WHERE username = $1`, lockoutKey(username)); err != nil {
+		return fmt.Errorf("failed to reset login failures: %w", err)
+	}
+	return nil
+}
+
+// UnlockLogin lifts the lock of a username and restarts its failure count;
+// its lockouts still count towards the next lock
+func (r *postgresRepository) UnlockLogin(ctx context.Context, username string) (*models.LoginLockout, error) {
+	var l models.LoginLockout
+	err := scanLoginLockout(r.db.QueryRow(ctx, `
+		UPDATE login_lockouts SET failures = 0, locked_until = NULL
+		WHERE username = $1
+		RETURNING `+lockoutColumns, lockoutKey(username)), &l)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to unlock login: %w", err)
+	}
+	return &l, nil
+}
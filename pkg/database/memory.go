@@ -0,0 +1,1147 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/provenance"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// ErrNotSupported is returned by the in-memory repository for operations it does not implement
+var ErrNotSupported = errors.New("not supported by the in-memory repository")
+
+// memoryType is a stored type; merged types stay behind soft-deleted
+type memoryType struct {
+	models.Type
+	deleted bool
+}
+
+// memoryCadence is a submission calendar entry
+type memoryCadence struct {
+	cadence   string
+	updatedAt time.Time
+}
+
+// memoryRepository keeps types, operators, generators, productions, users,
+// grants, the submission calendar and holidays in memory. Snapshots, corrections, annotations,
+// reports and templates are not kept: their listings are empty, lookups find
+// nothing and writes fail with ErrNotSupported.
+type memoryRepository struct {
+	mu        sync.RWMutex
+	types     map[uuid.UUID]*memoryType
+	operators map[uuid.UUID]*models.Operator
+	plants    map[uuid.UUID]*models.Plant
+	regions   map[uuid.UUID]*models.Region
+	users     map[uuid.UUID]*memoryUser
+	grants    map[uuid.UUID]map[uuid.UUID]time.Time
+	// lockouts is keyed by lower-case username
+	lockouts    map[string]*models.LoginLockout
+	twoFactor   map[uuid.UUID]*memoryTwoFactor
+	generators  map[uuid.UUID]*models.Generator
+	productions map[uuid.UUID]*models.Production
+	// translations is keyed by type id, then language
+	translations map[uuid.UUID]map[string]*models.TypeTranslation
+	// cadences is keyed by calendar scope, then type or generator id
+	cadences map[string]map[uuid.UUID]memoryCadence
+	// connectorFiles are the fetched partner feed files in fetch order
+	connectorFiles []*models.ConnectorFile
+	holidays       map[uuid.UUID]*models.Holiday
+}
+
+// NewMemoryRepository creates an empty repository that keeps its data in
+// memory, for demos and development without a database
+func NewMemoryRepository() Repository {
+	return &memoryRepository{
+		types:        map[uuid.UUID]*memoryType{},
+		operators:    map[uuid.UUID]*models.Operator{},
+		plants:       map[uuid.UUID]*models.Plant{},
+		regions:      map[uuid.UUID]*models.Region{},
+		users:        map[uuid.UUID]*memoryUser{},
+		grants:       map[uuid.UUID]map[uuid.UUID]time.Time{},
+		lockouts:     map[string]*models.LoginLockout{},
+		twoFactor:    map[uuid.UUID]*memoryTwoFactor{},
+		generators:   map[uuid.UUID]*models.Generator{},
+		productions:  map[uuid.UUID]*models.Production{},
+		translations: map[uuid.UUID]map[string]*models.TypeTranslation{},
+		holidays:     map[uuid.UUID]*models.Holiday{},
+		cadences: map[string]map[uuid.UUID]memoryCadence{
+			models.CadenceScopeType:      {},
+			models.CadenceScopeGenerator: {},
+		},
+	}
+}
+
+// uuidLess orders ids like PostgreSQL compares uuid values
+func uuidLess(a, b uuid.UUID) bool {
+	return bytes.Compare(a[:], b[:]) < 0
+}
+
+// ===================== Types =====================
+
+func (r *memoryRepository) CreateType(ctx context.Context, req *models.CreateTypeRequest) (*models.Type, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	t := &memoryType{Type: models.Type{
+		ID:             uuid.New(),
+		Name:           req.Name,
+		Description:    req.Description,
+		IsRenewable:    req.IsRenewable,
+		TechnologyCode: req.TechnologyCode,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Version:        1,
+	}}
+	r.types[t.ID] = t
+	out := t.Type
+	return &out, nil
+}
+
+func (r *memoryRepository) GetTypeByID(ctx context.Context, id uuid.UUID) (*models.Type, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.types[id]
+	if !ok || t.deleted {
+		return nil, sql.ErrNoRows
+	}
+	out := t.Type
+	return &out, nil
+}
+
+func (r *memoryRepository) GetAllTypes(ctx context.Context, isRenewable *bool) ([]*models.Type, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var list []*models.Type
+	for _, t := range r.types {
+		if t.deleted || (isRenewable != nil && t.IsRenewable != *isRenewable) {
+			continue
+		}
+		out := t.Type
+		list = append(list, &out)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+func (r *memoryRepository) UpdateType(ctx context.Context, id uuid.UUID, req *models.UpdateTypeRequest) (*models.Type, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.types[id]
+	if !ok || t.deleted {
+		return nil, sql.ErrNoRows
+	}
+	if err := checkVersion(req.IfMatch, t.Version); err != nil {
+		return nil, err
+	}
+	// A PUT writes the name and description even when left out
+	if !req.Fields.Partial() || req.Fields.Has("name") {
+		t.Name = req.Name
+	}
+	if !req.Fields.Partial() || req.Fields.Has("description") {
+		t.Description = req.Description
+	}
+	if req.IsRenewable != nil {
+		t.IsRenewable = *req.IsRenewable
+	}
+	if req.TechnologyCode != "" || req.Fields.Has("technologyCode") {
+		t.TechnologyCode = req.TechnologyCode
+	}
+	t.UpdatedAt = time.Now()
+	t.Version++
+	out := t.Type
+	return &out, nil
+}
+
+// DeleteType removes a type with its generators and their productions, like the foreign key cascades
+func (r *memoryRepository) DeleteType(ctx context.Context, id uuid.UUID, cascade bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.types[id]; !ok {
+		return sql.ErrNoRows
+	}
+	for gid, g := range r.generators {
+		if g.TypeID != id {
+			continue
+		}
+		if !cascade {
+			return fmt.Errorf("failed to delete type: %w by generators", ErrStillReferenced)
+		}
+		r.deleteGenerator(gid)
+	}
+	delete(r.types, id)
+	delete(r.translations, id)
+	delete(r.cadences[models.CadenceScopeType], id)
+	return nil
+}
+
+func (r *memoryRepository) MergeType(ctx context.Context, sourceID, targetID uuid.UUID) (*models.TypeMergeResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	source, okSource := r.types[sourceID]
+	target, okTarget := r.types[targetID]
+	if !okSource || !okTarget || source.deleted || target.deleted || sourceID == targetID {
+		return nil, sql.ErrNoRows
+	}
+
+	now := time.Now()
+	var moved int64
+	for _, g := range r.generators {
+		if g.TypeID == sourceID {
+			g.TypeID = targetID
+			g.UpdatedAt = now
+			g.Version++
+			moved++
+		}
+	}
+	source.deleted = true
+	source.UpdatedAt = now
+	source.Version++
+
+	return &models.TypeMergeResult{
+		SourceID:        sourceID,
+		TargetID:        targetID,
+		Alias:           source.Name,
+		GeneratorsMoved: moved,
+		MergedAt:        now,
+	}, nil
+}
+
+// ===================== Operators =====================
+
+func (r *memoryRepository) CreateOperator(ctx context.Context, req *models.CreateOperatorRequest) (*models.Operator, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	op := &models.Operator{ID: uuid.New(), Name: req.Name, Country: req.Country, CreatedAt: now, UpdatedAt: now}
+	r.operators[op.ID] = op
+	out := *op
+	return &out, nil
+}
+
+func (r *memoryRepository) GetOperatorByID(ctx context.Context, id uuid.UUID) (*models.Operator, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	op, ok := r.operators[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	out := *op
+	return &out, nil
+}
+
+func (r *memoryRepository) GetAllOperators(ctx context.Context) ([]*models.Operator, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var list []*models.Operator
+	for _, op := range r.operators {
+		out := *op
+		list = append(list, &out)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+func (r *memoryRepository) UpdateOperator(ctx context.Context, id uuid.UUID, req *models.UpdateOperatorRequest) (*models.Operator, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	op, ok := r.operators[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	if req.Name != nil {
+		op.Name = *req.Name
+	}
+	if req.Country != nil && *req.Country != "" {
+		op.Country = *req.Country
+	}
+	op.UpdatedAt = time.Now()
+	out := *op
+	return &out, nil
+}
+
+// DeleteOperator deletes an operator. Its generators are kept without operator.
+func (r *memoryRepository) DeleteOperator(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.operators[id]; !ok {
+		return sql.ErrNoRows
+	}
+	for _, g := range r.generators {
+		if g.OperatorID != nil && *g.OperatorID == id {
+			g.OperatorID = nil
+		}
+	}
+	for _, p := range r.plants {
+		if p.OwnerID != nil && *p.OwnerID == id {
+			p.OwnerID = nil
+		}
+	}
+	for _, grants := range r.grants {
+		delete(grants, id)
+	}
+	delete(r.operators, id)
+	return nil
+}
+
+func (r *memoryRepository) GetOperatorGrants(ctx context.Context, userID uuid.UUID) ([]*models.OperatorGrant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var list []*models.OperatorGrant
+	for operatorID, createdAt := range r.grants[userID] {
+		list = append(list, &models.OperatorGrant{
+			UserID:       userID,
+			OperatorID:   operatorID,
+			OperatorName: r.operators[operatorID].Name,
+			CreatedAt:    createdAt,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].OperatorName < list[j].OperatorName })
+	return list, nil
+}
+
+// GrantOperator gives a user write access to the generators of an operator.
+// Granting an existing grant again is a no-op.
+func (r *memoryRepository) GrantOperator(ctx context.Context, userID, operatorID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.operators[operatorID]; !ok {
+		return fmt.Errorf("failed to grant operator: operator %s does not exist", operatorID)
+	}
+	if r.grants[userID] == nil {
+		r.grants[userID] = map[uuid.UUID]time.Time{}
+	}
+	if _, ok := r.grants[userID][operatorID]; !ok {
+		r.grants[userID][operatorID] = time.Now()
+	}
+	return nil
+}
+
+func (r *memoryRepository) RevokeOperator(ctx context.Context, userID, operatorID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.grants[userID][operatorID]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.grants[userID], operatorID)
+	return nil
+}
+
+// ===================== Plants =====================
+
+// plant returns a copy of a stored plant with its owner and generators
+// joined; the caller holds the lock
+func (r *memoryRepository) plant(p *models.Plant) *models.Plant {
+	out := *p
+	if p.OwnerID != nil {
+		id := *p.OwnerID
+		out.OwnerID = &id
+		if op, ok := r.operators[id]; ok {
+			out.OwnerName = op.Name
+		}
+	}
+	out.Latitude, out.Longitude = copyFloat(p.Latitude), copyFloat(p.Longitude)
+	for _, g := range r.generators {
+		if g.PlantID != nil && *g.PlantID == p.ID {
+			out.GeneratorCount++
+			out.TotalCapacity = out.TotalCapacity.Add(g.Capacity)
+		}
+	}
+	out.TotalCapacity = numeric.RoundDecimal(out.TotalCapacity)
+	return &out
+}
+
+// checkPlantRefs returns the error of the name and owner of a plant, ErrPlantExists
+// when another plant has the name; the caller holds the lock
+func (r *memoryRepository) checkPlantRefs(id uuid.UUID, name string, ownerID *uuid.UUID) error {
+	for _, p := range r.plants {
+		if p.ID != id && p.Name == name {
+			return ErrPlantExists
+		}
+	}
+	if ownerID != nil {
+		if _, ok := r.operators[*ownerID]; !ok {
+			return fmt.Errorf("operator %s does not exist", *ownerID)
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) CreatePlant(ctx context.Context, req *models.CreatePlantRequest) (*models.Plant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	p := &models.Plant{ID: uuid.New(), Name: req.Name, Location: req.Location, CreatedAt: now, UpdatedAt: now}
+	if err := r.checkPlantRefs(p.ID, req.Name, req.OwnerID); err != nil {
+		return nil, fmt.Errorf("failed to create plant: %w", err)
+	}
+	if req.OwnerID != nil {
+		id := *req.OwnerID
+		p.OwnerID = &id
+	}
+	p.Latitude, p.Longitude = copyFloat(req.Latitude), copyFloat(req.Longitude)
+	r.plants[p.ID] = p
+	return r.plant(p), nil
+}
+
+func (r *memoryRepository) GetPlantByID(ctx context.Context, id uuid.UUID) (*models.Plant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.plants[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return r.plant(p), nil
+}
+
+func (r *memoryRepository) GetAllPlants(ctx context.Context, filter *models.PlantFilter) ([]*models.Plant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var list []*models.Plant
+	for _, p := range r.plants {
+		if filter.OwnerID != nil && (p.OwnerID == nil || *p.OwnerID != *filter.OwnerID) {
+			continue
+		}
+		list = append(list, r.plant(p))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	if filter.Offset >= len(list) {
+		return nil, nil
+	}
+	list = list[filter.Offset:]
+	if filter.Limit > 0 && len(list) > filter.Limit {
+		list = list[:filter.Limit]
+	}
+	return list, nil
+}
+
+func (r *memoryRepository) UpdatePlant(ctx context.Context, id uuid.UUID, req *models.UpdatePlantRequest) (*models.Plant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.plants[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	name, ownerID := p.Name, p.OwnerID
+	if req.Name != nil {
+		name = *req.Name
+	}
+	if req.OwnerID != nil {
+		id := *req.OwnerID
+		ownerID = &id
+	}
+	if err := r.checkPlantRefs(id, name, ownerID); err != nil {
+		return nil, fmt.Errorf("failed to update plant: %w", err)
+	}
+	p.Name, p.OwnerID = name, ownerID
+	if req.Location != nil {
+		p.Location = *req.Location
+	}
+	if req.Latitude != nil && req.Longitude != nil {
+		p.Latitude, p.Longitude = copyFloat(req.Latitude), copyFloat(req.Longitude)
+	}
+	p.UpdatedAt = time.Now()
+	return r.plant(p), nil
+}
+
+// DeletePlant deletes a plant. Its generators are kept without plant.
+func (r *memoryRepository) DeletePlant(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.plants[id]; !ok {
+		return sql.ErrNoRows
+	}
+	for _, g := range r.generators {
+		if g.PlantID != nil && *g.PlantID == id {
+			g.PlantID = nil
+		}
+	}
+	delete(r.plants, id)
+	return nil
+}
+
+// ===================== Regions =====================
+
+// region returns a copy of a stored region with its generators; the caller
+// holds the lock
+func (r *memoryRepository) region(rg *models.Region) *models.Region {
+	out := *rg
+	out.BBox = append([]float64(nil), rg.BBox...)
+	if len(out.BBox) == 0 {
+		out.BBox = nil
+	}
+	for _, g := range r.generators {
+		if g.RegionID != nil && *g.RegionID == rg.ID {
+			out.GeneratorCount++
+			out.TotalCapacity = out.TotalCapacity.Add(g.Capacity)
+		}
+	}
+	out.TotalCapacity = numeric.RoundDecimal(out.TotalCapacity)
+	return &out
+}
+
+// checkRegionNames returns ErrRegionExists when another region has the code
+// or name; the caller holds the lock
+func (r *memoryRepository) checkRegionNames(id uuid.UUID, code, name string) error {
+	for _, rg := range r.regions {
+		if rg.ID != id && (rg.Code == code || rg.Name == name) {
+			return ErrRegionExists
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) CreateRegion(ctx context.Context, req *models.CreateRegionRequest) (*models.Region, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	rg := &models.Region{ID: uuid.New(), Code: regionCode(req.Code), Name: strings.TrimSpace(req.Name), CreatedAt: now, UpdatedAt: now}
+	if err := r.checkRegionNames(rg.ID, rg.Code, rg.Name); err != nil {
+		return nil, err
+	}
+	rg.BBox = append([]float64(nil), req.BBox...)
+	r.regions[rg.ID] = rg
+	return r.region(rg), nil
+}
+
+func (r *memoryRepository) GetRegionByID(ctx context.Context, id uuid.UUID) (*models.Region, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rg, ok := r.regions[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return r.region(rg), nil
+}
+
+func (r *memoryRepository) GetAllRegions(ctx context.Context, filter *models.RegionFilter) ([]*models.Region, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var list []*models.Region
+	for _, rg := range r.regions {
+		list = append(list, r.region(rg))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	if filter.Offset >= len(list) {
+		return nil, nil
+	}
+	list = list[filter.Offset:]
+	if filter.Limit > 0 && len(list) > filter.Limit {
+		list = list[:filter.Limit]
+	}
+	return list, nil
+}
+
+func (r *memoryRepository) UpdateRegion(ctx context.Context, id uuid.UUID, req *models.UpdateRegionRequest) (*models.Region, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rg, ok := r.regions[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	code, name := rg.Code, rg.Name
+	if req.Code != nil {
+		code = regionCode(*req.Code)
+	}
+	if req.Name != nil {
+		name = strings.TrimSpace(*req.Name)
+	}
+	if err := r.checkRegionNames(id, code, name); err != nil {
+		return nil, err
+	}
+	rg.Code, rg.Name = code, name
+	if len(req.BBox) == 4 {
+		rg.BBox = append([]float64(nil), req.BBox...)
+	}
+	rg.UpdatedAt = time.Now()
+	return r.region(rg), nil
+}
+
+// DeleteRegion deletes a region. Its generators are kept without region.
+func (r *memoryRepository) DeleteRegion(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.regions[id]; !ok {
+		return sql.ErrNoRows
+	}
+	for _, g := range r.generators {
+		if g.RegionID != nil && *g.RegionID == id {
+			g.RegionID = nil
+		}
+	}
+	delete(r.regions, id)
+	return nil
+}
+
+// ===================== Generators =====================
+
+// generator returns a copy of a stored generator with its type, operator,
+// plant and region joined; the caller holds the lock
+func (r *memoryRepository) generator(g *models.Generator) *models.Generator {
+	out := *g
+	if t, ok := r.types[g.TypeID]; ok {
+		out.TypeName, out.TypeDesc, out.IsRenewable = t.Name, t.Description, t.IsRenewable
+	}
+	if g.OperatorID != nil {
+		id := *g.OperatorID
+		out.OperatorID = &id
+		if op, ok := r.operators[id]; ok {
+			out.OperatorName = op.Name
+		}
+	}
+	if g.PlantID != nil {
+		id := *g.PlantID
+		out.PlantID = &id
+		if p, ok := r.plants[id]; ok {
+			out.PlantName = p.Name
+		}
+	}
+	if g.RegionID != nil {
+		id := *g.RegionID
+		out.RegionID = &id
+		if rg, ok := r.regions[id]; ok {
+			out.RegionName = rg.Name
+		}
+	}
+	out.Latitude, out.Longitude = copyFloat(g.Latitude), copyFloat(g.Longitude)
+	out.CommissionedOn = copyString(g.CommissionedOn)
+	out.Capacity = numeric.RoundDecimal(g.Capacity)
+	return &out
+}
+
+func copyFloat(f *float64) *float64 {
+	if f == nil {
+		return nil
+	}
+	v := *f
+	return &v
+}
+
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := *s
+	return &v
+}
+
+func copyUUID(id *uuid.UUID) *uuid.UUID {
+	if id == nil {
+		return nil
+	}
+	v := *id
+	return &v
+}
+
+// checkGeneratorRefs returns the error of the foreign keys of a generator; the caller holds the lock
+func (r *memoryRepository) checkGeneratorRefs(typeID uuid.UUID, operatorID, plantID, regionID *uuid.UUID) error {
+	if _, ok := r.types[typeID]; !ok {
+		return fmt.Errorf("type %s does not exist", typeID)
+	}
+	if operatorID != nil {
+		if _, ok := r.operators[*operatorID]; !ok {
+			return fmt.Errorf("operator %s does not exist", *operatorID)
+		}
+	}
+	if plantID != nil {
+		if _, ok := r.plants[*plantID]; !ok {
+			return fmt.Errorf("plant %s does not exist", *plantID)
+		}
+	}
+	if regionID != nil {
+		if _, ok := r.regions[*regionID]; !ok {
+			return fmt.Errorf("region %s does not exist", *regionID)
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) CreateGenerator(ctx context.Context, req *models.CreateGeneratorRequest) (*models.Generator, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.checkGeneratorRefs(req.TypeID, req.OperatorID, req.PlantID, req.RegionID); err != nil {
+		return nil, fmt.Errorf("failed to create generator: %w", err)
+	}
+	now := time.Now()
+	g := &models.Generator{ID: uuid.New(), TypeID: req.TypeID, Capacity: req.Capacity, CreatedAt: now, UpdatedAt: now, Version: 1}
+	if req.OperatorID != nil {
+		id := *req.OperatorID
+		g.OperatorID = &id
+	}
+	if req.PlantID != nil {
+		id := *req.PlantID
+		g.PlantID = &id
+	}
+	if req.RegionID != nil {
+		id := *req.RegionID
+		g.RegionID = &id
+	}
+	g.Latitude, g.Longitude = copyFloat(req.Latitude), copyFloat(req.Longitude)
+	g.CommissionedOn = copyString(req.CommissionedOn)
+	r.generators[g.ID] = g
+	return r.generator(g), nil
+}
+
+func (r *memoryRepository) GetGeneratorByID(ctx context.Context, id uuid.UUID) (*models.Generator, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	g, ok := r.generators[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return r.generator(g), nil
+}
+
+func (r *memoryRepository) GetAllGenerators(ctx context.Context, filter models.GeneratorFilter) ([]*models.Generator, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var list []*models.Generator
+	for _, g := range r.generators {
+		if filter.TypeID != nil && g.TypeID != *filter.TypeID {
+			continue
+		}
+		if filter.OperatorID != nil && (g.OperatorID == nil || *g.OperatorID != *filter.OperatorID) {
+			continue
+		}
+		if filter.PlantID != nil && (g.PlantID == nil || *g.PlantID != *filter.PlantID) {
+			continue
+		}
+		if filter.RegionID != nil && (g.RegionID == nil || *g.RegionID != *filter.RegionID) {
+			continue
+		}
+		if !matchesGeoFilter(g, filter) {
+			continue
+		}
+		list = append(list, r.generator(g))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].TypeName != list[j].TypeName {
+			return list[i].TypeName < list[j].TypeName
+		}
+		return list[i].Capacity.GreaterThan(list[j].Capacity)
+	})
+	if filter.Near != nil {
+		sortByDistance(list, *filter.Near)
+	}
+	return list, nil
+}
+
+func (r *memoryRepository) UpdateGenerator(ctx context.Context, id uuid.UUID, req *models.UpdateGeneratorRequest) (*models.Generator, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g, ok := r.generators[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	if err := checkVersion(req.IfMatch, g.Version); err != nil {
+		return nil, err
+	}
+	typeID, operatorID, plantID, regionID := g.TypeID, g.OperatorID, g.PlantID, g.RegionID
+	if req.TypeID != nil {
+		typeID = *req.TypeID
+	}
+	// A PATCH clears the references set to null
+	if req.OperatorID != nil || req.Fields.Has("operatorId") {
+		operatorID = copyUUID(req.OperatorID)
+	}
+	if req.PlantID != nil || req.Fields.Has("plantId") {
+		plantID = copyUUID(req.PlantID)
+	}
+	if req.RegionID != nil || req.Fields.Has("regionId") {
+		regionID = copyUUID(req.RegionID)
+	}
+	if err := r.checkGeneratorRefs(typeID, operatorID, plantID, regionID); err != nil {
+		return nil, fmt.Errorf("failed to update generator: %w", err)
+	}
+	g.TypeID, g.OperatorID, g.PlantID, g.RegionID = typeID, operatorID, plantID, regionID
+	if req.Capacity != nil {
+		g.Capacity = *req.Capacity
+	}
+	if (req.Latitude != nil && req.Longitude != nil) || req.Fields.Has("latitude") {
+		g.Latitude, g.Longitude = copyFloat(req.Latitude), copyFloat(req.Longitude)
+	}
+	if req.CommissionedOn != nil || req.Fields.Has("commissionedOn") {
+		g.CommissionedOn = copyString(req.CommissionedOn)
+	}
+	g.UpdatedAt = time.Now()
+	g.Version++
+	return r.generator(g), nil
+}
+
+func (r *memoryRepository) DeleteGenerator(ctx context.Context, id uuid.UUID, cascade bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.generators[id]; !ok {
+		return sql.ErrNoRows
+	}
+	if !cascade {
+		for _, p := range r.productions {
+			if p.GeneratorID == id {
+				return fmt.Errorf("failed to delete generator: %w by productions", ErrStillReferenced)
+			}
+		}
+	}
+	r.deleteGenerator(id)
+	return nil
+}
+
+// deleteGenerator removes a generator with its productions and cadence; the caller holds the lock
+func (r *memoryRepository) deleteGenerator(id uuid.UUID) {
+	for pid, p := range r.productions {
+		if p.GeneratorID == id {
+			delete(r.productions, pid)
+		}
+	}
+	delete(r.cadences[models.CadenceScopeGenerator], id)
+	delete(r.generators, id)
+}
+
+// ===================== Productions =====================
+
+// production returns a copy of a stored production with its generator and
+// type joined, its signature verified and the rounding policy applied; the
+// caller holds the lock
+func (r *memoryRepository) production(p *models.Production) *models.Production {
+	out := *p
+	if g, ok := r.generators[p.GeneratorID]; ok {
+		out.GeneratorCapacity = numeric.RoundDecimal(g.Capacity)
+		if t, ok := r.types[g.TypeID]; ok {
+			out.TypeName, out.IsRenewable = t.Name, t.IsRenewable
+		}
+	}
+	out.SignatureValid = provenance.Verify(productionRecord(p), p.Signature)
+	out.ProductionMW = numeric.RoundDecimal(p.ProductionMW)
+	return &out
+}
+
+func productionRecord(p *models.Production) provenance.Record {
+	return provenance.Record{
+		ID:           p.ID,
+		GeneratorID:  p.GeneratorID,
+		Date:         p.Date,
+		ProductionMW: p.ProductionMW,
+		Source:       p.Source,
+		SourceRef:    p.SourceRef,
+	}
+}
+
+// checkProduction enforces the foreign key, date format and the one record
+// per generator and date of the productions table; the caller holds the lock
+func (r *memoryRepository) checkProduction(p *models.Production) error {
+	if _, ok := r.generators[p.GeneratorID]; !ok {
+		return fmt.Errorf("generator %s does not exist", p.GeneratorID)
+	}
+	date, err := productionDate(p.Date)
+	if err != nil {
+		return err
+	}
+	p.Date = date.Format(utils.DateLayout)
+	for _, other := range r.productions {
+		if other.ID != p.ID && other.GeneratorID == p.GeneratorID && other.Date == p.Date {
+			return fmt.Errorf("%w (%s on %s)", ErrDuplicateProduction, p.GeneratorID, p.Date)
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) CreateProduction(ctx context.Context, req *models.CreateProductionRequest) (*models.Production, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	p := &models.Production{
+		ID:           uuid.New(),
+		GeneratorID:  req.GeneratorID,
+		Date:         req.Date,
+		ProductionMW: req.ProductionMW,
+		Source:       req.Source,
+		SourceRef:    req.SourceRef,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Version:      1,
+	}
+	if p.Source == "" {
+		p.Source = "api"
+	}
+	if err := r.checkProduction(p); err != nil {
+		return nil, fmt.Errorf("failed to create production: %w", err)
+	}
+	if provenance.Enabled() {
+		p.Signature = provenance.Sign(productionRecord(p))
+	}
+	r.productions[p.ID] = p
+	return r.production(p), nil
+}
+
+func (r *memoryRepository) UpsertProduction(ctx context.Context, req *models.CreateProductionRequest) (*models.Production, bool, error) {
+	r.mu.Lock()
+	stored := r.productionOn(req.GeneratorID, req.Date)
+	if stored == nil {
+		r.mu.Unlock()
+		p, err := r.CreateProduction(ctx, req)
+		return p, err == nil, err
+	}
+	defer r.mu.Unlock()
+	stored.ProductionMW = req.ProductionMW
+	stored.Source, stored.SourceRef = req.Source, req.SourceRef
+	if stored.Source == "" {
+		stored.Source = "api"
+	}
+	stored.UpdatedAt = time.Now()
+	stored.Version++
+	if provenance.Enabled() {
+		stored.Signature = provenance.Sign(productionRecord(stored))
+	}
+	return r.production(stored), false, nil
+}
+
+func (r *memoryRepository) CreateProductions(ctx context.Context, reqs []*models.CreateProductionRequest) ([]*models.Production, []error, error) {
+	created := make([]*models.Production, len(reqs))
+	errs := make([]error, len(reqs))
+	for i, req := range reqs {
+		r.mu.RLock()
+		_, known := r.generators[req.GeneratorID]
+		duplicate := r.productionOn(req.GeneratorID, req.Date) != nil
+		r.mu.RUnlock()
+		switch {
+		case !known:
+			errs[i] = errUnknownGenerator
+		case duplicate:
+			errs[i] = ErrDuplicateProduction
+		default:
+			created[i], errs[i] = r.CreateProduction(ctx, req)
+		}
+	}
+	return created, errs, nil
+}
+
+func (r *memoryRepository) RecordTelemetry(ctx context.Context, reading *models.TelemetryReading) (*models.Production, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	p := &models.Production{
+		ID:           uuid.New(),
+		GeneratorID:  reading.GeneratorID,
+		Date:         reading.Date,
+		ProductionMW: reading.Value,
+		CreatedAt:    now,
+	}
+	if err := r.checkProduction(p); err != nil {
+		existing := r.productionOn(p.GeneratorID, p.Date)
+		if existing == nil {
+			return nil, fmt.Errorf("failed to record telemetry: %w", err)
+		}
+		p = existing
+		if reading.Mode == models.TelemetryModeSet {
+			p.ProductionMW = reading.Value
+		} else {
+			p.ProductionMW = p.ProductionMW.Add(reading.Value)
+		}
+	}
+	p.Source = provenance.SourceTelemetry
+	p.SourceRef = reading.Topic
+	p.UpdatedAt = now
+	p.Version++
+	if provenance.Enabled() {
+		p.Signature = provenance.Sign(productionRecord(p))
+	}
+	r.productions[p.ID] = p
+	return r.production(p), nil
+}
+
+// productionOn returns the stored production of a generator on a day; the
+// caller holds the lock
+func (r *memoryRepository) productionOn(generatorID uuid.UUID, date string) *models.Production {
+	for _, p := range r.productions {
+		if p.GeneratorID == generatorID && p.Date == date {
+			return p
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) GetProductionByID(ctx context.Context, id uuid.UUID) (*models.Production, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.productions[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return r.production(p), nil
+}
+
+// matchProductions returns the stored productions matching filter, in listing
+// order (newest date first); the caller holds the lock
+func (r *memoryRepository) matchProductions(filter *models.ProductionFilter) []*models.Production {
+	var list []*models.Production
+	for _, p := range r.productions {
+		if filter.GeneratorID != nil && p.GeneratorID != *filter.GeneratorID {
+			continue
+		}
+		if filter.PlantID != nil {
+			if g, ok := r.generators[p.GeneratorID]; !ok || g.PlantID == nil || *g.PlantID != *filter.PlantID {
+				continue
+			}
+		}
+		if filter.StartDate != nil && *filter.StartDate != "" && p.Date < *filter.StartDate {
+			continue
+		}
+		if filter.EndDate != nil && *filter.EndDate != "" && p.Date > *filter.EndDate {
+			continue
+		}
+		if filter.Source != nil && p.Source != *filter.Source {
+			continue
+		}
+		list = append(list, p)
+	}
+	typeName := func(p *models.Production) string {
+		if g, ok := r.generators[p.GeneratorID]; ok {
+			if t, ok := r.types[g.TypeID]; ok {
+				return t.Name
+			}
+		}
+		return ""
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Date != b.Date {
+			return a.Date > b.Date
+		}
+		if ta, tb := typeName(a), typeName(b); ta != tb {
+			return ta < tb
+		}
+		return uuidLess(a.ID, b.ID)
+	})
+	return list
+}
+
+func (r *memoryRepository) GetAllProductions(ctx context.Context, filter *models.ProductionFilter) ([]*models.Production, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	matched := r.matchProductions(filter)
+	if filter.Offset > 0 {
+		if filter.Offset >= len(matched) {
+			matched = nil
+		} else {
+			matched = matched[filter.Offset:]
+		}
+	}
+	if filter.Limit > 0 && filter.Limit < len(matched) {
+		matched = matched[:filter.Limit]
+	}
+	var list []*models.Production
+	for _, p := range matched {
+		list = append(list, r.production(p))
+	}
+	return list, nil
+}
+
+func (r *memoryRepository) UpdateProduction(ctx context.Context, id uuid.UUID, req *models.UpdateProductionRequest) (*models.Production, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.productions[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	if err := checkVersion(req.IfMatch, stored.Version); err != nil {
+		return nil, err
+	}
+	p := applyProductionUpdate(*stored, req)
+	if err := r.checkProduction(&p); err != nil {
+		return nil, fmt.Errorf("failed to update production: %w", err)
+	}
+	p.UpdatedAt = time.Now()
+	p.Version++
+	if provenance.Enabled() {
+		p.Signature = provenance.Sign(productionRecord(&p))
+	}
+	*stored = p
+	return r.production(stored), nil
+}
+
+// applyProductionUpdate returns p with the provided fields of req
+func applyProductionUpdate(p models.Production, req *models.UpdateProductionRequest) models.Production {
+	if req.GeneratorID != nil {
+		p.GeneratorID = *req.GeneratorID
+	}
+	if req.Date != nil {
+		p.Date = *req.Date
+	}
+	if req.ProductionMW != nil {
+		p.ProductionMW = *req.ProductionMW
+	}
+	if req.Source != "" {
+		p.Source, p.SourceRef = req.Source, req.SourceRef
+	} else if req.Fields.Has("sourceRef") {
+		p.SourceRef = req.SourceRef
+	}
+	return p
+}
+
+func (r *memoryRepository) UpdateProductions(ctx context.Context, updates []*models.BulkProductionUpdate, atomic bool) ([]*models.Production, []error, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	updated := make([]*models.Production, len(updates))
+	errs := make([]error, len(updates))
+	// The stored values before the first update of each record, to roll back
+	before := map[uuid.UUID]models.Production{}
+	failed := false
+	now := time.Now()
+	for i, u := range updates {
+		stored, ok := r.productions[u.ID]
+		if !ok {
+			errs[i], failed = errUnknownProduction, true
+			continue
+		}
+		p := applyProductionUpdate(*stored, u.Changes)
+		if _, ok := r.generators[p.GeneratorID]; !ok {
+			errs[i], failed = errUnknownGenerator, true
+			continue
+		}
+		if err := r.checkProduction(&p); err != nil {
+			errs[i], failed = ErrDuplicateProduction, true
+			continue
+		}
+		if _, ok := before[u.ID]; !ok {
+			before[u.ID] = *stored
+		}
+		p.UpdatedAt = now
+		p.Version++
+		if provenance.Enabled() {
+			p.Signature = provenance.Sign(productionRecord(&p))
+		}
+		*stored = p
+	}
+	if atomic && failed {
+		for id, p := range before {
+			*r.productions[id] = p
+		}
+		rollBackBulk(updated, errs)
+		return updated, errs, nil
+	}
+	for i, u := range updates {
+		if errs[i] == nil {
+			updated[i] = r.production(r.productions[u.ID])
+		}
+	}
+	return updated, errs, nil
+}
+
+func (r *memoryRepository) DeleteProduction(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.productions[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.productions, id)
+	return nil
+}
@@ -0,0 +1,670 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/metric"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// memoryRecord is a production joined with its generator, type and operator (nil without one)
+type memoryRecord struct {
+	p  *models.Production
+	g  *models.Generator
+	t  *memoryType
+	op *models.Operator
+}
+
+// records joins the productions dated between startDate and endDate; with
+// asOf, productions and generators created later are left out. Corrections
+// are not kept in memory, so values are the current ones. The caller holds
+// the lock.
+func (r *memoryRepository) records(startDate, endDate *string, asOf *time.Time) []memoryRecord {
+	filter := &models.ProductionFilter{StartDate: startDate, EndDate: endDate}
+	var list []memoryRecord
+	for _, p := range r.matchProductions(filter) {
+		g := r.generators[p.GeneratorID]
+		if asOf != nil && (p.CreatedAt.After(*asOf) || g.CreatedAt.After(*asOf)) {
+			continue
+		}
+		rec := memoryRecord{p: p, g: g, t: r.types[g.TypeID]}
+		if g.OperatorID != nil {
+			rec.op = r.operators[*g.OperatorID]
+		}
+		list = append(list, rec)
+	}
+	return list
+}
+
+// percentOf returns part as a percentage of whole, 0 when whole is zero
+func percentOf(part, whole decimal.Decimal) decimal.Decimal {
+	if whole.IsZero() {
+		return decimal.Zero
+	}
+	return part.Mul(decimal.NewFromInt(100)).DivRound(whole, 16)
+}
+
+func (r *memoryRepository) GetProductionByGenerator(ctx context.Context, startDate, endDate *string) ([]*models.GeneratorProduction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	totals := map[uuid.UUID]*models.GeneratorProduction{}
+	for _, rec := range r.records(startDate, endDate, nil) {
+		g := totals[rec.g.ID]
+		if g == nil {
+			g = &models.GeneratorProduction{GeneratorID: rec.g.ID}
+			totals[rec.g.ID] = g
+		}
+		g.TotalProduction = g.TotalProduction.Add(rec.p.ProductionMW)
+	}
+	list := make([]*models.GeneratorProduction, 0, len(totals))
+	for _, g := range totals {
+		g.TotalProduction = numeric.RoundDecimal(g.TotalProduction)
+		list = append(list, g)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].GeneratorID.String() < list[j].GeneratorID.String() })
+	return list, nil
+}
+
+func (r *memoryRepository) GetGeneratorEfficiency(ctx context.Context, startDate, endDate *string) ([]*models.GeneratorEfficiency, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	production := map[uuid.UUID]decimal.Decimal{}
+	days := map[uuid.UUID]map[string]bool{}
+	for _, rec := range r.records(startDate, endDate, nil) {
+		production[rec.g.ID] = production[rec.g.ID].Add(rec.p.ProductionMW)
+		if days[rec.g.ID] == nil {
+			days[rec.g.ID] = map[string]bool{}
+		}
+		days[rec.g.ID][rec.p.Date] = true
+	}
+	list := make([]*models.GeneratorEfficiency, 0, len(r.generators))
+	for _, g := range r.generators {
+		e := &models.GeneratorEfficiency{GeneratorID: g.ID, Capacity: g.Capacity, TotalProduction: production[g.ID]}
+		if t, ok := r.types[g.TypeID]; ok {
+			e.TypeName = t.Name
+		}
+		if n := len(days[g.ID]); n > 0 {
+			e.AvgDailyProduction = e.TotalProduction.DivRound(decimal.NewFromInt(int64(n)), 16)
+		}
+		e.EfficiencyPercentage = percentOf(e.AvgDailyProduction, g.Capacity)
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if !a.EfficiencyPercentage.Equal(b.EfficiencyPercentage) {
+			return a.EfficiencyPercentage.GreaterThan(b.EfficiencyPercentage)
+		}
+		return a.GeneratorID.String() < b.GeneratorID.String()
+	})
+	for _, e := range list {
+		e.Capacity = numeric.RoundDecimal(e.Capacity)
+		e.TotalProduction = numeric.RoundDecimal(e.TotalProduction)
+		e.AvgDailyProduction = numeric.RoundDecimal(e.AvgDailyProduction)
+		e.EfficiencyPercentage = numeric.RoundDecimal(e.EfficiencyPercentage)
+	}
+	return list, nil
+}
+
+func (r *memoryRepository) GetRenewableSummary(ctx context.Context, startDate, endDate *string) ([]*models.RenewableSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	renewable := &models.RenewableSummary{EnergyType: "Renewable"}
+	nonRenewable := &models.RenewableSummary{EnergyType: "Non-renewable"}
+	class := func(typeID uuid.UUID) *models.RenewableSummary {
+		if t, ok := r.types[typeID]; ok && t.IsRenewable {
+			return renewable
+		}
+		return nonRenewable
+	}
+	for _, g := range r.generators {
+		s := class(g.TypeID)
+		s.GeneratorCount++
+		s.TotalCapacity = s.TotalCapacity.Add(g.Capacity)
+	}
+	records := map[*models.RenewableSummary]int64{}
+	var total decimal.Decimal
+	for _, rec := range r.records(startDate, endDate, nil) {
+		s := class(rec.g.TypeID)
+		s.TotalProduction = s.TotalProduction.Add(rec.p.ProductionMW)
+		records[s]++
+		total = total.Add(rec.p.ProductionMW)
+	}
+	list := []*models.RenewableSummary{renewable, nonRenewable}
+	for _, s := range list {
+		if n := records[s]; n > 0 {
+			s.AvgProduction = numeric.RoundDecimal(s.TotalProduction.DivRound(decimal.NewFromInt(n), 16))
+		}
+		s.PercentageOfTotal = numeric.RoundDecimal(percentOf(s.TotalProduction, total))
+		s.TotalCapacity = numeric.RoundDecimal(s.TotalCapacity)
+		s.TotalProduction = numeric.RoundDecimal(s.TotalProduction)
+	}
+	return list, nil
+}
+
+func (r *memoryRepository) GetProductionFacets(ctx context.Context, filter *models.ProductionFilter) (*models.ProductionFacets, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	facets := models.ProductionFacets{
+		Generators: []*models.GeneratorFacet{},
+		Types:      []*models.TypeFacet{},
+		Sources:    []*models.SourceFacet{},
+	}
+	generators := map[uuid.UUID]*models.GeneratorFacet{}
+	types := map[uuid.UUID]*models.TypeFacet{}
+	sources := map[string]*models.SourceFacet{}
+	for _, rec := range r.records(filter.StartDate, filter.EndDate, nil) {
+		if filter.GeneratorID != nil && rec.p.GeneratorID != *filter.GeneratorID {
+			continue
+		}
+		if filter.PlantID != nil && (rec.g.PlantID == nil || *rec.g.PlantID != *filter.PlantID) {
+			continue
+		}
+		if filter.Source != nil && rec.p.Source != *filter.Source {
+			continue
+		}
+		facets.Total++
+		date := rec.p.Date
+		if facets.MinDate == nil || date < *facets.MinDate {
+			facets.MinDate = &date
+		}
+		if facets.MaxDate == nil || date > *facets.MaxDate {
+			facets.MaxDate = &date
+		}
+		if generators[rec.g.ID] == nil {
+			f := &models.GeneratorFacet{ID: rec.g.ID, TypeName: rec.t.Name, Capacity: numeric.RoundDecimal(rec.g.Capacity)}
+			if rec.op != nil {
+				f.OperatorName = rec.op.Name
+			}
+			generators[rec.g.ID] = f
+			facets.Generators = append(facets.Generators, f)
+		}
+		generators[rec.g.ID].Count++
+		if types[rec.t.ID] == nil {
+			types[rec.t.ID] = &models.TypeFacet{ID: rec.t.ID, Name: rec.t.Name, IsRenewable: rec.t.IsRenewable}
+			facets.Types = append(facets.Types, types[rec.t.ID])
+		}
+		types[rec.t.ID].Count++
+		if sources[rec.p.Source] == nil {
+			sources[rec.p.Source] = &models.SourceFacet{Source: rec.p.Source}
+			facets.Sources = append(facets.Sources, sources[rec.p.Source])
+		}
+		sources[rec.p.Source].Count++
+	}
+
+	sort.Slice(facets.Generators, func(i, j int) bool {
+		a, b := facets.Generators[i], facets.Generators[j]
+		if a.TypeName != b.TypeName {
+			return a.TypeName < b.TypeName
+		}
+		if !a.Capacity.Equal(b.Capacity) {
+			return a.Capacity.GreaterThan(b.Capacity)
+		}
+		return uuidLess(a.ID, b.ID)
+	})
+	sort.Slice(facets.Types, func(i, j int) bool { return facets.Types[i].Name < facets.Types[j].Name })
+	sort.Slice(facets.Sources, func(i, j int) bool {
+		a, b := facets.Sources[i], facets.Sources[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Source < b.Source
+	})
+	return &facets, nil
+}
+
+func (r *memoryRepository) GetTotalProductionByDate(ctx context.Context, startDate, endDate *string, asOf *time.Time) ([]*models.TotalProductionByDate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	byDate := map[string]*models.TotalProductionByDate{}
+	var list []*models.TotalProductionByDate
+	for _, rec := range r.records(startDate, endDate, asOf) {
+		t := byDate[rec.p.Date]
+		if t == nil {
+			t = &models.TotalProductionByDate{Date: rec.p.Date}
+			byDate[rec.p.Date] = t
+			list = append(list, t)
+		}
+		t.TotalProduction = t.TotalProduction.Add(rec.p.ProductionMW)
+		if rec.t.IsRenewable {
+			t.RenewableProduction = t.RenewableProduction.Add(rec.p.ProductionMW)
+		} else {
+			t.NonRenewableProduction = t.NonRenewableProduction.Add(rec.p.ProductionMW)
+		}
+	}
+	// records are newest first, so list already is
+	for _, t := range list {
+		t.TotalProduction = numeric.RoundDecimal(t.TotalProduction)
+		t.RenewableProduction = numeric.RoundDecimal(t.RenewableProduction)
+		t.NonRenewableProduction = numeric.RoundDecimal(t.NonRenewableProduction)
+	}
+	return list, nil
+}
+
+// GetMixByRegion returns the renewable and non-renewable production of every
+// region, ordered by name. Generators without region are grouped as
+// "Unassigned", last and only when there are any.
+func (r *memoryRepository) GetMixByRegion(ctx context.Context, startDate, endDate *string) ([]*models.RegionMix, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var list []*models.RegionMix
+	byRegion := map[uuid.UUID]*models.RegionMix{}
+	for _, rg := range r.regions {
+		id := rg.ID
+		m := &models.RegionMix{RegionID: &id, RegionCode: rg.Code, RegionName: rg.Name}
+		byRegion[id] = m
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].RegionName < list[j].RegionName })
+	unassigned := &models.RegionMix{RegionName: "Unassigned"}
+	mix := func(g *models.Generator) *models.RegionMix {
+		if g.RegionID != nil {
+			if m, ok := byRegion[*g.RegionID]; ok {
+				return m
+			}
+		}
+		return unassigned
+	}
+	for _, g := range r.generators {
+		mix(g).GeneratorCount++
+	}
+	for _, rec := range r.records(startDate, endDate, nil) {
+		m := mix(rec.g)
+		m.Records++
+		m.TotalProduction = m.TotalProduction.Add(rec.p.ProductionMW)
+		if rec.t.IsRenewable {
+			m.RenewableProduction = m.RenewableProduction.Add(rec.p.ProductionMW)
+		} else {
+			m.NonRenewableProduction = m.NonRenewableProduction.Add(rec.p.ProductionMW)
+		}
+	}
+	if unassigned.GeneratorCount > 0 {
+		list = append(list, unassigned)
+	}
+	for _, m := range list {
+		shareRegionMix(m)
+	}
+	return list, nil
+}
+
+// GetMarketShareByOperator returns capacity and production totals per operator with
+// their share of the whole fleet. Generators without operator are grouped as "Unassigned".
+func (r *memoryRepository) GetMarketShareByOperator(ctx context.Context, startDate, endDate *string, asOf *time.Time) ([]*models.OperatorMarketShare, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	production := map[uuid.UUID]decimal.Decimal{}
+	for _, rec := range r.records(startDate, endDate, asOf) {
+		production[rec.g.ID] = production[rec.g.ID].Add(rec.p.ProductionMW)
+	}
+
+	byOperator := map[uuid.UUID]*models.OperatorMarketShare{}
+	var (
+		list                       []*models.OperatorMarketShare
+		totalCapacity, totalOutput decimal.Decimal
+	)
+	for _, g := range r.generators {
+		if asOf != nil && g.CreatedAt.After(*asOf) {
+			continue
+		}
+		var key uuid.UUID
+		if g.OperatorID != nil {
+			key = *g.OperatorID
+		}
+		m := byOperator[key]
+		if m == nil {
+			m = &models.OperatorMarketShare{OperatorName: "Unassigned"}
+			if op, ok := r.operators[key]; ok {
+				id := op.ID
+				m.OperatorID, m.OperatorName = &id, op.Name
+			}
+			byOperator[key] = m
+			list = append(list, m)
+		}
+		m.GeneratorCount++
+		m.TotalCapacity = m.TotalCapacity.Add(g.Capacity)
+		m.TotalProduction = m.TotalProduction.Add(production[g.ID])
+		totalCapacity = totalCapacity.Add(g.Capacity)
+		totalOutput = totalOutput.Add(production[g.ID])
+	}
+	for _, m := range list {
+		m.ProductionShare = numeric.RoundDecimal(percentOf(m.TotalProduction, totalOutput))
+		m.CapacityShare = numeric.RoundDecimal(percentOf(m.TotalCapacity, totalCapacity))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if !a.TotalProduction.Equal(b.TotalProduction) {
+			return a.TotalProduction.GreaterThan(b.TotalProduction)
+		}
+		return a.OperatorName < b.OperatorName
+	})
+	for _, m := range list {
+		m.TotalCapacity = numeric.RoundDecimal(m.TotalCapacity)
+		m.TotalProduction = numeric.RoundDecimal(m.TotalProduction)
+	}
+	return list, nil
+}
+
+// memoryDimensions computes the crosstab dimensions of crosstabDimensions from a record
+var memoryDimensions = map[string]func(rec memoryRecord) string{
+	"type": func(rec memoryRecord) string { return rec.t.Name },
+	"technology": func(rec memoryRecord) string {
+		if rec.t.TechnologyCode == "" {
+			return "UNKNOWN"
+		}
+		return rec.t.TechnologyCode
+	},
+	"renewable": func(rec memoryRecord) string {
+		if rec.t.IsRenewable {
+			return "renewable"
+		}
+		return "non-renewable"
+	},
+	"operator": func(rec memoryRecord) string {
+		if rec.op == nil {
+			return "Unassigned"
+		}
+		return rec.op.Name
+	},
+	"generator": func(rec memoryRecord) string { return rec.g.ID.String() },
+	"source":    func(rec memoryRecord) string { return rec.p.Source },
+	"year":      func(rec memoryRecord) string { return rec.p.Date[:4] },
+	"month":     func(rec memoryRecord) string { return rec.p.Date[:7] },
+	"day":       func(rec memoryRecord) string { return rec.p.Date },
+}
+
+// GetCrosstab aggregates production over two whitelisted dimensions like the
+// PostgreSQL repository, evaluating the metric in Go
+func (r *memoryRepository) GetCrosstab(ctx context.Context, q *models.CrosstabQuery) (*models.Crosstab, error) {
+	expr, value, err := parseCrosstab(q)
+	if err != nil {
+		return nil, err
+	}
+	rowKey, colKey := memoryDimensions[q.Rows], memoryDimensions[q.Columns]
+
+	r.mu.RLock()
+	type pair struct{ row, col string }
+	var (
+		all    []metric.Row
+		groups = map[pair][]metric.Row{}
+		rows   = map[string][]metric.Row{}
+		cols   = map[string][]metric.Row{}
+	)
+	for _, rec := range r.records(q.StartDate, q.EndDate, q.AsOf) {
+		row := metric.Row{"productionMw": rec.p.ProductionMW, "capacity": rec.g.Capacity}
+		rk, ck := rowKey(rec), colKey(rec)
+		all = append(all, row)
+		groups[pair{rk, ck}] = append(groups[pair{rk, ck}], row)
+		rows[rk] = append(rows[rk], row)
+		cols[ck] = append(cols[ck], row)
+	}
+	r.mu.RUnlock()
+
+	eval := func(rows []metric.Row) *decimal.Decimal {
+		v := expr.Eval(rows)
+		if v == nil {
+			return nil
+		}
+		rounded := numeric.RoundDecimal(*v)
+		return &rounded
+	}
+	var cells []crosstabCell
+	for k, group := range groups {
+		cells = append(cells, crosstabCell{row: k.row, col: k.col, value: eval(group)})
+	}
+	rowTotals := make(map[string]*decimal.Decimal, len(rows))
+	for k, group := range rows {
+		rowTotals[k] = eval(group)
+	}
+	colTotals := make(map[string]*decimal.Decimal, len(cols))
+	for k, group := range cols {
+		colTotals[k] = eval(group)
+	}
+	return assembleCrosstab(q, value, cells, rowTotals, colTotals, eval(all)), nil
+}
+
+// cadenceOf is the expected cadence of a generator: its own, else its type's, else daily; the caller holds the lock
+func (r *memoryRepository) cadenceOf(g *models.Generator) string {
+	if c, ok := r.cadences[models.CadenceScopeGenerator][g.ID]; ok {
+		return c.cadence
+	}
+	if c, ok := r.cadences[models.CadenceScopeType][g.TypeID]; ok {
+		return c.cadence
+	}
+	return models.CadenceDaily
+}
+
+// GetLatestProductionDates returns the most recent production date of every
+// generator with its expected submission cadence, those that never reported
+// first and then the oldest
+func (r *memoryRepository) GetLatestProductionDates(ctx context.Context) ([]*models.GeneratorLatestProduction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	latest := map[uuid.UUID]string{}
+	for _, p := range r.productions {
+		if p.Date > latest[p.GeneratorID] {
+			latest[p.GeneratorID] = p.Date
+		}
+	}
+	var list []*models.GeneratorLatestProduction
+	for _, g := range r.generators {
+		l := &models.GeneratorLatestProduction{
+			GeneratorID: g.ID,
+			CreatedOn:   g.CreatedAt.UTC().Format("2006-01-02"),
+			Cadence:     r.cadenceOf(g),
+		}
+		if t, ok := r.types[g.TypeID]; ok {
+			l.TypeName = t.Name
+		}
+		if g.OperatorID != nil {
+			if op, ok := r.operators[*g.OperatorID]; ok {
+				l.OperatorName = op.Name
+			}
+		}
+		if date, ok := latest[g.ID]; ok {
+			l.LatestDate = &date
+		}
+		list = append(list, l)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		switch {
+		case a.LatestDate == nil && b.LatestDate != nil:
+			return true
+		case a.LatestDate != nil && b.LatestDate == nil:
+			return false
+		case a.LatestDate != nil && *a.LatestDate != *b.LatestDate:
+			return *a.LatestDate < *b.LatestDate
+		}
+		return uuidLess(a.GeneratorID, b.GeneratorID)
+	})
+	return list, nil
+}
+
+// ===================== Submission calendar =====================
+
+func (r *memoryRepository) GetSubmissionCalendar(ctx context.Context) ([]*models.SubmissionCadence, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var list []*models.SubmissionCadence
+	for id, c := range r.cadences[models.CadenceScopeType] {
+		entry := &models.SubmissionCadence{Scope: models.CadenceScopeType, ID: id, Name: r.types[id].Name, Cadence: c.cadence, UpdatedAt: c.updatedAt}
+		for _, g := range r.generators {
+			if _, own := r.cadences[models.CadenceScopeGenerator][g.ID]; g.TypeID == id && !own {
+				entry.Generators++
+			}
+		}
+		list = append(list, entry)
+	}
+	for id, c := range r.cadences[models.CadenceScopeGenerator] {
+		entry := &models.SubmissionCadence{Scope: models.CadenceScopeGenerator, ID: id, Cadence: c.cadence, Generators: 1, UpdatedAt: c.updatedAt}
+		if t, ok := r.types[r.generators[id].TypeID]; ok {
+			entry.Name = t.Name
+		}
+		list = append(list, entry)
+	}
+	// Types first, then by name and id
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Scope != b.Scope {
+			return a.Scope > b.Scope
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return uuidLess(a.ID, b.ID)
+	})
+	return list, nil
+}
+
+func (r *memoryRepository) SetSubmissionCadence(ctx context.Context, scope string, id uuid.UUID, cadence string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries, ok := r.cadences[scope]
+	if !ok {
+		return fmt.Errorf("unknown submission calendar scope %q", scope)
+	}
+	exists := false
+	if scope == models.CadenceScopeType {
+		_, exists = r.types[id]
+	} else {
+		_, exists = r.generators[id]
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	entries[id] = memoryCadence{cadence: cadence, updatedAt: time.Now()}
+	return nil
+}
+
+func (r *memoryRepository) DeleteSubmissionCadence(ctx context.Context, scope string, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries, ok := r.cadences[scope]
+	if !ok {
+		return fmt.Errorf("unknown submission calendar scope %q", scope)
+	}
+	if _, ok := entries[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(entries, id)
+	return nil
+}
+
+func (r *memoryRepository) GetEnergyMix(ctx context.Context, date string) (*models.EnergyMix, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	mix := &models.EnergyMix{Date: date, Types: []*models.EnergyMixType{}}
+	byType := map[uuid.UUID]*models.EnergyMixType{}
+	for _, rec := range r.records(&date, &date, nil) {
+		t := byType[rec.t.ID]
+		if t == nil {
+			t = &models.EnergyMixType{TypeID: rec.t.ID, TypeName: rec.t.Name, IsRenewable: rec.t.IsRenewable}
+			byType[rec.t.ID] = t
+			mix.Types = append(mix.Types, t)
+		}
+		t.Records++
+		t.ProductionMW = t.ProductionMW.Add(rec.p.ProductionMW)
+	}
+	sort.SliceStable(mix.Types, func(i, j int) bool {
+		return mix.Types[i].ProductionMW.GreaterThan(mix.Types[j].ProductionMW)
+	})
+	totalMix(mix)
+	return mix, nil
+}
+
+func (r *memoryRepository) ComputeProductions(ctx context.Context, list []*models.Production, fields []string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	peaks := map[uuid.UUID]decimal.Decimal{}
+	for _, p := range r.productions {
+		if peak, ok := peaks[p.GeneratorID]; !ok || p.ProductionMW.GreaterThan(peak) {
+			peaks[p.GeneratorID] = p.ProductionMW
+		}
+	}
+	ratio := func(part, whole decimal.Decimal) *decimal.Decimal {
+		if whole.IsZero() {
+			return nil
+		}
+		v := numeric.RoundDecimal(percentOf(part, whole))
+		return &v
+	}
+	for _, p := range list {
+		for _, f := range fields {
+			switch f {
+			case models.ComputeCapacityFactor:
+				if g, ok := r.generators[p.GeneratorID]; ok {
+					p.CapacityFactor = ratio(p.ProductionMW, g.Capacity)
+				}
+			case models.ComputeUtilization:
+				p.Utilization = ratio(p.ProductionMW, peaks[p.GeneratorID])
+			}
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) GetTimeSeries(ctx context.Context, q *models.TimeSeriesQuery) (*models.TimeSeries, error) {
+	if err := validTimeSeries(q); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	var points []timeSeriesPoint
+	for _, rec := range r.records(q.StartDate, q.EndDate, q.AsOf) {
+		date, err := time.Parse(time.DateOnly, rec.p.Date)
+		if err != nil {
+			continue
+		}
+		p := timeSeriesPoint{bucket: bucketStart(q.GroupBy, date), key: "total", name: "Total", production: rec.p.ProductionMW}
+		switch {
+		case q.Metric == models.TimeSeriesByType:
+			p.key, p.name = rec.t.ID.String(), rec.t.Name
+		case q.Metric == models.TimeSeriesRenewable && rec.t.IsRenewable:
+			p.key, p.name = "renewable", "Renewable"
+		case q.Metric == models.TimeSeriesRenewable:
+			p.key, p.name = "nonRenewable", "Non-renewable"
+		}
+		points = append(points, p)
+	}
+	r.mu.RUnlock()
+	return buildTimeSeries(q, points)
+}
+
+func (r *memoryRepository) GetCapacityFactorTrend(ctx context.Context, id uuid.UUID, q *models.CapacityFactorQuery) (*models.CapacityFactorTrend, error) {
+	r.mu.RLock()
+	g, ok := r.generators[id]
+	if !ok {
+		r.mu.RUnlock()
+		return nil, sql.ErrNoRows
+	}
+	gen := r.generator(g)
+	byBucket := map[time.Time]*capacityFactorPeriod{}
+	var periods []*capacityFactorPeriod
+	for _, p := range r.matchProductions(&models.ProductionFilter{GeneratorID: &id, StartDate: q.StartDate, EndDate: q.EndDate}) {
+		date, err := time.Parse(time.DateOnly, p.Date)
+		if err != nil {
+			continue
+		}
+		b := bucketStart(q.GroupBy, date)
+		period := byBucket[b]
+		if period == nil {
+			period = &capacityFactorPeriod{bucket: b}
+			byBucket[b] = period
+			periods = append(periods, period)
+		}
+		// A generator has one production per date
+		period.production = period.production.Add(p.ProductionMW)
+		period.days++
+	}
+	r.mu.RUnlock()
+	list := make([]capacityFactorPeriod, len(periods))
+	for i, p := range periods {
+		list[i] = *p
+	}
+	return buildCapacityFactorTrend(gen, q, list)
+}
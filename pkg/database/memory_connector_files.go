@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+)
+
+func (r *memoryRepository) ConnectorFileSeen(ctx context.Context, source, remotePath string, size int64, modifiedAt time.Time) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, f := range r.connectorFiles {
+		if f.Source == source && f.RemotePath == remotePath && f.Size == size && f.ModifiedAt.Equal(modifiedAt) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRepository) ConnectorChecksumStaged(ctx context.Context, source, sha256 string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, f := range r.connectorFiles {
+		if f.Source == source && f.SHA256 == sha256 && f.Status == models.ConnectorFileStaged {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRepository) CreateConnectorFile(ctx context.Context, f *models.ConnectorFile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *f
+	r.connectorFiles = append(r.connectorFiles, &stored)
+	return nil
+}
+
+func (r *memoryRepository) GetConnectorFiles(ctx context.Context, connector, source string, limit int) ([]*models.ConnectorFile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := []*models.ConnectorFile{}
+	for i := len(r.connectorFiles) - 1; i >= 0 && len(list) < limit; i-- {
+		if f := r.connectorFiles[i]; f.Connector == connector && (source == "" || f.Source == source) {
+			stored := *f
+			list = append(list, &stored)
+		}
+	}
+	return list, nil
+}
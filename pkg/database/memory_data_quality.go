@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+)
+
+// ScanDataQuality runs the data quality checks (all when empty), listing at
+// most limit findings of each
+func (r *memoryRepository) ScanDataQuality(ctx context.Context, checks []string, limit int) (*models.DataQualityReport, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	productions := make([]*models.Production, 0, len(r.productions))
+	for _, p := range r.productions {
+		productions = append(productions, p)
+	}
+	sort.Slice(productions, func(i, j int) bool {
+		if productions[i].Date != productions[j].Date {
+			return productions[i].Date < productions[j].Date
+		}
+		return productions[i].ID.String() < productions[j].ID.String()
+	})
+	production := func(p *models.Production, detail string) *models.DataQualityFinding {
+		id, date := p.GeneratorID, p.Date
+		return &models.DataQualityFinding{Entity: models.QualityEntityProduction, EntityID: p.ID, GeneratorID: &id, Date: &date, Detail: detail}
+	}
+
+	report := newQualityReport(checks)
+	for _, c := range report.Checks {
+		var found []*models.DataQualityFinding
+		switch c.Check {
+		case models.QualityOrphanProduction:
+			for _, p := range productions {
+				if _, ok := r.generators[p.GeneratorID]; !ok {
+					found = append(found, production(p, "generator "+p.GeneratorID.String()+" does not exist"))
+				}
+			}
+		case models.QualityOrphanGrant:
+			for userID, grants := range r.grants {
+				if _, ok := r.users[userID]; ok {
+					continue
+				}
+				for operatorID := range grants {
+					name := operatorID.String()
+					if op, ok := r.operators[operatorID]; ok {
+						name = op.Name
+					}
+					found = append(found, &models.DataQualityFinding{Entity: models.QualityEntityUser, EntityID: userID,
+						Detail: "user does not exist but has a grant on operator " + name})
+				}
+			}
+			sort.Slice(found, func(i, j int) bool { return found[i].EntityID.String() < found[j].EntityID.String() })
+		case models.QualityDeletedType:
+			for _, g := range r.generators {
+				if t, ok := r.types[g.TypeID]; ok && t.deleted {
+					id := g.ID
+					found = append(found, &models.DataQualityFinding{Entity: models.QualityEntityGenerator, EntityID: g.ID, GeneratorID: &id,
+						Detail: "type " + t.Name + " is deleted"})
+				}
+			}
+			sort.Slice(found, func(i, j int) bool { return found[i].EntityID.String() < found[j].EntityID.String() })
+		case models.QualityBeforeCommissioning:
+			for _, p := range productions {
+				if g, ok := r.generators[p.GeneratorID]; ok && g.CommissionedOn != nil && p.Date < *g.CommissionedOn {
+					found = append(found, production(p, "dated before the commissioning of the generator on "+*g.CommissionedOn))
+				}
+			}
+		case models.QualityOverCapacity:
+			for _, p := range productions {
+				if g, ok := r.generators[p.GeneratorID]; ok && p.ProductionMW.GreaterThan(g.Capacity) {
+					found = append(found, production(p, p.ProductionMW.String()+" MW exceeds the capacity of "+g.Capacity.String()+" MW"))
+				}
+			}
+		case models.QualityUnusedType:
+			used := map[uuid.UUID]bool{}
+			for _, g := range r.generators {
+				used[g.TypeID] = true
+			}
+			for _, t := range r.types {
+				if !t.deleted && !used[t.ID] {
+					found = append(found, &models.DataQualityFinding{Entity: models.QualityEntityType, EntityID: t.ID,
+						Detail: "type " + t.Name + " has no generators"})
+				}
+			}
+			sort.Slice(found, func(i, j int) bool { return strings.ToLower(found[i].Detail) < strings.ToLower(found[j].Detail) })
+		}
+		c.Count = int64(len(found))
+		if len(found) > limit {
+			found = found[:limit]
+			report.Truncated = true
+		}
+		for _, f := range found {
+			f.Check = c.Check
+			report.Findings = append(report.Findings, f)
+		}
+	}
+	return report, nil
+}
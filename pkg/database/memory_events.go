@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+	"github.com/google/uuid"
+)
+
+// The in-memory repository keeps no event log: its projections are computed
+// from the current records on every read, so there is nothing to catch up
+// or rebuild.
+
+func (r *memoryRepository) GetDomainEvents(ctx context.Context, filter *models.DomainEventFilter) ([]*models.DomainEvent, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetProjections(ctx context.Context) ([]*models.Projection, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) CatchUpProjections(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (r *memoryRepository) RebuildProjection(ctx context.Context, name string) (*models.Projection, error) {
+	if _, ok := projectionTables[name]; !ok {
+		return nil, ErrUnknownProjection
+	}
+	return nil, ErrNotSupported
+}
+
+func (r *memoryRepository) GetDailyProductionSummary(ctx context.Context, startDate, endDate *string) ([]*models.DailyProductionSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	type key struct {
+		date   string
+		typeID uuid.UUID
+	}
+	byKey := map[key]*models.DailyProductionSummary{}
+	var list []*models.DailyProductionSummary
+	for _, rec := range r.records(startDate, endDate, nil) {
+		k := key{rec.p.Date, rec.t.ID}
+		s := byKey[k]
+		if s == nil {
+			s = &models.DailyProductionSummary{Date: rec.p.Date, TypeID: rec.t.ID, TypeName: rec.t.Name, IsRenewable: rec.t.IsRenewable}
+			byKey[k] = s
+			list = append(list, s)
+		}
+		s.Records++
+		s.ProductionMW = s.ProductionMW.Add(rec.p.ProductionMW)
+	}
+	// records are newest first and by type name within a day, so list already is
+	for _, s := range list {
+		s.ProductionMW = numeric.RoundDecimal(s.ProductionMW)
+	}
+	return list, nil
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+)
+
+func (r *memoryRepository) ListHolidays(ctx context.Context, country string) ([]*models.Holiday, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	country = strings.ToUpper(country)
+	var list []*models.Holiday
+	for _, h := range r.holidays {
+		if country == "" || h.Country == country {
+			out := *h
+			list = append(list, &out)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Country != b.Country {
+			return a.Country < b.Country
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.Date < b.Date
+	})
+	return list, nil
+}
+
+func (r *memoryRepository) CreateHoliday(ctx context.Context, req *models.HolidayRequest) (*models.Holiday, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := &models.Holiday{
+		ID:        uuid.New(),
+		Country:   strings.ToUpper(req.Country),
+		Region:    regionCode(req.Region),
+		Date:      req.Date,
+		Name:      strings.TrimSpace(req.Name),
+		CreatedAt: time.Now(),
+	}
+	for _, o := range r.holidays {
+		if o.Country == h.Country && o.Region == h.Region && o.Date == h.Date {
+			return nil, ErrHolidayExists
+		}
+	}
+	r.holidays[h.ID] = h
+	out := *h
+	return &out, nil
+}
+
+func (r *memoryRepository) DeleteHoliday(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.holidays[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.holidays, id)
+	return nil
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+)
+
+func (r *memoryRepository) GetTypeTranslations(ctx context.Context, typeID uuid.UUID) ([]*models.TypeTranslation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if t, ok := r.types[typeID]; !ok || t.deleted {
+		return nil, sql.ErrNoRows
+	}
+	list := []*models.TypeTranslation{}
+	for _, tr := range r.translations[typeID] {
+		out := *tr
+		list = append(list, &out)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Language < list[j].Language })
+	return list, nil
+}
+
+func (r *memoryRepository) GetTranslationsByLanguage(ctx context.Context, language string) (map[uuid.UUID]*models.TypeTranslation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	byType := map[uuid.UUID]*models.TypeTranslation{}
+	for id, byLanguage := range r.translations {
+		if tr, ok := byLanguage[language]; ok {
+			out := *tr
+			byType[id] = &out
+		}
+	}
+	return byType, nil
+}
+
+func (r *memoryRepository) SetTypeTranslation(ctx context.Context, typeID uuid.UUID, language string, req *models.TypeTranslationRequest) (*models.TypeTranslation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.types[typeID]; !ok || t.deleted {
+		return nil, sql.ErrNoRows
+	}
+	if r.translations[typeID] == nil {
+		r.translations[typeID] = map[string]*models.TypeTranslation{}
+	}
+	tr := &models.TypeTranslation{TypeID: typeID, Language: language, Name: req.Name, Description: req.Description, UpdatedAt: time.Now()}
+	r.translations[typeID][language] = tr
+	out := *tr
+	return &out, nil
+}
+
+func (r *memoryRepository) DeleteTypeTranslation(ctx context.Context, typeID uuid.UUID, language string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.translations[typeID][language]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.translations[typeID], language)
+	return nil
+}
@@ -0,0 +1,421 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+)
+
+// The in-memory repository keeps no snapshots, corrections, recalculations,
+// annotations, reports, templates, attachments or trash: listings are empty,
+// lookups find nothing and writes fail with ErrNotSupported.
+
+// ===================== Snapshots =====================
+
+func (r *memoryRepository) PublishSnapshot(ctx context.Context, month time.Time) (*models.Snapshot, error) {
+	return nil, fmt.Errorf("failed to publish snapshot: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) GetSnapshots(ctx context.Context) ([]*models.Snapshot, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetSnapshot(ctx context.Context, month time.Time) (*models.SnapshotDetail, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) VerifySnapshot(ctx context.Context, month time.Time) (*models.SnapshotVerification, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) ApplyCorrection(ctx context.Context, productionID uuid.UUID, req *models.CreateCorrectionRequest) (*models.ProductionCorrection, error) {
+	return nil, fmt.Errorf("failed to apply correction: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) GetCorrections(ctx context.Context, productionID uuid.UUID) ([]*models.ProductionCorrection, error) {
+	return nil, nil
+}
+
+// ===================== Recalculations =====================
+
+func (r *memoryRepository) PreviewRecalculation(ctx context.Context, req *models.RecalculationRequest) (*models.Recalculation, error) {
+	return nil, fmt.Errorf("failed to preview recalculation: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) ApplyRecalculation(ctx context.Context, req *models.RecalculationRequest, userID *uuid.UUID) (*models.Recalculation, error) {
+	return nil, fmt.Errorf("failed to apply recalculation: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) GetRecalculations(ctx context.Context) ([]*models.Recalculation, error) {
+	return nil, nil
+}
+
+// ===================== Custom fields =====================
+
+func (r *memoryRepository) GetCustomFields(ctx context.Context, entity string) ([]*models.CustomField, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetCustomFieldByID(ctx context.Context, id uuid.UUID) (*models.CustomField, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) CreateCustomField(ctx context.Context, req *models.CreateCustomFieldRequest) (*models.CustomField, error) {
+	return nil, fmt.Errorf("failed to create custom field: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) UpdateCustomField(ctx context.Context, id uuid.UUID, req *models.UpdateCustomFieldRequest) (*models.CustomField, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) DeleteCustomField(ctx context.Context, id uuid.UUID) error {
+	return sql.ErrNoRows
+}
+
+// ===================== Dead letters =====================
+
+func (r *memoryRepository) CreateDeadLetter(ctx context.Context, d *models.DeadLetter) error {
+	return fmt.Errorf("failed to create dead letter: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) GetDeadLetters(ctx context.Context, filter *models.DeadLetterFilter) ([]*models.DeadLetter, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetDeadLetterByID(ctx context.Context, id uuid.UUID) (*models.DeadLetter, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) UpdateDeadLetter(ctx context.Context, id uuid.UUID, req *models.UpdateDeadLetterRequest) (*models.DeadLetter, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) RecordDeadLetterAttempt(ctx context.Context, id uuid.UUID, productionID *uuid.UUID, errMsg string) (*models.DeadLetter, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) DeleteDeadLetter(ctx context.Context, id uuid.UUID) error {
+	return sql.ErrNoRows
+}
+
+// ===================== Import profiles =====================
+
+func (r *memoryRepository) GetImportProfiles(ctx context.Context, source string) ([]*models.ImportProfile, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetImportProfileByID(ctx context.Context, id uuid.UUID) (*models.ImportProfile, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) CreateImportProfile(ctx context.Context, req *models.CreateImportProfileRequest) (*models.ImportProfile, error) {
+	return nil, fmt.Errorf("failed to create import profile: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) UpdateImportProfile(ctx context.Context, id uuid.UUID, req *models.UpdateImportProfileRequest) (*models.ImportProfile, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) DeleteImportProfile(ctx context.Context, id uuid.UUID) error {
+	return sql.ErrNoRows
+}
+
+// ===================== Annotations =====================
+
+func (r *memoryRepository) CreateAnnotation(ctx context.Context, req *models.CreateAnnotationRequest) (*models.Annotation, error) {
+	return nil, fmt.Errorf("failed to create annotation: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) GetAnnotationByID(ctx context.Context, id uuid.UUID) (*models.Annotation, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) GetAnnotations(ctx context.Context, filter *models.AnnotationFilter) ([]*models.Annotation, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) UpdateAnnotation(ctx context.Context, id uuid.UUID, req *models.UpdateAnnotationRequest) (*models.Annotation, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) DeleteAnnotation(ctx context.Context, id uuid.UUID) error {
+	return sql.ErrNoRows
+}
+
+// ===================== Reports =====================
+
+func (r *memoryRepository) CreateReport(ctx context.Context, req *models.ReportRequest, nextRunAt *time.Time) (*models.Report, error) {
+	return nil, fmt.Errorf("failed to create report: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) GetReports(ctx context.Context) ([]*models.Report, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetReportByID(ctx context.Context, id uuid.UUID) (*models.Report, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) UpdateReport(ctx context.Context, id uuid.UUID, req *models.ReportRequest, nextRunAt *time.Time) (*models.Report, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) DeleteReport(ctx context.Context, id uuid.UUID) error {
+	return sql.ErrNoRows
+}
+
+func (r *memoryRepository) GetDueReports(ctx context.Context, now time.Time) ([]*models.Report, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) ClaimReportRun(ctx context.Context, id uuid.UUID, due time.Time, next *time.Time) (bool, error) {
+	return false, nil
+}
+
+func (r *memoryRepository) CreateReportRun(ctx context.Context, run *models.ReportRun) error {
+	return fmt.Errorf("failed to create report run: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) FinishReportRun(ctx context.Context, run *models.ReportRun) error {
+	return fmt.Errorf("failed to finish report run: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) GetReportRuns(ctx context.Context, reportID uuid.UUID, limit int) ([]*models.ReportRun, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetReportWebhookSecret(ctx context.Context, reportID uuid.UUID) (string, error) {
+	return "", sql.ErrNoRows
+}
+
+func (r *memoryRepository) RotateReportWebhookSecret(ctx context.Context, reportID uuid.UUID) (*models.WebhookSecret, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) CreateWebhookDelivery(ctx context.Context, d *models.WebhookDelivery) error {
+	return fmt.Errorf("failed to record webhook delivery: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) GetWebhookDeliveries(ctx context.Context, reportID uuid.UUID, limit int) ([]*models.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetWebhookDeliveryByID(ctx context.Context, reportID, id uuid.UUID) (*models.WebhookDelivery, error) {
+	return nil, sql.ErrNoRows
+}
+
+// ===================== Report templates =====================
+
+func (r *memoryRepository) CreateReportTemplate(ctx context.Context, req *models.ReportTemplateRequest) (*models.ReportTemplate, error) {
+	return nil, fmt.Errorf("failed to create report template: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) GetReportTemplates(ctx context.Context) ([]*models.ReportTemplate, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetReportTemplateByID(ctx context.Context, id uuid.UUID) (*models.ReportTemplate, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) UpdateReportTemplate(ctx context.Context, id uuid.UUID, req *models.ReportTemplateRequest) (*models.ReportTemplate, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) DeleteReportTemplate(ctx context.Context, id uuid.UUID) error {
+	return sql.ErrNoRows
+}
+
+// ===================== Email templates =====================
+
+func (r *memoryRepository) GetEmailTemplate(ctx context.Context, key string) (*models.EmailTemplate, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) GetEmailTemplateVersions(ctx context.Context, key string) ([]*models.EmailTemplate, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetEmailTemplateVersion(ctx context.Context, key string, version int) (*models.EmailTemplate, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) CreateEmailTemplateVersion(ctx context.Context, key string, req *models.EmailTemplateRequest, userID *uuid.UUID) (*models.EmailTemplate, error) {
+	return nil, fmt.Errorf("failed to create email template version: %w", ErrNotSupported)
+}
+
+// ===================== Notification preferences =====================
+
+func (r *memoryRepository) GetNotificationPreferences(ctx context.Context, userID uuid.UUID) ([]*models.NotificationPreference, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) SetNotificationPreference(ctx context.Context, userID uuid.UUID, alertType string, req *models.NotificationPreferenceRequest) (*models.NotificationPreference, error) {
+	return nil, fmt.Errorf("failed to set notification preference: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) DeleteNotificationPreference(ctx context.Context, userID uuid.UUID, alertType string) error {
+	return sql.ErrNoRows
+}
+
+func (r *memoryRepository) GetNotificationSubscribers(ctx context.Context, alertType string) ([]*models.NotificationSubscriber, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) QueueDigestItem(ctx context.Context, item *models.DigestItem) error {
+	return fmt.Errorf("failed to queue digest item: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) GetDigestItems(ctx context.Context) ([]*models.DigestItem, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) DeleteDigestItems(ctx context.Context, ids []int64) error {
+	return nil
+}
+
+// ===================== Notification inbox =====================
+
+func (r *memoryRepository) CreateNotification(ctx context.Context, n *models.Notification) error {
+	return fmt.Errorf("failed to create notification: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) GetNotifications(ctx context.Context, filter *models.NotificationFilter) ([]*models.Notification, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetUnreadNotifications(ctx context.Context, userID uuid.UUID) (*models.UnreadNotifications, error) {
+	return &models.UnreadNotifications{ByAlertType: map[string]int{}}, nil
+}
+
+func (r *memoryRepository) MarkNotificationRead(ctx context.Context, userID, id uuid.UUID) (*models.Notification, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) MarkNotificationsRead(ctx context.Context, userID uuid.UUID, alertType *string) (int64, error) {
+	return 0, nil
+}
+
+func (r *memoryRepository) PurgeNotifications(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// ===================== Impersonations =====================
+
+func (r *memoryRepository) CreateImpersonation(ctx context.Context, s *models.Impersonation) error {
+	return fmt.Errorf("failed to create impersonation: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) GetImpersonation(ctx context.Context, id uuid.UUID) (*models.Impersonation, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) GetImpersonations(ctx context.Context, filter *models.ImpersonationFilter) ([]*models.Impersonation, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) EndImpersonation(ctx context.Context, id uuid.UUID) (*models.Impersonation, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) CreateImpersonationEvent(ctx context.Context, e *models.ImpersonationEvent) error {
+	return fmt.Errorf("failed to create impersonation event: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) GetImpersonationEvents(ctx context.Context, sessionID uuid.UUID, limit, offset int) ([]*models.ImpersonationEvent, error) {
+	return nil, nil
+}
+
+// ===================== Audit log =====================
+
+func (r *memoryRepository) CreateAuditEntry(ctx context.Context, e *models.AuditEntry) error {
+	return fmt.Errorf("failed to create audit entry: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) GetAuditEntries(ctx context.Context, filter *models.AuditFilter) ([]*models.AuditEntry, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetAuditEntry(ctx context.Context, id int64) (*models.AuditEntry, error) {
+	return nil, sql.ErrNoRows
+}
+
+// ===================== Schema =====================
+
+func (r *memoryRepository) GetSchema(ctx context.Context) (*models.DatabaseSchema, error) {
+	return nil, fmt.Errorf("failed to read schema: %w", ErrNotSupported)
+}
+
+// ===================== Trash =====================
+
+// Deletes in memory are final, so the trash stays empty
+
+func (r *memoryRepository) GetTrash(ctx context.Context, resource string) ([]*models.TrashItem, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetTrashItem(ctx context.Context, id uuid.UUID) (*models.TrashItem, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) RestoreTrashItem(ctx context.Context, id uuid.UUID) (*models.TrashItem, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) PurgeTrashItem(ctx context.Context, id uuid.UUID) error {
+	return sql.ErrNoRows
+}
+
+func (r *memoryRepository) PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+// ===================== Attachments =====================
+
+func (r *memoryRepository) CreateAttachment(ctx context.Context, a *models.Attachment, content []byte) (*models.Attachment, error) {
+	return nil, fmt.Errorf("failed to create attachment: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) GetAttachments(ctx context.Context, scope string, entityID uuid.UUID) ([]*models.Attachment, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetAttachment(ctx context.Context, scope string, entityID, id uuid.UUID) (*models.Attachment, []byte, error) {
+	return nil, nil, sql.ErrNoRows
+}
+
+func (r *memoryRepository) DeleteAttachment(ctx context.Context, scope string, entityID, id uuid.UUID) error {
+	return sql.ErrNoRows
+}
+
+func (r *memoryRepository) GetAttachmentObjectDeletions(ctx context.Context, limit int) ([]string, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) ForgetAttachmentObject(ctx context.Context, key string) error {
+	return nil
+}
+
+// ===================== Read snapshots =====================
+
+// ReadConsistent runs fn on the repository itself: its reads are not isolated
+// from concurrent writes
+func (r *memoryRepository) ReadConsistent(ctx context.Context, fn func(Repository) error) error {
+	return fn(r)
+}
+
+func (r *memoryRepository) OpenReadSnapshot(ctx context.Context) (*models.ReadSnapshot, error) {
+	return nil, fmt.Errorf("failed to open read snapshot: %w", ErrNotSupported)
+}
+
+func (r *memoryRepository) InReadSnapshot(ctx context.Context, id uuid.UUID, fn func(Repository) error) error {
+	return ErrReadSnapshotNotFound
+}
+
+func (r *memoryRepository) CloseReadSnapshot(ctx context.Context, id uuid.UUID) error {
+	return ErrReadSnapshotNotFound
+}
@@ -0,0 +1,287 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+)
+
+// memoryUser is a stored user with its password hash
+type memoryUser struct {
+	models.User
+	passwordHash string
+}
+
+func (r *memoryRepository) CreateUser(ctx context.Context, req *models.RegisterRequest, passwordHash string, roles []string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	username, email := strings.TrimSpace(req.Username), strings.TrimSpace(req.Email)
+	for _, u := range r.users {
+		if strings.EqualFold(u.Username, username) || strings.EqualFold(u.Email, email) {
+			return nil, ErrUserExists
+		}
+	}
+	now := time.Now()
+	u := &memoryUser{
+		User:         models.User{ID: uuid.New(), Username: username, Email: email, Roles: append([]string(nil), roles...), CreatedAt: now, UpdatedAt: now},
+		passwordHash: passwordHash,
+	}
+	r.users[u.ID] = u
+	out := u.User
+	return &out, nil
+}
+
+func (r *memoryRepository) CreateFirstUser(ctx context.Context, req *models.RegisterRequest, passwordHash string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.users) > 0 {
+		return nil, ErrUsersExist
+	}
+	now := time.Now()
+	u := &memoryUser{
+		User:         models.User{ID: uuid.New(), Username: strings.TrimSpace(req.Username), Email: strings.TrimSpace(req.Email), Roles: []string{auth.RoleAdmin}, CreatedAt: now, UpdatedAt: now},
+		passwordHash: passwordHash,
+	}
+	r.users[u.ID] = u
+	out := u.User
+	return &out, nil
+}
+
+func (r *memoryRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	u, ok := r.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	out := u.User
+	return &out, nil
+}
+
+func (r *memoryRepository) GetUserCredentials(ctx context.Context, username string) (*models.User, string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	username = strings.TrimSpace(username)
+	for _, u := range r.users {
+		if strings.EqualFold(u.Username, username) {
+			out := u.User
+			return &out, u.passwordHash, nil
+		}
+	}
+	return nil, "", sql.ErrNoRows
+}
+
+func (r *memoryRepository) SetUserRoles(ctx context.Context, id uuid.UUID, roles []string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	u.Roles = append([]string(nil), roles...)
+	u.UpdatedAt = time.Now()
+	out := u.User
+	return &out, nil
+}
+
+func (r *memoryRepository) CountUsers(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return int64(len(r.users)), nil
+}
+
+// copyLockout returns a copy of a stored lockout with Locked as of now
+func copyLockout(l *models.LoginLockout) *models.LoginLockout {
+	out := *l
+	out.Locked = out.LockedUntil != nil && out.LockedUntil.After(time.Now())
+	return &out
+}
+
+func (r *memoryRepository) GetLoginLockout(ctx context.Context, username string) (*models.LoginLockout, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	l, ok := r.lockouts[lockoutKey(username)]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return copyLockout(l), nil
+}
+
+func (r *memoryRepository) GetLoginLockouts(ctx context.Context, filter *models.LoginLockoutFilter) ([]*models.LoginLockout, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var list []*models.LoginLockout
+	for _, l := range r.lockouts {
+		out := copyLockout(l)
+		if filter.Locked != nil && out.Locked != *filter.Locked {
+			continue
+		}
+		list = append(list, out)
+	}
+	// Lockouts are created by a failure, so they all have one
+	sort.Slice(list, func(i, j int) bool { return list[i].LastFailureAt.After(*list[j].LastFailureAt) })
+	if filter.Offset > 0 {
+		if filter.Offset >= len(list) {
+			list = nil
+		} else {
+			list = list[filter.Offset:]
+		}
+	}
+	if filter.Limit > 0 && filter.Limit < len(list) {
+		list = list[:filter.Limit]
+	}
+	return list, nil
+}
+
+func (r *memoryRepository) RecordLoginFailure(ctx context.Context, username, ip string, window time.Duration) (*models.LoginLockout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, now := lockoutKey(username), time.Now()
+	l, ok := r.lockouts[key]
+	if !ok {
+		l = &models.LoginLockout{Username: key}
+		r.lockouts[key] = l
+	}
+	if l.LastFailureAt == nil || l.LastFailureAt.Before(now.Add(-window)) {
+		l.Failures = 0
+	}
+	l.Failures++
+	l.LastFailureAt, l.LastIP = &now, ip
+	return copyLockout(l), nil
+}
+
+func (r *memoryRepository) LockLogin(ctx context.Context, username string, until time.Time) (*models.LoginLockout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.lockouts[lockoutKey(username)]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	l.Failures, l.Lockouts, l.LockedUntil = 0, l.Lockouts+1, &until
+	return copyLockout(l), nil
+}
+
+func (r *memoryRepository) ResetLoginFailures(ctx context.Context, username string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.lockouts, lockoutKey(username))
+	return nil
+}
+
+func (r *memoryRepository) UnlockLogin(ctx context.Context, username string) (*models.LoginLockout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.lockouts[lockoutKey(username)]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	l.Failures, l.LockedUntil = 0, nil
+	return copyLockout(l), nil
+}
+
+// memoryTwoFactor is a stored TOTP enrollment with its backup codes, keyed
+// by hash and true once used
+type memoryTwoFactor struct {
+	models.TwoFactor
+	backupCodes map[string]bool
+}
+
+func (r *memoryRepository) GetTwoFactor(ctx context.Context, userID uuid.UUID) (*models.TwoFactor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.twoFactor[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	out := t.TwoFactor
+	out.BackupCodesLeft = 0
+	for _, used := range t.backupCodes {
+		if !used {
+			out.BackupCodesLeft++
+		}
+	}
+	return &out, nil
+}
+
+func (r *memoryRepository) SetTwoFactorSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.twoFactor[userID]; ok && t.Enabled() {
+		return ErrTwoFactorEnabled
+	}
+	r.twoFactor[userID] = &memoryTwoFactor{TwoFactor: models.TwoFactor{UserID: userID, Secret: secret, CreatedAt: time.Now()}}
+	return nil
+}
+
+func (r *memoryRepository) EnableTwoFactor(ctx context.Context, userID uuid.UUID, step int64, codeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.twoFactor[userID]
+	if !ok || t.Enabled() {
+		return sql.ErrNoRows
+	}
+	now := time.Now()
+	t.EnabledAt, t.LastStep = &now, step
+	t.backupCodes = backupCodeSet(codeHashes)
+	return nil
+}
+
+func (r *memoryRepository) UseTwoFactorStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.twoFactor[userID]
+	if !ok || t.LastStep >= step {
+		return false, nil
+	}
+	t.LastStep = step
+	return true, nil
+}
+
+func (r *memoryRepository) UseBackupCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.twoFactor[userID]
+	if !ok {
+		return false, nil
+	}
+	if used, ok := t.backupCodes[codeHash]; !ok || used {
+		return false, nil
+	}
+	t.backupCodes[codeHash] = true
+	return true, nil
+}
+
+func (r *memoryRepository) ReplaceBackupCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.twoFactor[userID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	t.backupCodes = backupCodeSet(codeHashes)
+	return nil
+}
+
+func (r *memoryRepository) DeleteTwoFactor(ctx context.Context, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.twoFactor[userID]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(r.twoFactor, userID)
+	return nil
+}
+
+func backupCodeSet(codeHashes []string) map[string]bool {
+	set := make(map[string]bool, len(codeHashes))
+	for _, h := range codeHashes {
+		set[h] = false
+	}
+	return set
+}
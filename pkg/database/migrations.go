@@ -0,0 +1,164 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migration is an incremental migration of sql/migrations, applied in the
+// order of its file name
+type Migration struct {
+	Name string
+	SQL  string
+}
+
+// LoadMigrations reads the .sql files of dir, sorted by name
+func LoadMigrations(dir string) ([]Migration, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	sort.Strings(paths)
+	migrations := make([]Migration, 0, len(paths))
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration: %w", err)
+		}
+		migrations = append(migrations, Migration{Name: filepath.Base(path), SQL: string(content)})
+	}
+	return migrations, nil
+}
+
+// AppliedMigrations returns the names of the migrations recorded in
+// core.schema_migrations; none when the table does not exist yet
+func AppliedMigrations(ctx context.Context, pool *pgxpool.Pool) (map[string]bool, error) {
+	var exists bool
+	if err := pool.QueryRow(ctx, `SELECT to_regclass('core.schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check migration history: %w", err)
+	}
+	applied := map[string]bool{}
+	if !exists {
+		return applied, nil
+	}
+	rows, err := pool.Query(ctx, `SELECT name FROM core.schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query migration history: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		applied[name] = true
+	}
+	return applied, rows.Err()
+}
+
+// PendingMigrations keeps the migrations not applied yet
+func PendingMigrations(migrations []Migration, applied map[string]bool) []Migration {
+	var pending []Migration
+	for _, m := range migrations {
+		if !applied[m.Name] {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// ApplyMigration runs a migration and records it. Migrations hold their own
+// BEGIN and COMMIT, so a failing one leaves nothing behind and is not recorded.
+func ApplyMigration(ctx context.Context, pool *pgxpool.Pool, m Migration) error {
+	if _, err := pool.Exec(ctx, m.SQL); err != nil {
+		return fmt.Errorf("%s: %w", m.Name, err)
+	}
+	return RecordMigration(ctx, pool, m)
+}
+
+// RecordMigration marks a migration as applied without running it, e.g. for
+// a database created from sql/create.sql
+func RecordMigration(ctx context.Context, pool *pgxpool.Pool, m Migration) error {
+	_, err := pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS core.schema_migrations (
+			name       VARCHAR(255) PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to create migration history: %w", err)
+	}
+	if _, err := pool.Exec(ctx, `INSERT INTO core.schema_migrations (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, m.Name); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", m.Name, err)
+	}
+	return nil
+}
+
+// CreateShadow copies the database template into a new database named name
+// and connects to it with config. PostgreSQL copies a template only while
+// nobody else is connected to it, so the copy is made from the maintenance
+// database and pools connected to the template must be closed before.
+func CreateShadow(ctx context.Context, config *pgxpool.Config, maintenance, template, name string) (*DB, error) {
+	conn, err := connectTo(ctx, config, maintenance)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+	_, err = conn.Exec(ctx, `CREATE DATABASE `+pgx.Identifier{name}.Sanitize()+` TEMPLATE `+pgx.Identifier{template}.Sanitize())
+	if err != nil {
+		// object_in_use: someone, typically the API, is connected to the template
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "55006" {
+			return nil, fmt.Errorf("cannot copy %s into a shadow database while other sessions are connected to it: "+
+				"stop the API or pass --template with an idle copy of the database: %w", template, err)
+		}
+		return nil, fmt.Errorf("failed to copy %s into a shadow database: %w", template, err)
+	}
+
+	shadow := config.Copy()
+	shadow.ConnConfig.Database = name
+	shadow.MinConns = 0
+	pool, err := pgxpool.NewWithConfig(ctx, shadow)
+	if err == nil {
+		err = pool.Ping(ctx)
+	}
+	if err != nil {
+		if pool != nil {
+			pool.Close()
+		}
+		_ = DropShadow(ctx, config, maintenance, name)
+		return nil, fmt.Errorf("failed to connect to the shadow database: %w", err)
+	}
+	return &DB{Pool: pool}, nil
+}
+
+// DropShadow drops a database made by CreateShadow
+func DropShadow(ctx context.Context, config *pgxpool.Config, maintenance, name string) error {
+	conn, err := connectTo(ctx, config, maintenance)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	if _, err := conn.Exec(ctx, `DROP DATABASE IF EXISTS `+pgx.Identifier{name}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to drop the shadow database: %w", err)
+	}
+	return nil
+}
+
+// connectTo opens a single connection to another database of the server
+func connectTo(ctx context.Context, config *pgxpool.Config, database string) (*pgx.Conn, error) {
+	cc := config.ConnConfig.Copy()
+	cc.Database = database
+	conn, err := pgx.ConnectConfig(ctx, cc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database %s: %w", database, err)
+	}
+	return conn, nil
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+	"github.com/google/uuid"
+)
+
+// GetEnergyMix returns the production of date (YYYY-MM-DD) per generator type
+// from the running daily totals, which the database keeps current on every
+// write, so reading a day costs one row per type
+func (r *postgresRepository) GetEnergyMix(ctx context.Context, date string) (*models.EnergyMix, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT d.type_id, t.name, t.isrenuevable, d.records, d.production_mw
+		FROM daily_totals d
+		JOIN types t ON d.type_id = t.id
+		WHERE d.date = $1 AND d.records > 0
+		ORDER BY d.production_mw DESC, t.name`, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily totals: %w", err)
+	}
+	defer rows.Close()
+
+	mix := &models.EnergyMix{Date: date, Types: []*models.EnergyMixType{}}
+	for rows.Next() {
+		var t models.EnergyMixType
+		if err := rows.Scan(&t.TypeID, &t.TypeName, &t.IsRenewable, &t.Records, &t.ProductionMW); err != nil {
+			return nil, fmt.Errorf("failed to scan daily total: %w", err)
+		}
+		mix.Types = append(mix.Types, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	totalMix(mix)
+	return mix, nil
+}
+
+// totalMix fills the totals and shares of a mix from its types and rounds them
+func totalMix(mix *models.EnergyMix) {
+	for _, t := range mix.Types {
+		mix.Records += t.Records
+		mix.TotalProduction = mix.TotalProduction.Add(t.ProductionMW)
+		if t.IsRenewable {
+			mix.RenewableProduction = mix.RenewableProduction.Add(t.ProductionMW)
+		} else {
+			mix.NonRenewableProduction = mix.NonRenewableProduction.Add(t.ProductionMW)
+		}
+	}
+	for _, t := range mix.Types {
+		t.Share = numeric.RoundDecimal(percentOf(t.ProductionMW, mix.TotalProduction))
+		t.ProductionMW = numeric.RoundDecimal(t.ProductionMW)
+	}
+	mix.RenewableShare = numeric.RoundDecimal(percentOf(mix.RenewableProduction, mix.TotalProduction))
+	mix.NonRenewableShare = numeric.RoundDecimal(percentOf(mix.NonRenewableProduction, mix.TotalProduction))
+	mix.TotalProduction = numeric.RoundDecimal(mix.TotalProduction)
+	mix.RenewableProduction = numeric.RoundDecimal(mix.RenewableProduction)
+	mix.NonRenewableProduction = numeric.RoundDecimal(mix.NonRenewableProduction)
+}
+
+// CompareEnergyMix returns the change from the mix of previous to that of
+// current, per type in the order of current followed by the types only
+// previous has
+func CompareEnergyMix(current, previous *models.EnergyMix) *models.EnergyMixComparison {
+	cmp := &models.EnergyMixComparison{
+		Current:  current,
+		Previous: previous,
+		Change:   numeric.RoundDecimal(current.TotalProduction.Sub(previous.TotalProduction)),
+		Types:    []*models.EnergyMixTypeChange{},
+	}
+	if !previous.TotalProduction.IsZero() {
+		pct := numeric.RoundDecimal(percentOf(current.TotalProduction.Sub(previous.TotalProduction), previous.TotalProduction))
+		cmp.ChangePercent = &pct
+	}
+	byType := map[uuid.UUID]*models.EnergyMixTypeChange{}
+	for _, t := range current.Types {
+		ch := &models.EnergyMixTypeChange{TypeID: t.TypeID, TypeName: t.TypeName, ProductionMW: t.ProductionMW}
+		byType[t.TypeID] = ch
+		cmp.Types = append(cmp.Types, ch)
+	}
+	for _, t := range previous.Types {
+		ch, ok := byType[t.TypeID]
+		if !ok {
+			ch = &models.EnergyMixTypeChange{TypeID: t.TypeID, TypeName: t.TypeName}
+			cmp.Types = append(cmp.Types, ch)
+		}
+		ch.PreviousMW = t.ProductionMW
+	}
+	for _, ch := range cmp.Types {
+		ch.Change = numeric.RoundDecimal(ch.ProductionMW.Sub(ch.PreviousMW))
+	}
+	return cmp
+}
@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+)
+
+// GetNotificationPreferences lists the preferences a user chose, by alert type
+func (r *postgresRepository) GetNotificationPreferences(ctx context.Context, userID uuid.UUID) ([]*models.NotificationPreference, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT alert_type, delivery, channels, updated_at
+		FROM notification_preferences
+		WHERE user_id = $1
+		ORDER BY alert_type`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.NotificationPreference
+	for rows.Next() {
+		var p models.NotificationPreference
+		if err := rows.Scan(&p.AlertType, &p.Delivery, &p.Channels, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference: %w", err)
+		}
+		list = append(list, &p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// SetNotificationPreference stores the delivery a user chose for an alert type
+func (r *postgresRepository) SetNotificationPreference(ctx context.Context, userID uuid.UUID, alertType string, req *models.NotificationPreferenceRequest) (*models.NotificationPreference, error) {
+	query := `
+		INSERT INTO notification_preferences (user_id, alert_type, delivery, channels, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, alert_type) DO UPDATE
+		SET delivery = EXCLUDED.delivery, channels = EXCLUDED.channels, updated_at = EXCLUDED.updated_at
+		RETURNING alert_type, delivery, channels, updated_at`
+
+	var p models.NotificationPreference
+	err := r.db.QueryRow(ctx, query, userID, alertType, req.Delivery, req.Channels, time.Now()).
+		Scan(&p.AlertType, &p.Delivery, &p.Channels, &p.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set notification preference: %w", err)
+	}
+	return &p, nil
+}
+
+// DeleteNotificationPreference drops the preference of a user for an alert
+// type, which falls back to the default
+func (r *postgresRepository) DeleteNotificationPreference(ctx context.Context, userID uuid.UUID, alertType string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM notification_preferences WHERE user_id = $1 AND alert_type = $2`, userID, alertType)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification preference: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetNotificationSubscribers lists every user with its preference for an
+// alert type; users that never chose one have an empty delivery
+func (r *postgresRepository) GetNotificationSubscribers(ctx context.Context, alertType string) ([]*models.NotificationSubscriber, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT u.id, u.email, COALESCE(p.delivery, ''), COALESCE(p.channels, '{}')
+		FROM users u
+		LEFT JOIN notification_preferences p ON p.user_id = u.id AND p.alert_type = $1
+		ORDER BY u.created_at`, alertType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification subscribers: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.NotificationSubscriber
+	for rows.Next() {
+		var s models.NotificationSubscriber
+		if err := rows.Scan(&s.UserID, &s.Email, &s.Delivery, &s.Channels); err != nil {
+			return nil, fmt.Errorf("failed to scan notification subscriber: %w", err)
+		}
+		list = append(list, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// QueueDigestItem keeps an alert for the next digest of a user
+func (r *postgresRepository) QueueDigestItem(ctx context.Context, item *models.DigestItem) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO notification_digest_items (user_id, alert_type, subject, body, raised_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`, item.UserID, item.AlertType, item.Subject, item.Body, item.RaisedAt).Scan(&item.ID)
+	if err != nil {
+		return fmt.Errorf("failed to queue digest item: %w", err)
+	}
+	return nil
+}
+
+// GetDigestItems lists the queued alerts of every user, oldest first
+func (r *postgresRepository) GetDigestItems(ctx context.Context) ([]*models.DigestItem, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, user_id, alert_type, subject, body, raised_at
+		FROM notification_digest_items
+		ORDER BY user_id, raised_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query digest items: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.DigestItem
+	for rows.Next() {
+		var item models.DigestItem
+		if err := rows.Scan(&item.ID, &item.UserID, &item.AlertType, &item.Subject, &item.Body, &item.RaisedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan digest item: %w", err)
+		}
+		list = append(list, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// DeleteDigestItems drops queued alerts once their digest was sent
+func (r *postgresRepository) DeleteDigestItems(ctx context.Context, ids []int64) error {
+	if _, err := r.db.Exec(ctx, `DELETE FROM notification_digest_items WHERE id = ANY($1)`, ids); err != nil {
+		return fmt.Errorf("failed to delete digest items: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const notificationColumns = `id, user_id, alert_type, subject, body, read_at IS NOT NULL, read_at, created_at`
+
+func scanNotification(row pgx.Row, n *models.Notification) error {
+	return row.Scan(&n.ID, &n.UserID, &n.AlertType, &n.Subject, &n.Body, &n.Read, &n.ReadAt, &n.CreatedAt)
+}
+
+// CreateNotification puts an alert in the inbox of a user
+func (r *postgresRepository) CreateNotification(ctx context.Context, n *models.Notification) error {
+	n.ID, n.Read, n.ReadAt, n.CreatedAt = uuid.New(), false, nil, time.Now()
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO notifications (id, user_id, alert_type, subject, body, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		n.ID, n.UserID, n.AlertType, n.Subject, n.Body, n.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+	return nil
+}
+
+// GetNotifications lists the inbox of a user, newest first
+func (r *postgresRepository) GetNotifications(ctx context.Context, filter *models.NotificationFilter) ([]*models.Notification, error) {
+	args := []any{filter.UserID}
+	conds := []string{"user_id = $1"}
+	if filter.AlertType != nil {
+		args = append(args, *filter.AlertType)
+		conds = append(conds, fmt.Sprintf("alert_type = $%d", len(args)))
+	}
+	if filter.Unread {
+		conds = append(conds, "read_at IS NULL")
+	}
+	query := `SELECT ` + notificationColumns + ` FROM notifications` + whereClause(conds) + `
+		ORDER BY created_at DESC, id`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.Notification
+	for rows.Next() {
+		var n models.Notification
+		if err := scanNotification(rows, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		list = append(list, &n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// GetUnreadNotifications counts the unread notifications of a user by alert type
+func (r *postgresRepository) GetUnreadNotifications(ctx context.Context, userID uuid.UUID) (*models.UnreadNotifications, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT alert_type, COUNT(*)
+		FROM notifications
+		WHERE user_id = $1 AND read_at IS NULL
+		GROUP BY alert_type`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	defer rows.Close()
+
+	out := &models.UnreadNotifications{ByAlertType: map[string]int{}}
+	for rows.Next() {
+		var alertType string
+		var n int
+		if err := rows.Scan(&alertType, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan unread notifications: %w", err)
+		}
+		out.ByAlertType[alertType] = n
+		out.Unread += n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return out, nil
+}
+
+// MarkNotificationRead marks a notification of a user read; notifications
+// that were already read keep the time they were first read
+func (r *postgresRepository) MarkNotificationRead(ctx context.Context, userID, id uuid.UUID) (*models.Notification, error) {
+	var n models.Notification
+	err := scanNotification(r.db.QueryRow(ctx, `
+		UPDATE notifications SET read_at = COALESCE(read_at, $3)
+		WHERE user_id = $1 AND id = $2
+		RETURNING `+notificationColumns, userID, id, time.Now()), &n)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	return &n, nil
+}
+
+// MarkNotificationsRead marks the unread notifications of a user read, of
+// one alert type when alertType is set, and returns how many there were
+func (r *postgresRepository) MarkNotificationsRead(ctx context.Context, userID uuid.UUID, alertType *string) (int64, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE notifications SET read_at = $2
+		WHERE user_id = $1 AND read_at IS NULL AND ($3::text IS NULL OR alert_type = $3)`,
+		userID, time.Now(), alertType)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// PurgeNotifications deletes the notifications created before a time and
+// returns how many there were
+func (r *postgresRepository) PurgeNotifications(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM notifications WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge notifications: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const operatorColumns = `id, name, COALESCE(country, ''), custom_attributes, created_at, updated_at`
+
+func scanOperator(row pgx.Row, o *models.Operator) error {
+	return row.Scan(&o.ID, &o.Name, &o.Country, &o.CustomAttributes, &o.CreatedAt, &o.UpdatedAt)
+}
+
+// CreateOperator creates a new generator operator
+func (r *postgresRepository) CreateOperator(ctx context.Context, req *models.CreateOperatorRequest) (*models.Operator, error) {
+	query := `
+		INSERT INTO operators (id, name, country, custom_attributes, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3, ''), jsonb_strip_nulls(COALESCE($6::jsonb, '{}')), $4, $5)
+		RETURNING ` + operatorColumns
+
+	now := time.Now()
+	var op models.Operator
+	if err := scanOperator(r.db.QueryRow(ctx, query, uuid.New(), req.Name, req.Country, now, now, attributesParam(req.CustomAttributes)), &op); err != nil {
+		return nil, fmt.Errorf("failed to create operator: %w", err)
+	}
+	return &op, nil
+}
+
+// GetOperatorByID retrieves an operator by its ID
+func (r *postgresRepository) GetOperatorByID(ctx context.Context, id uuid.UUID) (*models.Operator, error) {
+	query := `SELECT ` + operatorColumns + ` FROM operators WHERE id = $1`
+
+	var op models.Operator
+	if err := scanOperator(r.db.QueryRow(ctx, query, id), &op); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get operator: %w", err)
+	}
+	return &op, nil
+}
+
+// GetAllOperators retrieves all operators ordered by name
+func (r *postgresRepository) GetAllOperators(ctx context.Context) ([]*models.Operator, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+operatorColumns+` FROM operators ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query operators: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.Operator
+	for rows.Next() {
+		var op models.Operator
+		if err := scanOperator(rows, &op); err != nil {
+			return nil, fmt.Errorf("failed to scan operator: %w", err)
+		}
+		list = append(list, &op)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// UpdateOperator updates the provided fields of an operator
+func (r *postgresRepository) UpdateOperator(ctx context.Context, id uuid.UUID, req *models.UpdateOperatorRequest) (*models.Operator, error) {
+	query := `
+		UPDATE operators
+		SET name = COALESCE($2, name),
+		    country = COALESCE(NULLIF($3, ''), country),
+		    custom_attributes = jsonb_strip_nulls(custom_attributes || COALESCE($5::jsonb, '{}')),
+		    updated_at = $4
+		WHERE id = $1
+		RETURNING ` + operatorColumns
+
+	var op models.Operator
+	if err := scanOperator(r.db.QueryRow(ctx, query, id, req.Name, req.Country, time.Now(), attributesParam(req.CustomAttributes)), &op); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to update operator: %w", err)
+	}
+	return &op, nil
+}
+
+// DeleteOperator moves an operator to the trash. Its generators are kept without operator.
+func (r *postgresRepository) DeleteOperator(ctx context.Context, id uuid.UUID) error {
+	return r.moveToTrash(ctx, models.TrashOperator, id, false)
+}
+
+// GetOperatorGrants lists the operators a user may write generators for
+func (r *postgresRepository) GetOperatorGrants(ctx context.Context, userID uuid.UUID) ([]*models.OperatorGrant, error) {
+	query := `
+		SELECT g.user_id, g.operator_id, o.name, g.created_at
+		FROM user_operator_grants g
+		JOIN operators o ON g.operator_id = o.id
+		WHERE g.user_id = $1
+		ORDER BY o.name`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query operator grants: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.OperatorGrant
+	for rows.Next() {
+		var g models.OperatorGrant
+		if err := rows.Scan(&g.UserID, &g.OperatorID, &g.OperatorName, &g.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan operator grant: %w", err)
+		}
+		list = append(list, &g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// GrantOperator gives a user write access to the generators of an operator.
+// Granting an existing grant again is a no-op.
+func (r *postgresRepository) GrantOperator(ctx context.Context, userID, operatorID uuid.UUID) error {
+	query := `
+		INSERT INTO user_operator_grants (user_id, operator_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, operator_id) DO NOTHING`
+
+	if _, err := r.db.Exec(ctx, query, userID, operatorID, time.Now()); err != nil {
+		return fmt.Errorf("failed to grant operator: %w", err)
+	}
+	return nil
+}
+
+// RevokeOperator removes a grant created with GrantOperator
+func (r *postgresRepository) RevokeOperator(ctx context.Context, userID, operatorID uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM user_operator_grants WHERE user_id = $1 AND operator_id = $2`, userID, operatorID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke operator: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// patchSet builds the SET list of a partial update from the members present
+// in the body of a PATCH. The ID of the row is $1.
+type patchSet struct {
+	fields models.Fields
+	sets   []string
+	args   []any
+}
+
+func newPatchSet(fields models.Fields, id uuid.UUID) *patchSet {
+	return &patchSet{fields: fields, args: []any{id}}
+}
+
+// set adds the assignment when the body has member; %[1]s in the assignment
+// is the placeholder of value
+func (s *patchSet) set(member, assignment string, value any) {
+	if s.fields.Has(member) {
+		s.add(assignment, value)
+	}
+}
+
+// add adds the assignment whatever the body has
+func (s *patchSet) add(assignment string, value any) {
+	s.args = append(s.args, value)
+	s.sets = append(s.sets, fmt.Sprintf(assignment, "$"+strconv.Itoa(len(s.args))))
+}
+
+// attributes merges the custom attributes of the body into the stored ones,
+// removing those set to null; customAttributes set to null removes them all
+func (s *patchSet) attributes(attrs models.CustomAttributes) {
+	if !s.fields.Has("customAttributes") {
+		return
+	}
+	if attrs == nil {
+		s.sets = append(s.sets, "custom_attributes = '{}'")
+		return
+	}
+	s.add("custom_attributes = jsonb_strip_nulls(custom_attributes || %[1]s::jsonb)", attrs)
+}
+
+func (s *patchSet) clause() string {
+	return strings.Join(s.sets, ", ")
+}
+
+// patchType updates the members of a PATCH body of a type
+func (r *postgresRepository) patchType(ctx context.Context, id uuid.UUID, req *models.UpdateTypeRequest) (*models.Type, error) {
+	s := newPatchSet(req.Fields, id)
+	s.set("name", "name = %[1]s", req.Name)
+	s.set("description", "description = %[1]s", req.Description)
+	s.set("isRenewable", "isrenuevable = %[1]s", req.IsRenewable)
+	s.set("technologyCode", "technology_code = NULLIF(%[1]s, '')", req.TechnologyCode)
+	s.attributes(req.CustomAttributes)
+	s.add("updated_at = %[1]s", time.Now())
+
+	version, args := versionCondition(req.IfMatch, s.args)
+	var t models.Type
+	err := r.db.QueryRow(ctx, `
+		UPDATE types
+		SET `+s.clause()+`
+		WHERE id = $1 AND deleted_at IS NULL`+version+`
+		RETURNING id, name, description, isrenuevable, COALESCE(technology_code, ''), custom_attributes, created_at, updated_at, version`, args...).Scan(
+		&t.ID, &t.Name, &t.Description, &t.IsRenewable, &t.TechnologyCode, &t.CustomAttributes, &t.CreatedAt, &t.UpdatedAt, &t.Version,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			if req.IfMatch != nil {
+				return nil, versionError(ctx, r.db, "types", id)
+			}
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to update type: %w", err)
+	}
+	return &t, nil
+}
+
+// patchGenerator updates the members of a PATCH body of a generator; null
+// clears the operator, plant, region, location and commissioning date
+func (r *postgresRepository) patchGenerator(ctx context.Context, id uuid.UUID, req *models.UpdateGeneratorRequest) (*models.Generator, error) {
+	s := newPatchSet(req.Fields, id)
+	s.set("typeId", "type = %[1]s", req.TypeID)
+	s.set("capacity", "capacity = %[1]s", req.Capacity)
+	s.set("operatorId", "operator_id = %[1]s", req.OperatorID)
+	s.set("plantId", "plant_id = %[1]s", req.PlantID)
+	s.set("regionId", "region_id = %[1]s", req.RegionID)
+	s.set("latitude", "latitude = %[1]s", req.Latitude)
+	s.set("longitude", "longitude = %[1]s", req.Longitude)
+	s.set("commissionedOn", "commissioned_on = %[1]s::date", req.CommissionedOn)
+	s.attributes(req.CustomAttributes)
+	s.add("updated_at = %[1]s", time.Now())
+
+	version, args := versionCondition(req.IfMatch, s.args)
+	tag, err := r.db.Exec(ctx, `UPDATE generators SET `+s.clause()+` WHERE id = $1`+version, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update generator: %w", publishedError(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, versionError(ctx, r.db, "generators", id)
+	}
+	return r.GetGeneratorByID(ctx, id)
+}
+
+// patchProduction updates the members of a PATCH body of a production and
+// signs it again. As with a PUT, a new source replaces the source reference,
+// which is cleared unless the body has one.
+func (r *postgresRepository) patchProduction(ctx context.Context, id uuid.UUID, req *models.UpdateProductionRequest) (*models.Production, error) {
+	date, err := optionalProductionDate(req.Date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update production: %w", err)
+	}
+	s := newPatchSet(req.Fields, id)
+	s.set("generatorId", "generator_id = %[1]s", req.GeneratorID)
+	s.set("date", "date = %[1]s", date)
+	s.set("productionMw", "production_mw = %[1]s", req.ProductionMW)
+	s.set("source", "source = %[1]s", req.Source)
+	if req.Fields.Has("source") || req.Fields.Has("sourceRef") {
+		s.add("source_ref = NULLIF(%[1]s, '')", req.SourceRef)
+	}
+	s.attributes(req.CustomAttributes)
+	s.add("updated_at = %[1]s", time.Now())
+
+	version, args := versionCondition(req.IfMatch, s.args)
+	err = r.writeSigned(ctx, id, func(q execQuerier) error {
+		tag, err := q.Exec(ctx, `UPDATE productions SET `+s.clause()+` WHERE id = $1`+version, args...)
+		if err == nil && tag.RowsAffected() == 0 {
+			return versionError(ctx, q, "productions", id)
+		}
+		return err
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to update production: %w", duplicateError(err))
+	}
+	return r.GetProductionByID(ctx, id)
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrPlantExists is returned when another plant has the name
+var ErrPlantExists = errors.New("plant already exists")
+
+// plantSelect reads plants with their owner and the count and capacity of
+// their generators
+const plantSelect = `
+	SELECT pl.id, pl.name, pl.location, pl.latitude, pl.longitude, pl.owner_id, COALESCE(o.name, ''),
+	       COUNT(g.id), COALESCE(SUM(g.capacity), 0), pl.created_at, pl.updated_at
+	FROM plants pl
+	LEFT JOIN operators o ON pl.owner_id = o.id
+	LEFT JOIN generators g ON g.plant_id = pl.id`
+
+const plantGroupBy = ` GROUP BY pl.id, o.name`
+
+func scanPlant(row pgx.Row, p *models.Plant) error {
+	if err := row.Scan(&p.ID, &p.Name, &p.Location, &p.Latitude, &p.Longitude, &p.OwnerID, &p.OwnerName,
+		&p.GeneratorCount, &p.TotalCapacity, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return err
+	}
+	p.TotalCapacity = numeric.RoundDecimal(p.TotalCapacity)
+	return nil
+}
+
+// plantError maps the errors of writing a plant
+func plantError(action string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrPlantExists
+	}
+	return fmt.Errorf("failed to %s plant: %w", action, err)
+}
+
+// CreatePlant creates a new plant
+func (r *postgresRepository) CreatePlant(ctx context.Context, req *models.CreatePlantRequest) (*models.Plant, error) {
+	query := `
+		INSERT INTO plants (id, name, location, latitude, longitude, owner_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)`
+
+	id := uuid.New()
+	if _, err := r.db.Exec(ctx, query, id, req.Name, req.Location, req.Latitude, req.Longitude, req.OwnerID, time.Now()); err != nil {
+		return nil, plantError("create", err)
+	}
+	return r.GetPlantByID(ctx, id)
+}
+
+// GetPlantByID retrieves a plant by its ID
+func (r *postgresRepository) GetPlantByID(ctx context.Context, id uuid.UUID) (*models.Plant, error) {
+	var p models.Plant
+	if err := scanPlant(r.db.QueryRow(ctx, plantSelect+` WHERE pl.id = $1`+plantGroupBy, id), &p); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get plant: %w", err)
+	}
+	return &p, nil
+}
+
+// GetAllPlants retrieves the plants matching filter ordered by name
+func (r *postgresRepository) GetAllPlants(ctx context.Context, filter *models.PlantFilter) ([]*models.Plant, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	if filter.OwnerID != nil {
+		args = append(args, *filter.OwnerID)
+		conds = append(conds, fmt.Sprintf("pl.owner_id = $%d", len(args)))
+	}
+	query := plantSelect + whereClause(conds) + plantGroupBy + ` ORDER BY pl.name`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query plants: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.Plant
+	for rows.Next() {
+		var p models.Plant
+		if err := scanPlant(rows, &p); err != nil {
+			return nil, fmt.Errorf("failed to scan plant: %w", err)
+		}
+		list = append(list, &p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// UpdatePlant updates the provided fields of a plant
+func (r *postgresRepository) UpdatePlant(ctx context.Context, id uuid.UUID, req *models.UpdatePlantRequest) (*models.Plant, error) {
+	query := `
+		UPDATE plants
+		SET name = COALESCE($2, name),
+		    location = COALESCE($3, location),
+		    latitude = COALESCE($4, latitude),
+		    longitude = COALESCE($5, longitude),
+		    owner_id = COALESCE($6, owner_id),
+		    updated_at = $7
+		WHERE id = $1`
+
+	result, err := r.db.Exec(ctx, query, id, req.Name, req.Location, req.Latitude, req.Longitude, req.OwnerID, time.Now())
+	if err != nil {
+		return nil, plantError("update", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return r.GetPlantByID(ctx, id)
+}
+
+// DeletePlant deletes a plant. Its generators are kept without plant.
+func (r *postgresRepository) DeletePlant(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM plants WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete plant: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/provenance"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// execQuerier is satisfied by both the pool and a transaction
+type execQuerier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// writeSigned runs write and, when provenance signing is enabled, re-signs the
+// production row id in the same transaction so the signature always covers
+// the values as stored.
+func (r *postgresRepository) writeSigned(ctx context.Context, id uuid.UUID, write func(q execQuerier) error) error {
+	if !provenance.Enabled() {
+		return write(r.db)
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := write(tx); err != nil {
+		return err
+	}
+	if err := signProduction(ctx, tx, id); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit production: %w", err)
+	}
+	return nil
+}
+
+// signProduction stores the provenance signature of the production as currently stored
+func signProduction(ctx context.Context, q execQuerier, id uuid.UUID) error {
+	rec := provenance.Record{ID: id}
+	err := q.QueryRow(ctx, `
+		SELECT generator_id, date::text, production_mw, source, COALESCE(source_ref, '')
+		FROM productions
+		WHERE id = $1`, id).Scan(&rec.GeneratorID, &rec.Date, &rec.ProductionMW, &rec.Source, &rec.SourceRef)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return sql.ErrNoRows
+		}
+		return fmt.Errorf("failed to read production for signing: %w", err)
+	}
+
+	if _, err := q.Exec(ctx, `UPDATE productions SET signature = $2 WHERE id = $1`, id, provenance.Sign(rec)); err != nil {
+		return fmt.Errorf("failed to sign production: %w", err)
+	}
+	return nil
+}
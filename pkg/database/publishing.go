@@ -0,0 +1,258 @@
+package database
+
+import (
+	"context"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/events"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+)
+
+// publishingRepository publishes an event to bus after every successful
+// change of the reference data and production records. Reads pass through.
+type publishingRepository struct {
+	Repository
+	bus *events.Bus
+}
+
+// NewPublishingRepository wraps repo so its changes are published to bus,
+// for the subscribers to react to them, e.g. by dropping cached analytics
+func NewPublishingRepository(repo Repository, bus *events.Bus) Repository {
+	return &publishingRepository{Repository: repo, bus: bus}
+}
+
+// changed publishes the event of a change when err is nil and returns err
+func (r *publishingRepository) changed(ctx context.Context, err error, e events.Event) error {
+	if err == nil {
+		r.bus.Publish(ctx, e)
+	}
+	return err
+}
+
+// ===================== Types =====================
+
+func (r *publishingRepository) CreateType(ctx context.Context, req *models.CreateTypeRequest) (*models.Type, error) {
+	t, err := r.Repository.CreateType(ctx, req)
+	if err == nil {
+		r.bus.Publish(ctx, events.TypeCreated{Type: t})
+	}
+	return t, err
+}
+
+func (r *publishingRepository) UpdateType(ctx context.Context, id uuid.UUID, req *models.UpdateTypeRequest) (*models.Type, error) {
+	t, err := r.Repository.UpdateType(ctx, id, req)
+	if err == nil {
+		r.bus.Publish(ctx, events.TypeUpdated{Type: t})
+	}
+	return t, err
+}
+
+func (r *publishingRepository) DeleteType(ctx context.Context, id uuid.UUID, cascade bool) error {
+	return r.changed(ctx, r.Repository.DeleteType(ctx, id, cascade), events.TypeDeleted{ID: id})
+}
+
+func (r *publishingRepository) MergeType(ctx context.Context, sourceID, targetID uuid.UUID) (*models.TypeMergeResult, error) {
+	res, err := r.Repository.MergeType(ctx, sourceID, targetID)
+	if err == nil {
+		r.bus.Publish(ctx, events.TypeMerged{Result: res})
+	}
+	return res, err
+}
+
+func (r *publishingRepository) SetTypeTranslation(ctx context.Context, typeID uuid.UUID, language string, req *models.TypeTranslationRequest) (*models.TypeTranslation, error) {
+	t, err := r.Repository.SetTypeTranslation(ctx, typeID, language, req)
+	if err == nil {
+		r.bus.Publish(ctx, events.TypeTranslated{TypeID: typeID, Language: language})
+	}
+	return t, err
+}
+
+func (r *publishingRepository) DeleteTypeTranslation(ctx context.Context, typeID uuid.UUID, language string) error {
+	return r.changed(ctx, r.Repository.DeleteTypeTranslation(ctx, typeID, language), events.TypeTranslated{TypeID: typeID, Language: language})
+}
+
+// ===================== Operators =====================
+
+func (r *publishingRepository) CreateOperator(ctx context.Context, req *models.CreateOperatorRequest) (*models.Operator, error) {
+	o, err := r.Repository.CreateOperator(ctx, req)
+	if err == nil {
+		r.bus.Publish(ctx, events.OperatorCreated{Operator: o})
+	}
+	return o, err
+}
+
+func (r *publishingRepository) UpdateOperator(ctx context.Context, id uuid.UUID, req *models.UpdateOperatorRequest) (*models.Operator, error) {
+	o, err := r.Repository.UpdateOperator(ctx, id, req)
+	if err == nil {
+		r.bus.Publish(ctx, events.OperatorUpdated{Operator: o})
+	}
+	return o, err
+}
+
+func (r *publishingRepository) DeleteOperator(ctx context.Context, id uuid.UUID) error {
+	return r.changed(ctx, r.Repository.DeleteOperator(ctx, id), events.OperatorDeleted{ID: id})
+}
+
+// ===================== Plants =====================
+
+func (r *publishingRepository) CreatePlant(ctx context.Context, req *models.CreatePlantRequest) (*models.Plant, error) {
+	p, err := r.Repository.CreatePlant(ctx, req)
+	if err == nil {
+		r.bus.Publish(ctx, events.PlantCreated{Plant: p})
+	}
+	return p, err
+}
+
+func (r *publishingRepository) UpdatePlant(ctx context.Context, id uuid.UUID, req *models.UpdatePlantRequest) (*models.Plant, error) {
+	p, err := r.Repository.UpdatePlant(ctx, id, req)
+	if err == nil {
+		r.bus.Publish(ctx, events.PlantUpdated{Plant: p})
+	}
+	return p, err
+}
+
+func (r *publishingRepository) DeletePlant(ctx context.Context, id uuid.UUID) error {
+	return r.changed(ctx, r.Repository.DeletePlant(ctx, id), events.PlantDeleted{ID: id})
+}
+
+// ===================== Regions =====================
+
+func (r *publishingRepository) CreateRegion(ctx context.Context, req *models.CreateRegionRequest) (*models.Region, error) {
+	reg, err := r.Repository.CreateRegion(ctx, req)
+	if err == nil {
+		r.bus.Publish(ctx, events.RegionCreated{Region: reg})
+	}
+	return reg, err
+}
+
+func (r *publishingRepository) UpdateRegion(ctx context.Context, id uuid.UUID, req *models.UpdateRegionRequest) (*models.Region, error) {
+	reg, err := r.Repository.UpdateRegion(ctx, id, req)
+	if err == nil {
+		r.bus.Publish(ctx, events.RegionUpdated{Region: reg})
+	}
+	return reg, err
+}
+
+func (r *publishingRepository) DeleteRegion(ctx context.Context, id uuid.UUID) error {
+	return r.changed(ctx, r.Repository.DeleteRegion(ctx, id), events.RegionDeleted{ID: id})
+}
+
+// ===================== Generators =====================
+
+func (r *publishingRepository) CreateGenerator(ctx context.Context, req *models.CreateGeneratorRequest) (*models.Generator, error) {
+	g, err := r.Repository.CreateGenerator(ctx, req)
+	if err == nil {
+		r.bus.Publish(ctx, events.GeneratorCreated{Generator: g})
+	}
+	return g, err
+}
+
+func (r *publishingRepository) UpdateGenerator(ctx context.Context, id uuid.UUID, req *models.UpdateGeneratorRequest) (*models.Generator, error) {
+	g, err := r.Repository.UpdateGenerator(ctx, id, req)
+	if err == nil {
+		r.bus.Publish(ctx, events.GeneratorUpdated{Generator: g})
+	}
+	return g, err
+}
+
+func (r *publishingRepository) DeleteGenerator(ctx context.Context, id uuid.UUID, cascade bool) error {
+	return r.changed(ctx, r.Repository.DeleteGenerator(ctx, id, cascade), events.GeneratorDeleted{ID: id})
+}
+
+// ===================== Productions =====================
+
+func (r *publishingRepository) CreateProduction(ctx context.Context, req *models.CreateProductionRequest) (*models.Production, error) {
+	p, err := r.Repository.CreateProduction(ctx, req)
+	if err == nil {
+		r.bus.Publish(ctx, events.ProductionCreated{Production: p})
+	}
+	return p, err
+}
+
+func (r *publishingRepository) UpsertProduction(ctx context.Context, req *models.CreateProductionRequest) (*models.Production, bool, error) {
+	p, created, err := r.Repository.UpsertProduction(ctx, req)
+	if err == nil {
+		if created {
+			r.bus.Publish(ctx, events.ProductionCreated{Production: p})
+		} else {
+			r.bus.Publish(ctx, events.ProductionUpdated{Production: p})
+		}
+	}
+	return p, created, err
+}
+
+// CreateProductions publishes the records written; those with an error were
+// rolled back to their savepoint
+func (r *publishingRepository) CreateProductions(ctx context.Context, reqs []*models.CreateProductionRequest) ([]*models.Production, []error, error) {
+	created, errs, err := r.Repository.CreateProductions(ctx, reqs)
+	if err == nil {
+		var e []events.Event
+		for i, p := range created {
+			if p != nil && errs[i] == nil {
+				e = append(e, events.ProductionCreated{Production: p})
+			}
+		}
+		r.bus.Publish(ctx, e...)
+	}
+	return created, errs, err
+}
+
+func (r *publishingRepository) UpdateProductions(ctx context.Context, updates []*models.BulkProductionUpdate, atomic bool) ([]*models.Production, []error, error) {
+	updated, errs, err := r.Repository.UpdateProductions(ctx, updates, atomic)
+	if err == nil {
+		var e []events.Event
+		for i, p := range updated {
+			if p != nil && errs[i] == nil {
+				e = append(e, events.ProductionUpdated{Production: p})
+			}
+		}
+		r.bus.Publish(ctx, e...)
+	}
+	return updated, errs, err
+}
+
+func (r *publishingRepository) UpdateProduction(ctx context.Context, id uuid.UUID, req *models.UpdateProductionRequest) (*models.Production, error) {
+	p, err := r.Repository.UpdateProduction(ctx, id, req)
+	if err == nil {
+		r.bus.Publish(ctx, events.ProductionUpdated{Production: p})
+	}
+	return p, err
+}
+
+func (r *publishingRepository) DeleteProduction(ctx context.Context, id uuid.UUID) error {
+	return r.changed(ctx, r.Repository.DeleteProduction(ctx, id), events.ProductionDeleted{ID: id})
+}
+
+func (r *publishingRepository) RecordTelemetry(ctx context.Context, reading *models.TelemetryReading) (*models.Production, error) {
+	p, err := r.Repository.RecordTelemetry(ctx, reading)
+	if err == nil {
+		r.bus.Publish(ctx, events.ProductionUpdated{Production: p})
+	}
+	return p, err
+}
+
+func (r *publishingRepository) ApplyCorrection(ctx context.Context, productionID uuid.UUID, req *models.CreateCorrectionRequest) (*models.ProductionCorrection, error) {
+	c, err := r.Repository.ApplyCorrection(ctx, productionID, req)
+	if err == nil {
+		r.bus.Publish(ctx, events.ProductionCorrected{Correction: c})
+	}
+	return c, err
+}
+
+func (r *publishingRepository) ApplyRecalculation(ctx context.Context, req *models.RecalculationRequest, userID *uuid.UUID) (*models.Recalculation, error) {
+	rc, err := r.Repository.ApplyRecalculation(ctx, req, userID)
+	if err == nil {
+		r.bus.Publish(ctx, events.RecalculationApplied{Recalculation: rc})
+	}
+	return rc, err
+}
+
+// ===================== Trash =====================
+
+func (r *publishingRepository) RestoreTrashItem(ctx context.Context, id uuid.UUID) (*models.TrashItem, error) {
+	item, err := r.Repository.RestoreTrashItem(ctx, id)
+	if err == nil {
+		r.bus.Publish(ctx, events.TrashItemRestored{Item: item})
+	}
+	return item, err
+}
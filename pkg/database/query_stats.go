@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// QueryStats adds up the queries run with a context, e.g. those of one
+// request, to tell clients why a request was slow
+type QueryStats struct {
+	mu       sync.Mutex
+	queries  int
+	rows     int64
+	duration time.Duration
+}
+
+// Totals returns the number of queries, the rows they returned or changed
+// (from their command tags) and the time they took
+func (s *QueryStats) Totals() (queries int, rows int64, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries, s.rows, s.duration
+}
+
+func (s *QueryStats) add(rows int64, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+	s.rows += rows
+	s.duration += d
+}
+
+type queryStatsKey struct{}
+
+// WithQueryStats returns a context whose queries are added up in the
+// returned QueryStats
+func WithQueryStats(ctx context.Context) (context.Context, *QueryStats) {
+	stats := &QueryStats{}
+	return context.WithValue(ctx, queryStatsKey{}, stats), stats
+}
+
+type queryStartKey struct{}
+
+// queryTracer times the queries of contexts with QueryStats
+type queryTracer struct{}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	if ctx.Value(queryStatsKey{}) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, queryStartKey{}, time.Now())
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	stats, ok := ctx.Value(queryStatsKey{}).(*QueryStats)
+	start, started := ctx.Value(queryStartKey{}).(time.Time)
+	if !ok || !started {
+		return
+	}
+	stats.add(data.CommandTag.RowsAffected(), time.Since(start))
+}
@@ -0,0 +1,214 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Read snapshot errors
+var (
+	// ErrReadSnapshotNotFound is returned for unknown, closed or expired read snapshots
+	ErrReadSnapshotNotFound = errors.New("read snapshot not found or expired")
+	// ErrTooManyReadSnapshots is returned when opening a read snapshot while
+	// the most allowed are open
+	ErrTooManyReadSnapshots = errors.New("too many open read snapshots")
+)
+
+// dbtx is what the repository queries through: the pool, or the transaction
+// of a consistent read
+type dbtx interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// txBeginner starts the transactions of ReadConsistent and read snapshots:
+// the pool, or the pools of the tenants
+type txBeginner interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// ReadSnapshotConfig represents the read snapshots pinned for paginated walks
+type ReadSnapshotConfig struct {
+	// TTL is how long a read snapshot stays open after it is opened
+	TTL time.Duration
+	// Max bounds the open read snapshots; each holds a database connection
+	// until it is closed or expires
+	Max int
+}
+
+// LoadReadSnapshotConfig loads read snapshot configuration from environment variables
+func LoadReadSnapshotConfig() *ReadSnapshotConfig {
+	cfg := &ReadSnapshotConfig{
+		TTL: utils.GetEnvAsDuration("READ_SNAPSHOT_TTL", 10*time.Minute),
+		Max: utils.GetEnvAsInt("READ_SNAPSHOT_MAX", 4),
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 10 * time.Minute
+	}
+	if cfg.Max < 0 {
+		cfg.Max = 0
+	}
+	return cfg
+}
+
+// consistentRead is the transaction of reads that must see one state of the
+// database: repeatable read, so every query sees the snapshot of the first,
+// and read only
+var consistentRead = pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+
+// snapshotName matches the names pg_export_snapshot returns, e.g. 00000003-0000001B-1
+var snapshotName = regexp.MustCompile(`^[0-9A-F]+(-[0-9A-F]+)+$`)
+
+// readSnapshot is an open read snapshot: the transaction that exported it
+// stays open, so other transactions can import it, until it is closed
+type readSnapshot struct {
+	snapshot *models.ReadSnapshot
+	name     string
+	// tenant opened it; the snapshot lives in the database of that tenant
+	tenant string
+	tx     pgx.Tx
+	timer  *time.Timer
+}
+
+// readSnapshots are the open read snapshots of a repository
+type readSnapshots struct {
+	cfg *ReadSnapshotConfig
+
+	mu   sync.Mutex
+	open map[uuid.UUID]*readSnapshot
+}
+
+func newReadSnapshots(cfg *ReadSnapshotConfig) *readSnapshots {
+	return &readSnapshots{cfg: cfg, open: map[uuid.UUID]*readSnapshot{}}
+}
+
+// ReadConsistent runs fn with a repository whose reads all happen in one
+// repeatable-read, read-only transaction
+func (r *postgresRepository) ReadConsistent(ctx context.Context, fn func(Repository) error) error {
+	tx, err := r.pool.BeginTx(ctx, consistentRead)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	return fn(r.within(tx))
+}
+
+// OpenReadSnapshot exports the snapshot of a transaction kept open for the
+// TTL, for the reads of InReadSnapshot
+func (r *postgresRepository) OpenReadSnapshot(ctx context.Context) (*models.ReadSnapshot, error) {
+	s := r.snapshots
+	s.mu.Lock()
+	full := len(s.open) >= s.cfg.Max
+	s.mu.Unlock()
+	if full {
+		return nil, fmt.Errorf("%w: at most %d, close one or wait for one to expire", ErrTooManyReadSnapshots, s.cfg.Max)
+	}
+
+	// The transaction outlives the request that opens it
+	tx, err := r.pool.BeginTx(context.WithoutCancel(ctx), consistentRead)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	var name string
+	if err := tx.QueryRow(ctx, `SELECT pg_export_snapshot()`).Scan(&name); err != nil {
+		_ = tx.Rollback(context.Background())
+		return nil, fmt.Errorf("failed to export snapshot: %w", err)
+	}
+	if !snapshotName.MatchString(name) {
+		_ = tx.Rollback(context.Background())
+		return nil, fmt.Errorf("failed to export snapshot: unexpected name %q", name)
+	}
+
+	now := time.Now().UTC()
+	rs := &readSnapshot{
+		snapshot: &models.ReadSnapshot{ID: uuid.New(), CreatedAt: now, ExpiresAt: now.Add(s.cfg.TTL)},
+		name:     name,
+		tenant:   auth.TenantFrom(ctx),
+		tx:       tx,
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.open) >= s.cfg.Max {
+		_ = tx.Rollback(context.Background())
+		return nil, fmt.Errorf("%w: at most %d, close one or wait for one to expire", ErrTooManyReadSnapshots, s.cfg.Max)
+	}
+	s.open[rs.snapshot.ID] = rs
+	id := rs.snapshot.ID
+	rs.timer = time.AfterFunc(s.cfg.TTL, func() { _ = s.close(id) })
+	return rs.snapshot, nil
+}
+
+// InReadSnapshot runs fn with a repository reading from the read snapshot id;
+// ErrReadSnapshotNotFound when it is not open
+func (r *postgresRepository) InReadSnapshot(ctx context.Context, id uuid.UUID, fn func(Repository) error) error {
+	rs, ok := r.snapshots.get(ctx, id)
+	if !ok {
+		return ErrReadSnapshotNotFound
+	}
+
+	tx, err := r.pool.BeginTx(ctx, consistentRead)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	// The name is checked against snapshotName; SET TRANSACTION takes no parameters
+	if _, err := tx.Exec(ctx, `SET TRANSACTION SNAPSHOT '`+rs.name+`'`); err != nil {
+		// The exporting transaction ended, e.g. its connection was lost
+		if r.snapshots.close(id) == nil {
+			return fmt.Errorf("%w: %v", ErrReadSnapshotNotFound, err)
+		}
+		return ErrReadSnapshotNotFound
+	}
+	return fn(r.within(tx))
+}
+
+// CloseReadSnapshot ends the transaction of a read snapshot before it expires;
+// ErrReadSnapshotNotFound when it is not open
+func (r *postgresRepository) CloseReadSnapshot(ctx context.Context, id uuid.UUID) error {
+	if _, ok := r.snapshots.get(ctx, id); !ok {
+		return ErrReadSnapshotNotFound
+	}
+	return r.snapshots.close(id)
+}
+
+// get returns the open read snapshot id of the tenant of ctx
+func (s *readSnapshots) get(ctx context.Context, id uuid.UUID) (*readSnapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs, ok := s.open[id]
+	if !ok || rs.tenant != auth.TenantFrom(ctx) {
+		return nil, false
+	}
+	return rs, true
+}
+
+func (s *readSnapshots) close(id uuid.UUID) error {
+	s.mu.Lock()
+	rs, ok := s.open[id]
+	delete(s.open, id)
+	s.mu.Unlock()
+	if !ok {
+		return ErrReadSnapshotNotFound
+	}
+	rs.timer.Stop()
+	_ = rs.tx.Rollback(context.Background())
+	return nil
+}
+
+// within returns the repository querying through tx
+func (r *postgresRepository) within(tx pgx.Tx) *postgresRepository {
+	return &postgresRepository{db: tx, pool: r.pool, snapshots: r.snapshots, plan: r.plan}
+}
@@ -0,0 +1,213 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/provenance"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+// ErrNegativeProduction is returned when a recalculation would leave a record below zero
+var ErrNegativeProduction = errors.New("the formula makes the production of some records negative")
+
+// recalculationSampleSize is the number of changes a preview lists
+const recalculationSampleSize = 100
+
+// recalculation fills the parameters of a run from the request, defaulting
+// the formula to the identity
+func recalculation(req *models.RecalculationRequest) *models.Recalculation {
+	rc := &models.Recalculation{
+		GeneratorID: req.GeneratorID,
+		TypeID:      req.TypeID,
+		StartDate:   req.StartDate,
+		EndDate:     req.EndDate,
+		Source:      req.Source,
+		Factor:      decimal.NewFromInt(1),
+		Reason:      req.Reason,
+	}
+	if req.Factor != nil {
+		rc.Factor = *req.Factor
+	}
+	if req.Offset != nil {
+		rc.Offset = *req.Offset
+	}
+	return rc
+}
+
+// recalculationScope returns the FROM and WHERE clauses selecting the records
+// of a recalculation, joined to the published snapshot of their month, and
+// the new value expression
+func recalculationScope(rc *models.Recalculation) (from, newMW string, args []any) {
+	conds, args := productionConditions(&models.ProductionFilter{
+		GeneratorID: rc.GeneratorID,
+		StartDate:   &rc.StartDate,
+		EndDate:     &rc.EndDate,
+		Source:      rc.Source,
+	})
+	if rc.TypeID != nil {
+		args = append(args, *rc.TypeID)
+		conds = append(conds, fmt.Sprintf("g.type = $%d", len(args)))
+	}
+	args = append(args, rc.Factor, rc.Offset)
+	newMW = fmt.Sprintf("round(p.production_mw * $%d + $%d, 4)", len(args)-1, len(args))
+	from = `
+		FROM productions p
+		JOIN generators g ON p.generator_id = g.id
+		LEFT JOIN published_snapshots s ON s.month = date_trunc('month', p.date)::date` + whereClause(conds)
+	return from, newMW, args
+}
+
+// summarizeRecalculation counts the records of a recalculation and their totals
+func summarizeRecalculation(ctx context.Context, q execQuerier, rc *models.Recalculation) error {
+	from, newMW, args := recalculationScope(rc)
+	var negative int
+	err := q.QueryRow(ctx, `
+		SELECT COUNT(*), COUNT(s.id), COALESCE(SUM(p.production_mw), 0), COALESCE(SUM(`+newMW+`), 0),
+		       COUNT(*) FILTER (WHERE `+newMW+` < 0)`+from, args...).
+		Scan(&rc.Records, &rc.PublishedRecords, &rc.PreviousTotalMW, &rc.NewTotalMW, &negative)
+	if err != nil {
+		return fmt.Errorf("failed to summarize recalculation: %w", err)
+	}
+	if negative > 0 {
+		return fmt.Errorf("%w (%d records)", ErrNegativeProduction, negative)
+	}
+	rc.PreviousTotalMW = numeric.RoundDecimal(rc.PreviousTotalMW)
+	rc.NewTotalMW = numeric.RoundDecimal(rc.NewTotalMW)
+	return nil
+}
+
+// PreviewRecalculation reports what a recalculation would change, with the
+// first changes in date order, without changing anything
+func (r *postgresRepository) PreviewRecalculation(ctx context.Context, req *models.RecalculationRequest) (*models.Recalculation, error) {
+	rc := recalculation(req)
+	if err := summarizeRecalculation(ctx, r.db, rc); err != nil {
+		return nil, err
+	}
+
+	from, newMW, args := recalculationScope(rc)
+	rows, err := r.db.Query(ctx, `
+		SELECT p.id, p.generator_id, p.date::text, p.production_mw, `+newMW+from+fmt.Sprintf(`
+		ORDER BY p.date, p.generator_id
+		LIMIT %d`, recalculationSampleSize), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recalculation changes: %w", err)
+	}
+	defer rows.Close()
+	rc.Changes = []*models.RecalculationChange{}
+	for rows.Next() {
+		var ch models.RecalculationChange
+		if err := rows.Scan(&ch.ProductionID, &ch.GeneratorID, &ch.Date, &ch.PreviousMW, &ch.NewMW); err != nil {
+			return nil, fmt.Errorf("failed to scan recalculation change: %w", err)
+		}
+		ch.PreviousMW = numeric.RoundDecimal(ch.PreviousMW)
+		ch.NewMW = numeric.RoundDecimal(ch.NewMW)
+		rc.Changes = append(rc.Changes, &ch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return rc, nil
+}
+
+// ApplyRecalculation changes the matching records in one transaction. Like a
+// correction it reaches records of published months without touching the
+// snapshots, and leaves a correction entry on every record it changed. The
+// run itself is kept with its author for GetRecalculations.
+func (r *postgresRepository) ApplyRecalculation(ctx context.Context, req *models.RecalculationRequest, userID *uuid.UUID) (*models.Recalculation, error) {
+	rc := recalculation(req)
+	id := uuid.New()
+	now := time.Now()
+	rc.ID, rc.UserID, rc.CreatedAt, rc.Applied = &id, userID, &now, true
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lets the published-month trigger accept this transaction's writes
+	if _, err := tx.Exec(ctx, `SELECT set_config('tadb.allow_published_edit', 'on', true)`); err != nil {
+		return nil, fmt.Errorf("failed to enable correction mode: %w", err)
+	}
+	if err := summarizeRecalculation(ctx, tx, rc); err != nil {
+		return nil, err
+	}
+
+	from, newMW, args := recalculationScope(rc)
+	args = append(args, "recalculation "+id.String()+": "+rc.Reason, now, provenance.SourceManual, "recalculation:"+id.String())
+	n := len(args)
+	rows, err := tx.Query(ctx, `
+		WITH target AS (
+			SELECT p.id, s.id AS snapshot_id, p.production_mw AS previous_mw, `+newMW+` AS new_mw`+from+`
+			FOR UPDATE OF p
+		), corrections AS (
+			INSERT INTO production_corrections (production_id, snapshot_id, previous_mw, corrected_mw, reason, created_at)
+			SELECT id, snapshot_id, previous_mw, new_mw, `+fmt.Sprintf("$%d, $%d", n-3, n-2)+`
+			FROM target
+		)
+		UPDATE productions p
+		SET production_mw = t.new_mw, source = `+fmt.Sprintf("$%d, source_ref = $%d, updated_at = $%d", n-1, n, n-2)+`
+		FROM target t
+		WHERE p.id = t.id
+		RETURNING p.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recalculate productions: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("failed to recalculate productions: %w", err)
+	}
+	rc.Records = len(ids)
+	if provenance.Enabled() {
+		for _, pid := range ids {
+			if err := signProduction(ctx, tx, pid); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO recalculations (id, generator_id, type_id, start_date, end_date, source, factor, offset_mw, reason,
+		                            user_id, records, published_records, previous_total_mw, new_total_mw, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+		id, rc.GeneratorID, rc.TypeID, rc.StartDate, rc.EndDate, rc.Source, rc.Factor, rc.Offset, rc.Reason,
+		userID, rc.Records, rc.PublishedRecords, rc.PreviousTotalMW, rc.NewTotalMW, now); err != nil {
+		return nil, fmt.Errorf("failed to record recalculation: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit recalculation: %w", err)
+	}
+	return rc, nil
+}
+
+// GetRecalculations lists the applied recalculations, newest first
+func (r *postgresRepository) GetRecalculations(ctx context.Context) ([]*models.Recalculation, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, generator_id, type_id, start_date::text, end_date::text, source, factor, offset_mw, reason,
+		       user_id, records, published_records, previous_total_mw, new_total_mw, created_at
+		FROM recalculations
+		ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recalculations: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.Recalculation
+	for rows.Next() {
+		rc := models.Recalculation{Applied: true}
+		if err := rows.Scan(&rc.ID, &rc.GeneratorID, &rc.TypeID, &rc.StartDate, &rc.EndDate, &rc.Source, &rc.Factor, &rc.Offset, &rc.Reason,
+			&rc.UserID, &rc.Records, &rc.PublishedRecords, &rc.PreviousTotalMW, &rc.NewTotalMW, &rc.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan recalculation: %w", err)
+		}
+		list = append(list, &rc)
+	}
+	return list, rows.Err()
+}
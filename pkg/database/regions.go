@@ -0,0 +1,224 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrRegionExists is returned when another region has the code or name
+var ErrRegionExists = errors.New("region already exists")
+
+// regionSelect reads regions with the count and capacity of their generators
+const regionSelect = `
+	SELECT rg.id, rg.code, rg.name, rg.min_lng, rg.min_lat, rg.max_lng, rg.max_lat,
+	       COUNT(g.id), COALESCE(SUM(g.capacity), 0), rg.created_at, rg.updated_at
+	FROM regions rg
+	LEFT JOIN generators g ON g.region_id = rg.id`
+
+const regionGroupBy = ` GROUP BY rg.id`
+
+func scanRegion(row pgx.Row, rg *models.Region) error {
+	var minLng, minLat, maxLng, maxLat *float64
+	if err := row.Scan(&rg.ID, &rg.Code, &rg.Name, &minLng, &minLat, &maxLng, &maxLat,
+		&rg.GeneratorCount, &rg.TotalCapacity, &rg.CreatedAt, &rg.UpdatedAt); err != nil {
+		return err
+	}
+	if minLng != nil {
+		rg.BBox = []float64{*minLng, *minLat, *maxLng, *maxLat}
+	}
+	rg.TotalCapacity = numeric.RoundDecimal(rg.TotalCapacity)
+	return nil
+}
+
+// regionCode normalizes the code of a region
+func regionCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// bboxParams returns the columns of a bounding box, nil without one
+func bboxParams(bbox []float64) (minLng, minLat, maxLng, maxLat *float64) {
+	if len(bbox) != 4 {
+		return nil, nil, nil, nil
+	}
+	return &bbox[0], &bbox[1], &bbox[2], &bbox[3]
+}
+
+// regionError maps the errors of writing a region
+func regionError(action string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrRegionExists
+	}
+	return fmt.Errorf("failed to %s region: %w", action, err)
+}
+
+// CreateRegion creates a new region
+func (r *postgresRepository) CreateRegion(ctx context.Context, req *models.CreateRegionRequest) (*models.Region, error) {
+	query := `
+		INSERT INTO regions (id, code, name, min_lng, min_lat, max_lng, max_lat, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)`
+
+	id := uuid.New()
+	minLng, minLat, maxLng, maxLat := bboxParams(req.BBox)
+	if _, err := r.db.Exec(ctx, query, id, regionCode(req.Code), strings.TrimSpace(req.Name), minLng, minLat, maxLng, maxLat, time.Now()); err != nil {
+		return nil, regionError("create", err)
+	}
+	return r.GetRegionByID(ctx, id)
+}
+
+// GetRegionByID retrieves a region by its ID
+func (r *postgresRepository) GetRegionByID(ctx context.Context, id uuid.UUID) (*models.Region, error) {
+	var rg models.Region
+	if err := scanRegion(r.db.QueryRow(ctx, regionSelect+` WHERE rg.id = $1`+regionGroupBy, id), &rg); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get region: %w", err)
+	}
+	return &rg, nil
+}
+
+// GetAllRegions retrieves the regions ordered by name
+func (r *postgresRepository) GetAllRegions(ctx context.Context, filter *models.RegionFilter) ([]*models.Region, error) {
+	var args []any
+	query := regionSelect + regionGroupBy + ` ORDER BY rg.name`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query regions: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.Region
+	for rows.Next() {
+		var rg models.Region
+		if err := scanRegion(rows, &rg); err != nil {
+			return nil, fmt.Errorf("failed to scan region: %w", err)
+		}
+		list = append(list, &rg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// UpdateRegion updates the provided fields of a region
+func (r *postgresRepository) UpdateRegion(ctx context.Context, id uuid.UUID, req *models.UpdateRegionRequest) (*models.Region, error) {
+	query := `
+		UPDATE regions
+		SET code = COALESCE($2, code),
+		    name = COALESCE($3, name),
+		    min_lng = COALESCE($4, min_lng),
+		    min_lat = COALESCE($5, min_lat),
+		    max_lng = COALESCE($6, max_lng),
+		    max_lat = COALESCE($7, max_lat),
+		    updated_at = $8
+		WHERE id = $1`
+
+	var code, name *string
+	if req.Code != nil {
+		c := regionCode(*req.Code)
+		code = &c
+	}
+	if req.Name != nil {
+		n := strings.TrimSpace(*req.Name)
+		name = &n
+	}
+	minLng, minLat, maxLng, maxLat := bboxParams(req.BBox)
+	result, err := r.db.Exec(ctx, query, id, code, name, minLng, minLat, maxLng, maxLat, time.Now())
+	if err != nil {
+		return nil, regionError("update", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return r.GetRegionByID(ctx, id)
+}
+
+// DeleteRegion deletes a region. Its generators are kept without region.
+func (r *postgresRepository) DeleteRegion(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM regions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete region: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetMixByRegion returns the renewable and non-renewable production of every
+// region over the date range, ordered by name. Generators without region are
+// grouped as "Unassigned", last and only when there are any.
+func (r *postgresRepository) GetMixByRegion(ctx context.Context, startDate, endDate *string) ([]*models.RegionMix, error) {
+	conds, args := dateRangeConditions("p.date", startDate, endDate, []string{"p.generator_id = g.id"}, nil)
+	query := `
+		WITH gen AS (
+			SELECT g.id, g.region_id, t.isrenuevable AS renewable,
+			       COALESCE(SUM(p.production_mw), 0) AS production, COUNT(p.id) AS records
+			FROM generators g
+			JOIN types t ON g.type = t.id
+			LEFT JOIN productions p ON ` + strings.Join(conds, " AND ") + `
+			GROUP BY g.id, g.region_id, t.isrenuevable
+		)
+		SELECT rg.id, COALESCE(rg.code, ''), COALESCE(rg.name, 'Unassigned'),
+		       COUNT(gen.id),
+		       COALESCE(SUM(gen.records), 0),
+		       COALESCE(SUM(gen.production), 0),
+		       COALESCE(SUM(gen.production) FILTER (WHERE gen.renewable), 0),
+		       COALESCE(SUM(gen.production) FILTER (WHERE NOT gen.renewable), 0)
+		FROM regions rg
+		FULL JOIN gen ON gen.region_id = rg.id
+		GROUP BY rg.id, rg.code, rg.name
+		ORDER BY rg.name NULLS LAST`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query mix by region: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.RegionMix
+	for rows.Next() {
+		var m models.RegionMix
+		if err := rows.Scan(&m.RegionID, &m.RegionCode, &m.RegionName, &m.GeneratorCount, &m.Records,
+			&m.TotalProduction, &m.RenewableProduction, &m.NonRenewableProduction); err != nil {
+			return nil, fmt.Errorf("failed to scan mix by region: %w", err)
+		}
+		shareRegionMix(&m)
+		list = append(list, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// shareRegionMix fills the shares of a region mix from its production and
+// rounds it
+func shareRegionMix(m *models.RegionMix) {
+	m.RenewableShare = numeric.RoundDecimal(percentOf(m.RenewableProduction, m.TotalProduction))
+	m.NonRenewableShare = numeric.RoundDecimal(percentOf(m.NonRenewableProduction, m.TotalProduction))
+	m.TotalProduction = numeric.RoundDecimal(m.TotalProduction)
+	m.RenewableProduction = numeric.RoundDecimal(m.RenewableProduction)
+	m.NonRenewableProduction = numeric.RoundDecimal(m.NonRenewableProduction)
+}
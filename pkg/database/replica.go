@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReplicaConfig represents a read replica of the default database
+type ReplicaConfig struct {
+	// URI of the replica; without it every query runs on the primary
+	URI string
+	// MaxLag is how long after a write its client reads from the primary
+	// when it sends the consistency token of the write back
+	MaxLag time.Duration
+}
+
+// LoadReplicaConfig loads the read replica settings from environment variables
+func LoadReplicaConfig() *ReplicaConfig {
+	return &ReplicaConfig{
+		URI:    utils.GetEnv("DB_REPLICA_URI", ""),
+		MaxLag: utils.GetEnvAsDuration("DB_REPLICA_MAX_LAG", 5*time.Second),
+	}
+}
+
+// NewReplicaConnection creates the connection pool of the read replica at
+// uri. An unreachable replica is logged, not fatal: its queries fail until it
+// comes back, and requests asking for consistent reads still use the primary.
+func NewReplicaConnection(ctx context.Context, uri string) (*DB, error) {
+	poolConfig, err := pgxpool.ParseConfig(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DB_REPLICA_URI: %w", err)
+	}
+	configurePool(poolConfig)
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool of the replica: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		log.Printf("Warning: read replica is unreachable: %v", err)
+	}
+	log.Printf("Reading from replica %s@%s:%d/%s",
+		poolConfig.ConnConfig.User, poolConfig.ConnConfig.Host, poolConfig.ConnConfig.Port, poolConfig.ConnConfig.Database)
+	return &DB{Pool: pool}, nil
+}
+
+var (
+	readSQL     = regexp.MustCompile(`(?i)^\s*(SELECT|WITH)\b`)
+	notReadOnly = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|SHARE|NEXTVAL|SETVAL)\b`)
+)
+
+// readOnlySQL reports whether a replica can run sql: a SELECT or WITH query
+// that neither writes, takes sequence values nor locks rows (FOR UPDATE/SHARE)
+func readOnlySQL(sql string) bool {
+	return readSQL.MatchString(sql) && !notReadOnly.MatchString(sql)
+}
+
+// replicaDB runs the read-only queries of requests allowed to read from the
+// replica (see auth.WithReplicaReads) there, and everything else, writes and
+// transactions included, on the primary
+type replicaDB struct {
+	primary *pgxpool.Pool
+	replica *pgxpool.Pool
+}
+
+func (d replicaDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return d.primary.Begin(ctx)
+}
+
+func (d replicaDB) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	return d.primary.BeginTx(ctx, opts)
+}
+
+func (d replicaDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return d.primary.Exec(ctx, sql, args...)
+}
+
+func (d replicaDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return d.pool(ctx, sql).Query(ctx, sql, args...)
+}
+
+func (d replicaDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return d.pool(ctx, sql).QueryRow(ctx, sql, args...)
+}
+
+func (d replicaDB) pool(ctx context.Context, sql string) *pgxpool.Pool {
+	if auth.ReplicaReadsFrom(ctx) && readOnlySQL(sql) {
+		return d.replica
+	}
+	return d.primary
+}
+
+// NewReplicaRepository creates a repository on primary that sends the reads
+// of requests allowed to lag behind to replica
+func NewReplicaRepository(primary, replica *pgxpool.Pool) Repository {
+	db := replicaDB{primary: primary, replica: replica}
+	return &postgresRepository{
+		db:        db,
+		pool:      db,
+		snapshots: newReadSnapshots(LoadReadSnapshotConfig()),
+		plan:      LoadAnalyticsPlanConfig(),
+	}
+}
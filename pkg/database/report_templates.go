@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const reportTemplateColumns = `id, name, description, language, branding, sections, labels, subject, email_body, created_at, updated_at`
+
+func scanReportTemplate(row pgx.Row, t *models.ReportTemplate) error {
+	return row.Scan(&t.ID, &t.Name, &t.Description, &t.Language, &t.Branding, &t.Sections, &t.Labels,
+		&t.Subject, &t.EmailBody, &t.CreatedAt, &t.UpdatedAt)
+}
+
+// templateArgs returns the stored form of a template request: English by
+// default and empty JSON instead of NULL for missing sections and labels
+func templateArgs(req *models.ReportTemplateRequest) (string, []models.ReportSection, map[string]string) {
+	language, sections, labels := req.Language, req.Sections, req.Labels
+	if language == "" {
+		language = "en"
+	}
+	if sections == nil {
+		sections = []models.ReportSection{}
+	}
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	return language, sections, labels
+}
+
+// CreateReportTemplate saves a report template
+func (r *postgresRepository) CreateReportTemplate(ctx context.Context, req *models.ReportTemplateRequest) (*models.ReportTemplate, error) {
+	language, sections, labels := templateArgs(req)
+	query := `
+		INSERT INTO report_templates (id, name, description, language, branding, sections, labels, subject, email_body, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+		RETURNING ` + reportTemplateColumns
+
+	var t models.ReportTemplate
+	err := scanReportTemplate(r.db.QueryRow(ctx, query, uuid.New(), req.Name, req.Description, language,
+		req.Branding, sections, labels, req.Subject, req.EmailBody, time.Now()), &t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report template: %w", err)
+	}
+	return &t, nil
+}
+
+// GetReportTemplates lists report templates ordered by name
+func (r *postgresRepository) GetReportTemplates(ctx context.Context) ([]*models.ReportTemplate, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+reportTemplateColumns+` FROM report_templates ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report templates: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.ReportTemplate
+	for rows.Next() {
+		var t models.ReportTemplate
+		if err := scanReportTemplate(rows, &t); err != nil {
+			return nil, fmt.Errorf("failed to scan report template: %w", err)
+		}
+		list = append(list, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// GetReportTemplateByID retrieves a report template
+func (r *postgresRepository) GetReportTemplateByID(ctx context.Context, id uuid.UUID) (*models.ReportTemplate, error) {
+	var t models.ReportTemplate
+	err := scanReportTemplate(r.db.QueryRow(ctx, `SELECT `+reportTemplateColumns+` FROM report_templates WHERE id = $1`, id), &t)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get report template: %w", err)
+	}
+	return &t, nil
+}
+
+// UpdateReportTemplate replaces a report template; reports using it pick the change up on their next run
+func (r *postgresRepository) UpdateReportTemplate(ctx context.Context, id uuid.UUID, req *models.ReportTemplateRequest) (*models.ReportTemplate, error) {
+	language, sections, labels := templateArgs(req)
+	query := `
+		UPDATE report_templates
+		SET name = $2, description = $3, language = $4, branding = $5, sections = $6, labels = $7,
+		    subject = $8, email_body = $9, updated_at = $10
+		WHERE id = $1
+		RETURNING ` + reportTemplateColumns
+
+	var t models.ReportTemplate
+	err := scanReportTemplate(r.db.QueryRow(ctx, query, id, req.Name, req.Description, language,
+		req.Branding, sections, labels, req.Subject, req.EmailBody, time.Now()), &t)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to update report template: %w", err)
+	}
+	return &t, nil
+}
+
+// DeleteReportTemplate moves a report template to the trash; the reports using it fall back to the standard bulletin
+func (r *postgresRepository) DeleteReportTemplate(ctx context.Context, id uuid.UUID) error {
+	return r.moveToTrash(ctx, models.TrashReportTemplate, id, false)
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/webhook"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const reportColumns = `id, name, kind, format, filters, schedule, delivery, template_id, enabled, next_run_at, last_run_at, created_at, updated_at`
+
+func scanReport(row pgx.Row, rep *models.Report) error {
+	return row.Scan(&rep.ID, &rep.Name, &rep.Kind, &rep.Format, &rep.Filters, &rep.Schedule, &rep.Delivery,
+		&rep.TemplateID, &rep.Enabled, &rep.NextRunAt, &rep.LastRunAt, &rep.CreatedAt, &rep.UpdatedAt)
+}
+
+func scanReports(rows pgx.Rows) ([]*models.Report, error) {
+	defer rows.Close()
+
+	var list []*models.Report
+	for rows.Next() {
+		var rep models.Report
+		if err := scanReport(rows, &rep); err != nil {
+			return nil, fmt.Errorf("failed to scan report: %w", err)
+		}
+		list = append(list, &rep)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// CreateReport saves a report definition with a new webhook signing secret;
+// nextRunAt is its first scheduled run (nil for on-demand reports)
+func (r *postgresRepository) CreateReport(ctx context.Context, req *models.ReportRequest, nextRunAt *time.Time) (*models.Report, error) {
+	enabled := req.Enabled == nil || *req.Enabled
+	query := `
+		INSERT INTO reports (id, name, kind, format, filters, schedule, delivery, template_id, webhook_secret, enabled, next_run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12)
+		RETURNING ` + reportColumns
+
+	var rep models.Report
+	err := scanReport(r.db.QueryRow(ctx, query, uuid.New(), req.Name, req.Kind, req.Format,
+		req.Filters, req.Schedule, req.Delivery, req.TemplateID, webhook.NewSecret(), enabled, nextRunAt, time.Now()), &rep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report: %w", err)
+	}
+	return &rep, nil
+}
+
+// GetReports lists saved reports ordered by name
+func (r *postgresRepository) GetReports(ctx context.Context) ([]*models.Report, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+reportColumns+` FROM reports ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reports: %w", err)
+	}
+	return scanReports(rows)
+}
+
+// GetReportByID retrieves a saved report
+func (r *postgresRepository) GetReportByID(ctx context.Context, id uuid.UUID) (*models.Report, error) {
+	var rep models.Report
+	if err := scanReport(r.db.QueryRow(ctx, `SELECT `+reportColumns+` FROM reports WHERE id = $1`, id), &rep); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get report: %w", err)
+	}
+	return &rep, nil
+}
+
+// UpdateReport replaces a report definition and its next scheduled run
+func (r *postgresRepository) UpdateReport(ctx context.Context, id uuid.UUID, req *models.ReportRequest, nextRunAt *time.Time) (*models.Report, error) {
+	enabled := req.Enabled == nil || *req.Enabled
+	query := `
+		UPDATE reports
+		SET name = $2, kind = $3, format = $4, filters = $5, schedule = $6, delivery = $7,
+		    template_id = $8, enabled = $9, next_run_at = $10, updated_at = $11
+		WHERE id = $1
+		RETURNING ` + reportColumns
+
+	var rep models.Report
+	err := scanReport(r.db.QueryRow(ctx, query, id, req.Name, req.Kind, req.Format,
+		req.Filters, req.Schedule, req.Delivery, req.TemplateID, enabled, nextRunAt, time.Now()), &rep)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to update report: %w", err)
+	}
+	return &rep, nil
+}
+
+// DeleteReport moves a report to the trash together with its run history
+func (r *postgresRepository) DeleteReport(ctx context.Context, id uuid.UUID) error {
+	return r.moveToTrash(ctx, models.TrashReport, id, false)
+}
+
+// GetDueReports lists enabled reports whose next run is at or before now
+func (r *postgresRepository) GetDueReports(ctx context.Context, now time.Time) ([]*models.Report, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+reportColumns+`
+		FROM reports
+		WHERE enabled AND next_run_at <= $1
+		ORDER BY next_run_at`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due reports: %w", err)
+	}
+	return scanReports(rows)
+}
+
+// ClaimReportRun moves the next run of a report from due to next. It returns
+// false when another instance claimed that run first.
+func (r *postgresRepository) ClaimReportRun(ctx context.Context, id uuid.UUID, due time.Time, next *time.Time) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE reports SET next_run_at = $3
+		WHERE id = $1 AND next_run_at = $2`, id, due, next)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim report run: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// CreateReportRun records the start of a run and sets the last run of its report
+func (r *postgresRepository) CreateReportRun(ctx context.Context, run *models.ReportRun) error {
+	_, err := r.db.Exec(ctx, `
+		WITH run AS (
+			INSERT INTO report_runs (id, report_id, trigger, status, period_start, period_end, started_at)
+			VALUES ($1, $2, $3, $4, NULLIF($5, '')::date, NULLIF($6, '')::date, $7)
+		)
+		UPDATE reports SET last_run_at = $7 WHERE id = $2`,
+		run.ID, run.ReportID, run.Trigger, run.Status, run.PeriodStart, run.PeriodEnd, run.StartedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create report run: %w", err)
+	}
+	return nil
+}
+
+// FinishReportRun records the outcome of a run
+func (r *postgresRepository) FinishReportRun(ctx context.Context, run *models.ReportRun) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE report_runs
+		SET status = $2, row_count = $3, byte_count = $4, error = NULLIF($5, ''), finished_at = $6
+		WHERE id = $1`,
+		run.ID, run.Status, run.Rows, run.Bytes, run.Error, run.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to finish report run: %w", err)
+	}
+	return nil
+}
+
+// GetReportRuns lists the latest runs of a report, newest first
+func (r *postgresRepository) GetReportRuns(ctx context.Context, reportID uuid.UUID, limit int) ([]*models.ReportRun, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, report_id, trigger, status, COALESCE(period_start::text, ''), COALESCE(period_end::text, ''),
+		       row_count, byte_count, COALESCE(error, ''), started_at, finished_at
+		FROM report_runs
+		WHERE report_id = $1
+		ORDER BY started_at DESC
+		LIMIT $2`, reportID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report runs: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.ReportRun
+	for rows.Next() {
+		var run models.ReportRun
+		if err := rows.Scan(&run.ID, &run.ReportID, &run.Trigger, &run.Status, &run.PeriodStart, &run.PeriodEnd,
+			&run.Rows, &run.Bytes, &run.Error, &run.StartedAt, &run.FinishedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan report run: %w", err)
+		}
+		list = append(list, &run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
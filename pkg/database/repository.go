@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/geo"
 	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/provenance"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -19,55 +22,333 @@ type Repository interface {
     GetTypeByID(ctx context.Context, id uuid.UUID) (*models.Type, error)
     GetAllTypes(ctx context.Context, isRenewable *bool) ([]*models.Type, error)
     UpdateType(ctx context.Context, id uuid.UUID, req *models.UpdateTypeRequest) (*models.Type, error)
-    DeleteType(ctx context.Context, id uuid.UUID) error
-
-    // User operations (placeholder for future implementation)
+    DeleteType(ctx context.Context, id uuid.UUID, cascade bool) error
+    MergeType(ctx context.Context, sourceID, targetID uuid.UUID) (*models.TypeMergeResult, error)
+
+    // Type translation operations; languages are models.Languages
+    GetTypeTranslations(ctx context.Context, typeID uuid.UUID) ([]*models.TypeTranslation, error)
+    GetTranslationsByLanguage(ctx context.Context, language string) (map[uuid.UUID]*models.TypeTranslation, error)
+    SetTypeTranslation(ctx context.Context, typeID uuid.UUID, language string, req *models.TypeTranslationRequest) (*models.TypeTranslation, error)
+    DeleteTypeTranslation(ctx context.Context, typeID uuid.UUID, language string) error
+
+    // User operations; CreateUser fails with ErrUserExists for a taken
+    // username or email, and GetUserCredentials returns the password hash.
+    // CreateFirstUser creates an admin atomically with the check that there
+    // are no users, failing with ErrUsersExist otherwise.
+    CreateUser(ctx context.Context, req *models.RegisterRequest, passwordHash string, roles []string) (*models.User, error)
+    CreateFirstUser(ctx context.Context, req *models.RegisterRequest, passwordHash string) (*models.User, error)
     GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+    GetUserCredentials(ctx context.Context, username string) (*models.User, string, error)
+    SetUserRoles(ctx context.Context, id uuid.UUID, roles []string) (*models.User, error)
+    CountUsers(ctx context.Context) (int64, error)
+
+    // Login lockout operations, per lower-case username; GetLoginLockout and
+    // UnlockLogin return sql.ErrNoRows for usernames without failed logins
+    GetLoginLockout(ctx context.Context, username string) (*models.LoginLockout, error)
+    GetLoginLockouts(ctx context.Context, filter *models.LoginLockoutFilter) ([]*models.LoginLockout, error)
+    // RecordLoginFailure counts a failed login, restarting the count when
+    // the previous failure is older than window
+    RecordLoginFailure(ctx context.Context, username, ip string, window time.Duration) (*models.LoginLockout, error)
+    LockLogin(ctx context.Context, username string, until time.Time) (*models.LoginLockout, error)
+    ResetLoginFailures(ctx context.Context, username string) error
+    UnlockLogin(ctx context.Context, username string) (*models.LoginLockout, error)
+
+    // Two-factor operations; GetTwoFactor and DeleteTwoFactor return
+    // sql.ErrNoRows for users without an enrollment
+    GetTwoFactor(ctx context.Context, userID uuid.UUID) (*models.TwoFactor, error)
+    // SetTwoFactorSecret starts a pending enrollment, replacing a pending
+    // one; it fails with ErrTwoFactorEnabled for enabled ones
+    SetTwoFactorSecret(ctx context.Context, userID uuid.UUID, secret string) error
+    // EnableTwoFactor confirms a pending enrollment with the step of its
+    // first code and stores its backup codes
+    EnableTwoFactor(ctx context.Context, userID uuid.UUID, step int64, codeHashes []string) error
+    // UseTwoFactorStep records the step of an accepted code; false when a
+    // code of that step or a later one was already accepted
+    UseTwoFactorStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+    // UseBackupCode uses up a backup code; false when it is unknown or used
+    UseBackupCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+    ReplaceBackupCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+    DeleteTwoFactor(ctx context.Context, userID uuid.UUID) error
+
+    // Operator operations
+    CreateOperator(ctx context.Context, req *models.CreateOperatorRequest) (*models.Operator, error)
+    GetOperatorByID(ctx context.Context, id uuid.UUID) (*models.Operator, error)
+    GetAllOperators(ctx context.Context) ([]*models.Operator, error)
+    UpdateOperator(ctx context.Context, id uuid.UUID, req *models.UpdateOperatorRequest) (*models.Operator, error)
+    DeleteOperator(ctx context.Context, id uuid.UUID) error
+    GetOperatorGrants(ctx context.Context, userID uuid.UUID) ([]*models.OperatorGrant, error)
+    GrantOperator(ctx context.Context, userID, operatorID uuid.UUID) error
+    RevokeOperator(ctx context.Context, userID, operatorID uuid.UUID) error
+
+    // Plant operations; deleting a plant keeps its generators without plant
+    CreatePlant(ctx context.Context, req *models.CreatePlantRequest) (*models.Plant, error)
+    GetPlantByID(ctx context.Context, id uuid.UUID) (*models.Plant, error)
+    GetAllPlants(ctx context.Context, filter *models.PlantFilter) ([]*models.Plant, error)
+    UpdatePlant(ctx context.Context, id uuid.UUID, req *models.UpdatePlantRequest) (*models.Plant, error)
+    DeletePlant(ctx context.Context, id uuid.UUID) error
+
+    // Region operations; codes are stored upper-case and deleting a region
+    // keeps its generators without region
+    CreateRegion(ctx context.Context, req *models.CreateRegionRequest) (*models.Region, error)
+    GetRegionByID(ctx context.Context, id uuid.UUID) (*models.Region, error)
+    GetAllRegions(ctx context.Context, filter *models.RegionFilter) ([]*models.Region, error)
+    UpdateRegion(ctx context.Context, id uuid.UUID, req *models.UpdateRegionRequest) (*models.Region, error)
+    DeleteRegion(ctx context.Context, id uuid.UUID) error
+
+    // Holiday operations; country is an ISO 3166-1 alpha-2 code and a holiday
+    // with region only applies to that region of the country
+    ListHolidays(ctx context.Context, country string) ([]*models.Holiday, error)
+    CreateHoliday(ctx context.Context, req *models.HolidayRequest) (*models.Holiday, error)
+    DeleteHoliday(ctx context.Context, id uuid.UUID) error
+
+    // Custom field operations; entity is one of the models.CustomEntity values, empty for all.
+    // Deleting a field removes its values from the records of its entity
+    GetCustomFields(ctx context.Context, entity string) ([]*models.CustomField, error)
+    GetCustomFieldByID(ctx context.Context, id uuid.UUID) (*models.CustomField, error)
+    CreateCustomField(ctx context.Context, req *models.CreateCustomFieldRequest) (*models.CustomField, error)
+    UpdateCustomField(ctx context.Context, id uuid.UUID, req *models.UpdateCustomFieldRequest) (*models.CustomField, error)
+    DeleteCustomField(ctx context.Context, id uuid.UUID) error
+
+    // Import profile operations; source filters by provider, empty for all
+    GetImportProfiles(ctx context.Context, source string) ([]*models.ImportProfile, error)
+    GetImportProfileByID(ctx context.Context, id uuid.UUID) (*models.ImportProfile, error)
+    CreateImportProfile(ctx context.Context, req *models.CreateImportProfileRequest) (*models.ImportProfile, error)
+    UpdateImportProfile(ctx context.Context, id uuid.UUID, req *models.UpdateImportProfileRequest) (*models.ImportProfile, error)
+    DeleteImportProfile(ctx context.Context, id uuid.UUID) error
+
+    // Dead letter operations; rows rejected by imports, kept to be corrected and resubmitted
+    CreateDeadLetter(ctx context.Context, d *models.DeadLetter) error
+    GetDeadLetters(ctx context.Context, filter *models.DeadLetterFilter) ([]*models.DeadLetter, error)
+    GetDeadLetterByID(ctx context.Context, id uuid.UUID) (*models.DeadLetter, error)
+    UpdateDeadLetter(ctx context.Context, id uuid.UUID, req *models.UpdateDeadLetterRequest) (*models.DeadLetter, error)
+    RecordDeadLetterAttempt(ctx context.Context, id uuid.UUID, productionID *uuid.UUID, errMsg string) (*models.DeadLetter, error)
+    DeleteDeadLetter(ctx context.Context, id uuid.UUID) error
 
     // Generator operations
     CreateGenerator(ctx context.Context, req *models.CreateGeneratorRequest) (*models.Generator, error)
     GetGeneratorByID(ctx context.Context, id uuid.UUID) (*models.Generator, error)
-    GetAllGenerators(ctx context.Context, typeID *uuid.UUID) ([]*models.Generator, error)
+    GetAllGenerators(ctx context.Context, filter models.GeneratorFilter) ([]*models.Generator, error)
     UpdateGenerator(ctx context.Context, id uuid.UUID, req *models.UpdateGeneratorRequest) (*models.Generator, error)
-    DeleteGenerator(ctx context.Context, id uuid.UUID) error
+    DeleteGenerator(ctx context.Context, id uuid.UUID, cascade bool) error
 
     // Production operations
     CreateProduction(ctx context.Context, req *models.CreateProductionRequest) (*models.Production, error)
+    // UpsertProduction creates the production or updates the one of its generator and
+    // date; created reports which
+    UpsertProduction(ctx context.Context, req *models.CreateProductionRequest) (p *models.Production, created bool, err error)
+    // CreateProductions creates many productions at once; records that fail
+    // have their error at their index in errs and do not stop the others
+    CreateProductions(ctx context.Context, reqs []*models.CreateProductionRequest) (created []*models.Production, errs []error, err error)
+    // UpdateProductions applies many updates in one transaction, reporting
+    // failures like CreateProductions. With atomic, one failure rolls back
+    // every update and the others report ErrBulkRolledBack
+    UpdateProductions(ctx context.Context, updates []*models.BulkProductionUpdate, atomic bool) (updated []*models.Production, errs []error, err error)
     GetProductionByID(ctx context.Context, id uuid.UUID) (*models.Production, error)
-    GetAllProductions(ctx context.Context, generatorID *uuid.UUID, startDate, endDate *string) ([]*models.Production, error)
+    GetAllProductions(ctx context.Context, filter *models.ProductionFilter) ([]*models.Production, error)
+    GetProductionFacets(ctx context.Context, filter *models.ProductionFilter) (*models.ProductionFacets, error)
     UpdateProduction(ctx context.Context, id uuid.UUID, req *models.UpdateProductionRequest) (*models.Production, error)
     DeleteProduction(ctx context.Context, id uuid.UUID) error
+    // ComputeProductions sets the requested computed fields (models.Compute*) of productions
+    ComputeProductions(ctx context.Context, list []*models.Production, fields []string) error
+    // RecordTelemetry adds or sets the production of the reading's generator and day
+    RecordTelemetry(ctx context.Context, reading *models.TelemetryReading) (*models.Production, error)
+
+    // Consistent read operations; the repository given to fn reads one state of the
+    // database, as of the start of the call or the opening of the read snapshot, and
+    // only reads. InReadSnapshot and CloseReadSnapshot return ErrReadSnapshotNotFound
+    // for snapshots that are not open
+    ReadConsistent(ctx context.Context, fn func(Repository) error) error
+    OpenReadSnapshot(ctx context.Context) (*models.ReadSnapshot, error)
+    InReadSnapshot(ctx context.Context, id uuid.UUID, fn func(Repository) error) error
+    CloseReadSnapshot(ctx context.Context, id uuid.UUID) error
+
+    // Published snapshot operations; published months only change through corrections
+    PublishSnapshot(ctx context.Context, month time.Time) (*models.Snapshot, error)
+    GetSnapshots(ctx context.Context) ([]*models.Snapshot, error)
+    GetSnapshot(ctx context.Context, month time.Time) (*models.SnapshotDetail, error)
+    VerifySnapshot(ctx context.Context, month time.Time) (*models.SnapshotVerification, error)
+    ApplyCorrection(ctx context.Context, productionID uuid.UUID, req *models.CreateCorrectionRequest) (*models.ProductionCorrection, error)
+    GetCorrections(ctx context.Context, productionID uuid.UUID) ([]*models.ProductionCorrection, error)
+
+    // Recalculation operations; rescale the production records matching a filter, with
+    // a correction entry on each. Previews change nothing and list the first changes
+    PreviewRecalculation(ctx context.Context, req *models.RecalculationRequest) (*models.Recalculation, error)
+    ApplyRecalculation(ctx context.Context, req *models.RecalculationRequest, userID *uuid.UUID) (*models.Recalculation, error)
+    GetRecalculations(ctx context.Context) ([]*models.Recalculation, error)
+
+    // Annotation operations; notes on a production record or on a date range of a generator or the fleet
+    CreateAnnotation(ctx context.Context, req *models.CreateAnnotationRequest) (*models.Annotation, error)
+    GetAnnotationByID(ctx context.Context, id uuid.UUID) (*models.Annotation, error)
+    GetAnnotations(ctx context.Context, filter *models.AnnotationFilter) ([]*models.Annotation, error)
+    UpdateAnnotation(ctx context.Context, id uuid.UUID, req *models.UpdateAnnotationRequest) (*models.Annotation, error)
+    DeleteAnnotation(ctx context.Context, id uuid.UUID) error
+
+    // Analytics operations
+    GetTotalProductionByDate(ctx context.Context, startDate, endDate *string, asOf *time.Time) ([]*models.TotalProductionByDate, error)
+    GetMarketShareByOperator(ctx context.Context, startDate, endDate *string, asOf *time.Time) ([]*models.OperatorMarketShare, error)
+    GetCrosstab(ctx context.Context, q *models.CrosstabQuery) (*models.Crosstab, error)
+    GetLatestProductionDates(ctx context.Context) ([]*models.GeneratorLatestProduction, error)
+    GetEnergyMix(ctx context.Context, date string) (*models.EnergyMix, error)
+    GetProductionByGenerator(ctx context.Context, startDate, endDate *string) ([]*models.GeneratorProduction, error)
+    GetGeneratorEfficiency(ctx context.Context, startDate, endDate *string) ([]*models.GeneratorEfficiency, error)
+    GetRenewableSummary(ctx context.Context, startDate, endDate *string) ([]*models.RenewableSummary, error)
+    GetMixByRegion(ctx context.Context, startDate, endDate *string) ([]*models.RegionMix, error)
+    GetTimeSeries(ctx context.Context, q *models.TimeSeriesQuery) (*models.TimeSeries, error)
+    GetCapacityFactorTrend(ctx context.Context, generatorID uuid.UUID, q *models.CapacityFactorQuery) (*models.CapacityFactorTrend, error)
+
+    // Submission calendar operations; scope is models.CadenceScopeType or CadenceScopeGenerator
+    GetSubmissionCalendar(ctx context.Context) ([]*models.SubmissionCadence, error)
+    SetSubmissionCadence(ctx context.Context, scope string, id uuid.UUID, cadence string) error
+    DeleteSubmissionCadence(ctx context.Context, scope string, id uuid.UUID) error
+
+    // Saved report operations; runs are claimed through next_run_at so each is done once
+    CreateReport(ctx context.Context, req *models.ReportRequest, nextRunAt *time.Time) (*models.Report, error)
+    GetReports(ctx context.Context) ([]*models.Report, error)
+    GetReportByID(ctx context.Context, id uuid.UUID) (*models.Report, error)
+    UpdateReport(ctx context.Context, id uuid.UUID, req *models.ReportRequest, nextRunAt *time.Time) (*models.Report, error)
+    DeleteReport(ctx context.Context, id uuid.UUID) error
+    GetDueReports(ctx context.Context, now time.Time) ([]*models.Report, error)
+    ClaimReportRun(ctx context.Context, id uuid.UUID, due time.Time, next *time.Time) (bool, error)
+    CreateReportRun(ctx context.Context, run *models.ReportRun) error
+    FinishReportRun(ctx context.Context, run *models.ReportRun) error
+    GetReportRuns(ctx context.Context, reportID uuid.UUID, limit int) ([]*models.ReportRun, error)
+
+    // Report webhook operations
+    GetReportWebhookSecret(ctx context.Context, reportID uuid.UUID) (string, error)
+    RotateReportWebhookSecret(ctx context.Context, reportID uuid.UUID) (*models.WebhookSecret, error)
+    CreateWebhookDelivery(ctx context.Context, d *models.WebhookDelivery) error
+    GetWebhookDeliveries(ctx context.Context, reportID uuid.UUID, limit int) ([]*models.WebhookDelivery, error)
+    GetWebhookDeliveryByID(ctx context.Context, reportID, id uuid.UUID) (*models.WebhookDelivery, error)
+
+    // Report template operations
+    CreateReportTemplate(ctx context.Context, req *models.ReportTemplateRequest) (*models.ReportTemplate, error)
+    GetReportTemplates(ctx context.Context) ([]*models.ReportTemplate, error)
+    GetReportTemplateByID(ctx context.Context, id uuid.UUID) (*models.ReportTemplate, error)
+    UpdateReportTemplate(ctx context.Context, id uuid.UUID, req *models.ReportTemplateRequest) (*models.ReportTemplate, error)
+    DeleteReportTemplate(ctx context.Context, id uuid.UUID) error
+
+    // Email template operations; every edit adds a version and the latest is current
+    GetEmailTemplate(ctx context.Context, key string) (*models.EmailTemplate, error)
+    GetEmailTemplateVersions(ctx context.Context, key string) ([]*models.EmailTemplate, error)
+    GetEmailTemplateVersion(ctx context.Context, key string, version int) (*models.EmailTemplate, error)
+    CreateEmailTemplateVersion(ctx context.Context, key string, req *models.EmailTemplateRequest, userID *uuid.UUID) (*models.EmailTemplate, error)
+
+    // Notification preference operations; alerts for digest users are queued as digest items
+    GetNotificationPreferences(ctx context.Context, userID uuid.UUID) ([]*models.NotificationPreference, error)
+    SetNotificationPreference(ctx context.Context, userID uuid.UUID, alertType string, req *models.NotificationPreferenceRequest) (*models.NotificationPreference, error)
+    DeleteNotificationPreference(ctx context.Context, userID uuid.UUID, alertType string) error
+    GetNotificationSubscribers(ctx context.Context, alertType string) ([]*models.NotificationSubscriber, error)
+    QueueDigestItem(ctx context.Context, item *models.DigestItem) error
+    GetDigestItems(ctx context.Context) ([]*models.DigestItem, error)
+    DeleteDigestItems(ctx context.Context, ids []int64) error
+
+    // Notification inbox operations; notifications belong to one user
+    CreateNotification(ctx context.Context, n *models.Notification) error
+    GetNotifications(ctx context.Context, filter *models.NotificationFilter) ([]*models.Notification, error)
+    GetUnreadNotifications(ctx context.Context, userID uuid.UUID) (*models.UnreadNotifications, error)
+    MarkNotificationRead(ctx context.Context, userID, id uuid.UUID) (*models.Notification, error)
+    MarkNotificationsRead(ctx context.Context, userID uuid.UUID, alertType *string) (int64, error)
+    PurgeNotifications(ctx context.Context, before time.Time) (int64, error)
+
+    // Impersonation operations; every request of a session is recorded as an event
+    CreateImpersonation(ctx context.Context, s *models.Impersonation) error
+    GetImpersonation(ctx context.Context, id uuid.UUID) (*models.Impersonation, error)
+    GetImpersonations(ctx context.Context, filter *models.ImpersonationFilter) ([]*models.Impersonation, error)
+    EndImpersonation(ctx context.Context, id uuid.UUID) (*models.Impersonation, error)
+    CreateImpersonationEvent(ctx context.Context, e *models.ImpersonationEvent) error
+    GetImpersonationEvents(ctx context.Context, sessionID uuid.UUID, limit, offset int) ([]*models.ImpersonationEvent, error)
+
+    // Audit log operations; entries are written by NewAuditedRepository on every audited change
+    CreateAuditEntry(ctx context.Context, e *models.AuditEntry) error
+    GetAuditEntries(ctx context.Context, filter *models.AuditFilter) ([]*models.AuditEntry, error)
+    GetAuditEntry(ctx context.Context, id int64) (*models.AuditEntry, error)
+
+    // Data quality operations; checks are models.QualityChecks, all when empty
+    ScanDataQuality(ctx context.Context, checks []string, limit int) (*models.DataQualityReport, error)
+
+    // Schema introspection
+    GetSchema(ctx context.Context) (*models.DatabaseSchema, error)
+
+    // Event log and projection operations; projections catch up from the log and can be rebuilt from it alone
+    GetDomainEvents(ctx context.Context, filter *models.DomainEventFilter) ([]*models.DomainEvent, error)
+    GetProjections(ctx context.Context) ([]*models.Projection, error)
+    CatchUpProjections(ctx context.Context) (int64, error)
+    RebuildProjection(ctx context.Context, name string) (*models.Projection, error)
+    GetDailyProductionSummary(ctx context.Context, startDate, endDate *string) ([]*models.DailyProductionSummary, error)
+
+    // Trash operations; deleting a type, operator, generator, production, report or template moves it to the trash
+    GetTrash(ctx context.Context, resource string) ([]*models.TrashItem, error)
+    GetTrashItem(ctx context.Context, id uuid.UUID) (*models.TrashItem, error)
+    RestoreTrashItem(ctx context.Context, id uuid.UUID) (*models.TrashItem, error)
+    PurgeTrashItem(ctx context.Context, id uuid.UUID) error
+    PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error)
+
+    // Attachment operations; scope is models.AttachmentScopeType or AttachmentScopeGenerator.
+    // Attachments are deleted with their entity and the objects they leave are queued for removal
+    CreateAttachment(ctx context.Context, a *models.Attachment, content []byte) (*models.Attachment, error)
+    GetAttachments(ctx context.Context, scope string, entityID uuid.UUID) ([]*models.Attachment, error)
+    GetAttachment(ctx context.Context, scope string, entityID, id uuid.UUID) (*models.Attachment, []byte, error)
+    DeleteAttachment(ctx context.Context, scope string, entityID, id uuid.UUID) error
+    GetAttachmentObjectDeletions(ctx context.Context, limit int) ([]string, error)
+    ForgetAttachmentObject(ctx context.Context, key string) error
+
+    // Partner feed operations; connectors track the files they fetched by path and checksum
+    ConnectorFileSeen(ctx context.Context, source, remotePath string, size int64, modifiedAt time.Time) (bool, error)
+    ConnectorChecksumStaged(ctx context.Context, source, sha256 string) (bool, error)
+    CreateConnectorFile(ctx context.Context, f *models.ConnectorFile) error
+    GetConnectorFiles(ctx context.Context, connector, source string, limit int) ([]*models.ConnectorFile, error)
 }
 
 // postgresRepository implements Repository interface
 type postgresRepository struct {
-	db *pgxpool.Pool
+	db        dbtx
+	pool      txBeginner
+	snapshots *readSnapshots
+	plan      *AnalyticsPlanConfig
 }
 
 // NewRepository creates a new repository instance
 func NewRepository(db *pgxpool.Pool) Repository {
     return &postgresRepository{
-        db: db,
+        db:        db,
+        pool:      db,
+        snapshots: newReadSnapshots(LoadReadSnapshotConfig()),
+        plan:      LoadAnalyticsPlanConfig(),
     }
 }
 
 // Helper to scan Generator with joined fields
 func scanGenerator(row pgx.Row, g *models.Generator) error {
-    return row.Scan(
+    if err := row.Scan(
         &g.ID,
         &g.TypeID,
         &g.TypeName,
         &g.TypeDesc,
         &g.IsRenewable,
         &g.Capacity,
+        &g.OperatorID,
+        &g.OperatorName,
+        &g.PlantID,
+        &g.PlantName,
+        &g.RegionID,
+        &g.RegionName,
+        &g.Latitude,
+        &g.Longitude,
+        &g.CommissionedOn,
+        &g.CustomAttributes,
         &g.CreatedAt,
         &g.UpdatedAt,
-    )
+        &g.Version,
+    ); err != nil {
+        return err
+    }
+    g.Capacity = numeric.RoundDecimal(g.Capacity)
+    return nil
 }
 
 // Helper to scan Production with joined fields
 func scanProduction(row pgx.Row, p *models.Production) error {
-    return row.Scan(
+    if err := row.Scan(
         &p.ID,
         &p.GeneratorID,
         &p.GeneratorCapacity,
@@ -75,29 +356,51 @@ func scanProduction(row pgx.Row, p *models.Production) error {
         &p.IsRenewable,
         &p.Date,
         &p.ProductionMW,
+        &p.Source,
+        &p.SourceRef,
+        &p.Signature,
+        &p.CustomAttributes,
         &p.CreatedAt,
         &p.UpdatedAt,
-    )
+        &p.Version,
+    ); err != nil {
+        return err
+    }
+    // Verify against the stored (unrounded) value before applying the rounding policy
+    p.SignatureValid = provenance.Verify(provenance.Record{
+        ID:           p.ID,
+        GeneratorID:  p.GeneratorID,
+        Date:         p.Date,
+        ProductionMW: p.ProductionMW,
+        Source:       p.Source,
+        SourceRef:    p.SourceRef,
+    }, p.Signature)
+    p.GeneratorCapacity = numeric.RoundDecimal(p.GeneratorCapacity)
+    p.ProductionMW = numeric.RoundDecimal(p.ProductionMW)
+    return nil
 }
 
 // CreateType creates a new energy generator type
 func (r *postgresRepository) CreateType(ctx context.Context, req *models.CreateTypeRequest) (*models.Type, error) {
 	query := `
-		INSERT INTO types (id, name, description, isrenuevable, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, name, description, isrenuevable, created_at, updated_at`
+		INSERT INTO types (id, name, description, isrenuevable, technology_code, custom_attributes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), jsonb_strip_nulls(COALESCE($8::jsonb, '{}')), $6, $7)
+		RETURNING id, name, description, isrenuevable, COALESCE(technology_code, ''), custom_attributes, created_at, updated_at, version`
 
 	id := uuid.New()
 	now := time.Now()
 
 	var typeRecord models.Type
-	err := r.db.QueryRow(ctx, query, id, req.Name, req.Description, req.IsRenewable, now, now).Scan(
+	err := r.db.QueryRow(ctx, query, id, req.Name, req.Description, req.IsRenewable, req.TechnologyCode, now, now, attributesParam(req.CustomAttributes)).Scan(
 		&typeRecord.ID,
 		&typeRecord.Name,
 		&typeRecord.Description,
 		&typeRecord.IsRenewable,
+		&typeRecord.TechnologyCode,
+		&typeRecord.CustomAttributes,
 		&typeRecord.CreatedAt,
 		&typeRecord.UpdatedAt,
+		&typeRecord.Version,
 	)
 
 	if err != nil {
@@ -110,9 +413,9 @@ func (r *postgresRepository) CreateType(ctx context.Context, req *models.CreateT
 // GetTypeByID retrieves a type by its ID
 func (r *postgresRepository) GetTypeByID(ctx context.Context, id uuid.UUID) (*models.Type, error) {
 	query := `
-		SELECT id, name, description, isrenuevable, created_at, updated_at
+		SELECT id, name, description, isrenuevable, COALESCE(technology_code, ''), custom_attributes, created_at, updated_at, version
 		FROM types
-		WHERE id = $1`
+		WHERE id = $1 AND deleted_at IS NULL`
 
 	var typeRecord models.Type
 	err := r.db.QueryRow(ctx, query, id).Scan(
@@ -120,8 +423,11 @@ func (r *postgresRepository) GetTypeByID(ctx context.Context, id uuid.UUID) (*mo
 		&typeRecord.Name,
 		&typeRecord.Description,
 		&typeRecord.IsRenewable,
+		&typeRecord.TechnologyCode,
+		&typeRecord.CustomAttributes,
 		&typeRecord.CreatedAt,
 		&typeRecord.UpdatedAt,
+		&typeRecord.Version,
 	)
 
 	if err != nil {
@@ -141,15 +447,16 @@ func (r *postgresRepository) GetAllTypes(ctx context.Context, isRenewable *bool)
 
 	if isRenewable != nil {
 		query = `
-			SELECT id, name, description, isrenuevable, created_at, updated_at
+			SELECT id, name, description, isrenuevable, COALESCE(technology_code, ''), custom_attributes, created_at, updated_at, version
 			FROM types
-			WHERE isrenuevable = $1
+			WHERE isrenuevable = $1 AND deleted_at IS NULL
 			ORDER BY name`
 		args = append(args, *isRenewable)
 	} else {
 		query = `
-			SELECT id, name, description, isrenuevable, created_at, updated_at
+			SELECT id, name, description, isrenuevable, COALESCE(technology_code, ''), custom_attributes, created_at, updated_at, version
 			FROM types
+			WHERE deleted_at IS NULL
 			ORDER BY name`
 	}
 
@@ -167,8 +474,12 @@ func (r *postgresRepository) GetAllTypes(ctx context.Context, isRenewable *bool)
 			&typeRecord.Name,
 			&typeRecord.Description,
 			&typeRecord.IsRenewable,
+			&typeRecord.TechnologyCode,
+			&typeRecord.CustomAttributes,
+		&typeRecord.CustomAttributes,
 			&typeRecord.CreatedAt,
 			&typeRecord.UpdatedAt,
+			&typeRecord.Version,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan type: %w", err)
@@ -185,26 +496,37 @@ func (r *postgresRepository) GetAllTypes(ctx context.Context, isRenewable *bool)
 
 // UpdateType updates an existing type
 func (r *postgresRepository) UpdateType(ctx context.Context, id uuid.UUID, req *models.UpdateTypeRequest) (*models.Type, error) {
+	if req.Fields.Partial() {
+		return r.patchType(ctx, id, req)
+	}
+	now := time.Now()
+	version, args := versionCondition(req.IfMatch, []any{id, req.Name, req.Description, req.IsRenewable, req.TechnologyCode, now, attributesParam(req.CustomAttributes)})
 	query := `
 		UPDATE types
-		SET name = $2, description = $3, isrenuevable = $4, updated_at = $5
-		WHERE id = $1
-		RETURNING id, name, description, isrenuevable, created_at, updated_at`
-
-	now := time.Now()
+		SET name = $2, description = $3, isrenuevable = $4,
+			technology_code = COALESCE(NULLIF($5, ''), technology_code),
+			custom_attributes = jsonb_strip_nulls(custom_attributes || COALESCE($7::jsonb, '{}')), updated_at = $6
+		WHERE id = $1 AND deleted_at IS NULL` + version + `
+		RETURNING id, name, description, isrenuevable, COALESCE(technology_code, ''), custom_attributes, created_at, updated_at, version`
 
 	var typeRecord models.Type
-	err := r.db.QueryRow(ctx, query, id, req.Name, req.Description, req.IsRenewable, now).Scan(
+	err := r.db.QueryRow(ctx, query, args...).Scan(
 		&typeRecord.ID,
 		&typeRecord.Name,
 		&typeRecord.Description,
 		&typeRecord.IsRenewable,
+		&typeRecord.TechnologyCode,
+		&typeRecord.CustomAttributes,
 		&typeRecord.CreatedAt,
 		&typeRecord.UpdatedAt,
+		&typeRecord.Version,
 	)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
+			if req.IfMatch != nil {
+				return nil, versionError(ctx, r.db, "types", id)
+			}
 			return nil, sql.ErrNoRows
 		}
 		return nil, fmt.Errorf("failed to update type: %w", err)
@@ -213,37 +535,21 @@ func (r *postgresRepository) UpdateType(ctx context.Context, id uuid.UUID, req *
 	return &typeRecord, nil
 }
 
-// DeleteType deletes a type by its ID
-func (r *postgresRepository) DeleteType(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM types WHERE id = $1`
-
-	result, err := r.db.Exec(ctx, query, id)
-	if err != nil {
-		return fmt.Errorf("failed to delete type: %w", err)
-	}
-
-	if result.RowsAffected() == 0 {
-		return sql.ErrNoRows
-	}
-
-	return nil
-}
-
-// GetUserByID is a placeholder implementation
-func (r *postgresRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
-    // TODO: Implement user operations when User model is ready
-    return nil, fmt.Errorf("user operations not implemented yet")
+// DeleteType moves a type to the trash; with cascade its generators and
+// their productions go with it, otherwise they make it fail with ErrStillReferenced
+func (r *postgresRepository) DeleteType(ctx context.Context, id uuid.UUID, cascade bool) error {
+	return r.moveToTrash(ctx, models.TrashType, id, cascade)
 }
 
 // ===================== Generators =====================
 func (r *postgresRepository) CreateGenerator(ctx context.Context, req *models.CreateGeneratorRequest) (*models.Generator, error) {
     query := `
-        INSERT INTO generators (id, type, capacity, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5)
+        INSERT INTO generators (id, type, capacity, operator_id, latitude, longitude, custom_attributes, created_at, updated_at, plant_id, region_id, commissioned_on)
+        VALUES ($1, $2, $3, $4, $5, $6, jsonb_strip_nulls(COALESCE($9::jsonb, '{}')), $7, $8, $10, $11, $12::date)
         RETURNING id`
     id := uuid.New()
     now := time.Now()
-    if _, err := r.db.Exec(ctx, query, id, req.TypeID, req.Capacity, now, now); err != nil {
+    if _, err := r.db.Exec(ctx, query, id, req.TypeID, req.Capacity, req.OperatorID, req.Latitude, req.Longitude, now, now, attributesParam(req.CustomAttributes), req.PlantID, req.RegionID, req.CommissionedOn); err != nil {
         return nil, fmt.Errorf("failed to create generator: %w", err)
     }
     return r.GetGeneratorByID(ctx, id)
@@ -251,9 +557,12 @@ func (r *postgresRepository) CreateGenerator(ctx context.Context, req *models.Cr
 
 func (r *postgresRepository) GetGeneratorByID(ctx context.Context, id uuid.UUID) (*models.Generator, error) {
     query := `
-        SELECT g.id, g.type, t.name, t.description, t.isrenuevable, g.capacity, g.created_at, g.updated_at
+        SELECT g.id, g.type, t.name, t.description, t.isrenuevable, g.capacity, g.operator_id, COALESCE(o.name, ''), g.plant_id, COALESCE(pl.name, ''), g.region_id, COALESCE(rg.name, ''), g.latitude, g.longitude, g.commissioned_on::text, g.custom_attributes, g.created_at, g.updated_at, g.version
         FROM generators g
         JOIN types t ON g.type = t.id
+        LEFT JOIN operators o ON g.operator_id = o.id
+        LEFT JOIN plants pl ON g.plant_id = pl.id
+        LEFT JOIN regions rg ON g.region_id = rg.id
         WHERE g.id = $1`
     var gen models.Generator
     err := scanGenerator(r.db.QueryRow(ctx, query, id), &gen)
@@ -266,26 +575,53 @@ func (r *postgresRepository) GetGeneratorByID(ctx context.Context, id uuid.UUID)
     return &gen, nil
 }
 
-func (r *postgresRepository) GetAllGenerators(ctx context.Context, typeID *uuid.UUID) ([]*models.Generator, error) {
+func (r *postgresRepository) GetAllGenerators(ctx context.Context, filter models.GeneratorFilter) ([]*models.Generator, error) {
     var (
-        query string
+        conds []string
         args []any
     )
-    if typeID != nil {
-        query = `
-            SELECT g.id, g.type, t.name, t.description, t.isrenuevable, g.capacity, g.created_at, g.updated_at
-            FROM generators g
-            JOIN types t ON g.type = t.id
-            WHERE g.type = $1
-            ORDER BY t.name, g.capacity DESC`
-        args = append(args, *typeID)
-    } else {
-        query = `
-            SELECT g.id, g.type, t.name, t.description, t.isrenuevable, g.capacity, g.created_at, g.updated_at
-            FROM generators g
-            JOIN types t ON g.type = t.id
-            ORDER BY t.name, g.capacity DESC`
+    if filter.TypeID != nil {
+        args = append(args, *filter.TypeID)
+        conds = append(conds, fmt.Sprintf("g.type = $%d", len(args)))
+    }
+    if filter.OperatorID != nil {
+        args = append(args, *filter.OperatorID)
+        conds = append(conds, fmt.Sprintf("g.operator_id = $%d", len(args)))
+    }
+    if filter.PlantID != nil {
+        args = append(args, *filter.PlantID)
+        conds = append(conds, fmt.Sprintf("g.plant_id = $%d", len(args)))
+    }
+    if filter.RegionID != nil {
+        args = append(args, *filter.RegionID)
+        conds = append(conds, fmt.Sprintf("g.region_id = $%d", len(args)))
+    }
+    if b := filter.Within; b != nil {
+        args = append(args, b.MinLat, b.MaxLat, b.MinLng, b.MaxLng)
+        n := len(args)
+        conds = append(conds, fmt.Sprintf("g.latitude BETWEEN $%d AND $%d AND g.longitude BETWEEN $%d AND $%d", n-3, n-2, n-1, n))
+    }
+    if len(filter.Attributes) > 0 {
+        args = append(args, filter.Attributes)
+        conds = append(conds, fmt.Sprintf("g.custom_attributes @> $%d", len(args)))
     }
+    if p := filter.Near; p != nil {
+        // Haversine distance; no PostGIS needed at fleet scale
+        args = append(args, p.Lat, p.Lng, filter.RadiusKm)
+        n := len(args)
+        conds = append(conds, fmt.Sprintf(`%f * 2 * asin(least(1, sqrt(
+            power(sin(radians(g.latitude - $%[2]d) / 2), 2) +
+            cos(radians($%[2]d)) * cos(radians(g.latitude)) * power(sin(radians(g.longitude - $%[3]d) / 2), 2)))) <= $%[4]d`,
+            geo.EarthRadiusKm, n-2, n-1, n))
+    }
+    query := `
+        SELECT g.id, g.type, t.name, t.description, t.isrenuevable, g.capacity, g.operator_id, COALESCE(o.name, ''), g.plant_id, COALESCE(pl.name, ''), g.region_id, COALESCE(rg.name, ''), g.latitude, g.longitude, g.commissioned_on::text, g.custom_attributes, g.created_at, g.updated_at, g.version
+        FROM generators g
+        JOIN types t ON g.type = t.id
+        LEFT JOIN operators o ON g.operator_id = o.id
+        LEFT JOIN plants pl ON g.plant_id = pl.id
+        LEFT JOIN regions rg ON g.region_id = rg.id` + whereClause(conds) + `
+        ORDER BY t.name, g.capacity DESC`
     rows, err := r.db.Query(ctx, query, args...)
     if err != nil {
         return nil, fmt.Errorf("failed to query generators: %w", err)
@@ -302,56 +638,132 @@ func (r *postgresRepository) GetAllGenerators(ctx context.Context, typeID *uuid.
     if err := rows.Err(); err != nil {
         return nil, fmt.Errorf("row iteration error: %w", err)
     }
+    if filter.Near != nil {
+        sortByDistance(list, *filter.Near)
+    }
     return list, nil
 }
 
 func (r *postgresRepository) UpdateGenerator(ctx context.Context, id uuid.UUID, req *models.UpdateGeneratorRequest) (*models.Generator, error) {
+    if req.Fields.Partial() {
+        return r.patchGenerator(ctx, id, req)
+    }
     // Build dynamic update
     // For simplicity, set all fields using COALESCE on provided values
     query := `
         UPDATE generators
         SET type = COALESCE($2, type),
             capacity = COALESCE($3, capacity),
-            updated_at = $4
+            operator_id = COALESCE($4, operator_id),
+            latitude = COALESCE($5, latitude),
+            longitude = COALESCE($6, longitude),
+            plant_id = COALESCE($9, plant_id),
+            region_id = COALESCE($10, region_id),
+            commissioned_on = COALESCE($11::date, commissioned_on),
+            custom_attributes = jsonb_strip_nulls(custom_attributes || COALESCE($8::jsonb, '{}')),
+            updated_at = $7
         WHERE id = $1`
     now := time.Now()
-    if _, err := r.db.Exec(ctx, query, id, req.TypeID, req.Capacity, now); err != nil {
+    version, args := versionCondition(req.IfMatch, []any{id, req.TypeID, req.Capacity, req.OperatorID, req.Latitude, req.Longitude, now, attributesParam(req.CustomAttributes), req.PlantID, req.RegionID, req.CommissionedOn})
+    tag, err := r.db.Exec(ctx, query+version, args...)
+    if err != nil {
         if err == pgx.ErrNoRows {
             return nil, sql.ErrNoRows
         }
-        return nil, fmt.Errorf("failed to update generator: %w", err)
+        return nil, fmt.Errorf("failed to update generator: %w", publishedError(err))
+    }
+    if tag.RowsAffected() == 0 && req.IfMatch != nil {
+        return nil, versionError(ctx, r.db, "generators", id)
     }
     return r.GetGeneratorByID(ctx, id)
 }
 
-func (r *postgresRepository) DeleteGenerator(ctx context.Context, id uuid.UUID) error {
-    res, err := r.db.Exec(ctx, `DELETE FROM generators WHERE id = $1`, id)
-    if err != nil {
-        return fmt.Errorf("failed to delete generator: %w", err)
-    }
-    if res.RowsAffected() == 0 {
-        return sql.ErrNoRows
-    }
-    return nil
+func (r *postgresRepository) DeleteGenerator(ctx context.Context, id uuid.UUID, cascade bool) error {
+    return r.moveToTrash(ctx, models.TrashGenerator, id, cascade)
 }
 
 // ===================== Productions =====================
+
+// updateProductionQuery sets the provided fields of a production; its
+// arguments are the ID, generator, date, value, source, source reference,
+// update time and custom attributes
+const updateProductionQuery = `
+        UPDATE productions
+        SET generator_id = COALESCE($2, generator_id),
+            date = COALESCE($3, date),
+            production_mw = COALESCE($4, production_mw),
+            source = COALESCE(NULLIF($5, ''), source),
+            source_ref = CASE WHEN $5 <> '' THEN NULLIF($6, '') ELSE source_ref END,
+            custom_attributes = jsonb_strip_nulls(custom_attributes || COALESCE($8::jsonb, '{}')),
+            updated_at = $7
+        WHERE id = $1`
+
 func (r *postgresRepository) CreateProduction(ctx context.Context, req *models.CreateProductionRequest) (*models.Production, error) {
     query := `
-        INSERT INTO productions (id, generator_id, date, production_mw, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6)
-        RETURNING id`
+        INSERT INTO productions (id, generator_id, date, production_mw, source, source_ref, custom_attributes, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5, ''), 'api'), NULLIF($6, ''), jsonb_strip_nulls(COALESCE($9::jsonb, '{}')), $7, $8)`
+    date, err := productionDate(req.Date)
+    if err != nil {
+        return nil, fmt.Errorf("failed to create production: %w", err)
+    }
     id := uuid.New()
     now := time.Now()
-    if _, err := r.db.Exec(ctx, query, id, req.GeneratorID, req.Date, req.ProductionMW, now, now); err != nil {
-        return nil, fmt.Errorf("failed to create production: %w", err)
+    err = r.writeSigned(ctx, id, func(q execQuerier) error {
+        _, err := q.Exec(ctx, query, id, req.GeneratorID, date, req.ProductionMW, req.Source, req.SourceRef, now, now, attributesParam(req.CustomAttributes))
+        return err
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to create production: %w", duplicateError(err))
     }
     return r.GetProductionByID(ctx, id)
 }
 
+// UpsertProduction creates the production, or replaces the value and source of
+// the one the generator already has on the date, merging the custom attributes
+func (r *postgresRepository) UpsertProduction(ctx context.Context, req *models.CreateProductionRequest) (*models.Production, bool, error) {
+    query := `
+        INSERT INTO productions (id, generator_id, date, production_mw, source, source_ref, custom_attributes, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5, ''), 'api'), NULLIF($6, ''), jsonb_strip_nulls(COALESCE($8::jsonb, '{}')), $7, $7)
+        ON CONFLICT (generator_id, date) DO UPDATE
+        SET production_mw = EXCLUDED.production_mw,
+            source = EXCLUDED.source,
+            source_ref = EXCLUDED.source_ref,
+            custom_attributes = jsonb_strip_nulls(productions.custom_attributes || COALESCE($8::jsonb, '{}')),
+            updated_at = EXCLUDED.updated_at
+        RETURNING id, xmax = 0`
+    date, err := productionDate(req.Date)
+    if err != nil {
+        return nil, false, fmt.Errorf("failed to upsert production: %w", err)
+    }
+    tx, err := r.db.Begin(ctx)
+    if err != nil {
+        return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+    }
+    defer tx.Rollback(ctx)
+
+    var id uuid.UUID
+    var created bool
+    err = tx.QueryRow(ctx, query, uuid.New(), req.GeneratorID, date, req.ProductionMW, req.Source, req.SourceRef, time.Now(),
+        attributesParam(req.CustomAttributes)).Scan(&id, &created)
+    if err != nil {
+        return nil, false, fmt.Errorf("failed to upsert production: %w", publishedError(err))
+    }
+    if provenance.Enabled() {
+        if err := signProduction(ctx, tx, id); err != nil {
+            return nil, false, err
+        }
+    }
+    if err := tx.Commit(ctx); err != nil {
+        return nil, false, fmt.Errorf("failed to commit production: %w", publishedError(err))
+    }
+    p, err := r.GetProductionByID(ctx, id)
+    return p, created, err
+}
+
 func (r *postgresRepository) GetProductionByID(ctx context.Context, id uuid.UUID) (*models.Production, error) {
     query := `
-        SELECT p.id, p.generator_id, g.capacity, t.name, t.isrenuevable, p.date, p.production_mw, p.created_at, p.updated_at
+        SELECT p.id, p.generator_id, g.capacity, t.name, t.isrenuevable, p.date, p.production_mw,
+               p.source, COALESCE(p.source_ref, ''), COALESCE(p.signature, ''), p.custom_attributes, p.created_at, p.updated_at, p.version
         FROM productions p
         JOIN generators g ON p.generator_id = g.id
         JOIN types t ON g.type = t.id
@@ -367,37 +779,23 @@ func (r *postgresRepository) GetProductionByID(ctx context.Context, id uuid.UUID
     return &pr, nil
 }
 
-func (r *postgresRepository) GetAllProductions(ctx context.Context, generatorID *uuid.UUID, startDate, endDate *string) ([]*models.Production, error) {
-    var (
-        query string
-        args []any
-    )
-    base := `
-        SELECT p.id, p.generator_id, g.capacity, t.name, t.isrenuevable, p.date, p.production_mw, p.created_at, p.updated_at
+func (r *postgresRepository) GetAllProductions(ctx context.Context, filter *models.ProductionFilter) ([]*models.Production, error) {
+    conds, args := productionConditions(filter)
+    query := `
+        SELECT p.id, p.generator_id, g.capacity, t.name, t.isrenuevable, p.date, p.production_mw,
+               p.source, COALESCE(p.source_ref, ''), COALESCE(p.signature, ''), p.custom_attributes, p.created_at, p.updated_at, p.version
         FROM productions p
         JOIN generators g ON p.generator_id = g.id
-        JOIN types t ON g.type = t.id`
-    where := ""
-    idx := 1
-    if generatorID != nil {
-        where += fmt.Sprintf(" WHERE p.generator_id = $%d", idx)
-        args = append(args, *generatorID)
-        idx++
-    }
-    if startDate != nil && *startDate != "" {
-        if where == "" { where = " WHERE" } else { where += " AND" }
-        where += fmt.Sprintf(" p.date >= $%d", idx)
-        args = append(args, *startDate)
-        idx++
-    }
-    if endDate != nil && *endDate != "" {
-        if where == "" { where = " WHERE" } else { where += " AND" }
-        where += fmt.Sprintf(" p.date <= $%d", idx)
-        args = append(args, *endDate)
-        idx++
-    }
-    order := " ORDER BY p.date DESC, t.name"
-    query = base + where + order
+        JOIN types t ON g.type = t.id` + whereClause(conds) + `
+        ORDER BY p.date DESC, t.name, p.id`
+    if filter.Limit > 0 {
+        args = append(args, filter.Limit)
+        query += fmt.Sprintf(" LIMIT $%d", len(args))
+    }
+    if filter.Offset > 0 {
+        args = append(args, filter.Offset)
+        query += fmt.Sprintf(" OFFSET $%d", len(args))
+    }
 
     rows, err := r.db.Query(ctx, query, args...)
     if err != nil {
@@ -419,30 +817,65 @@ func (r *postgresRepository) GetAllProductions(ctx context.Context, generatorID
 }
 
 func (r *postgresRepository) UpdateProduction(ctx context.Context, id uuid.UUID, req *models.UpdateProductionRequest) (*models.Production, error) {
-    query := `
-        UPDATE productions
-        SET generator_id = COALESCE($2, generator_id),
-            date = COALESCE($3, date),
-            production_mw = COALESCE($4, production_mw),
-            updated_at = $5
-        WHERE id = $1`
+    if req.Fields.Partial() {
+        return r.patchProduction(ctx, id, req)
+    }
+    date, err := optionalProductionDate(req.Date)
+    if err != nil {
+        return nil, fmt.Errorf("failed to update production: %w", err)
+    }
     now := time.Now()
-    if _, err := r.db.Exec(ctx, query, id, req.GeneratorID, req.Date, req.ProductionMW, now); err != nil {
-        if err == pgx.ErrNoRows {
+    version, args := versionCondition(req.IfMatch, []any{id, req.GeneratorID, date, req.ProductionMW, req.Source, req.SourceRef, now, attributesParam(req.CustomAttributes)})
+    err = r.writeSigned(ctx, id, func(q execQuerier) error {
+        tag, err := q.Exec(ctx, updateProductionQuery+version, args...)
+        if err == nil && tag.RowsAffected() == 0 && req.IfMatch != nil {
+            return versionError(ctx, q, "productions", id)
+        }
+        return err
+    })
+    if err != nil {
+        if err == sql.ErrNoRows {
             return nil, sql.ErrNoRows
         }
-        return nil, fmt.Errorf("failed to update production: %w", err)
+        return nil, fmt.Errorf("failed to update production: %w", duplicateError(err))
     }
     return r.GetProductionByID(ctx, id)
 }
 
 func (r *postgresRepository) DeleteProduction(ctx context.Context, id uuid.UUID) error {
-    res, err := r.db.Exec(ctx, `DELETE FROM productions WHERE id = $1`, id)
+    return r.moveToTrash(ctx, models.TrashProduction, id, false)
+}
+
+func (r *postgresRepository) RecordTelemetry(ctx context.Context, reading *models.TelemetryReading) (*models.Production, error) {
+    query := `
+        INSERT INTO productions (id, generator_id, date, production_mw, source, source_ref, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, 'telemetry', NULLIF($5, ''), $6, $6)
+        ON CONFLICT (generator_id, date) DO UPDATE
+        SET production_mw = CASE WHEN $7 THEN productions.production_mw + EXCLUDED.production_mw
+                                 ELSE EXCLUDED.production_mw END,
+            source = EXCLUDED.source,
+            source_ref = EXCLUDED.source_ref,
+            updated_at = EXCLUDED.updated_at
+        RETURNING id`
+    tx, err := r.db.Begin(ctx)
     if err != nil {
-        return fmt.Errorf("failed to delete production: %w", err)
+        return nil, fmt.Errorf("failed to begin transaction: %w", err)
     }
-    if res.RowsAffected() == 0 {
-        return sql.ErrNoRows
+    defer tx.Rollback(ctx)
+
+    var id uuid.UUID
+    add := reading.Mode != models.TelemetryModeSet
+    err = tx.QueryRow(ctx, query, uuid.New(), reading.GeneratorID, reading.Date, reading.Value, reading.Topic, time.Now(), add).Scan(&id)
+    if err != nil {
+        return nil, fmt.Errorf("failed to record telemetry: %w", publishedError(err))
     }
-    return nil
+    if provenance.Enabled() {
+        if err := signProduction(ctx, tx, id); err != nil {
+            return nil, err
+        }
+    }
+    if err := tx.Commit(ctx); err != nil {
+        return nil, fmt.Errorf("failed to commit telemetry: %w", publishedError(err))
+    }
+    return r.GetProductionByID(ctx, id)
 }
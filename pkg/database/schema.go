@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+)
+
+// GetSchema describes the tables of the current schema (the first one of the
+// search path), their columns, keys and foreign keys, from information_schema
+func (r *postgresRepository) GetSchema(ctx context.Context) (*models.DatabaseSchema, error) {
+	s := &models.DatabaseSchema{
+		Tables:        []*models.SchemaTable{},
+		Relationships: []*models.SchemaRelationship{},
+	}
+	if err := r.db.QueryRow(ctx, `SELECT current_schema()`).Scan(&s.Schema); err != nil {
+		return nil, fmt.Errorf("failed to get current schema: %w", err)
+	}
+
+	tables := map[string]*models.SchemaTable{}
+	rows, err := r.db.Query(ctx, `
+		SELECT t.table_name,
+		       COALESCE(obj_description(format('%I.%I', t.table_schema, t.table_name)::regclass, 'pg_class'), '')
+		FROM information_schema.tables t
+		WHERE t.table_schema = $1 AND t.table_type = 'BASE TABLE'
+		ORDER BY t.table_name`, s.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		t := &models.SchemaTable{PrimaryKey: []string{}, Columns: []*models.SchemaColumn{}}
+		if err := rows.Scan(&t.Name, &t.Comment); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		tables[t.Name] = t
+		s.Tables = append(s.Tables, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	// format_type gives the declared type (numeric(14,4), varchar(80)) that
+	// information_schema spreads over several columns
+	rows, err = r.db.Query(ctx, `
+		SELECT c.table_name, c.column_name,
+		       format_type(a.atttypid, a.atttypmod),
+		       c.is_nullable = 'YES', c.column_default,
+		       COALESCE(col_description(a.attrelid, a.attnum), '')
+		FROM information_schema.columns c
+		JOIN pg_attribute a ON a.attrelid = format('%I.%I', c.table_schema, c.table_name)::regclass
+		                   AND a.attname = c.column_name
+		WHERE c.table_schema = $1
+		ORDER BY c.table_name, c.ordinal_position`, s.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query columns: %w", err)
+	}
+	defer rows.Close()
+	columns := map[[2]string]*models.SchemaColumn{}
+	for rows.Next() {
+		var (
+			table string
+			col   models.SchemaColumn
+		)
+		if err := rows.Scan(&table, &col.Name, &col.Type, &col.Nullable, &col.Default, &col.Comment); err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		// Views have columns too but are not listed
+		if t, ok := tables[table]; ok {
+			t.Columns = append(t.Columns, &col)
+			columns[[2]string{table, col.Name}] = &col
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	rows, err = r.db.Query(ctx, `
+		SELECT tc.table_name, tc.constraint_type, kcu.column_name,
+		       COUNT(*) OVER (PARTITION BY tc.table_name, tc.constraint_name)
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+		  ON kcu.constraint_schema = tc.constraint_schema
+		 AND kcu.constraint_name = tc.constraint_name
+		 AND kcu.table_name = tc.table_name
+		WHERE tc.table_schema = $1 AND tc.constraint_type IN ('PRIMARY KEY', 'UNIQUE')
+		ORDER BY tc.table_name, tc.constraint_name, kcu.ordinal_position`, s.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query keys: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			table, kind, column string
+			width               int
+		)
+		if err := rows.Scan(&table, &kind, &column, &width); err != nil {
+			return nil, fmt.Errorf("failed to scan key: %w", err)
+		}
+		t, ok := tables[table]
+		if !ok {
+			continue
+		}
+		switch {
+		case kind == "PRIMARY KEY":
+			t.PrimaryKey = append(t.PrimaryKey, column)
+		case width == 1:
+			if col, ok := columns[[2]string{table, column}]; ok {
+				col.Unique = true
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	// Columns of a foreign key pair up with the referenced key through
+	// position_in_unique_constraint
+	rows, err = r.db.Query(ctx, `
+		SELECT kcu.table_name, rc.constraint_name, kcu.column_name, ref.table_name, ref.column_name, rc.delete_rule
+		FROM information_schema.referential_constraints rc
+		JOIN information_schema.key_column_usage kcu
+		  ON kcu.constraint_schema = rc.constraint_schema
+		 AND kcu.constraint_name = rc.constraint_name
+		JOIN information_schema.key_column_usage ref
+		  ON ref.constraint_schema = rc.unique_constraint_schema
+		 AND ref.constraint_name = rc.unique_constraint_name
+		 AND ref.ordinal_position = kcu.position_in_unique_constraint
+		WHERE rc.constraint_schema = $1
+		ORDER BY kcu.table_name, rc.constraint_name, kcu.ordinal_position`, s.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query relationships: %w", err)
+	}
+	defer rows.Close()
+	var last *models.SchemaRelationship
+	for rows.Next() {
+		var (
+			fk                       models.SchemaRelationship
+			column, referencedColumn string
+		)
+		if err := rows.Scan(&fk.Table, &fk.Name, &column, &fk.ReferencedTable, &referencedColumn, &fk.OnDelete); err != nil {
+			return nil, fmt.Errorf("failed to scan relationship: %w", err)
+		}
+		if last == nil || last.Table != fk.Table || last.Name != fk.Name {
+			last = &fk
+			s.Relationships = append(s.Relationships, last)
+		}
+		last.Columns = append(last.Columns, column)
+		last.ReferencedColumns = append(last.ReferencedColumns, referencedColumn)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return s, nil
+}
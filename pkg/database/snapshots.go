@@ -0,0 +1,324 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/provenance"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shopspring/decimal"
+)
+
+// ErrPeriodPublished is returned when a write touches a month that has been
+// published; such records can only be changed through a correction
+var ErrPeriodPublished = errors.New("production month is published and can only be changed through a correction")
+
+// ErrAlreadyPublished is returned when publishing a month twice
+var ErrAlreadyPublished = errors.New("month is already published")
+
+// publishedPeriodCode is the SQLSTATE raised by the core.protect_published_productions trigger
+const publishedPeriodCode = "TP001"
+
+// publishedError maps the trigger error raised for published months to ErrPeriodPublished
+func publishedError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == publishedPeriodCode {
+		return ErrPeriodPublished
+	}
+	return err
+}
+
+// PublishSnapshot freezes the productions of the month starting at month into
+// an immutable snapshot and records its hash. Afterwards the database rejects
+// writes to that month except through ApplyCorrection.
+func (r *postgresRepository) PublishSnapshot(ctx context.Context, month time.Time) (*models.Snapshot, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Block concurrent writes so the frozen copy matches what gets protected
+	if _, err := tx.Exec(ctx, `LOCK TABLE productions IN SHARE MODE`); err != nil {
+		return nil, fmt.Errorf("failed to lock productions: %w", err)
+	}
+
+	id := uuid.New()
+	now := time.Now()
+	res, err := tx.Exec(ctx, `
+		INSERT INTO published_snapshots (id, month, published_at, record_count, total_production, hash)
+		VALUES ($1, $2, $3, 0, 0, '')
+		ON CONFLICT (month) DO NOTHING`, id, month, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return nil, ErrAlreadyPublished
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO snapshot_productions (snapshot_id, production_id, generator_id, date, production_mw, source, source_ref)
+		SELECT $1, id, generator_id, date, production_mw, source, source_ref
+		FROM productions
+		WHERE date >= $2 AND date < ($2::date + INTERVAL '1 month')`, id, month); err != nil {
+		return nil, fmt.Errorf("failed to freeze productions: %w", err)
+	}
+
+	rows, err := snapshotRows(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	snap := &models.Snapshot{
+		ID:              id,
+		Month:           month.Format("2006-01"),
+		PublishedAt:     now,
+		RecordCount:     int64(len(rows)),
+		TotalProduction: sumSnapshot(rows),
+		Hash:            hashSnapshot(rows),
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE published_snapshots
+		SET record_count = $2, total_production = $3, hash = $4
+		WHERE id = $1`, id, snap.RecordCount, snap.TotalProduction, snap.Hash); err != nil {
+		return nil, fmt.Errorf("failed to record snapshot hash: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit snapshot: %w", err)
+	}
+
+	snap.TotalProduction = numeric.RoundDecimal(snap.TotalProduction)
+	return snap, nil
+}
+
+const snapshotColumns = `id, to_char(month, 'YYYY-MM'), published_at, record_count, total_production, hash`
+
+func scanSnapshot(row pgx.Row, s *models.Snapshot) error {
+	if err := row.Scan(&s.ID, &s.Month, &s.PublishedAt, &s.RecordCount, &s.TotalProduction, &s.Hash); err != nil {
+		return err
+	}
+	s.TotalProduction = numeric.RoundDecimal(s.TotalProduction)
+	return nil
+}
+
+// GetSnapshots lists published snapshots, newest month first
+func (r *postgresRepository) GetSnapshots(ctx context.Context) ([]*models.Snapshot, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+snapshotColumns+` FROM published_snapshots ORDER BY month DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.Snapshot
+	for rows.Next() {
+		var s models.Snapshot
+		if err := scanSnapshot(rows, &s); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+		list = append(list, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// GetSnapshot returns the snapshot of a month with its frozen records
+func (r *postgresRepository) GetSnapshot(ctx context.Context, month time.Time) (*models.SnapshotDetail, error) {
+	var detail models.SnapshotDetail
+	err := scanSnapshot(r.db.QueryRow(ctx, `SELECT `+snapshotColumns+` FROM published_snapshots WHERE month = $1`, month), &detail.Snapshot)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+
+	rows, err := snapshotRows(ctx, r.db, detail.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range rows {
+		p.ProductionMW = numeric.RoundDecimal(p.ProductionMW)
+	}
+	detail.Productions = rows
+	return &detail, nil
+}
+
+// VerifySnapshot recomputes the hash of a snapshot from its frozen records
+func (r *postgresRepository) VerifySnapshot(ctx context.Context, month time.Time) (*models.SnapshotVerification, error) {
+	var id uuid.UUID
+	v := models.SnapshotVerification{Month: month.Format("2006-01")}
+	err := r.db.QueryRow(ctx, `SELECT id, hash FROM published_snapshots WHERE month = $1`, month).Scan(&id, &v.Hash)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+
+	rows, err := snapshotRows(ctx, r.db, id)
+	if err != nil {
+		return nil, err
+	}
+	v.ComputedHash = hashSnapshot(rows)
+	v.Valid = v.ComputedHash == v.Hash
+	return &v, nil
+}
+
+// ApplyCorrection changes the value of a production record, including records
+// of published months, and keeps a correction entry with the previous value
+// and the reason. Published snapshots are not modified.
+func (r *postgresRepository) ApplyCorrection(ctx context.Context, productionID uuid.UUID, req *models.CreateCorrectionRequest) (*models.ProductionCorrection, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lets the published-month trigger accept this transaction's writes
+	if _, err := tx.Exec(ctx, `SELECT set_config('tadb.allow_published_edit', 'on', true)`); err != nil {
+		return nil, fmt.Errorf("failed to enable correction mode: %w", err)
+	}
+
+	corr := models.ProductionCorrection{
+		ID:           uuid.New(),
+		ProductionID: productionID,
+		CorrectedMW:  *req.ProductionMW,
+		Reason:       req.Reason,
+		CreatedAt:    time.Now(),
+	}
+	err = tx.QueryRow(ctx, `
+		SELECT p.production_mw, s.id
+		FROM productions p
+		LEFT JOIN published_snapshots s ON s.month = date_trunc('month', p.date)::date
+		WHERE p.id = $1
+		FOR UPDATE OF p`, productionID).Scan(&corr.PreviousMW, &corr.SnapshotID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get production: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE productions
+		SET production_mw = $2, source = $3, source_ref = $4, updated_at = $5
+		WHERE id = $1`, productionID, corr.CorrectedMW, provenance.SourceManual, "correction:"+corr.ID.String(), corr.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to correct production: %w", err)
+	}
+	if provenance.Enabled() {
+		if err := signProduction(ctx, tx, productionID); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO production_corrections (id, production_id, snapshot_id, previous_mw, corrected_mw, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		corr.ID, productionID, corr.SnapshotID, corr.PreviousMW, corr.CorrectedMW, corr.Reason, corr.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to record correction: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit correction: %w", err)
+	}
+
+	corr.PreviousMW = numeric.RoundDecimal(corr.PreviousMW)
+	corr.CorrectedMW = numeric.RoundDecimal(corr.CorrectedMW)
+	return &corr, nil
+}
+
+// GetCorrections lists the corrections of a production record, newest first
+func (r *postgresRepository) GetCorrections(ctx context.Context, productionID uuid.UUID) ([]*models.ProductionCorrection, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, production_id, snapshot_id, previous_mw, corrected_mw, reason, created_at
+		FROM production_corrections
+		WHERE production_id = $1
+		ORDER BY created_at DESC`, productionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query corrections: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.ProductionCorrection
+	for rows.Next() {
+		var c models.ProductionCorrection
+		if err := rows.Scan(&c.ID, &c.ProductionID, &c.SnapshotID, &c.PreviousMW, &c.CorrectedMW, &c.Reason, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan correction: %w", err)
+		}
+		c.PreviousMW = numeric.RoundDecimal(c.PreviousMW)
+		c.CorrectedMW = numeric.RoundDecimal(c.CorrectedMW)
+		list = append(list, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// snapshotQuerier is satisfied by both the pool and a transaction
+type snapshotQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// snapshotRows reads the frozen records of a snapshot in hash order
+func snapshotRows(ctx context.Context, q snapshotQuerier, snapshotID uuid.UUID) ([]*models.SnapshotProduction, error) {
+	rows, err := q.Query(ctx, `
+		SELECT production_id, generator_id, date::text, production_mw, source, COALESCE(source_ref, '')
+		FROM snapshot_productions
+		WHERE snapshot_id = $1
+		ORDER BY date, generator_id, production_id`, snapshotID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshot productions: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*models.SnapshotProduction{}
+	for rows.Next() {
+		var p models.SnapshotProduction
+		if err := rows.Scan(&p.ProductionID, &p.GeneratorID, &p.Date, &p.ProductionMW, &p.Source, &p.SourceRef); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot production: %w", err)
+		}
+		list = append(list, &p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// hashSnapshot returns the SHA-256 of the frozen records, one canonical line per record
+func hashSnapshot(rows []*models.SnapshotProduction) string {
+	h := sha256.New()
+	for _, p := range rows {
+		h.Write([]byte(strings.Join([]string{
+			p.ProductionID.String(),
+			p.GeneratorID.String(),
+			p.Date,
+			p.ProductionMW.String(),
+			p.Source,
+			p.SourceRef,
+		}, "|") + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func sumSnapshot(rows []*models.SnapshotProduction) decimal.Decimal {
+	total := decimal.Zero
+	for _, p := range rows {
+		total = total.Add(p.ProductionMW)
+	}
+	return total
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// cadenceTargets maps a calendar scope to its column and the table it refers to
+var cadenceTargets = map[string]struct{ column, table string }{
+	models.CadenceScopeType:      {"type_id", "types"},
+	models.CadenceScopeGenerator: {"generator_id", "generators"},
+}
+
+// GetSubmissionCalendar lists the cadences set per type and per generator,
+// with the number of generators following each entry
+func (r *postgresRepository) GetSubmissionCalendar(ctx context.Context) ([]*models.SubmissionCadence, error) {
+	query := `
+		SELECT 'type', t.id, t.name, c.cadence,
+		       (SELECT COUNT(*) FROM generators g
+		        WHERE g.type = t.id
+		          AND NOT EXISTS (SELECT 1 FROM submission_calendar o WHERE o.generator_id = g.id)),
+		       c.updated_at
+		FROM submission_calendar c
+		JOIN types t ON c.type_id = t.id
+		UNION ALL
+		SELECT 'generator', g.id, t.name, c.cadence, 1, c.updated_at
+		FROM submission_calendar c
+		JOIN generators g ON c.generator_id = g.id
+		JOIN types t ON g.type = t.id
+		ORDER BY 1 DESC, 3, 2`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query submission calendar: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.SubmissionCadence
+	for rows.Next() {
+		var c models.SubmissionCadence
+		if err := rows.Scan(&c.Scope, &c.ID, &c.Name, &c.Cadence, &c.Generators, &c.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan submission cadence: %w", err)
+		}
+		list = append(list, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// SetSubmissionCadence sets the cadence of a type or generator (scope), replacing any previous one
+func (r *postgresRepository) SetSubmissionCadence(ctx context.Context, scope string, id uuid.UUID, cadence string) error {
+	target, ok := cadenceTargets[scope]
+	if !ok {
+		return fmt.Errorf("unknown submission calendar scope %q", scope)
+	}
+	// Selecting from the target table turns an unknown id into no row instead of a foreign key error
+	query := fmt.Sprintf(`
+		INSERT INTO submission_calendar (%[1]s, cadence, created_at, updated_at)
+		SELECT id, $2, $3, $3 FROM %[2]s WHERE id = $1
+		ON CONFLICT (%[1]s) DO UPDATE SET cadence = EXCLUDED.cadence, updated_at = EXCLUDED.updated_at
+		RETURNING id`, target.column, target.table)
+
+	var entryID uuid.UUID
+	if err := r.db.QueryRow(ctx, query, id, cadence, time.Now()).Scan(&entryID); err != nil {
+		if err == pgx.ErrNoRows {
+			return sql.ErrNoRows
+		}
+		return fmt.Errorf("failed to set submission cadence: %w", err)
+	}
+	return nil
+}
+
+// DeleteSubmissionCadence removes the cadence of a type or generator, which then reports daily (or as its type)
+func (r *postgresRepository) DeleteSubmissionCadence(ctx context.Context, scope string, id uuid.UUID) error {
+	target, ok := cadenceTargets[scope]
+	if !ok {
+		return fmt.Errorf("unknown submission calendar scope %q", scope)
+	}
+	result, err := r.db.Exec(ctx, `DELETE FROM submission_calendar WHERE `+target.column+` = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete submission cadence: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
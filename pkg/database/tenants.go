@@ -0,0 +1,248 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrUnknownTenant is returned for queries of a tenant missing from the registry
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// validTenantID matches tenant IDs, e.g. acme or acme-energy
+var validTenantID = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// TenantConfig represents the tenants served from databases of their own
+type TenantConfig struct {
+	// File is the tenant registry; without it every request is served from
+	// the default database
+	File string
+	// Header names the tenant of a request
+	Header string
+}
+
+// LoadTenantConfig loads the tenant settings from environment variables
+func LoadTenantConfig() *TenantConfig {
+	return &TenantConfig{
+		File:   utils.GetEnv("TENANTS_FILE", ""),
+		Header: utils.GetEnv("TENANT_HEADER", "X-Tenant"),
+	}
+}
+
+// Tenant is a tenant with a database of its own
+type Tenant struct {
+	ID string `json:"id"`
+	// DBURI is the connection URI of its database; ${VAR} is replaced with
+	// the environment variable VAR, so passwords stay out of the file
+	DBURI string `json:"dbUri"`
+	// Hosts are the host names that select the tenant without the header
+	Hosts []string `json:"hosts,omitempty"`
+	// MaxConnections bounds its pool, DB_MAX_CONNECTIONS by default
+	MaxConnections int32 `json:"maxConnections,omitempty"`
+}
+
+// tenantsFile is the layout of TENANTS_FILE
+type tenantsFile struct {
+	Tenants []*Tenant `json:"tenants"`
+}
+
+// LoadTenants reads and checks a tenant registry; no file name gives no tenants
+func LoadTenants(file string) ([]*Tenant, error) {
+	if file == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants: %w", err)
+	}
+	var f tenantsFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants: %w", err)
+	}
+	ids, hosts := map[string]bool{}, map[string]bool{}
+	for i, t := range f.Tenants {
+		t.ID = strings.ToLower(t.ID)
+		t.DBURI = os.ExpandEnv(t.DBURI)
+		switch {
+		case !validTenantID.MatchString(t.ID):
+			return nil, fmt.Errorf("tenant %d: id must be lower-case letters, digits and dashes", i)
+		case ids[t.ID]:
+			return nil, fmt.Errorf("tenant %d: duplicate id %s", i, t.ID)
+		case t.DBURI == "":
+			return nil, fmt.Errorf("tenant %s: dbUri is required", t.ID)
+		case t.MaxConnections < 0:
+			return nil, fmt.Errorf("tenant %s: maxConnections cannot be negative", t.ID)
+		}
+		ids[t.ID] = true
+		for j, host := range t.Hosts {
+			host = strings.ToLower(host)
+			if hosts[host] {
+				return nil, fmt.Errorf("tenant %s: host %s belongs to another tenant", t.ID, host)
+			}
+			hosts[host] = true
+			t.Hosts[j] = host
+		}
+	}
+	return f.Tenants, nil
+}
+
+// NewTenantConnection creates the connection pool of a tenant database. It
+// connects on first use, so a tenant whose database is down does not keep
+// the others from starting.
+func NewTenantConnection(ctx context.Context, t *Tenant) (*DB, error) {
+	poolConfig, err := pgxpool.ParseConfig(t.DBURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse dbUri of tenant %s: %w", t.ID, err)
+	}
+	if t.MaxConnections > 0 {
+		poolConfig.MaxConns = t.MaxConnections
+	}
+	configurePool(poolConfig)
+	// Idle tenants hold no connections
+	poolConfig.MinConns = 0
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool of tenant %s: %w", t.ID, err)
+	}
+	return &DB{Pool: pool}, nil
+}
+
+// Tenants holds the connection pools of the default database and of every
+// tenant of the registry
+type Tenants struct {
+	fallback *pgxpool.Pool
+	pools    map[string]*pgxpool.Pool
+	hosts    map[string]string
+}
+
+// NewTenants creates the pools of tenants; the requests of no tenant run on fallback
+func NewTenants(ctx context.Context, fallback *pgxpool.Pool, tenants []*Tenant) (*Tenants, error) {
+	ts := &Tenants{fallback: fallback, pools: map[string]*pgxpool.Pool{}, hosts: map[string]string{}}
+	for _, t := range tenants {
+		db, err := NewTenantConnection(ctx, t)
+		if err != nil {
+			ts.Close()
+			return nil, err
+		}
+		if err := db.Pool.Ping(ctx); err != nil {
+			log.Printf("Warning: database of tenant %s is unreachable: %v", t.ID, err)
+		}
+		ts.pools[t.ID] = db.Pool
+		for _, host := range t.Hosts {
+			ts.hosts[host] = t.ID
+		}
+	}
+	log.Printf("Routing %d tenants to databases of their own", len(tenants))
+	return ts, nil
+}
+
+// Resolve returns the tenant named by a request, from its tenant header or
+// else its host name (without port); empty for the default database. False
+// when the header names a tenant missing from the registry.
+func (ts *Tenants) Resolve(host, header string) (string, bool) {
+	if header != "" {
+		tenant := strings.ToLower(header)
+		_, ok := ts.pools[tenant]
+		return tenant, ok
+	}
+	if h, _, found := strings.Cut(host, ":"); found {
+		host = h
+	}
+	return ts.hosts[strings.ToLower(host)], true
+}
+
+// pool returns the pool of the tenant of ctx
+func (ts *Tenants) pool(ctx context.Context) (*pgxpool.Pool, error) {
+	tenant := auth.TenantFrom(ctx)
+	if tenant == "" {
+		return ts.fallback, nil
+	}
+	pool, ok := ts.pools[tenant]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTenant, tenant)
+	}
+	return pool, nil
+}
+
+// Close closes the pools of the tenants; the default database is closed by its owner
+func (ts *Tenants) Close() {
+	for _, pool := range ts.pools {
+		pool.Close()
+	}
+}
+
+// tenantDB runs every query on the pool of the tenant of its context
+type tenantDB struct {
+	tenants *Tenants
+}
+
+func (d tenantDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	pool, err := d.tenants.pool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pool.Begin(ctx)
+}
+
+func (d tenantDB) BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	pool, err := d.tenants.pool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pool.BeginTx(ctx, opts)
+}
+
+func (d tenantDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	pool, err := d.tenants.pool(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return pool.Exec(ctx, sql, args...)
+}
+
+func (d tenantDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	pool, err := d.tenants.pool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pool.Query(ctx, sql, args...)
+}
+
+func (d tenantDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	pool, err := d.tenants.pool(ctx)
+	if err != nil {
+		return errRow{err}
+	}
+	return pool.QueryRow(ctx, sql, args...)
+}
+
+// errRow is a row whose Scan fails with err
+type errRow struct{ err error }
+
+func (r errRow) Scan(...any) error { return r.err }
+
+// NewTenantRepository creates a repository running the queries of every
+// request on the database of its tenant (see auth.WithTenant)
+func NewTenantRepository(tenants *Tenants) Repository {
+	db := tenantDB{tenants: tenants}
+	return &postgresRepository{
+		db:        db,
+		pool:      db,
+		snapshots: newReadSnapshots(LoadReadSnapshotConfig()),
+		plan:      LoadAnalyticsPlanConfig(),
+	}
+}
@@ -0,0 +1,217 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidTimeSeries is returned for time series with an unknown bucket or
+// metric, or with too many buckets
+var ErrInvalidTimeSeries = errors.New("invalid time series")
+
+// maxTimeSeriesBuckets bounds the buckets of a time series, e.g. 13 years of days
+const maxTimeSeriesBuckets = 5000
+
+// timeSeriesPoint is the production of one series in one bucket
+type timeSeriesPoint struct {
+	bucket     time.Time
+	key, name  string
+	production decimal.Decimal
+}
+
+// validTimeSeries checks the bucket and metric of a query
+func validTimeSeries(q *models.TimeSeriesQuery) error {
+	if !slices.Contains(models.TimeSeriesBuckets, q.GroupBy) {
+		return fmt.Errorf("%w: unknown groupBy %q", ErrInvalidTimeSeries, q.GroupBy)
+	}
+	if !slices.Contains(models.TimeSeriesMetrics, q.Metric) {
+		return fmt.Errorf("%w: unknown metric %q", ErrInvalidTimeSeries, q.Metric)
+	}
+	return nil
+}
+
+// bucketStart returns the first day of the bucket of date, as date_trunc does
+func bucketStart(groupBy string, date time.Time) time.Time {
+	y, m, d := date.Date()
+	switch groupBy {
+	case models.BucketWeek:
+		return time.Date(y, m, d-(int(date.Weekday())+6)%7, 0, 0, 0, 0, time.UTC)
+	case models.BucketMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	case models.BucketYear:
+		return time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// nextBucket returns the first day of the bucket after the one starting at start
+func nextBucket(groupBy string, start time.Time) time.Time {
+	switch groupBy {
+	case models.BucketWeek:
+		return start.AddDate(0, 0, 7)
+	case models.BucketMonth:
+		return start.AddDate(0, 1, 0)
+	case models.BucketYear:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// timeSeriesSeries returns the series a metric always has, even without
+// records; series per type only exist for the types with records
+func timeSeriesSeries(metric string) []*models.TimeSeriesLine {
+	switch metric {
+	case models.TimeSeriesTotal:
+		return []*models.TimeSeriesLine{{Key: "total", Name: "Total"}}
+	case models.TimeSeriesRenewable:
+		return []*models.TimeSeriesLine{{Key: "renewable", Name: "Renewable"}, {Key: "nonRenewable", Name: "Non-renewable"}}
+	}
+	return nil
+}
+
+// timeBuckets returns the buckets from the start of the range (or the first
+// bucket with data) to its end (or the last bucket with data), without gaps
+func timeBuckets(groupBy string, startDate, endDate *string, data []time.Time) ([]time.Time, error) {
+	var first, last time.Time
+	for i, b := range data {
+		if i == 0 || b.Before(first) {
+			first = b
+		}
+		if i == 0 || b.After(last) {
+			last = b
+		}
+	}
+	if startDate != nil {
+		if d, err := time.Parse(time.DateOnly, *startDate); err == nil {
+			first = bucketStart(groupBy, d)
+		}
+	}
+	if endDate != nil {
+		if d, err := time.Parse(time.DateOnly, *endDate); err == nil {
+			last = bucketStart(groupBy, d)
+		}
+	}
+	if first.IsZero() || last.IsZero() {
+		return nil, nil
+	}
+	var buckets []time.Time
+	for b := first; !b.After(last); b = nextBucket(groupBy, b) {
+		if len(buckets) == maxTimeSeriesBuckets {
+			return nil, fmt.Errorf("%w: more than %d buckets, narrow the date range or use a larger groupBy", ErrInvalidTimeSeries, maxTimeSeriesBuckets)
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, nil
+}
+
+// buildTimeSeries lays the points out on their buckets, filling the buckets
+// without records with zeros
+func buildTimeSeries(q *models.TimeSeriesQuery, points []timeSeriesPoint) (*models.TimeSeries, error) {
+	ts := &models.TimeSeries{GroupBy: q.GroupBy, Metric: q.Metric, Buckets: []string{}, Series: timeSeriesSeries(q.Metric)}
+
+	data := make([]time.Time, len(points))
+	for i, p := range points {
+		data[i] = p.bucket
+	}
+	buckets, err := timeBuckets(q.GroupBy, q.StartDate, q.EndDate, data)
+	if err != nil {
+		return nil, err
+	}
+	index := map[time.Time]int{}
+	for i, b := range buckets {
+		index[b] = i
+		ts.Buckets = append(ts.Buckets, b.Format(time.DateOnly))
+	}
+
+	series := map[string]*models.TimeSeriesLine{}
+	for _, s := range ts.Series {
+		series[s.Key] = s
+	}
+	for _, p := range points {
+		s := series[p.key]
+		if s == nil {
+			s = &models.TimeSeriesLine{Key: p.key, Name: p.name}
+			if id, err := uuid.Parse(p.key); err == nil {
+				s.TypeID = &id
+			}
+			series[p.key] = s
+			ts.Series = append(ts.Series, s)
+		}
+		i, ok := index[p.bucket]
+		if !ok {
+			continue
+		}
+		if s.Values == nil {
+			s.Values = make([]decimal.Decimal, len(ts.Buckets))
+		}
+		s.Values[i] = s.Values[i].Add(p.production)
+	}
+	if q.Metric == models.TimeSeriesByType {
+		sort.Slice(ts.Series, func(i, j int) bool { return ts.Series[i].Name < ts.Series[j].Name })
+	}
+	for _, s := range ts.Series {
+		if s.Values == nil {
+			s.Values = make([]decimal.Decimal, len(ts.Buckets))
+		}
+		for i, v := range s.Values {
+			s.Total = s.Total.Add(v)
+			s.Values[i] = numeric.RoundDecimal(v)
+		}
+		s.Total = numeric.RoundDecimal(s.Total)
+	}
+	if ts.Series == nil {
+		ts.Series = []*models.TimeSeriesLine{}
+	}
+	return ts, nil
+}
+
+// GetTimeSeries returns production summed per bucket of q.GroupBy with
+// date_trunc, in the series of q.Metric; wide ranges read the daily totals
+func (r *postgresRepository) GetTimeSeries(ctx context.Context, q *models.TimeSeriesQuery) (*models.TimeSeries, error) {
+	if err := validTimeSeries(q); err != nil {
+		return nil, err
+	}
+	from, conds, args := r.productionRelation(q.StartDate, q.EndDate, q.AsOf, []any{q.GroupBy})
+	conds, args = dateRangeConditions("p.date", q.StartDate, q.EndDate, conds, args)
+	series := `'total', 'Total'`
+	switch q.Metric {
+	case models.TimeSeriesRenewable:
+		series = `CASE WHEN t.isrenuevable THEN 'renewable' ELSE 'nonRenewable' END,
+		       CASE WHEN t.isrenuevable THEN 'Renewable' ELSE 'Non-renewable' END`
+	case models.TimeSeriesByType:
+		series = `t.id::text, t.name`
+	}
+	query := `
+		SELECT date_trunc($1, p.date::timestamp)::date, ` + series + `, SUM(p.production_mw)
+		FROM ` + from + whereClause(conds) + `
+		GROUP BY 1, 2, 3
+		ORDER BY 1`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query time series: %w", err)
+	}
+	defer rows.Close()
+
+	var points []timeSeriesPoint
+	for rows.Next() {
+		var p timeSeriesPoint
+		if err := rows.Scan(&p.bucket, &p.key, &p.name, &p.production); err != nil {
+			return nil, fmt.Errorf("failed to scan time series: %w", err)
+		}
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return buildTimeSeries(q, points)
+}
@@ -0,0 +1,317 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrRestoreConflict is returned when a trash item cannot be restored because
+// the data changed since it was deleted, e.g. its name was taken again or
+// the record it belongs to is gone
+var ErrRestoreConflict = errors.New("trash item conflicts with current data")
+
+// ErrStillReferenced is returned when a resource cannot be deleted because
+// other rows still reference it, e.g. the generators of a type
+var ErrStillReferenced = errors.New("still referenced")
+
+// trashTable is a table whose rows go to the trash with a resource; where
+// selects them with the resource id as $1. Tables whose rows are not deleted
+// but have column set to NULL (ON DELETE SET NULL) set link to that column:
+// only the ids of their rows are kept, to point them back on restore.
+type trashTable struct {
+	table string
+	where string
+	link  string
+}
+
+// trashResource describes a deletable resource: label reads its name with the
+// id as $1 and tables lists the first table, whose row is the resource, and
+// those its deletion cascades to in the order they are restored
+type trashResource struct {
+	label  string
+	tables []trashTable
+}
+
+var trashResources = map[string]trashResource{
+	models.TrashType: {
+		label: `SELECT name FROM types WHERE id = $1`,
+		tables: []trashTable{
+			{table: "types", where: "id = $1"},
+			{table: "type_aliases", where: "type_id = $1"},
+			{table: "type_translations", where: "type_id = $1"},
+			{table: "generators", where: "type = $1"},
+			{table: "productions", where: "generator_id IN (SELECT id FROM generators WHERE type = $1)"},
+			{table: "production_corrections", where: "production_id IN (SELECT p.id FROM productions p JOIN generators g ON p.generator_id = g.id WHERE g.type = $1)"},
+			{table: "annotations", where: "generator_id IN (SELECT id FROM generators WHERE type = $1) OR production_id IN (SELECT p.id FROM productions p JOIN generators g ON p.generator_id = g.id WHERE g.type = $1)"},
+			{table: "submission_calendar", where: "type_id = $1 OR generator_id IN (SELECT id FROM generators WHERE type = $1)"},
+		},
+	},
+	models.TrashOperator: {
+		label: `SELECT name FROM operators WHERE id = $1`,
+		tables: []trashTable{
+			{table: "operators", where: "id = $1"},
+			{table: "user_operator_grants", where: "operator_id = $1"},
+			{table: "generators", where: "operator_id = $1", link: "operator_id"},
+		},
+	},
+	models.TrashGenerator: {
+		label: `SELECT t.name || ' generator, ' || g.capacity::text || ' MW' FROM generators g JOIN types t ON g.type = t.id WHERE g.id = $1`,
+		tables: []trashTable{
+			{table: "generators", where: "id = $1"},
+			{table: "productions", where: "generator_id = $1"},
+			{table: "production_corrections", where: "production_id IN (SELECT id FROM productions WHERE generator_id = $1)"},
+			{table: "annotations", where: "generator_id = $1 OR production_id IN (SELECT id FROM productions WHERE generator_id = $1)"},
+			{table: "submission_calendar", where: "generator_id = $1"},
+		},
+	},
+	models.TrashProduction: {
+		label: `SELECT 'Production of ' || date::text || ', ' || production_mw::text || ' MW' FROM productions WHERE id = $1`,
+		tables: []trashTable{
+			{table: "productions", where: "id = $1"},
+			{table: "production_corrections", where: "production_id = $1"},
+			{table: "annotations", where: "production_id = $1"},
+			{table: "import_dead_letters", where: "production_id = $1", link: "production_id"},
+		},
+	},
+	models.TrashReport: {
+		label: `SELECT name FROM reports WHERE id = $1`,
+		tables: []trashTable{
+			{table: "reports", where: "id = $1"},
+			{table: "report_runs", where: "report_id = $1"},
+			{table: "webhook_deliveries", where: "report_id = $1"},
+		},
+	},
+	models.TrashReportTemplate: {
+		label: `SELECT name FROM report_templates WHERE id = $1`,
+		tables: []trashTable{
+			{table: "report_templates", where: "id = $1"},
+			{table: "reports", where: "template_id = $1", link: "template_id"},
+		},
+	},
+}
+
+// trashKey is the payload key of a table; link tables are keyed by table and column
+func (t trashTable) key() string {
+	if t.link != "" {
+		return t.table + "." + t.link
+	}
+	return t.table
+}
+
+// moveToTrash deletes a resource after keeping it, and the rows its deletion
+// cascades to, in the trash. With cascade the rows of the other tables are
+// deleted first; otherwise rows that must not go with the resource (the
+// generators of a type, the productions of a generator) make the delete fail
+// with ErrStillReferenced. It returns sql.ErrNoRows when there is no such resource.
+func (r *postgresRepository) moveToTrash(ctx context.Context, resource string, id uuid.UUID, cascade bool) error {
+	res := trashResources[resource]
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var label string
+	if err := tx.QueryRow(ctx, res.label, id).Scan(&label); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return sql.ErrNoRows
+		}
+		return fmt.Errorf("failed to read %s: %w", resource, err)
+	}
+
+	payload := map[string]json.RawMessage{}
+	rowCount := 0
+	for _, t := range res.tables {
+		var (
+			rows json.RawMessage
+			n    int
+		)
+		query := `SELECT COALESCE(jsonb_agg(to_jsonb(x)), '[]'), COUNT(*) FROM ` + t.table + ` x WHERE ` + t.where
+		if t.link != "" {
+			query = `SELECT COALESCE(jsonb_agg(id), '[]'), 0 FROM ` + t.table + ` WHERE ` + t.where
+		}
+		if err := tx.QueryRow(ctx, query, id).Scan(&rows, &n); err != nil {
+			return fmt.Errorf("failed to copy %s to the trash: %w", t.table, err)
+		}
+		payload[t.key()] = rows
+		rowCount += n
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO trash (id, resource, resource_id, label, row_count, payload, deleted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		uuid.New(), resource, id, truncate(label, 200), rowCount, payload, time.Now()); err != nil {
+		return fmt.Errorf("failed to move %s to the trash: %w", resource, err)
+	}
+	if cascade {
+		for i := len(res.tables) - 1; i > 0; i-- {
+			if t := res.tables[i]; t.link == "" {
+				if _, err := tx.Exec(ctx, `DELETE FROM `+t.table+` WHERE `+t.where, id); err != nil {
+					return fmt.Errorf("failed to delete %s of %s: %w", t.table, resource, publishedError(err))
+				}
+			}
+		}
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM `+res.tables[0].table+` WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", resource, deleteError(err))
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", publishedError(err))
+	}
+	return nil
+}
+
+// deleteError maps foreign key violations to ErrStillReferenced, naming the
+// referencing table, and writes to published months to ErrPeriodPublished
+func deleteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return fmt.Errorf("%w by %s", ErrStillReferenced, pgErr.TableName)
+	}
+	return publishedError(err)
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+const trashColumns = `id, resource, resource_id, label, row_count, deleted_at`
+
+// GetTrash lists the items in the trash, most recently deleted first; a
+// non-empty resource limits the listing to that kind of resource
+func (r *postgresRepository) GetTrash(ctx context.Context, resource string) ([]*models.TrashItem, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+trashColumns+`
+		FROM trash
+		WHERE $1 = '' OR resource = $1
+		ORDER BY deleted_at DESC, id`, resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trash: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.TrashItem
+	for rows.Next() {
+		var t models.TrashItem
+		if err := rows.Scan(&t.ID, &t.Resource, &t.ResourceID, &t.Label, &t.Rows, &t.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan trash item: %w", err)
+		}
+		list = append(list, &t)
+	}
+	return list, rows.Err()
+}
+
+// GetTrashItem returns an item of the trash
+func (r *postgresRepository) GetTrashItem(ctx context.Context, id uuid.UUID) (*models.TrashItem, error) {
+	var t models.TrashItem
+	err := r.db.QueryRow(ctx, `SELECT `+trashColumns+` FROM trash WHERE id = $1`, id).
+		Scan(&t.ID, &t.Resource, &t.ResourceID, &t.Label, &t.Rows, &t.DeletedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get trash item: %w", err)
+	}
+	return &t, nil
+}
+
+// RestoreTrashItem re-inserts the rows of a trash item, points the rows that
+// referenced it back to it and removes it from the trash. It fails with
+// ErrRestoreConflict when the rows no longer fit the current data.
+func (r *postgresRepository) RestoreTrashItem(ctx context.Context, id uuid.UUID) (*models.TrashItem, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		t       models.TrashItem
+		payload map[string]json.RawMessage
+	)
+	err = tx.QueryRow(ctx, `
+		DELETE FROM trash WHERE id = $1
+		RETURNING `+trashColumns+`, payload`, id).
+		Scan(&t.ID, &t.Resource, &t.ResourceID, &t.Label, &t.Rows, &t.DeletedAt, &payload)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to read trash item: %w", err)
+	}
+	res, ok := trashResources[t.Resource]
+	if !ok {
+		return nil, fmt.Errorf("unknown trash resource %q", t.Resource)
+	}
+
+	for _, tbl := range res.tables {
+		rows, ok := payload[tbl.key()]
+		if !ok {
+			continue
+		}
+		query := `INSERT INTO ` + tbl.table + ` SELECT * FROM jsonb_populate_recordset(NULL::` + tbl.table + `, $1)`
+		args := []any{rows}
+		if tbl.link != "" {
+			query = `UPDATE ` + tbl.table + ` SET ` + tbl.link + ` = $2
+				WHERE id IN (SELECT jsonb_array_elements_text($1)::uuid) AND ` + tbl.link + ` IS NULL`
+			args = append(args, t.ResourceID)
+		}
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", tbl.table, restoreError(err))
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", restoreError(err))
+	}
+	return &t, nil
+}
+
+// restoreError maps constraint violations of restored rows to ErrRestoreConflict
+// and writes to published months to ErrPeriodPublished
+func restoreError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505", "23503":
+			return fmt.Errorf("%w: %s", ErrRestoreConflict, pgErr.Message)
+		case publishedPeriodCode:
+			return ErrPeriodPublished
+		}
+	}
+	return err
+}
+
+// PurgeTrashItem deletes an item of the trash for good
+func (r *postgresRepository) PurgeTrashItem(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.Exec(ctx, `DELETE FROM trash WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to purge trash item: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// PurgeTrash deletes for good the trash items deleted before cutoff and
+// returns how many there were
+func (r *postgresRepository) PurgeTrash(ctx context.Context, cutoff time.Time) (int64, error) {
+	res, err := r.db.Exec(ctx, `DELETE FROM trash WHERE deleted_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+	return res.RowsAffected(), nil
+}
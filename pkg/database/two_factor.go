@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrTwoFactorEnabled is returned when enrolling a user whose two-factor
+// authentication is already enabled
+var ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+
+// GetTwoFactor retrieves the TOTP enrollment of a user with the count of its
+// unused backup codes
+func (r *postgresRepository) GetTwoFactor(ctx context.Context, userID uuid.UUID) (*models.TwoFactor, error) {
+	query := `
+		SELECT t.user_id, t.secret, t.enabled_at, t.last_step, t.created_at,
+		       (SELECT COUNT(*) FROM user_backup_codes b WHERE b.user_id = t.user_id AND b.used_at IS NULL)
+		FROM user_two_factor t
+		WHERE t.user_id = $1`
+
+	var t models.TwoFactor
+	err := r.db.QueryRow(ctx, query, userID).Scan(&t.UserID, &t.Secret, &t.EnabledAt, &t.LastStep, &t.CreatedAt, &t.BackupCodesLeft)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get two-factor enrollment: %w", err)
+	}
+	return &t, nil
+}
+
+// SetTwoFactorSecret starts a pending TOTP enrollment
+func (r *postgresRepository) SetTwoFactorSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	query := `
+		INSERT INTO user_two_factor (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_step = 0, created_at = now()
+		WHERE user_two_factor.enabled_at IS NULL`
+
+	result, err := r.db.Exec(ctx, query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to set two-factor secret: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrTwoFactorEnabled
+	}
+	return nil
+}
+
+// EnableTwoFactor confirms a pending TOTP enrollment
+func (r *postgresRepository) EnableTwoFactor(ctx context.Context, userID uuid.UUID, step int64, codeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE user_two_factor SET enabled_at = now(), last_step = $2
+		WHERE user_id = $1 AND enabled_at IS NULL`, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	if err := replaceBackupCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit two-factor enrollment: %w", err)
+	}
+	return nil
+}
+
+// UseTwoFactorStep records the step of an accepted code unless a code of
+// that step or a later one was accepted before, which makes codes single use
+func (r *postgresRepository) UseTwoFactorStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE user_two_factor SET last_step = $2
+		WHERE user_id = $1 AND last_step < $2`, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record two-factor code: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// UseBackupCode marks an unused backup code as used
+func (r *postgresRepository) UseBackupCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE user_backup_codes SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use backup code: %w", err)
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// ReplaceBackupCodes replaces the backup codes of an enrollment
+func (r *postgresRepository) ReplaceBackupCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceBackupCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit backup codes: %w", err)
+	}
+	return nil
+}
+
+func replaceBackupCodes(ctx context.Context, tx pgx.Tx, userID uuid.UUID, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM user_backup_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete backup codes: %w", err)
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO user_backup_codes (user_id, code_hash)
+		SELECT $1, unnest($2::text[])`, userID, codeHashes)
+	if err != nil {
+		return fmt.Errorf("failed to store backup codes: %w", err)
+	}
+	return nil
+}
+
+// DeleteTwoFactor removes the TOTP enrollment of a user with its backup codes
+func (r *postgresRepository) DeleteTwoFactor(ctx context.Context, userID uuid.UUID) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM user_two_factor WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete two-factor enrollment: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+)
+
+// MergeType re-points every generator of sourceID to targetID, records the
+// source name as an alias of the target and soft-deletes the source, all in
+// one transaction. sql.ErrNoRows is returned if either type does not exist.
+func (r *postgresRepository) MergeType(ctx context.Context, sourceID, targetID uuid.UUID) (*models.TypeMergeResult, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock both rows in a stable order so concurrent merges cannot deadlock
+	rows, err := tx.Query(ctx, `
+		SELECT id, name FROM types
+		WHERE id IN ($1, $2) AND deleted_at IS NULL
+		ORDER BY id
+		FOR UPDATE`, sourceID, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock types: %w", err)
+	}
+	var alias string
+	found := 0
+	for rows.Next() {
+		var id uuid.UUID
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan type: %w", err)
+		}
+		if id == sourceID {
+			alias = name
+		}
+		found++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	if found < 2 {
+		return nil, sql.ErrNoRows
+	}
+
+	now := time.Now()
+
+	moved, err := tx.Exec(ctx, `UPDATE generators SET type = $2, updated_at = $3 WHERE type = $1`, sourceID, targetID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move generators: %w", err)
+	}
+
+	// Aliases and earlier merges that pointed at the source now point at the target
+	if _, err := tx.Exec(ctx, `UPDATE type_aliases SET type_id = $2 WHERE type_id = $1`, sourceID, targetID); err != nil {
+		return nil, fmt.Errorf("failed to move aliases: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE types SET merged_into = $2 WHERE merged_into = $1`, sourceID, targetID); err != nil {
+		return nil, fmt.Errorf("failed to move merged types: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO type_aliases (id, alias, type_id, created_at)
+		VALUES ($1, $2, $3, $4)`, uuid.New(), alias, targetID, now); err != nil {
+		return nil, fmt.Errorf("failed to record alias: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE types
+		SET deleted_at = $2, merged_into = $3, updated_at = $2
+		WHERE id = $1`, sourceID, now, targetID); err != nil {
+		return nil, fmt.Errorf("failed to soft-delete type: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit merge: %w", err)
+	}
+
+	return &models.TypeMergeResult{
+		SourceID:        sourceID,
+		TargetID:        targetID,
+		Alias:           alias,
+		GeneratorsMoved: moved.RowsAffected(),
+		MergedAt:        now,
+	}, nil
+}
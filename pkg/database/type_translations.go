@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetTypeTranslations lists the translations of a type by language; sql.ErrNoRows when there is no such type
+func (r *postgresRepository) GetTypeTranslations(ctx context.Context, typeID uuid.UUID) ([]*models.TypeTranslation, error) {
+	query := `
+		SELECT t.id, tr.language, tr.name, tr.description, tr.updated_at
+		FROM types t
+		LEFT JOIN type_translations tr ON tr.type_id = t.id
+		WHERE t.id = $1 AND t.deleted_at IS NULL
+		ORDER BY tr.language`
+
+	rows, err := r.db.Query(ctx, query, typeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query type translations: %w", err)
+	}
+	defer rows.Close()
+
+	found := false
+	list := []*models.TypeTranslation{}
+	for rows.Next() {
+		found = true
+		var (
+			tr         models.TypeTranslation
+			language   *string
+			name, desc *string
+			updatedAt  *time.Time
+		)
+		if err := rows.Scan(&tr.TypeID, &language, &name, &desc, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan type translation: %w", err)
+		}
+		// A type without translations comes back as one row of NULLs
+		if language == nil {
+			continue
+		}
+		tr.Language, tr.Name, tr.Description, tr.UpdatedAt = *language, *name, *desc, *updatedAt
+		list = append(list, &tr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	if !found {
+		return nil, sql.ErrNoRows
+	}
+	return list, nil
+}
+
+// GetTranslationsByLanguage returns the translations to language keyed by type id
+func (r *postgresRepository) GetTranslationsByLanguage(ctx context.Context, language string) (map[uuid.UUID]*models.TypeTranslation, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT type_id, language, name, description, updated_at
+		FROM type_translations
+		WHERE language = $1`, language)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query type translations: %w", err)
+	}
+	defer rows.Close()
+
+	byType := map[uuid.UUID]*models.TypeTranslation{}
+	for rows.Next() {
+		var tr models.TypeTranslation
+		if err := rows.Scan(&tr.TypeID, &tr.Language, &tr.Name, &tr.Description, &tr.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan type translation: %w", err)
+		}
+		byType[tr.TypeID] = &tr
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return byType, nil
+}
+
+// SetTypeTranslation sets the name and description of a type in language,
+// replacing any previous translation to it
+func (r *postgresRepository) SetTypeTranslation(ctx context.Context, typeID uuid.UUID, language string, req *models.TypeTranslationRequest) (*models.TypeTranslation, error) {
+	// Selecting from types turns an unknown id into no row instead of a foreign key error
+	query := `
+		INSERT INTO type_translations (type_id, language, name, description, created_at, updated_at)
+		SELECT id, $2, $3, $4, $5, $5 FROM types WHERE id = $1 AND deleted_at IS NULL
+		ON CONFLICT (type_id, language) DO UPDATE
+		SET name = EXCLUDED.name, description = EXCLUDED.description, updated_at = EXCLUDED.updated_at
+		RETURNING type_id, language, name, description, updated_at`
+
+	var tr models.TypeTranslation
+	err := r.db.QueryRow(ctx, query, typeID, language, req.Name, req.Description, time.Now()).
+		Scan(&tr.TypeID, &tr.Language, &tr.Name, &tr.Description, &tr.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to set type translation: %w", err)
+	}
+	return &tr, nil
+}
+
+// DeleteTypeTranslation removes the translation of a type to language
+func (r *postgresRepository) DeleteTypeTranslation(ctx context.Context, typeID uuid.UUID, language string) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM type_translations WHERE type_id = $1 AND language = $2`, typeID, language)
+	if err != nil {
+		return fmt.Errorf("failed to delete type translation: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
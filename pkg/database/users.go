@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrUserExists is returned when registering a username or email that is taken
+var ErrUserExists = errors.New("username or email is already registered")
+
+// ErrUsersExist is returned by CreateFirstUser once there is any user
+var ErrUsersExist = errors.New("the first user is already registered")
+
+const userColumns = `id, username, email, roles, created_at, updated_at`
+
+func (r *postgresRepository) CreateUser(ctx context.Context, req *models.RegisterRequest, passwordHash string, roles []string) (*models.User, error) {
+	query := `
+		INSERT INTO users (id, username, email, password_hash, roles, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)
+		RETURNING ` + userColumns
+
+	var u models.User
+	err := r.db.QueryRow(ctx, query, uuid.New(), strings.TrimSpace(req.Username), strings.TrimSpace(req.Email), passwordHash, roles, time.Now()).
+		Scan(&u.ID, &u.Username, &u.Email, &u.Roles, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrUserExists
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return &u, nil
+}
+
+// CreateFirstUser creates an admin in the same transaction as the check that
+// there are no users yet. Concurrent calls queue on an advisory lock, as two
+// inserts guarded by NOT EXISTS could otherwise both see an empty table.
+func (r *postgresRepository) CreateFirstUser(ctx context.Context, req *models.RegisterRequest, passwordHash string) (*models.User, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('core.users:first'))`); err != nil {
+		return nil, fmt.Errorf("failed to lock users: %w", err)
+	}
+	query := `
+		INSERT INTO users (id, username, email, password_hash, roles, created_at, updated_at)
+		SELECT $1, $2, $3, $4, $5, $6, $6
+		WHERE NOT EXISTS (SELECT 1 FROM users)
+		RETURNING ` + userColumns
+
+	var u models.User
+	err = tx.QueryRow(ctx, query, uuid.New(), strings.TrimSpace(req.Username), strings.TrimSpace(req.Email), passwordHash, []string{auth.RoleAdmin}, time.Now()).
+		Scan(&u.ID, &u.Username, &u.Email, &u.Roles, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrUsersExist
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrUserExists
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &u, nil
+}
+
+func (r *postgresRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	var u models.User
+	err := r.db.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id).
+		Scan(&u.ID, &u.Username, &u.Email, &u.Roles, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &u, nil
+}
+
+// GetUserCredentials finds a user by username, ignoring case, with its password hash
+func (r *postgresRepository) GetUserCredentials(ctx context.Context, username string) (*models.User, string, error) {
+	var u models.User
+	var hash string
+	err := r.db.QueryRow(ctx, `SELECT `+userColumns+`, password_hash FROM users WHERE lower(username) = lower($1)`, strings.TrimSpace(username)).
+		Scan(&u.ID, &u.Username, &u.Email, &u.Roles, &u.CreatedAt, &u.UpdatedAt, &hash)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, "", sql.ErrNoRows
+		}
+		return nil, "", fmt.Errorf("failed to get user credentials: %w", err)
+	}
+	return &u, hash, nil
+}
+
+// SetUserRoles replaces the roles of a user
+func (r *postgresRepository) SetUserRoles(ctx context.Context, id uuid.UUID, roles []string) (*models.User, error) {
+	query := `UPDATE users SET roles = $2, updated_at = $3 WHERE id = $1 RETURNING ` + userColumns
+
+	var u models.User
+	err := r.db.QueryRow(ctx, query, id, roles, time.Now()).
+		Scan(&u.ID, &u.Username, &u.Email, &u.Roles, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to set user roles: %w", err)
+	}
+	return &u, nil
+}
+
+func (r *postgresRepository) CountUsers(ctx context.Context) (int64, error) {
+	var n int64
+	if err := r.db.QueryRow(ctx, `SELECT count(*) FROM users`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ErrVersionMismatch is returned by updates with an If-Match version when
+// the record has been updated since the client read it
+var ErrVersionMismatch = errors.New("the record was changed since it was read")
+
+// versionCondition returns the condition an update with ifMatch puts on the
+// version of the row, appending its argument to args
+func versionCondition(ifMatch *int, args []any) (string, []any) {
+	if ifMatch == nil {
+		return "", args
+	}
+	args = append(args, *ifMatch)
+	return fmt.Sprintf(" AND version = $%d", len(args)), args
+}
+
+// checkVersion is the version condition of the in-memory repository
+func checkVersion(ifMatch *int, version int) error {
+	if ifMatch != nil && *ifMatch != version {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+// versionError tells why an update with an If-Match version matched no row
+// of table: ErrVersionMismatch when the row is there, sql.ErrNoRows otherwise
+func versionError(ctx context.Context, q execQuerier, table string, id uuid.UUID) error {
+	cond := ""
+	if table == "types" {
+		cond = " AND deleted_at IS NULL"
+	}
+	var exists bool
+	if err := q.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM `+table+` WHERE id = $1`+cond+`)`, id).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check version: %w", err)
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+	return sql.ErrNoRows
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/webhook"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const webhookDeliveryColumns = `id, report_id, run_id, redelivery_of, url, COALESCE(period_start::text, ''), COALESCE(period_end::text, ''),
+	status_code, succeeded, COALESCE(error, ''), byte_count, duration_ms, delivered_at`
+
+func scanWebhookDelivery(row pgx.Row, d *models.WebhookDelivery) error {
+	return row.Scan(&d.ID, &d.ReportID, &d.RunID, &d.RedeliveryOf, &d.URL, &d.PeriodStart, &d.PeriodEnd,
+		&d.StatusCode, &d.Succeeded, &d.Error, &d.Bytes, &d.DurationMs, &d.DeliveredAt)
+}
+
+// GetReportWebhookSecret returns the secret a report signs its webhook requests with
+func (r *postgresRepository) GetReportWebhookSecret(ctx context.Context, reportID uuid.UUID) (string, error) {
+	var secret string
+	if err := r.db.QueryRow(ctx, `SELECT webhook_secret FROM reports WHERE id = $1`, reportID).Scan(&secret); err != nil {
+		if err == pgx.ErrNoRows {
+			return "", sql.ErrNoRows
+		}
+		return "", fmt.Errorf("failed to get webhook secret: %w", err)
+	}
+	return secret, nil
+}
+
+// RotateReportWebhookSecret replaces the webhook secret of a report with a new random one
+func (r *postgresRepository) RotateReportWebhookSecret(ctx context.Context, reportID uuid.UUID) (*models.WebhookSecret, error) {
+	s := &models.WebhookSecret{ReportID: reportID, Secret: webhook.NewSecret(), RotatedAt: time.Now()}
+	result, err := r.db.Exec(ctx, `UPDATE reports SET webhook_secret = $2, updated_at = $3 WHERE id = $1`, reportID, s.Secret, s.RotatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return s, nil
+}
+
+// CreateWebhookDelivery records a webhook request and its outcome
+func (r *postgresRepository) CreateWebhookDelivery(ctx context.Context, d *models.WebhookDelivery) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO webhook_deliveries (id, report_id, run_id, redelivery_of, url, period_start, period_end,
+		                                status_code, succeeded, error, byte_count, duration_ms, delivered_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, '')::date, NULLIF($7, '')::date, $8, $9, NULLIF($10, ''), $11, $12, $13)`,
+		d.ID, d.ReportID, d.RunID, d.RedeliveryOf, d.URL, d.PeriodStart, d.PeriodEnd,
+		d.StatusCode, d.Succeeded, d.Error, d.Bytes, d.DurationMs, d.DeliveredAt)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookDeliveries lists the latest webhook requests of a report, newest first
+func (r *postgresRepository) GetWebhookDeliveries(ctx context.Context, reportID uuid.UUID, limit int) ([]*models.WebhookDelivery, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries
+		WHERE report_id = $1
+		ORDER BY delivered_at DESC
+		LIMIT $2`, reportID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &d); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		list = append(list, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return list, nil
+}
+
+// GetWebhookDeliveryByID retrieves a webhook request of a report
+func (r *postgresRepository) GetWebhookDeliveryByID(ctx context.Context, reportID, id uuid.UUID) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	err := scanWebhookDelivery(r.db.QueryRow(ctx, `SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE report_id = $1 AND id = $2`, reportID, id), &d)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	return &d, nil
+}
@@ -0,0 +1,259 @@
+// Package demo runs the API as a sandbox for public demos and teaching: it
+// seeds an in-memory repository with a synthetic fleet and its production
+// history, keeps generating plausible production data in the background and
+// labels every response as synthetic.
+package demo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// SyntheticHeader is set to "true" on every response served in demo mode
+const SyntheticHeader = "X-Synthetic-Data"
+
+// sourceRef marks the production records written by the generator
+const sourceRef = "demo generator"
+
+// Config represents the demo mode settings
+type Config struct {
+	// Enabled runs the API on the in-memory repository with synthetic data
+	Enabled bool
+	// Interval is how often the production of today is updated
+	Interval time.Duration
+	// HistoryDays is the number of past days seeded at startup
+	HistoryDays int
+	// Seed makes the seeded history reproducible
+	Seed uint64
+}
+
+// LoadConfig loads demo configuration from environment variables
+func LoadConfig() *Config {
+	cfg := &Config{
+		Enabled:     utils.GetEnvAsBool("DEMO_MODE", false),
+		Interval:    utils.GetEnvAsDuration("DEMO_INTERVAL", time.Minute),
+		HistoryDays: utils.GetEnvAsInt("DEMO_HISTORY_DAYS", 90),
+		Seed:        uint64(utils.GetEnvAsInt64("DEMO_SEED", 1)),
+	}
+	if cfg.HistoryDays < 0 {
+		cfg.HistoryDays = 90
+	}
+	return cfg
+}
+
+// Middleware labels every response as synthetic data
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(SyntheticHeader, "true")
+		c.Next()
+	}
+}
+
+// technology is the production profile of a generation technology
+type technology struct {
+	// capacityFactor is the average share of the capacity produced
+	capacityFactor float64
+	// seasonality is the relative swing over the year and peak the day of the year it peaks on
+	seasonality float64
+	peak        int
+	// volatility is the relative day-to-day noise
+	volatility float64
+}
+
+// technologies maps technology codes to their profile; thermal plants follow
+// the dry season, when hydro produces least
+var technologies = map[string]technology{
+	"SOLAR":   {capacityFactor: 0.22, seasonality: 0.10, peak: 30, volatility: 0.15},
+	"WIND":    {capacityFactor: 0.38, seasonality: 0.30, peak: 200, volatility: 0.25},
+	"HYDRO":   {capacityFactor: 0.55, seasonality: 0.25, peak: 140, volatility: 0.05},
+	"BIOMASS": {capacityFactor: 0.60, seasonality: 0.05, peak: 180, volatility: 0.05},
+	"GAS":     {capacityFactor: 0.45, seasonality: 0.30, peak: 40, volatility: 0.10},
+	"COAL":    {capacityFactor: 0.65, seasonality: 0.20, peak: 40, volatility: 0.05},
+}
+
+// defaultTechnology applies to types without a known technology code
+var defaultTechnology = technology{capacityFactor: 0.5, seasonality: 0.1, peak: 180, volatility: 0.1}
+
+// fleet is the synthetic fleet seeded at startup
+var (
+	fleetTypes = []models.CreateTypeRequest{
+		{Name: "Solar", Description: "Solar photovoltaic parks", IsRenewable: true, TechnologyCode: "SOLAR"},
+		{Name: "Eólica", Description: "Onshore wind farms", IsRenewable: true, TechnologyCode: "WIND"},
+		{Name: "Hidráulica", Description: "Reservoir and run-of-river hydro plants", IsRenewable: true, TechnologyCode: "HYDRO"},
+		{Name: "Biomasa", Description: "Sugar cane bagasse cogeneration", IsRenewable: true, TechnologyCode: "BIOMASS"},
+		{Name: "Gas natural", Description: "Combined cycle gas turbines", IsRenewable: false, TechnologyCode: "GAS"},
+		{Name: "Carbón", Description: "Coal-fired thermal plants", IsRenewable: false, TechnologyCode: "COAL"},
+	}
+	fleetOperators = []models.CreateOperatorRequest{
+		{Name: "Andes Energía (demo)", Country: "CO"},
+		{Name: "Caribe Power (demo)", Country: "CO"},
+		{Name: "Pacífico Renovables (demo)", Country: "CO"},
+	}
+	// fleetGenerators are type index, operator index (-1 for none), capacity
+	// in MW and location
+	fleetGenerators = []struct {
+		typ, operator int
+		capacity      int64
+		lat, lng      float64
+	}{
+		{0, 2, 90, 10.4631, -73.2532}, {0, 2, 45, 4.1420, -73.6266}, {0, 1, 20, 10.9685, -74.7813},
+		{1, 1, 200, 11.7167, -72.2667}, {1, 2, 20, 12.2000, -71.9000},
+		{2, 0, 1200, 6.9033, -75.5770}, {2, 0, 560, 5.3295, -72.8733}, {2, 2, 85, 3.8801, -76.4870},
+		{3, -1, 40, 3.5394, -76.3036},
+		{4, 1, 450, 10.3910, -75.4794}, {4, 0, 300, 7.0653, -73.8547},
+		{5, 1, 250, 5.7081, -72.9314},
+	}
+)
+
+// Generator seeds and keeps generating synthetic production data
+type Generator struct {
+	repo database.Repository
+	cfg  *Config
+	rng  *rand.Rand
+	now  func() time.Time
+}
+
+// NewGenerator creates a new Generator writing through repo
+func NewGenerator(repo database.Repository, cfg *Config) *Generator {
+	return &Generator{
+		repo: repo,
+		cfg:  cfg,
+		rng:  rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)),
+		now:  time.Now,
+	}
+}
+
+// Seed creates the synthetic fleet and its daily production for the last
+// HistoryDays days and today
+func (g *Generator) Seed(ctx context.Context) error {
+	typeIDs := make([]uuid.UUID, len(fleetTypes))
+	for i := range fleetTypes {
+		t, err := g.repo.CreateType(ctx, &fleetTypes[i])
+		if err != nil {
+			return err
+		}
+		typeIDs[i] = t.ID
+	}
+	operatorIDs := make([]uuid.UUID, len(fleetOperators))
+	for i := range fleetOperators {
+		op, err := g.repo.CreateOperator(ctx, &fleetOperators[i])
+		if err != nil {
+			return err
+		}
+		operatorIDs[i] = op.ID
+	}
+	for _, spec := range fleetGenerators {
+		lat, lng := spec.lat, spec.lng
+		req := &models.CreateGeneratorRequest{TypeID: typeIDs[spec.typ], Capacity: decimal.NewFromInt(spec.capacity), Latitude: &lat, Longitude: &lng}
+		if spec.operator >= 0 {
+			req.OperatorID = &operatorIDs[spec.operator]
+		}
+		if _, err := g.repo.CreateGenerator(ctx, req); err != nil {
+			return err
+		}
+	}
+
+	generators, err := g.repo.GetAllGenerators(ctx, models.GeneratorFilter{})
+	if err != nil {
+		return err
+	}
+	today := g.today()
+	for day := today.AddDate(0, 0, -g.cfg.HistoryDays); !day.After(today); day = day.AddDate(0, 0, 1) {
+		for _, gen := range generators {
+			if err := g.write(ctx, gen, day); err != nil {
+				return err
+			}
+		}
+	}
+	utils.LogInfo(fmt.Sprintf("Demo mode: seeded %d generators with %d days of synthetic production", len(generators), g.cfg.HistoryDays+1))
+	return nil
+}
+
+// Run updates the production of today for every generator, including those
+// created through the API, every Interval until ctx is cancelled. After a day
+// change the new day starts from a fresh record.
+func (g *Generator) Run(ctx context.Context) {
+	if g.cfg.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(g.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := g.tick(ctx); err != nil {
+				utils.LogError("demo generator", err)
+			}
+		}
+	}
+}
+
+func (g *Generator) tick(ctx context.Context) error {
+	generators, err := g.repo.GetAllGenerators(ctx, models.GeneratorFilter{})
+	if err != nil {
+		return err
+	}
+	today := g.today()
+	for _, gen := range generators {
+		if err := g.write(ctx, gen, today); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write samples the production of gen on day and stores it, replacing the
+// record of that day when there is one
+func (g *Generator) write(ctx context.Context, gen *models.Generator, day time.Time) error {
+	profile := defaultTechnology
+	if t, err := g.repo.GetTypeByID(ctx, gen.TypeID); err == nil {
+		if p, ok := technologies[t.TechnologyCode]; ok {
+			profile = p
+		}
+	}
+	date := day.Format("2006-01-02")
+	value := decimal.NewFromFloat(g.sample(profile, gen.Capacity.InexactFloat64(), day)).Round(3)
+
+	existing, err := g.repo.GetAllProductions(ctx, &models.ProductionFilter{GeneratorID: &gen.ID, StartDate: &date, EndDate: &date})
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		_, err = g.repo.UpdateProduction(ctx, existing[0].ID, &models.UpdateProductionRequest{ProductionMW: &value})
+		return err
+	}
+	_, err = g.repo.CreateProduction(ctx, &models.CreateProductionRequest{
+		GeneratorID:  gen.ID,
+		Date:         date,
+		ProductionMW: value,
+		Source:       "external",
+		SourceRef:    sourceRef,
+	})
+	return err
+}
+
+// sample draws the average production in MW of a generator on day: its
+// capacity times the capacity factor of its technology, a seasonal swing and
+// noise, bounded by the capacity
+func (g *Generator) sample(profile technology, capacity float64, day time.Time) float64 {
+	season := 1 + profile.seasonality*math.Cos(2*math.Pi*float64(day.YearDay()-profile.peak)/365)
+	noise := 1 + profile.volatility*g.rng.NormFloat64()
+	return math.Min(capacity, math.Max(0, capacity*profile.capacityFactor*season*noise))
+}
+
+func (g *Generator) today() time.Time {
+	y, m, d := g.now().UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,157 @@
+// Package erd renders the introspected data model as an entity-relationship
+// diagram, in Mermaid (erDiagram) or Graphviz DOT syntax.
+package erd
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+)
+
+// Formats lists the diagram formats, sorted
+var Formats = []string{"dot", "mermaid"}
+
+// Render renders s in format, one of Formats
+func Render(s *models.DatabaseSchema, format string) (string, error) {
+	switch format {
+	case "mermaid":
+		return Mermaid(s), nil
+	case "dot":
+		return DOT(s), nil
+	}
+	return "", fmt.Errorf("unknown diagram format %q: must be one of %s", format, strings.Join(Formats, ", "))
+}
+
+// Mermaid renders s as a Mermaid erDiagram
+func Mermaid(s *models.DatabaseSchema) string {
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+	for _, t := range s.Tables {
+		keys := columnKeys(s, t)
+		fmt.Fprintf(&b, "    %s {\n", t.Name)
+		for _, c := range t.Columns {
+			fmt.Fprintf(&b, "        %s %s", mermaidType(c.Type), c.Name)
+			if k := keys[c.Name]; len(k) > 0 {
+				b.WriteString(" " + strings.Join(k, ", "))
+			}
+			if c.Comment != "" {
+				fmt.Fprintf(&b, " %q", strings.ReplaceAll(c.Comment, `"`, "'"))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("    }\n")
+	}
+	for _, r := range s.Relationships {
+		// The referenced row is optional when the key may be NULL, and there
+		// is at most one referencing row when the key is unique
+		parent, child := "||", "o{"
+		if nullable(s, r) {
+			parent = "|o"
+		}
+		if unique(s, r) {
+			child = "o|"
+		}
+		fmt.Fprintf(&b, "    %s %s--%s %s : %q\n", r.ReferencedTable, parent, child, r.Table, strings.Join(r.Columns, ", "))
+	}
+	return b.String()
+}
+
+// DOT renders s as a Graphviz digraph, one HTML-table node per table and an
+// edge from each foreign key to the table it references
+func DOT(s *models.DatabaseSchema) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", s.Schema)
+	b.WriteString("    rankdir=LR;\n")
+	b.WriteString("    node [shape=plain, fontname=\"Helvetica\", fontsize=10];\n")
+	b.WriteString("    edge [fontname=\"Helvetica\", fontsize=9];\n")
+	for _, t := range s.Tables {
+		keys := columnKeys(s, t)
+		fmt.Fprintf(&b, "    %q [label=<<table border=\"0\" cellborder=\"1\" cellspacing=\"0\" cellpadding=\"4\">\n", t.Name)
+		fmt.Fprintf(&b, "        <tr><td colspan=\"3\" bgcolor=\"#E6ECF4\"><b>%s</b></td></tr>\n", html.EscapeString(t.Name))
+		for _, c := range t.Columns {
+			name := html.EscapeString(c.Name)
+			if !c.Nullable {
+				name = "<b>" + name + "</b>"
+			}
+			fmt.Fprintf(&b, "        <tr><td align=\"left\" port=%q>%s</td><td align=\"left\">%s</td><td>%s</td></tr>\n",
+				c.Name, name, html.EscapeString(c.Type), strings.Join(keys[c.Name], ", "))
+		}
+		b.WriteString("    </table>>];\n")
+	}
+	for _, r := range s.Relationships {
+		fmt.Fprintf(&b, "    %q:%q -> %q:%q [label=%q];\n",
+			r.Table, r.Columns[0], r.ReferencedTable, r.ReferencedColumns[0], strings.Join(r.Columns, ", "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// columnKeys returns the key markers (PK, FK, UK) of the columns of t
+func columnKeys(s *models.DatabaseSchema, t *models.SchemaTable) map[string][]string {
+	keys := map[string][]string{}
+	for _, c := range t.PrimaryKey {
+		keys[c] = append(keys[c], "PK")
+	}
+	seen := map[string]bool{}
+	for _, r := range s.Relationships {
+		if r.Table != t.Name {
+			continue
+		}
+		for _, c := range r.Columns {
+			if !seen[c] {
+				seen[c] = true
+				keys[c] = append(keys[c], "FK")
+			}
+		}
+	}
+	for _, c := range t.Columns {
+		if c.Unique {
+			keys[c.Name] = append(keys[c.Name], "UK")
+		}
+	}
+	return keys
+}
+
+// column finds a column of the referencing table of r
+func column(s *models.DatabaseSchema, r *models.SchemaRelationship, name string) *models.SchemaColumn {
+	for _, t := range s.Tables {
+		if t.Name != r.Table {
+			continue
+		}
+		for _, c := range t.Columns {
+			if c.Name == name {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+func nullable(s *models.DatabaseSchema, r *models.SchemaRelationship) bool {
+	for _, name := range r.Columns {
+		if c := column(s, r, name); c != nil && c.Nullable {
+			return true
+		}
+	}
+	return false
+}
+
+func unique(s *models.DatabaseSchema, r *models.SchemaRelationship) bool {
+	if len(r.Columns) != 1 {
+		return false
+	}
+	c := column(s, r, r.Columns[0])
+	return c != nil && c.Unique
+}
+
+var nonWord = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// mermaidType turns a SQL type into a Mermaid attribute type, which must be a
+// single word: numeric(14,4) becomes numeric_14_4 and character varying(80)
+// character_varying_80
+func mermaidType(t string) string {
+	return strings.Trim(nonWord.ReplaceAllString(t, "_"), "_")
+}
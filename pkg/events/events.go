@@ -0,0 +1,192 @@
+// Package events is the in-process bus of domain events. The repository
+// publishes a typed event for every change it makes, e.g. ProductionCreated
+// or GeneratorDeleted, and the side effects of a change (dropping cached
+// analytics, pushing records to the live feeds, ...) subscribe to them
+// instead of being called by the request handlers. Unlike the event log of
+// the projections, these events are not stored: a subscriber only sees the
+// changes made by its own instance while it runs.
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// Event is a change made to the data, published once it is made
+type Event interface {
+	// Name identifies the kind of event, e.g. production.created
+	Name() string
+}
+
+// Handler receives the events of one change, e.g. the records of a bulk
+// create, in the order they were made
+type Handler func(ctx context.Context, events []Event)
+
+// Bus delivers the published events to its subscribers
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+// NewBus creates a new Bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe adds h to the handlers of every event published from now on
+func (b *Bus) Subscribe(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+// Publish hands events to the subscribers, one after the other, before it
+// returns: a request that changed data only answers once its side effects,
+// such as invalidating a cache, took place. Handlers must therefore not
+// block; slow work belongs in a goroutine of their own. ctx is that of the
+// change, without its cancellation, as the change is made. A handler that
+// panics is logged and does not affect the others.
+func (b *Bus) Publish(ctx context.Context, events ...Event) {
+	if len(events) == 0 {
+		return
+	}
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+	ctx = context.WithoutCancel(ctx)
+	for _, h := range handlers {
+		deliver(ctx, h, events)
+	}
+}
+
+func deliver(ctx context.Context, h Handler, events []Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			utils.LogError("events: "+events[0].Name(), fmt.Errorf("handler panicked: %v", r))
+		}
+	}()
+	h(ctx, events)
+}
+
+// ===================== Types =====================
+
+// TypeCreated is published when a generator type is created
+type TypeCreated struct{ Type *models.Type }
+
+// TypeUpdated is published when a generator type is updated
+type TypeUpdated struct{ Type *models.Type }
+
+// TypeDeleted is published when a generator type is moved to the trash
+type TypeDeleted struct{ ID uuid.UUID }
+
+// TypeMerged is published when a duplicate type is merged into another
+type TypeMerged struct{ Result *models.TypeMergeResult }
+
+// TypeTranslated is published when a translation of a type is set or deleted
+type TypeTranslated struct {
+	TypeID   uuid.UUID
+	Language string
+}
+
+func (TypeCreated) Name() string    { return "type.created" }
+func (TypeUpdated) Name() string    { return "type.updated" }
+func (TypeDeleted) Name() string    { return "type.deleted" }
+func (TypeMerged) Name() string     { return "type.merged" }
+func (TypeTranslated) Name() string { return "type.translated" }
+
+// ===================== Operators =====================
+
+// OperatorCreated is published when an operator is created
+type OperatorCreated struct{ Operator *models.Operator }
+
+// OperatorUpdated is published when an operator is updated
+type OperatorUpdated struct{ Operator *models.Operator }
+
+// OperatorDeleted is published when an operator is deleted
+type OperatorDeleted struct{ ID uuid.UUID }
+
+func (OperatorCreated) Name() string { return "operator.created" }
+func (OperatorUpdated) Name() string { return "operator.updated" }
+func (OperatorDeleted) Name() string { return "operator.deleted" }
+
+// ===================== Plants =====================
+
+// PlantCreated is published when a plant is created
+type PlantCreated struct{ Plant *models.Plant }
+
+// PlantUpdated is published when a plant is updated
+type PlantUpdated struct{ Plant *models.Plant }
+
+// PlantDeleted is published when a plant is deleted
+type PlantDeleted struct{ ID uuid.UUID }
+
+func (PlantCreated) Name() string { return "plant.created" }
+func (PlantUpdated) Name() string { return "plant.updated" }
+func (PlantDeleted) Name() string { return "plant.deleted" }
+
+// ===================== Regions =====================
+
+// RegionCreated is published when a region is created
+type RegionCreated struct{ Region *models.Region }
+
+// RegionUpdated is published when a region is updated
+type RegionUpdated struct{ Region *models.Region }
+
+// RegionDeleted is published when a region is deleted
+type RegionDeleted struct{ ID uuid.UUID }
+
+func (RegionCreated) Name() string { return "region.created" }
+func (RegionUpdated) Name() string { return "region.updated" }
+func (RegionDeleted) Name() string { return "region.deleted" }
+
+// ===================== Generators =====================
+
+// GeneratorCreated is published when a generator is created
+type GeneratorCreated struct{ Generator *models.Generator }
+
+// GeneratorUpdated is published when a generator is updated
+type GeneratorUpdated struct{ Generator *models.Generator }
+
+// GeneratorDeleted is published when a generator is moved to the trash
+type GeneratorDeleted struct{ ID uuid.UUID }
+
+func (GeneratorCreated) Name() string { return "generator.created" }
+func (GeneratorUpdated) Name() string { return "generator.updated" }
+func (GeneratorDeleted) Name() string { return "generator.deleted" }
+
+// ===================== Productions =====================
+
+// ProductionCreated is published when a production record is created
+type ProductionCreated struct{ Production *models.Production }
+
+// ProductionUpdated is published when a production record is updated,
+// including by an upsert or a telemetry reading of its day
+type ProductionUpdated struct{ Production *models.Production }
+
+// ProductionDeleted is published when a production record is moved to the trash
+type ProductionDeleted struct{ ID uuid.UUID }
+
+// ProductionCorrected is published when a correction is applied to a
+// production record
+type ProductionCorrected struct{ Correction *models.ProductionCorrection }
+
+// RecalculationApplied is published when production records are recalculated
+type RecalculationApplied struct{ Recalculation *models.Recalculation }
+
+func (ProductionCreated) Name() string    { return "production.created" }
+func (ProductionUpdated) Name() string    { return "production.updated" }
+func (ProductionDeleted) Name() string    { return "production.deleted" }
+func (ProductionCorrected) Name() string  { return "production.corrected" }
+func (RecalculationApplied) Name() string { return "recalculation.applied" }
+
+// ===================== Trash =====================
+
+// TrashItemRestored is published when a deleted resource is restored
+type TrashItemRestored struct{ Item *models.TrashItem }
+
+func (TrashItemRestored) Name() string { return "trash.restored" }
@@ -0,0 +1,96 @@
+// Package export writes tabular datasets as downloadable files. Writers
+// stream: rows are encoded as they are written, so an export of any size is
+// never held in memory.
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Supported formats
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Formats lists the supported formats
+var Formats = []string{FormatCSV, FormatXLSX}
+
+// Writer encodes rows of a table. Cells are strings, numbers (int, float64,
+// decimal.Decimal), bools, times or nil for an empty cell.
+type Writer interface {
+	// WriteRow encodes one row; the first row is the header
+	WriteRow(cells []any) error
+	// Flush writes the buffered rows to the underlying writer
+	Flush() error
+	// Close finishes the file; it does not close the underlying writer
+	Close() error
+}
+
+// NewWriter creates a writer of the format, which must be one of Formats
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// ContentType returns the media type of the format
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+type csvWriter struct {
+	w   *csv.Writer
+	row []string
+}
+
+func (w *csvWriter) WriteRow(cells []any) error {
+	w.row = w.row[:0]
+	for _, cell := range cells {
+		w.row = append(w.row, text(cell))
+	}
+	return w.w.Write(w.row)
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func (w *csvWriter) Close() error {
+	return w.Flush()
+}
+
+// text formats a cell as a string
+func text(cell any) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case decimal.Decimal:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprint(cell)
+}
@@ -0,0 +1,274 @@
+package freshness
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	// Embedded zone database so FRESHNESS_TIMEZONE resolves in minimal containers
+	_ "time/tzdata"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/calendar"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// Config represents the freshness thresholds and alerting settings
+type Config struct {
+	// MaxLagDays is the number of days data may lag behind today before it is stale
+	MaxLagDays int
+	// Location is the timezone "today" is taken in
+	Location *time.Location
+	// Calendar is the code of the holiday calendar lags are counted in
+	// business days of; empty counts every day
+	Calendar      string
+	AlertInterval time.Duration
+	AlertCooldown time.Duration
+}
+
+// LoadConfig loads freshness configuration from environment variables
+func LoadConfig() *Config {
+	cfg := &Config{
+		MaxLagDays:    utils.GetEnvAsInt("FRESHNESS_MAX_LAG_DAYS", 2),
+		Location:      time.UTC,
+		Calendar:      utils.GetEnv("FRESHNESS_CALENDAR", ""),
+		AlertInterval: utils.GetEnvAsDuration("FRESHNESS_ALERT_INTERVAL", time.Hour),
+		AlertCooldown: utils.GetEnvAsDuration("FRESHNESS_ALERT_COOLDOWN", 24*time.Hour),
+	}
+	if name := utils.GetEnv("FRESHNESS_TIMEZONE", ""); name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			cfg.Location = loc
+		} else {
+			utils.LogError("freshness: FRESHNESS_TIMEZONE", err)
+		}
+	}
+	if cfg.MaxLagDays < 0 {
+		cfg.MaxLagDays = 2
+	}
+	return cfg
+}
+
+// Alert is emitted when the data, or the data of some generators, becomes stale
+type Alert struct {
+	// Overall is set when the most recent data of any generator is stale
+	Overall    bool                         `json:"overall"`
+	LagDays    *int                         `json:"lagDays"`
+	Generators []*models.GeneratorFreshness `json:"generators"`
+	RaisedAt   time.Time                    `json:"raisedAt"`
+}
+
+// Notifier delivers freshness alerts
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// LogNotifier writes alerts to the server log
+type LogNotifier struct{}
+
+// Notify implements Notifier
+func (LogNotifier) Notify(_ context.Context, a Alert) error {
+	if a.Overall {
+		lag := "no production data"
+		if a.LagDays != nil {
+			lag = fmt.Sprintf("latest data is %d days old", *a.LagDays)
+		}
+		utils.LogInfo("Production data is stale: " + lag)
+	}
+	if len(a.Generators) > 0 {
+		ids := make([]string, len(a.Generators))
+		for i, g := range a.Generators {
+			ids[i] = fmt.Sprintf("%s (%d days)", g.GeneratorID, g.LagDays)
+		}
+		utils.LogInfo(fmt.Sprintf("%d generators have stale data: %s", len(ids), strings.Join(ids, ", ")))
+	}
+	return nil
+}
+
+// Monitor computes data freshness and alerts when it exceeds the threshold
+type Monitor struct {
+	repo      database.Repository
+	cfg       *Config
+	calendars *calendar.Service
+	notifier  Notifier
+	now       func() time.Time
+
+	mu      sync.Mutex
+	alerted map[string]time.Time
+}
+
+// NewMonitor creates a new Monitor; alerts are delivered to notifier and
+// cfg.Calendar is resolved with calendars
+func NewMonitor(repo database.Repository, cfg *Config, calendars *calendar.Service, notifier Notifier) *Monitor {
+	if notifier == nil {
+		notifier = LogNotifier{}
+	}
+	return &Monitor{
+		repo:      repo,
+		cfg:       cfg,
+		calendars: calendars,
+		notifier:  notifier,
+		now:       time.Now,
+		alerted:   map[string]time.Time{},
+	}
+}
+
+// Config returns the monitor configuration
+func (m *Monitor) Config() *Config {
+	return m.cfg
+}
+
+// Report computes the freshness of the data against today; maxLagDays
+// overrides the configured threshold when not negative
+func (m *Monitor) Report(ctx context.Context, maxLagDays int) (*models.Freshness, error) {
+	if maxLagDays < 0 {
+		maxLagDays = m.cfg.MaxLagDays
+	}
+	latest, err := m.repo.GetLatestProductionDates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lagDays := daysBetween
+	var cal *calendar.Calendar
+	if m.cfg.Calendar != "" {
+		if cal, err = m.calendars.Calendar(ctx, m.cfg.Calendar); err != nil {
+			return nil, err
+		}
+		lagDays = func(from, to string) int { return businessDaysBetween(cal, from, to) }
+	}
+
+	now := m.now()
+	today := now.In(m.cfg.Location).Format("2006-01-02")
+	f := &models.Freshness{
+		Today:          today,
+		MaxLagDays:     maxLagDays,
+		GeneratorCount: len(latest),
+		Generators:     make([]*models.GeneratorFreshness, 0, len(latest)),
+		CheckedAt:      now.UTC(),
+	}
+	if cal != nil {
+		f.Calendar = cal.Country
+	}
+	for _, l := range latest {
+		since := l.CreatedOn
+		if l.LatestDate != nil {
+			since = *l.LatestDate
+			if f.LatestDate == nil || *l.LatestDate > *f.LatestDate {
+				f.LatestDate = l.LatestDate
+			}
+		}
+		g := &models.GeneratorFreshness{
+			GeneratorLatestProduction: *l,
+			LagDays:                   lagDays(since, today),
+			AllowedLagDays:            allowedLag(l.Cadence, maxLagDays),
+		}
+		g.Stale = g.LagDays > g.AllowedLagDays
+		if g.Stale {
+			f.StaleGenerators++
+		}
+		f.Generators = append(f.Generators, g)
+	}
+	if f.LatestDate != nil {
+		lag := lagDays(*f.LatestDate, today)
+		f.LagDays = &lag
+		f.Stale = lag > maxLagDays
+	} else {
+		f.Stale = len(latest) > 0
+	}
+	return f, nil
+}
+
+// allowedLag is how many days a generator may go without data: the days
+// between its expected submissions beyond the first, plus maxLagDays
+func allowedLag(cadence string, maxLagDays int) int {
+	days, ok := models.CadenceDays[cadence]
+	if !ok {
+		days = 1
+	}
+	return days - 1 + maxLagDays
+}
+
+// daysBetween counts the days from one YYYY-MM-DD date to another; unparsable dates count as 0
+func daysBetween(from, to string) int {
+	a, errA := time.Parse("2006-01-02", from)
+	b, errB := time.Parse("2006-01-02", to)
+	if errA != nil || errB != nil {
+		return 0
+	}
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// businessDaysBetween counts the business days of cal after one YYYY-MM-DD
+// date up to another; unparsable dates count as 0
+func businessDaysBetween(cal *calendar.Calendar, from, to string) int {
+	a, errA := time.Parse("2006-01-02", from)
+	b, errB := time.Parse("2006-01-02", to)
+	if errA != nil || errB != nil {
+		return 0
+	}
+	return cal.BusinessDaysBetween(a, b)
+}
+
+// Run checks freshness every AlertInterval and notifies what became stale,
+// at most once per AlertCooldown for the overall data and each generator,
+// until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context) {
+	if m.cfg.AlertInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(m.cfg.AlertInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.checkAlerts(ctx)
+		}
+	}
+}
+
+func (m *Monitor) checkAlerts(ctx context.Context) {
+	f, err := m.Report(ctx, -1)
+	if err != nil {
+		utils.LogError("freshness check", err)
+		return
+	}
+
+	now := m.now()
+	alert := Alert{LagDays: f.LagDays, RaisedAt: now}
+	m.mu.Lock()
+	due := func(key string) bool {
+		if last, seen := m.alerted[key]; seen && now.Sub(last) < m.cfg.AlertCooldown {
+			return false
+		}
+		m.alerted[key] = now
+		return true
+	}
+	if f.Stale {
+		alert.Overall = due("overall")
+	} else {
+		delete(m.alerted, "overall")
+	}
+	for _, g := range f.Generators {
+		key := g.GeneratorID.String()
+		if !g.Stale {
+			// Fresh again: a later lapse alerts right away
+			delete(m.alerted, key)
+			continue
+		}
+		if due(key) {
+			alert.Generators = append(alert.Generators, g)
+		}
+	}
+	m.mu.Unlock()
+
+	if !alert.Overall && len(alert.Generators) == 0 {
+		return
+	}
+	if err := m.notifier.Notify(ctx, alert); err != nil {
+		utils.LogError("freshness alert", err)
+	}
+}
@@ -0,0 +1,164 @@
+// Package geo holds the coordinates of generators, the great-circle
+// distance between them and the GeoJSON (RFC 7946) documents maps are
+// drawn from. Distances use the haversine formula on a spherical Earth,
+// which is within 0.5% of the ellipsoid at the scale of a national fleet.
+package geo
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// EarthRadiusKm is the mean Earth radius
+const EarthRadiusKm = 6371.0088
+
+// Point is a WGS 84 position
+type Point struct {
+	Lat float64
+	Lng float64
+}
+
+// BBox is a longitude/latitude bounding box; boxes crossing the antimeridian are not supported
+type BBox struct {
+	MinLng float64
+	MinLat float64
+	MaxLng float64
+	MaxLat float64
+}
+
+// ParsePoint parses "lat,lng"
+func ParsePoint(s string) (Point, error) {
+	v, err := parseFloats(s, 2)
+	if err != nil {
+		return Point{}, errors.New("must be lat,lng")
+	}
+	p := Point{Lat: v[0], Lng: v[1]}
+	if !ValidLat(p.Lat) || !ValidLng(p.Lng) {
+		return Point{}, errors.New("must be lat,lng with lat between -90 and 90 and lng between -180 and 180")
+	}
+	return p, nil
+}
+
+// ParseBBox parses "minLng,minLat,maxLng,maxLat", the order of the GeoJSON bbox member
+func ParseBBox(s string) (BBox, error) {
+	v, err := parseFloats(s, 4)
+	if err != nil {
+		return BBox{}, errors.New("must be minLng,minLat,maxLng,maxLat")
+	}
+	return NewBBox(v)
+}
+
+// NewBBox returns the box of a GeoJSON bbox member, [minLng, minLat, maxLng, maxLat]
+func NewBBox(v []float64) (BBox, error) {
+	if len(v) != 4 {
+		return BBox{}, errors.New("must be minLng,minLat,maxLng,maxLat")
+	}
+	b := BBox{MinLng: v[0], MinLat: v[1], MaxLng: v[2], MaxLat: v[3]}
+	switch {
+	case !ValidLng(b.MinLng) || !ValidLng(b.MaxLng) || !ValidLat(b.MinLat) || !ValidLat(b.MaxLat):
+		return BBox{}, errors.New("must be minLng,minLat,maxLng,maxLat within -180..180 and -90..90")
+	case b.MinLng > b.MaxLng || b.MinLat > b.MaxLat:
+		return BBox{}, errors.New("minimums must not exceed maximums")
+	}
+	return b, nil
+}
+
+func parseFloats(s string, n int) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, errors.New("wrong number of values")
+	}
+	v := make([]float64, n)
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, errors.New("not a number")
+		}
+		v[i] = f
+	}
+	return v, nil
+}
+
+// ValidLat reports whether lat is a latitude
+func ValidLat(lat float64) bool {
+	return lat >= -90 && lat <= 90
+}
+
+// ValidLng reports whether lng is a longitude
+func ValidLng(lng float64) bool {
+	return lng >= -180 && lng <= 180
+}
+
+// DistanceKm is the great-circle distance between a and b
+func DistanceKm(a, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat, dLng := lat2-lat1, radians(b.Lng-a.Lng)
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLng/2), 2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Contains reports whether p is inside b, edges included
+func (b BBox) Contains(p Point) bool {
+	return p.Lng >= b.MinLng && p.Lng <= b.MaxLng && p.Lat >= b.MinLat && p.Lat <= b.MaxLat
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+// MaxZoom is the deepest web map zoom level
+const MaxZoom = 22
+
+// GridCell returns the cell of p in a grid of cellsPerTile x cellsPerTile
+// cells per web map tile (Web Mercator) at zoom, so points drawn closer
+// than about 256/cellsPerTile pixels share a cell
+func GridCell(p Point, zoom, cellsPerTile int) (x, y int) {
+	n := float64(int(1)<<zoom) * float64(cellsPerTile)
+	// Web Mercator is undefined at the poles
+	lat := math.Max(-85.05112878, math.Min(85.05112878, p.Lat))
+	fx := (p.Lng + 180) / 360 * n
+	fy := (1 - math.Log(math.Tan(radians(lat))+1/math.Cos(radians(lat)))/math.Pi) / 2 * n
+	last := int(n) - 1
+	return min(last, max(0, int(math.Floor(fx)))), min(last, max(0, int(math.Floor(fy))))
+}
+
+// ContentType is the media type of GeoJSON documents
+const ContentType = "application/geo+json"
+
+// FeatureCollection is a GeoJSON feature collection
+// @Description GeoJSON FeatureCollection (RFC 7946)
+type FeatureCollection struct {
+	Type     string     `json:"type" example:"FeatureCollection"`
+	Features []*Feature `json:"features"`
+}
+
+// Feature is a GeoJSON feature
+// @Description GeoJSON Feature with its properties
+type Feature struct {
+	Type       string         `json:"type" example:"Feature"`
+	ID         string         `json:"id,omitempty" example:"550e8400-e29b-41d4-a716-446655440001"`
+	Geometry   *Geometry      `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+// Geometry is a GeoJSON geometry; Coordinates nest according to Type
+// @Description GeoJSON geometry
+type Geometry struct {
+	Type        string `json:"type" example:"Point"`
+	Coordinates any    `json:"coordinates" swaggertype:"array,number"`
+}
+
+// NewFeatureCollection returns a collection of features, empty rather than null
+func NewFeatureCollection(features []*Feature) *FeatureCollection {
+	if features == nil {
+		features = []*Feature{}
+	}
+	return &FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+// PointGeometry returns the GeoJSON geometry of p, which lists longitude first
+func PointGeometry(p Point) *Geometry {
+	return &Geometry{Type: "Point", Coordinates: []float64{p.Lng, p.Lat}}
+}
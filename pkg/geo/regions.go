@@ -0,0 +1,188 @@
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// Config represents the geographic reference data settings
+type Config struct {
+	// RegionsFile is a GeoJSON FeatureCollection of region polygons; empty disables regions
+	RegionsFile string
+}
+
+// LoadConfig loads geographic configuration from environment variables
+func LoadConfig() *Config {
+	return &Config{
+		RegionsFile: utils.GetEnv("REGIONS_GEOJSON_FILE", ""),
+	}
+}
+
+// Region is a named area of the regions file
+type Region struct {
+	ID   string
+	Name string
+	// Geometry and Properties are kept as read, to be served back
+	Geometry   *Geometry
+	Properties map[string]any
+
+	// polygons are rings of [lng, lat] positions; the first ring of each is
+	// the outer boundary and the others holes
+	polygons [][][][2]float64
+}
+
+// Regions are the regions of the regions file, in file order
+type Regions struct {
+	list []*Region
+}
+
+// regionFeature is a feature of the regions file
+type regionFeature struct {
+	ID       any `json:"id"`
+	Geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+// LoadRegions reads the regions of cfg.RegionsFile; without a file there are no regions
+func LoadRegions(cfg *Config) (*Regions, error) {
+	if cfg.RegionsFile == "" {
+		return &Regions{}, nil
+	}
+	data, err := os.ReadFile(cfg.RegionsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read regions: %w", err)
+	}
+	var fc struct {
+		Type     string           `json:"type"`
+		Features []*regionFeature `json:"features"`
+	}
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return nil, fmt.Errorf("failed to parse regions: %w", err)
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, errors.New("regions file must be a GeoJSON FeatureCollection")
+	}
+	regions := &Regions{}
+	ids := map[string]bool{}
+	for i, f := range fc.Features {
+		r, err := newRegion(f)
+		if err != nil {
+			return nil, fmt.Errorf("region %d: %w", i, err)
+		}
+		if ids[r.ID] {
+			return nil, fmt.Errorf("region %d: duplicate id %s", i, r.ID)
+		}
+		ids[r.ID] = true
+		regions.list = append(regions.list, r)
+	}
+	return regions, nil
+}
+
+func newRegion(f *regionFeature) (*Region, error) {
+	r := &Region{Properties: f.Properties}
+	if r.Properties == nil {
+		r.Properties = map[string]any{}
+	}
+	// The id is the feature id, else an id or code property
+	for _, v := range []any{f.ID, r.Properties["id"], r.Properties["code"]} {
+		if v != nil && fmt.Sprint(v) != "" {
+			r.ID = fmt.Sprint(v)
+			break
+		}
+	}
+	if r.ID == "" {
+		return nil, errors.New("feature needs an id, or an id or code property")
+	}
+	r.Name, _ = r.Properties["name"].(string)
+	if r.Name == "" {
+		r.Name = r.ID
+	}
+
+	switch f.Geometry.Type {
+	case "Polygon":
+		var rings [][][2]float64
+		if err := json.Unmarshal(f.Geometry.Coordinates, &rings); err != nil {
+			return nil, fmt.Errorf("invalid polygon: %w", err)
+		}
+		r.polygons = [][][][2]float64{rings}
+	case "MultiPolygon":
+		if err := json.Unmarshal(f.Geometry.Coordinates, &r.polygons); err != nil {
+			return nil, fmt.Errorf("invalid multipolygon: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("geometry must be a Polygon or MultiPolygon, not %q", f.Geometry.Type)
+	}
+	for _, poly := range r.polygons {
+		if len(poly) == 0 || len(poly[0]) < 4 {
+			return nil, errors.New("polygon rings need at least four positions")
+		}
+	}
+	r.Geometry = &Geometry{Type: f.Geometry.Type, Coordinates: f.Geometry.Coordinates}
+	return r, nil
+}
+
+// List returns the regions in file order
+func (rs *Regions) List() []*Region {
+	return rs.list
+}
+
+// Get returns the region with the id; nil when there is none
+func (rs *Regions) Get(id string) *Region {
+	for _, r := range rs.list {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// Locate returns the first region containing p; nil when p is in none
+func (rs *Regions) Locate(p Point) *Region {
+	for _, r := range rs.list {
+		if r.Contains(p) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Contains reports whether p is inside the region and outside its holes
+func (r *Region) Contains(p Point) bool {
+	for _, poly := range r.polygons {
+		if !inRing(poly[0], p) {
+			continue
+		}
+		inHole := false
+		for _, hole := range poly[1:] {
+			if inRing(hole, p) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// inRing casts a ray from p towards increasing longitude and counts the
+// edges of ring it crosses (even-odd rule)
+func inRing(ring [][2]float64, p Point) bool {
+	in := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > p.Lat) != (yj > p.Lat) && p.Lng < (xj-xi)*(p.Lat-yi)/(yj-yi)+xi {
+			in = !in
+		}
+	}
+	return in
+}
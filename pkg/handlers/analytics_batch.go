@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// analyticsBatchWorkers is how many queries of a batch run at once, so one
+// batch takes no more than a few pool connections
+const analyticsBatchWorkers = 4
+
+// QueryAnalytics handles POST /analytics/query
+// @Summary Several analytics in one request
+// @Description Runs up to 20 analytics queries (totalProduction, timeseries, mix, topGenerators, renewableSummary, marketShare, mixByRegion) concurrently and returns their results in the order of the queries, so a dashboard loads in one round trip. Each query takes the parameters of its GET endpoint; startDate and endDate of the body apply to the queries without their own. topGenerators lists the generators with the highest production, 10 by default. A failed query reports its status and error in its result without failing the others
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Param request body models.AnalyticsBatchRequest true "Queries"
+// @Success 200 {object} models.AnalyticsBatch
+// @Failure 400 {object} models.ErrorResponse
+// @Router /analytics/query [post]
+func (h *AnalyticsHandler) QueryAnalytics(c *gin.Context) {
+	var req models.AnalyticsBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+	results := make([]*models.AnalyticsResult, len(req.Queries))
+	slots := make(chan struct{}, analyticsBatchWorkers)
+	var wg sync.WaitGroup
+	for i, q := range req.Queries {
+		if q.StartDate == nil && q.EndDate == nil {
+			q.StartDate, q.EndDate = req.StartDate, req.EndDate
+		}
+		if q.ID == "" {
+			q.ID = strconv.Itoa(i)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = h.runAnalyticsQuery(ctx, q)
+		}()
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, &models.AnalyticsBatch{Results: results})
+}
+
+// runAnalyticsQuery answers one query of a batch with the data or the error
+// its GET endpoint would have answered
+func (h *AnalyticsHandler) runAnalyticsQuery(ctx context.Context, q *models.AnalyticsQuery) *models.AnalyticsResult {
+	res := &models.AnalyticsResult{ID: q.ID, Type: q.Type, Status: http.StatusOK}
+	fail := func(status int, msg string) *models.AnalyticsResult {
+		res.Status, res.Error = status, msg
+		return res
+	}
+	// Dates in DateLayout order lexically
+	if q.StartDate != nil && q.EndDate != nil && *q.EndDate < *q.StartDate {
+		return fail(http.StatusBadRequest, "Invalid endDate: must not be before startDate")
+	}
+
+	var err error
+	switch q.Type {
+	case models.AnalyticsTotalProduction:
+		var list []*models.TotalProductionByDate
+		if list, err = h.repo.GetTotalProductionByDate(ctx, q.StartDate, q.EndDate, nil); err == nil {
+			res.Data = nonNil(list)
+		}
+	case models.AnalyticsTimeSeries:
+		ts := &models.TimeSeriesQuery{GroupBy: models.BucketDay, Metric: models.TimeSeriesTotal, StartDate: q.StartDate, EndDate: q.EndDate}
+		if q.GroupBy != "" {
+			ts.GroupBy = q.GroupBy
+		}
+		if q.Metric != "" {
+			ts.Metric = q.Metric
+		}
+		res.Data, err = h.repo.GetTimeSeries(ctx, ts)
+		if errors.Is(err, database.ErrInvalidTimeSeries) {
+			return fail(http.StatusBadRequest, "Invalid time series: "+strings.TrimPrefix(err.Error(), database.ErrInvalidTimeSeries.Error()+": "))
+		}
+	case models.AnalyticsMix:
+		date := time.Now().UTC().Format(httpx.DateLayout)
+		if q.Date != nil {
+			date = *q.Date
+		}
+		res.Data, err = h.repo.GetEnergyMix(ctx, date)
+	case models.AnalyticsTopGenerators:
+		var list []*models.GeneratorEfficiency
+		if list, err = h.repo.GetGeneratorEfficiency(ctx, q.StartDate, q.EndDate); err == nil {
+			res.Data = topGenerators(list, q.Limit)
+		}
+	case models.AnalyticsRenewableSummary:
+		var list []*models.RenewableSummary
+		if list, err = h.repo.GetRenewableSummary(ctx, q.StartDate, q.EndDate); err == nil {
+			res.Data = nonNil(list)
+		}
+	case models.AnalyticsMarketShare:
+		var list []*models.OperatorMarketShare
+		if list, err = h.repo.GetMarketShareByOperator(ctx, q.StartDate, q.EndDate, nil); err == nil {
+			res.Data = nonNil(list)
+		}
+	case models.AnalyticsMixByRegion:
+		var list []*models.RegionMix
+		if list, err = h.repo.GetMixByRegion(ctx, q.StartDate, q.EndDate); err == nil {
+			res.Data = nonNil(list)
+		}
+	}
+	if err != nil {
+		res.Data = nil
+		return fail(http.StatusInternalServerError, "Failed to get "+q.Type+": "+err.Error())
+	}
+	return res
+}
+
+// topGenerators keeps the limit generators with the highest production,
+// 10 without limit
+func topGenerators(list []*models.GeneratorEfficiency, limit int) []*models.GeneratorEfficiency {
+	if limit <= 0 {
+		limit = 10
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].TotalProduction.GreaterThan(list[j].TotalProduction)
+	})
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return nonNil(list)
+}
@@ -0,0 +1,490 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/calendar"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/geo"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/pb"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// AnalyticsHandler handles HTTP requests for aggregated reports
+type AnalyticsHandler struct {
+	repo      database.Repository
+	regions   *geo.Regions
+	calendars *calendar.Service
+}
+
+// NewAnalyticsHandler creates a new AnalyticsHandler instance
+func NewAnalyticsHandler(repo database.Repository, regions *geo.Regions, calendars *calendar.Service) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		repo:      repo,
+		regions:   regions,
+		calendars: calendars,
+	}
+}
+
+// GetTotalProduction handles GET /analytics/total-production
+// @Summary Total production by date
+// @Description Daily production totals with renewable / non-renewable breakdown, optionally limited to a date range (YYYY-MM-DD). With asOf the totals are the ones seen at that moment: later records and generators are left out and corrected values revert to what they were
+// @Tags analytics
+// @Produce json,application/x-protobuf,application/msgpack
+// @Param startDate query string false "Start date (YYYY-MM-DD)"
+// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Param asOf query string false "Restate the data to this moment (RFC 3339), e.g. when a bulletin was published"
+// @Success 200 {array} models.TotalProductionByDate
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /analytics/total-production [get]
+func (h *AnalyticsHandler) GetTotalProduction(c *gin.Context) {
+	start, end := dateRangeParams(c)
+	asOf, ok := asOfParam(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.repo.GetTotalProductionByDate(c.Request.Context(), start, end, asOf)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get total production: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.TotalProductionByDate{}
+	}
+
+	respond(c, http.StatusOK, list, func() []byte { return pb.TotalProductionSeries(list) })
+}
+
+// GetMarketShare handles GET /analytics/market-share
+// @Summary Market share by operator
+// @Description Capacity and production per operator with their share of the total, optionally limiting production to a date range (YYYY-MM-DD). Generators without operator are reported as "Unassigned". asOf restates the shares to a past moment
+// @Tags analytics
+// @Produce json
+// @Param startDate query string false "Start date (YYYY-MM-DD)"
+// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Param asOf query string false "Restate the data to this moment (RFC 3339), e.g. when a bulletin was published"
+// @Success 200 {array} models.OperatorMarketShare
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /analytics/market-share [get]
+func (h *AnalyticsHandler) GetMarketShare(c *gin.Context) {
+	start, end := dateRangeParams(c)
+	asOf, ok := asOfParam(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.repo.GetMarketShareByOperator(c.Request.Context(), start, end, asOf)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get market share: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.OperatorMarketShare{}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetGeneratorEfficiency handles GET /analytics/generator-efficiency
+// @Summary Generator efficiency
+// @Description Total production of every generator, optionally limited to a date range (YYYY-MM-DD), with its average per day with records and its capacity factor: that average as a percentage of the capacity. Generators without records are listed with zeros. Highest capacity factor first
+// @Tags analytics
+// @Produce json
+// @Param startDate query string false "Start date (YYYY-MM-DD)"
+// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Success 200 {array} models.GeneratorEfficiency
+// @Failure 500 {object} models.ErrorResponse
+// @Router /analytics/generator-efficiency [get]
+func (h *AnalyticsHandler) GetGeneratorEfficiency(c *gin.Context) {
+	start, end := dateRangeParams(c)
+
+	list, err := h.repo.GetGeneratorEfficiency(c.Request.Context(), start, end)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get generator efficiency: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.GeneratorEfficiency{}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetRenewableSummary handles GET /analytics/renewable-summary
+// @Summary Renewable vs non-renewable summary
+// @Description Capacity, generator count and production of the renewable and non-renewable generators, with the average production per record and each class's percentage of the total production. startDate/endDate (YYYY-MM-DD) limit production; capacity and count cover the whole fleet
+// @Tags analytics
+// @Produce json
+// @Param startDate query string false "Start date (YYYY-MM-DD)"
+// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Success 200 {array} models.RenewableSummary
+// @Failure 500 {object} models.ErrorResponse
+// @Router /analytics/renewable-summary [get]
+func (h *AnalyticsHandler) GetRenewableSummary(c *gin.Context) {
+	start, end := dateRangeParams(c)
+
+	list, err := h.repo.GetRenewableSummary(c.Request.Context(), start, end)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get renewable summary: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.RenewableSummary{}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetCrosstab handles GET /analytics/crosstab
+// @Summary Production cross-tab (energy matrix)
+// @Description Matrix of aggregated production with one dimension on the rows and another on the columns, plus row, column and grand totals. Dimensions: type, technology, renewable, operator, generator, source, year, month, day. Values: sum, avg, min, max, count, or a metric expression such as sum(productionMw)/sum(capacity) over the fields productionMw and capacity with the functions sum, avg, min, max, count, numbers, + - * / and parentheses
+// @Tags analytics
+// @Produce json
+// @Param rows query string false "Row dimension" default(type)
+// @Param cols query string false "Column dimension" default(month)
+// @Param value query string false "Aggregate of productionMw" default(sum)
+// @Param metric query string false "Metric expression, replaces value (e.g. sum(productionMw)/sum(capacity))"
+// @Param startDate query string false "Start date (YYYY-MM-DD)"
+// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Param asOf query string false "Restate the data to this moment (RFC 3339), e.g. when a bulletin was published"
+// @Success 200 {object} models.Crosstab
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /analytics/crosstab [get]
+func (h *AnalyticsHandler) GetCrosstab(c *gin.Context) {
+	start, end := dateRangeParams(c)
+	asOf, ok := asOfParam(c)
+	if !ok {
+		return
+	}
+	q := &models.CrosstabQuery{
+		Rows:      c.DefaultQuery("rows", "type"),
+		Columns:   c.DefaultQuery("cols", "month"),
+		Value:     c.DefaultQuery("value", "sum"),
+		Metric:    c.Query("metric"),
+		StartDate: start,
+		EndDate:   end,
+		AsOf:      asOf,
+	}
+
+	ct, err := h.repo.GetCrosstab(c.Request.Context(), q)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidCrosstab) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid crosstab: "+strings.TrimPrefix(err.Error(), database.ErrInvalidCrosstab.Error()+": "))
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get crosstab: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, ct)
+}
+
+// GetTimeSeries handles GET /analytics/timeseries
+// @Summary Production time series
+// @Description Production summed per day, week (from Monday), month or year with date_trunc, for charting. metric=total gives one series, renewable a renewable and a non-renewable series, byType one series per generator type with records. Buckets run without gaps from the start date (or the first record) to the end date (or the last record); buckets without records are 0. At most 5000 buckets
+// @Tags analytics
+// @Produce json
+// @Param groupBy query string false "Bucket size (day, week, month, year)" default(day)
+// @Param metric query string false "Series (total, renewable, byType)" default(total)
+// @Param startDate query string false "Start date (YYYY-MM-DD)"
+// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Param asOf query string false "Restate the data to this moment (RFC 3339), e.g. when a bulletin was published"
+// @Success 200 {object} models.TimeSeries
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /analytics/timeseries [get]
+func (h *AnalyticsHandler) GetTimeSeries(c *gin.Context) {
+	p := httpx.New(c)
+	q := &models.TimeSeriesQuery{GroupBy: models.BucketDay, Metric: models.TimeSeriesTotal}
+	if groupBy := p.Enum("groupBy", models.TimeSeriesBuckets...); groupBy != nil {
+		q.GroupBy = *groupBy
+	}
+	if metric := p.Enum("metric", models.TimeSeriesMetrics...); metric != nil {
+		q.Metric = *metric
+	}
+	q.StartDate, q.EndDate = p.DateRange("startDate", "endDate")
+	if !p.Valid() {
+		return
+	}
+	asOf, ok := asOfParam(c)
+	if !ok {
+		return
+	}
+	q.AsOf = asOf
+
+	ts, err := h.repo.GetTimeSeries(c.Request.Context(), q)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidTimeSeries) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid time series: "+strings.TrimPrefix(err.Error(), database.ErrInvalidTimeSeries.Error()+": "))
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get time series: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, ts)
+}
+
+// GetMix handles GET /analytics/mix
+// @Summary Energy mix of a day
+// @Description Production in MW and share in percent of each generator type on one day (today in UTC by default), with the renewable and non-renewable totals and shares: the energy matrix of the day. Read from running daily totals kept current on every write, so the latest figures cost no aggregation
+// @Tags analytics
+// @Produce json
+// @Param date query string false "Day (YYYY-MM-DD), default today"
+// @Success 200 {object} models.EnergyMix
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /analytics/mix [get]
+func (h *AnalyticsHandler) GetMix(c *gin.Context) {
+	p := httpx.New(c)
+	day := p.Date("date")
+	if !p.Valid() {
+		return
+	}
+	if day == nil {
+		today := time.Now().UTC().Format(httpx.DateLayout)
+		day = &today
+	}
+
+	mix, err := h.repo.GetEnergyMix(c.Request.Context(), *day)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get energy mix: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, mix)
+}
+
+// GetMixComparison handles GET /analytics/mix/compare
+// @Summary Energy mix against the previous business day
+// @Description Energy mix of a day (today in UTC by default) next to that of the previous business day of a holiday calendar, with the change of the total and of each type. Without calendar the previous business day skips Saturdays and Sundays only
+// @Tags analytics
+// @Produce json
+// @Param date query string false "Day (YYYY-MM-DD), default today"
+// @Param calendar query string false "Holiday calendar: country code (CO) or country and region code (CO-ANT)"
+// @Success 200 {object} models.EnergyMixComparison
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /analytics/mix/compare [get]
+func (h *AnalyticsHandler) GetMixComparison(c *gin.Context) {
+	p := httpx.New(c)
+	day := p.Date("date")
+	if !p.Valid() {
+		return
+	}
+	if day == nil {
+		today := time.Now().UTC().Format(httpx.DateLayout)
+		day = &today
+	}
+	date, _ := time.Parse(httpx.DateLayout, *day)
+
+	ctx := c.Request.Context()
+	cal, err := h.calendars.Calendar(ctx, c.Query("calendar"))
+	if err != nil {
+		if errors.Is(err, calendar.ErrUnknownCalendar) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid calendar: "+err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get calendar: "+err.Error())
+		return
+	}
+	current, err := h.repo.GetEnergyMix(ctx, *day)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get energy mix: "+err.Error())
+		return
+	}
+	previous, err := h.repo.GetEnergyMix(ctx, cal.PreviousBusinessDay(date).Format(httpx.DateLayout))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get energy mix: "+err.Error())
+		return
+	}
+
+	cmp := database.CompareEnergyMix(current, previous)
+	cmp.Calendar = cal.Country
+	c.JSON(http.StatusOK, cmp)
+}
+
+// GetMixByRegion handles GET /analytics/mix-by-region
+// @Summary Energy mix by region
+// @Description Renewable and non-renewable production of the generators of every region (see /regions) over a date range (YYYY-MM-DD), with their shares in percent. Regions are ordered by name; generators without region are reported last as "Unassigned"
+// @Tags analytics
+// @Produce json
+// @Param startDate query string false "Start date (YYYY-MM-DD)"
+// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Success 200 {array} models.RegionMix
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /analytics/mix-by-region [get]
+func (h *AnalyticsHandler) GetMixByRegion(c *gin.Context) {
+	p := httpx.New(c)
+	start, end := p.DateRange("startDate", "endDate")
+	if !p.Valid() {
+		return
+	}
+
+	list, err := h.repo.GetMixByRegion(c.Request.Context(), start, end)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get mix by region: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.RegionMix{}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetDailySummary handles GET /analytics/daily-summary
+// @Summary Daily production by generator type
+// @Description Production and record count per day and generator type, read from the daily_production_summary projection of the event log, optionally limited to a date range (YYYY-MM-DD). The projection trails writes by up to PROJECTIONS_INTERVAL
+// @Tags analytics
+// @Produce json,application/x-protobuf,application/msgpack
+// @Param startDate query string false "Start date (YYYY-MM-DD)"
+// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Success 200 {array} models.DailyProductionSummary
+// @Failure 500 {object} models.ErrorResponse
+// @Router /analytics/daily-summary [get]
+func (h *AnalyticsHandler) GetDailySummary(c *gin.Context) {
+	start, end := dateRangeParams(c)
+
+	list, err := h.repo.GetDailyProductionSummary(c.Request.Context(), start, end)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get daily summary: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.DailyProductionSummary{}
+	}
+
+	respond(c, http.StatusOK, list, func() []byte { return pb.DailyProductionSummaryList(list) })
+}
+
+// regionTotals accumulates the generators and production of a region
+type regionTotals struct {
+	generators          int
+	capacity            decimal.Decimal
+	production          decimal.Decimal
+	renewableProduction decimal.Decimal
+}
+
+// GetRegionsGeoJSON handles GET /analytics/regions.geojson
+// @Summary Production by region as GeoJSON
+// @Description The region polygons of REGIONS_GEOJSON_FILE as a GeoJSON FeatureCollection ready for choropleth maps. The properties of the file are kept and each region gets the generators located inside it with their capacity, production and renewable production over the date range (YYYY-MM-DD) and the renewable share of that production in percent. Generators without coordinates or outside every region are left out
+// @Tags analytics
+// @Produce json
+// @Param startDate query string false "Start date (YYYY-MM-DD)"
+// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Success 200 {object} geo.FeatureCollection
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /analytics/regions.geojson [get]
+func (h *AnalyticsHandler) GetRegionsGeoJSON(c *gin.Context) {
+	if len(h.regions.List()) == 0 {
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "Regions are not configured")
+		return
+	}
+	start, end := dateRangeParams(c)
+
+	generators, err := h.repo.GetAllGenerators(c.Request.Context(), models.GeneratorFilter{})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list generators: "+err.Error())
+		return
+	}
+	production, err := h.repo.GetProductionByGenerator(c.Request.Context(), start, end)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get production by generator: "+err.Error())
+		return
+	}
+	byGenerator := make(map[uuid.UUID]decimal.Decimal, len(production))
+	for _, p := range production {
+		byGenerator[p.GeneratorID] = p.TotalProduction
+	}
+
+	totals := map[string]*regionTotals{}
+	for _, g := range generators {
+		loc, ok := g.Location()
+		if !ok {
+			continue
+		}
+		region := h.regions.Locate(loc)
+		if region == nil {
+			continue
+		}
+		t, ok := totals[region.ID]
+		if !ok {
+			t = &regionTotals{}
+			totals[region.ID] = t
+		}
+		t.generators++
+		t.capacity = t.capacity.Add(g.Capacity)
+		t.production = t.production.Add(byGenerator[g.ID])
+		if g.IsRenewable {
+			t.renewableProduction = t.renewableProduction.Add(byGenerator[g.ID])
+		}
+	}
+
+	features := make([]*geo.Feature, 0, len(h.regions.List()))
+	for _, region := range h.regions.List() {
+		t, ok := totals[region.ID]
+		if !ok {
+			t = &regionTotals{}
+		}
+		props := make(map[string]any, len(region.Properties)+7)
+		for k, v := range region.Properties {
+			props[k] = v
+		}
+		share := decimal.Zero
+		if !t.production.IsZero() {
+			share = t.renewableProduction.Mul(decimal.NewFromInt(100)).DivRound(t.production, 16)
+		}
+		props["name"] = region.Name
+		props["generators"] = t.generators
+		props["capacity"] = numeric.RoundDecimal(t.capacity)
+		props["production"] = numeric.RoundDecimal(t.production)
+		props["renewableProduction"] = numeric.RoundDecimal(t.renewableProduction)
+		props["nonRenewableProduction"] = numeric.RoundDecimal(t.production.Sub(t.renewableProduction))
+		props["renewableShare"] = numeric.RoundDecimal(share)
+		features = append(features, &geo.Feature{Type: "Feature", ID: region.ID, Geometry: region.Geometry, Properties: props})
+	}
+	c.Header("Content-Type", geo.ContentType)
+	c.JSON(http.StatusOK, geo.NewFeatureCollection(features))
+}
+
+// dateRangeParams reads the optional startDate/endDate query parameters
+func dateRangeParams(c *gin.Context) (start, end *string) {
+	if s := c.Query("startDate"); s != "" {
+		start = &s
+	}
+	if e := c.Query("endDate"); e != "" {
+		end = &e
+	}
+	return start, end
+}
+
+// asOfParam reads the optional asOf query parameter, answering 400 and
+// returning false when it is not an RFC 3339 timestamp
+func asOfParam(c *gin.Context) (*time.Time, bool) {
+	s := c.Query("asOf")
+	if s == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid asOf: must be an RFC 3339 timestamp such as 2025-10-01T00:00:00Z")
+		return nil, false
+	}
+	return &t, true
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AnnotationHandler handles HTTP requests for the notes of analysts on production data
+type AnnotationHandler struct {
+	repo database.Repository
+}
+
+// NewAnnotationHandler creates a new AnnotationHandler instance
+func NewAnnotationHandler(repo database.Repository) *AnnotationHandler {
+	return &AnnotationHandler{repo: repo}
+}
+
+// CreateAnnotation handles POST /annotations
+// @Summary Annotate production data
+// @Description Add a note on a production record (productionId) or on the inclusive range startDate..endDate (endDate defaults to startDate) of a generator, or of the whole fleet when generatorId is omitted. Annotations are listed with the records they apply to and added to report exports
+// @Tags annotations
+// @Accept json
+// @Produce json
+// @Param body body models.CreateAnnotationRequest true "Target and note"
+// @Success 201 {object} models.Annotation
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /annotations [post]
+func (h *AnnotationHandler) CreateAnnotation(c *gin.Context) {
+	var req models.CreateAnnotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if reason := annotationTargetError(&req); reason != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+reason)
+		return
+	}
+
+	a, err := h.repo.CreateAnnotation(c.Request.Context(), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrForbidden):
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+		case err == sql.ErrNoRows && req.ProductionID != nil:
+			utils.ErrorResponse(c, http.StatusNotFound, "Production not found")
+		case err == sql.ErrNoRows:
+			utils.ErrorResponse(c, http.StatusNotFound, "Generator not found")
+		case errors.Is(err, database.ErrNotSupported):
+			utils.ErrorResponse(c, http.StatusNotImplemented, "Not supported: "+err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create annotation: "+err.Error())
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, a)
+}
+
+// annotationTargetError checks that a request annotates either a record or a
+// range; empty when it does
+func annotationTargetError(req *models.CreateAnnotationRequest) string {
+	switch {
+	case req.ProductionID != nil && (req.StartDate != nil || req.EndDate != nil || req.GeneratorID != nil):
+		return "an annotation on productionId takes no startDate, endDate or generatorId"
+	case req.ProductionID == nil && req.StartDate == nil:
+		return "set productionId or startDate"
+	// Dates in YYYY-MM-DD order lexically
+	case req.EndDate != nil && *req.EndDate < *req.StartDate:
+		return "endDate must not be before startDate"
+	}
+	return ""
+}
+
+// GetAnnotations handles GET /annotations
+// @Summary List annotations
+// @Description Annotations that apply to the production data selected by the filters, in date order: those on its records and the ranges overlapping it. generatorId includes fleet-wide ranges; productionId returns what applies to that record
+// @Tags annotations
+// @Produce json
+// @Param productionId query string false "Production ID (UUID)"
+// @Param generatorId query string false "Generator ID (UUID)"
+// @Param startDate query string false "Start date (YYYY-MM-DD)"
+// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Success 200 {array} models.Annotation
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /annotations [get]
+func (h *AnnotationHandler) GetAnnotations(c *gin.Context) {
+	q := httpx.New(c)
+	filter := &models.AnnotationFilter{
+		ProductionID: q.UUID("productionId"),
+		GeneratorID:  q.UUID("generatorId"),
+	}
+	filter.StartDate, filter.EndDate = q.DateRange("startDate", "endDate")
+	if !q.Valid() {
+		return
+	}
+	h.list(c, filter)
+}
+
+// GetProductionAnnotations handles GET /productions/:id/annotations
+// @Summary Annotations of a production record
+// @Description The notes on the record itself and the ranges covering its date for its generator or the fleet, in date order; shown with the corrections of the record in its history
+// @Tags productions
+// @Produce json
+// @Param id path string true "Production ID"
+// @Success 200 {array} models.Annotation
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /productions/{id}/annotations [get]
+func (h *AnnotationHandler) GetProductionAnnotations(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	if _, err := h.repo.GetProductionByID(c.Request.Context(), id); err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Production not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get production: "+err.Error())
+		return
+	}
+	h.list(c, &models.AnnotationFilter{ProductionID: &id})
+}
+
+func (h *AnnotationHandler) list(c *gin.Context, filter *models.AnnotationFilter) {
+	list, err := h.repo.GetAnnotations(c.Request.Context(), filter)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list annotations: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.Annotation{}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetAnnotationByID handles GET /annotations/:id
+// @Summary Get annotation by ID
+// @Tags annotations
+// @Produce json
+// @Param id path string true "Annotation ID"
+// @Success 200 {object} models.Annotation
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /annotations/{id} [get]
+func (h *AnnotationHandler) GetAnnotationByID(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	a, err := h.repo.GetAnnotationByID(c.Request.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Annotation not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get annotation: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// UpdateAnnotation handles PUT /annotations/:id
+// @Summary Update annotation
+// @Description Change the note of an annotation; what it is on stays the same
+// @Tags annotations
+// @Accept json
+// @Produce json
+// @Param id path string true "Annotation ID"
+// @Param body body models.UpdateAnnotationRequest true "Note"
+// @Success 200 {object} models.Annotation
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /annotations/{id} [put]
+func (h *AnnotationHandler) UpdateAnnotation(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	var req models.UpdateAnnotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	a, err := h.repo.UpdateAnnotation(c.Request.Context(), id, &req)
+	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Annotation not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update annotation: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// DeleteAnnotation handles DELETE /annotations/:id
+// @Summary Delete annotation
+// @Tags annotations
+// @Param id path string true "Annotation ID"
+// @Success 204
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /annotations/{id} [delete]
+func (h *AnnotationHandler) DeleteAnnotation(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	if err := h.repo.DeleteAnnotation(c.Request.Context(), id); err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Annotation not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete annotation: "+err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
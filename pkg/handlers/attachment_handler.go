@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/attachments"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/storage"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead is the room left for multipart boundaries and headers
+// when a request is rejected by its Content-Length
+const multipartOverhead = 64 << 10
+
+// AttachmentHandler handles HTTP requests for the documents attached to types and generators
+type AttachmentHandler struct {
+	repo        database.Repository
+	attachments *attachments.Service
+}
+
+// NewAttachmentHandler creates a new AttachmentHandler instance
+func NewAttachmentHandler(repo database.Repository, service *attachments.Service) *AttachmentHandler {
+	return &AttachmentHandler{
+		repo:        repo,
+		attachments: service,
+	}
+}
+
+// GetTypeAttachments handles GET /types/:id/attachments
+// @Summary List the attachments of a type
+// @Description Documents attached to a type, newest first
+// @Tags types
+// @Produce json
+// @Param id path string true "Type ID (UUID)"
+// @Success 200 {array} models.Attachment
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Router /types/{id}/attachments [get]
+func (h *AttachmentHandler) GetTypeAttachments(c *gin.Context) {
+	h.list(c, models.AttachmentScopeType, "Type")
+}
+
+// UploadTypeAttachment handles POST /types/:id/attachments
+// @Summary Attach a document to a type
+// @Description Upload a document (e.g. a datasheet) as the multipart "file" field. The size is limited by ATTACHMENT_MAX_BYTES and the media type, declared or sniffed, must be one of ATTACHMENT_CONTENT_TYPES
+// @Tags types
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Type ID (UUID)"
+// @Param file formData file true "Document"
+// @Success 201 {object} models.Attachment
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /types/{id}/attachments [post]
+func (h *AttachmentHandler) UploadTypeAttachment(c *gin.Context) {
+	h.upload(c, models.AttachmentScopeType, "Type")
+}
+
+// DownloadTypeAttachment handles GET /types/:id/attachments/:attachmentId
+// @Summary Download an attachment of a type
+// @Description The content of the document with its media type, as a download
+// @Tags types
+// @Produce octet-stream
+// @Param id path string true "Type ID (UUID)"
+// @Param attachmentId path string true "Attachment ID (UUID)"
+// @Success 200 {file} binary
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /types/{id}/attachments/{attachmentId} [get]
+func (h *AttachmentHandler) DownloadTypeAttachment(c *gin.Context) {
+	h.download(c, models.AttachmentScopeType)
+}
+
+// DeleteTypeAttachment handles DELETE /types/:id/attachments/:attachmentId
+// @Summary Delete an attachment of a type
+// @Description Delete the document for good; a copy in object storage is removed in the background
+// @Tags types
+// @Param id path string true "Type ID (UUID)"
+// @Param attachmentId path string true "Attachment ID (UUID)"
+// @Success 204
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /types/{id}/attachments/{attachmentId} [delete]
+func (h *AttachmentHandler) DeleteTypeAttachment(c *gin.Context) {
+	h.delete(c, models.AttachmentScopeType)
+}
+
+// GetGeneratorAttachments handles GET /generators/:id/attachments
+// @Summary List the attachments of a generator
+// @Description Documents attached to a generator, newest first
+// @Tags generators
+// @Produce json
+// @Param id path string true "Generator ID (UUID)"
+// @Success 200 {array} models.Attachment
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Router /generators/{id}/attachments [get]
+func (h *AttachmentHandler) GetGeneratorAttachments(c *gin.Context) {
+	h.list(c, models.AttachmentScopeGenerator, "Generator")
+}
+
+// UploadGeneratorAttachment handles POST /generators/:id/attachments
+// @Summary Attach a document to a generator
+// @Description Upload a document (e.g. a permit or bulletin PDF) as the multipart "file" field. The size is limited by ATTACHMENT_MAX_BYTES and the media type, declared or sniffed, must be one of ATTACHMENT_CONTENT_TYPES
+// @Tags generators
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Generator ID (UUID)"
+// @Param file formData file true "Document"
+// @Success 201 {object} models.Attachment
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /generators/{id}/attachments [post]
+func (h *AttachmentHandler) UploadGeneratorAttachment(c *gin.Context) {
+	h.upload(c, models.AttachmentScopeGenerator, "Generator")
+}
+
+// DownloadGeneratorAttachment handles GET /generators/:id/attachments/:attachmentId
+// @Summary Download an attachment of a generator
+// @Description The content of the document with its media type, as a download
+// @Tags generators
+// @Produce octet-stream
+// @Param id path string true "Generator ID (UUID)"
+// @Param attachmentId path string true "Attachment ID (UUID)"
+// @Success 200 {file} binary
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /generators/{id}/attachments/{attachmentId} [get]
+func (h *AttachmentHandler) DownloadGeneratorAttachment(c *gin.Context) {
+	h.download(c, models.AttachmentScopeGenerator)
+}
+
+// DeleteGeneratorAttachment handles DELETE /generators/:id/attachments/:attachmentId
+// @Summary Delete an attachment of a generator
+// @Description Delete the document for good; a copy in object storage is removed in the background
+// @Tags generators
+// @Param id path string true "Generator ID (UUID)"
+// @Param attachmentId path string true "Attachment ID (UUID)"
+// @Success 204
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /generators/{id}/attachments/{attachmentId} [delete]
+func (h *AttachmentHandler) DeleteGeneratorAttachment(c *gin.Context) {
+	h.delete(c, models.AttachmentScopeGenerator)
+}
+
+func (h *AttachmentHandler) list(c *gin.Context, scope, entity string) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+
+	list, err := h.repo.GetAttachments(c.Request.Context(), scope, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, entity+" not found")
+			return
+		}
+		if errors.Is(err, database.ErrNotSupported) {
+			utils.ErrorResponse(c, http.StatusNotImplemented, "Not supported: "+err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get attachments: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.Attachment{}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *AttachmentHandler) upload(c *gin.Context, scope, entity string) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	if max := h.attachments.Config().MaxBytes; c.Request.ContentLength > max+multipartOverhead {
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Attachment too large: maximum size is "+strconv.FormatInt(max, 10)+" bytes")
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		utils.ErrorResponse(c, http.StatusBadRequest, `Invalid attachment: send the document as the multipart "file" field`)
+		return
+	}
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid attachment: "+err.Error())
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			utils.ErrorResponse(c, http.StatusBadRequest, `Invalid attachment: multipart request has no "file" part`)
+			return
+		}
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid attachment: "+err.Error())
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		a, err := h.attachments.Upload(c.Request.Context(), scope, id, part.FileName(), part.Header.Get("Content-Type"), part)
+		if err != nil {
+			switch {
+			case errors.Is(err, auth.ErrForbidden):
+				utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			case err == sql.ErrNoRows:
+				utils.ErrorResponse(c, http.StatusNotFound, entity+" not found")
+			case errors.Is(err, attachments.ErrTooLarge):
+				utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Attachment too large: "+err.Error())
+			case errors.Is(err, attachments.ErrContentType):
+				utils.ErrorResponse(c, http.StatusUnsupportedMediaType, "Unsupported attachment: "+err.Error())
+			case errors.Is(err, attachments.ErrEmpty):
+				utils.ErrorResponse(c, http.StatusBadRequest, "Invalid attachment: "+err.Error())
+			case errors.Is(err, database.ErrNotSupported):
+				utils.ErrorResponse(c, http.StatusNotImplemented, "Not supported: "+err.Error())
+			default:
+				utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to upload attachment: "+err.Error())
+			}
+			return
+		}
+		c.JSON(http.StatusCreated, a)
+		return
+	}
+}
+
+func (h *AttachmentHandler) download(c *gin.Context, scope string) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	attachmentID := q.PathUUID("attachmentId")
+	if !q.Valid() {
+		return
+	}
+
+	a, body, err := h.attachments.Open(c.Request.Context(), scope, id, attachmentID)
+	if err != nil {
+		switch {
+		case err == sql.ErrNoRows, errors.Is(err, storage.ErrObjectNotFound):
+			utils.ErrorResponse(c, http.StatusNotFound, "Attachment not found")
+		case errors.Is(err, storage.ErrNotConfigured):
+			utils.ErrorResponse(c, http.StatusServiceUnavailable, "Object storage is not configured")
+		case errors.Is(err, database.ErrNotSupported):
+			utils.ErrorResponse(c, http.StatusNotImplemented, "Not supported: "+err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to download attachment: "+err.Error())
+		}
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, a.SizeBytes, a.ContentType, body, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": a.FileName}),
+		"ETag":                `"` + a.SHA256 + `"`,
+	})
+}
+
+func (h *AttachmentHandler) delete(c *gin.Context, scope string) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	attachmentID := q.PathUUID("attachmentId")
+	if !q.Valid() {
+		return
+	}
+
+	if err := h.repo.DeleteAttachment(c.Request.Context(), scope, id, attachmentID); err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Attachment not found")
+			return
+		}
+		if errors.Is(err, database.ErrNotSupported) {
+			utils.ErrorResponse(c, http.StatusNotImplemented, "Not supported: "+err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete attachment: "+err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler handles HTTP requests for the audit log
+type AuditHandler struct {
+	repo database.Repository
+}
+
+// NewAuditHandler creates a new AuditHandler instance
+func NewAuditHandler(repo database.Repository) *AuditHandler {
+	return &AuditHandler{repo: repo}
+}
+
+// GetAuditEntries handles GET /audit
+// @Summary List audit log
+// @Description Changes to types, operators, generators, productions, annotations, custom fields, import profiles, reports, report templates and users, newest first, with the entity before and after each change and who made it; admins only
+// @Tags audit
+// @Produce json
+// @Param entityType query string false "Entity type (type, operator, plant, region, generator, production, annotation, custom_field, import_profile, report, report_template, user)"
+// @Param entityId query string false "Entity ID (UUID)"
+// @Param userId query string false "User who made the change"
+// @Param action query string false "Action (create, update, delete)"
+// @Param startDate query string false "First day (YYYY-MM-DD)"
+// @Param endDate query string false "Last day (YYYY-MM-DD)"
+// @Param limit query int false "Page size (1-1000, default 100)"
+// @Param offset query int false "Entries to skip"
+// @Success 200 {array} models.AuditEntry
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /audit [get]
+func (h *AuditHandler) GetAuditEntries(c *gin.Context) {
+	q := httpx.New(c)
+	filter := &models.AuditFilter{
+		EntityType: q.Enum("entityType", models.AuditEntities...),
+		EntityID:   q.UUID("entityId"),
+		UserID:     q.UUID("userId"),
+		Action:     q.Enum("action", models.AuditCreate, models.AuditUpdate, models.AuditDelete),
+		Limit:      q.Int("limit", 100, 1, 1000),
+		Offset:     q.Int("offset", 0, 0, math.MaxInt),
+	}
+	filter.StartDate, filter.EndDate = q.DateRange("startDate", "endDate")
+	if !q.Valid() {
+		return
+	}
+	list, err := h.repo.GetAuditEntries(c.Request.Context(), filter)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list audit log: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.AuditEntry{}
+	}
+	if len(list) == filter.Limit {
+		setNextLink(c, filter.Limit, filter.Offset)
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetAuditEntry handles GET /audit/:id
+// @Summary Get audit entry
+// @Tags audit
+// @Produce json
+// @Param id path int true "Entry ID"
+// @Success 200 {object} models.AuditEntry
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /audit/{id} [get]
+func (h *AuditHandler) GetAuditEntry(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathInt("id", 1, math.MaxInt)
+	if !q.Valid() {
+		return
+	}
+	e, err := h.repo.GetAuditEntry(c.Request.Context(), int64(id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Audit entry not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get audit entry: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, e)
+}
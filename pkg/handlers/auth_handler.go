@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// unknownUserHash is compared with the password of logins for unknown
+// usernames, so they take as long as wrong passwords and do not reveal
+// which usernames exist
+var unknownUserHash, _ = auth.HashPassword("unknown user")
+
+// AuthHandler handles registration and login
+type AuthHandler struct {
+	repo     database.Repository
+	cfg      *auth.Config
+	tokens   *auth.Tokens
+	notifier auth.Notifier
+}
+
+// NewAuthHandler creates a new AuthHandler instance; lockouts are reported
+// to notifier
+func NewAuthHandler(repo database.Repository, cfg *auth.Config, tokens *auth.Tokens, notifier auth.Notifier) *AuthHandler {
+	if notifier == nil {
+		notifier = auth.LogNotifier{}
+	}
+	return &AuthHandler{repo: repo, cfg: cfg, tokens: tokens, notifier: notifier}
+}
+
+// Register handles POST /auth/register
+// @Summary Register user
+// @Description Create a user account and sign it in. The first account can be registered without a token and becomes admin unless AUTH_FIRST_USER_ADMIN is false; further accounts are viewers, created by admins unless open registration is enabled
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body models.RegisterRequest true "Account data"
+// @Success 201 {object} models.TokenResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /auth/register [post]
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req models.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+
+	// The first account administers the others. The count only spares the
+	// password hash later registrations; CreateFirstUser decides atomically.
+	if h.cfg.FirstUserAdmin {
+		n, err := h.repo.CountUsers(ctx)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to register user: "+err.Error())
+			return
+		}
+		if n == 0 {
+			hash, err := auth.HashPassword(req.Password)
+			if err != nil {
+				utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to register user: "+err.Error())
+				return
+			}
+			user, err := h.repo.CreateFirstUser(ctx, &req, hash)
+			if err == nil {
+				h.respondToken(c, http.StatusCreated, user, false)
+				return
+			}
+			if !errors.Is(err, database.ErrUsersExist) {
+				h.registerFailed(c, err)
+				return
+			}
+			// Another registration came first; this one is an ordinary one
+		}
+	}
+
+	roles := []string{auth.RoleViewer}
+	if !h.cfg.OpenRegistration {
+		p, ok := auth.PrincipalFrom(ctx)
+		if !ok {
+			c.Header("WWW-Authenticate", `Bearer realm="tadb"`)
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Registration is closed: accounts are created by admins")
+			return
+		}
+		if !p.HasRole(auth.RoleAdmin) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: only admins can create accounts")
+			return
+		}
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to register user: "+err.Error())
+		return
+	}
+	user, err := h.repo.CreateUser(ctx, &req, hash, roles)
+	if err != nil {
+		h.registerFailed(c, err)
+		return
+	}
+	h.respondToken(c, http.StatusCreated, user, false)
+}
+
+// registerFailed answers a registration the repository refused
+func (h *AuthHandler) registerFailed(c *gin.Context, err error) {
+	if errors.Is(err, database.ErrUserExists) {
+		utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+		return
+	}
+	utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to register user: "+err.Error())
+}
+
+// HeaderTwoFactor flags 401 answers to logins that need a two-factor code
+const HeaderTwoFactor = "X-Two-Factor"
+
+// Login handles POST /auth/login
+// @Summary Log in
+// @Description Exchange a username and password for an access token. Send it as "Authorization: Bearer <token>" on writes. Accounts with two-factor authentication also need a code of the authenticator app or a backup code; without one the login is answered 401 with "X-Two-Factor: required". Failed logins are answered more slowly the more fail in a row, and too many lock the username for a while (429 with Retry-After), whether the account exists or not
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body models.LoginRequest true "Credentials"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req models.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	lockouts := h.cfg.Lockout.Threshold > 0
+
+	// Locked usernames are refused before the password is checked, so
+	// guesses during the lock tell nothing
+	var state *models.LoginLockout
+	if lockouts {
+		var err error
+		state, err = h.repo.GetLoginLockout(ctx, req.Username)
+		if err != nil && err != sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to log in: "+err.Error())
+			return
+		}
+		if state != nil && state.Locked {
+			respondLocked(c, *state.LockedUntil)
+			return
+		}
+	}
+
+	user, hash, err := h.repo.GetUserCredentials(ctx, req.Username)
+	if err != nil && err != sql.ErrNoRows {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to log in: "+err.Error())
+		return
+	}
+	if err == sql.ErrNoRows {
+		hash = unknownUserHash
+	}
+	if err := auth.CheckPassword(hash, req.Password); err != nil || user == nil {
+		h.loginFailed(c, req.Username, user, auth.ErrInvalidCredentials)
+		return
+	}
+
+	tf, err := h.repo.GetTwoFactor(ctx, user.ID)
+	if err != nil && err != sql.ErrNoRows {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to log in: "+err.Error())
+		return
+	}
+	twoFactor := tf.Enabled()
+	if twoFactor {
+		// Asking for the code is no failure: clients send the password first
+		if req.Code == "" {
+			c.Header(HeaderTwoFactor, "required")
+			utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized: "+auth.ErrTwoFactorRequired.Error())
+			return
+		}
+		ok, err := useTwoFactorCode(ctx, h.repo, h.cfg.TwoFactor, tf, req.Code, true)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to log in: "+err.Error())
+			return
+		}
+		if !ok {
+			h.loginFailed(c, req.Username, user, auth.ErrInvalidTwoFactorCode)
+			return
+		}
+	}
+	if state != nil {
+		if err := h.repo.ResetLoginFailures(ctx, req.Username); err != nil {
+			utils.LogError("auth: reset login failures", err)
+		}
+	}
+	h.respondToken(c, http.StatusOK, user, twoFactor)
+}
+
+// loginFailed answers a failed login with reason. With lockouts it counts
+// the failure and answers after a delay growing with the failures in a row,
+// or with 429 when they lock the username.
+func (h *AuthHandler) loginFailed(c *gin.Context, username string, user *models.User, reason error) {
+	if h.cfg.Lockout.Threshold <= 0 {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized: "+reason.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	cfg := h.cfg.Lockout
+	state, err := h.repo.RecordLoginFailure(ctx, username, c.ClientIP(), cfg.Window)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to log in: "+err.Error())
+		return
+	}
+	if state.Failures >= cfg.Threshold {
+		failures := state.Failures
+		now := time.Now()
+		state, err = h.repo.LockLogin(ctx, username, now.Add(cfg.LockFor(state.Lockouts)))
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to log in: "+err.Error())
+			return
+		}
+		alert := auth.LockoutAlert{
+			Username:    state.Username,
+			Failures:    failures,
+			Lockouts:    state.Lockouts,
+			LastIP:      state.LastIP,
+			LockedUntil: *state.LockedUntil,
+			RaisedAt:    now,
+		}
+		if user != nil {
+			alert.Username, alert.UserID = user.Username, &user.ID
+		}
+		// Deliver after answering; the request context ends with it
+		go func() {
+			if err := h.notifier.Notify(context.WithoutCancel(ctx), alert); err != nil {
+				utils.LogError("auth: notify lockout of "+alert.Username, err)
+			}
+		}()
+		respondLocked(c, *state.LockedUntil)
+		return
+	}
+
+	select {
+	case <-time.After(cfg.FailureDelay(state.Failures)):
+	case <-ctx.Done():
+	}
+	utils.ErrorResponse(c, http.StatusUnauthorized, "Unauthorized: "+reason.Error())
+}
+
+func respondLocked(c *gin.Context, until time.Time) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(until).Seconds()))))
+	utils.ErrorResponse(c, http.StatusTooManyRequests, fmt.Sprintf("%s: logins are locked until %s", auth.ErrAccountLocked, until.UTC().Format(time.RFC3339)))
+}
+
+func (h *AuthHandler) respondToken(c *gin.Context, code int, user *models.User, twoFactor bool) {
+	token, expiresAt, err := h.tokens.Issue(user.ID, twoFactor)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to issue access token: "+err.Error())
+		return
+	}
+	c.JSON(code, &models.TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresAt:   expiresAt,
+		User:        user,
+	})
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/calendar"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxCalendarDays bounds the date range of a calendar lookup
+const maxCalendarDays = 1100
+
+// CalendarHandler handles HTTP requests for holidays and business-day calendars
+type CalendarHandler struct {
+	repo      database.Repository
+	calendars *calendar.Service
+}
+
+// NewCalendarHandler creates a new CalendarHandler instance
+func NewCalendarHandler(repo database.Repository, calendars *calendar.Service) *CalendarHandler {
+	return &CalendarHandler{repo: repo, calendars: calendars}
+}
+
+// GetHolidays handles GET /admin/holidays
+// @Summary List holidays
+// @Description Holidays managed through the API, by country, region and date; the calendars of HOLIDAY_CALENDARS_FILE are not listed
+// @Tags admin
+// @Produce json
+// @Param country query string false "Country code (ISO 3166-1 alpha-2), all by default"
+// @Success 200 {array} models.Holiday
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/holidays [get]
+func (h *CalendarHandler) GetHolidays(c *gin.Context) {
+	list, err := h.repo.ListHolidays(c.Request.Context(), c.Query("country"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list holidays: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.Holiday{}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// CreateHoliday handles POST /admin/holidays
+// @Summary Add a holiday
+// @Description Add a holiday to a country, or only to a region of it, on top of the calendars of HOLIDAY_CALENDARS_FILE. date is YYYY-MM-DD for one day or MM-DD for every year. Freshness lags, report schedules and mix comparisons on the calendar use it at once
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param holiday body models.HolidayRequest true "Holiday"
+// @Success 201 {object} models.Holiday
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/holidays [post]
+func (h *CalendarHandler) CreateHoliday(c *gin.Context) {
+	var req models.HolidayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if !calendar.ValidHolidayDate(req.Date) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: date must be YYYY-MM-DD or MM-DD")
+		return
+	}
+	holiday, err := h.repo.CreateHoliday(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, database.ErrHolidayExists) {
+			utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create holiday: "+err.Error())
+		return
+	}
+	h.calendars.Invalidate()
+	c.JSON(http.StatusCreated, holiday)
+}
+
+// DeleteHoliday handles DELETE /admin/holidays/:id
+// @Summary Delete a holiday
+// @Description Delete a holiday added through the API
+// @Tags admin
+// @Produce json
+// @Param id path string true "Holiday ID (UUID)"
+// @Success 204
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/holidays/{id} [delete]
+func (h *CalendarHandler) DeleteHoliday(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	if err := h.repo.DeleteHoliday(c.Request.Context(), id); err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Holiday not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete holiday: "+err.Error())
+		return
+	}
+	h.calendars.Invalidate()
+	c.Status(http.StatusNoContent)
+}
+
+// GetCalendar handles GET /admin/calendars/:code
+// @Summary Business days of a calendar
+// @Description Resolve a calendar over a date range (the current month by default): its weekend, the holidays in the range from the file and the API, the number of business days and the business days before and after the range. code is a country (CO) or a country and region (CO-ANT); region calendars add the holidays of the region to those of the country
+// @Tags admin
+// @Produce json
+// @Param code path string true "Calendar code"
+// @Param startDate query string false "Start date (YYYY-MM-DD)"
+// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Success 200 {object} models.BusinessCalendar
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/calendars/{code} [get]
+func (h *CalendarHandler) GetCalendar(c *gin.Context) {
+	q := httpx.New(c)
+	startDate, endDate := q.DateRange("startDate", "endDate")
+	if !q.Valid() {
+		return
+	}
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, -1)
+	if startDate != nil {
+		start, _ = time.Parse(httpx.DateLayout, *startDate)
+		if endDate == nil {
+			end = start.AddDate(0, 1, -1)
+		}
+	}
+	if endDate != nil {
+		end, _ = time.Parse(httpx.DateLayout, *endDate)
+		if startDate == nil {
+			start = end.AddDate(0, -1, 1)
+		}
+	}
+	if end.Sub(start) > maxCalendarDays*24*time.Hour {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid date range: at most 1100 days")
+		return
+	}
+
+	cal, err := h.calendars.Range(c.Request.Context(), c.Param("code"), start, end)
+	if err != nil {
+		if errors.Is(err, calendar.ErrUnknownCalendar) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Calendar not found: "+strings.TrimPrefix(err.Error(), calendar.ErrUnknownCalendar.Error()+": "))
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get calendar: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, cal)
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/catalog"
+	"github.com/gin-gonic/gin"
+)
+
+// CatalogHandler handles HTTP requests for the canonical technology catalog
+type CatalogHandler struct{}
+
+// NewCatalogHandler creates a new CatalogHandler instance
+func NewCatalogHandler() *CatalogHandler {
+	return &CatalogHandler{}
+}
+
+// GetTechnologies handles GET /catalog/technologies
+// @Summary List canonical technologies
+// @Description List the well-known generation technologies with their codes and accepted name aliases. Type names are mapped to these codes for cross-dataset comparability
+// @Tags catalog
+// @Produce json
+// @Success 200 {array} catalog.Technology
+// @Router /catalog/technologies [get]
+func (h *CatalogHandler) GetTechnologies(c *gin.Context) {
+	c.JSON(http.StatusOK, catalog.All())
+}
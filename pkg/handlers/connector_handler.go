@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/connectors"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ConnectorHandler handles HTTP requests for the partner feed connectors
+type ConnectorHandler struct {
+	repo      database.Repository
+	connector *connectors.Connector
+	mailboxes *connectors.MailboxPoller
+}
+
+// NewConnectorHandler creates a new ConnectorHandler instance
+func NewConnectorHandler(repo database.Repository, connector *connectors.Connector, mailboxes *connectors.MailboxPoller) *ConnectorHandler {
+	return &ConnectorHandler{
+		repo:      repo,
+		connector: connector,
+		mailboxes: mailboxes,
+	}
+}
+
+// GetSFTPSources handles GET /imports/sftp/sources
+// @Summary SFTP partner feeds
+// @Description Sources of SFTP_SOURCES_FILE with their schedule, the outcome of the last pull and the number of files staged since the server started
+// @Tags imports
+// @Produce json
+// @Success 200 {array} models.ConnectorSourceStatus
+// @Router /imports/sftp/sources [get]
+func (h *ConnectorHandler) GetSFTPSources(c *gin.Context) {
+	c.JSON(http.StatusOK, h.connector.Sources())
+}
+
+// GetSFTPFiles handles GET /imports/sftp/files
+// @Summary Files pulled from SFTP partner feeds
+// @Description Remote files downloaded by the SFTP connector, newest first, with their SHA-256 and the name they were staged under in the drop folder. Files whose checksum was staged before are listed as duplicate and not imported again
+// @Tags imports
+// @Produce json
+// @Param source query string false "Only files of this source"
+// @Param limit query int false "Maximum number of files (default 100, up to 1000)"
+// @Success 200 {array} models.ConnectorFile
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /imports/sftp/files [get]
+func (h *ConnectorHandler) GetSFTPFiles(c *gin.Context) {
+	q := httpx.New(c)
+	limit := q.Int("limit", 100, 1, 1000)
+	if !q.Valid() {
+		return
+	}
+
+	files, err := h.repo.GetConnectorFiles(c.Request.Context(), models.ConnectorSFTP, c.Query("source"), limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get connector files: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, files)
+}
+
+// GetMailboxes handles GET /imports/email/mailboxes
+// @Summary Polled IMAP mailboxes
+// @Description Mailboxes of IMAP_MAILBOXES_FILE with their schedule, the outcome of the last poll and the number of messages read and attachments staged since the server started
+// @Tags imports
+// @Produce json
+// @Success 200 {array} models.MailboxStatus
+// @Router /imports/email/mailboxes [get]
+func (h *ConnectorHandler) GetMailboxes(c *gin.Context) {
+	c.JSON(http.StatusOK, h.mailboxes.Mailboxes())
+}
+
+// GetEmailAttachments handles GET /imports/email/attachments
+// @Summary Attachments staged from emailed spreadsheets
+// @Description Attachments taken from polled mailboxes, newest first. The remote path is the message ID and the file name; attachments whose checksum was staged from the mailbox before are listed as duplicate and not imported again
+// @Tags imports
+// @Produce json
+// @Param mailbox query string false "Only attachments of this mailbox"
+// @Param limit query int false "Maximum number of attachments (default 100, up to 1000)"
+// @Success 200 {array} models.ConnectorFile
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /imports/email/attachments [get]
+func (h *ConnectorHandler) GetEmailAttachments(c *gin.Context) {
+	q := httpx.New(c)
+	limit := q.Int("limit", 100, 1, 1000)
+	if !q.Valid() {
+		return
+	}
+
+	files, err := h.repo.GetConnectorFiles(c.Request.Context(), models.ConnectorEmail, c.Query("mailbox"), limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get email attachments: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, files)
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/customfields"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// attributeFilterPrefix prefixes the query parameters filtering listings by custom attribute
+const attributeFilterPrefix = "attr."
+
+// CustomFieldHandler handles HTTP requests for the registry of custom fields
+type CustomFieldHandler struct {
+	repo database.Repository
+}
+
+// NewCustomFieldHandler creates a new CustomFieldHandler instance
+func NewCustomFieldHandler(repo database.Repository) *CustomFieldHandler {
+	return &CustomFieldHandler{repo: repo}
+}
+
+// GetCustomFields handles GET /custom-fields
+// @Summary List custom fields
+// @Description The custom fields registered for types, operators, generators and productions, by entity and name
+// @Tags custom-fields
+// @Produce json
+// @Param entity query string false "Entity (type, operator, generator, production)"
+// @Success 200 {array} models.CustomField
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /custom-fields [get]
+func (h *CustomFieldHandler) GetCustomFields(c *gin.Context) {
+	q := httpx.New(c)
+	entity := q.Enum("entity", models.CustomEntityType, models.CustomEntityOperator, models.CustomEntityGenerator, models.CustomEntityProduction)
+	if !q.Valid() {
+		return
+	}
+	var e string
+	if entity != nil {
+		e = *entity
+	}
+	list, err := h.repo.GetCustomFields(c.Request.Context(), e)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list custom fields: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.CustomField{}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetCustomFieldByID handles GET /custom-fields/:id
+// @Summary Get custom field by ID
+// @Tags custom-fields
+// @Produce json
+// @Param id path string true "Custom field ID"
+// @Success 200 {object} models.CustomField
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /custom-fields/{id} [get]
+func (h *CustomFieldHandler) GetCustomFieldByID(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	f, err := h.repo.GetCustomFieldByID(c.Request.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Custom field not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get custom field: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, f)
+}
+
+// CreateCustomField handles POST /custom-fields
+// @Summary Register custom field
+// @Description Add an attribute to an entity. Records accept it in customAttributes from then on, checked against its type and validation, and listings filter on it with attr.<name>=value. A required field applies to records created afterwards
+// @Tags custom-fields
+// @Accept json
+// @Produce json
+// @Param body body models.CreateCustomFieldRequest true "Field definition"
+// @Success 201 {object} models.CustomField
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /custom-fields [post]
+func (h *CustomFieldHandler) CreateCustomField(c *gin.Context) {
+	var req models.CreateCustomFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if err := customfields.CheckDefinition(req.Name, req.Type, req.Pattern, req.Min, req.Max, req.Options); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	f, err := h.repo.CreateCustomField(c.Request.Context(), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrForbidden):
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+		case errors.Is(err, database.ErrCustomFieldExists):
+			utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+		case errors.Is(err, database.ErrNotSupported):
+			utils.ErrorResponse(c, http.StatusNotImplemented, "Not supported: "+err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create custom field: "+err.Error())
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, f)
+}
+
+// UpdateCustomField handles PUT /custom-fields/:id
+// @Summary Update custom field
+// @Description Replace the description and validation of a custom field; its entity, name and type stay the same. Stored values are checked against the new validation when their record is next written
+// @Tags custom-fields
+// @Accept json
+// @Produce json
+// @Param id path string true "Custom field ID"
+// @Param body body models.UpdateCustomFieldRequest true "Description and validation"
+// @Success 200 {object} models.CustomField
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /custom-fields/{id} [put]
+func (h *CustomFieldHandler) UpdateCustomField(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	var req models.UpdateCustomFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	current, err := h.repo.GetCustomFieldByID(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Custom field not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update custom field: "+err.Error())
+		return
+	}
+	if err := customfields.CheckDefinition(current.Name, current.Type, req.Pattern, req.Min, req.Max, req.Options); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	f, err := h.repo.UpdateCustomField(ctx, id, &req)
+	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Custom field not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update custom field: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, f)
+}
+
+// DeleteCustomField handles DELETE /custom-fields/:id
+// @Summary Delete custom field
+// @Description Remove a custom field and its values from every record of its entity. This cannot be undone
+// @Tags custom-fields
+// @Param id path string true "Custom field ID"
+// @Success 204
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /custom-fields/{id} [delete]
+func (h *CustomFieldHandler) DeleteCustomField(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	if err := h.repo.DeleteCustomField(c.Request.Context(), id); err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Custom field not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete custom field: "+err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// checkAttributes validates the custom attributes of a write to a record of
+// entity against its fields. Creates are always checked, for required
+// fields; partial updates only when they set attributes. False when it
+// answered the request.
+func checkAttributes(c *gin.Context, repo database.Repository, entity string, attrs models.CustomAttributes, partial bool) bool {
+	if partial && len(attrs) == 0 {
+		return true
+	}
+	fields, err := repo.GetCustomFields(c.Request.Context(), entity)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get custom fields: "+err.Error())
+		return false
+	}
+	if err := customfields.Validate(fields, attrs, partial); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// attributeFilter reads the attr.<name>=value filters of a listing of entity,
+// parsing each value as the type of its field. Unknown fields and invalid
+// values are recorded in q; err is set when the fields could not be read.
+func attributeFilter(c *gin.Context, q *httpx.Params, repo database.Repository, entity string) (models.CustomAttributes, error) {
+	var names []string
+	query := c.Request.URL.Query()
+	for key := range query {
+		if strings.HasPrefix(key, attributeFilterPrefix) {
+			names = append(names, key)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	sort.Strings(names)
+
+	fields, err := repo.GetCustomFields(c.Request.Context(), entity)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*models.CustomField, len(fields))
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+	attrs := models.CustomAttributes{}
+	for _, key := range names {
+		f, ok := byName[strings.TrimPrefix(key, attributeFilterPrefix)]
+		if !ok {
+			q.Fail(httpx.InQuery, key, "unknown custom field")
+			continue
+		}
+		v, err := customfields.ParseValue(f, query.Get(key))
+		if err != nil {
+			q.Fail(httpx.InQuery, key, err.Error())
+			continue
+		}
+		attrs[f.Name] = v
+	}
+	return attrs, nil
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/quality"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// DataQualityHandler handles HTTP requests for the data quality scan
+type DataQualityHandler struct {
+	monitor *quality.Monitor
+}
+
+// NewDataQualityHandler creates a new DataQualityHandler instance
+func NewDataQualityHandler(monitor *quality.Monitor) *DataQualityHandler {
+	return &DataQualityHandler{monitor: monitor}
+}
+
+// GetDataQuality handles GET /admin/data-quality
+// @Summary Data quality scan
+// @Description Scan now for orphaned and inconsistent rows: productions of generators that do not exist (orphanProduction), operator grants of users that do not exist (orphanGrant), generators of deleted or merged types (generatorOfDeletedType), productions dated before the commissioning of their generator (productionBeforeCommissioning), productions above the capacity of their generator (productionOverCapacity) and types without generators (typeWithoutGenerators). Every check reports its count; findings lists at most limit rows of each
+// @Tags admin
+// @Produce json
+// @Param check query string false "Checks to run, comma-separated (all by default)"
+// @Param limit query int false "Findings listed per check (1-1000, default 100)"
+// @Success 200 {object} models.DataQualityReport
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/data-quality [get]
+func (h *DataQualityHandler) GetDataQuality(c *gin.Context) {
+	q := httpx.New(c)
+	checks := q.EnumList("check", models.QualityChecks...)
+	limit := q.Int("limit", 100, 1, 1000)
+	if !q.Valid() {
+		return
+	}
+	report, err := h.monitor.Report(c.Request.Context(), checks, limit)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to scan data quality: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/scrub"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// errDatasetTooLarge stops a dataset read that passed the row limit
+var errDatasetTooLarge = errors.New("dataset too large")
+
+// DatasetHandler handles HTTP requests for whole-dataset exports
+type DatasetHandler struct {
+	repo   database.Repository
+	scrub  *scrub.Config
+	limits *ResultLimitConfig
+}
+
+// NewDatasetHandler creates a new DatasetHandler instance
+func NewDatasetHandler(repo database.Repository, scrubConfig *scrub.Config, limits *ResultLimitConfig) *DatasetHandler {
+	return &DatasetHandler{repo: repo, scrub: scrubConfig, limits: limits}
+}
+
+// GetDataset handles GET /dataset
+// @Summary Export a dataset
+// @Description Download the types, operators, plants, regions and generators with the productions of a date range as one JSON document, read consistently. With scrub=true the dataset is made shareable by the policy of the SCRUB_* settings, described in its scrub member: operator names are pseudonymized or dropped, plants renamed, coordinates rounded or removed, source references and custom attributes removed, and generators, plants and productions get new IDs so they cannot be looked up in the API. More productions than RESULT_MAX_ROWS are answered with 413
+// @Tags dataset
+// @Produce json
+// @Param startDate query string false "Start date of the productions (YYYY-MM-DD)"
+// @Param endDate query string false "End date of the productions (YYYY-MM-DD)"
+// @Param scrub query bool false "Strip identifying details (default false)"
+// @Param readSnapshot query string false "Read snapshot ID (see POST /productions/read-snapshots) to export from"
+// @Success 200 {object} models.Dataset
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 410 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /dataset [get]
+func (h *DatasetHandler) GetDataset(c *gin.Context) {
+	q := httpx.New(c)
+	startDate, endDate := q.DateRange("startDate", "endDate")
+	scrubbed := q.Bool("scrub")
+	snapshotID := q.UUID("readSnapshot")
+	if !q.Valid() {
+		return
+	}
+
+	ds := &models.Dataset{GeneratedAt: time.Now().UTC(), StartDate: startDate, EndDate: endDate}
+	read := func(repo database.Repository) error {
+		return h.readDataset(c, repo, ds)
+	}
+	var err error
+	if snapshotID != nil {
+		err = h.repo.InReadSnapshot(c.Request.Context(), *snapshotID, read)
+	} else {
+		err = h.repo.ReadConsistent(c.Request.Context(), read)
+	}
+	if errors.Is(err, errDatasetTooLarge) {
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, fmt.Sprintf(
+			"Result too large: more than %d productions match; narrow the date range", h.limits.MaxRows))
+		return
+	}
+	if err != nil {
+		readSnapshotError(c, err, "Failed to export dataset: ")
+		return
+	}
+
+	if scrubbed != nil && *scrubbed {
+		scrub.Apply(h.scrub, ds)
+	}
+	c.Header("Content-Disposition", `attachment; filename="dataset-`+ds.GeneratedAt.Format("20060102")+`.json"`)
+	respond(c, http.StatusOK, ds, nil)
+}
+
+// readDataset fills ds from repo
+func (h *DatasetHandler) readDataset(c *gin.Context, repo database.Repository, ds *models.Dataset) error {
+	ctx := c.Request.Context()
+	var err error
+	if ds.Types, err = repo.GetAllTypes(ctx, nil); err != nil {
+		return err
+	}
+	if ds.Operators, err = repo.GetAllOperators(ctx); err != nil {
+		return err
+	}
+	if ds.Plants, err = repo.GetAllPlants(ctx, &models.PlantFilter{}); err != nil {
+		return err
+	}
+	if ds.Regions, err = repo.GetAllRegions(ctx, &models.RegionFilter{}); err != nil {
+		return err
+	}
+	if ds.Generators, err = repo.GetAllGenerators(ctx, models.GeneratorFilter{}); err != nil {
+		return err
+	}
+	filter := &models.ProductionFilter{StartDate: ds.StartDate, EndDate: ds.EndDate}
+	if h.limits.MaxRows > 0 {
+		filter.Limit = h.limits.MaxRows + 1
+	}
+	if ds.Productions, err = repo.GetAllProductions(ctx, filter); err != nil {
+		return err
+	}
+	if h.limits.MaxRows > 0 && len(ds.Productions) > h.limits.MaxRows {
+		return errDatasetTooLarge
+	}
+	// Empty lists are sent as [] rather than null
+	ds.Types = nonNil(ds.Types)
+	ds.Operators = nonNil(ds.Operators)
+	ds.Plants = nonNil(ds.Plants)
+	ds.Regions = nonNil(ds.Regions)
+	ds.Generators = nonNil(ds.Generators)
+	ds.Productions = nonNil(ds.Productions)
+	return nil
+}
+
+// nonNil returns list, or an empty list when it is nil
+func nonNil[T any](list []T) []T {
+	if list == nil {
+		return []T{}
+	}
+	return list
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/imports"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxDeadLetterPage caps a page of dead letters and the letters of one bulk resubmission
+const maxDeadLetterPage = 1000
+
+// DeadLetterHandler handles HTTP requests for the rows rejected by imports
+type DeadLetterHandler struct {
+	repo     database.Repository
+	importer *imports.Importer
+}
+
+// NewDeadLetterHandler creates a new DeadLetterHandler instance
+func NewDeadLetterHandler(repo database.Repository, importer *imports.Importer) *DeadLetterHandler {
+	return &DeadLetterHandler{repo: repo, importer: importer}
+}
+
+// GetDeadLetters handles GET /imports/dead-letters
+// @Summary List dead letters
+// @Description Rows rejected by imports, with the values read from the file and why they were rejected, newest first
+// @Tags imports
+// @Produce json
+// @Param jobId query string false "Import job ID"
+// @Param status query string false "Status (pending, resubmitted)"
+// @Param limit query int false "Page size (1-1000, default 100)"
+// @Param offset query int false "Rows to skip"
+// @Success 200 {array} models.DeadLetter
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /imports/dead-letters [get]
+func (h *DeadLetterHandler) GetDeadLetters(c *gin.Context) {
+	q := httpx.New(c)
+	filter := &models.DeadLetterFilter{
+		JobID:  q.UUID("jobId"),
+		Status: q.Enum("status", models.DeadLetterPending, models.DeadLetterResubmitted),
+		Limit:  q.Int("limit", 100, 1, maxDeadLetterPage),
+		Offset: q.Int("offset", 0, 0, math.MaxInt),
+	}
+	if !q.Valid() {
+		return
+	}
+	list, err := h.repo.GetDeadLetters(c.Request.Context(), filter)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list dead letters: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.DeadLetter{}
+	}
+	if len(list) == filter.Limit {
+		setNextLink(c, filter.Limit, filter.Offset)
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetDeadLetterByID handles GET /imports/dead-letters/:id
+// @Summary Get dead letter by ID
+// @Tags imports
+// @Produce json
+// @Param id path string true "Dead letter ID"
+// @Success 200 {object} models.DeadLetter
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /imports/dead-letters/{id} [get]
+func (h *DeadLetterHandler) GetDeadLetterByID(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	d, err := h.repo.GetDeadLetterByID(c.Request.Context(), id)
+	if err != nil {
+		respondDeadLetterError(c, "get", err)
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// UpdateDeadLetter handles PUT /imports/dead-letters/:id
+// @Summary Correct dead letter
+// @Description Replace the values of a pending dead letter before resubmitting it
+// @Tags imports
+// @Accept json
+// @Produce json
+// @Param id path string true "Dead letter ID"
+// @Param body body models.UpdateDeadLetterRequest true "Corrected values"
+// @Success 200 {object} models.DeadLetter
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /imports/dead-letters/{id} [put]
+func (h *DeadLetterHandler) UpdateDeadLetter(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	var req models.UpdateDeadLetterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	d, err := h.repo.UpdateDeadLetter(c.Request.Context(), id, &req)
+	if err != nil {
+		respondDeadLetterError(c, "update", err)
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// ResubmitDeadLetter handles POST /imports/dead-letters/:id/resubmit
+// @Summary Resubmit dead letter
+// @Description Import the values of a pending dead letter again. On success it becomes resubmitted with the created production; otherwise it stays pending with the new error, answered with 422
+// @Tags imports
+// @Produce json
+// @Param id path string true "Dead letter ID"
+// @Success 200 {object} models.DeadLetter
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /imports/dead-letters/{id}/resubmit [post]
+func (h *DeadLetterHandler) ResubmitDeadLetter(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	ctx := c.Request.Context()
+	letter, err := h.repo.GetDeadLetterByID(ctx, id)
+	if err != nil {
+		respondDeadLetterError(c, "resubmit", err)
+		return
+	}
+	d, rowErr, err := h.importer.Resubmit(ctx, letter)
+	if err != nil {
+		respondDeadLetterError(c, "resubmit", err)
+		return
+	}
+	if rowErr != nil {
+		if errors.Is(rowErr, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+rowErr.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Resubmission rejected: "+rowErr.Error())
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// ResubmitDeadLetters handles POST /imports/dead-letters/resubmit
+// @Summary Resubmit pending dead letters
+// @Description Import the pending dead letters again, those of one job when jobId is set, up to 1000 per request. Each is reported with its new status or error
+// @Tags imports
+// @Produce json
+// @Param jobId query string false "Import job ID"
+// @Success 200 {object} models.DeadLetterResubmission
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /imports/dead-letters/resubmit [post]
+func (h *DeadLetterHandler) ResubmitDeadLetters(c *gin.Context) {
+	q := httpx.New(c)
+	pending := models.DeadLetterPending
+	filter := &models.DeadLetterFilter{JobID: q.UUID("jobId"), Status: &pending, Limit: maxDeadLetterPage}
+	if !q.Valid() {
+		return
+	}
+	ctx := c.Request.Context()
+	letters, err := h.repo.GetDeadLetters(ctx, filter)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list dead letters: "+err.Error())
+		return
+	}
+
+	result := &models.DeadLetterResubmission{Items: []*models.DeadLetter{}}
+	for _, letter := range letters {
+		d, rowErr, err := h.importer.Resubmit(ctx, letter)
+		if errors.Is(err, database.ErrDeadLetterResolved) {
+			// Resubmitted by another request meanwhile
+			continue
+		}
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to resubmit dead letters: "+err.Error())
+			return
+		}
+		if rowErr != nil {
+			result.Failed++
+		} else {
+			result.Resubmitted++
+		}
+		result.Items = append(result.Items, d)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// DeleteDeadLetter handles DELETE /imports/dead-letters/:id
+// @Summary Discard dead letter
+// @Tags imports
+// @Param id path string true "Dead letter ID"
+// @Success 204
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /imports/dead-letters/{id} [delete]
+func (h *DeadLetterHandler) DeleteDeadLetter(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	if err := h.repo.DeleteDeadLetter(c.Request.Context(), id); err != nil {
+		respondDeadLetterError(c, "delete", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func respondDeadLetterError(c *gin.Context, action string, err error) {
+	switch {
+	case err == sql.ErrNoRows:
+		utils.ErrorResponse(c, http.StatusNotFound, "Dead letter not found")
+	case errors.Is(err, database.ErrDeadLetterResolved):
+		utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to "+action+" dead letter: "+err.Error())
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/imports"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// DropFolderHandler handles HTTP requests for the drop folder processing log
+type DropFolderHandler struct {
+	folder *imports.DropFolder
+}
+
+// NewDropFolderHandler creates a new DropFolderHandler instance
+func NewDropFolderHandler(folder *imports.DropFolder) *DropFolderHandler {
+	return &DropFolderHandler{
+		folder: folder,
+	}
+}
+
+// GetDropFiles handles GET /imports/drop-folder
+// @Summary Drop folder processing log
+// @Description Files picked up from IMPORT_DROP_DIR, most recent first, with their checksum, import job and outcome: archived (imported in full), rejected (failed validation, nothing imported) or failed (rows failed to import)
+// @Tags imports
+// @Produce json
+// @Param status query string false "Only files with this status (archived, rejected, failed)"
+// @Param limit query int false "Maximum number of files (default 100, up to 1000)"
+// @Success 200 {array} imports.DropFile
+// @Failure 400 {object} httpx.ErrorResponse
+// @Router /imports/drop-folder [get]
+func (h *DropFolderHandler) GetDropFiles(c *gin.Context) {
+	q := httpx.New(c)
+	status := q.Enum("status", imports.DropStatusArchived, imports.DropStatusRejected, imports.DropStatusFailed)
+	limit := q.Int("limit", 100, 1, 1000)
+	if !q.Valid() {
+		return
+	}
+
+	filter := ""
+	if status != nil {
+		filter = *status
+	}
+	c.JSON(http.StatusOK, h.folder.List(filter, limit))
+}
+
+// GetDropFile handles GET /imports/drop-folder/:id
+// @Summary Get a drop folder file
+// @Tags imports
+// @Produce json
+// @Param id path string true "Drop file ID (UUID)"
+// @Success 200 {object} imports.DropFile
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /imports/drop-folder/{id} [get]
+func (h *DropFolderHandler) GetDropFile(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+
+	f, err := h.folder.Get(id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Drop file not found")
+		return
+	}
+	c.JSON(http.StatusOK, f)
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/notify"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// EmailTemplateHandler handles HTTP requests for the notification email templates
+type EmailTemplateHandler struct {
+	repo      database.Repository
+	templates *notify.Templates
+}
+
+// NewEmailTemplateHandler creates a new EmailTemplateHandler instance
+func NewEmailTemplateHandler(repo database.Repository, templates *notify.Templates) *EmailTemplateHandler {
+	return &EmailTemplateHandler{repo: repo, templates: templates}
+}
+
+// GetEmailTemplates handles GET /admin/email-templates
+// @Summary List email templates
+// @Description The current template of every notification email, version 0 for the built-in ones, with the data their texts can use
+// @Tags admin
+// @Produce json
+// @Success 200 {array} models.EmailTemplate
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/email-templates [get]
+func (h *EmailTemplateHandler) GetEmailTemplates(c *gin.Context) {
+	list, err := h.templates.List(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list email templates: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetEmailTemplate handles GET /admin/email-templates/:key
+// @Summary Get email template
+// @Description The current template of a notification email: its latest version, or the built-in one (version 0) when it was never edited
+// @Tags admin
+// @Produce json
+// @Param key path string true "Template key (freshness-alert, slo-alert, lockout-alert, alert-digest)"
+// @Success 200 {object} models.EmailTemplate
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/email-templates/{key} [get]
+func (h *EmailTemplateHandler) GetEmailTemplate(c *gin.Context) {
+	t, err := h.templates.Current(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		respondEmailTemplateError(c, "get", err)
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// UpdateEmailTemplate handles PUT /admin/email-templates/:key
+// @Summary Edit email template
+// @Description Save a new version of a notification email, used from the next notification on. Subject and body are Go text/templates over the notification data and must render with the sample data
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param key path string true "Template key (freshness-alert, slo-alert, lockout-alert, alert-digest)"
+// @Param body body models.EmailTemplateRequest true "Email template"
+// @Success 201 {object} models.EmailTemplate
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/email-templates/{key} [put]
+func (h *EmailTemplateHandler) UpdateEmailTemplate(c *gin.Context) {
+	key := c.Param("key")
+	var req models.EmailTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if err := notify.Validate(key, req.Subject, req.Body); err != nil {
+		respondEmailTemplateError(c, "update", err)
+		return
+	}
+	h.createVersion(c, key, &req)
+}
+
+// RestoreEmailTemplateVersion handles POST /admin/email-templates/:key/versions/:version/restore
+// @Summary Restore email template version
+// @Description Save the texts of an earlier version as a new version, which becomes the current one
+// @Tags admin
+// @Produce json
+// @Param key path string true "Template key (freshness-alert, slo-alert, lockout-alert, alert-digest)"
+// @Param version path int true "Version to restore"
+// @Success 201 {object} models.EmailTemplate
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/email-templates/{key}/versions/{version}/restore [post]
+func (h *EmailTemplateHandler) RestoreEmailTemplateVersion(c *gin.Context) {
+	key, version, ok := h.versionParams(c)
+	if !ok {
+		return
+	}
+	old, err := h.repo.GetEmailTemplateVersion(c.Request.Context(), key, version)
+	if err != nil {
+		respondEmailTemplateError(c, "restore", err)
+		return
+	}
+	h.createVersion(c, key, &models.EmailTemplateRequest{
+		Subject: old.Subject,
+		Body:    old.Body,
+		Comment: fmt.Sprintf("Restored version %d", version),
+	})
+}
+
+func (h *EmailTemplateHandler) createVersion(c *gin.Context, key string, req *models.EmailTemplateRequest) {
+	var userID *uuid.UUID
+	if p, ok := auth.PrincipalFrom(c.Request.Context()); ok {
+		userID = &p.UserID
+	}
+	t, err := h.repo.CreateEmailTemplateVersion(c.Request.Context(), key, req, userID)
+	if err != nil {
+		respondEmailTemplateError(c, "update", err)
+		return
+	}
+	if builtin, err := notify.Builtin(key); err == nil {
+		t.Description = builtin.Description
+	}
+	c.JSON(http.StatusCreated, t)
+}
+
+// GetEmailTemplateVersions handles GET /admin/email-templates/:key/versions
+// @Summary List email template versions
+// @Description The saved versions of a notification email, newest first, with their author and comment
+// @Tags admin
+// @Produce json
+// @Param key path string true "Template key (freshness-alert, slo-alert, lockout-alert, alert-digest)"
+// @Success 200 {array} models.EmailTemplate
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/email-templates/{key}/versions [get]
+func (h *EmailTemplateHandler) GetEmailTemplateVersions(c *gin.Context) {
+	key := c.Param("key")
+	if _, err := notify.Builtin(key); err != nil {
+		respondEmailTemplateError(c, "list", err)
+		return
+	}
+	list, err := h.repo.GetEmailTemplateVersions(c.Request.Context(), key)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list email template versions: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.EmailTemplate{}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetEmailTemplateVersion handles GET /admin/email-templates/:key/versions/:version
+// @Summary Get email template version
+// @Tags admin
+// @Produce json
+// @Param key path string true "Template key (freshness-alert, slo-alert, lockout-alert, alert-digest)"
+// @Param version path int true "Version"
+// @Success 200 {object} models.EmailTemplate
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/email-templates/{key}/versions/{version} [get]
+func (h *EmailTemplateHandler) GetEmailTemplateVersion(c *gin.Context) {
+	key, version, ok := h.versionParams(c)
+	if !ok {
+		return
+	}
+	t, err := h.repo.GetEmailTemplateVersion(c.Request.Context(), key, version)
+	if err != nil {
+		respondEmailTemplateError(c, "get", err)
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// PreviewEmailTemplate handles POST /admin/email-templates/:key/preview
+// @Summary Preview email template
+// @Description Render a notification email without sending it: the current template, a stored version or draft texts, with sample data or the data given (in the JSON form of the alert, e.g. {"overall": true, "lagDays": 4})
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param key path string true "Template key (freshness-alert, slo-alert, lockout-alert, alert-digest)"
+// @Param body body models.EmailTemplatePreviewRequest false "Draft texts and data"
+// @Success 200 {object} models.EmailPreview
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/email-templates/{key}/preview [post]
+func (h *EmailTemplateHandler) PreviewEmailTemplate(c *gin.Context) {
+	key := c.Param("key")
+	if _, err := notify.Builtin(key); err != nil {
+		respondEmailTemplateError(c, "preview", err)
+		return
+	}
+	var req models.EmailTemplatePreviewRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	var t *models.EmailTemplate
+	var err error
+	if req.Version != nil {
+		t, err = h.repo.GetEmailTemplateVersion(ctx, key, *req.Version)
+	} else {
+		t, err = h.templates.Current(ctx, key)
+	}
+	if err != nil {
+		respondEmailTemplateError(c, "preview", err)
+		return
+	}
+	if req.Subject != nil {
+		t.Subject = *req.Subject
+	}
+	if req.Body != nil {
+		t.Body = *req.Body
+	}
+
+	data, err := notify.Data(key, req.Data)
+	if err != nil {
+		respondEmailTemplateError(c, "preview", err)
+		return
+	}
+	subject, body, err := notify.Render(t.Subject, t.Body, data)
+	if err != nil {
+		respondEmailTemplateError(c, "preview", err)
+		return
+	}
+	c.JSON(http.StatusOK, &models.EmailPreview{Key: key, Subject: subject, Body: body, Data: data})
+}
+
+// versionParams parses the key and version of a version route, answering
+// 404 for unknown keys and 400 for invalid versions
+func (h *EmailTemplateHandler) versionParams(c *gin.Context) (string, int, bool) {
+	key := c.Param("key")
+	q := httpx.New(c)
+	version := q.PathInt("version", 1, math.MaxInt)
+	if !q.Valid() {
+		return "", 0, false
+	}
+	if _, err := notify.Builtin(key); err != nil {
+		respondEmailTemplateError(c, "get", err)
+		return "", 0, false
+	}
+	return key, version, true
+}
+
+func respondEmailTemplateError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, notify.ErrUnknownTemplate):
+		utils.ErrorResponse(c, http.StatusNotFound, "Email template not found: "+c.Param("key"))
+	case err == sql.ErrNoRows:
+		utils.ErrorResponse(c, http.StatusNotFound, "Email template version not found")
+	case errors.Is(err, notify.ErrInvalidTemplate):
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, auth.ErrForbidden):
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+	case errors.Is(err, database.ErrEmailTemplateEdited):
+		utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+	case errors.Is(err, database.ErrNotSupported):
+		utils.ErrorResponse(c, http.StatusNotImplemented, "Not supported: "+err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to "+action+" email template: "+err.Error())
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxEventPage caps the events of one GET /admin/events response
+const maxEventPage = 1000
+
+// EventHandler handles HTTP requests for the domain event log and its projections
+type EventHandler struct {
+	repo database.Repository
+}
+
+// NewEventHandler creates a new EventHandler instance
+func NewEventHandler(repo database.Repository) *EventHandler {
+	return &EventHandler{repo: repo}
+}
+
+// GetEvents handles GET /admin/events
+// @Summary Read the domain event log
+// @Description Events of the append-only log in log order: every create, update and delete of types, operators, generators and productions with the row before and after it. Page with afterSeq set to the seq of the last event received
+// @Tags admin
+// @Produce json
+// @Param aggregate query string false "Aggregate: type, operator, generator or production"
+// @Param aggregateId query string false "Aggregate ID (UUID)"
+// @Param afterSeq query int false "Return events after this sequence number" default(0)
+// @Param limit query int false "Maximum events to return (up to 1000)" default(100)
+// @Success 200 {array} models.DomainEvent
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/events [get]
+func (h *EventHandler) GetEvents(c *gin.Context) {
+	filter := &models.DomainEventFilter{}
+	if a := c.Query("aggregate"); a != "" {
+		filter.Aggregate = &a
+	}
+	if v := c.Query("aggregateId"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid aggregateId: must be UUID")
+			return
+		}
+		filter.AggregateID = &id
+	}
+	if v := c.Query("afterSeq"); v != "" {
+		seq, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || seq < 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid afterSeq: must be a non-negative integer")
+			return
+		}
+		filter.AfterSeq = seq
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxEventPage {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid limit: must be between 1 and 1000")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	list, err := h.repo.GetDomainEvents(c.Request.Context(), filter)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list domain events: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.DomainEvent{}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetProjections handles GET /admin/projections
+// @Summary List projections
+// @Description Summary tables derived from the event log, with the last event each has processed and how many events it is behind
+// @Tags admin
+// @Produce json
+// @Success 200 {array} models.Projection
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/projections [get]
+func (h *EventHandler) GetProjections(c *gin.Context) {
+	list, err := h.repo.GetProjections(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list projections: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.Projection{}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// RebuildProjection handles POST /admin/projections/:name/rebuild
+// @Summary Rebuild a projection
+// @Description Recompute a projection from the event log alone, replacing its contents, e.g. after fixing an aggregation bug. Only unrestricted users may rebuild
+// @Tags admin
+// @Produce json
+// @Param name path string true "Projection name" example(daily_production_summary)
+// @Success 200 {object} models.Projection
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/projections/{name}/rebuild [post]
+func (h *EventHandler) RebuildProjection(c *gin.Context) {
+	p, err := h.repo.RebuildProjection(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if errors.Is(err, database.ErrUnknownProjection) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Projection not found")
+			return
+		}
+		if errors.Is(err, database.ErrNotSupported) {
+			utils.ErrorResponse(c, http.StatusNotImplemented, "Not supported: "+err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to rebuild projection: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/freshness"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// FreshnessHandler handles HTTP requests for the data freshness indicator
+type FreshnessHandler struct {
+	monitor *freshness.Monitor
+}
+
+// NewFreshnessHandler creates a new FreshnessHandler instance
+func NewFreshnessHandler(monitor *freshness.Monitor) *FreshnessHandler {
+	return &FreshnessHandler{
+		monitor: monitor,
+	}
+}
+
+// GetFreshness handles GET /analytics/freshness
+// @Summary Data freshness
+// @Description Most recent production date overall and per generator with the lag in days against today. Data lagging more than maxLagDays (FRESHNESS_MAX_LAG_DAYS by default) is flagged stale; generators that never reported count from their creation date
+// @Tags analytics
+// @Produce json
+// @Param maxLagDays query int false "Days data may lag before it is stale"
+// @Param staleOnly query bool false "Only list stale generators"
+// @Success 200 {object} models.Freshness
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /analytics/freshness [get]
+func (h *FreshnessHandler) GetFreshness(c *gin.Context) {
+	maxLag := -1
+	if v := c.Query("maxLagDays"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid maxLagDays: must be a non-negative integer")
+			return
+		}
+		maxLag = n
+	}
+
+	f, err := h.monitor.Report(c.Request.Context(), maxLag)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get freshness: "+err.Error())
+		return
+	}
+	if c.Query("staleOnly") == "true" {
+		stale := []*models.GeneratorFreshness{}
+		for _, g := range f.Generators {
+			if g.Stale {
+				stale = append(stale, g)
+			}
+		}
+		f.Generators = stale
+	}
+
+	c.JSON(http.StatusOK, f)
+}
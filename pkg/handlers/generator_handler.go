@@ -2,10 +2,16 @@ package handlers
 
 import (
     "database/sql"
+    "errors"
     "net/http"
+    "strings"
 
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
     "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/geo"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
     "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/redact"
     "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
     "github.com/gin-gonic/gin"
     "github.com/google/uuid"
@@ -28,7 +34,9 @@ func NewGeneratorHandler(repo database.Repository) *GeneratorHandler {
 // @Param body body models.CreateGeneratorRequest true "Generator data"
 // @Success 201 {object} models.Generator
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
 // @Router /generators [post]
 func (h *GeneratorHandler) CreateGenerator(c *gin.Context) {
     var req models.CreateGeneratorRequest
@@ -36,12 +44,19 @@ func (h *GeneratorHandler) CreateGenerator(c *gin.Context) {
         utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
         return
     }
+    if !checkAttributes(c, h.repo, models.CustomEntityGenerator, req.CustomAttributes, false) {
+        return
+    }
     gen, err := h.repo.CreateGenerator(c.Request.Context(), &req)
     if err != nil {
+        if errors.Is(err, auth.ErrForbidden) {
+            utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+            return
+        }
         utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create generator: "+err.Error())
         return
     }
-    c.JSON(http.StatusCreated, gen)
+    respond(c, http.StatusCreated, gen, nil)
 }
 
 // GetGeneratorByID handles GET /generators/:id
@@ -50,15 +65,15 @@ func (h *GeneratorHandler) CreateGenerator(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Generator ID"
 // @Success 200 {object} models.Generator
-// @Failure 400 {object} models.ErrorResponse
+// @Header 200 {string} ETag "Version of the generator, for If-Match on updates"
+// @Failure 400 {object} httpx.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /generators/{id} [get]
 func (h *GeneratorHandler) GetGeneratorByID(c *gin.Context) {
-    idStr := c.Param("id")
-    id, err := uuid.Parse(idStr)
-    if err != nil {
-        utils.ErrorResponse(c, http.StatusBadRequest, "Invalid generator ID: must be UUID")
+    q := httpx.New(c)
+    id := q.PathUUID("id")
+    if !q.Valid() {
         return
     }
     gen, err := h.repo.GetGeneratorByID(c.Request.Context(), id)
@@ -70,36 +85,180 @@ func (h *GeneratorHandler) GetGeneratorByID(c *gin.Context) {
         utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get generator: "+err.Error())
         return
     }
-    c.JSON(http.StatusOK, gen)
+    setVersion(c, gen.Version)
+    respond(c, http.StatusOK, gen, nil)
+}
+
+// GetCapacityFactor handles GET /generators/:id/capacity-factor
+// @Summary Capacity factor trend of a generator
+// @Description Capacity factor per day, week (from Monday), month or year: the average production of the days with records as a percentage of the current capacity, null for periods without records. smoothing=sma adds the simple moving average of the last window periods and ema the exponential moving average with a span of window periods, so a degrading generator shows as a falling line. Periods run without gaps from the start date (or the first record) to the end date (or the last record)
+// @Tags generators
+// @Produce json
+// @Param id path string true "Generator ID"
+// @Param groupBy query string false "Period (day, week, month, year)" default(day)
+// @Param smoothing query string false "Moving average (none, sma, ema)" default(none)
+// @Param window query int false "Periods of the moving average (2-366)" default(7)
+// @Param startDate query string false "Start date (YYYY-MM-DD)"
+// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Success 200 {object} models.CapacityFactorTrend
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /generators/{id}/capacity-factor [get]
+func (h *GeneratorHandler) GetCapacityFactor(c *gin.Context) {
+    q := httpx.New(c)
+    id := q.PathUUID("id")
+    query := &models.CapacityFactorQuery{
+        GroupBy:   models.BucketDay,
+        Smoothing: models.SmoothingNone,
+        Window:    q.Int("window", 7, 2, 366),
+    }
+    if groupBy := q.Enum("groupBy", models.TimeSeriesBuckets...); groupBy != nil {
+        query.GroupBy = *groupBy
+    }
+    if smoothing := q.Enum("smoothing", models.Smoothings...); smoothing != nil {
+        query.Smoothing = *smoothing
+    }
+    query.StartDate, query.EndDate = q.DateRange("startDate", "endDate")
+    if !q.Valid() {
+        return
+    }
+    trend, err := h.repo.GetCapacityFactorTrend(c.Request.Context(), id, query)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            utils.ErrorResponse(c, http.StatusNotFound, "Generator not found")
+            return
+        }
+        if errors.Is(err, database.ErrInvalidTimeSeries) {
+            utils.ErrorResponse(c, http.StatusBadRequest, "Invalid capacity factor trend: "+strings.TrimPrefix(err.Error(), database.ErrInvalidTimeSeries.Error()+": "))
+            return
+        }
+        utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get capacity factor: "+err.Error())
+        return
+    }
+    c.JSON(http.StatusOK, trend)
 }
 
 // GetAllGenerators handles GET /generators
 // @Summary List generators
-// @Description List all generators, optionally filtered by typeId
+// @Description List all generators, optionally filtered by typeId, operatorId, plantId and/or regionId. near keeps the generators within radiusKm of a point, nearest first and with their distanceKm; bbox keeps those inside a box. Location filters leave out generators without coordinates
 // @Tags generators
 // @Produce json
 // @Param typeId query string false "Type ID (UUID)"
+// @Param operatorId query string false "Operator ID (UUID)"
+// @Param plantId query string false "Plant ID (UUID)"
+// @Param regionId query string false "Region ID (UUID)"
+// @Param near query string false "Point as lat,lng (e.g. 6.2442,-75.5812)"
+// @Param radiusKm query number false "Radius around near in km (default 50, up to 20000)"
+// @Param bbox query string false "Bounding box as minLng,minLat,maxLng,maxLat"
+// @Param attr.name query string false "Custom attribute equal to the value, for each custom field (e.g. attr.gridNode=MED-4)"
 // @Success 200 {array} models.Generator
-// @Failure 400 {object} models.ErrorResponse
+// @Failure 400 {object} httpx.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /generators [get]
 func (h *GeneratorHandler) GetAllGenerators(c *gin.Context) {
-    var typeID *uuid.UUID
-    if t := c.Query("typeId"); t != "" {
-        id, err := uuid.Parse(t)
-        if err != nil {
-            utils.ErrorResponse(c, http.StatusBadRequest, "Invalid typeId: must be UUID")
-            return
-        }
-        typeID = &id
+    filter, ok := h.generatorFilter(c)
+    if !ok {
+        return
     }
-    list, err := h.repo.GetAllGenerators(c.Request.Context(), typeID)
+    list, err := h.repo.GetAllGenerators(c.Request.Context(), filter)
     if err != nil {
         utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list generators: "+err.Error())
         return
     }
     if list == nil { list = []*models.Generator{} }
-    c.JSON(http.StatusOK, list)
+    respond(c, http.StatusOK, list, nil)
+}
+
+// GetGeneratorsGeoJSON handles GET /generators.geojson
+// @Summary Generators as GeoJSON
+// @Description The generators with coordinates as a GeoJSON FeatureCollection of points, for mapping the fleet. Accepts the filters of GET /generators; the properties of each feature are the type, capacity and operator of the generator
+// @Tags generators
+// @Produce json
+// @Param typeId query string false "Type ID (UUID)"
+// @Param operatorId query string false "Operator ID (UUID)"
+// @Param plantId query string false "Plant ID (UUID)"
+// @Param regionId query string false "Region ID (UUID)"
+// @Param near query string false "Point as lat,lng (e.g. 6.2442,-75.5812)"
+// @Param radiusKm query number false "Radius around near in km (default 50, up to 20000)"
+// @Param bbox query string false "Bounding box as minLng,minLat,maxLng,maxLat"
+// @Param attr.name query string false "Custom attribute equal to the value, for each custom field (e.g. attr.gridNode=MED-4)"
+// @Success 200 {object} geo.FeatureCollection
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /generators.geojson [get]
+func (h *GeneratorHandler) GetGeneratorsGeoJSON(c *gin.Context) {
+    filter, ok := h.generatorFilter(c)
+    if !ok {
+        return
+    }
+    list, err := h.repo.GetAllGenerators(c.Request.Context(), filter)
+    if err != nil {
+        utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list generators: "+err.Error())
+        return
+    }
+    ctx := c.Request.Context()
+    var features []*geo.Feature
+    for _, g := range list {
+        loc, ok := g.Location()
+        if !ok || redact.Hidden(ctx, g, "latitude") || redact.Hidden(ctx, g, "longitude") {
+            continue
+        }
+        props := map[string]any{
+            "typeId":      g.TypeID,
+            "typeName":    g.TypeName,
+            "isRenewable": g.IsRenewable,
+            "capacity":    g.Capacity,
+        }
+        if g.OperatorID != nil {
+            props["operatorId"], props["operatorName"] = g.OperatorID, g.OperatorName
+        }
+        if g.PlantID != nil {
+            props["plantId"], props["plantName"] = g.PlantID, g.PlantName
+        }
+        if g.RegionID != nil {
+            props["regionId"], props["regionName"] = g.RegionID, g.RegionName
+        }
+        if g.DistanceKm != nil {
+            props["distanceKm"] = *g.DistanceKm
+        }
+        for name := range props {
+            if redact.Hidden(ctx, g, name) {
+                delete(props, name)
+            }
+        }
+        features = append(features, &geo.Feature{Type: "Feature", ID: g.ID.String(), Geometry: geo.PointGeometry(loc), Properties: props})
+    }
+    if redact.Enabled() {
+        c.Writer.Header().Add("Vary", "Authorization")
+    }
+    c.Header("Content-Type", geo.ContentType)
+    c.JSON(http.StatusOK, geo.NewFeatureCollection(features))
+}
+
+// generatorFilter parses the filters of the generator listings; false when
+// a parameter was invalid and the request was answered
+func (h *GeneratorHandler) generatorFilter(c *gin.Context) (models.GeneratorFilter, bool) {
+    q := httpx.New(c)
+    filter := models.GeneratorFilter{
+        TypeID:     q.UUID("typeId"),
+        OperatorID: q.UUID("operatorId"),
+        PlantID:    q.UUID("plantId"),
+        RegionID:   q.UUID("regionId"),
+        Near:       q.Point("near"),
+        RadiusKm:   q.Float("radiusKm", 50, 0, 20000),
+        Within:     q.BBox("bbox"),
+    }
+    if c.Query("radiusKm") != "" && filter.Near == nil {
+        q.Fail(httpx.InQuery, "radiusKm", "needs near")
+    }
+    attrs, err := attributeFilter(c, q, h.repo, models.CustomEntityGenerator)
+    if err != nil {
+        utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get custom fields: "+err.Error())
+        return filter, false
+    }
+    filter.Attributes = attrs
+    return filter, q.Valid()
 }
 
 // UpdateGenerator handles PUT /generators/:id
@@ -109,16 +268,20 @@ func (h *GeneratorHandler) GetAllGenerators(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Generator ID"
 // @Param body body models.UpdateGeneratorRequest true "Update data"
+// @Param If-Match header string false "ETag of the generator as read; the update fails with 412 if it changed since"
 // @Success 200 {object} models.Generator
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 412 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
 // @Router /generators/{id} [put]
 func (h *GeneratorHandler) UpdateGenerator(c *gin.Context) {
-    idStr := c.Param("id")
-    id, err := uuid.Parse(idStr)
-    if err != nil {
-        utils.ErrorResponse(c, http.StatusBadRequest, "Invalid generator ID: must be UUID")
+    q := httpx.New(c)
+    id := q.PathUUID("id")
+    if !q.Valid() {
         return
     }
     var req models.UpdateGeneratorRequest
@@ -126,8 +289,73 @@ func (h *GeneratorHandler) UpdateGenerator(c *gin.Context) {
         utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
         return
     }
-    gen, err := h.repo.UpdateGenerator(c.Request.Context(), id, &req)
+    if !checkAttributes(c, h.repo, models.CustomEntityGenerator, req.CustomAttributes, true) {
+        return
+    }
+    h.saveGenerator(c, id, &req)
+}
+
+// PatchGenerator handles PATCH /generators/:id
+// @Summary Partially update generator
+// @Description Update only the fields present in the body, leaving the others as they are. null clears operatorId, plantId, regionId, commissionedOn and the location (latitude and longitude, which go together); customAttributes set to null removes them all. typeId and capacity cannot be null
+// @Tags generators
+// @Accept json
+// @Produce json
+// @Param id path string true "Generator ID"
+// @Param body body models.UpdateGeneratorRequest true "Fields to update"
+// @Param If-Match header string false "ETag of the generator as read; the update fails with 412 if it changed since"
+// @Success 200 {object} models.Generator
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 412 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /generators/{id} [patch]
+func (h *GeneratorHandler) PatchGenerator(c *gin.Context) {
+    q := httpx.New(c)
+    id := q.PathUUID("id")
+    if !q.Valid() {
+        return
+    }
+    var req models.UpdateGeneratorRequest
+    fields, ok := bindPatch(c, &req, "typeId", "capacity")
+    if !ok {
+        return
+    }
+    if fields.Has("latitude") != fields.Has("longitude") {
+        utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: latitude and longitude must be updated together")
+        return
+    }
+    req.Fields = fields
+    if !checkAttributes(c, h.repo, models.CustomEntityGenerator, req.CustomAttributes, true) {
+        return
+    }
+    h.saveGenerator(c, id, &req)
+}
+
+// saveGenerator stores the update of a generator and answers with the generator
+func (h *GeneratorHandler) saveGenerator(c *gin.Context, id uuid.UUID, req *models.UpdateGeneratorRequest) {
+    version, ok := ifMatch(c)
+    if !ok {
+        return
+    }
+    req.IfMatch = version
+    gen, err := h.repo.UpdateGenerator(c.Request.Context(), id, req)
     if err != nil {
+        if errors.Is(err, auth.ErrForbidden) {
+            utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+            return
+        }
+        if errors.Is(err, database.ErrVersionMismatch) {
+            utils.ErrorResponse(c, http.StatusPreconditionFailed, "Precondition failed: "+err.Error())
+            return
+        }
+        if errors.Is(err, database.ErrPeriodPublished) {
+            utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+            return
+        }
         if err == sql.ErrNoRows {
             utils.ErrorResponse(c, http.StatusNotFound, "Generator not found")
             return
@@ -135,27 +363,45 @@ func (h *GeneratorHandler) UpdateGenerator(c *gin.Context) {
         utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update generator: "+err.Error())
         return
     }
-    c.JSON(http.StatusOK, gen)
+    setVersion(c, gen.Version)
+    respond(c, http.StatusOK, gen, nil)
 }
 
 // DeleteGenerator handles DELETE /generators/:id
 // @Summary Delete generator
+// @Description Move a generator to the trash; restore it with POST /trash/{id}/restore. A generator that still has production records is not deleted (409) unless cascade is set, which moves them to the trash with it
 // @Tags generators
 // @Produce json
 // @Param id path string true "Generator ID"
+// @Param cascade query bool false "Also delete the production records of the generator"
 // @Success 204
-// @Failure 400 {object} models.ErrorResponse
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
 // @Router /generators/{id} [delete]
 func (h *GeneratorHandler) DeleteGenerator(c *gin.Context) {
-    idStr := c.Param("id")
-    id, err := uuid.Parse(idStr)
-    if err != nil {
-        utils.ErrorResponse(c, http.StatusBadRequest, "Invalid generator ID: must be UUID")
+    q := httpx.New(c)
+    id := q.PathUUID("id")
+    cascade := q.Bool("cascade")
+    if !q.Valid() {
         return
     }
-    if err := h.repo.DeleteGenerator(c.Request.Context(), id); err != nil {
+    if err := h.repo.DeleteGenerator(c.Request.Context(), id, cascade != nil && *cascade); err != nil {
+        if errors.Is(err, auth.ErrForbidden) {
+            utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+            return
+        }
+        if errors.Is(err, database.ErrStillReferenced) {
+            utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error()+"; delete them first or set cascade=true")
+            return
+        }
+        if errors.Is(err, database.ErrPeriodPublished) {
+            utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+            return
+        }
         if err == sql.ErrNoRows {
             utils.ErrorResponse(c, http.StatusNotFound, "Generator not found")
             return
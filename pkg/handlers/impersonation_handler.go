@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ImpersonationHandler handles HTTP requests for impersonation sessions
+type ImpersonationHandler struct {
+	repo   database.Repository
+	cfg    *auth.Config
+	tokens *auth.Tokens
+}
+
+// NewImpersonationHandler creates a new ImpersonationHandler instance
+func NewImpersonationHandler(repo database.Repository, cfg *auth.Config, tokens *auth.Tokens) *ImpersonationHandler {
+	return &ImpersonationHandler{repo: repo, cfg: cfg, tokens: tokens}
+}
+
+// StartImpersonation handles POST /admin/impersonations
+// @Summary Start impersonation
+// @Description Act as a user (userId), or as an operator user scoped to an operator (operatorId), to debug permission and visibility issues. Returns a token valid until the session ends or expires; every request made with it is recorded and its responses carry X-Impersonation-Session and X-Impersonated-By
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param body body models.ImpersonationRequest true "Whom to impersonate and why"
+// @Success 201 {object} models.ImpersonationResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/impersonations [post]
+func (h *ImpersonationHandler) StartImpersonation(c *gin.Context) {
+	actorID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	actor, _ := auth.PrincipalFrom(ctx)
+	if actor.Impersonated() || !actor.HasRole(auth.RoleAdmin) {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: only admins acting as themselves can impersonate")
+		return
+	}
+	var req models.ImpersonationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	ttl := h.cfg.ImpersonationTTL
+	if req.DurationMinutes > 0 {
+		d := time.Duration(req.DurationMinutes) * time.Minute
+		if d > ttl {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid durationMinutes: sessions last at most %s", ttl))
+			return
+		}
+		ttl = d
+	}
+
+	target := ""
+	if req.UserID != nil {
+		if *req.UserID == actorID {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid userId: admins cannot impersonate themselves")
+			return
+		}
+		user, err := h.repo.GetUserByID(ctx, *req.UserID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+				return
+			}
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start impersonation: "+err.Error())
+			return
+		}
+		target = "user " + user.Username
+	} else {
+		operator, err := h.repo.GetOperatorByID(ctx, *req.OperatorID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				utils.ErrorResponse(c, http.StatusNotFound, "Operator not found")
+				return
+			}
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start impersonation: "+err.Error())
+			return
+		}
+		target = "operator " + operator.Name
+	}
+
+	now := time.Now()
+	session := &models.Impersonation{
+		ID:         uuid.New(),
+		ActorID:    actorID,
+		UserID:     req.UserID,
+		OperatorID: req.OperatorID,
+		Reason:     req.Reason,
+		StartedAt:  now,
+		ExpiresAt:  now.Add(ttl),
+		Active:     true,
+	}
+	if err := h.repo.CreateImpersonation(ctx, session); err != nil {
+		if errors.Is(err, database.ErrNotSupported) {
+			utils.ErrorResponse(c, http.StatusNotImplemented, "Not supported: "+err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start impersonation: "+err.Error())
+		return
+	}
+	token, err := h.tokens.IssueImpersonation(actorID, session.ID, session.ExpiresAt, actor.TwoFactor)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to issue access token: "+err.Error())
+		return
+	}
+	utils.LogInfo(fmt.Sprintf("Impersonation %s started: admin %s acts as %s until %s (%s)",
+		session.ID, actorID, target, session.ExpiresAt.Format(time.RFC3339), session.Reason))
+
+	c.JSON(http.StatusCreated, &models.ImpersonationResponse{
+		Session:     session,
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresAt:   session.ExpiresAt,
+	})
+}
+
+// GetImpersonations handles GET /admin/impersonations
+// @Summary List impersonation sessions
+// @Description Impersonation sessions, newest first
+// @Tags admin
+// @Produce json
+// @Param actorId query string false "Admin who impersonated"
+// @Param userId query string false "Impersonated user"
+// @Param active query bool false "Only sessions that have (or have not) ended or expired"
+// @Param limit query int false "Page size (1-1000, default 100)"
+// @Param offset query int false "Sessions to skip"
+// @Success 200 {array} models.Impersonation
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/impersonations [get]
+func (h *ImpersonationHandler) GetImpersonations(c *gin.Context) {
+	q := httpx.New(c)
+	filter := &models.ImpersonationFilter{
+		ActorID: q.UUID("actorId"),
+		UserID:  q.UUID("userId"),
+		Active:  q.Bool("active"),
+		Limit:   q.Int("limit", 100, 1, 1000),
+		Offset:  q.Int("offset", 0, 0, math.MaxInt),
+	}
+	if !q.Valid() {
+		return
+	}
+	list, err := h.repo.GetImpersonations(c.Request.Context(), filter)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list impersonations: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.Impersonation{}
+	}
+	if len(list) == filter.Limit {
+		setNextLink(c, filter.Limit, filter.Offset)
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetImpersonation handles GET /admin/impersonations/:id
+// @Summary Get impersonation session
+// @Tags admin
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} models.Impersonation
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/impersonations/{id} [get]
+func (h *ImpersonationHandler) GetImpersonation(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	session, err := h.repo.GetImpersonation(c.Request.Context(), id)
+	if err != nil {
+		respondImpersonationError(c, "get", err)
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// EndImpersonation handles POST /admin/impersonations/:id/end
+// @Summary End impersonation
+// @Description End an impersonation session; its token is rejected from then on. Send the own token of an admin, not the one of the session
+// @Tags admin
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} models.Impersonation
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/impersonations/{id}/end [post]
+func (h *ImpersonationHandler) EndImpersonation(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	session, err := h.repo.EndImpersonation(c.Request.Context(), id)
+	if err != nil {
+		respondImpersonationError(c, "end", err)
+		return
+	}
+	utils.LogInfo(fmt.Sprintf("Impersonation %s ended", session.ID))
+	c.JSON(http.StatusOK, session)
+}
+
+// GetImpersonationEvents handles GET /admin/impersonations/:id/events
+// @Summary List impersonation requests
+// @Description The requests made with the token of an impersonation session, in the order they were made
+// @Tags admin
+// @Produce json
+// @Param id path string true "Session ID"
+// @Param limit query int false "Page size (1-1000, default 100)"
+// @Param offset query int false "Requests to skip"
+// @Success 200 {array} models.ImpersonationEvent
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/impersonations/{id}/events [get]
+func (h *ImpersonationHandler) GetImpersonationEvents(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	limit := q.Int("limit", 100, 1, 1000)
+	offset := q.Int("offset", 0, 0, math.MaxInt)
+	if !q.Valid() {
+		return
+	}
+	ctx := c.Request.Context()
+	if _, err := h.repo.GetImpersonation(ctx, id); err != nil {
+		respondImpersonationError(c, "get", err)
+		return
+	}
+	list, err := h.repo.GetImpersonationEvents(ctx, id, limit, offset)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list impersonation events: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.ImpersonationEvent{}
+	}
+	if len(list) == limit {
+		setNextLink(c, limit, offset)
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func respondImpersonationError(c *gin.Context, action string, err error) {
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(c, http.StatusNotFound, "Impersonation not found")
+		return
+	}
+	utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to "+action+" impersonation: "+err.Error())
+}
@@ -0,0 +1,496 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/imports"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/storage"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// presignedKeyMetadata is the job metadata key holding the object storage key of the file
+const presignedKeyMetadata = "storageKey"
+
+// ImportHandler handles HTTP requests for bulk file imports
+type ImportHandler struct {
+	importer *imports.Importer
+	uploads  *imports.UploadStore
+	storage  *storage.Client
+}
+
+// NewImportHandler creates a new ImportHandler instance
+func NewImportHandler(importer *imports.Importer, uploads *imports.UploadStore, store *storage.Client) *ImportHandler {
+	return &ImportHandler{
+		importer: importer,
+		uploads:  uploads,
+		storage:  store,
+	}
+}
+
+// ImportProductions handles POST /imports/productions
+// @Summary Import productions from CSV
+// @Description Stream a CSV file (columns generatorId,date,productionMw) into production records. The file is spooled to disk, not memory, and imported row by row by a background job; send it as the raw body (text/csv) or as a multipart "file" field. The queued job is returned at once and its progress and result can be polled through GET /jobs/{id}.
+// @Tags imports
+// @Accept text/csv
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file false "CSV file"
+// @Param profileId query string false "Import profile naming the columns and delimiter of the file"
+// @Success 202 {object} jobs.Job
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /imports/productions [post]
+func (h *ImportHandler) ImportProductions(c *gin.Context) {
+	cfg := h.importer.Config()
+	if cfg.MaxBytes > 0 && c.Request.ContentLength > cfg.MaxBytes {
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Import file too large: maximum size exceeded")
+		return
+	}
+	mapping, ok := h.importMapping(c)
+	if !ok {
+		return
+	}
+
+	body, err := importBody(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid import file: "+err.Error())
+		return
+	}
+
+	// The body is gone once the request is answered
+	file, size, err := h.importer.Spool(body)
+	if err != nil {
+		h.respondImportError(c, err)
+		return
+	}
+
+	job := h.importer.NewJob(size)
+	h.setProfileMetadata(c, job.ID)
+	h.importer.RunInBackground(c.Request.Context(), job.ID, file, mapping, nil)
+
+	h.respondJob(c, http.StatusAccepted, job.ID)
+}
+
+// InitUpload handles POST /imports/uploads
+// @Summary Start a chunked upload
+// @Description Start a resumable upload for a large import file. The file is then sent in chunks of chunkSize bytes (the last one may be smaller) through PUT /imports/uploads/{id}/chunks/{index}.
+// @Tags imports
+// @Accept json
+// @Produce json
+// @Param body body models.InitUploadRequest true "Upload metadata"
+// @Success 201 {object} imports.Upload
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /imports/uploads [post]
+func (h *ImportHandler) InitUpload(c *gin.Context) {
+	var req models.InitUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	upload, err := h.uploads.Init(req.FileName, req.TotalSize, req.ChunkSize)
+	if err != nil {
+		switch {
+		case errors.Is(err, imports.ErrMaxSizeExceeded):
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Upload rejected: "+err.Error())
+		case errors.Is(err, imports.ErrChunkSize):
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid upload: "+err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start upload: "+err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, upload)
+}
+
+// GetUpload handles GET /imports/uploads/:id
+// @Summary Get chunked upload status
+// @Description Get an upload session, including the chunks still missing so an interrupted upload can be resumed
+// @Tags imports
+// @Produce json
+// @Param id path string true "Upload ID (UUID)"
+// @Success 200 {object} imports.Upload
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /imports/uploads/{id} [get]
+func (h *ImportHandler) GetUpload(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid upload ID: must be UUID")
+		return
+	}
+
+	upload, err := h.uploads.Get(id)
+	if err != nil {
+		h.respondUploadLookupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, upload)
+}
+
+// PutUploadChunk handles PUT /imports/uploads/:id/chunks/:index
+// @Summary Upload a chunk
+// @Description Upload one chunk as the raw request body. The X-Chunk-Checksum header must contain the hex SHA-256 of the chunk; chunks can be re-sent safely.
+// @Tags imports
+// @Accept application/octet-stream
+// @Produce json
+// @Param id path string true "Upload ID (UUID)"
+// @Param index path int true "Chunk index (0-based)"
+// @Param X-Chunk-Checksum header string true "SHA-256 of the chunk (hex)"
+// @Success 200 {object} imports.Upload
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /imports/uploads/{id}/chunks/{index} [put]
+func (h *ImportHandler) PutUploadChunk(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid upload ID: must be UUID")
+		return
+	}
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid chunk index: must be an integer")
+		return
+	}
+	checksum := c.GetHeader("X-Chunk-Checksum")
+	if checksum == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Missing X-Chunk-Checksum header")
+		return
+	}
+
+	upload, err := h.uploads.PutChunk(id, index, checksum, c.Request.Body)
+	if err != nil {
+		h.respondUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, upload)
+}
+
+// CompleteUpload handles POST /imports/uploads/:id/complete
+// @Summary Complete a chunked upload
+// @Description Assemble the uploaded chunks and start the import job in the background. Poll GET /jobs/{jobId} for progress.
+// @Tags imports
+// @Produce json
+// @Param id path string true "Upload ID (UUID)"
+// @Param profileId query string false "Import profile naming the columns and delimiter of the file"
+// @Success 202 {object} jobs.Job
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /imports/uploads/{id}/complete [post]
+func (h *ImportHandler) CompleteUpload(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid upload ID: must be UUID")
+		return
+	}
+	mapping, ok := h.importMapping(c)
+	if !ok {
+		return
+	}
+
+	upload, err := h.uploads.Get(id)
+	if err != nil {
+		h.respondUploadError(c, err)
+		return
+	}
+	file, err := h.uploads.Open(id)
+	if err != nil {
+		h.respondUploadError(c, err)
+		return
+	}
+
+	job := h.importer.NewJob(upload.TotalSize)
+	if _, err := h.uploads.MarkCompleted(id, job.ID); err != nil {
+		file.Close()
+		h.importer.Jobs().Fail(job.ID, err, nil)
+		h.respondUploadError(c, err)
+		return
+	}
+
+	h.setProfileMetadata(c, job.ID)
+	h.importer.RunInBackground(c.Request.Context(), job.ID, file, mapping, func(error) {
+		if err := h.uploads.RemoveChunks(id); err != nil {
+			utils.LogError("remove upload chunks "+id.String(), err)
+		}
+	})
+
+	h.respondJob(c, http.StatusAccepted, job.ID)
+}
+
+// DeleteUpload handles DELETE /imports/uploads/:id
+// @Summary Abort a chunked upload
+// @Description Delete an upload session and its stored chunks
+// @Tags imports
+// @Produce json
+// @Param id path string true "Upload ID (UUID)"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /imports/uploads/{id} [delete]
+func (h *ImportHandler) DeleteUpload(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid upload ID: must be UUID")
+		return
+	}
+
+	if err := h.uploads.Delete(id); err != nil {
+		h.respondUploadLookupError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CreatePresignedUpload handles POST /imports/presigned
+// @Summary Request a direct-to-storage upload URL
+// @Description Register an import job and return a presigned object storage URL. The client PUTs the file to uploadUrl and then calls POST /imports/presigned/{jobId}/confirm.
+// @Tags imports
+// @Accept json
+// @Produce json
+// @Param body body models.PresignedUploadRequest true "File metadata"
+// @Success 201 {object} models.PresignedUpload
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /imports/presigned [post]
+func (h *ImportHandler) CreatePresignedUpload(c *gin.Context) {
+	if !h.storage.Enabled() {
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "Object storage is not configured")
+		return
+	}
+
+	var req models.PresignedUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	// The object is named after the last element of fileName, which must be a name
+	name := path.Base(req.FileName)
+	if strings.TrimSpace(name) == "" || name == "." || name == "/" || name == ".." {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid fileName: must name a file")
+		return
+	}
+
+	job := h.importer.NewJob(0)
+	key := "imports/" + job.ID.String() + "/" + name
+	ttl := h.storage.URLTTL()
+	uploadURL, err := h.storage.PresignPut(key, ttl)
+	if err != nil {
+		h.importer.Jobs().Fail(job.ID, err, nil)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to presign upload: "+err.Error())
+		return
+	}
+	h.importer.Jobs().SetMetadata(job.ID, presignedKeyMetadata, key)
+
+	c.JSON(http.StatusCreated, models.PresignedUpload{
+		JobID:     job.ID,
+		Method:    http.MethodPut,
+		UploadURL: uploadURL,
+		Key:       key,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+}
+
+// ConfirmPresignedUpload handles POST /imports/presigned/:jobId/confirm
+// @Summary Confirm a direct-to-storage upload
+// @Description Tell the API the file was uploaded to the presigned URL; the import job then streams it from object storage in the background
+// @Tags imports
+// @Produce json
+// @Param jobId path string true "Import job ID (UUID)"
+// @Param profileId query string false "Import profile naming the columns and delimiter of the file"
+// @Success 202 {object} jobs.Job
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /imports/presigned/{jobId}/confirm [post]
+func (h *ImportHandler) ConfirmPresignedUpload(c *gin.Context) {
+	if !h.storage.Enabled() {
+		utils.ErrorResponse(c, http.StatusServiceUnavailable, "Object storage is not configured")
+		return
+	}
+
+	jobID, err := uuid.Parse(c.Param("jobId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid job ID: must be UUID")
+		return
+	}
+	mapping, ok := h.importMapping(c)
+	if !ok {
+		return
+	}
+	job, err := h.importer.Jobs().Get(jobID)
+	if err != nil || job.Metadata[presignedKeyMetadata] == "" {
+		utils.ErrorResponse(c, http.StatusNotFound, "Presigned import job not found")
+		return
+	}
+	key := job.Metadata[presignedKeyMetadata]
+	object, size, err := h.storage.Open(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			utils.ErrorResponse(c, http.StatusConflict, "Uploaded file not found in storage: upload it before confirming")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to open uploaded file: "+err.Error())
+		return
+	}
+	// Only the request that claims the job may fail or run it
+	if !h.importer.Jobs().Claim(jobID) {
+		object.Close()
+		utils.ErrorResponse(c, http.StatusConflict, "Import job already confirmed")
+		return
+	}
+	if cfg := h.importer.Config(); cfg.MaxBytes > 0 && size > cfg.MaxBytes {
+		object.Close()
+		h.importer.Jobs().Fail(jobID, imports.ErrMaxSizeExceeded, nil)
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Import file too large: maximum size exceeded")
+		return
+	}
+	h.importer.Jobs().SetTotalBytes(jobID, size)
+	h.setProfileMetadata(c, jobID)
+	h.importer.RunInBackground(c.Request.Context(), jobID, object, mapping, func(error) {
+		if err := h.storage.Delete(context.Background(), key); err != nil {
+			utils.LogError("delete imported object "+key, err)
+		}
+	})
+
+	h.respondJob(c, http.StatusAccepted, jobID)
+}
+
+// importMapping loads the mapping of the profileId query parameter, nil
+// when there is none. False when it answered the request.
+func (h *ImportHandler) importMapping(c *gin.Context) (*imports.Mapping, bool) {
+	q := httpx.New(c)
+	profileID := q.UUID("profileId")
+	if !q.Valid() {
+		return nil, false
+	}
+	if profileID == nil {
+		return nil, true
+	}
+	mapping, err := h.importer.Mapping(c.Request.Context(), *profileID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Import profile not found")
+			return nil, false
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get import profile: "+err.Error())
+		return nil, false
+	}
+	return mapping, true
+}
+
+// setProfileMetadata records on the job the profile it was imported with
+func (h *ImportHandler) setProfileMetadata(c *gin.Context, jobID uuid.UUID) {
+	if profileID := c.Query("profileId"); profileID != "" {
+		h.importer.Jobs().SetMetadata(jobID, "profileId", profileID)
+	}
+}
+
+// importBody returns a reader over the uploaded file without buffering it,
+// reading either the raw body or the first file part of a multipart request
+func importBody(c *gin.Context) (io.Reader, error) {
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return c.Request.Body, nil
+	}
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errors.New(`multipart request has no "file" part`)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+	}
+}
+
+func (h *ImportHandler) respondImportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, imports.ErrMaxSizeExceeded):
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Import aborted: "+err.Error())
+	case errors.Is(err, imports.ErrMaxRowsExceeded), errors.Is(err, imports.ErrMissingColumn),
+		errors.Is(err, imports.ErrInvalidFile):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Import aborted: "+err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Import failed: "+err.Error())
+	}
+}
+
+// respondUploadLookupError answers the errors of reading or deleting an upload
+func (h *ImportHandler) respondUploadLookupError(c *gin.Context, err error) {
+	if errors.Is(err, imports.ErrUploadNotFound) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Upload not found")
+		return
+	}
+	utils.ErrorResponse(c, http.StatusInternalServerError, "Upload failed: "+err.Error())
+}
+
+// respondUploadError answers the errors of writing chunks and completing an upload
+func (h *ImportHandler) respondUploadError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, imports.ErrUploadNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Upload not found")
+	case errors.Is(err, imports.ErrUploadFinished), errors.Is(err, imports.ErrUploadIncomplete):
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, imports.ErrChunkChecksum):
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Chunk rejected: "+err.Error())
+	case errors.Is(err, imports.ErrMaxSizeExceeded):
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Upload rejected: "+err.Error())
+	case errors.Is(err, imports.ErrChunkOutOfRange), errors.Is(err, imports.ErrChunkSize):
+		utils.ErrorResponse(c, http.StatusBadRequest, "Chunk rejected: "+err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Upload failed: "+err.Error())
+	}
+}
+
+func (h *ImportHandler) respondJob(c *gin.Context, code int, id uuid.UUID) {
+	job, err := h.importer.Jobs().Get(id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get import job: "+err.Error())
+		return
+	}
+	c.JSON(code, job)
+}
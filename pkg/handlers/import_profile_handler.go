@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ImportProfileHandler handles HTTP requests for the saved column mappings of imports
+type ImportProfileHandler struct {
+	repo database.Repository
+}
+
+// NewImportProfileHandler creates a new ImportProfileHandler instance
+func NewImportProfileHandler(repo database.Repository) *ImportProfileHandler {
+	return &ImportProfileHandler{repo: repo}
+}
+
+// GetImportProfiles handles GET /imports/profiles
+// @Summary List import profiles
+// @Description The saved column mappings, by source and name
+// @Tags imports
+// @Produce json
+// @Param source query string false "Source (provider), case-insensitive"
+// @Success 200 {array} models.ImportProfile
+// @Failure 500 {object} models.ErrorResponse
+// @Router /imports/profiles [get]
+func (h *ImportProfileHandler) GetImportProfiles(c *gin.Context) {
+	list, err := h.repo.GetImportProfiles(c.Request.Context(), strings.TrimSpace(c.Query("source")))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list import profiles: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.ImportProfile{}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetImportProfileByID handles GET /imports/profiles/:id
+// @Summary Get import profile by ID
+// @Tags imports
+// @Produce json
+// @Param id path string true "Import profile ID"
+// @Success 200 {object} models.ImportProfile
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /imports/profiles/{id} [get]
+func (h *ImportProfileHandler) GetImportProfileByID(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	p, err := h.repo.GetImportProfileByID(c.Request.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Import profile not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get import profile: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// CreateImportProfile handles POST /imports/profiles
+// @Summary Save import profile
+// @Description Save the headers of the columns and the delimiter of a provider's files. Imports then take profileId instead of the file being rewritten to the usual headers
+// @Tags imports
+// @Accept json
+// @Produce json
+// @Param body body models.CreateImportProfileRequest true "Column mapping"
+// @Success 201 {object} models.ImportProfile
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /imports/profiles [post]
+func (h *ImportProfileHandler) CreateImportProfile(c *gin.Context) {
+	var req models.CreateImportProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if !checkImportDelimiter(c, req.Delimiter) {
+		return
+	}
+	p, err := h.repo.CreateImportProfile(c.Request.Context(), &req)
+	if err != nil {
+		respondImportProfileError(c, "create", err)
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// UpdateImportProfile handles PUT /imports/profiles/:id
+// @Summary Update import profile
+// @Description Replace an import profile. Imports already running keep the mapping they started with
+// @Tags imports
+// @Accept json
+// @Produce json
+// @Param id path string true "Import profile ID"
+// @Param body body models.UpdateImportProfileRequest true "Column mapping"
+// @Success 200 {object} models.ImportProfile
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /imports/profiles/{id} [put]
+func (h *ImportProfileHandler) UpdateImportProfile(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	var req models.UpdateImportProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if !checkImportDelimiter(c, req.Delimiter) {
+		return
+	}
+	p, err := h.repo.UpdateImportProfile(c.Request.Context(), id, &req)
+	if err != nil {
+		respondImportProfileError(c, "update", err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// DeleteImportProfile handles DELETE /imports/profiles/:id
+// @Summary Delete import profile
+// @Tags imports
+// @Param id path string true "Import profile ID"
+// @Success 204
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /imports/profiles/{id} [delete]
+func (h *ImportProfileHandler) DeleteImportProfile(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	if err := h.repo.DeleteImportProfile(c.Request.Context(), id); err != nil {
+		respondImportProfileError(c, "delete", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// checkImportDelimiter rejects the delimiters a CSV reader cannot split on.
+// False when it answered the request.
+func checkImportDelimiter(c *gin.Context, delimiter string) bool {
+	if delimiter == "" || !strings.ContainsAny(delimiter, "\"\r\n") {
+		return true
+	}
+	utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: delimiter cannot be a quote or a line break")
+	return false
+}
+
+func respondImportProfileError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, auth.ErrForbidden):
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+	case err == sql.ErrNoRows:
+		utils.ErrorResponse(c, http.StatusNotFound, "Import profile not found")
+	case errors.Is(err, database.ErrImportProfileExists):
+		utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+	case errors.Is(err, database.ErrNotSupported):
+		utils.ErrorResponse(c, http.StatusNotImplemented, "Not supported: "+err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to "+action+" import profile: "+err.Error())
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/jobs"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// JobHandler handles HTTP requests for background jobs
+type JobHandler struct {
+	jobs *jobs.Manager
+}
+
+// NewJobHandler creates a new JobHandler instance
+func NewJobHandler(jobManager *jobs.Manager) *JobHandler {
+	return &JobHandler{
+		jobs: jobManager,
+	}
+}
+
+// GetAllJobs handles GET /jobs
+// @Summary List jobs
+// @Description List background jobs (imports, exports, ...), newest first
+// @Tags jobs
+// @Produce json
+// @Success 200 {array} jobs.Job
+// @Router /jobs [get]
+func (h *JobHandler) GetAllJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, h.jobs.List())
+}
+
+// GetJobByID handles GET /jobs/:id
+// @Summary Get job by ID
+// @Description Get the status and progress of a background job
+// @Tags jobs
+// @Produce json
+// @Param id path string true "Job ID (UUID)"
+// @Success 200 {object} jobs.Job
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /jobs/{id} [get]
+func (h *JobHandler) GetJobByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid job ID: must be UUID")
+		return
+	}
+
+	job, err := h.jobs.Get(id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/live"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/redact"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// LiveHandler handles the live production feeds
+type LiveHandler struct {
+	hub *live.Hub
+	cfg *live.Config
+}
+
+// NewLiveHandler creates a new LiveHandler instance
+func NewLiveHandler(hub *live.Hub, cfg *live.Config) *LiveHandler {
+	return &LiveHandler{hub: hub, cfg: cfg}
+}
+
+// ProductionsSocket handles GET /ws/productions
+// @Summary Live feed of production records over a WebSocket
+// @Description Upgrades to a WebSocket and sends a text message, a models.ProductionEvent, for every production record created or updated through the API from then on. generatorId or typeId only send the records of one generator or of the generators of one type. Messages from the client are ignored; the server pings idle connections. A client that falls too far behind is disconnected with close code 1013 and should reconnect and re-read what it missed
+// @Tags productions
+// @Produce json
+// @Param generatorId query string false "Only records of this generator (UUID)"
+// @Param typeId query string false "Only records of generators of this type (UUID)"
+// @Success 101 {object} models.ProductionEvent
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 426 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /ws/productions [get]
+func (h *LiveHandler) ProductionsSocket(c *gin.Context) {
+	q := httpx.New(c)
+	filter := live.Filter{GeneratorID: q.UUID("generatorId"), TypeID: q.UUID("typeId")}
+	if !q.Valid() {
+		return
+	}
+	if !live.IsWebSocket(c.Request) {
+		c.Header("Upgrade", "websocket")
+		utils.ErrorResponse(c, http.StatusUpgradeRequired, "Upgrade required: connect with a WebSocket client")
+		return
+	}
+
+	sub, err := h.hub.Subscribe(c.Request.Context(), filter)
+	if err != nil {
+		if errors.Is(err, live.ErrTooManySubscribers) {
+			c.Header("Retry-After", "30")
+			utils.ErrorResponse(c, http.StatusServiceUnavailable, "Service unavailable: "+err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to subscribe: "+err.Error())
+		return
+	}
+	defer sub.Close()
+
+	conn, err := live.Upgrade(c.Writer, c.Request)
+	if err != nil {
+		if errors.Is(err, live.ErrNotWebSocket) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid WebSocket handshake: "+err.Error())
+			return
+		}
+		utils.LogError("live: upgrade", err)
+		return
+	}
+	defer conn.Close(live.CloseGoingAway, "")
+
+	// The request context is not cancelled when a hijacked connection ends
+	done := make(chan struct{})
+	go func() {
+		_ = conn.Drain()
+		close(done)
+	}()
+
+	var ping <-chan time.Time
+	if h.cfg.PingInterval > 0 {
+		ticker := time.NewTicker(h.cfg.PingInterval)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+	for {
+		select {
+		case <-done:
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				conn.Close(live.CloseTryAgainLater, "too far behind, reconnect")
+				return
+			}
+			// Events go through the redaction policy as responses do
+			message, _ := redact.Apply(c.Request.Context(), event)
+			if err := conn.WriteJSON(message); err != nil {
+				return
+			}
+		case <-ping:
+			if err := conn.Ping(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// ProductionsStream handles GET /stream/productions
+// @Summary Live feed of production records as Server-Sent Events
+// @Description The live feed of /ws/productions as a text/event-stream, for clients behind proxies that do not pass WebSockets. Every event has the ID, the type (created or updated) and, as data, the models.ProductionEvent. A client reconnecting with Last-Event-ID, as EventSource does, first gets the events it missed; when some of them are no longer kept the stream starts with a reset event and the client should re-read the records. Comments are sent on idle streams. A client that falls too far behind is disconnected and resumes by reconnecting
+// @Tags productions
+// @Produce text/event-stream
+// @Param generatorId query string false "Only records of this generator (UUID)"
+// @Param typeId query string false "Only records of generators of this type (UUID)"
+// @Param Last-Event-ID header string false "ID of the last event received, to resume after it"
+// @Success 200 {object} models.ProductionEvent
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /stream/productions [get]
+func (h *LiveHandler) ProductionsStream(c *gin.Context) {
+	q := httpx.New(c)
+	filter := live.Filter{GeneratorID: q.UUID("generatorId"), TypeID: q.UUID("typeId")}
+	if !q.Valid() {
+		return
+	}
+
+	ctx := c.Request.Context()
+	var sub *live.Subscription
+	backlog := &live.Backlog{Complete: true}
+	var err error
+	if lastEventID := c.GetHeader("Last-Event-ID"); lastEventID != "" {
+		sub, backlog, err = h.hub.SubscribeAfter(ctx, filter, lastEventID)
+	} else {
+		sub, err = h.hub.Subscribe(ctx, filter)
+	}
+	if err != nil {
+		if errors.Is(err, live.ErrTooManySubscribers) {
+			c.Header("Retry-After", "30")
+			utils.ErrorResponse(c, http.StatusServiceUnavailable, "Service unavailable: "+err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to subscribe: "+err.Error())
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	// Keep reverse proxies such as nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	if !backlog.Complete {
+		fmt.Fprint(c.Writer, "event: reset\ndata: {\"error\":\"events after Last-Event-ID are no longer kept; re-read the records\"}\n\n")
+	}
+	for _, event := range backlog.Events {
+		if writeEvent(c, event) != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	var ping <-chan time.Time
+	if h.cfg.PingInterval > 0 {
+		ticker := time.NewTicker(h.cfg.PingInterval)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				// Too far behind: the client reconnects and resumes from its last event
+				return
+			}
+			if writeEvent(c, event) != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-ping:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeEvent writes event to a stream, through the redaction policy as
+// responses are
+func writeEvent(c *gin.Context, event *models.ProductionEvent) error {
+	message, _ := redact.Apply(c.Request.Context(), event)
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
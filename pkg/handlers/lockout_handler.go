@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// LockoutHandler handles HTTP requests for the failed logins and lockouts of usernames
+type LockoutHandler struct {
+	repo database.Repository
+}
+
+// NewLockoutHandler creates a new LockoutHandler instance
+func NewLockoutHandler(repo database.Repository) *LockoutHandler {
+	return &LockoutHandler{repo: repo}
+}
+
+// GetLoginLockouts handles GET /admin/login-lockouts
+// @Summary List login lockouts
+// @Description Usernames with failed logins since their last successful one, most recent failure first, whether an account exists or not
+// @Tags admin
+// @Produce json
+// @Param locked query bool false "Only usernames locked now (true) or not locked (false)"
+// @Param limit query int false "Page size (1-1000, default 100)"
+// @Param offset query int false "Usernames to skip"
+// @Success 200 {array} models.LoginLockout
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/login-lockouts [get]
+func (h *LockoutHandler) GetLoginLockouts(c *gin.Context) {
+	q := httpx.New(c)
+	filter := &models.LoginLockoutFilter{
+		Locked: q.Bool("locked"),
+		Limit:  q.Int("limit", 100, 1, 1000),
+		Offset: q.Int("offset", 0, 0, math.MaxInt),
+	}
+	if !q.Valid() {
+		return
+	}
+	list, err := h.repo.GetLoginLockouts(c.Request.Context(), filter)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list login lockouts: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.LoginLockout{}
+	}
+	if len(list) == filter.Limit {
+		setNextLink(c, filter.Limit, filter.Offset)
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// UnlockLogin handles POST /admin/login-lockouts/:username/unlock
+// @Summary Unlock login
+// @Description Lift the lock of a username and restart its count of failed logins. Its earlier lockouts still double the next lock until a successful login
+// @Tags admin
+// @Produce json
+// @Param username path string true "Username"
+// @Success 200 {object} models.LoginLockout
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/login-lockouts/{username}/unlock [post]
+func (h *LockoutHandler) UnlockLogin(c *gin.Context) {
+	username := c.Param("username")
+	l, err := h.repo.UnlockLogin(c.Request.Context(), username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Login lockout not found: "+username+" has no failed logins")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to unlock login: "+err.Error())
+		return
+	}
+	utils.LogInfo(fmt.Sprintf("Login of %q unlocked", l.Username))
+	c.JSON(http.StatusOK, l)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/geo"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/numeric"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/redact"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// mapCellsPerTile splits each 256 px map tile into 64 px cluster cells
+const mapCellsPerTile = 4
+
+// MapHandler handles HTTP requests for the dashboard map
+type MapHandler struct {
+	repo database.Repository
+}
+
+// NewMapHandler creates a new MapHandler instance
+func NewMapHandler(repo database.Repository) *MapHandler {
+	return &MapHandler{
+		repo: repo,
+	}
+}
+
+// mapCluster accumulates the generators of a grid cell
+type mapCluster struct {
+	generators        []*models.Generator
+	latSum, lngSum    float64
+	capacity          decimal.Decimal
+	renewableCapacity decimal.Decimal
+}
+
+// GetMapGenerators handles GET /map/generators
+// @Summary Clustered generator points
+// @Description Generators inside bbox grouped into clusters for the map at the given zoom level, so maps stay responsive with thousands of units. Generators closer than about 64 px at that zoom share a cluster, placed at the mean of their positions, with their count and total and renewable capacity; clusters of a single generator carry its id and type. Returned as a GeoJSON FeatureCollection, largest capacity first
+// @Tags generators
+// @Produce json
+// @Param bbox query string true "Visible area as minLng,minLat,maxLng,maxLat"
+// @Param zoom query int true "Web map zoom level (0 to 22)"
+// @Param typeId query string false "Type ID (UUID)"
+// @Param operatorId query string false "Operator ID (UUID)"
+// @Param plantId query string false "Plant ID (UUID)"
+// @Param regionId query string false "Region ID (UUID)"
+// @Success 200 {object} geo.FeatureCollection
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /map/generators [get]
+func (h *MapHandler) GetMapGenerators(c *gin.Context) {
+	q := httpx.New(c)
+	bbox := q.BBox("bbox")
+	zoom := q.Int("zoom", 0, 0, geo.MaxZoom)
+	filter := models.GeneratorFilter{
+		TypeID:     q.UUID("typeId"),
+		OperatorID: q.UUID("operatorId"),
+		PlantID:    q.UUID("plantId"),
+		RegionID:   q.UUID("regionId"),
+		Within:     bbox,
+	}
+	if c.Query("bbox") == "" {
+		q.Fail(httpx.InQuery, "bbox", "is required")
+	}
+	if c.Query("zoom") == "" {
+		q.Fail(httpx.InQuery, "zoom", "is required")
+	}
+	if !q.Valid() {
+		return
+	}
+
+	ctx := c.Request.Context()
+	list, err := h.repo.GetAllGenerators(ctx, filter)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list generators: "+err.Error())
+		return
+	}
+	// Generators whose position the redaction policy hides are left off the map
+	hidden := func(field string) bool { return redact.Hidden(ctx, models.Generator{}, field) }
+	cells := map[[2]int]*mapCluster{}
+	for _, g := range list {
+		loc, ok := g.Location()
+		if !ok || hidden("latitude") || hidden("longitude") {
+			continue
+		}
+		x, y := geo.GridCell(loc, zoom, mapCellsPerTile)
+		cl, ok := cells[[2]int{x, y}]
+		if !ok {
+			cl = &mapCluster{}
+			cells[[2]int{x, y}] = cl
+		}
+		cl.generators = append(cl.generators, g)
+		cl.latSum += loc.Lat
+		cl.lngSum += loc.Lng
+		cl.capacity = cl.capacity.Add(g.Capacity)
+		if g.IsRenewable {
+			cl.renewableCapacity = cl.renewableCapacity.Add(g.Capacity)
+		}
+	}
+
+	features := make([]*geo.Feature, 0, len(cells))
+	// The order stays by capacity when the policy hides it from the response
+	capacity := make(map[string]decimal.Decimal, len(cells))
+	for key, cl := range cells {
+		n := len(cl.generators)
+		props := map[string]any{
+			"cluster": n > 1,
+			"count":   n,
+		}
+		if !hidden("capacity") {
+			props["capacity"] = numeric.RoundDecimal(cl.capacity)
+			props["renewableCapacity"] = numeric.RoundDecimal(cl.renewableCapacity)
+		}
+		id := "cluster-" + strconv.Itoa(zoom) + "-" + strconv.Itoa(key[0]) + "-" + strconv.Itoa(key[1])
+		if n == 1 {
+			g := cl.generators[0]
+			id = g.ID.String()
+			props["generatorId"] = g.ID
+			if !hidden("typeName") {
+				props["typeName"] = g.TypeName
+			}
+			if !hidden("isRenewable") {
+				props["isRenewable"] = g.IsRenewable
+			}
+		}
+		capacity[id] = cl.capacity
+		center := geo.Point{Lat: cl.latSum / float64(n), Lng: cl.lngSum / float64(n)}
+		features = append(features, &geo.Feature{Type: "Feature", ID: id, Geometry: geo.PointGeometry(center), Properties: props})
+	}
+	sort.Slice(features, func(i, j int) bool {
+		ci, cj := capacity[features[i].ID], capacity[features[j].ID]
+		if !ci.Equal(cj) {
+			return ci.GreaterThan(cj)
+		}
+		return features[i].ID < features[j].ID
+	})
+	if redact.Enabled() {
+		c.Writer.Header().Add("Vary", "Authorization")
+	}
+	c.Header("Content-Type", geo.ContentType)
+	c.JSON(http.StatusOK, geo.NewFeatureCollection(features))
+}
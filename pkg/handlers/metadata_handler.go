@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/metadata"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// MetadataHandler handles HTTP requests for the data dictionary
+type MetadataHandler struct{}
+
+// NewMetadataHandler creates a new MetadataHandler instance
+func NewMetadataHandler() *MetadataHandler {
+	return &MetadataHandler{}
+}
+
+// GetFields handles GET /metadata/fields
+// @Summary Data dictionary
+// @Description Fields of each API resource (type, generator, operator, production) with JSON type and format, unit, English and Spanish labels, description, example and the validation rules of the create request (required, constraints, enum), so clients can render forms. Fields the create request does not accept are readOnly
+// @Tags metadata
+// @Produce json
+// @Param resource query string false "Only this resource (type, generator, operator, production)"
+// @Success 200 {array} metadata.Resource
+// @Failure 400 {object} models.ErrorResponse
+// @Router /metadata/fields [get]
+func (h *MetadataHandler) GetFields(c *gin.Context) {
+	name := c.Query("resource")
+	if name == "" {
+		c.JSON(http.StatusOK, metadata.All())
+		return
+	}
+
+	r, ok := metadata.ByName(name)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid resource: must be one of "+strings.Join(metadata.Names(), ", "))
+		return
+	}
+	c.JSON(http.StatusOK, []metadata.Resource{*r})
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/pb"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/redact"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ugorji/go/codec"
+)
+
+// Body is a response body: the value rendered as JSON and, on endpoints
+// with a protobuf message, the function building that message
+type Body struct {
+	Value any
+	Proto func() []byte
+}
+
+// Encoder renders response bodies in a media type other than JSON
+type Encoder interface {
+	// CanEncode reports whether b has an encoding, e.g. only endpoints with
+	// a protobuf message can answer protobuf
+	CanEncode(b Body) bool
+	Encode(b Body) ([]byte, error)
+}
+
+type registeredEncoder struct {
+	mime    string
+	encoder Encoder
+}
+
+// encoders are the media types responses can be negotiated to besides
+// JSON, which stays the default
+var encoders []registeredEncoder
+
+// RegisterEncoder lets clients ask for mime with the Accept header on the
+// endpoints that answer through respond
+func RegisterEncoder(mime string, e Encoder) {
+	encoders = append(encoders, registeredEncoder{mime: mime, encoder: e})
+}
+
+func init() {
+	RegisterEncoder(pb.MIMEType, protobufEncoder{})
+	RegisterEncoder(binding.MIMEMSGPACK2, msgpackEncoder{})
+	RegisterEncoder(binding.MIMEMSGPACK, msgpackEncoder{})
+}
+
+// respond writes v as JSON, or in the registered media type the Accept
+// header prefers; proto builds the protobuf message of endpoints that have
+// one. The fields the redaction policy hides from the caller are left out,
+// in every media type but protobuf, which is then not offered.
+func respond(c *gin.Context, code int, v any, proto func() []byte) {
+	c.Writer.Header().Add("Vary", "Accept")
+	if redact.Enabled() {
+		c.Writer.Header().Add("Vary", "Authorization")
+		if redacted, ok := redact.Apply(c.Request.Context(), v); ok {
+			v, proto = redacted, nil
+		}
+	}
+	body := Body{Value: v, Proto: proto}
+
+	offered := []string{gin.MIMEJSON}
+	for _, e := range encoders {
+		if e.encoder.CanEncode(body) {
+			offered = append(offered, e.mime)
+		}
+	}
+	mime := c.NegotiateFormat(offered...)
+	for _, e := range encoders {
+		if e.mime != mime || !e.encoder.CanEncode(body) {
+			continue
+		}
+		data, err := e.encoder.Encode(body)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to encode response: "+err.Error())
+			return
+		}
+		c.Data(code, mime, data)
+		return
+	}
+	c.JSON(code, v)
+}
+
+// protobufEncoder renders the messages of tadb.proto
+type protobufEncoder struct{}
+
+func (protobufEncoder) CanEncode(b Body) bool { return b.Proto != nil }
+
+func (protobufEncoder) Encode(b Body) ([]byte, error) { return b.Proto(), nil }
+
+// msgpackHandle writes the current MessagePack spec (str8 and bin types)
+var msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+
+// msgpackEncoder renders any body as MessagePack with the field names and
+// values of its JSON rendering: decimals become numbers and times RFC 3339
+// strings, as they are in JSON
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) CanEncode(Body) bool { return true }
+
+func (msgpackEncoder) Encode(b Body) ([]byte, error) {
+	data, err := json.Marshal(b.Value)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	if err := codec.NewEncoderBytes(&out, msgpackHandle).Encode(msgpackValue(v)); err != nil {
+		return nil, fmt.Errorf("msgpack: %w", err)
+	}
+	return out, nil
+}
+
+// msgpackValue turns the JSON numbers of v into integers where they are
+// whole and floats otherwise
+func msgpackValue(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, e := range v {
+			v[k] = msgpackValue(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = msgpackValue(e)
+		}
+	}
+	return v
+}
+
+// negotiateLanguage returns the language of supported the Accept-Language
+// header prefers, matching on the primary subtag (es-CO is es); empty when
+// it names none of them. It sets Vary, and Content-Language when one matched.
+func negotiateLanguage(c *gin.Context, supported ...string) string {
+	c.Writer.Header().Add("Vary", "Accept-Language")
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		for _, lang := range supported {
+			if primary == lang && q > bestQ {
+				best, bestQ = lang, q
+			}
+		}
+	}
+	if best != "" {
+		c.Header("Content-Language", best)
+	}
+	return best
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler handles HTTP requests for the in-app inbox of the current user
+type NotificationHandler struct {
+	repo database.Repository
+}
+
+// NewNotificationHandler creates a new NotificationHandler instance
+func NewNotificationHandler(repo database.Repository) *NotificationHandler {
+	return &NotificationHandler{repo: repo}
+}
+
+// GetNotifications handles GET /users/me/notifications
+// @Summary List notifications
+// @Description Alerts in the inbox of the current user, newest first. X-Unread-Count carries the number of unread notifications
+// @Tags users
+// @Produce json
+// @Param unread query bool false "Only unread notifications"
+// @Param alertType query string false "Alert type (freshness, slo, security)"
+// @Param limit query int false "Page size (1-1000, default 100)"
+// @Param offset query int false "Notifications to skip"
+// @Success 200 {array} models.Notification
+// @Header 200 {integer} X-Unread-Count "Unread notifications"
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/notifications [get]
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	q := httpx.New(c)
+	filter := &models.NotificationFilter{
+		UserID:    userID,
+		AlertType: q.Enum("alertType", models.AlertTypes...),
+		Limit:     q.Int("limit", 100, 1, 1000),
+		Offset:    q.Int("offset", 0, 0, math.MaxInt),
+	}
+	unread := q.Bool("unread")
+	if !q.Valid() {
+		return
+	}
+	filter.Unread = unread != nil && *unread
+
+	ctx := c.Request.Context()
+	list, err := h.repo.GetNotifications(ctx, filter)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list notifications: "+err.Error())
+		return
+	}
+	counts, err := h.repo.GetUnreadNotifications(ctx, userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list notifications: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.Notification{}
+	}
+	if len(list) == filter.Limit {
+		setNextLink(c, filter.Limit, filter.Offset)
+	}
+	c.Header("X-Unread-Count", strconv.Itoa(counts.Unread))
+	c.JSON(http.StatusOK, list)
+}
+
+// GetUnreadNotifications handles GET /users/me/notifications/unread
+// @Summary Count unread notifications
+// @Description Unread notifications in the inbox of the current user, in total and by alert type
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.UnreadNotifications
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/notifications/unread [get]
+func (h *NotificationHandler) GetUnreadNotifications(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	counts, err := h.repo.GetUnreadNotifications(c.Request.Context(), userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to count unread notifications: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, counts)
+}
+
+// MarkNotificationRead handles POST /users/me/notifications/:id/read
+// @Summary Mark notification read
+// @Tags users
+// @Produce json
+// @Param id path string true "Notification ID"
+// @Success 200 {object} models.Notification
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/notifications/{id}/read [post]
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	n, err := h.repo.MarkNotificationRead(c.Request.Context(), userID, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Notification not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to mark notification read: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, n)
+}
+
+// MarkNotificationsRead handles POST /users/me/notifications/read
+// @Summary Mark notifications read
+// @Description Mark every unread notification of the current user read, or those of one alert type
+// @Tags users
+// @Produce json
+// @Param alertType query string false "Alert type (freshness, slo, security)"
+// @Success 200 {object} models.MarkNotificationsReadResult
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/notifications/read [post]
+func (h *NotificationHandler) MarkNotificationsRead(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	q := httpx.New(c)
+	alertType := q.Enum("alertType", models.AlertTypes...)
+	if !q.Valid() {
+		return
+	}
+	n, err := h.repo.MarkNotificationsRead(c.Request.Context(), userID, alertType)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to mark notifications read: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, &models.MarkNotificationsReadResult{Marked: n})
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/notify"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationPreferenceHandler handles HTTP requests for the notification
+// preferences of the current user
+type NotificationPreferenceHandler struct {
+	repo       database.Repository
+	dispatcher *notify.Dispatcher
+}
+
+// NewNotificationPreferenceHandler creates a new NotificationPreferenceHandler instance
+func NewNotificationPreferenceHandler(repo database.Repository, dispatcher *notify.Dispatcher) *NotificationPreferenceHandler {
+	return &NotificationPreferenceHandler{repo: repo, dispatcher: dispatcher}
+}
+
+// GetNotificationPreferences handles GET /users/me/notification-preferences
+// @Summary Get notification preferences
+// @Description How the current user is told about every alert type; types the user never chose show the default with default=true
+// @Tags users
+// @Produce json
+// @Success 200 {array} models.NotificationPreference
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/notification-preferences [get]
+func (h *NotificationPreferenceHandler) GetNotificationPreferences(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	list, err := h.dispatcher.Preferences(c.Request.Context(), userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get notification preferences: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// SetNotificationPreference handles PUT /users/me/notification-preferences/:alertType
+// @Summary Set notification preference
+// @Description Choose how the current user is told about an alert type: immediate (an email per alert), digest (one email a day with the alerts raised since the last one) or mute
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param alertType path string true "Alert type (freshness, slo, security)"
+// @Param body body models.NotificationPreferenceRequest true "Delivery and channels"
+// @Success 200 {object} models.NotificationPreference
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/notification-preferences/{alertType} [put]
+func (h *NotificationPreferenceHandler) SetNotificationPreference(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	alertType, ok := alertTypeParam(c)
+	if !ok {
+		return
+	}
+	var req models.NotificationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if len(req.Channels) == 0 {
+		req.Channels = []string{models.ChannelEmail}
+	}
+	slices.Sort(req.Channels)
+	req.Channels = slices.Compact(req.Channels)
+
+	p, err := h.repo.SetNotificationPreference(c.Request.Context(), userID, alertType, &req)
+	if err != nil {
+		if errors.Is(err, database.ErrNotSupported) {
+			utils.ErrorResponse(c, http.StatusNotImplemented, "Not supported: "+err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to set notification preference: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// DeleteNotificationPreference handles DELETE /users/me/notification-preferences/:alertType
+// @Summary Reset notification preference
+// @Description Drop the choice of the current user for an alert type, which falls back to the default
+// @Tags users
+// @Produce json
+// @Param alertType path string true "Alert type (freshness, slo, security)"
+// @Success 204
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/notification-preferences/{alertType} [delete]
+func (h *NotificationPreferenceHandler) DeleteNotificationPreference(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	alertType, ok := alertTypeParam(c)
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteNotificationPreference(c.Request.Context(), userID, alertType); err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Notification preference not found: the default applies to "+alertType)
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete notification preference: "+err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// alertTypeParam returns the alert type of the route, answering 404 to unknown ones
+func alertTypeParam(c *gin.Context) (string, bool) {
+	alertType := c.Param("alertType")
+	if !slices.Contains(models.AlertTypes, alertType) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Alert type not found: "+alertType)
+		return "", false
+	}
+	return alertType, true
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// OperatorHandler handles HTTP requests for generator operators (companies)
+type OperatorHandler struct {
+	repo database.Repository
+}
+
+// NewOperatorHandler creates a new OperatorHandler instance
+func NewOperatorHandler(repo database.Repository) *OperatorHandler {
+	return &OperatorHandler{
+		repo: repo,
+	}
+}
+
+// CreateOperator handles POST /operators
+// @Summary Create operator
+// @Description Create a new company owning or operating generators
+// @Tags operators
+// @Accept json
+// @Produce json
+// @Param operator body models.CreateOperatorRequest true "Operator data"
+// @Success 201 {object} models.Operator
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /operators [post]
+func (h *OperatorHandler) CreateOperator(c *gin.Context) {
+	var req models.CreateOperatorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if !checkAttributes(c, h.repo, models.CustomEntityOperator, req.CustomAttributes, false) {
+		return
+	}
+
+	op, err := h.repo.CreateOperator(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create operator: "+err.Error())
+		return
+	}
+
+	respond(c, http.StatusCreated, op, nil)
+}
+
+// GetOperatorByID handles GET /operators/:id
+// @Summary Get operator by ID
+// @Tags operators
+// @Produce json
+// @Param id path string true "Operator ID (UUID)"
+// @Success 200 {object} models.Operator
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /operators/{id} [get]
+func (h *OperatorHandler) GetOperatorByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid operator ID: ID must be a valid UUID")
+		return
+	}
+
+	op, err := h.repo.GetOperatorByID(c.Request.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Operator not found: No operator found with the given ID")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get operator: "+err.Error())
+		return
+	}
+
+	respond(c, http.StatusOK, op, nil)
+}
+
+// GetAllOperators handles GET /operators
+// @Summary List operators
+// @Tags operators
+// @Produce json
+// @Success 200 {array} models.Operator
+// @Failure 500 {object} models.ErrorResponse
+// @Router /operators [get]
+func (h *OperatorHandler) GetAllOperators(c *gin.Context) {
+	list, err := h.repo.GetAllOperators(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get operators: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.Operator{}
+	}
+
+	respond(c, http.StatusOK, list, nil)
+}
+
+// UpdateOperator handles PUT /operators/:id
+// @Summary Update operator
+// @Tags operators
+// @Accept json
+// @Produce json
+// @Param id path string true "Operator ID (UUID)"
+// @Param operator body models.UpdateOperatorRequest true "Updated operator data"
+// @Success 200 {object} models.Operator
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /operators/{id} [put]
+func (h *OperatorHandler) UpdateOperator(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid operator ID: ID must be a valid UUID")
+		return
+	}
+
+	var req models.UpdateOperatorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if !checkAttributes(c, h.repo, models.CustomEntityOperator, req.CustomAttributes, true) {
+		return
+	}
+
+	op, err := h.repo.UpdateOperator(c.Request.Context(), id, &req)
+	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Operator not found: No operator found with the given ID")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update operator: "+err.Error())
+		return
+	}
+
+	respond(c, http.StatusOK, op, nil)
+}
+
+// DeleteOperator handles DELETE /operators/:id
+// @Summary Delete operator
+// @Description Move an operator to the trash; its generators are kept without operator until it is restored
+// @Tags operators
+// @Produce json
+// @Param id path string true "Operator ID (UUID)"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /operators/{id} [delete]
+func (h *OperatorHandler) DeleteOperator(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid operator ID: ID must be a valid UUID")
+		return
+	}
+
+	if err := h.repo.DeleteOperator(c.Request.Context(), id); err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Operator not found: No operator found with the given ID")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete operator: "+err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
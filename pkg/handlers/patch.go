@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// bindPatch binds the body of a PATCH into req and returns the members it
+// has, so the update writes only those. The members of notNull are required
+// fields of the resource and cannot be null; the others are cleared by null.
+func bindPatch(c *gin.Context, req any, notNull ...string) (models.Fields, bool) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Failed to read request body: "+err.Error())
+		return nil, false
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil || members == nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: expected a JSON object")
+		return nil, false
+	}
+	for _, name := range notNull {
+		if raw, ok := members[name]; ok && bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+name+" cannot be null")
+			return nil, false
+		}
+	}
+	if err := binding.JSON.BindBody(body, req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return nil, false
+	}
+	fields := models.Fields{}
+	for name := range members {
+		fields[name] = true
+	}
+	return fields, true
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// PlantHandler handles HTTP requests for plants (facilities grouping generators)
+type PlantHandler struct {
+	repo database.Repository
+}
+
+// NewPlantHandler creates a new PlantHandler instance
+func NewPlantHandler(repo database.Repository) *PlantHandler {
+	return &PlantHandler{repo: repo}
+}
+
+// CreatePlant handles POST /plants
+// @Summary Create plant
+// @Description Create a new plant (facility) to group generators under; its owner is an operator
+// @Tags plants
+// @Accept json
+// @Produce json
+// @Param plant body models.CreatePlantRequest true "Plant data"
+// @Success 201 {object} models.Plant
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /plants [post]
+func (h *PlantHandler) CreatePlant(c *gin.Context) {
+	var req models.CreatePlantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	plant, err := h.repo.CreatePlant(c.Request.Context(), &req)
+	if err != nil {
+		respondPlantError(c, "create", err)
+		return
+	}
+	respond(c, http.StatusCreated, plant, nil)
+}
+
+// GetPlantByID handles GET /plants/:id
+// @Summary Get plant by ID
+// @Tags plants
+// @Produce json
+// @Param id path string true "Plant ID (UUID)"
+// @Success 200 {object} models.Plant
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /plants/{id} [get]
+func (h *PlantHandler) GetPlantByID(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	plant, err := h.repo.GetPlantByID(c.Request.Context(), id)
+	if err != nil {
+		respondPlantError(c, "get", err)
+		return
+	}
+	respond(c, http.StatusOK, plant, nil)
+}
+
+// GetAllPlants handles GET /plants
+// @Summary List plants
+// @Description Plants ordered by name, with the count and total capacity of their generators
+// @Tags plants
+// @Produce json
+// @Param ownerId query string false "Owning operator ID (UUID)"
+// @Param limit query int false "Page size (1-1000, default 100)"
+// @Param offset query int false "Plants to skip"
+// @Success 200 {array} models.Plant
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /plants [get]
+func (h *PlantHandler) GetAllPlants(c *gin.Context) {
+	q := httpx.New(c)
+	filter := &models.PlantFilter{
+		OwnerID: q.UUID("ownerId"),
+		Limit:   q.Int("limit", 100, 1, 1000),
+		Offset:  q.Int("offset", 0, 0, math.MaxInt),
+	}
+	if !q.Valid() {
+		return
+	}
+	list, err := h.repo.GetAllPlants(c.Request.Context(), filter)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get plants: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.Plant{}
+	}
+	if len(list) == filter.Limit {
+		setNextLink(c, filter.Limit, filter.Offset)
+	}
+	respond(c, http.StatusOK, list, nil)
+}
+
+// UpdatePlant handles PUT /plants/:id
+// @Summary Update plant
+// @Tags plants
+// @Accept json
+// @Produce json
+// @Param id path string true "Plant ID (UUID)"
+// @Param plant body models.UpdatePlantRequest true "Updated plant data"
+// @Success 200 {object} models.Plant
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /plants/{id} [put]
+func (h *PlantHandler) UpdatePlant(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	var req models.UpdatePlantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	plant, err := h.repo.UpdatePlant(c.Request.Context(), id, &req)
+	if err != nil {
+		respondPlantError(c, "update", err)
+		return
+	}
+	respond(c, http.StatusOK, plant, nil)
+}
+
+// DeletePlant handles DELETE /plants/:id
+// @Summary Delete plant
+// @Description Delete a plant; its generators are kept without plant
+// @Tags plants
+// @Produce json
+// @Param id path string true "Plant ID (UUID)"
+// @Success 204
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /plants/{id} [delete]
+func (h *PlantHandler) DeletePlant(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	if err := h.repo.DeletePlant(c.Request.Context(), id); err != nil {
+		respondPlantError(c, "delete", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func respondPlantError(c *gin.Context, action string, err error) {
+	switch {
+	case err == sql.ErrNoRows:
+		utils.ErrorResponse(c, http.StatusNotFound, "Plant not found: No plant found with the given ID")
+	case errors.Is(err, auth.ErrForbidden):
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+	case errors.Is(err, database.ErrPlantExists):
+		utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to "+action+" plant: "+err.Error())
+	}
+}
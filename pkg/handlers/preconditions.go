@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ifMatch reads the If-Match header of an update: the ETag of the record as
+// the client read it, e.g. "3". Without the header, or with *, the update
+// applies to any version.
+func ifMatch(c *gin.Context) (*int, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil, true
+	}
+	tag, ok := strings.CutPrefix(header, `"`)
+	tag, closed := strings.CutSuffix(tag, `"`)
+	version, err := strconv.Atoi(tag)
+	if !ok || !closed || err != nil || version < 1 {
+		utils.ErrorResponse(c, http.StatusBadRequest, `Invalid If-Match: must be the ETag of the record, e.g. "3"`)
+		return nil, false
+	}
+	return &version, true
+}
+
+// setVersion sends the version of a record as its ETag
+func setVersion(c *gin.Context, version int) {
+	if version > 0 {
+		c.Header("ETag", `"`+strconv.Itoa(version)+`"`)
+	}
+}
@@ -1,33 +1,56 @@
 package handlers
 
 import (
+    "context"
     "database/sql"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "math"
     "net/http"
+    "strings"
+    "time"
 
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/customfields"
     "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/export"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
     "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/pb"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/provenance"
+    "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/redact"
     "github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
     "github.com/gin-gonic/gin"
+    "github.com/gin-gonic/gin/binding"
     "github.com/google/uuid"
 )
 
 type ProductionHandler struct {
-    repo database.Repository
+    repo   database.Repository
+    limits *ResultLimitConfig
 }
 
-func NewProductionHandler(repo database.Repository) *ProductionHandler {
-    return &ProductionHandler{repo: repo}
+func NewProductionHandler(repo database.Repository, limits *ResultLimitConfig) *ProductionHandler {
+    return &ProductionHandler{repo: repo, limits: limits}
 }
 
 // CreateProduction handles POST /productions
 // @Summary Create production record
+// @Description A generator has one record per date: a second one is rejected with 409, unless upsert=true, which replaces the value and source of the existing record and merges its custom attributes
 // @Tags productions
 // @Accept json
 // @Produce json
 // @Param body body models.CreateProductionRequest true "Production data"
+// @Param upsert query bool false "Update the record of the generator and date if there is one"
+// @Success 200 {object} models.Production
 // @Success 201 {object} models.Production
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
 // @Router /productions [post]
 func (h *ProductionHandler) CreateProduction(c *gin.Context) {
     var req models.CreateProductionRequest
@@ -35,12 +58,255 @@ func (h *ProductionHandler) CreateProduction(c *gin.Context) {
         utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
         return
     }
-    pr, err := h.repo.CreateProduction(c.Request.Context(), &req)
+    q := httpx.New(c)
+    upsert := q.Bool("upsert")
+    if !q.Valid() {
+        return
+    }
+    if !checkAttributes(c, h.repo, models.CustomEntityProduction, req.CustomAttributes, false) {
+        return
+    }
+
+    var pr *models.Production
+    var err error
+    created := true
+    if upsert != nil && *upsert {
+        pr, created, err = h.repo.UpsertProduction(c.Request.Context(), &req)
+    } else {
+        pr, err = h.repo.CreateProduction(c.Request.Context(), &req)
+    }
     if err != nil {
+        if errors.Is(err, auth.ErrForbidden) {
+            utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+            return
+        }
+        if errors.Is(err, database.ErrInvalidDate) {
+            utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+            return
+        }
+        if errors.Is(err, database.ErrDuplicateProduction) {
+            utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+database.ErrDuplicateProduction.Error()+"; update it or set upsert=true")
+            return
+        }
+        if errors.Is(err, database.ErrPeriodPublished) {
+            utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+            return
+        }
         utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create production: "+err.Error())
         return
     }
-    c.JSON(http.StatusCreated, pr)
+    if !created {
+        respond(c, http.StatusOK, pr, nil)
+        return
+    }
+    respond(c, http.StatusCreated, pr, nil)
+}
+
+// maxBulkProductions caps the records of one bulk request
+const maxBulkProductions = 5000
+
+// CreateProductions handles POST /productions/bulk
+// @Summary Create production records in bulk
+// @Description Create up to 5000 production records, e.g. the daily readings of a whole fleet, in one transaction. Every record is validated and written on its own: the response reports each one at its index, with its status and the created record or the error. Answers 201 when every record was created and 207 when some failed
+// @Tags productions
+// @Accept json
+// @Produce json
+// @Param body body []models.CreateProductionRequest true "Production records"
+// @Success 201 {object} models.BulkProductionResult
+// @Success 207 {object} models.BulkProductionResult
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /productions/bulk [post]
+func (h *ProductionHandler) CreateProductions(c *gin.Context) {
+    var reqs []*models.CreateProductionRequest
+    if err := json.NewDecoder(c.Request.Body).Decode(&reqs); err != nil {
+        utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: expected an array of production records: "+err.Error())
+        return
+    }
+    if len(reqs) == 0 {
+        utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: no production records")
+        return
+    }
+    if len(reqs) > maxBulkProductions {
+        utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("Too many records: at most %d per request", maxBulkProductions))
+        return
+    }
+
+    fields, err := h.repo.GetCustomFields(c.Request.Context(), models.CustomEntityProduction)
+    if err != nil {
+        utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get custom fields: "+err.Error())
+        return
+    }
+
+    result := &models.BulkProductionResult{Items: make([]*models.BulkProductionItem, len(reqs))}
+    var valid []*models.CreateProductionRequest
+    var at []int
+    for i, req := range reqs {
+        result.Items[i] = &models.BulkProductionItem{Index: i}
+        if req == nil {
+            result.Items[i].Status, result.Items[i].Error = http.StatusBadRequest, "record is null"
+            continue
+        }
+        if err := binding.Validator.ValidateStruct(req); err != nil {
+            result.Items[i].Status, result.Items[i].Error = http.StatusBadRequest, err.Error()
+            continue
+        }
+        if err := customfields.Validate(fields, req.CustomAttributes, false); err != nil {
+            result.Items[i].Status, result.Items[i].Error = http.StatusBadRequest, err.Error()
+            continue
+        }
+        valid = append(valid, req)
+        at = append(at, i)
+    }
+
+    created, errs, err := h.repo.CreateProductions(c.Request.Context(), valid)
+    if err != nil {
+        utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create productions: "+err.Error())
+        return
+    }
+    for j, i := range at {
+        setBulkItem(result.Items[i], http.StatusCreated, created[j], errs[j])
+    }
+    for _, item := range result.Items {
+        if item.Production != nil {
+            result.Created++
+        } else {
+            result.Failed++
+        }
+    }
+
+    status := http.StatusCreated
+    if result.Failed > 0 {
+        status = http.StatusMultiStatus
+    }
+    c.JSON(status, result)
+}
+
+// UpdateProductions handles PATCH /productions/bulk
+// @Summary Update production records in bulk
+// @Description Apply up to 5000 updates ({id, changes}, where changes is the body of PUT /productions/{id}) in one transaction, e.g. to correct values after a unit conversion mistake. Every update is validated and applied on its own and reported at its index. With atomic=true any failure rolls back the whole request and the updates that succeeded report 424. Answers 200 when every record was updated and 207 otherwise
+// @Tags productions
+// @Accept json
+// @Produce json
+// @Param atomic query bool false "Apply all updates or none"
+// @Param body body []models.BulkProductionUpdate true "Updates"
+// @Success 200 {object} models.BulkProductionUpdateResult
+// @Success 207 {object} models.BulkProductionUpdateResult
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /productions/bulk [patch]
+func (h *ProductionHandler) UpdateProductions(c *gin.Context) {
+    q := httpx.New(c)
+    atomic := q.Bool("atomic")
+    if !q.Valid() {
+        return
+    }
+    var updates []*models.BulkProductionUpdate
+    if err := json.NewDecoder(c.Request.Body).Decode(&updates); err != nil {
+        utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: expected an array of {id, changes}: "+err.Error())
+        return
+    }
+    if len(updates) == 0 {
+        utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: no updates")
+        return
+    }
+    if len(updates) > maxBulkProductions {
+        utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("Too many records: at most %d per request", maxBulkProductions))
+        return
+    }
+    fields, err := h.repo.GetCustomFields(c.Request.Context(), models.CustomEntityProduction)
+    if err != nil {
+        utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get custom fields: "+err.Error())
+        return
+    }
+
+    result := &models.BulkProductionUpdateResult{Items: make([]*models.BulkProductionItem, len(updates))}
+    var valid []*models.BulkProductionUpdate
+    var at []int
+    for i, u := range updates {
+        result.Items[i] = &models.BulkProductionItem{Index: i}
+        if reason := bulkUpdateError(u, fields); reason != "" {
+            result.Items[i].Status, result.Items[i].Error = http.StatusBadRequest, reason
+            continue
+        }
+        // An edit through the API replaces the provenance of the record
+        if u.Changes.Source == "" {
+            u.Changes.Source = provenance.SourceAPI
+        }
+        valid = append(valid, u)
+        at = append(at, i)
+    }
+
+    updated := make([]*models.Production, len(valid))
+    errs := make([]error, len(valid))
+    if atomic != nil && *atomic && len(valid) < len(updates) {
+        for j := range errs {
+            errs[j] = database.ErrBulkRolledBack
+        }
+    } else {
+        updated, errs, err = h.repo.UpdateProductions(c.Request.Context(), valid, atomic != nil && *atomic)
+        if err != nil {
+            utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update productions: "+err.Error())
+            return
+        }
+    }
+    for j, i := range at {
+        setBulkItem(result.Items[i], http.StatusOK, updated[j], errs[j])
+    }
+    for _, item := range result.Items {
+        if item.Production != nil {
+            result.Updated++
+        } else {
+            result.Failed++
+        }
+    }
+
+    status := http.StatusOK
+    if result.Failed > 0 {
+        status = http.StatusMultiStatus
+    }
+    c.JSON(status, result)
+}
+
+// bulkUpdateError checks one update of a bulk request; empty when it is valid
+func bulkUpdateError(u *models.BulkProductionUpdate, fields []*models.CustomField) string {
+    if u == nil {
+        return "record is null"
+    }
+    if err := binding.Validator.ValidateStruct(u); err != nil {
+        return err.Error()
+    }
+    if len(u.Changes.CustomAttributes) > 0 {
+        if err := customfields.Validate(fields, u.Changes.CustomAttributes, true); err != nil {
+            return err.Error()
+        }
+    }
+    return ""
+}
+
+// setBulkItem reports the outcome of one record of a bulk request; ok is its
+// status when it was written
+func setBulkItem(item *models.BulkProductionItem, ok int, p *models.Production, err error) {
+    switch {
+    case err == nil:
+        item.Status, item.Production = ok, p
+    case errors.Is(err, auth.ErrForbidden):
+        item.Status, item.Error = http.StatusForbidden, err.Error()
+    case errors.Is(err, database.ErrInvalidDate):
+        item.Status, item.Error = http.StatusBadRequest, err.Error()
+    case errors.Is(err, database.ErrPeriodPublished), errors.Is(err, database.ErrDuplicateProduction):
+        item.Status, item.Error = http.StatusConflict, err.Error()
+    case errors.Is(err, database.ErrBulkRolledBack):
+        item.Status, item.Error = http.StatusFailedDependency, err.Error()
+    case errors.Is(err, sql.ErrNoRows):
+        item.Status, item.Error = http.StatusNotFound, err.Error()
+    default:
+        item.Status, item.Error = http.StatusInternalServerError, err.Error()
+    }
 }
 
 // GetProductionByID handles GET /productions/:id
@@ -48,16 +314,18 @@ func (h *ProductionHandler) CreateProduction(c *gin.Context) {
 // @Tags productions
 // @Produce json
 // @Param id path string true "Production ID"
+// @Param compute query string false "Computed fields to add, comma-separated (capacityFactor, utilization)"
 // @Success 200 {object} models.Production
-// @Failure 400 {object} models.ErrorResponse
+// @Header 200 {string} ETag "Version of the production, for If-Match on updates"
+// @Failure 400 {object} httpx.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /productions/{id} [get]
 func (h *ProductionHandler) GetProductionByID(c *gin.Context) {
-    idStr := c.Param("id")
-    id, err := uuid.Parse(idStr)
-    if err != nil {
-        utils.ErrorResponse(c, http.StatusBadRequest, "Invalid production ID: must be UUID")
+    q := httpx.New(c)
+    id := q.PathUUID("id")
+    compute := q.EnumList("compute", models.ComputedProductionFields...)
+    if !q.Valid() {
         return
     }
     pr, err := h.repo.GetProductionByID(c.Request.Context(), id)
@@ -69,45 +337,377 @@ func (h *ProductionHandler) GetProductionByID(c *gin.Context) {
         utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get production: "+err.Error())
         return
     }
-    c.JSON(http.StatusOK, pr)
+    if err := h.repo.ComputeProductions(c.Request.Context(), []*models.Production{pr}, compute); err != nil {
+        utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get production: "+err.Error())
+        return
+    }
+    setVersion(c, pr.Version)
+    respond(c, http.StatusOK, pr, nil)
 }
 
 // GetAllProductions handles GET /productions with mixed search
 // @Summary List productions (filter by generator/date range)
-// @Description List all productions, optionally filtered by generatorId and startDate/endDate (YYYY-MM-DD)
+// @Description List all productions, optionally filtered by generatorId, startDate/endDate (YYYY-MM-DD) and provenance source. compute adds server-computed fields: capacityFactor (productionMw as a percentage of the generator's capacity) and utilization (as a percentage of the generator's highest recorded production)
 // @Tags productions
-// @Produce json
+// @Produce json,application/x-protobuf,application/msgpack
 // @Param generatorId query string false "Generator ID (UUID)"
+// @Param plantId query string false "Plant ID (UUID); the productions of its generators"
 // @Param startDate query string false "Start date (YYYY-MM-DD)"
 // @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Param source query string false "Provenance source (manual, api, import, external, telemetry)"
+// @Param attr.name query string false "Custom attribute equal to the value, for each custom field (e.g. attr.meterId=M-12)"
+// @Param limit query int false "Page size (capped by the deployment's row limit)"
+// @Param offset query int false "Rows to skip"
+// @Param compute query string false "Computed fields to add, comma-separated (capacityFactor, utilization)"
+// @Param readSnapshot query string false "Read snapshot ID (see POST /productions/read-snapshots); every page reads the data as of its opening"
 // @Success 200 {array} models.Production
-// @Failure 400 {object} models.ErrorResponse
+// @Header 200 {string} Link "Next page, when there are more rows"
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 410 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /productions [get]
 func (h *ProductionHandler) GetAllProductions(c *gin.Context) {
-    var genID *uuid.UUID
-    if g := c.Query("generatorId"); g != "" {
-        id, err := uuid.Parse(g)
-        if err != nil {
-            utils.ErrorResponse(c, http.StatusBadRequest, "Invalid generatorId: must be UUID")
+    q := httpx.New(c)
+    filter, err := h.productionFilterParams(c, q)
+    if err != nil {
+        utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get custom fields: "+err.Error())
+        return
+    }
+    limit := q.Int("limit", 0, 1, math.MaxInt)
+    offset := q.Int("offset", 0, 0, math.MaxInt)
+    filter.Compute = q.EnumList("compute", models.ComputedProductionFields...)
+    snapshotID := q.UUID("readSnapshot")
+    if !q.Valid() {
+        return
+    }
+    filter.Offset = offset
+    // Fetch one extra row to know whether another page exists
+    size := h.limits.pageSize(limit)
+    if size > 0 {
+        filter.Limit = size + 1
+    }
+    ctx := c.Request.Context()
+    var list []*models.Production
+    read := func(repo database.Repository) error {
+        if list, err = repo.GetAllProductions(ctx, filter); err != nil {
+            return err
+        }
+        return repo.ComputeProductions(ctx, list, filter.Compute)
+    }
+    if snapshotID != nil {
+        err = h.repo.InReadSnapshot(ctx, *snapshotID, read)
+    } else {
+        err = read(h.repo)
+    }
+    if err != nil {
+        readSnapshotError(c, err, "Failed to list productions: ")
+        return
+    }
+    if size > 0 && len(list) > size {
+        if limit == 0 && h.limits.overflowed(c, "productions") {
             return
         }
-        genID = &id
+        list = list[:size]
+        setNextLink(c, size, offset)
     }
-    var start, end *string
-    if s := c.Query("startDate"); s != "" {
-        start = &s
+    if list == nil { list = []*models.Production{} }
+    respond(c, http.StatusOK, list, func() []byte { return pb.ProductionList(list) })
+}
+
+// OpenReadSnapshot handles POST /productions/read-snapshots
+// @Summary Open a read snapshot
+// @Description Pin the production listing and export to the data as of now: pass the returned id as readSnapshot and every page reads the same repeatable-read snapshot, unaffected by records written since, until the snapshot expires or is closed. Snapshots hold a database connection each, so few can be open at once
+// @Tags productions
+// @Produce json
+// @Success 201 {object} models.ReadSnapshot
+// @Failure 501 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /productions/read-snapshots [post]
+func (h *ProductionHandler) OpenReadSnapshot(c *gin.Context) {
+    snapshot, err := h.repo.OpenReadSnapshot(c.Request.Context())
+    if err != nil {
+        readSnapshotError(c, err, "Failed to open read snapshot: ")
+        return
     }
-    if e := c.Query("endDate"); e != "" {
-        end = &e
+    c.JSON(http.StatusCreated, snapshot)
+}
+
+// CloseReadSnapshot handles DELETE /productions/read-snapshots/:id
+// @Summary Close a read snapshot
+// @Description Release a read snapshot before it expires, once the walk it pinned is done
+// @Tags productions
+// @Produce json
+// @Param id path string true "Read snapshot ID"
+// @Success 204
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 410 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /productions/read-snapshots/{id} [delete]
+func (h *ProductionHandler) CloseReadSnapshot(c *gin.Context) {
+    q := httpx.New(c)
+    id := q.PathUUID("id")
+    if !q.Valid() {
+        return
+    }
+    if err := h.repo.CloseReadSnapshot(c.Request.Context(), id); err != nil {
+        readSnapshotError(c, err, "Failed to close read snapshot: ")
+        return
+    }
+    c.Status(http.StatusNoContent)
+}
+
+// readSnapshotError answers the errors of reads through read snapshots
+func readSnapshotError(c *gin.Context, err error, prefix string) {
+    switch {
+    case errors.Is(err, database.ErrReadSnapshotNotFound):
+        utils.ErrorResponse(c, http.StatusGone, "Read snapshot not found or expired; open a new one and start over")
+    case errors.Is(err, database.ErrTooManyReadSnapshots):
+        c.Header("Retry-After", "60")
+        utils.ErrorResponse(c, http.StatusServiceUnavailable, err.Error())
+    case errors.Is(err, database.ErrNotSupported):
+        utils.ErrorResponse(c, http.StatusNotImplemented, "Not supported: "+err.Error())
+    default:
+        utils.ErrorResponse(c, http.StatusInternalServerError, prefix+err.Error())
+    }
+}
+
+// GetProductionFacets handles GET /productions/facets
+// @Summary Production facets
+// @Description Generators, types and provenance sources present in the productions matching the filter, with record counts and the date bounds, to fill filter dropdowns in one call
+// @Tags productions
+// @Produce json
+// @Param generatorId query string false "Generator ID (UUID)"
+// @Param plantId query string false "Plant ID (UUID); the productions of its generators"
+// @Param startDate query string false "Start date (YYYY-MM-DD)"
+// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Param source query string false "Provenance source (manual, api, import, external, telemetry)"
+// @Param attr.name query string false "Custom attribute equal to the value, for each custom field (e.g. attr.meterId=M-12)"
+// @Success 200 {object} models.ProductionFacets
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /productions/facets [get]
+func (h *ProductionHandler) GetProductionFacets(c *gin.Context) {
+    q := httpx.New(c)
+    filter, err := h.productionFilterParams(c, q)
+    if err != nil {
+        utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get custom fields: "+err.Error())
+        return
     }
-    list, err := h.repo.GetAllProductions(c.Request.Context(), genID, start, end)
+    if !q.Valid() {
+        return
+    }
+    facets, err := h.repo.GetProductionFacets(c.Request.Context(), filter)
     if err != nil {
-        utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list productions: "+err.Error())
+        utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get production facets: "+err.Error())
         return
     }
-    if list == nil { list = []*models.Production{} }
-    c.JSON(http.StatusOK, list)
+    c.JSON(http.StatusOK, facets)
+}
+
+// exportPageSize is the number of productions an export reads at a time
+const exportPageSize = 1000
+
+// ExportProductions handles GET /productions/export
+// @Summary Export productions
+// @Description Download the productions matching the filters (the same as GET /productions) as a CSV or Excel file, one row per record with a column per custom field. The file is streamed while the records are read, so there is no row limit and no pagination
+// @Tags productions
+// @Produce text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "File format (csv, xlsx)" default(csv)
+// @Param generatorId query string false "Generator ID (UUID)"
+// @Param plantId query string false "Plant ID (UUID); the productions of its generators"
+// @Param startDate query string false "Start date (YYYY-MM-DD)"
+// @Param endDate query string false "End date (YYYY-MM-DD)"
+// @Param source query string false "Provenance source (manual, api, import, external, telemetry)"
+// @Param attr.name query string false "Custom attribute equal to the value, for each custom field (e.g. attr.meterId=M-12)"
+// @Param compute query string false "Computed columns to add, comma-separated (capacityFactor, utilization)"
+// @Param readSnapshot query string false "Read snapshot ID (see POST /productions/read-snapshots) to export from"
+// @Success 200 {file} file
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 410 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /productions/export [get]
+func (h *ProductionHandler) ExportProductions(c *gin.Context) {
+    q := httpx.New(c)
+    filter, err := h.productionFilterParams(c, q)
+    if err != nil {
+        utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get custom fields: "+err.Error())
+        return
+    }
+    format := export.FormatCSV
+    if f := q.Enum("format", export.Formats...); f != nil {
+        format = *f
+    }
+    filter.Compute = q.EnumList("compute", models.ComputedProductionFields...)
+    snapshotID := q.UUID("readSnapshot")
+    if !q.Valid() {
+        return
+    }
+    ctx := c.Request.Context()
+    fields, err := h.repo.GetCustomFields(ctx, models.CustomEntityProduction)
+    if err != nil {
+        utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get custom fields: "+err.Error())
+        return
+    }
+    // Every page is read in one snapshot, so rows changing mid-export are
+    // neither skipped nor repeated
+    write := func(repo database.Repository) error {
+        return h.writeExport(c, repo, format, filter, fields)
+    }
+    if snapshotID != nil {
+        err = h.repo.InReadSnapshot(ctx, *snapshotID, write)
+    } else {
+        err = h.repo.ReadConsistent(ctx, write)
+    }
+    if err != nil && !c.Writer.Written() {
+        readSnapshotError(c, err, "Failed to export productions: ")
+    }
+}
+
+// writeExport streams the productions of filter in pages read from repo; the
+// errors it returns came before anything was written
+func (h *ProductionHandler) writeExport(c *gin.Context, repo database.Repository, format string, filter *models.ProductionFilter, fields []*models.CustomField) error {
+    ctx := c.Request.Context()
+    // Read the first page before answering so that errors still get a status
+    filter.Limit = exportPageSize
+    page, err := repo.GetAllProductions(ctx, filter)
+    if err == nil {
+        err = repo.ComputeProductions(ctx, page, filter.Compute)
+    }
+    if err != nil {
+        return err
+    }
+
+    cols := exportColumns(ctx, filter.Compute, fields)
+    if redact.Enabled() {
+        c.Writer.Header().Add("Vary", "Authorization")
+    }
+    c.Header("Content-Type", export.ContentType(format))
+    c.Header("Content-Disposition", `attachment; filename="productions-`+time.Now().Format("20060102")+"."+format+`"`)
+    c.Status(http.StatusOK)
+    w, err := export.NewWriter(format, c.Writer)
+    if err == nil {
+        err = w.WriteRow(exportHeader(cols))
+    }
+    for err == nil && len(page) > 0 {
+        for _, p := range page {
+            if err = w.WriteRow(exportRow(p, cols)); err != nil {
+                break
+            }
+        }
+        if err != nil || len(page) < exportPageSize {
+            break
+        }
+        if err = w.Flush(); err != nil {
+            break
+        }
+        c.Writer.Flush()
+        filter.Offset += exportPageSize
+        if page, err = repo.GetAllProductions(ctx, filter); err == nil {
+            err = repo.ComputeProductions(ctx, page, filter.Compute)
+        }
+    }
+    if err == nil {
+        err = w.Close()
+    }
+    if err != nil {
+        // The status is already sent, all that is left is to stop writing
+        log.Printf("production export aborted: %v", err)
+        c.Abort()
+    }
+    return nil
+}
+
+// exportColumn is a column of a production export: its header and the
+// JSON field of the production it holds, as the redaction policy names it
+type exportColumn struct {
+    name  string
+    field string
+    value func(p *models.Production) any
+}
+
+// exportColumns lists the columns of a production export, leaving out those
+// the redaction policy hides from the request of ctx
+func exportColumns(ctx context.Context, compute []string, fields []*models.CustomField) []exportColumn {
+    column := func(name string, value func(p *models.Production) any) exportColumn {
+        return exportColumn{name: name, field: name, value: value}
+    }
+    cols := []exportColumn{
+        column("id", func(p *models.Production) any { return p.ID.String() }),
+        column("generatorId", func(p *models.Production) any { return p.GeneratorID.String() }),
+        column("typeName", func(p *models.Production) any { return p.TypeName }),
+        column("isRenewable", func(p *models.Production) any { return p.IsRenewable }),
+        column("generatorCapacity", func(p *models.Production) any { return p.GeneratorCapacity }),
+        column("date", func(p *models.Production) any { return p.Date }),
+        column("productionMw", func(p *models.Production) any { return p.ProductionMW }),
+        column("source", func(p *models.Production) any { return p.Source }),
+        column("sourceRef", func(p *models.Production) any { return p.SourceRef }),
+        column("createdAt", func(p *models.Production) any { return p.CreatedAt }),
+        column("updatedAt", func(p *models.Production) any { return p.UpdatedAt }),
+    }
+    for _, name := range compute {
+        cols = append(cols, column(name, func(p *models.Production) any {
+            v := p.CapacityFactor
+            if name == models.ComputeUtilization {
+                v = p.Utilization
+            }
+            if v == nil {
+                return nil
+            }
+            return *v
+        }))
+    }
+    for _, f := range fields {
+        cols = append(cols, exportColumn{name: "attr." + f.Name, field: "customAttributes." + f.Name, value: func(p *models.Production) any {
+            switch v := p.CustomAttributes[f.Name].(type) {
+            case map[string]any, []any:
+                return fmt.Sprint(v)
+            default:
+                return v
+            }
+        }})
+    }
+
+    visible := cols[:0]
+    for _, col := range cols {
+        top, _, nested := strings.Cut(col.field, ".")
+        if redact.Hidden(ctx, models.Production{}, col.field) || nested && redact.Hidden(ctx, models.Production{}, top) {
+            continue
+        }
+        visible = append(visible, col)
+    }
+    return visible
+}
+
+// exportHeader names the columns of a production export
+func exportHeader(cols []exportColumn) []any {
+    row := make([]any, len(cols))
+    for i, col := range cols {
+        row[i] = col.name
+    }
+    return row
+}
+
+// exportRow lays out a production in the columns of exportHeader
+func exportRow(p *models.Production, cols []exportColumn) []any {
+    row := make([]any, len(cols))
+    for i, col := range cols {
+        row[i] = col.value(p)
+    }
+    return row
+}
+
+// productionFilterParams reads the production filter query parameters
+func (h *ProductionHandler) productionFilterParams(c *gin.Context, q *httpx.Params) (*models.ProductionFilter, error) {
+    filter := &models.ProductionFilter{GeneratorID: q.UUID("generatorId"), PlantID: q.UUID("plantId")}
+    filter.StartDate, filter.EndDate = q.DateRange("startDate", "endDate")
+    filter.Source = q.Enum("source", provenance.SourceManual, provenance.SourceAPI, provenance.SourceImport, provenance.SourceExternal, provenance.SourceTelemetry)
+    attrs, err := attributeFilter(c, q, h.repo, models.CustomEntityProduction)
+    filter.Attributes = attrs
+    return filter, err
 }
 
 // UpdateProduction handles PUT /productions/:id
@@ -117,16 +717,20 @@ func (h *ProductionHandler) GetAllProductions(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Production ID"
 // @Param body body models.UpdateProductionRequest true "Update data"
+// @Param If-Match header string false "ETag of the production as read; the update fails with 412 if it changed since"
 // @Success 200 {object} models.Production
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 412 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
 // @Router /productions/{id} [put]
 func (h *ProductionHandler) UpdateProduction(c *gin.Context) {
-    idStr := c.Param("id")
-    id, err := uuid.Parse(idStr)
-    if err != nil {
-        utils.ErrorResponse(c, http.StatusBadRequest, "Invalid production ID: must be UUID")
+    q := httpx.New(c)
+    id := q.PathUUID("id")
+    if !q.Valid() {
         return
     }
     var req models.UpdateProductionRequest
@@ -134,8 +738,81 @@ func (h *ProductionHandler) UpdateProduction(c *gin.Context) {
         utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
         return
     }
-    pr, err := h.repo.UpdateProduction(c.Request.Context(), id, &req)
+    if !checkAttributes(c, h.repo, models.CustomEntityProduction, req.CustomAttributes, true) {
+        return
+    }
+    // An edit through the API replaces the provenance of the record
+    if req.Source == "" {
+        req.Source = provenance.SourceAPI
+    }
+    h.saveProduction(c, id, &req)
+}
+
+// PatchProduction handles PATCH /productions/:id
+// @Summary Partially update production
+// @Description Update only the fields present in the body, leaving the others as they are. The source becomes api unless the body sets it, and sourceRef is cleared unless the body sets it, as with a PUT; customAttributes set to null removes them all. generatorId, date, productionMw and source cannot be null
+// @Tags productions
+// @Accept json
+// @Produce json
+// @Param id path string true "Production ID"
+// @Param body body models.UpdateProductionRequest true "Fields to update"
+// @Param If-Match header string false "ETag of the production as read; the update fails with 412 if it changed since"
+// @Success 200 {object} models.Production
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 412 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /productions/{id} [patch]
+func (h *ProductionHandler) PatchProduction(c *gin.Context) {
+    q := httpx.New(c)
+    id := q.PathUUID("id")
+    if !q.Valid() {
+        return
+    }
+    var req models.UpdateProductionRequest
+    fields, ok := bindPatch(c, &req, "generatorId", "date", "productionMw", "source")
+    if !ok {
+        return
+    }
+    req.Fields = fields
+    if !checkAttributes(c, h.repo, models.CustomEntityProduction, req.CustomAttributes, true) {
+        return
+    }
+    if req.Source == "" {
+        req.Source = provenance.SourceAPI
+        req.Fields["source"] = true
+    }
+    h.saveProduction(c, id, &req)
+}
+
+// saveProduction stores the update of a production and answers with the production
+func (h *ProductionHandler) saveProduction(c *gin.Context, id uuid.UUID, req *models.UpdateProductionRequest) {
+    version, ok := ifMatch(c)
+    if !ok {
+        return
+    }
+    req.IfMatch = version
+    pr, err := h.repo.UpdateProduction(c.Request.Context(), id, req)
     if err != nil {
+        if errors.Is(err, auth.ErrForbidden) {
+            utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+            return
+        }
+        if errors.Is(err, database.ErrVersionMismatch) {
+            utils.ErrorResponse(c, http.StatusPreconditionFailed, "Precondition failed: "+err.Error())
+            return
+        }
+        if errors.Is(err, database.ErrInvalidDate) {
+            utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+            return
+        }
+        if errors.Is(err, database.ErrPeriodPublished) || errors.Is(err, database.ErrDuplicateProduction) {
+            utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+            return
+        }
         if err == sql.ErrNoRows {
             utils.ErrorResponse(c, http.StatusNotFound, "Production not found")
             return
@@ -143,27 +820,39 @@ func (h *ProductionHandler) UpdateProduction(c *gin.Context) {
         utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update production: "+err.Error())
         return
     }
-    c.JSON(http.StatusOK, pr)
+    setVersion(c, pr.Version)
+    respond(c, http.StatusOK, pr, nil)
 }
 
 // DeleteProduction handles DELETE /productions/:id
 // @Summary Delete production
+// @Description Move a production record to the trash; restore it with POST /trash/{id}/restore
 // @Tags productions
 // @Produce json
 // @Param id path string true "Production ID"
 // @Success 204
-// @Failure 400 {object} models.ErrorResponse
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
 // @Router /productions/{id} [delete]
 func (h *ProductionHandler) DeleteProduction(c *gin.Context) {
-    idStr := c.Param("id")
-    id, err := uuid.Parse(idStr)
-    if err != nil {
-        utils.ErrorResponse(c, http.StatusBadRequest, "Invalid production ID: must be UUID")
+    q := httpx.New(c)
+    id := q.PathUUID("id")
+    if !q.Valid() {
         return
     }
     if err := h.repo.DeleteProduction(c.Request.Context(), id); err != nil {
+        if errors.Is(err, auth.ErrForbidden) {
+            utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+            return
+        }
+        if errors.Is(err, database.ErrPeriodPublished) {
+            utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+            return
+        }
         if err == sql.ErrNoRows {
             utils.ErrorResponse(c, http.StatusNotFound, "Production not found")
             return
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RecalculationHandler handles HTTP requests rescaling production records in bulk
+type RecalculationHandler struct {
+	repo database.Repository
+}
+
+// NewRecalculationHandler creates a new RecalculationHandler instance
+func NewRecalculationHandler(repo database.Repository) *RecalculationHandler {
+	return &RecalculationHandler{repo: repo}
+}
+
+// PreviewRecalculation handles POST /admin/recalculations/preview
+// @Summary Preview a recalculation
+// @Description Report how many production records a recalculation would change, their total before and after and the first 100 changes in date order, without changing anything
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param body body models.RecalculationRequest true "Records and formula"
+// @Success 200 {object} models.Recalculation
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/recalculations/preview [post]
+func (h *RecalculationHandler) PreviewRecalculation(c *gin.Context) {
+	req, ok := bindRecalculation(c)
+	if !ok {
+		return
+	}
+	rc, err := h.repo.PreviewRecalculation(c.Request.Context(), req)
+	if err != nil {
+		recalculationError(c, err, "Failed to preview recalculation: ")
+		return
+	}
+	c.JSON(http.StatusOK, rc)
+}
+
+// ApplyRecalculation handles POST /admin/recalculations
+// @Summary Apply a recalculation
+// @Description Set productionMw = productionMw * factor + offset on the production records matching the filter in one transaction, e.g. factor 0.001 on a month of hydro records loaded in kW. Records of published months are changed like corrections, without touching the snapshots. Every changed record gets a correction entry with the reason and the run is kept with its author
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param body body models.RecalculationRequest true "Records and formula"
+// @Success 201 {object} models.Recalculation
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 501 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/recalculations [post]
+func (h *RecalculationHandler) ApplyRecalculation(c *gin.Context) {
+	req, ok := bindRecalculation(c)
+	if !ok {
+		return
+	}
+	var userID *uuid.UUID
+	if p, ok := auth.PrincipalFrom(c.Request.Context()); ok {
+		userID = &p.UserID
+	}
+	rc, err := h.repo.ApplyRecalculation(c.Request.Context(), req, userID)
+	if err != nil {
+		recalculationError(c, err, "Failed to apply recalculation: ")
+		return
+	}
+	c.JSON(http.StatusCreated, rc)
+}
+
+// GetRecalculations handles GET /admin/recalculations
+// @Summary List recalculations
+// @Description Applied recalculations with their filter, formula, author and totals, newest first
+// @Tags admin
+// @Produce json
+// @Success 200 {array} models.Recalculation
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /admin/recalculations [get]
+func (h *RecalculationHandler) GetRecalculations(c *gin.Context) {
+	list, err := h.repo.GetRecalculations(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list recalculations: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.Recalculation{}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// bindRecalculation reads and checks a recalculation request; false when it
+// was invalid and the request was answered
+func bindRecalculation(c *gin.Context) (*models.RecalculationRequest, bool) {
+	var req models.RecalculationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return nil, false
+	}
+	switch {
+	// Dates in YYYY-MM-DD order lexically
+	case req.EndDate < req.StartDate:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: endDate must not be before startDate")
+		return nil, false
+	case req.Factor == nil && req.Offset == nil:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: set factor or offset")
+		return nil, false
+	}
+	return &req, true
+}
+
+func recalculationError(c *gin.Context, err error, prefix string) {
+	switch {
+	case errors.Is(err, auth.ErrForbidden):
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+	case errors.Is(err, database.ErrNegativeProduction):
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, database.ErrNotSupported):
+		utils.ErrorResponse(c, http.StatusNotImplemented, "Not supported: "+err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, prefix+err.Error())
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/geo"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RegionHandler handles HTTP requests for geographic regions
+type RegionHandler struct {
+	repo database.Repository
+}
+
+// NewRegionHandler creates a new RegionHandler instance
+func NewRegionHandler(repo database.Repository) *RegionHandler {
+	return &RegionHandler{repo: repo}
+}
+
+// CreateRegion handles POST /regions
+// @Summary Create region
+// @Description Create a geographic region to assign generators to. The code is stored upper-case; bbox is [minLng, minLat, maxLng, maxLat]
+// @Tags regions
+// @Accept json
+// @Produce json
+// @Param region body models.CreateRegionRequest true "Region data"
+// @Success 201 {object} models.Region
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /regions [post]
+func (h *RegionHandler) CreateRegion(c *gin.Context) {
+	var req models.CreateRegionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if !validRegionBBox(c, req.BBox) {
+		return
+	}
+	region, err := h.repo.CreateRegion(c.Request.Context(), &req)
+	if err != nil {
+		respondRegionError(c, "create", err)
+		return
+	}
+	respond(c, http.StatusCreated, region, nil)
+}
+
+// GetRegionByID handles GET /regions/:id
+// @Summary Get region by ID
+// @Tags regions
+// @Produce json
+// @Param id path string true "Region ID (UUID)"
+// @Success 200 {object} models.Region
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /regions/{id} [get]
+func (h *RegionHandler) GetRegionByID(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	region, err := h.repo.GetRegionByID(c.Request.Context(), id)
+	if err != nil {
+		respondRegionError(c, "get", err)
+		return
+	}
+	respond(c, http.StatusOK, region, nil)
+}
+
+// GetAllRegions handles GET /regions
+// @Summary List regions
+// @Description Regions ordered by name, with the count and total capacity of their generators
+// @Tags regions
+// @Produce json
+// @Param limit query int false "Page size (1-1000, default 100)"
+// @Param offset query int false "Regions to skip"
+// @Success 200 {array} models.Region
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /regions [get]
+func (h *RegionHandler) GetAllRegions(c *gin.Context) {
+	q := httpx.New(c)
+	filter := &models.RegionFilter{
+		Limit:  q.Int("limit", 100, 1, 1000),
+		Offset: q.Int("offset", 0, 0, math.MaxInt),
+	}
+	if !q.Valid() {
+		return
+	}
+	list, err := h.repo.GetAllRegions(c.Request.Context(), filter)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get regions: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.Region{}
+	}
+	if len(list) == filter.Limit {
+		setNextLink(c, filter.Limit, filter.Offset)
+	}
+	respond(c, http.StatusOK, list, nil)
+}
+
+// UpdateRegion handles PUT /regions/:id
+// @Summary Update region
+// @Tags regions
+// @Accept json
+// @Produce json
+// @Param id path string true "Region ID (UUID)"
+// @Param region body models.UpdateRegionRequest true "Updated region data"
+// @Success 200 {object} models.Region
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /regions/{id} [put]
+func (h *RegionHandler) UpdateRegion(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	var req models.UpdateRegionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if !validRegionBBox(c, req.BBox) {
+		return
+	}
+	region, err := h.repo.UpdateRegion(c.Request.Context(), id, &req)
+	if err != nil {
+		respondRegionError(c, "update", err)
+		return
+	}
+	respond(c, http.StatusOK, region, nil)
+}
+
+// DeleteRegion handles DELETE /regions/:id
+// @Summary Delete region
+// @Description Delete a region; its generators are kept without region
+// @Tags regions
+// @Produce json
+// @Param id path string true "Region ID (UUID)"
+// @Success 204
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /regions/{id} [delete]
+func (h *RegionHandler) DeleteRegion(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	if err := h.repo.DeleteRegion(c.Request.Context(), id); err != nil {
+		respondRegionError(c, "delete", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// validRegionBBox checks the bounding box of a region request, answering 400
+// to invalid ones
+func validRegionBBox(c *gin.Context, bbox []float64) bool {
+	if bbox == nil {
+		return true
+	}
+	if _, err := geo.NewBBox(bbox); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: bbox "+err.Error())
+		return false
+	}
+	return true
+}
+
+func respondRegionError(c *gin.Context, action string, err error) {
+	switch {
+	case err == sql.ErrNoRows:
+		utils.ErrorResponse(c, http.StatusNotFound, "Region not found: No region found with the given ID")
+	case errors.Is(err, auth.ErrForbidden):
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+	case errors.Is(err, database.ErrRegionExists):
+		utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to "+action+" region: "+err.Error())
+	}
+}
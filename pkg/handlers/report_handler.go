@@ -0,0 +1,479 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/calendar"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/reports"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ReportHandler handles HTTP requests for saved and scheduled reports
+type ReportHandler struct {
+	repo      database.Repository
+	scheduler *reports.Scheduler
+}
+
+// NewReportHandler creates a new ReportHandler instance
+func NewReportHandler(repo database.Repository, scheduler *reports.Scheduler) *ReportHandler {
+	return &ReportHandler{repo: repo, scheduler: scheduler}
+}
+
+// bindReport reads and validates a report definition, answering 400 and returning false when it is invalid
+func (h *ReportHandler) bindReport(c *gin.Context) (*models.ReportRequest, *time.Time, bool) {
+	var req models.ReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return nil, nil, false
+	}
+	if err := reports.Validate(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return nil, nil, false
+	}
+	if req.TemplateID != nil && !h.templateExists(c, *req.TemplateID) {
+		return nil, nil, false
+	}
+	cal, ok := h.calendar(c, req.Schedule)
+	if !ok {
+		return nil, nil, false
+	}
+	return &req, reports.NextRun(req.Schedule, time.Now(), cal), true
+}
+
+// calendar resolves the calendar of a schedule, answering 400/500 and
+// returning false when it cannot be used
+func (h *ReportHandler) calendar(c *gin.Context, s models.ReportSchedule) (*calendar.Calendar, bool) {
+	cal, err := h.scheduler.Calendar(c.Request.Context(), s)
+	if err != nil {
+		if errors.Is(err, calendar.ErrUnknownCalendar) {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid report: "+err.Error())
+			return nil, false
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get calendar: "+err.Error())
+		return nil, false
+	}
+	return cal, true
+}
+
+// templateExists checks a report template, answering 400/500 and returning false when it cannot be used
+func (h *ReportHandler) templateExists(c *gin.Context, id uuid.UUID) bool {
+	if _, err := h.repo.GetReportTemplateByID(c.Request.Context(), id); err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid report: unknown template "+id.String())
+			return false
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get report template: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// reportID parses the :id parameter, answering 400 and returning false when it is not a UUID
+func reportID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid report ID: must be UUID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// CreateReport handles POST /reports
+// @Summary Create a saved report
+// @Description Save a report definition (data selection, format, schedule and delivery). Scheduled reports run at the given hour of every day, week (day = weekday) or month (day = day of the month) and cover the previous period unless filters fix a range. With a schedule calendar, runs on weekends and holidays move to the next business day
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param body body models.ReportRequest true "Report definition"
+// @Success 201 {object} models.Report
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /reports [post]
+func (h *ReportHandler) CreateReport(c *gin.Context) {
+	req, nextRun, ok := h.bindReport(c)
+	if !ok {
+		return
+	}
+
+	rep, err := h.repo.CreateReport(c.Request.Context(), req, nextRun)
+	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create report: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, rep)
+}
+
+// GetReports handles GET /reports
+// @Summary List saved reports
+// @Tags reports
+// @Produce json
+// @Success 200 {array} models.Report
+// @Failure 500 {object} models.ErrorResponse
+// @Router /reports [get]
+func (h *ReportHandler) GetReports(c *gin.Context) {
+	list, err := h.repo.GetReports(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list reports: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.Report{}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetReportByID handles GET /reports/:id
+// @Summary Get a saved report
+// @Tags reports
+// @Produce json
+// @Param id path string true "Report ID (UUID)"
+// @Success 200 {object} models.Report
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /reports/{id} [get]
+func (h *ReportHandler) GetReportByID(c *gin.Context) {
+	rep, ok := h.report(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, rep)
+}
+
+// UpdateReport handles PUT /reports/:id
+// @Summary Replace a saved report
+// @Description Replace a report definition; its next run is recomputed from the new schedule
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param id path string true "Report ID (UUID)"
+// @Param body body models.ReportRequest true "Report definition"
+// @Success 200 {object} models.Report
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /reports/{id} [put]
+func (h *ReportHandler) UpdateReport(c *gin.Context) {
+	id, ok := reportID(c)
+	if !ok {
+		return
+	}
+	req, nextRun, ok := h.bindReport(c)
+	if !ok {
+		return
+	}
+
+	rep, err := h.repo.UpdateReport(c.Request.Context(), id, req, nextRun)
+	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Report not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update report: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, rep)
+}
+
+// DeleteReport handles DELETE /reports/:id
+// @Summary Delete a saved report
+// @Description Move a report to the trash together with its run history
+// @Tags reports
+// @Param id path string true "Report ID (UUID)"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /reports/{id} [delete]
+func (h *ReportHandler) DeleteReport(c *gin.Context) {
+	id, ok := reportID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteReport(c.Request.Context(), id); err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Report not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete report: "+err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RunReport handles POST /reports/:id/run
+// @Summary Run a report now
+// @Description Render and deliver a report immediately, outside its schedule. The run is recorded in its history; a failed run is returned with status failed
+// @Tags reports
+// @Produce json
+// @Param id path string true "Report ID (UUID)"
+// @Success 200 {object} models.ReportRun
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /reports/{id}/run [post]
+func (h *ReportHandler) RunReport(c *gin.Context) {
+	rep, ok := h.report(c)
+	if !ok {
+		return
+	}
+
+	run, err := h.scheduler.Execute(c.Request.Context(), rep, reports.TriggerManual, time.Now())
+	if run == nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to run report: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// GetReportRuns handles GET /reports/:id/runs
+// @Summary Report run history
+// @Description Latest runs of a report, newest first
+// @Tags reports
+// @Produce json
+// @Param id path string true "Report ID (UUID)"
+// @Success 200 {array} models.ReportRun
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /reports/{id}/runs [get]
+func (h *ReportHandler) GetReportRuns(c *gin.Context) {
+	id, ok := reportID(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.repo.GetReportRuns(c.Request.Context(), id, h.scheduler.Config().RunHistory)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list report runs: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.ReportRun{}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// DownloadReport handles GET /reports/:id/download
+// @Summary Download a report
+// @Description Render a report for the period a run now would cover, without delivering it or recording a run. PDF reports are a bulletin with key figures, daily production and operator charts and the report table
+// @Tags reports
+// @Produce text/csv,application/pdf
+// @Param id path string true "Report ID (UUID)"
+// @Param format query string false "Output format, overriding the report's (csv, pdf)"
+// @Param templateId query string false "Template ID (UUID) to preview the report with, overriding the report's"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /reports/{id}/download [get]
+func (h *ReportHandler) DownloadReport(c *gin.Context) {
+	rep, ok := h.report(c)
+	if !ok {
+		return
+	}
+
+	switch format := c.Query("format"); format {
+	case "":
+	case models.ReportFormatCSV, models.ReportFormatPDF:
+		rep.Format = format
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid format: must be csv or pdf")
+		return
+	}
+	if v := c.Query("templateId"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid templateId: must be UUID")
+			return
+		}
+		if !h.templateExists(c, id) {
+			return
+		}
+		rep.TemplateID = &id
+	}
+
+	cal, ok := h.calendar(c, rep.Schedule)
+	if !ok {
+		return
+	}
+	start, end := reports.Period(rep, time.Now(), cal)
+	out, err := reports.Render(c.Request.Context(), h.repo, rep, start, end, h.scheduler.Config())
+	if err != nil {
+		if errors.Is(err, reports.ErrInvalidTemplate) {
+			utils.ErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to render report: "+err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+out.Filename+`"`)
+	c.Data(http.StatusOK, out.ContentType, out.Body)
+}
+
+// GetWebhookDeliveries handles GET /reports/:id/deliveries
+// @Summary Webhook delivery log
+// @Description Latest webhook requests of a report, newest first, with the status code, error and duration of each
+// @Tags reports
+// @Produce json
+// @Param id path string true "Report ID (UUID)"
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /reports/{id}/deliveries [get]
+func (h *ReportHandler) GetWebhookDeliveries(c *gin.Context) {
+	id, ok := reportID(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.repo.GetWebhookDeliveries(c.Request.Context(), id, h.scheduler.Config().RunHistory)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list webhook deliveries: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.WebhookDelivery{}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// RedeliverWebhook handles POST /reports/:id/deliveries/:deliveryId/redeliver
+// @Summary Redeliver a webhook
+// @Description Render the period of a past delivery again and post it to the current webhook URL, signed with a new timestamp and nonce. The new delivery is logged and returned; a failed one is returned with succeeded false
+// @Tags reports
+// @Produce json
+// @Param id path string true "Report ID (UUID)"
+// @Param deliveryId path string true "Delivery ID (UUID)"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /reports/{id}/deliveries/{deliveryId}/redeliver [post]
+func (h *ReportHandler) RedeliverWebhook(c *gin.Context) {
+	rep, ok := h.report(c)
+	if !ok {
+		return
+	}
+	deliveryID, err := uuid.Parse(c.Param("deliveryId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid delivery ID: must be UUID")
+		return
+	}
+	original, err := h.repo.GetWebhookDeliveryByID(c.Request.Context(), rep.ID, deliveryID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Webhook delivery not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get webhook delivery: "+err.Error())
+		return
+	}
+
+	d, err := h.scheduler.Redeliver(c.Request.Context(), rep, original)
+	if d == nil {
+		switch {
+		case errors.Is(err, reports.ErrNoWebhook):
+			utils.ErrorResponse(c, http.StatusBadRequest, "Cannot redeliver: "+err.Error())
+		case errors.Is(err, reports.ErrInvalidTemplate):
+			utils.ErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to redeliver webhook: "+err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, d)
+}
+
+// RotateWebhookSecret handles POST /reports/:id/webhook-secret
+// @Summary Rotate the webhook secret
+// @Description Replace the secret the report signs its webhook requests with and return it; this is the only time a secret is shown. Requests are signed with the new secret from now on
+// @Tags reports
+// @Produce json
+// @Param id path string true "Report ID (UUID)"
+// @Success 200 {object} models.WebhookSecret
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /reports/{id}/webhook-secret [post]
+func (h *ReportHandler) RotateWebhookSecret(c *gin.Context) {
+	id, ok := reportID(c)
+	if !ok {
+		return
+	}
+
+	secret, err := h.repo.RotateReportWebhookSecret(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Report not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to rotate webhook secret: "+err.Error())
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, secret)
+}
+
+// report loads the report of the :id parameter, answering 400/404 and returning false when missing
+func (h *ReportHandler) report(c *gin.Context) (*models.Report, bool) {
+	id, ok := reportID(c)
+	if !ok {
+		return nil, false
+	}
+	rep, err := h.repo.GetReportByID(c.Request.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Report not found")
+			return nil, false
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get report: "+err.Error())
+		return nil, false
+	}
+	return rep, true
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/reports"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ReportTemplateHandler handles HTTP requests for report templates
+type ReportTemplateHandler struct {
+	repo database.Repository
+}
+
+// NewReportTemplateHandler creates a new ReportTemplateHandler instance
+func NewReportTemplateHandler(repo database.Repository) *ReportTemplateHandler {
+	return &ReportTemplateHandler{repo: repo}
+}
+
+// bindReportTemplate reads a template and checks that it renders, answering 400 and returning false when it does not
+func bindReportTemplate(c *gin.Context) (*models.ReportTemplateRequest, bool) {
+	var req models.ReportTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return nil, false
+	}
+	if err := reports.ValidateTemplate(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return &req, true
+}
+
+// templateID parses the :templateId parameter, answering 400 and returning false when it is not a UUID
+func templateID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("templateId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid template ID: must be UUID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// CreateReportTemplate handles POST /reports/templates
+// @Summary Create a report template
+// @Description Save the layout of report bulletins: branding (organization, title, footer, colours, logo), the sections in order and the language (en, es) with label overrides. Every text is a Go text/template over the report data (.Report, .Organization, .Period, .Start, .End, .Generated, .Rows, .Figures, .Crosstab, .Table) with a label function
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param body body models.ReportTemplateRequest true "Report template"
+// @Success 201 {object} models.ReportTemplate
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /reports/templates [post]
+func (h *ReportTemplateHandler) CreateReportTemplate(c *gin.Context) {
+	req, ok := bindReportTemplate(c)
+	if !ok {
+		return
+	}
+
+	t, err := h.repo.CreateReportTemplate(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create report template: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, t)
+}
+
+// GetReportTemplates handles GET /reports/templates
+// @Summary List report templates
+// @Tags reports
+// @Produce json
+// @Success 200 {array} models.ReportTemplate
+// @Failure 500 {object} models.ErrorResponse
+// @Router /reports/templates [get]
+func (h *ReportTemplateHandler) GetReportTemplates(c *gin.Context) {
+	list, err := h.repo.GetReportTemplates(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list report templates: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.ReportTemplate{}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetReportTemplateByID handles GET /reports/templates/:templateId
+// @Summary Get a report template
+// @Tags reports
+// @Produce json
+// @Param templateId path string true "Template ID (UUID)"
+// @Success 200 {object} models.ReportTemplate
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /reports/templates/{templateId} [get]
+func (h *ReportTemplateHandler) GetReportTemplateByID(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+
+	t, err := h.repo.GetReportTemplateByID(c.Request.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Report template not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get report template: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+// UpdateReportTemplate handles PUT /reports/templates/:templateId
+// @Summary Replace a report template
+// @Description Replace a report template; the reports using it follow the change from their next run
+// @Tags reports
+// @Accept json
+// @Produce json
+// @Param templateId path string true "Template ID (UUID)"
+// @Param body body models.ReportTemplateRequest true "Report template"
+// @Success 200 {object} models.ReportTemplate
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /reports/templates/{templateId} [put]
+func (h *ReportTemplateHandler) UpdateReportTemplate(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+	req, ok := bindReportTemplate(c)
+	if !ok {
+		return
+	}
+
+	t, err := h.repo.UpdateReportTemplate(c.Request.Context(), id, req)
+	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Report template not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update report template: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+// DeleteReportTemplate handles DELETE /reports/templates/:templateId
+// @Summary Delete a report template
+// @Description Move a report template to the trash; the reports using it fall back to the standard bulletin until it is restored
+// @Tags reports
+// @Param templateId path string true "Template ID (UUID)"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /reports/templates/{templateId} [delete]
+func (h *ReportTemplateHandler) DeleteReportTemplate(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteReportTemplate(c.Request.Context(), id); err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Report template not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete report template: "+err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Overflow policies for unpaginated listings larger than the row limit
+const (
+	// OverflowPaginate returns the first page with a warning and a next link
+	OverflowPaginate = "paginate"
+	// OverflowReject answers 413 asking the client to narrow or page the query
+	OverflowReject = "reject"
+)
+
+// ResultLimitConfig represents the guardrails on listing sizes
+type ResultLimitConfig struct {
+	// MaxRows caps the rows of one response; 0 disables the guardrail
+	MaxRows  int
+	Overflow string
+}
+
+// LoadResultLimitConfig loads the result size guardrails from environment variables
+func LoadResultLimitConfig() *ResultLimitConfig {
+	cfg := &ResultLimitConfig{
+		MaxRows:  utils.GetEnvAsInt("RESULT_MAX_ROWS", 10000),
+		Overflow: strings.ToLower(utils.GetEnv("RESULT_OVERFLOW", OverflowPaginate)),
+	}
+	if cfg.MaxRows < 0 {
+		cfg.MaxRows = 0
+	}
+	if cfg.Overflow != OverflowReject {
+		cfg.Overflow = OverflowPaginate
+	}
+	return cfg
+}
+
+// pageSize returns the rows to return for a request asking for limit rows
+// (0 = all), capped by the guardrail; 0 means unlimited
+func (cfg *ResultLimitConfig) pageSize(limit int) int {
+	if cfg.MaxRows > 0 && (limit == 0 || limit > cfg.MaxRows) {
+		return cfg.MaxRows
+	}
+	return limit
+}
+
+// setNextLink points the client to the page after offset with a Link header
+func setNextLink(c *gin.Context, limit, offset int) {
+	q := c.Request.URL.Query()
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("offset", strconv.Itoa(offset+limit))
+	c.Header("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, c.Request.URL.Path, q.Encode()))
+}
+
+// overflowed applies the overflow policy to an unpaginated listing that had
+// more than MaxRows results. It returns true when the request was rejected.
+func (cfg *ResultLimitConfig) overflowed(c *gin.Context, resource string) bool {
+	if cfg.Overflow == OverflowReject {
+		utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, fmt.Sprintf(
+			"Result too large: more than %d %s match; narrow the filters or page with limit (at most %d) and offset",
+			cfg.MaxRows, resource, cfg.MaxRows))
+		return true
+	}
+	c.Header("Warning", fmt.Sprintf(`199 tadb "result truncated to %d %s; follow the Link header or use limit/offset"`, cfg.MaxRows, resource))
+	c.Header("X-Result-Truncated", "true")
+	return false
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/erd"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SchemaHandler handles HTTP requests for the data model documentation
+type SchemaHandler struct {
+	repo database.Repository
+}
+
+// NewSchemaHandler creates a new SchemaHandler instance
+func NewSchemaHandler(repo database.Repository) *SchemaHandler {
+	return &SchemaHandler{repo: repo}
+}
+
+// diagramContentTypes maps the diagram formats to the media type they are served as
+var diagramContentTypes = map[string]string{
+	"mermaid": "text/vnd.mermaid; charset=utf-8",
+	"dot":     "text/vnd.graphviz; charset=utf-8",
+}
+
+// GetSchema handles GET /admin/schema
+// @Summary Describe the database schema
+// @Description Tables, columns (type, nullability, default, keys, comments) and foreign keys of the API schema, introspected from information_schema. format=mermaid returns a Mermaid erDiagram and format=dot a Graphviz digraph of the same model
+// @Tags admin
+// @Produce json
+// @Produce plain
+// @Param format query string false "Output format: json, mermaid or dot" default(json)
+// @Success 200 {object} models.DatabaseSchema
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/schema [get]
+func (h *SchemaHandler) GetSchema(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	contentType, ok := diagramContentTypes[format]
+	if !ok && format != "json" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid format: must be json, mermaid or dot")
+		return
+	}
+
+	s, err := h.repo.GetSchema(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to describe schema: "+err.Error())
+		return
+	}
+	if format == "json" {
+		c.JSON(http.StatusOK, s)
+		return
+	}
+
+	diagram, err := erd.Render(s, format)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, contentType, []byte(diagram))
+}
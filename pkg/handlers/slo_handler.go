@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/slo"
+	"github.com/gin-gonic/gin"
+)
+
+// SLOHandler handles HTTP requests for the response time SLOs
+type SLOHandler struct {
+	tracker *slo.Tracker
+}
+
+// NewSLOHandler creates a new SLOHandler instance
+func NewSLOHandler(tracker *slo.Tracker) *SLOHandler {
+	return &SLOHandler{
+		tracker: tracker,
+	}
+}
+
+// GetSLO handles GET /admin/slo
+// @Summary Get SLO status
+// @Description Per-route latency and error budget over the rolling window, with the current burn rate. Routes burning faster than the alert threshold are flagged atRisk
+// @Tags admin
+// @Produce json
+// @Success 200 {object} slo.Report
+// @Router /admin/slo [get]
+func (h *SLOHandler) GetSLO(c *gin.Context) {
+	c.JSON(http.StatusOK, h.tracker.Report())
+}
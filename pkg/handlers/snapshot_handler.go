@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SnapshotHandler handles HTTP requests for published monthly snapshots and
+// the correction workflow of production records
+type SnapshotHandler struct {
+	repo database.Repository
+}
+
+// NewSnapshotHandler creates a new SnapshotHandler instance
+func NewSnapshotHandler(repo database.Repository) *SnapshotHandler {
+	return &SnapshotHandler{repo: repo}
+}
+
+// parseMonth parses a YYYY-MM month into the first day of that month
+func parseMonth(s string) (time.Time, error) {
+	return time.Parse("2006-01", s)
+}
+
+// PublishSnapshot handles POST /snapshots
+// @Summary Publish a month
+// @Description Freeze the production data of a month into an immutable snapshot and record its SHA-256 hash. Afterwards the month can only be changed through corrections
+// @Tags snapshots
+// @Accept json
+// @Produce json
+// @Param body body models.PublishSnapshotRequest true "Month to publish (YYYY-MM)"
+// @Success 201 {object} models.Snapshot
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /snapshots [post]
+func (h *SnapshotHandler) PublishSnapshot(c *gin.Context) {
+	var req models.PublishSnapshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	month, err := parseMonth(req.Month)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid month: must be YYYY-MM")
+		return
+	}
+
+	snap, err := h.repo.PublishSnapshot(c.Request.Context(), month)
+	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if errors.Is(err, database.ErrAlreadyPublished) {
+			utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to publish snapshot: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, snap)
+}
+
+// GetSnapshots handles GET /snapshots
+// @Summary List published snapshots
+// @Description List published monthly snapshots, newest month first
+// @Tags snapshots
+// @Produce json
+// @Success 200 {array} models.Snapshot
+// @Failure 500 {object} models.ErrorResponse
+// @Router /snapshots [get]
+func (h *SnapshotHandler) GetSnapshots(c *gin.Context) {
+	list, err := h.repo.GetSnapshots(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get snapshots: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.Snapshot{}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetSnapshot handles GET /snapshots/:month
+// @Summary Get a published snapshot
+// @Description Get the snapshot of a month with its frozen production records
+// @Tags snapshots
+// @Produce json
+// @Param month path string true "Month (YYYY-MM)"
+// @Success 200 {object} models.SnapshotDetail
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /snapshots/{month} [get]
+func (h *SnapshotHandler) GetSnapshot(c *gin.Context) {
+	month, err := parseMonth(c.Param("month"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid month: must be YYYY-MM")
+		return
+	}
+
+	detail, err := h.repo.GetSnapshot(c.Request.Context(), month)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Snapshot not found: month is not published")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get snapshot: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
+// VerifySnapshot handles GET /snapshots/:month/verify
+// @Summary Verify a published snapshot
+// @Description Recompute the hash of a snapshot from its frozen records and compare it with the recorded one
+// @Tags snapshots
+// @Produce json
+// @Param month path string true "Month (YYYY-MM)"
+// @Success 200 {object} models.SnapshotVerification
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /snapshots/{month}/verify [get]
+func (h *SnapshotHandler) VerifySnapshot(c *gin.Context) {
+	month, err := parseMonth(c.Param("month"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid month: must be YYYY-MM")
+		return
+	}
+
+	v, err := h.repo.VerifySnapshot(c.Request.Context(), month)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Snapshot not found: month is not published")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify snapshot: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, v)
+}
+
+// CreateCorrection handles POST /productions/:id/corrections
+// @Summary Correct a production record
+// @Description Change the value of a production record, including records of published months, keeping the previous value and the reason. Published snapshots are not modified
+// @Tags productions
+// @Accept json
+// @Produce json
+// @Param id path string true "Production ID"
+// @Param body body models.CreateCorrectionRequest true "Corrected value and reason"
+// @Success 201 {object} models.ProductionCorrection
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /productions/{id}/corrections [post]
+func (h *SnapshotHandler) CreateCorrection(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid production ID: must be UUID")
+		return
+	}
+	var req models.CreateCorrectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	corr, err := h.repo.ApplyCorrection(c.Request.Context(), id, &req)
+	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Production not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to correct production: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, corr)
+}
+
+// GetCorrections handles GET /productions/:id/corrections
+// @Summary List corrections of a production record
+// @Tags productions
+// @Produce json
+// @Param id path string true "Production ID"
+// @Success 200 {array} models.ProductionCorrection
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /productions/{id}/corrections [get]
+func (h *SnapshotHandler) GetCorrections(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid production ID: must be UUID")
+		return
+	}
+
+	list, err := h.repo.GetCorrections(c.Request.Context(), id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get corrections: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.ProductionCorrection{}
+	}
+	c.JSON(http.StatusOK, list)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SubmissionCalendarHandler handles HTTP requests for the expected submission calendar
+type SubmissionCalendarHandler struct {
+	repo database.Repository
+}
+
+// NewSubmissionCalendarHandler creates a new SubmissionCalendarHandler instance
+func NewSubmissionCalendarHandler(repo database.Repository) *SubmissionCalendarHandler {
+	return &SubmissionCalendarHandler{
+		repo: repo,
+	}
+}
+
+// GetSubmissionCalendar handles GET /submission-calendar
+// @Summary Expected submission calendar
+// @Description Reporting cadences set per type and per generator (generator entries override their type, generators without any report daily), with the number of generators following each entry
+// @Tags analytics
+// @Produce json
+// @Success 200 {array} models.SubmissionCadence
+// @Failure 500 {object} models.ErrorResponse
+// @Router /submission-calendar [get]
+func (h *SubmissionCalendarHandler) GetSubmissionCalendar(c *gin.Context) {
+	list, err := h.repo.GetSubmissionCalendar(c.Request.Context())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get submission calendar: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.SubmissionCadence{}
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// SetTypeCadence handles PUT /types/:id/submission-cadence
+// @Summary Set the submission cadence of a type
+// @Description Expected reporting cadence (daily, weekly or monthly) of the generators of a type without a cadence of their own
+// @Tags types
+// @Accept json
+// @Param id path string true "Type ID (UUID)"
+// @Param body body models.SubmissionCadenceRequest true "Cadence"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /types/{id}/submission-cadence [put]
+func (h *SubmissionCalendarHandler) SetTypeCadence(c *gin.Context) {
+	h.setCadence(c, models.CadenceScopeType, "Type")
+}
+
+// DeleteTypeCadence handles DELETE /types/:id/submission-cadence
+// @Summary Clear the submission cadence of a type
+// @Description Its generators without a cadence of their own are expected to report daily again
+// @Tags types
+// @Param id path string true "Type ID (UUID)"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /types/{id}/submission-cadence [delete]
+func (h *SubmissionCalendarHandler) DeleteTypeCadence(c *gin.Context) {
+	h.deleteCadence(c, models.CadenceScopeType, "Type")
+}
+
+// SetGeneratorCadence handles PUT /generators/:id/submission-cadence
+// @Summary Set the submission cadence of a generator
+// @Description Expected reporting cadence (daily, weekly or monthly) of a generator, overriding the cadence of its type
+// @Tags generators
+// @Accept json
+// @Param id path string true "Generator ID (UUID)"
+// @Param body body models.SubmissionCadenceRequest true "Cadence"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /generators/{id}/submission-cadence [put]
+func (h *SubmissionCalendarHandler) SetGeneratorCadence(c *gin.Context) {
+	h.setCadence(c, models.CadenceScopeGenerator, "Generator")
+}
+
+// DeleteGeneratorCadence handles DELETE /generators/:id/submission-cadence
+// @Summary Clear the submission cadence of a generator
+// @Description The generator follows the cadence of its type again
+// @Tags generators
+// @Param id path string true "Generator ID (UUID)"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /generators/{id}/submission-cadence [delete]
+func (h *SubmissionCalendarHandler) DeleteGeneratorCadence(c *gin.Context) {
+	h.deleteCadence(c, models.CadenceScopeGenerator, "Generator")
+}
+
+func (h *SubmissionCalendarHandler) setCadence(c *gin.Context, scope, entity string) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid "+scope+" ID: must be UUID")
+		return
+	}
+	var req models.SubmissionCadenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.repo.SetSubmissionCadence(c.Request.Context(), scope, id, req.Cadence); err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, entity+" not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to set submission cadence: "+err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SubmissionCalendarHandler) deleteCadence(c *gin.Context, scope, entity string) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid "+scope+" ID: must be UUID")
+		return
+	}
+
+	if err := h.repo.DeleteSubmissionCadence(c.Request.Context(), scope, id); err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, entity+" has no submission cadence")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete submission cadence: "+err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/telemetry"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxTelemetryBytes bounds the payload of a pushed meter message
+const maxTelemetryBytes = 64 << 10
+
+// TelemetryHandler handles HTTP requests for meter telemetry
+type TelemetryHandler struct {
+	bridge *telemetry.Bridge
+}
+
+// NewTelemetryHandler creates a new TelemetryHandler instance
+func NewTelemetryHandler(bridge *telemetry.Bridge) *TelemetryHandler {
+	return &TelemetryHandler{
+		bridge: bridge,
+	}
+}
+
+// PostTelemetry handles POST /telemetry/*topic
+// @Summary Push a meter message
+// @Description Records a meter message as if it was published on the MQTT topic in the path, for meters that cannot reach the broker. The message is mapped by the first mapping of MQTT_MAPPING_FILE matching the topic: readings in add mode are added to the production of the day, in set mode they replace it. The production stores source telemetry and the topic as source reference
+// @Tags telemetry
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param topic path string true "MQTT topic, e.g. plants/7/meter"
+// @Param body body object true "Meter message"
+// @Success 200 {object} models.Production
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /telemetry/{topic} [post]
+func (h *TelemetryHandler) PostTelemetry(c *gin.Context) {
+	topic := strings.Trim(c.Param("topic"), "/")
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxTelemetryBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Telemetry message too large: maximum size exceeded")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to read telemetry message: "+err.Error())
+		return
+	}
+
+	pr, err := h.bridge.Handle(c.Request.Context(), topic, payload)
+	if err != nil {
+		if errors.Is(err, telemetry.ErrNoMapping) || errors.Is(err, telemetry.ErrInvalidMessage) {
+			utils.ErrorResponse(c, http.StatusUnprocessableEntity, "Unprocessable telemetry: "+err.Error())
+			return
+		}
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if errors.Is(err, database.ErrPeriodPublished) {
+			utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to record telemetry: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, pr)
+}
+
+// GetTelemetryStatus handles GET /admin/telemetry
+// @Summary Telemetry bridge status
+// @Description MQTT broker connection, subscribed topics and counters of received, recorded and failed meter messages since the server started
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.TelemetryStatus
+// @Router /admin/telemetry [get]
+func (h *TelemetryHandler) GetTelemetryStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.bridge.Status())
+}
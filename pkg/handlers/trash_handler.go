@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/trash"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// trashResources are the values accepted by the resource filter of GET /trash
+var trashResources = map[string]bool{
+	models.TrashType:           true,
+	models.TrashOperator:       true,
+	models.TrashGenerator:      true,
+	models.TrashProduction:     true,
+	models.TrashReport:         true,
+	models.TrashReportTemplate: true,
+}
+
+// TrashHandler handles HTTP requests for deleted resources
+type TrashHandler struct {
+	repo   database.Repository
+	purger *trash.Purger
+}
+
+// NewTrashHandler creates a new TrashHandler instance
+func NewTrashHandler(repo database.Repository, purger *trash.Purger) *TrashHandler {
+	return &TrashHandler{repo: repo, purger: purger}
+}
+
+// trashItemID reads the :id path parameter, answering 400 when it is not a UUID
+func trashItemID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid trash item ID: must be UUID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// GetTrash handles GET /trash
+// @Summary List the trash
+// @Description Deleted types, operators, generators, productions, reports and report templates that can still be restored, most recently deleted first. Each item counts the records deleted with it and says when it is purged
+// @Tags trash
+// @Produce json
+// @Param resource query string false "Only this kind of resource: type, operator, generator, production, report or report_template"
+// @Success 200 {array} models.TrashItem
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /trash [get]
+func (h *TrashHandler) GetTrash(c *gin.Context) {
+	resource := c.Query("resource")
+	if resource != "" && !trashResources[resource] {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid resource: must be type, operator, generator, production, report or report_template")
+		return
+	}
+
+	list, err := h.repo.GetTrash(c.Request.Context(), resource)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to list trash: "+err.Error())
+		return
+	}
+	if list == nil {
+		list = []*models.TrashItem{}
+	}
+	for _, t := range list {
+		t.PurgeAt = h.purger.PurgeAt(t.DeletedAt)
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetTrashItem handles GET /trash/:id
+// @Summary Get a trash item
+// @Tags trash
+// @Produce json
+// @Param id path string true "Trash item ID (UUID)"
+// @Success 200 {object} models.TrashItem
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /trash/{id} [get]
+func (h *TrashHandler) GetTrashItem(c *gin.Context) {
+	id, ok := trashItemID(c)
+	if !ok {
+		return
+	}
+
+	t, err := h.repo.GetTrashItem(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Trash item not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get trash item: "+err.Error())
+		return
+	}
+	t.PurgeAt = h.purger.PurgeAt(t.DeletedAt)
+
+	c.JSON(http.StatusOK, t)
+}
+
+// RestoreTrashItem handles POST /trash/:id/restore
+// @Summary Restore a deleted resource
+// @Description Put a deleted resource back with the records deleted with it, under its original ID, and remove it from the trash. Fails with 409 when the data changed in the meantime, e.g. the name was taken again or the generator of a production was deleted. Only unrestricted users may restore
+// @Tags trash
+// @Produce json
+// @Param id path string true "Trash item ID (UUID)"
+// @Success 200 {object} models.TrashItem
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /trash/{id}/restore [post]
+func (h *TrashHandler) RestoreTrashItem(c *gin.Context) {
+	id, ok := trashItemID(c)
+	if !ok {
+		return
+	}
+
+	t, err := h.repo.RestoreTrashItem(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Trash item not found")
+			return
+		}
+		if errors.Is(err, database.ErrRestoreConflict) || errors.Is(err, database.ErrPeriodPublished) {
+			utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to restore trash item: "+err.Error())
+		return
+	}
+	t.PurgeAt = h.purger.PurgeAt(t.DeletedAt)
+
+	c.JSON(http.StatusOK, t)
+}
+
+// PurgeTrashItem handles DELETE /trash/:id
+// @Summary Purge a trash item
+// @Description Delete a trash item for good before its retention period ends. Only unrestricted users may purge
+// @Tags trash
+// @Param id path string true "Trash item ID (UUID)"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /trash/{id} [delete]
+func (h *TrashHandler) PurgeTrashItem(c *gin.Context) {
+	id, ok := trashItemID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.PurgeTrashItem(c.Request.Context(), id); err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, http.StatusNotFound, "Trash item not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to purge trash item: "+err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
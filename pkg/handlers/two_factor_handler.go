@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorHandler handles TOTP enrollment of the current user and its reset
+// by admins
+type TwoFactorHandler struct {
+	repo   database.Repository
+	cfg    *auth.Config
+	tokens *auth.Tokens
+}
+
+// NewTwoFactorHandler creates a new TwoFactorHandler instance
+func NewTwoFactorHandler(repo database.Repository, cfg *auth.Config, tokens *auth.Tokens) *TwoFactorHandler {
+	return &TwoFactorHandler{repo: repo, cfg: cfg, tokens: tokens}
+}
+
+// GetTwoFactor handles GET /users/me/2fa
+// @Summary Get two-factor status
+// @Description Whether the current user has two-factor authentication enabled or pending, its unused backup codes, and whether a role of the user requires it for writes
+// @Tags users
+// @Produce json
+// @Success 200 {object} models.TwoFactorStatus
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/2fa [get]
+func (h *TwoFactorHandler) GetTwoFactor(c *gin.Context) {
+	p, ok := h.principal(c, false)
+	if !ok {
+		return
+	}
+	tf, err := h.repo.GetTwoFactor(c.Request.Context(), p.UserID)
+	if err != nil && err != sql.ErrNoRows {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get two-factor status: "+err.Error())
+		return
+	}
+	_, required := h.cfg.TwoFactor.RequiredFor(p.Roles)
+	status := &models.TwoFactorStatus{Required: required}
+	if tf != nil {
+		status.Enabled, status.Pending = tf.Enabled(), !tf.Enabled()
+		status.EnabledAt, status.BackupCodesLeft = tf.EnabledAt, tf.BackupCodesLeft
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// EnrollTwoFactor handles POST /users/me/2fa
+// @Summary Start two-factor enrollment
+// @Description Generate a TOTP secret for the current user. Render provisioningUri as a QR code for an authenticator app (or type in the secret), then confirm with a code at POST /users/me/2fa/confirm. Starting over replaces a pending enrollment
+// @Tags users
+// @Produce json
+// @Success 201 {object} models.TwoFactorEnrollment
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/2fa [post]
+func (h *TwoFactorHandler) EnrollTwoFactor(c *gin.Context) {
+	p, ok := h.principal(c, true)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	user, err := h.repo.GetUserByID(ctx, p.UserID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to enroll: "+err.Error())
+		return
+	}
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to enroll: "+err.Error())
+		return
+	}
+	if err := h.repo.SetTwoFactorSecret(ctx, p.UserID, secret); err != nil {
+		respondTwoFactorError(c, "enroll", err)
+		return
+	}
+	issuer := h.cfg.TwoFactor.Issuer
+	c.JSON(http.StatusCreated, &models.TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: auth.ProvisioningURI(issuer, user.Username, secret),
+		Issuer:          issuer,
+		Account:         user.Username,
+		Algorithm:       auth.TOTPAlgorithm,
+		Digits:          auth.TOTPDigits,
+		Period:          auth.TOTPPeriod,
+	})
+}
+
+// ConfirmTwoFactor handles POST /users/me/2fa/confirm
+// @Summary Confirm two-factor enrollment
+// @Description Enable two-factor authentication with a first code of the authenticator app. Answers an access token carrying the second factor and the backup codes, which are shown only this once; each logs in once in place of a code
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param body body models.TwoFactorCodeRequest true "Code of the authenticator app"
+// @Success 200 {object} models.TwoFactorEnabled
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/2fa/confirm [post]
+func (h *TwoFactorHandler) ConfirmTwoFactor(c *gin.Context) {
+	p, ok := h.principal(c, true)
+	if !ok {
+		return
+	}
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	ctx := c.Request.Context()
+	tf, err := h.repo.GetTwoFactor(ctx, p.UserID)
+	if err != nil {
+		respondTwoFactorError(c, "confirm two-factor enrollment", err)
+		return
+	}
+	if tf.Enabled() {
+		respondTwoFactorError(c, "confirm two-factor enrollment", database.ErrTwoFactorEnabled)
+		return
+	}
+	step, ok := auth.ValidateTOTP(tf.Secret, req.Code, time.Now(), h.cfg.TwoFactor.Skew, 0)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid two-factor code: check the clock of the device and enter the current code")
+		return
+	}
+	codes, hashes, err := newBackupCodes()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to confirm two-factor enrollment: "+err.Error())
+		return
+	}
+	if err := h.repo.EnableTwoFactor(ctx, p.UserID, step, hashes); err != nil {
+		respondTwoFactorError(c, "confirm two-factor enrollment", err)
+		return
+	}
+	user, err := h.repo.GetUserByID(ctx, p.UserID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to confirm two-factor enrollment: "+err.Error())
+		return
+	}
+	token, expiresAt, err := h.tokens.Issue(user.ID, true)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to issue access token: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, &models.TwoFactorEnabled{
+		TokenResponse: models.TokenResponse{AccessToken: token, TokenType: "Bearer", ExpiresAt: expiresAt, User: user},
+		BackupCodes:   codes,
+	})
+}
+
+// RegenerateBackupCodes handles POST /users/me/2fa/backup-codes
+// @Summary Regenerate backup codes
+// @Description Replace the backup codes of the current user, given a code of the authenticator app. The new codes are shown only this once
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param body body models.TwoFactorCodeRequest true "Code of the authenticator app"
+// @Success 200 {object} models.BackupCodes
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/2fa/backup-codes [post]
+func (h *TwoFactorHandler) RegenerateBackupCodes(c *gin.Context) {
+	tf, ok := h.checkCode(c, "regenerate backup codes", false)
+	if !ok {
+		return
+	}
+	codes, hashes, err := newBackupCodes()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to regenerate backup codes: "+err.Error())
+		return
+	}
+	if err := h.repo.ReplaceBackupCodes(c.Request.Context(), tf.UserID, hashes); err != nil {
+		respondTwoFactorError(c, "regenerate backup codes", err)
+		return
+	}
+	c.JSON(http.StatusOK, &models.BackupCodes{BackupCodes: codes})
+}
+
+// DisableTwoFactor handles POST /users/me/2fa/disable
+// @Summary Disable two-factor authentication
+// @Description Turn off two-factor authentication of the current user, given a code of the authenticator app or a backup code. Users whose role requires it cannot
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param body body models.TwoFactorCodeRequest true "Code of the authenticator app, or a backup code"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /users/me/2fa/disable [post]
+func (h *TwoFactorHandler) DisableTwoFactor(c *gin.Context) {
+	if p, ok := auth.PrincipalFrom(c.Request.Context()); ok {
+		if role, required := h.cfg.TwoFactor.RequiredFor(p.Roles); required {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: two-factor authentication is required for role "+role)
+			return
+		}
+	}
+	tf, ok := h.checkCode(c, "disable two-factor authentication", true)
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteTwoFactor(c.Request.Context(), tf.UserID); err != nil {
+		respondTwoFactorError(c, "disable two-factor authentication", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ResetTwoFactor handles DELETE /users/:id/2fa
+// @Summary Reset two-factor authentication
+// @Description Remove the two-factor enrollment of a user who lost their authenticator app and backup codes, so they can log in with their password and enroll again
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID (UUID)"
+// @Success 204
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/2fa [delete]
+func (h *TwoFactorHandler) ResetTwoFactor(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+	if err := h.repo.DeleteTwoFactor(c.Request.Context(), id); err != nil {
+		respondTwoFactorError(c, "reset two-factor authentication", err)
+		return
+	}
+	utils.LogInfo("Two-factor authentication of user " + id.String() + " reset")
+	c.Status(http.StatusNoContent)
+}
+
+// principal returns the principal of the request, answering 401 to requests
+// without one. Changing the enrollment is up to the user, so writes are
+// refused during impersonation.
+func (h *TwoFactorHandler) principal(c *gin.Context, write bool) (*auth.Principal, bool) {
+	if _, ok := currentUser(c); !ok {
+		return nil, false
+	}
+	p, _ := auth.PrincipalFrom(c.Request.Context())
+	if write && p.Impersonated() {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: two-factor authentication cannot be changed while impersonating")
+		return nil, false
+	}
+	return p, true
+}
+
+// checkCode reads a code from the request body and checks it against the
+// enabled enrollment of the current user; backup codes are accepted when
+// backup is set
+func (h *TwoFactorHandler) checkCode(c *gin.Context, action string, backup bool) (*models.TwoFactor, bool) {
+	p, ok := h.principal(c, true)
+	if !ok {
+		return nil, false
+	}
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return nil, false
+	}
+	ctx := c.Request.Context()
+	tf, err := h.repo.GetTwoFactor(ctx, p.UserID)
+	if err == nil && !tf.Enabled() {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		respondTwoFactorError(c, action, err)
+		return nil, false
+	}
+	ok, err = useTwoFactorCode(ctx, h.repo, h.cfg.TwoFactor, tf, req.Code, backup)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to "+action+": "+err.Error())
+		return nil, false
+	}
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid two-factor code")
+		return nil, false
+	}
+	return tf, true
+}
+
+// useTwoFactorCode checks a code against an enabled enrollment and uses it
+// up: TOTP codes by their time step, backup codes, when accepted, one by one
+func useTwoFactorCode(ctx context.Context, repo database.Repository, cfg auth.TwoFactorConfig, tf *models.TwoFactor, code string, backup bool) (bool, error) {
+	if step, ok := auth.ValidateTOTP(tf.Secret, code, time.Now(), cfg.Skew, tf.LastStep); ok {
+		return repo.UseTwoFactorStep(ctx, tf.UserID, step)
+	}
+	if !backup {
+		return false, nil
+	}
+	return repo.UseBackupCode(ctx, tf.UserID, auth.HashBackupCode(code))
+}
+
+// newBackupCodes returns new backup codes and their hashes
+func newBackupCodes() ([]string, []string, error) {
+	codes, err := auth.NewBackupCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashBackupCode(code)
+	}
+	return codes, hashes, nil
+}
+
+func respondTwoFactorError(c *gin.Context, action string, err error) {
+	switch {
+	case err == sql.ErrNoRows:
+		utils.ErrorResponse(c, http.StatusNotFound, "Two-factor authentication not found: start an enrollment with POST /users/me/2fa")
+	case errors.Is(err, database.ErrTwoFactorEnabled):
+		utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+	case errors.Is(err, auth.ErrForbidden):
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to "+action+": "+err.Error())
+	}
+}
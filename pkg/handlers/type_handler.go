@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
-	"strconv"
+	"strings"
 
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/catalog"
 	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpx"
 	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
 	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
 	"github.com/gin-gonic/gin"
@@ -14,26 +18,30 @@ import (
 
 // TypeHandler handles HTTP requests for energy generator types
 type TypeHandler struct {
-	repo database.Repository
+	repo    database.Repository
+	catalog *catalog.Config
 }
 
 // NewTypeHandler creates a new TypeHandler instance
-func NewTypeHandler(repo database.Repository) *TypeHandler {
+func NewTypeHandler(repo database.Repository, catalogConfig *catalog.Config) *TypeHandler {
 	return &TypeHandler{
-		repo: repo,
+		repo:    repo,
+		catalog: catalogConfig,
 	}
 }
 
 // CreateType handles POST /types
 // @Summary Create a new energy generator type
-// @Description Create a new energy generator type (renewable/non-renewable)
+// @Description Create a new energy generator type (renewable/non-renewable). The technology code is inferred from the name when omitted
 // @Tags types
 // @Accept json
 // @Produce json
 // @Param type body models.CreateTypeRequest true "Type data"
 // @Success 201 {object} models.Type
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
 // @Router /types [post]
 func (h *TypeHandler) CreateType(c *gin.Context) {
 	var req models.CreateTypeRequest
@@ -41,32 +49,47 @@ func (h *TypeHandler) CreateType(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
 		return
 	}
+	if !checkAttributes(c, h.repo, models.CustomEntityType, req.CustomAttributes, false) {
+		return
+	}
+
+	code, err := catalog.Resolve(req.Name, req.TechnologyCode, h.catalog.Enforce)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid technology: name or technologyCode does not match the catalog (see /api/v1/catalog/technologies)")
+		return
+	}
+	req.TechnologyCode = code
 
 	typeRecord, err := h.repo.CreateType(c.Request.Context(), &req)
 	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create type: "+err.Error())
 		return
 	}
 
-	c.JSON(http.StatusCreated, typeRecord)
+	respond(c, http.StatusCreated, typeRecord, nil)
 }
 
 // GetTypeByID handles GET /types/:id
 // @Summary Get type by ID
-// @Description Get an energy generator type by its UUID
+// @Description Get an energy generator type by its UUID. The name and description are translated to the language of Accept-Language (es or en) when the type has a translation to it. Untranslated responses send the version of the type as the ETag, for If-Match on updates
 // @Tags types
 // @Produce json
 // @Param id path string true "Type ID (UUID)"
 // @Success 200 {object} models.Type
-// @Failure 400 {object} models.ErrorResponse
+// @Header 200 {string} ETag "Version of the type, unless translated"
+// @Failure 400 {object} httpx.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Param Accept-Language header string false "Preferred language (es, en)"
 // @Router /types/{id} [get]
 func (h *TypeHandler) GetTypeByID(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
-	if err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid type ID: ID must be a valid UUID")
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
 		return
 	}
 
@@ -79,30 +102,42 @@ func (h *TypeHandler) GetTypeByID(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get type: "+err.Error())
 		return
 	}
+	lang := negotiateLanguage(c, models.Languages...)
+	if lang == "" {
+		// The ETag is the version; translated responses keep the hash of their content
+		setVersion(c, typeRecord.Version)
+	} else {
+		translations, err := h.repo.GetTypeTranslations(c.Request.Context(), id)
+		if err != nil && err != sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get type translations: "+err.Error())
+			return
+		}
+		for _, tr := range translations {
+			if tr.Language == lang {
+				typeRecord.Localize(tr)
+			}
+		}
+	}
 
-	c.JSON(http.StatusOK, typeRecord)
+	respond(c, http.StatusOK, typeRecord, nil)
 }
 
 // GetAllTypes handles GET /types
 // @Summary Get all types
-// @Description Get all energy generator types, optionally filtered by renewable status
+// @Description Get all energy generator types, optionally filtered by renewable status. Names and descriptions are translated to the language of Accept-Language (es or en) where a type has a translation to it
 // @Tags types
 // @Produce json
 // @Param renewable query boolean false "Filter by renewable status (true/false)"
+// @Param Accept-Language header string false "Preferred language (es, en)"
 // @Success 200 {array} models.Type
-// @Failure 400 {object} models.ErrorResponse
+// @Failure 400 {object} httpx.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /types [get]
 func (h *TypeHandler) GetAllTypes(c *gin.Context) {
-	var isRenewable *bool
-
-	if renewableParam := c.Query("renewable"); renewableParam != "" {
-		renewable, err := strconv.ParseBool(renewableParam)
-		if err != nil {
-			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid renewable parameter: renewable must be true or false")
-			return
-		}
-		isRenewable = &renewable
+	q := httpx.New(c)
+	isRenewable := q.Bool("renewable")
+	if !q.Valid() {
+		return
 	}
 
 	types, err := h.repo.GetAllTypes(c.Request.Context(), isRenewable)
@@ -114,8 +149,18 @@ func (h *TypeHandler) GetAllTypes(c *gin.Context) {
 	if types == nil {
 		types = []*models.Type{}
 	}
+	if lang := negotiateLanguage(c, models.Languages...); lang != "" {
+		translations, err := h.repo.GetTranslationsByLanguage(c.Request.Context(), lang)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get type translations: "+err.Error())
+			return
+		}
+		for _, t := range types {
+			t.Localize(translations[t.ID])
+		}
+	}
 
-	c.JSON(http.StatusOK, types)
+	respond(c, http.StatusOK, types, nil)
 }
 
 // UpdateType handles PUT /types/:id
@@ -126,16 +171,19 @@ func (h *TypeHandler) GetAllTypes(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Type ID (UUID)"
 // @Param type body models.UpdateTypeRequest true "Updated type data"
+// @Param If-Match header string false "ETag of the type as read; the update fails with 412 if it changed since"
 // @Success 200 {object} models.Type
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 412 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
 // @Router /types/{id} [put]
 func (h *TypeHandler) UpdateType(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
-	if err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid type ID: ID must be a valid UUID")
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
 		return
 	}
 
@@ -144,9 +192,87 @@ func (h *TypeHandler) UpdateType(c *gin.Context) {
 		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
 		return
 	}
+	if !checkAttributes(c, h.repo, models.CustomEntityType, req.CustomAttributes, true) {
+		return
+	}
+
+	if req.Name != "" || req.TechnologyCode != "" {
+		code, err := catalog.Resolve(req.Name, req.TechnologyCode, h.catalog.Enforce)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid technology: name or technologyCode does not match the catalog (see /api/v1/catalog/technologies)")
+			return
+		}
+		req.TechnologyCode = code
+	}
+	h.saveType(c, id, &req)
+}
+
+// PatchType handles PATCH /types/:id
+// @Summary Partially update type
+// @Description Update only the fields present in the body, leaving the others as they are; technologyCode set to null clears it and customAttributes set to null removes them all. name, description and isRenewable cannot be null
+// @Tags types
+// @Accept json
+// @Produce json
+// @Param id path string true "Type ID (UUID)"
+// @Param type body models.UpdateTypeRequest true "Fields to update"
+// @Param If-Match header string false "ETag of the type as read; the update fails with 412 if it changed since"
+// @Success 200 {object} models.Type
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 412 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /types/{id} [patch]
+func (h *TypeHandler) PatchType(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+
+	var req models.UpdateTypeRequest
+	fields, ok := bindPatch(c, &req, "name", "description", "isRenewable")
+	if !ok {
+		return
+	}
+	req.Fields = fields
+	if !checkAttributes(c, h.repo, models.CustomEntityType, req.CustomAttributes, true) {
+		return
+	}
+
+	if req.Name != "" || req.TechnologyCode != "" {
+		code, err := catalog.Resolve(req.Name, req.TechnologyCode, h.catalog.Enforce)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid technology: name or technologyCode does not match the catalog (see /api/v1/catalog/technologies)")
+			return
+		}
+		// A new name maps to its technology, as with a PUT
+		if code != "" {
+			req.TechnologyCode = code
+			req.Fields["technologyCode"] = true
+		}
+	}
+	h.saveType(c, id, &req)
+}
 
-	typeRecord, err := h.repo.UpdateType(c.Request.Context(), id, &req)
+// saveType stores the update of a type and answers with the type
+func (h *TypeHandler) saveType(c *gin.Context, id uuid.UUID, req *models.UpdateTypeRequest) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	req.IfMatch = version
+	typeRecord, err := h.repo.UpdateType(c.Request.Context(), id, req)
 	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if errors.Is(err, database.ErrVersionMismatch) {
+			utils.ErrorResponse(c, http.StatusPreconditionFailed, "Precondition failed: "+err.Error())
+			return
+		}
 		if err == sql.ErrNoRows {
 			utils.ErrorResponse(c, http.StatusNotFound, "Type not found: No type found with the given ID")
 			return
@@ -155,30 +281,47 @@ func (h *TypeHandler) UpdateType(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, typeRecord)
+	setVersion(c, typeRecord.Version)
+	respond(c, http.StatusOK, typeRecord, nil)
 }
 
 // DeleteType handles DELETE /types/:id
 // @Summary Delete type
-// @Description Delete an energy generator type by ID
+// @Description Move an energy generator type to the trash; restore it with POST /trash/{id}/restore. A type that still has generators is not deleted (409) unless cascade is set, which moves its generators and their production records to the trash with it
 // @Tags types
 // @Produce json
 // @Param id path string true "Type ID (UUID)"
+// @Param cascade query bool false "Also delete the generators of the type and their production records"
 // @Success 204
-// @Failure 400 {object} models.ErrorResponse
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
 // @Router /types/{id} [delete]
 func (h *TypeHandler) DeleteType(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
-	if err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid type ID: ID must be a valid UUID")
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	cascade := q.Bool("cascade")
+	if !q.Valid() {
 		return
 	}
 
-	err = h.repo.DeleteType(c.Request.Context(), id)
+	err := h.repo.DeleteType(c.Request.Context(), id, cascade != nil && *cascade)
 	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if errors.Is(err, database.ErrStillReferenced) {
+			utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error()+"; delete them first or set cascade=true")
+			return
+		}
+		if errors.Is(err, database.ErrPeriodPublished) {
+			utils.ErrorResponse(c, http.StatusConflict, "Conflict: "+err.Error())
+			return
+		}
 		if err == sql.ErrNoRows {
 			utils.ErrorResponse(c, http.StatusNotFound, "Type not found: No type found with the given ID")
 			return
@@ -189,3 +332,172 @@ func (h *TypeHandler) DeleteType(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+// MergeType handles POST /types/:id/merge-into/:targetId
+// @Summary Merge duplicate type
+// @Description Re-point all generators of a duplicate type to the target type, record the duplicate name as an alias and soft-delete the duplicate, in one transaction
+// @Tags types
+// @Produce json
+// @Param id path string true "Duplicate type ID (UUID)"
+// @Param targetId path string true "Target type ID (UUID)"
+// @Success 200 {object} models.TypeMergeResult
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /types/{id}/merge-into/{targetId} [post]
+func (h *TypeHandler) MergeType(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	targetID := q.PathUUID("targetId")
+	if !q.Valid() {
+		return
+	}
+	if id == targetID {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid merge: a type cannot be merged into itself")
+		return
+	}
+
+	result, err := h.repo.MergeType(c.Request.Context(), id, targetID)
+	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Type not found: source or target type does not exist")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to merge type: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetTypeTranslations handles GET /types/:id/translations
+// @Summary List type translations
+// @Description Name and description of a type in each language it is translated to
+// @Tags types
+// @Produce json
+// @Param id path string true "Type ID (UUID)"
+// @Success 200 {array} models.TypeTranslation
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /types/{id}/translations [get]
+func (h *TypeHandler) GetTypeTranslations(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	if !q.Valid() {
+		return
+	}
+
+	list, err := h.repo.GetTypeTranslations(c.Request.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Type not found: No type found with the given ID")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get type translations: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// SetTypeTranslation handles PUT /types/:id/translations/:lang
+// @Summary Set a type translation
+// @Description Set the name and description of a type in a language (es or en), replacing any previous translation to it
+// @Tags types
+// @Accept json
+// @Produce json
+// @Param id path string true "Type ID (UUID)"
+// @Param lang path string true "Language (es, en)"
+// @Param translation body models.TypeTranslationRequest true "Translated texts"
+// @Success 200 {object} models.TypeTranslation
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /types/{id}/translations/{lang} [put]
+func (h *TypeHandler) SetTypeTranslation(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	lang := translationLanguage(q, c)
+	if !q.Valid() {
+		return
+	}
+	var req models.TypeTranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	tr, err := h.repo.SetTypeTranslation(c.Request.Context(), id, lang, &req)
+	if err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Type not found: No type found with the given ID")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to set type translation: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, tr)
+}
+
+// DeleteTypeTranslation handles DELETE /types/:id/translations/:lang
+// @Summary Delete a type translation
+// @Description The type is served with its own name and description to that language again
+// @Tags types
+// @Param id path string true "Type ID (UUID)"
+// @Param lang path string true "Language (es, en)"
+// @Success 204
+// @Failure 400 {object} httpx.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /types/{id}/translations/{lang} [delete]
+func (h *TypeHandler) DeleteTypeTranslation(c *gin.Context) {
+	q := httpx.New(c)
+	id := q.PathUUID("id")
+	lang := translationLanguage(q, c)
+	if !q.Valid() {
+		return
+	}
+
+	if err := h.repo.DeleteTypeTranslation(c.Request.Context(), id, lang); err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Translation not found: The type has no translation to this language")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete type translation: "+err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// translationLanguage reads the lang path parameter, which must be one of models.Languages
+func translationLanguage(q *httpx.Params, c *gin.Context) string {
+	lang := c.Param("lang")
+	for _, l := range models.Languages {
+		if lang == l {
+			return lang
+		}
+	}
+	q.Fail(httpx.InPath, "lang", "must be one of "+strings.Join(models.Languages, ", "))
+	return ""
+}
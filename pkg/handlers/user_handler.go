@@ -1,11 +1,17 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
+	"slices"
 
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
 	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
 	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // UserHandler handles HTTP requests for users
@@ -22,16 +28,210 @@ func NewUserHandler(repo database.Repository) *UserHandler {
 
 // GetUserProfile handles GET /users/profile
 // @Summary Get user profile
-// @Description Get the current user's profile information
+// @Description Get the profile of the user the access token was issued to
 // @Tags users
 // @Produce json
 // @Success 200 {object} models.User
+// @Failure 401 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
 // @Router /users/profile [get]
 func (h *UserHandler) GetUserProfile(c *gin.Context) {
-	// TODO: Implement user profile retrieval
-	// This is a placeholder implementation
-	utils.ErrorResponse(c, http.StatusNotImplemented, "Not implemented: User operations not yet implemented")
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.repo.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get user profile: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// currentUser returns the user the access token of the request was issued
+// to, answering 401 to requests without one
+func currentUser(c *gin.Context) (uuid.UUID, bool) {
+	p, ok := auth.PrincipalFrom(c.Request.Context())
+	if !ok {
+		c.Header("WWW-Authenticate", `Bearer realm="tadb"`)
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Authentication required: send an access token as \"Authorization: Bearer <token>\"")
+		return uuid.Nil, false
+	}
+	return p.UserID, true
+}
+
+// SetUserRoles handles PUT /users/:id/roles
+// @Summary Set user roles
+// @Description Replace the roles of a user: admin and operator users write types, generators and productions, viewers only read. Admins cannot drop their own admin role
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID (UUID)"
+// @Param body body models.SetUserRolesRequest true "Roles"
+// @Success 200 {object} models.User
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/roles [put]
+func (h *UserHandler) SetUserRoles(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID: ID must be a valid UUID")
+		return
+	}
+
+	var req models.SetUserRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	// An admin demoting itself could leave nobody to manage accounts
+	if p, ok := auth.PrincipalFrom(c.Request.Context()); ok && p.UserID == userID && !slices.Contains(req.Roles, auth.RoleAdmin) {
+		utils.ErrorResponse(c, http.StatusConflict, "Conflict: admins cannot drop their own admin role")
+		return
+	}
+
+	user, err := h.repo.SetUserRoles(c.Request.Context(), userID, req.Roles)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to set user roles: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// GetOperatorGrants handles GET /users/:id/operator-grants
+// @Summary List operator grants
+// @Description List the operators whose generators the user may write. A user without grants is not restricted
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID (UUID)"
+// @Success 200 {array} models.OperatorGrant
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /users/{id}/operator-grants [get]
+func (h *UserHandler) GetOperatorGrants(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID: ID must be a valid UUID")
+		return
+	}
+
+	grants, err := h.repo.GetOperatorGrants(c.Request.Context(), userID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get operator grants: "+err.Error())
+		return
+	}
+	if grants == nil {
+		grants = []*models.OperatorGrant{}
+	}
+
+	c.JSON(http.StatusOK, grants)
+}
+
+// GrantOperator handles POST /users/:id/operator-grants
+// @Summary Grant operator write access
+// @Description Restrict the user's writes to generators (and their productions) of the given operator, in addition to existing grants
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID (UUID)"
+// @Param grant body models.GrantOperatorRequest true "Operator to grant"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/operator-grants [post]
+func (h *UserHandler) GrantOperator(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID: ID must be a valid UUID")
+		return
+	}
+
+	var req models.GrantOperatorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if _, err := h.repo.GetOperatorByID(c.Request.Context(), req.OperatorID); err != nil {
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Operator not found: No operator found with the given ID")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to get operator: "+err.Error())
+		return
+	}
+
+	if err := h.repo.GrantOperator(c.Request.Context(), userID, req.OperatorID); err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to grant operator: "+err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeOperator handles DELETE /users/:id/operator-grants/:operatorId
+// @Summary Revoke operator write access
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID (UUID)"
+// @Param operatorId path string true "Operator ID (UUID)"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Security BearerAuth
+// @Router /users/{id}/operator-grants/{operatorId} [delete]
+func (h *UserHandler) RevokeOperator(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid user ID: ID must be a valid UUID")
+		return
+	}
+	operatorID, err := uuid.Parse(c.Param("operatorId"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid operator ID: ID must be a valid UUID")
+		return
+	}
+
+	if err := h.repo.RevokeOperator(c.Request.Context(), userID, operatorID); err != nil {
+		if errors.Is(err, auth.ErrForbidden) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: "+err.Error())
+			return
+		}
+		if err == sql.ErrNoRows {
+			utils.ErrorResponse(c, http.StatusNotFound, "Grant not found: the user has no grant for this operator")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke operator: "+err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
 // HealthCheck handles GET /health
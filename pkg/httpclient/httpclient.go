@@ -0,0 +1,117 @@
+// Package httpclient builds the HTTP clients of outbound integrations
+// (report webhooks, object storage) from one configuration, so proxies,
+// extra certificate authorities and timeouts are set in a single place.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// NoTimeout disables the overall request timeout of New for streamed bodies;
+// the connect and response header timeouts still apply
+const NoTimeout time.Duration = -1
+
+// Config represents the outbound HTTP settings. Proxies come from the
+// standard HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables.
+type Config struct {
+	// CABundle is a PEM file of certificate authorities trusted besides the system ones
+	CABundle string
+	// Timeout is the default limit of a whole request, body included
+	Timeout               time.Duration
+	ConnectTimeout        time.Duration
+	ResponseHeaderTimeout time.Duration
+}
+
+// LoadConfig loads outbound HTTP configuration from environment variables
+func LoadConfig() *Config {
+	return &Config{
+		CABundle:              utils.GetEnv("OUTBOUND_CA_BUNDLE", ""),
+		Timeout:               utils.GetEnvAsDuration("OUTBOUND_TIMEOUT", 30*time.Second),
+		ConnectTimeout:        utils.GetEnvAsDuration("OUTBOUND_CONNECT_TIMEOUT", 10*time.Second),
+		ResponseHeaderTimeout: utils.GetEnvAsDuration("OUTBOUND_RESPONSE_HEADER_TIMEOUT", 30*time.Second),
+	}
+}
+
+var (
+	mu        sync.RWMutex
+	transport http.RoundTripper = http.DefaultTransport
+	timeout                     = 30 * time.Second
+)
+
+// Configure applies cfg to the clients created afterwards. It fails when
+// the CA bundle cannot be read or holds no certificate.
+func Configure(cfg *Config) error {
+	t, err := NewTransport(cfg)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	transport = t
+	if cfg.Timeout > 0 {
+		timeout = cfg.Timeout
+	}
+	mu.Unlock()
+	return nil
+}
+
+// NewTransport builds the transport described by cfg
+func NewTransport(cfg *Config) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	if cfg.ConnectTimeout > 0 {
+		t.DialContext = (&net.Dialer{Timeout: cfg.ConnectTimeout, KeepAlive: 30 * time.Second}).DialContext
+		t.TLSHandshakeTimeout = cfg.ConnectTimeout
+	}
+	if cfg.ResponseHeaderTimeout > 0 {
+		t.ResponseHeaderTimeout = cfg.ResponseHeaderTimeout
+	}
+	if cfg.CABundle != "" {
+		pem, err := os.ReadFile(cfg.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %s holds no PEM certificate", cfg.CABundle)
+		}
+		t.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return t, nil
+}
+
+// New returns a client on the configured transport that gives up on
+// requests after timeout; 0 uses the configured default and NoTimeout none
+func New(d time.Duration) *http.Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	switch {
+	case d == 0:
+		d = timeout
+	case d < 0:
+		d = 0
+	}
+	return &http.Client{Transport: transport, Timeout: d}
+}
+
+// TLSConfig returns the TLS settings of the configured transport, with the
+// extra certificate authorities, for integrations dialing TLS themselves
+func TLSConfig() *tls.Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	if t, ok := transport.(*http.Transport); ok && t.TLSClientConfig != nil {
+		return t.TLSClientConfig.Clone()
+	}
+	return &tls.Config{MinVersion: tls.VersionTLS12}
+}
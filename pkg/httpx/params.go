@@ -0,0 +1,267 @@
+// Package httpx parses and validates the path and query parameters of a
+// request. Parsers collect every invalid parameter; Valid then answers 400
+// with all of them, so handlers report bad input the same way everywhere.
+package httpx
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/geo"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DateLayout is the format of date parameters
+const DateLayout = "2006-01-02"
+
+// Parameter locations
+const (
+	InPath  = "path"
+	InQuery = "query"
+)
+
+// ParamError describes an invalid parameter
+type ParamError struct {
+	Param  string `json:"param" example:"startDate"`
+	In     string `json:"in" example:"query"`
+	Reason string `json:"reason" example:"must be a date (YYYY-MM-DD)"`
+}
+
+func (e *ParamError) Error() string {
+	return "Invalid " + e.Param + ": " + e.Reason
+}
+
+// ErrorResponse is the body of a 400 answer to invalid parameters. Error
+// describes the first invalid parameter, as in every other error response;
+// Params lists all of them.
+type ErrorResponse struct {
+	Status string        `json:"status" example:"error"`
+	Error  string        `json:"error" example:"Invalid startDate: must be a date (YYYY-MM-DD)"`
+	Params []*ParamError `json:"params"`
+}
+
+// Params parses the parameters of a request
+type Params struct {
+	c    *gin.Context
+	errs []*ParamError
+}
+
+// New creates a parser for the parameters of c
+func New(c *gin.Context) *Params {
+	return &Params{c: c}
+}
+
+// Fail records an invalid parameter, for checks the parsers do not cover
+func (p *Params) Fail(in, name, reason string) {
+	p.errs = append(p.errs, &ParamError{Param: name, In: in, Reason: reason})
+}
+
+// Errors returns the invalid parameters found so far
+func (p *Params) Errors() []*ParamError {
+	return p.errs
+}
+
+// Valid reports whether every parameter parsed so far is valid; otherwise it
+// answers 400 listing the invalid ones and aborts the request
+func (p *Params) Valid() bool {
+	if len(p.errs) == 0 {
+		return true
+	}
+	p.c.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse{
+		Status: "error",
+		Error:  p.errs[0].Error(),
+		Params: p.errs,
+	})
+	return false
+}
+
+// PathUUID parses a required UUID path parameter
+func (p *Params) PathUUID(name string) uuid.UUID {
+	id, err := uuid.Parse(p.c.Param(name))
+	if err != nil {
+		p.Fail(InPath, name, "must be a UUID")
+		return uuid.Nil
+	}
+	return id
+}
+
+// PathInt parses a required integer path parameter between min and max (inclusive)
+func (p *Params) PathInt(name string, min, max int) int {
+	n, err := strconv.Atoi(p.c.Param(name))
+	if err != nil || n < min || n > max {
+		p.Fail(InPath, name, rangeReason(min, max))
+		return 0
+	}
+	return n
+}
+
+// UUID parses an optional UUID query parameter; nil when it is absent
+func (p *Params) UUID(name string) *uuid.UUID {
+	v := p.c.Query(name)
+	if v == "" {
+		return nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		p.Fail(InQuery, name, "must be a UUID")
+		return nil
+	}
+	return &id
+}
+
+// Date parses an optional YYYY-MM-DD query parameter; nil when it is absent
+func (p *Params) Date(name string) *string {
+	v := p.c.Query(name)
+	if v == "" {
+		return nil
+	}
+	if _, err := time.Parse(DateLayout, v); err != nil {
+		p.Fail(InQuery, name, "must be a date (YYYY-MM-DD)")
+		return nil
+	}
+	return &v
+}
+
+// DateRange parses optional start and end date query parameters and checks
+// that the range is not reversed
+func (p *Params) DateRange(startName, endName string) (start, end *string) {
+	start, end = p.Date(startName), p.Date(endName)
+	// Dates in DateLayout order lexically
+	if start != nil && end != nil && *end < *start {
+		p.Fail(InQuery, endName, "must not be before "+startName)
+	}
+	return start, end
+}
+
+// Bool parses an optional true/false query parameter; nil when it is absent
+func (p *Params) Bool(name string) *bool {
+	v := p.c.Query(name)
+	if v == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		p.Fail(InQuery, name, "must be true or false")
+		return nil
+	}
+	return &b
+}
+
+// Enum parses an optional query parameter that must be one of allowed; nil when it is absent
+func (p *Params) Enum(name string, allowed ...string) *string {
+	v := p.c.Query(name)
+	if v == "" {
+		return nil
+	}
+	for _, a := range allowed {
+		if v == a {
+			return &v
+		}
+	}
+	p.Fail(InQuery, name, "must be one of "+strings.Join(allowed, ", "))
+	return nil
+}
+
+// EnumList parses an optional comma-separated query parameter whose items
+// must each be one of allowed; duplicates are dropped and nil when it is absent
+func (p *Params) EnumList(name string, allowed ...string) []string {
+	v := p.c.Query(name)
+	if v == "" {
+		return nil
+	}
+	var list []string
+	seen := map[string]bool{}
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if seen[item] {
+			continue
+		}
+		ok := false
+		for _, a := range allowed {
+			ok = ok || item == a
+		}
+		if !ok {
+			p.Fail(InQuery, name, fmt.Sprintf("%q is not one of %s", item, strings.Join(allowed, ", ")))
+			return nil
+		}
+		seen[item] = true
+		list = append(list, item)
+	}
+	return list
+}
+
+// Int parses an optional integer query parameter between min and max
+// (inclusive); def when it is absent or invalid
+func (p *Params) Int(name string, def, min, max int) int {
+	v := p.c.Query(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min || n > max {
+		p.Fail(InQuery, name, rangeReason(min, max))
+		return def
+	}
+	return n
+}
+
+// rangeReason words the bounds of an integer parameter
+func rangeReason(min, max int) string {
+	switch {
+	case max == math.MaxInt && min == 0:
+		return "must be a non-negative integer"
+	case max == math.MaxInt && min == 1:
+		return "must be a positive integer"
+	case max == math.MaxInt:
+		return fmt.Sprintf("must be an integer of at least %d", min)
+	}
+	return fmt.Sprintf("must be an integer between %d and %d", min, max)
+}
+
+// Float parses an optional number query parameter between min and max
+// (inclusive); def when it is absent or invalid
+func (p *Params) Float(name string, def, min, max float64) float64 {
+	v := p.c.Query(name)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(f) || f < min || f > max {
+		p.Fail(InQuery, name, fmt.Sprintf("must be a number between %g and %g", min, max))
+		return def
+	}
+	return f
+}
+
+// Point parses an optional "lat,lng" query parameter; nil when it is absent
+func (p *Params) Point(name string) *geo.Point {
+	v := p.c.Query(name)
+	if v == "" {
+		return nil
+	}
+	pt, err := geo.ParsePoint(v)
+	if err != nil {
+		p.Fail(InQuery, name, err.Error())
+		return nil
+	}
+	return &pt
+}
+
+// BBox parses an optional "minLng,minLat,maxLng,maxLat" query parameter; nil when it is absent
+func (p *Params) BBox(name string) *geo.BBox {
+	v := p.c.Query(name)
+	if v == "" {
+		return nil
+	}
+	b, err := geo.ParseBBox(v)
+	if err != nil {
+		p.Fail(InQuery, name, err.Error())
+		return nil
+	}
+	return &b
+}
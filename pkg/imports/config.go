@@ -0,0 +1,58 @@
+package imports
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// Config represents import limits and tuning options
+type Config struct {
+	MaxRows           int64
+	MaxBytes          int64
+	ProgressInterval  int64
+	MaxReportedErrors int
+	Delimiter         rune
+	UploadDir         string
+	MaxChunkBytes     int64
+	// DropDir is the folder watched for CSV drops (e.g. SCADA exports); empty disables the watcher
+	DropDir string
+	// DropArchiveDir and DropErrorDir receive the processed files
+	DropArchiveDir string
+	DropErrorDir   string
+	DropInterval   time.Duration
+	// DropSettle is how long a file must stay unmodified before it is picked up
+	DropSettle time.Duration
+}
+
+// LoadConfig loads import configuration from environment variables
+func LoadConfig() *Config {
+	config := &Config{
+		MaxRows:           utils.GetEnvAsInt64("IMPORT_MAX_ROWS", 5_000_000),
+		MaxBytes:          utils.GetEnvAsInt64("IMPORT_MAX_BYTES", 4<<30), // 4 GiB
+		ProgressInterval:  utils.GetEnvAsInt64("IMPORT_PROGRESS_INTERVAL", 1000),
+		MaxReportedErrors: utils.GetEnvAsInt("IMPORT_MAX_REPORTED_ERRORS", 100),
+		Delimiter:         ',',
+		UploadDir:         utils.GetEnv("IMPORT_UPLOAD_DIR", filepath.Join(os.TempDir(), "tadb-uploads")),
+		MaxChunkBytes:     utils.GetEnvAsInt64("IMPORT_MAX_CHUNK_BYTES", 64<<20), // 64 MiB
+		DropDir:           utils.GetEnv("IMPORT_DROP_DIR", ""),
+		DropInterval:      utils.GetEnvAsDuration("IMPORT_DROP_INTERVAL", 30*time.Second),
+		DropSettle:        utils.GetEnvAsDuration("IMPORT_DROP_SETTLE", 10*time.Second),
+	}
+	config.DropArchiveDir = utils.GetEnv("IMPORT_DROP_ARCHIVE_DIR", filepath.Join(config.DropDir, "archive"))
+	config.DropErrorDir = utils.GetEnv("IMPORT_DROP_ERROR_DIR", filepath.Join(config.DropDir, "error"))
+
+	if d := utils.GetEnv("IMPORT_CSV_DELIMITER", ","); len(d) == 1 {
+		config.Delimiter = rune(d[0])
+	}
+	if config.ProgressInterval <= 0 {
+		config.ProgressInterval = 1000
+	}
+	if config.DropInterval <= 0 {
+		config.DropInterval = 30 * time.Second
+	}
+
+	return config
+}
@@ -0,0 +1,353 @@
+package imports
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// ErrDropFileNotFound is returned when no processed drop file has the given ID
+var ErrDropFileNotFound = errors.New("drop file not found")
+
+// Drop file status values
+const (
+	// DropStatusArchived files were imported in full and moved to the archive folder
+	DropStatusArchived = "archived"
+	// DropStatusRejected files failed validation; nothing was imported
+	DropStatusRejected = "rejected"
+	// DropStatusFailed files were imported with failed rows or aborted
+	// midway; the rows that succeeded stay imported
+	DropStatusFailed = "failed"
+)
+
+// dropLogFile is the processing log in the drop folder, one JSON entry per line
+const dropLogFile = "processing-log.jsonl"
+
+// maxDropLog is the number of processing log entries kept in memory
+const maxDropLog = 1000
+
+// DropFile is the processing log entry of a file picked up from the drop folder
+// @Description File picked up from the drop folder, with the outcome of its validation and import
+type DropFile struct {
+	ID       uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440050"`
+	FileName string    `json:"fileName" example:"scada-2025-09-30.csv"`
+	Size     int64     `json:"size" example:"48213"`
+	SHA256   string    `json:"sha256" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Status   string    `json:"status" example:"archived"`
+	// MovedTo is the path of the file in the archive or error folder
+	MovedTo     string     `json:"movedTo" example:"/var/lib/tadb/drop/archive/20251001T060000Z-scada-2025-09-30.csv"`
+	JobID       *uuid.UUID `json:"jobId,omitempty" example:"550e8400-e29b-41d4-a716-446655440040"`
+	Result      *Result    `json:"result,omitempty"`
+	Error       string     `json:"error,omitempty" example:"line 12: invalid date: value is required"`
+	ModifiedAt  time.Time  `json:"modifiedAt"`
+	ProcessedAt time.Time  `json:"processedAt"`
+}
+
+// DropFolder watches a folder for CSV files, validates each one, imports
+// it through the importer and moves it to the archive or error folder
+type DropFolder struct {
+	importer *Importer
+	cfg      *Config
+	now      func() time.Time
+
+	mu  sync.RWMutex
+	log []*DropFile
+}
+
+// NewDropFolder creates the drop, archive and error folders if needed and
+// loads the processing log; it returns a disabled watcher when no folder is configured
+func NewDropFolder(importer *Importer, cfg *Config) (*DropFolder, error) {
+	d := &DropFolder{importer: importer, cfg: cfg, now: time.Now}
+	if cfg.DropDir == "" {
+		return d, nil
+	}
+	for _, dir := range []string{cfg.DropDir, cfg.DropArchiveDir, cfg.DropErrorDir} {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create drop folder: %w", err)
+		}
+	}
+	if err := d.loadLog(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Enabled reports whether a drop folder is configured
+func (d *DropFolder) Enabled() bool {
+	return d.cfg.DropDir != ""
+}
+
+// List returns the processed files, most recent first, optionally only those with status
+func (d *DropFolder) List(status string, limit int) []*DropFile {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	list := []*DropFile{}
+	for i := len(d.log) - 1; i >= 0 && (limit <= 0 || len(list) < limit); i-- {
+		if status == "" || d.log[i].Status == status {
+			list = append(list, d.log[i])
+		}
+	}
+	return list
+}
+
+// Get returns a processed file by ID
+func (d *DropFolder) Get(id uuid.UUID) (*DropFile, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, f := range d.log {
+		if f.ID == id {
+			return f, nil
+		}
+	}
+	return nil, ErrDropFileNotFound
+}
+
+// Run processes the files dropped in the folder every DropInterval until
+// ctx is cancelled; it returns at once when no folder is configured
+func (d *DropFolder) Run(ctx context.Context) {
+	if !d.Enabled() {
+		return
+	}
+	ticker := time.NewTicker(d.cfg.DropInterval)
+	defer ticker.Stop()
+	for {
+		if err := d.Scan(ctx); err != nil {
+			utils.LogError("drop folder scan", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan processes the CSV files of the folder that have not been modified
+// for DropSettle, oldest first
+func (d *DropFolder) Scan(ctx context.Context) error {
+	entries, err := os.ReadDir(d.cfg.DropDir)
+	if err != nil {
+		return fmt.Errorf("failed to read drop folder: %w", err)
+	}
+	var ready []os.FileInfo
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || strings.HasPrefix(name, ".") || !strings.EqualFold(filepath.Ext(name), ".csv") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		// Files still being written are picked up on a later scan
+		if d.now().Sub(info.ModTime()) < d.cfg.DropSettle {
+			continue
+		}
+		ready = append(ready, info)
+	}
+	sort.Slice(ready, func(i, j int) bool { return ready[i].ModTime().Before(ready[j].ModTime()) })
+
+	for _, info := range ready {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f := d.process(ctx, info)
+		if err := d.record(f); err != nil {
+			utils.LogError("drop folder log", err)
+		}
+		utils.LogInfo(fmt.Sprintf("drop folder: %s %s", f.FileName, f.Status))
+	}
+	return nil
+}
+
+// process validates and imports one file and moves it out of the folder
+func (d *DropFolder) process(ctx context.Context, info os.FileInfo) *DropFile {
+	path := filepath.Join(d.cfg.DropDir, info.Name())
+	f := &DropFile{
+		ID:         uuid.New(),
+		FileName:   info.Name(),
+		Size:       info.Size(),
+		ModifiedAt: info.ModTime().UTC(),
+	}
+
+	sum, err := d.validate(path)
+	f.SHA256 = sum
+	if err != nil {
+		f.Status = DropStatusRejected
+		f.Error = err.Error()
+	} else {
+		d.importFile(ctx, path, f)
+	}
+
+	dir := d.cfg.DropArchiveDir
+	if f.Status != DropStatusArchived {
+		dir = d.cfg.DropErrorDir
+	}
+	f.ProcessedAt = d.now().UTC()
+	dest := filepath.Join(dir, f.ProcessedAt.Format("20060102T150405Z")+"-"+f.FileName)
+	if err := moveFile(path, dest); err != nil {
+		// Left in place the file would be imported again on the next scan
+		utils.LogError("drop folder: moving "+f.FileName, err)
+		if f.Error == "" {
+			f.Error = err.Error()
+		}
+	} else {
+		f.MovedTo = dest
+	}
+	return f
+}
+
+// validate parses the whole file without importing it and returns its
+// SHA-256; any row that does not parse rejects the file
+func (d *DropFolder) validate(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	parser := NewParser(io.TeeReader(file, hash), d.cfg, nil)
+	var rowErrs []string
+	for {
+		_, err := parser.Next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			if len(rowErrs) < d.cfg.MaxReportedErrors {
+				rowErrs = append(rowErrs, rowErr.Error())
+			}
+			continue
+		}
+		if err != nil {
+			return hex.EncodeToString(hash.Sum(nil)), err
+		}
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if parser.Rows() == 0 {
+		return sum, fmt.Errorf("%w: file has no data rows", ErrInvalidFile)
+	}
+	if len(rowErrs) > 0 {
+		return sum, fmt.Errorf("%w: %s", ErrInvalidFile, strings.Join(rowErrs, "; "))
+	}
+	return sum, nil
+}
+
+// importFile runs the file through an import job and sets the outcome on f
+func (d *DropFolder) importFile(ctx context.Context, path string, f *DropFile) {
+	file, err := os.Open(path)
+	if err != nil {
+		f.Status = DropStatusFailed
+		f.Error = err.Error()
+		return
+	}
+	defer file.Close()
+
+	job := d.importer.NewJob(f.Size)
+	d.importer.Jobs().SetMetadata(job.ID, "dropFile", f.FileName)
+	f.JobID = &job.ID
+	result, err := d.importer.Run(ctx, job.ID, file, nil)
+	f.Result = result
+	switch {
+	case err != nil:
+		f.Status = DropStatusFailed
+		f.Error = err.Error()
+	case result.RowsFailed > 0:
+		f.Status = DropStatusFailed
+		f.Error = fmt.Sprintf("%d rows failed to import; see job %s", result.RowsFailed, job.ID)
+	default:
+		f.Status = DropStatusArchived
+	}
+}
+
+// record appends f to the processing log
+func (d *DropFolder) record(f *DropFile) error {
+	d.mu.Lock()
+	d.log = append(d.log, f)
+	if len(d.log) > maxDropLog {
+		d.log = d.log[len(d.log)-maxDropLog:]
+	}
+	d.mu.Unlock()
+
+	line, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(filepath.Join(d.cfg.DropDir, dropLogFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open processing log: %w", err)
+	}
+	defer out.Close()
+	if _, err := out.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write processing log: %w", err)
+	}
+	return nil
+}
+
+// loadLog reads the most recent entries of the processing log
+func (d *DropFolder) loadLog() error {
+	in, err := os.Open(filepath.Join(d.cfg.DropDir, dropLogFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open processing log: %w", err)
+	}
+	defer in.Close()
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var f DropFile
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+			continue
+		}
+		d.log = append(d.log, &f)
+		if len(d.log) > maxDropLog {
+			d.log = d.log[1:]
+		}
+	}
+	return scanner.Err()
+}
+
+// moveFile renames src to dst, copying when they are on different file systems
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,246 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/jobs"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/provenance"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// JobTypeProductionImport identifies production import jobs
+const JobTypeProductionImport = "production_import"
+
+// Result summarizes a finished import
+// @Description Summary of a production import
+type Result struct {
+	RowsRead     int64 `json:"rowsRead" example:"1000"`
+	RowsImported int64 `json:"rowsImported" example:"997"`
+	RowsFailed   int64 `json:"rowsFailed" example:"3"`
+	// DeadLetters is the number of failed rows kept in GET /imports/dead-letters
+	DeadLetters int64      `json:"deadLetters" example:"3"`
+	BytesRead   int64      `json:"bytesRead" example:"48213"`
+	Errors      []RowError `json:"errors,omitempty"`
+}
+
+// Importer streams import files into the repository and reports progress to the jobs subsystem
+type Importer struct {
+	repo database.Repository
+	jobs *jobs.Manager
+	cfg  *Config
+}
+
+// NewImporter creates a new Importer instance
+func NewImporter(repo database.Repository, jobManager *jobs.Manager, cfg *Config) *Importer {
+	return &Importer{
+		repo: repo,
+		jobs: jobManager,
+		cfg:  cfg,
+	}
+}
+
+// Config returns the import configuration
+func (i *Importer) Config() *Config {
+	return i.cfg
+}
+
+// Jobs returns the job manager used to report progress
+func (i *Importer) Jobs() *jobs.Manager {
+	return i.jobs
+}
+
+// NewJob registers a pending import job
+func (i *Importer) NewJob(totalBytes int64) *jobs.Job {
+	return i.jobs.Create(JobTypeProductionImport, totalBytes)
+}
+
+// Run parses r row by row, inserting each production and updating the job as it goes.
+// mapping (may be nil) names the columns of the file. The returned error is non-nil
+// only when the import as a whole was aborted.
+func (i *Importer) Run(ctx context.Context, jobID uuid.UUID, r io.Reader, mapping *Mapping) (*Result, error) {
+	i.jobs.Start(jobID)
+
+	parser := NewParser(r, i.cfg, mapping)
+	result := &Result{}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return i.fail(jobID, parser, result, err)
+		}
+
+		row, err := parser.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var rowErr *RowError
+			if errors.As(err, &rowErr) {
+				i.recordError(ctx, jobID, parser, result, *rowErr)
+				i.reportProgress(jobID, parser, result)
+				continue
+			}
+			return i.fail(jobID, parser, result, err)
+		}
+
+		row.Request.Source = provenance.SourceImport
+		row.Request.SourceRef = jobID.String()
+		if _, err := i.repo.CreateProduction(ctx, &row.Request); err != nil {
+			i.recordError(ctx, jobID, parser, result, RowError{Line: row.Line, Message: err.Error()})
+		} else {
+			result.RowsImported++
+		}
+		i.reportProgress(jobID, parser, result)
+	}
+
+	result.RowsRead = parser.Rows()
+	result.BytesRead = parser.BytesRead()
+	i.jobs.ReportProgress(jobID, jobs.Progress{
+		RowsProcessed: result.RowsRead,
+		RowsFailed:    result.RowsFailed,
+		BytesRead:     result.BytesRead,
+	})
+	i.jobs.Complete(jobID, result)
+
+	utils.LogInfo("import job " + jobID.String() + " completed")
+	return result, nil
+}
+
+// RunInBackground runs the import in its own goroutine. The reader is closed
+// and done (if not nil) is called once the import finishes. The job keeps the
+// values of ctx (e.g. the request principal) but outlives its cancellation.
+func (i *Importer) RunInBackground(ctx context.Context, jobID uuid.UUID, r io.ReadCloser, mapping *Mapping, done func(err error)) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer r.Close()
+		_, err := i.Run(ctx, jobID, r, mapping)
+		if done != nil {
+			done(err)
+		}
+	}()
+}
+
+// Spool copies r to a temporary file of UploadDir, for an import to outlive
+// the request streaming it. The file is removed when the returned reader is
+// closed; files over MaxBytes fail with ErrMaxSizeExceeded.
+func (i *Importer) Spool(r io.Reader) (io.ReadCloser, int64, error) {
+	if err := os.MkdirAll(i.cfg.UploadDir, 0o750); err != nil {
+		return nil, 0, fmt.Errorf("failed to create upload dir: %w", err)
+	}
+	f, err := os.CreateTemp(i.cfg.UploadDir, "import-*.tmp")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create spool file: %w", err)
+	}
+	spool := &spoolFile{File: f}
+	src := r
+	if i.cfg.MaxBytes > 0 {
+		src = io.LimitReader(r, i.cfg.MaxBytes+1)
+	}
+	n, err := io.Copy(f, src)
+	if err == nil && i.cfg.MaxBytes > 0 && n > i.cfg.MaxBytes {
+		err = ErrMaxSizeExceeded
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		spool.Close()
+		return nil, 0, err
+	}
+	return spool, n, nil
+}
+
+// spoolFile is a spooled import file, removed once closed
+type spoolFile struct {
+	*os.File
+}
+
+func (f *spoolFile) Close() error {
+	err := f.File.Close()
+	if rmErr := os.Remove(f.Name()); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// recordError counts a failed row and keeps it, with the values the parser
+// read, as a dead letter to be corrected and resubmitted
+func (i *Importer) recordError(ctx context.Context, jobID uuid.UUID, parser *Parser, result *Result, rowErr RowError) {
+	result.RowsFailed++
+	if len(result.Errors) < i.cfg.MaxReportedErrors {
+		result.Errors = append(result.Errors, rowErr)
+	}
+
+	generatorID, date, productionMW := parser.Values()
+	err := i.repo.CreateDeadLetter(ctx, &models.DeadLetter{
+		JobID:        jobID,
+		Line:         rowErr.Line,
+		GeneratorID:  truncate(generatorID, maxDeadLetterValue),
+		Date:         truncate(date, maxDeadLetterValue),
+		ProductionMW: truncate(productionMW, maxDeadLetterValue),
+		Error:        rowErr.Message,
+	})
+	switch {
+	case err == nil:
+		result.DeadLetters++
+	case !errors.Is(err, database.ErrNotSupported):
+		utils.LogError("import job "+jobID.String()+" dead letter", err)
+	}
+}
+
+// Resubmit imports the values of a pending dead letter again and records
+// the outcome on it. rowErr is why the row was rejected again, e.g. an
+// unknown generator; err is set when the outcome could not be recorded.
+func (i *Importer) Resubmit(ctx context.Context, letter *models.DeadLetter) (updated *models.DeadLetter, rowErr error, err error) {
+	if letter.Status != models.DeadLetterPending {
+		return nil, nil, database.ErrDeadLetterResolved
+	}
+	req, rowErr := ParseValues(letter.GeneratorID, letter.Date, letter.ProductionMW)
+	if rowErr == nil {
+		req.Source = provenance.SourceImport
+		req.SourceRef = letter.JobID.String()
+		var p *models.Production
+		if p, rowErr = i.repo.CreateProduction(ctx, req); rowErr == nil {
+			updated, err = i.repo.RecordDeadLetterAttempt(ctx, letter.ID, &p.ID, "")
+			return updated, nil, err
+		}
+	}
+	updated, err = i.repo.RecordDeadLetterAttempt(ctx, letter.ID, nil, rowErr.Error())
+	return updated, rowErr, err
+}
+
+// maxDeadLetterValue is the length dead letter values are cut to
+const maxDeadLetterValue = 200
+
+// truncate cuts s to at most n runes
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+func (i *Importer) reportProgress(jobID uuid.UUID, parser *Parser, result *Result) {
+	if parser.Rows()%i.cfg.ProgressInterval != 0 {
+		return
+	}
+	i.jobs.ReportProgress(jobID, jobs.Progress{
+		RowsProcessed: parser.Rows(),
+		RowsFailed:    result.RowsFailed,
+		BytesRead:     parser.BytesRead(),
+	})
+}
+
+func (i *Importer) fail(jobID uuid.UUID, parser *Parser, result *Result, err error) (*Result, error) {
+	result.RowsRead = parser.Rows()
+	result.BytesRead = parser.BytesRead()
+	i.jobs.Fail(jobID, err, result)
+	utils.LogError("import job "+jobID.String(), err)
+	return result, err
+}
@@ -0,0 +1,46 @@
+package imports
+
+import (
+	"context"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+)
+
+// Mapping tells the parser where the columns of a file are when its header
+// does not use the usual names
+type Mapping struct {
+	// Columns maps the canonical column names to the header of the file;
+	// the ones left out are found through columnAliases
+	Columns map[string]string
+	// Delimiter replaces Config.Delimiter when not zero
+	Delimiter rune
+}
+
+// MappingFromProfile returns the mapping saved in an import profile
+func MappingFromProfile(p *models.ImportProfile) *Mapping {
+	m := &Mapping{Columns: make(map[string]string)}
+	for column, header := range map[string]string{
+		"generatorId":  p.Columns.GeneratorID,
+		"date":         p.Columns.Date,
+		"productionMw": p.Columns.ProductionMW,
+	} {
+		if header = strings.TrimSpace(header); header != "" {
+			m.Columns[column] = header
+		}
+	}
+	if p.Delimiter != "" {
+		m.Delimiter = []rune(p.Delimiter)[0]
+	}
+	return m
+}
+
+// Mapping loads the mapping of an import profile; sql.ErrNoRows when it does not exist
+func (i *Importer) Mapping(ctx context.Context, profileID uuid.UUID) (*Mapping, error) {
+	profile, err := i.repo.GetImportProfileByID(ctx, profileID)
+	if err != nil {
+		return nil, err
+	}
+	return MappingFromProfile(profile), nil
+}
@@ -0,0 +1,258 @@
+package imports
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrMaxRowsExceeded is returned when the file has more data rows than allowed
+	ErrMaxRowsExceeded = errors.New("import exceeds the maximum number of rows")
+	// ErrMaxSizeExceeded is returned when the file is bigger than allowed
+	ErrMaxSizeExceeded = errors.New("import exceeds the maximum file size")
+	// ErrInvalidFile is returned when the file cannot be read as CSV at all
+	ErrInvalidFile = errors.New("invalid import file")
+	// ErrMissingColumn is returned when the header does not contain a required column
+	ErrMissingColumn = errors.New("missing required column")
+)
+
+// RowError describes a single row that could not be parsed or imported
+// @Description Import error for a single CSV row
+type RowError struct {
+	Line    int64  `json:"line" example:"42"`
+	Message string `json:"error" example:"invalid productionMw: must be a number"`
+}
+
+// Row is a parsed data row of a production import file
+type Row struct {
+	Line    int64
+	Request models.CreateProductionRequest
+}
+
+// columnAliases maps accepted header names to the canonical column name
+var columnAliases = map[string]string{
+	"generatorid":   "generatorId",
+	"generator_id":  "generatorId",
+	"generator":     "generatorId",
+	"date":          "date",
+	"fecha":         "date",
+	"productionmw":  "productionMw",
+	"production_mw": "productionMw",
+	"production":    "productionMw",
+}
+
+// Parser reads production rows from a CSV stream one record at a time,
+// so memory usage does not depend on the file size
+type Parser struct {
+	cfg     *Config
+	counter *countingReader
+	reader  *csv.Reader
+	mapping *Mapping
+	columns map[string]int
+	rows    int64
+	// record is the last record read, nil when it could not be read
+	record []string
+}
+
+// NewParser creates a streaming CSV parser over r; mapping (may be nil)
+// names the columns of files that do not use the usual headers
+func NewParser(r io.Reader, cfg *Config, mapping *Mapping) *Parser {
+	counter := &countingReader{r: r, max: cfg.MaxBytes}
+	reader := csv.NewReader(counter)
+	reader.Comma = cfg.Delimiter
+	if mapping != nil && mapping.Delimiter != 0 {
+		reader.Comma = mapping.Delimiter
+	}
+	reader.ReuseRecord = true
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	return &Parser{
+		cfg:     cfg,
+		counter: counter,
+		reader:  reader,
+		mapping: mapping,
+	}
+}
+
+// BytesRead returns the number of bytes consumed from the underlying stream
+func (p *Parser) BytesRead() int64 {
+	return p.counter.n
+}
+
+// Rows returns the number of data rows read so far
+func (p *Parser) Rows() int64 {
+	return p.rows
+}
+
+// Next returns the next parsed row. It returns io.EOF when the stream is
+// exhausted, a *RowError for rows that can be skipped, and any other error
+// when the import must be aborted.
+func (p *Parser) Next() (*Row, error) {
+	if p.columns == nil {
+		if err := p.readHeader(); err != nil {
+			return nil, err
+		}
+	}
+
+	record, err := p.reader.Read()
+	p.record = record
+	if err != nil {
+		p.record = nil
+		if errors.Is(err, ErrMaxSizeExceeded) || err == io.EOF {
+			return nil, err
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if errors.Is(parseErr.Err, ErrMaxSizeExceeded) {
+				return nil, ErrMaxSizeExceeded
+			}
+			if err := p.count(); err != nil {
+				return nil, err
+			}
+			return nil, &RowError{Line: int64(parseErr.Line), Message: parseErr.Err.Error()}
+		}
+		return nil, fmt.Errorf("failed to read import file: %w", err)
+	}
+
+	if err := p.count(); err != nil {
+		return nil, err
+	}
+
+	line, _ := p.reader.FieldPos(0)
+	row := &Row{Line: int64(line)}
+	req, err := ParseValues(p.Values())
+	if err != nil {
+		return nil, &RowError{Line: row.Line, Message: err.Error()}
+	}
+	row.Request = *req
+	return row, nil
+}
+
+// count counts a data row read, valid or not, against MaxRows
+func (p *Parser) count() error {
+	p.rows++
+	if p.cfg.MaxRows > 0 && p.rows > p.cfg.MaxRows {
+		return ErrMaxRowsExceeded
+	}
+	return nil
+}
+
+// Values returns the generatorId, date and productionMw of the last record
+// read, as they are in the file; empty when it could not be read
+func (p *Parser) Values() (generatorID, date, productionMW string) {
+	if p.record == nil {
+		return "", "", ""
+	}
+	return p.field(p.record, "generatorId"), p.field(p.record, "date"), p.field(p.record, "productionMw")
+}
+
+// ParseValues parses the values of a production row
+func ParseValues(generatorID, date, productionMW string) (*models.CreateProductionRequest, error) {
+	genID, err := uuid.Parse(strings.TrimSpace(generatorID))
+	if err != nil {
+		return nil, errors.New("invalid generatorId: must be UUID")
+	}
+	date = strings.TrimSpace(date)
+	if date == "" {
+		return nil, errors.New("invalid date: value is required")
+	}
+	if _, err := utils.ParseDate(date); err != nil {
+		return nil, fmt.Errorf("invalid date: %w", err)
+	}
+	production, err := decimal.NewFromString(strings.TrimSpace(productionMW))
+	if err != nil || production.IsNegative() {
+		return nil, errors.New("invalid productionMw: must be a number greater than or equal to 0")
+	}
+	return &models.CreateProductionRequest{
+		GeneratorID:  genID,
+		Date:         date,
+		ProductionMW: production,
+	}, nil
+}
+
+func (p *Parser) readHeader() error {
+	header, err := p.reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return fmt.Errorf("%w: file is empty", ErrInvalidFile)
+		}
+		if errors.Is(err, ErrMaxSizeExceeded) {
+			return ErrMaxSizeExceeded
+		}
+		return fmt.Errorf("%w: failed to read header: %v", ErrInvalidFile, err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		key := headerKey(name)
+		if canonical, ok := columnAliases[key]; ok {
+			columns[canonical] = i
+		}
+	}
+	// Mapped headers take the place of the aliases, matched the same way
+	if p.mapping != nil {
+		for canonical, name := range p.mapping.Columns {
+			delete(columns, canonical)
+			for i, h := range header {
+				if headerKey(h) == headerKey(name) {
+					columns[canonical] = i
+					break
+				}
+			}
+			if _, ok := columns[canonical]; !ok {
+				return fmt.Errorf("%w: %s (mapped to %q)", ErrMissingColumn, canonical, name)
+			}
+		}
+	}
+	for _, required := range []string{"generatorId", "date", "productionMw"} {
+		if _, ok := columns[required]; !ok {
+			return fmt.Errorf("%w: %s", ErrMissingColumn, required)
+		}
+	}
+
+	p.columns = columns
+	return nil
+}
+
+// headerKey normalizes a header name for matching
+func headerKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+}
+
+func (p *Parser) field(record []string, column string) string {
+	idx := p.columns[column]
+	if idx >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[idx])
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// countingReader counts bytes read and enforces a maximum size
+type countingReader struct {
+	r   io.Reader
+	n   int64
+	max int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	if c.max > 0 && c.n > c.max {
+		return n, ErrMaxSizeExceeded
+	}
+	return n, err
+}
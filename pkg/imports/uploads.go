@@ -0,0 +1,358 @@
+package imports
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUploadNotFound is returned when an upload session does not exist
+	ErrUploadNotFound = errors.New("upload not found")
+	// ErrUploadIncomplete is returned when completing an upload with missing chunks
+	ErrUploadIncomplete = errors.New("upload is missing chunks")
+	// ErrUploadFinished is returned when modifying an upload that was already completed
+	ErrUploadFinished = errors.New("upload already completed")
+	// ErrChunkChecksum is returned when a chunk does not match its declared checksum
+	ErrChunkChecksum = errors.New("chunk checksum mismatch")
+	// ErrChunkOutOfRange is returned for chunk indexes outside the upload
+	ErrChunkOutOfRange = errors.New("chunk index out of range")
+	// ErrChunkSize is returned when a chunk is larger than declared
+	ErrChunkSize = errors.New("invalid chunk size")
+)
+
+// Upload status values
+const (
+	UploadStatusUploading = "uploading"
+	UploadStatusCompleted = "completed"
+)
+
+// Upload represents a resumable chunked upload session
+// @Description Chunked upload session; missingChunks tells the client what to (re)send
+type Upload struct {
+	ID             uuid.UUID  `json:"id" example:"550e8400-e29b-41d4-a716-446655440020"`
+	FileName       string     `json:"fileName" example:"productions-2025-09.csv"`
+	TotalSize      int64      `json:"totalSize" example:"104857600"`
+	ChunkSize      int64      `json:"chunkSize" example:"8388608"`
+	TotalChunks    int        `json:"totalChunks" example:"13"`
+	ReceivedChunks []int      `json:"receivedChunks"`
+	MissingChunks  []int      `json:"missingChunks"`
+	Status         string     `json:"status" example:"uploading"`
+	JobID          *uuid.UUID `json:"jobId,omitempty" example:"550e8400-e29b-41d4-a716-446655440040"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// UploadStore keeps chunked uploads on disk, one directory per upload, so
+// sessions survive dropped connections and server restarts
+type UploadStore struct {
+	dir          string
+	maxChunkSize int64
+	maxFileSize  int64
+	mu           sync.Mutex
+}
+
+// NewUploadStore creates the upload directory if needed and returns a store
+func NewUploadStore(cfg *Config) (*UploadStore, error) {
+	if err := os.MkdirAll(cfg.UploadDir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	return &UploadStore{
+		dir:          cfg.UploadDir,
+		maxChunkSize: cfg.MaxChunkBytes,
+		maxFileSize:  cfg.MaxBytes,
+	}, nil
+}
+
+// Init starts a new upload session
+func (s *UploadStore) Init(fileName string, totalSize, chunkSize int64) (*Upload, error) {
+	if totalSize <= 0 {
+		return nil, fmt.Errorf("%w: totalSize must be greater than 0", ErrChunkSize)
+	}
+	if s.maxFileSize > 0 && totalSize > s.maxFileSize {
+		return nil, ErrMaxSizeExceeded
+	}
+	if chunkSize <= 0 || chunkSize > s.maxChunkSize {
+		return nil, fmt.Errorf("%w: chunkSize must be between 1 and %d bytes", ErrChunkSize, s.maxChunkSize)
+	}
+
+	now := time.Now()
+	upload := &Upload{
+		ID:          uuid.New(),
+		FileName:    filepath.Base(fileName),
+		TotalSize:   totalSize,
+		ChunkSize:   chunkSize,
+		TotalChunks: int((totalSize + chunkSize - 1) / chunkSize),
+		Status:      UploadStatusUploading,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.uploadDir(upload.ID), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+	if err := s.writeManifest(upload); err != nil {
+		return nil, err
+	}
+	return s.withChunks(upload)
+}
+
+// Get returns an upload session with its received and missing chunks
+func (s *UploadStore) Get(id uuid.UUID) (*Upload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	upload, err := s.readManifest(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.withChunks(upload)
+}
+
+// PutChunk stores a chunk after verifying its SHA-256 checksum (hex encoded).
+// Re-sending a chunk that was already stored replaces it, so clients can
+// safely retry after a dropped connection.
+func (s *UploadStore) PutChunk(id uuid.UUID, index int, checksum string, r io.Reader) (*Upload, error) {
+	upload, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Status != UploadStatusUploading {
+		return nil, ErrUploadFinished
+	}
+	if index < 0 || index >= upload.TotalChunks {
+		return nil, ErrChunkOutOfRange
+	}
+
+	expected := upload.ChunkSize
+	if index == upload.TotalChunks-1 {
+		expected = upload.TotalSize - int64(index)*upload.ChunkSize
+	}
+
+	tmp, err := os.CreateTemp(s.uploadDir(id), "incoming-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to store chunk: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(r, expected+1))
+	closeErr := tmp.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to store chunk: %w", err)
+	}
+	if closeErr != nil {
+		return nil, fmt.Errorf("failed to store chunk: %w", closeErr)
+	}
+	if written != expected {
+		return nil, fmt.Errorf("%w: expected %d bytes, received %d", ErrChunkSize, expected, written)
+	}
+	if !strings.EqualFold(hex.EncodeToString(hash.Sum(nil)), strings.TrimSpace(checksum)) {
+		return nil, ErrChunkChecksum
+	}
+
+	// The upload may have been completed or deleted while the chunk was
+	// received; check again under the lock MarkCompleted takes, and update
+	// the manifest as it is now
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	upload, err = s.readManifest(id)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Status != UploadStatusUploading {
+		return nil, ErrUploadFinished
+	}
+	if err := os.Rename(tmp.Name(), s.chunkPath(id, index)); err != nil {
+		return nil, fmt.Errorf("failed to store chunk: %w", err)
+	}
+	upload.UpdatedAt = time.Now()
+	if err := s.writeManifest(upload); err != nil {
+		return nil, err
+	}
+	return s.withChunks(upload)
+}
+
+// Open returns a reader over the assembled file, streaming chunks in order
+func (s *UploadStore) Open(id uuid.UUID) (io.ReadCloser, error) {
+	upload, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(upload.MissingChunks) > 0 {
+		return nil, fmt.Errorf("%w: %v", ErrUploadIncomplete, upload.MissingChunks)
+	}
+	return &chunkReader{store: s, id: id, total: upload.TotalChunks}, nil
+}
+
+// MarkCompleted records the import job created from the upload
+func (s *UploadStore) MarkCompleted(id uuid.UUID, jobID uuid.UUID) (*Upload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	upload, err := s.readManifest(id)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Status == UploadStatusCompleted {
+		return nil, ErrUploadFinished
+	}
+	upload.Status = UploadStatusCompleted
+	upload.JobID = &jobID
+	upload.UpdatedAt = time.Now()
+	if err := s.writeManifest(upload); err != nil {
+		return nil, err
+	}
+	return s.withChunks(upload)
+}
+
+// RemoveChunks deletes the chunk files of an upload, keeping its manifest
+func (s *UploadStore) RemoveChunks(id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(s.uploadDir(id), "chunk-*"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes an upload session and all its chunks
+func (s *UploadStore) Delete(id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.readManifest(id); err != nil {
+		return err
+	}
+	return os.RemoveAll(s.uploadDir(id))
+}
+
+func (s *UploadStore) uploadDir(id uuid.UUID) string {
+	return filepath.Join(s.dir, id.String())
+}
+
+func (s *UploadStore) chunkPath(id uuid.UUID, index int) string {
+	return filepath.Join(s.uploadDir(id), fmt.Sprintf("chunk-%06d", index))
+}
+
+func (s *UploadStore) readManifest(id uuid.UUID) (*Upload, error) {
+	data, err := os.ReadFile(filepath.Join(s.uploadDir(id), "manifest.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	var upload Upload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, fmt.Errorf("failed to decode upload: %w", err)
+	}
+	return &upload, nil
+}
+
+func (s *UploadStore) writeManifest(upload *Upload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return fmt.Errorf("failed to encode upload: %w", err)
+	}
+	path := filepath.Join(s.uploadDir(upload.ID), "manifest.json")
+	if err := os.WriteFile(path+".tmp", data, 0o640); err != nil {
+		return fmt.Errorf("failed to write upload: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// withChunks fills the received and missing chunk lists from the files on disk
+func (s *UploadStore) withChunks(upload *Upload) (*Upload, error) {
+	files, err := filepath.Glob(filepath.Join(s.uploadDir(upload.ID), "chunk-[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	received := make(map[int]bool, len(files))
+	for _, f := range files {
+		var index int
+		if _, err := fmt.Sscanf(filepath.Base(f), "chunk-%06d", &index); err == nil {
+			received[index] = true
+		}
+	}
+
+	upload.ReceivedChunks = []int{}
+	upload.MissingChunks = []int{}
+	if upload.Status == UploadStatusCompleted {
+		return upload, nil
+	}
+	for i := 0; i < upload.TotalChunks; i++ {
+		if received[i] {
+			upload.ReceivedChunks = append(upload.ReceivedChunks, i)
+		} else {
+			upload.MissingChunks = append(upload.MissingChunks, i)
+		}
+	}
+	sort.Ints(upload.ReceivedChunks)
+	return upload, nil
+}
+
+// chunkReader reads the chunk files of an upload sequentially, keeping at most one open
+type chunkReader struct {
+	store   *UploadStore
+	id      uuid.UUID
+	total   int
+	next    int
+	current *os.File
+}
+
+func (r *chunkReader) Read(b []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if r.next >= r.total {
+				return 0, io.EOF
+			}
+			f, err := os.Open(r.store.chunkPath(r.id, r.next))
+			if err != nil {
+				return 0, err
+			}
+			r.current = f
+			r.next++
+		}
+
+		n, err := r.current.Read(b)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
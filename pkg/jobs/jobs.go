@@ -0,0 +1,199 @@
+package jobs
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status represents the lifecycle state of a job
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+// Job represents a long running background operation (imports, exports, ...)
+// @Description Background job with progress information
+type Job struct {
+	ID            uuid.UUID         `json:"id" example:"550e8400-e29b-41d4-a716-446655440010"`
+	Type          string            `json:"type" example:"production_import"`
+	Status        Status            `json:"status" example:"running"`
+	RowsProcessed int64             `json:"rowsProcessed" example:"15000"`
+	RowsFailed    int64             `json:"rowsFailed" example:"3"`
+	BytesRead     int64             `json:"bytesRead" example:"1048576"`
+	TotalBytes    int64             `json:"totalBytes,omitempty" example:"10485760"`
+	Percentage    float64           `json:"percentage,omitempty" example:"10.0"`
+	Error         string            `json:"error,omitempty" example:"row 42: invalid date"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	Result        interface{}       `json:"result,omitempty"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	UpdatedAt     time.Time         `json:"updatedAt"`
+	FinishedAt    *time.Time        `json:"finishedAt,omitempty"`
+}
+
+// Progress is a snapshot of work done, reported by job workers
+type Progress struct {
+	RowsProcessed int64
+	RowsFailed    int64
+	BytesRead     int64
+}
+
+// Manager keeps track of jobs in memory
+type Manager struct {
+	mu   sync.RWMutex
+	jobs map[uuid.UUID]*Job
+}
+
+// NewManager creates a new job manager
+func NewManager() *Manager {
+	return &Manager{
+		jobs: make(map[uuid.UUID]*Job),
+	}
+}
+
+// Create registers a new pending job
+func (m *Manager) Create(jobType string, totalBytes int64) *Job {
+	now := time.Now()
+	job := &Job{
+		ID:         uuid.New(),
+		Type:       jobType,
+		Status:     StatusPending,
+		TotalBytes: totalBytes,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	m.mu.Lock()
+	m.jobs[job.ID] = job
+	m.mu.Unlock()
+
+	return job.snapshot()
+}
+
+// Get returns a copy of the job with the given ID
+func (m *Manager) Get(id uuid.UUID) (*Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("job %s not found", id)
+	}
+	return job.snapshot(), nil
+}
+
+// List returns copies of all jobs, newest first
+func (m *Manager) List() []*Job {
+	m.mu.RLock()
+	list := make([]*Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		list = append(list, job.snapshot())
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	return list
+}
+
+// SetMetadata attaches a key/value pair to a job (e.g. the storage key of its input)
+func (m *Manager) SetMetadata(id uuid.UUID, key, value string) {
+	m.update(id, func(job *Job) {
+		if job.Metadata == nil {
+			job.Metadata = make(map[string]string)
+		}
+		job.Metadata[key] = value
+	})
+}
+
+// Start marks a job as running
+func (m *Manager) Start(id uuid.UUID) {
+	m.update(id, func(job *Job) {
+		job.Status = StatusRunning
+	})
+}
+
+// Claim atomically moves a pending job to running. It returns false if the
+// job does not exist or was already started, so only one worker processes it.
+func (m *Manager) Claim(id uuid.UUID) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok || job.Status != StatusPending {
+		return false
+	}
+	job.Status = StatusRunning
+	job.UpdatedAt = time.Now()
+	return true
+}
+
+// SetTotalBytes updates the expected size of the job input once it is known
+func (m *Manager) SetTotalBytes(id uuid.UUID, totalBytes int64) {
+	m.update(id, func(job *Job) {
+		job.TotalBytes = totalBytes
+	})
+}
+
+// ReportProgress updates the progress counters of a running job
+func (m *Manager) ReportProgress(id uuid.UUID, p Progress) {
+	m.update(id, func(job *Job) {
+		job.RowsProcessed = p.RowsProcessed
+		job.RowsFailed = p.RowsFailed
+		job.BytesRead = p.BytesRead
+		if job.TotalBytes > 0 {
+			job.Percentage = float64(p.BytesRead) / float64(job.TotalBytes) * 100
+		}
+	})
+}
+
+// Complete marks a job as completed and stores its result
+func (m *Manager) Complete(id uuid.UUID, result interface{}) {
+	m.update(id, func(job *Job) {
+		now := time.Now()
+		job.Status = StatusCompleted
+		job.Result = result
+		job.Percentage = 100
+		job.FinishedAt = &now
+	})
+}
+
+// Fail marks a job as failed with the given error
+func (m *Manager) Fail(id uuid.UUID, err error, result interface{}) {
+	m.update(id, func(job *Job) {
+		now := time.Now()
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		job.Result = result
+		job.FinishedAt = &now
+	})
+}
+
+func (m *Manager) update(id uuid.UUID, fn func(job *Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job, ok := m.jobs[id]; ok {
+		fn(job)
+		job.UpdatedAt = time.Now()
+	}
+}
+
+func (j *Job) snapshot() *Job {
+	cp := *j
+	if j.Metadata != nil {
+		cp.Metadata = make(map[string]string, len(j.Metadata))
+		for k, v := range j.Metadata {
+			cp.Metadata[k] = v
+		}
+	}
+	return &cp
+}
@@ -0,0 +1,312 @@
+// Package live pushes production records to dashboards as they are created
+// and updated. A Hub subscribed to the domain events receives the records
+// written and publishes them; every
+// subscriber, e.g. a WebSocket connection or an event stream, receives those
+// matching its filter, within its tenant. The latest events are kept, so a
+// subscriber reconnecting with the ID of the last event it saw gets those it
+// missed.
+package live
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/events"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// ErrTooManySubscribers is returned by Subscribe when the hub is full
+var ErrTooManySubscribers = errors.New("too many live subscribers")
+
+// subscriberBuffer is how many events a subscriber may fall behind before it
+// is dropped
+const subscriberBuffer = 256
+
+// Kinds of production events
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+)
+
+// Config represents the live feeds
+type Config struct {
+	// MaxSubscribers bounds the open feeds of the instance
+	MaxSubscribers int
+	// PingInterval is how often idle connections are pinged
+	PingInterval time.Duration
+	// ReplayBuffer is how many of the latest events are kept for
+	// subscribers resuming after a disconnect; 0 disables replay
+	ReplayBuffer int
+}
+
+// LoadConfig loads the live feed settings from environment variables
+func LoadConfig() *Config {
+	return &Config{
+		MaxSubscribers: utils.GetEnvAsInt("LIVE_MAX_SUBSCRIBERS", 1000),
+		PingInterval:   utils.GetEnvAsDuration("LIVE_PING_INTERVAL", 30*time.Second),
+		ReplayBuffer:   utils.GetEnvAsInt("LIVE_REPLAY_BUFFER", 1000),
+	}
+}
+
+// Filter selects the records of one generator, of the generators of one
+// type, or (both nil) all records
+type Filter struct {
+	GeneratorID *uuid.UUID
+	TypeID      *uuid.UUID
+}
+
+// Hub fans the published production events out to its subscribers
+type Hub struct {
+	cfg *Config
+	// typeOf returns the type of a generator, for subscribers filtering by type
+	typeOf func(ctx context.Context, generatorID uuid.UUID) (uuid.UUID, error)
+
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+	// seq is the ID of the latest event. It starts from the time the hub
+	// was created, so IDs keep increasing across restarts.
+	seq uint64
+	// replay holds the latest events, oldest first; evicted is the ID of
+	// the latest event that no longer is in it
+	replay  []replayed
+	evicted uint64
+}
+
+// replayed is an event kept for replay, with the tenant it belongs to
+type replayed struct {
+	id     uint64
+	tenant string
+	event  *models.ProductionEvent
+}
+
+// NewHub creates a new Hub; typeOf resolves the type of a generator
+func NewHub(cfg *Config, typeOf func(ctx context.Context, generatorID uuid.UUID) (uuid.UUID, error)) *Hub {
+	seq := uint64(time.Now().UnixMilli()) * 1000
+	return &Hub{cfg: cfg, typeOf: typeOf, subs: map[*Subscription]struct{}{}, seq: seq, evicted: seq}
+}
+
+// Subscription receives the events matching its filter until it is closed,
+// or dropped by the hub for falling behind
+type Subscription struct {
+	hub    *Hub
+	tenant string
+	filter Filter
+	events chan *models.ProductionEvent
+}
+
+// Subscribe starts receiving the events of the tenant of ctx matching filter
+func (h *Hub) Subscribe(ctx context.Context, filter Filter) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.subscribe(ctx, filter)
+}
+
+// subscribe adds a subscriber; the caller holds mu
+func (h *Hub) subscribe(ctx context.Context, filter Filter) (*Subscription, error) {
+	if len(h.subs) >= h.cfg.MaxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+	s := &Subscription{
+		hub:    h,
+		tenant: auth.TenantFrom(ctx),
+		filter: filter,
+		events: make(chan *models.ProductionEvent, subscriberBuffer),
+	}
+	h.subs[s] = struct{}{}
+	return s, nil
+}
+
+// Backlog is what a resuming subscriber missed
+type Backlog struct {
+	// Events are the kept events after the last one seen, oldest first
+	Events []*models.ProductionEvent
+	// Complete is false when some of the events after the last one seen
+	// are no longer kept, or that event is unknown to this hub; the
+	// subscriber should then re-read the records
+	Complete bool
+}
+
+// SubscribeAfter is Subscribe for a subscriber that last saw the event with
+// ID lastEventID: it also returns the kept events after that one matching
+// filter, which come before those of the subscription
+func (h *Hub) SubscribeAfter(ctx context.Context, filter Filter, lastEventID string) (*Subscription, *Backlog, error) {
+	h.mu.Lock()
+	s, err := h.subscribe(ctx, filter)
+	if err != nil {
+		h.mu.Unlock()
+		return nil, nil, err
+	}
+	last, err := strconv.ParseUint(lastEventID, 10, 64)
+	backlog := &Backlog{Complete: err == nil && last >= h.evicted && last <= h.seq}
+	var candidates []*models.ProductionEvent
+	for _, r := range h.replay {
+		if r.id > last && r.tenant == s.tenant {
+			candidates = append(candidates, r.event)
+		}
+	}
+	h.mu.Unlock()
+
+	var types map[uuid.UUID]uuid.UUID
+	if filter.TypeID != nil {
+		records := make([]*models.Production, len(candidates))
+		for i, e := range candidates {
+			records[i] = e.Production
+		}
+		types = h.types(ctx, records)
+	}
+	for _, e := range candidates {
+		if s.matches(e.Production, types) {
+			backlog.Events = append(backlog.Events, e)
+		}
+	}
+	return s, backlog, nil
+}
+
+// Events delivers the events; it is closed when the subscriber fell too far
+// behind and was dropped
+func (s *Subscription) Events() <-chan *models.ProductionEvent {
+	return s.events
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.drop(s)
+}
+
+// drop removes s and closes its channel; the caller holds mu
+func (h *Hub) drop(s *Subscription) {
+	if _, ok := h.subs[s]; ok {
+		delete(h.subs, s)
+		close(s.events)
+	}
+}
+
+// OnEvents subscribes the hub to the domain events: the production records
+// created and updated are published, those of one change together
+func (h *Hub) OnEvents(ctx context.Context, batch []events.Event) {
+	var kind string
+	var records []*models.Production
+	flush := func() {
+		if len(records) > 0 {
+			h.Publish(ctx, kind, records...)
+		}
+		records = nil
+	}
+	for _, e := range batch {
+		var k string
+		var p *models.Production
+		switch e := e.(type) {
+		case events.ProductionCreated:
+			k, p = EventCreated, e.Production
+		case events.ProductionUpdated:
+			k, p = EventUpdated, e.Production
+		default:
+			continue
+		}
+		if k != kind {
+			flush()
+			kind = k
+		}
+		records = append(records, p)
+	}
+	flush()
+}
+
+// Publish sends records, created or updated as kind says, to the
+// subscribers of the tenant of ctx and keeps them for replay. It does not
+// block: subscribers whose buffer is full are dropped.
+func (h *Hub) Publish(ctx context.Context, kind string, records ...*models.Production) {
+	tenant := auth.TenantFrom(ctx)
+	now := time.Now().UTC()
+	events := make([]*models.ProductionEvent, len(records))
+
+	// The events get their IDs and are kept together with taking the
+	// subscribers, so those subscribing later find them in their backlog
+	h.mu.Lock()
+	for i, p := range records {
+		h.seq++
+		events[i] = &models.ProductionEvent{ID: strconv.FormatUint(h.seq, 10), Type: kind, Production: p, OccurredAt: now}
+		h.keep(replayed{id: h.seq, tenant: tenant, event: events[i]})
+	}
+	var subs []*Subscription
+	byType := false
+	for s := range h.subs {
+		if s.tenant == tenant {
+			subs = append(subs, s)
+			byType = byType || s.filter.TypeID != nil
+		}
+	}
+	h.mu.Unlock()
+	if len(subs) == 0 {
+		return
+	}
+
+	// Generator types are only looked up when someone filters by type
+	var types map[uuid.UUID]uuid.UUID
+	if byType {
+		types = h.types(ctx, records)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, event := range events {
+		for _, s := range subs {
+			if _, ok := h.subs[s]; !ok || !s.matches(event.Production, types) {
+				continue
+			}
+			select {
+			case s.events <- event:
+			default:
+				h.drop(s)
+			}
+		}
+	}
+}
+
+// keep adds r to the replay buffer, evicting the oldest event when it is
+// full; the caller holds mu
+func (h *Hub) keep(r replayed) {
+	if h.cfg.ReplayBuffer <= 0 {
+		h.evicted = r.id
+		return
+	}
+	if len(h.replay) >= h.cfg.ReplayBuffer {
+		h.evicted = h.replay[0].id
+		h.replay = h.replay[1:]
+	}
+	h.replay = append(h.replay, r)
+}
+
+// types looks up the type of the generators of records, once per generator
+func (h *Hub) types(ctx context.Context, records []*models.Production) map[uuid.UUID]uuid.UUID {
+	types := map[uuid.UUID]uuid.UUID{}
+	for _, p := range records {
+		if _, ok := types[p.GeneratorID]; ok {
+			continue
+		}
+		typeID, err := h.typeOf(ctx, p.GeneratorID)
+		if err != nil {
+			utils.LogError("live: generator type", err)
+		}
+		types[p.GeneratorID] = typeID
+	}
+	return types
+}
+
+func (s *Subscription) matches(p *models.Production, types map[uuid.UUID]uuid.UUID) bool {
+	if s.filter.GeneratorID != nil && *s.filter.GeneratorID != p.GeneratorID {
+		return false
+	}
+	if s.filter.TypeID != nil && *s.filter.TypeID != types[p.GeneratorID] {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,313 @@
+package live
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotWebSocket is returned by Upgrade for requests that are not a
+// WebSocket handshake
+var ErrNotWebSocket = errors.New("not a WebSocket handshake")
+
+// websocketGUID is appended to the key of the client to compute the accept
+// key (RFC 6455, section 1.3)
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes and close codes
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+
+	CloseNormal        = 1000
+	CloseGoingAway     = 1001
+	CloseProtocolError = 1002
+	CloseTooBig        = 1009
+	CloseTryAgainLater = 1013
+)
+
+// CloseError is returned by ReadMessage once the other end closed the connection
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: closed with code %d %s", e.Code, e.Reason)
+}
+
+// Bounds of the messages read: clients only send control frames to a feed,
+// while the feed sends production records
+const (
+	maxServerRead = 4 << 10
+	maxClientRead = 1 << 20
+)
+
+// writeTimeout bounds every frame written
+const writeTimeout = 10 * time.Second
+
+// Conn is one end of a WebSocket connection. Writes may come from several
+// goroutines; reads from one.
+type Conn struct {
+	rwc io.ReadWriteCloser
+	r   *bufio.Reader
+	// client connections mask their frames and read unmasked ones
+	client  bool
+	maxRead int
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// IsWebSocket reports whether r asks to upgrade to a WebSocket
+func IsWebSocket(r *http.Request) bool {
+	return headerHasToken(r.Header, "Connection", "upgrade") && headerHasToken(r.Header, "Upgrade", "websocket")
+}
+
+// Upgrade completes the WebSocket handshake of r and takes over its connection
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet || !IsWebSocket(r) {
+		return nil, ErrNotWebSocket
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, fmt.Errorf("%w: Sec-WebSocket-Version must be 13", ErrNotWebSocket)
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, fmt.Errorf("%w: Sec-WebSocket-Key is missing", ErrNotWebSocket)
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("the connection cannot be taken over")
+	}
+	nc, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// Clear the deadlines of the HTTP server; the feed lives on
+	_ = nc.SetDeadline(time.Time{})
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + AcceptKey(key) + "\r\n\r\n"
+	_ = nc.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := io.WriteString(nc, response); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return &Conn{rwc: nc, r: rw.Reader, maxRead: maxServerRead}, nil
+}
+
+// SetHandshake adds the headers of the opening handshake to a client request
+// and returns its key, to check the response with AcceptKey
+func SetHandshake(h http.Header) string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	key := base64.StdEncoding.EncodeToString(b)
+	h.Set("Connection", "Upgrade")
+	h.Set("Upgrade", "websocket")
+	h.Set("Sec-WebSocket-Version", "13")
+	h.Set("Sec-WebSocket-Key", key)
+	return key
+}
+
+// AcceptKey returns the Sec-WebSocket-Accept answering key
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// NewClientConn is the client end of a connection, on the body of the 101
+// response to the handshake
+func NewClientConn(rwc io.ReadWriteCloser) *Conn {
+	return &Conn{rwc: rwc, r: bufio.NewReader(rwc), client: true, maxRead: maxClientRead}
+}
+
+// headerHasToken reports whether the comma-separated header name has token
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteJSON sends v as a text message
+func (c *Conn) WriteJSON(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.write(opText, b)
+}
+
+// Ping sends a ping; the other end answers with a pong, which keeps proxies
+// from closing an idle connection
+func (c *Conn) Ping() error {
+	return c.write(opPing, nil)
+}
+
+// Close sends a close frame with code and reason and closes the connection
+func (c *Conn) Close(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	_ = c.write(opClose, payload)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return c.rwc.Close()
+}
+
+// write sends one unfragmented frame, masked when c is a client
+func (c *Conn) write(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	frame := make([]byte, 2, 14+len(payload))
+	frame[0] = 0x80 | opcode
+	switch n := len(payload); {
+	case n < 126:
+		frame[1] = byte(n)
+	case n <= 0xFFFF:
+		frame[1] = 126
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame[1] = 127
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if c.client {
+		frame[1] |= 0x80
+		var mask [4]byte
+		_, _ = rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := range payload {
+			frame[start+i] ^= mask[i%4]
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	if d, ok := c.rwc.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		_ = d.SetWriteDeadline(time.Now().Add(writeTimeout))
+	}
+	_, err := c.rwc.Write(frame)
+	return err
+}
+
+// ReadMessage returns the next text or binary message, answering pings on
+// the way. It returns a *CloseError once the other end closed the connection.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var message []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case opPing:
+			if err := c.write(opPong, payload); err != nil {
+				return nil, err
+			}
+		case opPong:
+		case opClose:
+			// Echo the code of the other end, as the closing handshake asks
+			closeErr := &CloseError{Code: CloseNormal}
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Reason = string(payload[2:])
+			}
+			c.Close(closeErr.Code, "")
+			return nil, closeErr
+		case opText, opBinary, opContinuation:
+			if len(message)+len(payload) > c.maxRead {
+				c.Close(CloseTooBig, "message too big")
+				return nil, errors.New("websocket: message too big")
+			}
+			message = append(message, payload...)
+			if fin {
+				return message, nil
+			}
+		}
+	}
+}
+
+// Drain reads until the other end closes the connection, discarding its
+// messages. It returns the reason the connection ended.
+func (c *Conn) Drain() error {
+	for {
+		if _, err := c.ReadMessage(); err != nil {
+			return err
+		}
+	}
+}
+
+// readFrame reads one frame; frames of clients are masked, those of servers not
+func (c *Conn) readFrame() (bool, byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.r, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, opcode, masked := head[0]&0x80 != 0, head[0]&0x0F, head[1]&0x80 != 0
+	if masked == c.client {
+		c.Close(CloseProtocolError, "invalid masking")
+		return false, 0, nil, errors.New("websocket: invalid masking")
+	}
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > uint64(c.maxRead) {
+		c.Close(CloseTooBig, "message too big")
+		return false, 0, nil, errors.New("websocket: message too big")
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.r, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
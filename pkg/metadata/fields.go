@@ -0,0 +1,268 @@
+// Package metadata is the data dictionary of the API resources. Field names,
+// types, examples and validation rules are read from the struct tags of the
+// models, so they cannot drift from what the API accepts; units, labels and
+// descriptions come from the registry below.
+package metadata
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Field describes one JSON field of a resource
+// @Description Field of an API resource; constraints are the validation rules of the create request
+type Field struct {
+	Name        string            `json:"name" example:"capacity"`
+	Type        string            `json:"type" example:"number"`
+	Format      string            `json:"format,omitempty" example:"decimal"`
+	Unit        string            `json:"unit,omitempty" example:"MW"`
+	Label       string            `json:"label" example:"Capacity"`
+	LabelEs     string            `json:"labelEs" example:"Capacidad"`
+	Description string            `json:"description,omitempty" example:"Installed capacity of the generator"`
+	Required    bool              `json:"required" example:"true"`
+	ReadOnly    bool              `json:"readOnly" example:"false"`
+	Nullable    bool              `json:"nullable" example:"false"`
+	Constraints map[string]string `json:"constraints,omitempty"`
+	Enum        []string          `json:"enum,omitempty"`
+	Example     string            `json:"example,omitempty" example:"100.5"`
+}
+
+// Resource is a resource of the API with its fields in declaration order
+// @Description API resource and the fields of its representation
+type Resource struct {
+	Name    string  `json:"name" example:"generator"`
+	Label   string  `json:"label" example:"Generator"`
+	LabelEs string  `json:"labelEs" example:"Generador"`
+	Fields  []Field `json:"fields"`
+}
+
+// entry is the registry metadata of a field
+type entry struct {
+	label, labelEs, unit, format, description string
+}
+
+// resource pairs the representation of a resource with its create request,
+// which carries the validation rules; fields missing from it are read-only
+type resource struct {
+	label, labelEs string
+	model, create  any
+	fields         map[string]entry
+}
+
+var (
+	created = entry{label: "Created at", labelEs: "Fecha de creación", description: "When the record was created"}
+	updated = entry{label: "Updated at", labelEs: "Fecha de actualización", description: "When the record was last changed"}
+)
+
+var resources = map[string]resource{
+	"type": {
+		label: "Generator type", labelEs: "Tipo de generador",
+		model: models.Type{}, create: models.CreateTypeRequest{},
+		fields: map[string]entry{
+			"id":             {label: "ID", labelEs: "ID"},
+			"name":           {label: "Name", labelEs: "Nombre", description: "Unique name of the type"},
+			"description":    {label: "Description", labelEs: "Descripción"},
+			"isRenewable":    {label: "Renewable", labelEs: "Renovable", description: "Whether the type is a renewable source"},
+			"technologyCode": {label: "Technology code", labelEs: "Código de tecnología", description: "Canonical technology (GET /catalog/technologies); inferred from the name when empty"},
+			"createdAt":      created,
+			"updatedAt":      updated,
+		},
+	},
+	"generator": {
+		label: "Generator", labelEs: "Generador",
+		model: models.Generator{}, create: models.CreateGeneratorRequest{},
+		fields: map[string]entry{
+			"id":              {label: "ID", labelEs: "ID"},
+			"typeId":          {label: "Type", labelEs: "Tipo", description: "ID of the generator type"},
+			"typeName":        {label: "Type name", labelEs: "Nombre del tipo"},
+			"typeDescription": {label: "Type description", labelEs: "Descripción del tipo"},
+			"isRenewable":     {label: "Renewable", labelEs: "Renovable", description: "Whether the generator type is renewable"},
+			"capacity":        {label: "Capacity", labelEs: "Capacidad", unit: "MW", description: "Installed capacity of the generator"},
+			"operatorId":      {label: "Operator", labelEs: "Operador", description: "ID of the company operating the generator"},
+			"operatorName":    {label: "Operator name", labelEs: "Nombre del operador"},
+			"createdAt":       created,
+			"updatedAt":       updated,
+		},
+	},
+	"operator": {
+		label: "Operator", labelEs: "Operador",
+		model: models.Operator{}, create: models.CreateOperatorRequest{},
+		fields: map[string]entry{
+			"id":        {label: "ID", labelEs: "ID"},
+			"name":      {label: "Name", labelEs: "Nombre", description: "Unique name of the company"},
+			"country":   {label: "Country", labelEs: "País", description: "ISO 3166-1 alpha-2 country code"},
+			"createdAt": created,
+			"updatedAt": updated,
+		},
+	},
+	"production": {
+		label: "Production record", labelEs: "Registro de producción",
+		model: models.Production{}, create: models.CreateProductionRequest{},
+		fields: map[string]entry{
+			"id":                {label: "ID", labelEs: "ID"},
+			"generatorId":       {label: "Generator", labelEs: "Generador", description: "ID of the generator"},
+			"generatorCapacity": {label: "Generator capacity", labelEs: "Capacidad del generador", unit: "MW"},
+			"typeName":          {label: "Type name", labelEs: "Nombre del tipo"},
+			"isRenewable":       {label: "Renewable", labelEs: "Renovable"},
+			"date":              {label: "Date", labelEs: "Fecha", format: "date", description: "Day of the production (YYYY-MM-DD), one record per generator and day"},
+			"productionMw":      {label: "Production", labelEs: "Producción", unit: "MW", description: "Energy produced by the generator on the day"},
+			"source":            {label: "Source", labelEs: "Origen", description: "How the record entered the system; imports are set by the importer"},
+			"sourceRef":         {label: "Source reference", labelEs: "Referencia de origen", description: "Import job, bulletin or ticket the value comes from"},
+			"signature":         {label: "Signature", labelEs: "Firma", description: "HMAC-SHA256 of the record when provenance signing is enabled"},
+			"signatureValid":    {label: "Valid signature", labelEs: "Firma válida", description: "Result of verifying the signature, when requested"},
+			"capacityFactor":    {label: "Capacity factor", labelEs: "Factor de capacidad", unit: "%", description: "Production as a percentage of the generator capacity, when requested with compute"},
+			"utilization":       {label: "Utilization", labelEs: "Utilización", unit: "%", description: "Production as a percentage of the highest production of the generator, when requested with compute"},
+			"createdAt":         created,
+			"updatedAt":         updated,
+		},
+	},
+}
+
+// Names returns the resource names, sorted
+func Names() []string {
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// All returns the dictionary of every resource, sorted by name
+func All() []Resource {
+	list := make([]Resource, 0, len(resources))
+	for _, name := range Names() {
+		r, _ := ByName(name)
+		list = append(list, *r)
+	}
+	return list
+}
+
+// ByName returns the dictionary of one resource
+func ByName(name string) (*Resource, bool) {
+	def, ok := resources[name]
+	if !ok {
+		return nil, false
+	}
+	r := &Resource{Name: name, Label: def.label, LabelEs: def.labelEs, Fields: []Field{}}
+
+	rules := map[string]reflect.StructField{}
+	create := reflect.TypeOf(def.create)
+	for i := 0; i < create.NumField(); i++ {
+		if field := jsonName(create.Field(i)); field != "" {
+			rules[field] = create.Field(i)
+		}
+	}
+
+	model := reflect.TypeOf(def.model)
+	for i := 0; i < model.NumField(); i++ {
+		sf := model.Field(i)
+		field := jsonName(sf)
+		if field == "" {
+			continue
+		}
+		e := def.fields[field]
+		f := Field{
+			Name:        field,
+			Unit:        e.unit,
+			Label:       e.label,
+			LabelEs:     e.labelEs,
+			Description: e.description,
+			Nullable:    sf.Type.Kind() == reflect.Pointer,
+			Example:     sf.Tag.Get("example"),
+		}
+		if f.Label == "" {
+			f.Label, f.LabelEs = field, field
+		}
+		f.Type, f.Format = jsonType(sf.Type)
+		if e.format != "" {
+			f.Format = e.format
+		}
+		if rule, ok := rules[field]; ok {
+			applyBinding(&f, rule.Tag.Get("binding"))
+		} else {
+			f.ReadOnly = true
+		}
+		r.Fields = append(r.Fields, f)
+	}
+	return r, true
+}
+
+// jsonName is the JSON name of a struct field, empty for skipped fields
+func jsonName(sf reflect.StructField) string {
+	if !sf.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return sf.Name
+	}
+	return name
+}
+
+var (
+	uuidType    = reflect.TypeOf(uuid.UUID{})
+	decimalType = reflect.TypeOf(decimal.Decimal{})
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// jsonType maps a Go type to its JSON Schema type and format
+func jsonType(t reflect.Type) (string, string) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case uuidType:
+		return "string", "uuid"
+	case decimalType:
+		return "number", "decimal"
+	case timeType:
+		return "string", "date-time"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean", ""
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", ""
+	case reflect.Float32, reflect.Float64:
+		return "number", ""
+	case reflect.Slice, reflect.Array:
+		return "array", ""
+	case reflect.Map, reflect.Struct:
+		return "object", ""
+	}
+	return "string", ""
+}
+
+// applyBinding turns validator rules (required,max=20,oneof=a b) into the
+// required flag, the enum and the remaining constraints of f
+func applyBinding(f *Field, binding string) {
+	if binding == "" {
+		return
+	}
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			f.Required = true
+		case "omitempty", "dive":
+		case "oneof":
+			f.Enum = strings.Fields(value)
+		default:
+			if f.Constraints == nil {
+				f.Constraints = map[string]string{}
+			}
+			f.Constraints[key] = value
+		}
+	}
+}
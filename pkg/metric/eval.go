@@ -0,0 +1,129 @@
+package metric
+
+import "github.com/shopspring/decimal"
+
+// divisionPrecision is the number of decimals kept by divisions and averages
+const divisionPrecision = 16
+
+// Row is one record an expression is evaluated over, by field name
+type Row map[string]decimal.Decimal
+
+// Eval evaluates the expression over the records of one group, with the
+// semantics of SQL(): nil stands for NULL, which aggregates other than count
+// yield over no records and divisions yield when the divisor is zero
+func (e *Expr) Eval(rows []Row) *decimal.Decimal {
+	return eval(e.root, rows)
+}
+
+// eval evaluates a node outside aggregates, where fields cannot appear
+func eval(n node, rows []Row) *decimal.Decimal {
+	switch n := n.(type) {
+	case number:
+		v := n.value
+		return &v
+	case call:
+		return aggregate(n, rows)
+	case unary:
+		v := eval(n.operand, rows)
+		if v == nil {
+			return nil
+		}
+		neg := v.Neg()
+		return &neg
+	case binary:
+		return arithmetic(n.op, eval(n.left, rows), eval(n.right, rows))
+	}
+	return nil
+}
+
+// aggregate applies an aggregate call to rows, skipping NULL arguments like SQL
+func aggregate(n call, rows []Row) *decimal.Decimal {
+	if n.arg == nil {
+		count := decimal.NewFromInt(int64(len(rows)))
+		return &count
+	}
+	var (
+		values   []decimal.Decimal
+		sum      decimal.Decimal
+		min, max decimal.Decimal
+	)
+	for _, row := range rows {
+		v := evalRow(n.arg, row)
+		if v == nil {
+			continue
+		}
+		if len(values) == 0 || v.LessThan(min) {
+			min = *v
+		}
+		if len(values) == 0 || v.GreaterThan(max) {
+			max = *v
+		}
+		sum = sum.Add(*v)
+		values = append(values, *v)
+	}
+	if n.fn == "count" {
+		count := decimal.NewFromInt(int64(len(values)))
+		return &count
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	var result decimal.Decimal
+	switch n.fn {
+	case "sum":
+		result = sum
+	case "avg":
+		result = sum.DivRound(decimal.NewFromInt(int64(len(values))), divisionPrecision)
+	case "min":
+		result = min
+	case "max":
+		result = max
+	}
+	return &result
+}
+
+// evalRow evaluates the argument of an aggregate over a single record
+func evalRow(n node, row Row) *decimal.Decimal {
+	switch n := n.(type) {
+	case number:
+		v := n.value
+		return &v
+	case field:
+		v, ok := row[n.name]
+		if !ok {
+			return nil
+		}
+		return &v
+	case unary:
+		v := evalRow(n.operand, row)
+		if v == nil {
+			return nil
+		}
+		neg := v.Neg()
+		return &neg
+	case binary:
+		return arithmetic(n.op, evalRow(n.left, row), evalRow(n.right, row))
+	}
+	return nil
+}
+
+func arithmetic(op byte, left, right *decimal.Decimal) *decimal.Decimal {
+	if left == nil || right == nil {
+		return nil
+	}
+	var v decimal.Decimal
+	switch op {
+	case '+':
+		v = left.Add(*right)
+	case '-':
+		v = left.Sub(*right)
+	case '*':
+		v = left.Mul(*right)
+	case '/':
+		if right.IsZero() {
+			return nil
+		}
+		v = left.DivRound(*right, divisionPrecision)
+	}
+	return &v
+}
@@ -0,0 +1,395 @@
+// Package metric parses the aggregation expressions analysts can pass to the
+// analytics endpoints, e.g. sum(productionMw)/sum(capacity), and compiles them
+// to SQL. Only whitelisted fields and functions are accepted and literals are
+// re-rendered from their parsed value, so no input text reaches the query.
+package metric
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalid is returned for expressions that do not parse or use names outside the whitelist
+var ErrInvalid = errors.New("invalid metric")
+
+const (
+	// MaxLength is the longest expression accepted, in bytes
+	MaxLength = 200
+	// maxNodes bounds the size of the parsed tree
+	maxNodes = 64
+)
+
+// Fields maps the field names usable in expressions to the column they read
+var Fields = map[string]string{
+	"productionMw": "production_mw",
+	"capacity":     "capacity",
+}
+
+// functions maps the aggregate names to their SQL function
+var functions = map[string]string{
+	"sum":   "SUM",
+	"avg":   "AVG",
+	"min":   "MIN",
+	"max":   "MAX",
+	"count": "COUNT",
+}
+
+// FieldNames returns the field names usable in expressions, sorted
+func FieldNames() []string {
+	return sortedNames(Fields)
+}
+
+// FunctionNames returns the aggregate names usable in expressions, sorted
+func FunctionNames() []string {
+	return sortedNames(functions)
+}
+
+func sortedNames(m map[string]string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Expr is a parsed metric expression
+type Expr struct {
+	root node
+}
+
+// Parse parses and validates an expression. Every field must appear inside an
+// aggregate and aggregates cannot be nested, so the result is always a valid
+// value for a GROUP BY query.
+func Parse(src string) (*Expr, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, fmt.Errorf("%w: expression is empty", ErrInvalid)
+	}
+	if len(src) > MaxLength {
+		return nil, fmt.Errorf("%w: expression is longer than %d characters", ErrInvalid, MaxLength)
+	}
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	if err := validate(root, false); err != nil {
+		return nil, err
+	}
+	return &Expr{root: root}, nil
+}
+
+// SQL renders the expression over the columns of Fields. Divisions yield NULL
+// instead of failing when the divisor is zero.
+func (e *Expr) SQL() string {
+	return e.root.sql()
+}
+
+// String renders the expression in canonical form
+func (e *Expr) String() string {
+	return e.root.String()
+}
+
+// ===================== Tree =====================
+
+type node interface {
+	sql() string
+	String() string
+}
+
+type number struct{ value decimal.Decimal }
+
+type field struct{ name string }
+
+type call struct {
+	fn  string
+	arg node // nil for count()
+}
+
+type unary struct{ operand node }
+
+type binary struct {
+	op          byte
+	left, right node
+}
+
+func (n number) sql() string    { return n.value.String() + "::numeric" }
+func (n number) String() string { return n.value.String() }
+
+func (n field) sql() string    { return Fields[n.name] }
+func (n field) String() string { return n.name }
+
+func (n call) sql() string {
+	if n.arg == nil {
+		return "COUNT(*)::numeric"
+	}
+	if n.fn == "count" {
+		return "COUNT(" + n.arg.sql() + ")::numeric"
+	}
+	return functions[n.fn] + "(" + n.arg.sql() + ")"
+}
+
+func (n call) String() string {
+	if n.arg == nil {
+		return n.fn + "()"
+	}
+	return n.fn + "(" + n.arg.String() + ")"
+}
+
+func (n unary) sql() string { return "(-" + n.operand.sql() + ")" }
+func (n unary) String() string {
+	if _, ok := n.operand.(binary); ok {
+		return "-(" + n.operand.String() + ")"
+	}
+	return "-" + n.operand.String()
+}
+
+func (n binary) sql() string {
+	if n.op == '/' {
+		return "(" + n.left.sql() + " / NULLIF(" + n.right.sql() + ", 0))"
+	}
+	return "(" + n.left.sql() + " " + string(n.op) + " " + n.right.sql() + ")"
+}
+
+func (n binary) String() string {
+	return wrap(n.left, n.op, false) + string(n.op) + wrap(n.right, n.op, true)
+}
+
+// wrap parenthesises a child of a binary node when precedence requires it
+func wrap(child node, parent byte, right bool) string {
+	b, ok := child.(binary)
+	if !ok {
+		return child.String()
+	}
+	if precedence(b.op) < precedence(parent) || (right && precedence(b.op) == precedence(parent)) {
+		return "(" + b.String() + ")"
+	}
+	return b.String()
+}
+
+func precedence(op byte) int {
+	if op == '*' || op == '/' {
+		return 2
+	}
+	return 1
+}
+
+// validate checks that fields only appear inside a single level of aggregate
+func validate(n node, inAggregate bool) error {
+	switch n := n.(type) {
+	case field:
+		if !inAggregate {
+			return fmt.Errorf("%w: field %s must be inside an aggregate such as sum(%s)", ErrInvalid, n.name, n.name)
+		}
+	case call:
+		if inAggregate {
+			return fmt.Errorf("%w: aggregates cannot be nested (%s inside another aggregate)", ErrInvalid, n.fn)
+		}
+		if n.arg != nil {
+			return validate(n.arg, true)
+		}
+	case unary:
+		return validate(n.operand, inAggregate)
+	case binary:
+		if err := validate(n.left, inAggregate); err != nil {
+			return err
+		}
+		return validate(n.right, inAggregate)
+	}
+	return nil
+}
+
+// ===================== Parser =====================
+
+type parser struct {
+	tokens []token
+	pos    int
+	nodes  int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return fmt.Errorf("%w: %s at position %d", ErrInvalid, fmt.Sprintf(format, args...), t.pos+1)
+}
+
+func (p *parser) add(n node, t token) (node, error) {
+	p.nodes++
+	if p.nodes > maxNodes {
+		return nil, p.errorf(t, "expression is too complex")
+	}
+	return n, nil
+}
+
+// expr := term (('+' | '-') term)*
+func (p *parser) expr() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t.kind == tokOp && (t.text == "+" || t.text == "-"); t = p.peek() {
+		p.next()
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = p.add(binary{op: t.text[0], left: left, right: right}, t); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+// term := factor (('*' | '/') factor)*
+func (p *parser) term() (node, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t.kind == tokOp && (t.text == "*" || t.text == "/"); t = p.peek() {
+		p.next()
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = p.add(binary{op: t.text[0], left: left, right: right}, t); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+// factor := '-' factor | number | field | name '(' [expr] ')' | '(' expr ')'
+func (p *parser) factor() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokOp:
+		if t.text != "-" {
+			return nil, p.errorf(t, "unexpected %q", t.text)
+		}
+		operand, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return p.add(unary{operand: operand}, t)
+	case tokNumber:
+		value, err := decimal.NewFromString(t.text)
+		if err != nil {
+			return nil, p.errorf(t, "invalid number %q", t.text)
+		}
+		return p.add(number{value: value}, t)
+	case tokName:
+		if p.peek().kind != tokLParen {
+			if _, ok := Fields[t.text]; !ok {
+				return nil, p.errorf(t, "unknown field %q (allowed: %s)", t.text, strings.Join(FieldNames(), ", "))
+			}
+			return p.add(field{name: t.text}, t)
+		}
+		fn := strings.ToLower(t.text)
+		if _, ok := functions[fn]; !ok {
+			return nil, p.errorf(t, "unknown function %q (allowed: %s)", t.text, strings.Join(FunctionNames(), ", "))
+		}
+		p.next()
+		var arg node
+		if p.peek().kind != tokRParen {
+			var err error
+			if arg, err = p.expr(); err != nil {
+				return nil, err
+			}
+		} else if fn != "count" {
+			return nil, p.errorf(t, "%s needs an argument", fn)
+		}
+		if r := p.next(); r.kind != tokRParen {
+			return nil, p.errorf(r, "expected ')'")
+		}
+		return p.add(call{fn: fn, arg: arg}, t)
+	case tokLParen:
+		inner, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if r := p.next(); r.kind != tokRParen {
+			return nil, p.errorf(r, "expected ')'")
+		}
+		return inner, nil
+	case tokEOF:
+		return nil, p.errorf(t, "unexpected end of expression")
+	}
+	return nil, p.errorf(t, "unexpected %q", t.text)
+}
+
+// ===================== Tokenizer =====================
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokName
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		ch := src[i]
+		switch {
+		case ch == ' ' || ch == '\t':
+			i++
+		case ch == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case ch == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case strings.IndexByte("+-*/", ch) >= 0:
+			tokens = append(tokens, token{kind: tokOp, text: string(ch), pos: i})
+			i++
+		case isDigit(ch) || ch == '.':
+			start := i
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], pos: start})
+		case isLetter(ch):
+			start := i
+			for i < len(src) && (isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokName, text: src[start:i], pos: start})
+		default:
+			return nil, fmt.Errorf("%w: unexpected character %q at position %d", ErrInvalid, ch, i+1)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, text: "end of expression", pos: len(src)}), nil
+}
+
+func isDigit(ch byte) bool  { return ch >= '0' && ch <= '9' }
+func isLetter(ch byte) bool { return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_' }
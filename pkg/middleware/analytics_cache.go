@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/cache"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/events"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/redact"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// analyticsGenerationKey holds the generation of the cached analytics of a
+// tenant; invalidating replaces it, so the entries of older generations are
+// never read again and expire on their own
+func analyticsGenerationKey(ctx context.Context) string {
+	return "analytics:generation:" + auth.TenantFrom(ctx)
+}
+
+// AnalyticsCacheConfig represents caching of analytics responses
+type AnalyticsCacheConfig struct {
+	// TTL is how long a response is served from the cache; 0 disables it
+	TTL time.Duration
+}
+
+// LoadAnalyticsCacheConfig loads the analytics cache settings from environment variables
+func LoadAnalyticsCacheConfig() *AnalyticsCacheConfig {
+	return &AnalyticsCacheConfig{
+		TTL: utils.GetEnvAsDuration("ANALYTICS_CACHE_TTL", time.Minute),
+	}
+}
+
+// AnalyticsCache serves repeated analytics requests from a cache.Store, so
+// dashboards refreshing the same charts do not aggregate again. Responses
+// are keyed by method, URL, body and the headers they vary on, and dropped
+// all at once by Invalidate when the data they aggregate changes.
+type AnalyticsCache struct {
+	cfg   *AnalyticsCacheConfig
+	store cache.Store
+}
+
+// cachedResponse is a successful response as kept in the store
+type cachedResponse struct {
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// NewAnalyticsCache creates a new AnalyticsCache on store
+func NewAnalyticsCache(cfg *AnalyticsCacheConfig, store cache.Store) *AnalyticsCache {
+	return &AnalyticsCache{cfg: cfg, store: store}
+}
+
+// OnEvents subscribes the cache to the domain events: it invalidates when
+// the data analytics aggregate changes, i.e. production records and the
+// generators, types, operators and regions they are grouped by. New types
+// and operators have no production yet, and plants are not grouped by.
+func (ac *AnalyticsCache) OnEvents(ctx context.Context, batch []events.Event) {
+	for _, e := range batch {
+		switch e.(type) {
+		case events.TypeCreated, events.OperatorCreated,
+			events.PlantCreated, events.PlantUpdated, events.PlantDeleted:
+		default:
+			ac.Invalidate(ctx)
+			return
+		}
+	}
+}
+
+// Invalidate drops every cached response, on all replicas sharing the store.
+// Failing to do so is logged; the responses then expire after the TTL.
+func (ac *AnalyticsCache) Invalidate(ctx context.Context) {
+	if ac.cfg.TTL <= 0 {
+		return
+	}
+	if err := ac.store.Set(context.WithoutCancel(ctx), analyticsGenerationKey(ctx), []byte(newGeneration()), 0); err != nil {
+		utils.LogError("analytics cache: invalidate", err)
+	}
+}
+
+// generation returns the current generation, starting one when the store
+// has none
+func (ac *AnalyticsCache) generation(ctx context.Context) (string, error) {
+	key := analyticsGenerationKey(ctx)
+	gen, ok, err := ac.store.Get(ctx, key)
+	if err != nil || ok {
+		return string(gen), err
+	}
+	g := newGeneration()
+	return g, ac.store.Set(ctx, key, []byte(g), 0)
+}
+
+func newGeneration() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// key identifies the response to c: its tenant, method, URL, body, the
+// headers that select its format and language, and the audience of the
+// redaction policy, so a response is never replayed to a caller who may
+// see less of it
+func (ac *AnalyticsCache) key(c *gin.Context, gen string, body []byte) string {
+	h := sha256.New()
+	for _, part := range []string{
+		auth.TenantFrom(c.Request.Context()), c.Request.Method, c.Request.URL.RequestURI(),
+		c.GetHeader("Accept"), c.GetHeader("Accept-Language"), redact.Audience(c.Request.Context()),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return "analytics:" + gen + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Middleware serves GET and POST analytics requests from the cache and
+// caches their 200 responses for the TTL, with X-Cache: HIT or MISS.
+// Requests with Cache-Control: no-cache skip the cache and refresh it. When
+// the store fails the request is served without the cache.
+func (ac *AnalyticsCache) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ac.cfg.TTL <= 0 || (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodPost) {
+			c.Next()
+			return
+		}
+		ctx := c.Request.Context()
+		gen, err := ac.generation(ctx)
+		if err != nil {
+			utils.LogError("analytics cache: read generation", err)
+			c.Next()
+			return
+		}
+		var body []byte
+		if c.Request.Body != nil {
+			if body, err = io.ReadAll(c.Request.Body); err != nil {
+				utils.ErrorResponse(c, http.StatusBadRequest, "Failed to read request body: "+err.Error())
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		key := ac.key(c, gen, body)
+
+		if !strings.Contains(strings.ToLower(c.GetHeader("Cache-Control")), "no-cache") {
+			if cached, ok := ac.lookup(ctx, key); ok {
+				h := c.Writer.Header()
+				for name, values := range cached.Header {
+					h[name] = values
+				}
+				h.Set("X-Cache", "HIT")
+				c.Status(http.StatusOK)
+				_, _ = c.Writer.Write(cached.Body)
+				c.Abort()
+				return
+			}
+		}
+
+		original := c.Writer
+		before := original.Header().Clone()
+		buf := &bufferedWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = buf
+		c.Next()
+		c.Writer = original
+
+		if buf.status == http.StatusOK {
+			ac.save(ctx, key, responseHeader(before, original.Header()), buf.body.Bytes())
+			original.Header().Set("X-Cache", "MISS")
+		}
+		original.WriteHeader(buf.status)
+		_, _ = original.Write(buf.body.Bytes())
+	}
+}
+
+func (ac *AnalyticsCache) lookup(ctx context.Context, key string) (*cachedResponse, bool) {
+	b, ok, err := ac.store.Get(ctx, key)
+	if err != nil {
+		utils.LogError("analytics cache: get", err)
+		return nil, false
+	}
+	var cached cachedResponse
+	if !ok || json.Unmarshal(b, &cached) != nil {
+		return nil, false
+	}
+	return &cached, true
+}
+
+func (ac *AnalyticsCache) save(ctx context.Context, key string, header http.Header, body []byte) {
+	b, err := json.Marshal(&cachedResponse{Header: header, Body: body})
+	if err == nil {
+		err = ac.store.Set(ctx, key, b, ac.cfg.TTL)
+	}
+	if err != nil {
+		utils.LogError("analytics cache: set", err)
+	}
+}
+
+// responseHeader returns the headers the handlers set, those of after that
+// were not in before
+func responseHeader(before, after http.Header) http.Header {
+	h := http.Header{}
+	for name, values := range after {
+		if old, ok := before[name]; !ok || strings.Join(old, "\n") != strings.Join(values, "\n") {
+			h[name] = values
+		}
+	}
+	return h
+}
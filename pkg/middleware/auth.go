@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Headers flagging the responses to requests made during an impersonation
+// session, so clients can show a banner
+const (
+	HeaderImpersonationSession = "X-Impersonation-Session"
+	HeaderImpersonatedBy       = "X-Impersonated-By"
+)
+
+// Authenticator reads the "Authorization: Bearer <token>" header of requests
+// and attaches the principal of the user to the request context, where the
+// authorized repository checks writes against it. Writes without a token are
+// rejected when authentication is required; reads are public unless the route
+// requires a role (see RequireRole).
+type Authenticator struct {
+	cfg    *auth.Config
+	tokens *auth.Tokens
+	repo   database.Repository
+	// public are the routes (as registered, e.g. "/api/v1/auth/login") that
+	// accept writes without a token
+	public map[string]bool
+	// twoFactorExempt are the routes that accept writes from users whose
+	// role requires a second factor without one, to enroll
+	twoFactorExempt map[string]bool
+}
+
+// NewAuthenticator creates an authenticator verifying tokens issued by tokens
+func NewAuthenticator(cfg *auth.Config, tokens *auth.Tokens, repo database.Repository) *Authenticator {
+	return &Authenticator{cfg: cfg, tokens: tokens, repo: repo, public: map[string]bool{}, twoFactorExempt: map[string]bool{}}
+}
+
+// Public lets routes accept writes without a token, e.g. login. A token sent
+// to them is still verified.
+func (a *Authenticator) Public(routes ...string) {
+	for _, r := range routes {
+		a.public[r] = true
+	}
+}
+
+// TwoFactorExempt lets routes accept writes with tokens issued without a
+// second factor from users whose role requires one, e.g. enrollment
+func (a *Authenticator) TwoFactorExempt(routes ...string) {
+	for _, r := range routes {
+		a.twoFactorExempt[r] = true
+	}
+}
+
+// Middleware returns the authenticating middleware
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			if a.cfg.Required && isWrite(c.Request.Method) && !a.public[c.FullPath()] {
+				a.reject(c, "Authentication required: send an access token as \"Authorization: Bearer <token>\"")
+				return
+			}
+			c.Next()
+			return
+		}
+
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			a.reject(c, "Invalid authorization header: expected \"Bearer <token>\"")
+			return
+		}
+		id, err := a.tokens.VerifyIdentity(strings.TrimSpace(token))
+		if err != nil {
+			a.reject(c, "Unauthorized: "+err.Error())
+			return
+		}
+		ctx := c.Request.Context()
+		principal, err := database.LoadPrincipal(ctx, a.repo, id.UserID)
+		if err != nil {
+			// Tokens of deleted accounts stop working before they expire
+			if err == sql.ErrNoRows {
+				a.reject(c, "Unauthorized: the account of this token no longer exists")
+				return
+			}
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to authenticate: "+err.Error())
+			c.Abort()
+			return
+		}
+		principal.TwoFactor = id.TwoFactor
+		// The bearer of the token needs the second factor, also when
+		// impersonating someone whose roles do not
+		if isWrite(c.Request.Method) && !a.public[c.FullPath()] && !a.twoFactorExempt[c.FullPath()] &&
+			!a.checkTwoFactor(c, principal) {
+			return
+		}
+		if id.SessionID != nil {
+			a.impersonate(c, principal, *id.SessionID)
+			return
+		}
+		c.Request = c.Request.WithContext(auth.WithPrincipal(ctx, principal))
+		c.Next()
+	}
+}
+
+// checkTwoFactor answers 403 and returns false when a role of p requires a
+// second factor its token was issued without
+func (a *Authenticator) checkTwoFactor(c *gin.Context, p *auth.Principal) bool {
+	role, ok := a.cfg.TwoFactor.RequiredFor(p.Roles)
+	if !ok || p.TwoFactor {
+		return true
+	}
+	utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: two-factor authentication is required for role "+role+
+		": enroll at /api/v1/users/me/2fa and log in with a code")
+	c.Abort()
+	return false
+}
+
+// impersonate serves a request made with the token of an impersonation
+// session as the impersonated principal, flags the response with the
+// session and records the request
+func (a *Authenticator) impersonate(c *gin.Context, actor *auth.Principal, sessionID uuid.UUID) {
+	ctx := c.Request.Context()
+	session, err := a.repo.GetImpersonation(ctx, sessionID)
+	if err != nil && err != sql.ErrNoRows {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to authenticate: "+err.Error())
+		c.Abort()
+		return
+	}
+	if err == sql.ErrNoRows || session.ActorID != actor.UserID || !session.Active {
+		a.reject(c, "Unauthorized: the impersonation session of this token has ended")
+		return
+	}
+	// Admins who lost the role lose their sessions with it
+	if !actor.HasRole(auth.RoleAdmin) {
+		a.reject(c, "Unauthorized: only admins can impersonate")
+		return
+	}
+
+	var principal *auth.Principal
+	if session.UserID != nil {
+		principal, err = database.LoadPrincipal(ctx, a.repo, *session.UserID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				a.reject(c, "Unauthorized: the impersonated account no longer exists")
+				return
+			}
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to authenticate: "+err.Error())
+			c.Abort()
+			return
+		}
+	} else {
+		// A user of the operator: an operator scoped to it
+		principal = auth.NewPrincipal(actor.UserID, []string{auth.RoleOperator}, []uuid.UUID{*session.OperatorID})
+	}
+	principal.ImpersonatorID, principal.SessionID = actor.UserID, session.ID
+	principal.TwoFactor = actor.TwoFactor
+
+	c.Header(HeaderImpersonationSession, session.ID.String())
+	c.Header(HeaderImpersonatedBy, actor.UserID.String())
+	c.Request = c.Request.WithContext(auth.WithPrincipal(ctx, principal))
+	c.Next()
+
+	event := &models.ImpersonationEvent{
+		SessionID: session.ID,
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.RequestURI(),
+		Status:    c.Writer.Status(),
+		CreatedAt: time.Now(),
+	}
+	if err := a.repo.CreateImpersonationEvent(context.WithoutCancel(ctx), event); err != nil {
+		utils.LogError("impersonation event", err)
+	}
+}
+
+func (a *Authenticator) reject(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="tadb"`)
+	utils.ErrorResponse(c, http.StatusUnauthorized, message)
+	c.Abort()
+}
+
+// isWrite reports whether a request method changes data
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/cache"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/redact"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// referenceEntryTTL is how long the first-served time of a response is kept
+// after it was last served
+const referenceEntryTTL = 24 * time.Hour
+
+// CacheConfig represents HTTP caching of rarely changing reference data
+type CacheConfig struct {
+	MaxAge time.Duration
+}
+
+// LoadCacheConfig loads caching configuration from environment variables
+func LoadCacheConfig() *CacheConfig {
+	return &CacheConfig{
+		MaxAge: utils.GetEnvAsDuration("CACHE_REFERENCE_MAX_AGE", 5*time.Minute),
+	}
+}
+
+// ReferenceCache adds Cache-Control, ETag and Last-Modified headers to GET
+// responses and answers conditional requests with 304. The ETag is the hash
+// of the body, unless the handler set one (the version of a record), and
+// Last-Modified is the time the current ETag was first served, so both
+// change automatically when the data does. That time is kept in a
+// cache.Store, so replicas sharing one agree on it.
+type ReferenceCache struct {
+	cfg   *CacheConfig
+	store cache.Store
+}
+
+// NewReferenceCache creates a new ReferenceCache remembering ETags in store
+func NewReferenceCache(cfg *CacheConfig, store cache.Store) *ReferenceCache {
+	return &ReferenceCache{cfg: cfg, store: store}
+}
+
+// bufferedWriter holds the response until its ETag is known
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func (w *bufferedWriter) WriteHeader(code int) { w.status = code }
+func (w *bufferedWriter) WriteHeaderNow()      {}
+func (w *bufferedWriter) Status() int          { return w.status }
+func (w *bufferedWriter) Size() int            { return w.body.Len() }
+func (w *bufferedWriter) Written() bool        { return w.body.Len() > 0 }
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// Middleware applies the caching headers to successful GET responses
+func (rc *ReferenceCache) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		buf := &bufferedWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = buf
+		c.Next()
+		c.Writer = original
+
+		if buf.status != http.StatusOK {
+			original.WriteHeader(buf.status)
+			_, _ = original.Write(buf.body.Bytes())
+			return
+		}
+
+		h := original.Header()
+		etag := h.Get("ETag")
+		if etag == "" {
+			sum := sha256.Sum256(buf.body.Bytes())
+			etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+		}
+		// Translated responses are cached per language, redacted ones per
+		// audience, and every tenant has its own
+		key := auth.TenantFrom(c.Request.Context()) + c.Request.URL.RequestURI()
+		if lang := original.Header().Get("Content-Language"); lang != "" {
+			key += "#" + lang
+		}
+		if audience := redact.Audience(c.Request.Context()); audience != "" {
+			key += "@" + audience
+		}
+		modified := rc.touch(c.Request.Context(), key, etag)
+
+		h.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(rc.cfg.MaxAge.Seconds())))
+		h.Set("ETag", etag)
+		h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+
+		if notModified(c.Request, etag, modified) {
+			original.WriteHeader(http.StatusNotModified)
+			original.WriteHeaderNow()
+			return
+		}
+		original.WriteHeader(http.StatusOK)
+		_, _ = original.Write(buf.body.Bytes())
+	}
+}
+
+// touch records etag for key and returns when that content was first
+// served, stored as "<unix seconds> <etag>". When the store fails the
+// content is taken as new.
+func (rc *ReferenceCache) touch(ctx context.Context, key, etag string) time.Time {
+	// Whole seconds, as Last-Modified has no sub-second precision
+	now := time.Now().Truncate(time.Second)
+	key = "reference:" + key
+	b, ok, err := rc.store.Get(ctx, key)
+	if err != nil {
+		utils.LogError("reference cache: get", err)
+		return now
+	}
+	if ok {
+		if sec, stored, found := strings.Cut(string(b), " "); found && stored == etag {
+			if unix, err := strconv.ParseInt(sec, 10, 64); err == nil {
+				modified := time.Unix(unix, 0)
+				// Keep the entry while it is being served
+				_ = rc.store.Set(ctx, key, b, referenceEntryTTL)
+				return modified
+			}
+		}
+	}
+	if err := rc.store.Set(ctx, key, []byte(strconv.FormatInt(now.Unix(), 10)+" "+etag), referenceEntryTTL); err != nil {
+		utils.LogError("reference cache: set", err)
+	}
+	return now
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when no ETag was sent
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil {
+		return !modified.After(since)
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Limit caps the requests of a route group: MaxInFlight run at once and up
+// to MaxQueue more wait for a slot; anything beyond is rejected
+type Limit struct {
+	MaxInFlight int
+	MaxQueue    int
+}
+
+// ConcurrencyConfig represents the concurrency limits of the API route groups
+type ConcurrencyConfig struct {
+	Default Limit
+	// Groups overrides the default per route group (e.g. "analytics")
+	Groups       map[string]Limit
+	QueueTimeout time.Duration
+}
+
+// LoadConcurrencyConfig loads concurrency limits from environment variables.
+// CONCURRENCY_LIMITS overrides groups as "<group>=<inFlight>/<queue>", e.g.
+// "analytics=8/16,imports=2/4". A MaxInFlight of 0 disables the limit.
+func LoadConcurrencyConfig() *ConcurrencyConfig {
+	cfg := &ConcurrencyConfig{
+		Default: Limit{
+			MaxInFlight: utils.GetEnvAsInt("CONCURRENCY_MAX_IN_FLIGHT", 64),
+			MaxQueue:    utils.GetEnvAsInt("CONCURRENCY_MAX_QUEUE", 128),
+		},
+		// Aggregations and file processing hold DB connections for long
+		Groups: map[string]Limit{
+			"analytics": {MaxInFlight: 8, MaxQueue: 16},
+			"imports":   {MaxInFlight: 4, MaxQueue: 8},
+			"exports":   {MaxInFlight: 4, MaxQueue: 8},
+			"reports":   {MaxInFlight: 4, MaxQueue: 8},
+		},
+		QueueTimeout: utils.GetEnvAsDuration("CONCURRENCY_QUEUE_TIMEOUT", 5*time.Second),
+	}
+	for _, item := range utils.GetEnvAsList("CONCURRENCY_LIMITS", nil) {
+		group, spec, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		inFlight, queue, _ := strings.Cut(spec, "/")
+		n, err := strconv.Atoi(strings.TrimSpace(inFlight))
+		if err != nil || n < 0 {
+			continue
+		}
+		limit := Limit{MaxInFlight: n}
+		if q, err := strconv.Atoi(strings.TrimSpace(queue)); err == nil && q >= 0 {
+			limit.MaxQueue = q
+		}
+		cfg.Groups[strings.TrimSpace(group)] = limit
+	}
+	return cfg
+}
+
+// For returns the limiting middleware of a route group. Each call creates an
+// independent limiter, so call it once per group.
+func (cfg *ConcurrencyConfig) For(group string) gin.HandlerFunc {
+	limit, ok := cfg.Groups[group]
+	if !ok {
+		limit = cfg.Default
+	}
+	return NewConcurrencyLimiter(limit, cfg.QueueTimeout).Middleware()
+}
+
+// ConcurrencyLimiter bounds the in-flight requests of a route group with a
+// bounded waiting queue
+type ConcurrencyLimiter struct {
+	slots        chan struct{}
+	queue        chan struct{}
+	queueTimeout time.Duration
+	retryAfter   string
+}
+
+// NewConcurrencyLimiter creates a new ConcurrencyLimiter
+func NewConcurrencyLimiter(limit Limit, queueTimeout time.Duration) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{
+		queueTimeout: queueTimeout,
+		retryAfter:   strconv.Itoa(int(math.Max(1, math.Ceil(queueTimeout.Seconds())))),
+	}
+	if limit.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, limit.MaxInFlight)
+		l.queue = make(chan struct{}, limit.MaxQueue)
+	}
+	return l
+}
+
+// Middleware runs the request when a slot is free, queues it otherwise, and
+// answers 503 with Retry-After when the queue is full or the wait times out
+func (l *ConcurrencyLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l.slots == nil {
+			c.Next()
+			return
+		}
+		if !l.acquire(c) {
+			c.Header("Retry-After", l.retryAfter)
+			utils.ErrorResponse(c, http.StatusServiceUnavailable, "Server busy: too many concurrent requests, retry later")
+			c.Abort()
+			return
+		}
+		defer func() { <-l.slots }()
+		c.Next()
+	}
+}
+
+func (l *ConcurrencyLimiter) acquire(c *gin.Context) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	select {
+	case l.queue <- struct{}{}:
+	default:
+		return false
+	}
+	defer func() { <-l.queue }()
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-c.Request.Context().Done():
+		return false
+	}
+}
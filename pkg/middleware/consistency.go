@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// Headers of read-your-writes consistency
+const (
+	// ConsistencyTokenHeader carries the time of a write, in Unix
+	// milliseconds, from its response to the reads that must see it
+	ConsistencyTokenHeader = "X-Consistency-Token"
+	// ConsistencyHeader set to "strong" reads from the primary
+	ConsistencyHeader = "X-Consistency"
+)
+
+// ReadConsistency lets GET and HEAD requests read from the replica unless
+// they ask for the primary: with X-Consistency: strong, or with the
+// X-Consistency-Token of a write made less than maxLag ago, so a client that
+// just created a record finds it. Successful writes get that token in their
+// response. It goes before authentication, whose lookups then follow the
+// same rule.
+func ReadConsistency(maxLag time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead:
+			if !primaryRead(c.Request, maxLag) {
+				c.Request = c.Request.WithContext(auth.WithReplicaReads(c.Request.Context()))
+			}
+		case http.MethodOptions:
+		default:
+			w := &consistencyWriter{ResponseWriter: c.Writer}
+			c.Writer = w
+			c.Next()
+			// Responses without a body, e.g. 204, are written by gin after the handlers
+			w.stamp()
+			return
+		}
+		c.Next()
+	}
+}
+
+// primaryRead reports whether r must read from the primary
+func primaryRead(r *http.Request, maxLag time.Duration) bool {
+	if strings.EqualFold(r.Header.Get(ConsistencyHeader), "strong") {
+		return true
+	}
+	ms, err := strconv.ParseInt(r.Header.Get(ConsistencyTokenHeader), 10, 64)
+	if err != nil {
+		return false
+	}
+	return time.Since(time.UnixMilli(ms)) < maxLag
+}
+
+// consistencyWriter adds the consistency token to a successful response as
+// its headers are written, so the token is the time the write completed
+type consistencyWriter struct {
+	gin.ResponseWriter
+}
+
+func (w *consistencyWriter) stamp() {
+	if !w.Written() && w.Status() < http.StatusMultipleChoices {
+		w.Header().Set(ConsistencyTokenHeader, strconv.FormatInt(time.Now().UnixMilli(), 10))
+	}
+}
+
+func (w *consistencyWriter) WriteHeaderNow() {
+	w.stamp()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *consistencyWriter) Write(b []byte) (int, error) {
+	w.stamp()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *consistencyWriter) WriteString(s string) (int, error) {
+	w.stamp()
+	return w.ResponseWriter.WriteString(s)
+}
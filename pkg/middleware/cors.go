@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig represents the origins allowed to call the API from a browser
+type CORSConfig struct {
+	// AllowedOrigins are origins such as https://dashboard.example.com;
+	// https://*.example.com allows its subdomains and * every origin. None
+	// disables CORS, so browsers only call the API from its own origin.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers scripts may read
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers cache a preflight answer
+	MaxAge time.Duration
+}
+
+// LoadCORSConfig loads CORS configuration from environment variables
+func LoadCORSConfig() *CORSConfig {
+	return &CORSConfig{
+		AllowedOrigins: utils.GetEnvAsList("CORS_ALLOWED_ORIGINS", nil),
+		AllowedMethods: utils.GetEnvAsList("CORS_ALLOWED_METHODS", []string{
+			http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+		}),
+		AllowedHeaders: utils.GetEnvAsList("CORS_ALLOWED_HEADERS", []string{
+			"Authorization", "Content-Type", "Accept", "Accept-Language", "If-Match", "If-None-Match",
+			"If-Modified-Since", "X-Chunk-Checksum", "X-Query-Stats", "X-Tenant",
+			"X-Consistency", "X-Consistency-Token", "Last-Event-ID",
+		}),
+		ExposedHeaders: utils.GetEnvAsList("CORS_EXPOSED_HEADERS", []string{
+			"ETag", "Last-Modified", "Link", "Location", "Retry-After", "Warning", "Content-Disposition",
+			"Content-Language", "X-Result-Truncated", "X-Query-Count", "X-Rows-Scanned", "X-Query-Time-Ms",
+			"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-Consistency-Token",
+		}),
+		AllowCredentials: utils.GetEnvAsBool("CORS_ALLOW_CREDENTIALS", false),
+		MaxAge:           utils.GetEnvAsDuration("CORS_MAX_AGE", 10*time.Minute),
+	}
+}
+
+// CORS answers preflight requests and adds the CORS headers to the responses
+// of requests from allowed origins, for the routes under prefix. Requests
+// from other origins are served without the headers, so browsers hide the
+// responses from their scripts; their preflights are answered 403.
+type CORS struct {
+	cfg     *CORSConfig
+	prefix  string
+	methods string
+	headers map[string]bool
+	allowed string
+	exposed string
+}
+
+// NewCORS creates a new CORS for the routes under prefix, e.g. /api/v1
+func NewCORS(cfg *CORSConfig, prefix string) *CORS {
+	c := &CORS{
+		cfg:     cfg,
+		prefix:  prefix,
+		methods: strings.ToUpper(strings.Join(cfg.AllowedMethods, ", ")),
+		headers: map[string]bool{},
+		allowed: strings.Join(cfg.AllowedHeaders, ", "),
+		exposed: strings.Join(cfg.ExposedHeaders, ", "),
+	}
+	for _, h := range cfg.AllowedHeaders {
+		c.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	return c
+}
+
+// allowOrigin returns the value of Access-Control-Allow-Origin for origin,
+// empty when it is not allowed
+func (c *CORS) allowOrigin(origin string) string {
+	for _, allowed := range c.cfg.AllowedOrigins {
+		switch {
+		case allowed == "*":
+			// Credentials need the origin itself
+			if c.cfg.AllowCredentials {
+				return origin
+			}
+			return "*"
+		case strings.EqualFold(allowed, origin):
+			return origin
+		case strings.Contains(allowed, "://*."):
+			scheme, domain, _ := strings.Cut(allowed, "://*")
+			if strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(strings.ToLower(origin), strings.ToLower(domain)) {
+				return origin
+			}
+		}
+	}
+	return ""
+}
+
+// Middleware returns the middleware; it goes before authentication, as
+// preflight requests carry no credentials
+func (c *CORS) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		origin := ctx.GetHeader("Origin")
+		if len(c.cfg.AllowedOrigins) == 0 || origin == "" || !strings.HasPrefix(ctx.Request.URL.Path, c.prefix) {
+			ctx.Next()
+			return
+		}
+		h := ctx.Writer.Header()
+		h.Add("Vary", "Origin")
+		allow := c.allowOrigin(origin)
+
+		if ctx.Request.Method == http.MethodOptions && ctx.GetHeader("Access-Control-Request-Method") != "" {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			if allow == "" {
+				utils.ErrorResponse(ctx, http.StatusForbidden, "Forbidden: origin "+origin+" is not allowed")
+				ctx.Abort()
+				return
+			}
+			c.setOrigin(h, allow)
+			h.Set("Access-Control-Allow-Methods", c.methods)
+			if requested := c.requestedHeaders(ctx.GetHeader("Access-Control-Request-Headers")); requested != "" {
+				h.Set("Access-Control-Allow-Headers", requested)
+			}
+			if c.cfg.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.cfg.MaxAge.Seconds())))
+			}
+			ctx.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if allow != "" {
+			c.setOrigin(h, allow)
+			if c.exposed != "" {
+				h.Set("Access-Control-Expose-Headers", c.exposed)
+			}
+		}
+		ctx.Next()
+	}
+}
+
+func (c *CORS) setOrigin(h http.Header, allow string) {
+	h.Set("Access-Control-Allow-Origin", allow)
+	if c.cfg.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// requestedHeaders keeps the headers of a preflight that are allowed; with *
+// among the allowed headers all of them are
+func (c *CORS) requestedHeaders(requested string) string {
+	var allowed []string
+	for _, name := range strings.Split(requested, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && (c.headers["*"] || c.headers[http.CanonicalHeaderKey(name)]) {
+			allowed = append(allowed, name)
+		}
+	}
+	return strings.Join(allowed, ", ")
+}
@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Bounds of the deduplicated requests: larger or streamed bodies, larger
+// responses and requests beyond the remembered ones are not deduplicated
+const (
+	maxDedupBody     = 1 << 20
+	maxDedupResponse = 1 << 20
+	maxDedupEntries  = 4096
+)
+
+// DedupConfig represents the deduplication of double-submitted POSTs
+type DedupConfig struct {
+	// Window is how long a POST is answered again instead of run again; 0
+	// disables deduplication
+	Window time.Duration
+}
+
+// LoadDedupConfig loads deduplication configuration from environment variables
+func LoadDedupConfig() *DedupConfig {
+	return &DedupConfig{
+		Window: utils.GetEnvAsDuration("REQUEST_DEDUP_WINDOW", 5*time.Second),
+	}
+}
+
+// Deduplicator answers a POST repeated by the same client with the same body
+// within the window, e.g. a double-clicked form, with the response of the
+// first one instead of running it again. A duplicate arriving while the first
+// is still running waits for it. Responses of 5xx and 429 are not replayed,
+// so retries of failed requests do run.
+type Deduplicator struct {
+	cfg *DedupConfig
+
+	mu      sync.Mutex
+	entries map[string]*dedupEntry
+}
+
+type dedupEntry struct {
+	// done is closed once the response is recorded, or dropped when ok is
+	// false
+	done    chan struct{}
+	ok      bool
+	expires time.Time
+	status  int
+	header  http.Header
+	body    []byte
+}
+
+// NewDeduplicator creates a new Deduplicator
+func NewDeduplicator(cfg *DedupConfig) *Deduplicator {
+	return &Deduplicator{cfg: cfg, entries: map[string]*dedupEntry{}}
+}
+
+// recordingWriter passes the response through and keeps a copy of it
+type recordingWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.record(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *recordingWriter) record(b []byte) {
+	if w.overflow || w.body.Len()+len(b) > maxDedupResponse {
+		w.overflow = true
+		return
+	}
+	w.body.Write(b)
+}
+
+// Middleware deduplicates the POST requests of the routes it is applied to;
+// it reads the principal, so it goes after the authenticator
+func (d *Deduplicator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d.cfg.Window <= 0 || c.Request.Method != http.MethodPost ||
+			c.Request.ContentLength < 0 || c.Request.ContentLength > maxDedupBody {
+			c.Next()
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Failed to read request body: "+err.Error())
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := dedupKey(c, body)
+		entry, first := d.claim(key)
+		if first {
+			d.run(c, key, entry)
+			return
+		}
+		select {
+		case <-entry.done:
+		case <-c.Request.Context().Done():
+			c.Abort()
+			return
+		}
+		if !entry.ok {
+			// The first request failed or was not recorded: run this one
+			c.Next()
+			return
+		}
+		h := c.Writer.Header()
+		for k, v := range entry.header {
+			h[k] = v
+		}
+		h.Set("X-Duplicate-Request", "true")
+		c.Status(entry.status)
+		_, _ = c.Writer.Write(entry.body)
+		c.Abort()
+	}
+}
+
+// run serves the first request of a key and records its response for the
+// duplicates
+func (d *Deduplicator) run(c *gin.Context, key string, entry *dedupEntry) {
+	original := c.Writer
+	w := &recordingWriter{ResponseWriter: original}
+	c.Writer = w
+	defer func() {
+		c.Writer = original
+		failed := recover()
+		status := w.Status()
+		d.mu.Lock()
+		if failed != nil || w.overflow || status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+			delete(d.entries, key)
+		} else {
+			entry.ok = true
+			entry.status = status
+			entry.header = original.Header().Clone()
+			entry.body = w.body.Bytes()
+			entry.expires = time.Now().Add(d.cfg.Window)
+		}
+		d.mu.Unlock()
+		close(entry.done)
+		if failed != nil {
+			panic(failed)
+		}
+	}()
+	c.Next()
+}
+
+// claim returns the entry of key, creating it when there is none (or it
+// expired) and reporting whether the caller is the first request
+func (d *Deduplicator) claim(key string) (*dedupEntry, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	if entry, ok := d.entries[key]; ok && (!entry.ok || now.Before(entry.expires)) {
+		return entry, false
+	}
+	if len(d.entries) >= maxDedupEntries {
+		for k, e := range d.entries {
+			if e.ok && !now.Before(e.expires) {
+				delete(d.entries, k)
+			}
+		}
+	}
+	entry := &dedupEntry{done: make(chan struct{})}
+	if len(d.entries) < maxDedupEntries {
+		d.entries[key] = entry
+	}
+	return entry, true
+}
+
+// dedupKey identifies a request by its client, target and body. The client
+// is the user of the request (the impersonation session too, if any), else
+// its IP address.
+func dedupKey(c *gin.Context, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte("tenant:" + auth.TenantFrom(c.Request.Context()) + "\n"))
+	if p, ok := auth.PrincipalFrom(c.Request.Context()); ok {
+		h.Write([]byte("user:" + p.UserID.String() + ":" + p.SessionID.String()))
+	} else {
+		h.Write([]byte("ip:" + c.ClientIP()))
+	}
+	h.Write([]byte("\n" + c.Request.URL.RequestURI() + "\n" + c.GetHeader("Content-Type") + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
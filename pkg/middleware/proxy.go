@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ProxyConfig represents the reverse proxies in front of the API
+type ProxyConfig struct {
+	// TrustedProxies are the IPs and CIDR ranges of the proxies whose
+	// forwarding headers are believed; none by default, so clients cannot
+	// claim another address
+	TrustedProxies []string
+	// Headers carry the client address set by the proxies, tried in order
+	Headers []string
+}
+
+// LoadProxyConfig loads proxy configuration from environment variables
+func LoadProxyConfig() *ProxyConfig {
+	return &ProxyConfig{
+		TrustedProxies: utils.GetEnvAsList("TRUSTED_PROXIES", nil),
+		Headers:        utils.GetEnvAsList("CLIENT_IP_HEADERS", []string{"X-Forwarded-For", "X-Real-IP"}),
+	}
+}
+
+// ConfigureProxies makes the client IP of requests to r the address the
+// trusted proxies forwarded: X-Forwarded-For is read from right to left,
+// skipping trusted proxies, and the first other address is the client.
+// Requests from untrusted peers get their own address.
+func ConfigureProxies(r *gin.Engine, cfg *ProxyConfig) error {
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	r.ForwardedByClientIP = len(cfg.TrustedProxies) > 0
+	r.RemoteIPHeaders = cfg.Headers
+	return nil
+}
+
+// ClientIP attaches the resolved client IP to the request context, where the
+// repository reads it for the audit log
+func ClientIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(auth.WithClientIP(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
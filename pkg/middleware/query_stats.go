@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// QueryStatsConfig represents the query cost headers clients can ask for
+type QueryStatsConfig struct {
+	// Enabled lets clients ask for the headers; they reveal how long the
+	// database takes, so deployments can turn them off
+	Enabled bool
+}
+
+// LoadQueryStatsConfig loads the query cost header settings from environment variables
+func LoadQueryStatsConfig() *QueryStatsConfig {
+	return &QueryStatsConfig{
+		Enabled: utils.GetEnvAsBool("QUERY_STATS_HEADERS", true),
+	}
+}
+
+// statsWriter sets the query stats headers right before the response starts
+type statsWriter struct {
+	gin.ResponseWriter
+	stats *database.QueryStats
+	set   bool
+}
+
+func (w *statsWriter) setHeaders() {
+	if w.set || w.ResponseWriter.Written() {
+		return
+	}
+	w.set = true
+	queries, rows, duration := w.stats.Totals()
+	h := w.ResponseWriter.Header()
+	h.Set("X-Query-Count", strconv.Itoa(queries))
+	h.Set("X-Rows-Scanned", strconv.FormatInt(rows, 10))
+	h.Set("X-Query-Time-Ms", fmt.Sprintf("%.1f", float64(duration.Microseconds())/1000))
+}
+
+func (w *statsWriter) WriteHeaderNow() {
+	w.setHeaders()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *statsWriter) Write(b []byte) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statsWriter) WriteString(s string) (int, error) {
+	w.setHeaders()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *statsWriter) Flush() {
+	w.setHeaders()
+	w.ResponseWriter.Flush()
+}
+
+// QueryStats answers GET requests sent with X-Query-Stats: true with the
+// number of database queries they ran (X-Query-Count), the rows those
+// returned (X-Rows-Scanned, from the command tags) and the time they took in
+// the database (X-Query-Time-Ms). Streamed responses count the queries run
+// before their first byte. It goes first, so the headers cover the queries of
+// the other middleware too.
+func QueryStats(cfg *QueryStatsConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.Enabled || c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+		if on, _ := strconv.ParseBool(c.GetHeader("X-Query-Stats")); !on {
+			c.Next()
+			return
+		}
+		ctx, stats := database.WithQueryStats(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		w := &statsWriter{ResponseWriter: c.Writer, stats: stats}
+		c.Writer = w
+		c.Next()
+		// Responses without a body are sent after the handlers return
+		w.setHeaders()
+	}
+}
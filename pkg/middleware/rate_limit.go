@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/cache"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitConfig represents the requests a client may send per window
+type RateLimitConfig struct {
+	// Requests is the limit per client and window; 0 disables rate limiting
+	Requests int
+	Window   time.Duration
+}
+
+// LoadRateLimitConfig loads rate limiting configuration from environment variables
+func LoadRateLimitConfig() *RateLimitConfig {
+	return &RateLimitConfig{
+		Requests: utils.GetEnvAsInt("RATE_LIMIT_REQUESTS", 0),
+		Window:   utils.GetEnvAsDuration("RATE_LIMIT_WINDOW", time.Minute),
+	}
+}
+
+// RateLimiter counts the requests of every client in fixed windows in a
+// cache.Store, so replicas sharing a Redis store enforce one limit together.
+// The client is the user of the access token, or the client IP without one.
+type RateLimiter struct {
+	cfg   *RateLimitConfig
+	store cache.Store
+}
+
+// NewRateLimiter creates a new RateLimiter counting in store
+func NewRateLimiter(cfg *RateLimitConfig, store cache.Store) *RateLimiter {
+	return &RateLimiter{cfg: cfg, store: store}
+}
+
+// Middleware answers 429 with Retry-After once a client exceeds the limit in
+// the current window, and sends X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset (seconds until the window ends) on every response. It
+// reads the principal, so it goes after the authenticator. When the store
+// fails requests are let through.
+func (rl *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rl.cfg.Requests <= 0 || rl.cfg.Window <= 0 {
+			c.Next()
+			return
+		}
+		ctx := c.Request.Context()
+		client := "ip:" + c.ClientIP()
+		if p, ok := auth.PrincipalFrom(ctx); ok {
+			client = "user:" + p.UserID.String()
+		}
+		now := time.Now()
+		window := now.Truncate(rl.cfg.Window)
+		key := "ratelimit:" + auth.TenantFrom(ctx) + ":" + client + ":" + strconv.FormatInt(window.Unix(), 10)
+
+		count, err := rl.store.Incr(ctx, key, rl.cfg.Window)
+		if err != nil {
+			utils.LogError("rate limit", err)
+			c.Next()
+			return
+		}
+		reset := int(window.Add(rl.cfg.Window).Sub(now).Seconds()) + 1
+		c.Header("X-RateLimit-Limit", strconv.Itoa(rl.cfg.Requests))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(max(rl.cfg.Requests-int(count), 0)))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(reset))
+		if count > int64(rl.cfg.Requests) {
+			c.Header("Retry-After", strconv.Itoa(reset))
+			utils.ErrorResponse(c, http.StatusTooManyRequests, "Too many requests: at most "+strconv.Itoa(rl.cfg.Requests)+" per "+rl.cfg.Window.String()+", retry later")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
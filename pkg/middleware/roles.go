@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// RoleErrorResponse is the body of a 403 answer to a request the roles of
+// the user do not allow
+type RoleErrorResponse struct {
+	Status string `json:"status" example:"error"`
+	Error  string `json:"error" example:"Forbidden: writes to /api/v1/generators need the role admin or operator"`
+	// Roles are the roles of the user and RequiredRoles those that allow the request
+	Roles         []string `json:"roles" example:"viewer"`
+	RequiredRoles []string `json:"requiredRoles" example:"admin,operator"`
+}
+
+// RequireRole lets only users with one of roles use the routes it is applied
+// to, reads included. Anonymous requests are answered 401 unless
+// authentication is disabled. Users whose role requires two-factor
+// authentication need it for reads of these routes too, as they are not
+// public data.
+func (a *Authenticator) RequireRole(roles ...string) gin.HandlerFunc {
+	return a.requireRole(roles, false)
+}
+
+// RequireRoleToWrite is RequireRole for writes (POST, PUT, PATCH, DELETE)
+// only: reads of the routes it is applied to stay public. It is the explicit
+// opt-out for data anyone may read, e.g. generators.
+func (a *Authenticator) RequireRoleToWrite(roles ...string) gin.HandlerFunc {
+	return a.requireRole(roles, true)
+}
+
+func (a *Authenticator) requireRole(roles []string, publicReads bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		write := isWrite(c.Request.Method)
+		if publicReads && !write {
+			c.Next()
+			return
+		}
+		p, ok := auth.PrincipalFrom(c.Request.Context())
+		if !ok {
+			if a.cfg.Required {
+				a.reject(c, "Authentication required: send an access token as \"Authorization: Bearer <token>\"")
+				return
+			}
+			c.Next()
+			return
+		}
+		if p.HasRole(roles...) {
+			// Writes were checked by Middleware
+			if !write && !a.checkTwoFactor(c, p) {
+				return
+			}
+			c.Next()
+			return
+		}
+		have := p.Roles
+		if have == nil {
+			have = []string{}
+		}
+		access := "reads of "
+		if write {
+			access = "writes to "
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, RoleErrorResponse{
+			Status:        "error",
+			Error:         "Forbidden: " + access + c.FullPath() + " need the role " + strings.Join(roles, " or "),
+			Roles:         have,
+			RequiredRoles: roles,
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SecurityConfig represents the security headers sent with every response
+type SecurityConfig struct {
+	// HSTSMaxAge is how long browsers only connect over HTTPS; 0 leaves out
+	// Strict-Transport-Security
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	// FrameOptions and ReferrerPolicy are sent as is; empty leaves them out
+	FrameOptions   string
+	ReferrerPolicy string
+}
+
+// LoadSecurityConfig loads security header configuration from environment variables
+func LoadSecurityConfig() *SecurityConfig {
+	return &SecurityConfig{
+		HSTSMaxAge:            utils.GetEnvAsDuration("SECURITY_HSTS_MAX_AGE", 365*24*time.Hour),
+		HSTSIncludeSubdomains: utils.GetEnvAsBool("SECURITY_HSTS_INCLUDE_SUBDOMAINS", true),
+		FrameOptions:          utils.GetEnv("SECURITY_FRAME_OPTIONS", "DENY"),
+		ReferrerPolicy:        utils.GetEnv("SECURITY_REFERRER_POLICY", "no-referrer"),
+	}
+}
+
+// Security sets the security headers and makes sure request bodies of JSON
+// endpoints are JSON: writes with a body must declare application/json (or a
+// +json type) in UTF-8, else they get 415 before reaching the handler.
+type Security struct {
+	cfg  *SecurityConfig
+	hsts string
+	// anyContentType are the routes taking other bodies, e.g. file uploads
+	anyContentType map[string]bool
+}
+
+// NewSecurity creates a new Security
+func NewSecurity(cfg *SecurityConfig) *Security {
+	s := &Security{cfg: cfg, anyContentType: map[string]bool{}}
+	if cfg.HSTSMaxAge > 0 {
+		s.hsts = fmt.Sprintf("max-age=%d", int64(cfg.HSTSMaxAge/time.Second))
+		if cfg.HSTSIncludeSubdomains {
+			s.hsts += "; includeSubDomains"
+		}
+	}
+	return s
+}
+
+// AnyContentType lets routes accept bodies of any content type, e.g. CSV
+// imports and multipart uploads; they check the type themselves
+func (s *Security) AnyContentType(routes ...string) {
+	for _, r := range routes {
+		s.anyContentType[r] = true
+	}
+}
+
+// Middleware returns the middleware setting the headers and checking the
+// content type of request bodies
+func (s *Security) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		if s.hsts != "" {
+			h.Set("Strict-Transport-Security", s.hsts)
+		}
+		if s.cfg.FrameOptions != "" {
+			h.Set("X-Frame-Options", s.cfg.FrameOptions)
+		}
+		if s.cfg.ReferrerPolicy != "" {
+			h.Set("Referrer-Policy", s.cfg.ReferrerPolicy)
+		}
+
+		if hasBody(c.Request) && !s.anyContentType[c.FullPath()] {
+			if msg := checkJSONContentType(c.GetHeader("Content-Type")); msg != "" {
+				utils.ErrorResponse(c, http.StatusUnsupportedMediaType, "Unsupported Media Type: "+msg)
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// hasBody reports whether a write request carries a body; a length of -1 is
+// a chunked body of unknown size
+func hasBody(r *http.Request) bool {
+	return isWrite(r.Method) && r.ContentLength != 0
+}
+
+// checkJSONContentType returns why a Content-Type is not JSON in UTF-8,
+// empty when it is
+func checkJSONContentType(header string) string {
+	if header == "" {
+		return "send the body as Content-Type: application/json"
+	}
+	mediaType, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		return "invalid Content-Type " + header
+	}
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return "expected application/json, got " + mediaType
+	}
+	if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") {
+		return "charset must be utf-8, got " + charset
+	}
+	return ""
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Tenant attaches the tenant of each request, named by the header or its
+// host, to the request context, where the repository reads it to pick the
+// database. A header naming a tenant missing from the registry is answered
+// 404. It goes before authentication, as users are kept in the database of
+// their tenant.
+func Tenant(tenants *database.Tenants, header string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant, ok := tenants.Resolve(c.Request.Host, c.GetHeader(header))
+		if !ok {
+			utils.ErrorResponse(c, http.StatusNotFound, "Unknown tenant: "+tenant)
+			c.Abort()
+			return
+		}
+		if tenant != "" {
+			c.Request = c.Request.WithContext(auth.WithTenant(c.Request.Context(), tenant))
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,56 @@
+package models
+
+// Analytics of a batch query
+const (
+	AnalyticsTotalProduction  = "totalProduction"
+	AnalyticsTimeSeries       = "timeseries"
+	AnalyticsMix              = "mix"
+	AnalyticsTopGenerators    = "topGenerators"
+	AnalyticsRenewableSummary = "renewableSummary"
+	AnalyticsMarketShare      = "marketShare"
+	AnalyticsMixByRegion      = "mixByRegion"
+)
+
+// AnalyticsBatchRequest asks for several analytics in one request
+// @Description Analytics to compute together; startDate and endDate apply to the queries without their own
+type AnalyticsBatchRequest struct {
+	StartDate *string           `json:"startDate,omitempty" binding:"omitempty,isodate" example:"2025-01-01"`
+	EndDate   *string           `json:"endDate,omitempty" binding:"omitempty,isodate" example:"2025-12-31"`
+	Queries   []*AnalyticsQuery `json:"queries" binding:"required,min=1,max=20,dive"`
+}
+
+// AnalyticsQuery is one of the analytics of a batch, with the parameters of
+// its GET endpoint
+// @Description One analytics query of a batch
+type AnalyticsQuery struct {
+	// ID names the result; it defaults to the position of the query
+	ID   string `json:"id,omitempty" binding:"max=50" example:"monthly"`
+	Type string `json:"type" binding:"required,oneof=totalProduction timeseries mix topGenerators renewableSummary marketShare mixByRegion" example:"timeseries"`
+	// StartDate and EndDate limit the range, except of mix
+	StartDate *string `json:"startDate,omitempty" binding:"omitempty,isodate" example:"2025-01-01"`
+	EndDate   *string `json:"endDate,omitempty" binding:"omitempty,isodate" example:"2025-12-31"`
+	// Date is the day of mix, default today (UTC)
+	Date *string `json:"date,omitempty" binding:"omitempty,isodate" example:"2025-09-03"`
+	// GroupBy and Metric are those of timeseries
+	GroupBy string `json:"groupBy,omitempty" binding:"omitempty,oneof=day week month year" example:"month"`
+	Metric  string `json:"metric,omitempty" binding:"omitempty,oneof=total renewable byType" example:"renewable"`
+	// Limit is the number of topGenerators, default 10
+	Limit int `json:"limit,omitempty" binding:"omitempty,min=1,max=100" example:"5"`
+}
+
+// AnalyticsResult is the answer to one query of a batch: its data, or the
+// status and error its GET endpoint would have answered
+// @Description Result of one analytics query of a batch
+type AnalyticsResult struct {
+	ID     string `json:"id" example:"monthly"`
+	Type   string `json:"type" example:"timeseries"`
+	Status int    `json:"status" example:"200"`
+	Data   any    `json:"data,omitempty" swaggertype:"object"`
+	Error  string `json:"error,omitempty"`
+}
+
+// AnalyticsBatch holds the results of a batch in the order of its queries
+// @Description Results of an analytics batch
+type AnalyticsBatch struct {
+	Results []*AnalyticsResult `json:"results"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Annotation is a note of an analyst on production data: either on one
+// production record or on a date range, of one generator or of the whole fleet
+// @Description Note on a production record (productionId) or on the inclusive range startDate..endDate of a generator, or of every generator when generatorId is omitted
+type Annotation struct {
+	ID           uuid.UUID  `json:"id" example:"550e8400-e29b-41d4-a716-446655440060"`
+	ProductionID *uuid.UUID `json:"productionId,omitempty" example:"550e8400-e29b-41d4-a716-446655440002"`
+	GeneratorID  *uuid.UUID `json:"generatorId,omitempty" example:"550e8400-e29b-41d4-a716-446655440001"`
+	StartDate    *string    `json:"startDate,omitempty" example:"2025-09-03"`
+	EndDate      *string    `json:"endDate,omitempty" example:"2025-09-05"`
+	Note         string     `json:"note" example:"Sensor outage 3-5 Sept; values estimated by the operator"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+// AppliesTo reports whether the annotation is about the production record p:
+// it is on p itself, or its range covers the date of p for its generator
+func (a *Annotation) AppliesTo(p *Production) bool {
+	if a.ProductionID != nil {
+		return *a.ProductionID == p.ID
+	}
+	if a.GeneratorID != nil && *a.GeneratorID != p.GeneratorID {
+		return false
+	}
+	// Dates in YYYY-MM-DD order lexically
+	return a.StartDate != nil && a.EndDate != nil && *a.StartDate <= p.Date && p.Date <= *a.EndDate
+}
+
+// CreateAnnotationRequest represents the request payload for creating an annotation
+// @Description Request body for annotating a production record (productionId) or a date range (startDate, optional endDate and generatorId)
+type CreateAnnotationRequest struct {
+	ProductionID *uuid.UUID `json:"productionId" example:"550e8400-e29b-41d4-a716-446655440002"`
+	GeneratorID  *uuid.UUID `json:"generatorId" example:"550e8400-e29b-41d4-a716-446655440001"`
+	StartDate    *string    `json:"startDate" binding:"omitempty,datetime=2006-01-02" example:"2025-09-03"`
+	EndDate      *string    `json:"endDate" binding:"omitempty,datetime=2006-01-02" example:"2025-09-05"`
+	Note         string     `json:"note" binding:"required,max=1000" example:"Sensor outage 3-5 Sept; values estimated by the operator"`
+}
+
+// UpdateAnnotationRequest represents the request payload for changing the note of an annotation
+// @Description Request body for changing the note of an annotation
+type UpdateAnnotationRequest struct {
+	Note string `json:"note" binding:"required,max=1000" example:"Sensor outage 3-5 Sept; values confirmed by XM"`
+}
+
+// AnnotationFilter selects the annotations that apply to production data:
+// those on a record or range matching every field set
+type AnnotationFilter struct {
+	ProductionID *uuid.UUID
+	GeneratorID  *uuid.UUID
+	StartDate    *string
+	EndDate      *string
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entities documents can be attached to
+const (
+	AttachmentScopeType      = "type"
+	AttachmentScopeGenerator = "generator"
+)
+
+// Where the content of an attachment is kept
+const (
+	AttachmentStorageDatabase = "database"
+	AttachmentStorageObject   = "object"
+)
+
+// Attachment is a document (permit, datasheet, bulletin PDF, ...) attached to a type or generator
+// @Description Document attached to a type or generator; its content is downloaded separately
+type Attachment struct {
+	ID          uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440010"`
+	Scope       string    `json:"scope" example:"generator"`
+	EntityID    uuid.UUID `json:"entityId" example:"550e8400-e29b-41d4-a716-446655440001"`
+	FileName    string    `json:"fileName" example:"environmental-permit.pdf"`
+	ContentType string    `json:"contentType" example:"application/pdf"`
+	SizeBytes   int64     `json:"sizeBytes" example:"482133"`
+	SHA256      string    `json:"sha256" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	// Storage is AttachmentStorageDatabase or AttachmentStorageObject
+	Storage string `json:"storage" example:"object"`
+	// StorageKey is the object key of contents kept in object storage
+	StorageKey string    `json:"-"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
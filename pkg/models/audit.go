@@ -0,0 +1,68 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audited entity types
+const (
+	AuditEntityType           = "type"
+	AuditEntityOperator       = "operator"
+	AuditEntityPlant          = "plant"
+	AuditEntityRegion         = "region"
+	AuditEntityGenerator      = "generator"
+	AuditEntityProduction     = "production"
+	AuditEntityAnnotation     = "annotation"
+	AuditEntityCustomField    = "custom_field"
+	AuditEntityImportProfile  = "import_profile"
+	AuditEntityReport         = "report"
+	AuditEntityReportTemplate = "report_template"
+	AuditEntityUser           = "user"
+)
+
+// AuditEntities lists the audited entity types
+var AuditEntities = []string{
+	AuditEntityType, AuditEntityOperator, AuditEntityPlant, AuditEntityRegion, AuditEntityGenerator, AuditEntityProduction, AuditEntityAnnotation,
+	AuditEntityCustomField, AuditEntityImportProfile, AuditEntityReport, AuditEntityReportTemplate, AuditEntityUser,
+}
+
+// Audit actions
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditEntry records a change to an entity: who made it, when, and the
+// entity before and after it
+// @Description Change to an entity with its state before and after; before is left out for creates and after for deletes
+type AuditEntry struct {
+	ID         int64           `json:"id" example:"1042"`
+	EntityType string          `json:"entityType" example:"generator"`
+	EntityID   uuid.UUID       `json:"entityId" example:"550e8400-e29b-41d4-a716-446655440001"`
+	Action     string          `json:"action" example:"update"`
+	Before     json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After      json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	// UserID is who made the change, left out for anonymous and background
+	// writes; ImpersonatorID is the admin acting as the user, if any
+	UserID         *uuid.UUID `json:"userId,omitempty" example:"550e8400-e29b-41d4-a716-446655440060"`
+	ImpersonatorID *uuid.UUID `json:"impersonatorId,omitempty"`
+	// ClientIP is the address the change was requested from
+	ClientIP  string    `json:"clientIp,omitempty" example:"203.0.113.7"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AuditFilter selects the entries of an audit listing; dates are inclusive
+type AuditFilter struct {
+	EntityType *string
+	EntityID   *uuid.UUID
+	UserID     *uuid.UUID
+	Action     *string
+	StartDate  *string
+	EndDate    *string
+	Limit      int
+	Offset     int
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Connectors files are pulled by
+const (
+	// ConnectorSFTP files were downloaded from a partner SFTP server
+	ConnectorSFTP = "sftp"
+	// ConnectorEmail files were attachments of a message in a polled mailbox
+	ConnectorEmail = "email"
+)
+
+// Connector file status values
+const (
+	// ConnectorFileStaged files were passed on to the drop folder for import
+	ConnectorFileStaged = "staged"
+	// ConnectorFileDuplicate files had the checksum of a file staged before and were skipped
+	ConnectorFileDuplicate = "duplicate"
+)
+
+// ConnectorFile is a remote file downloaded by a partner feed connector;
+// for email attachments RemotePath is the message ID and the file name
+// @Description Remote file pulled from a partner feed, with its checksum and the name it was staged under
+type ConnectorFile struct {
+	ID         uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440060"`
+	Connector  string    `json:"connector" example:"sftp"`
+	Source     string    `json:"source" example:"xm-partner"`
+	RemotePath string    `json:"remotePath" example:"/outgoing/generation-2025-09-30.csv"`
+	Size       int64     `json:"size" example:"48213"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+	SHA256     string    `json:"sha256" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Status     string    `json:"status" example:"staged"`
+	// StagedAs is the file name in the drop folder
+	StagedAs  string    `json:"stagedAs,omitempty" example:"xm-partner-9f86d081-generation-2025-09-30.csv"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// ConnectorSourceStatus is the schedule and last outcome of a partner feed
+// @Description Partner feed source with its schedule (interval, or business day of the month) and the outcome of its last pull
+type ConnectorSourceStatus struct {
+	Name        string     `json:"name" example:"xm-partner"`
+	Host        string     `json:"host" example:"sftp.partner.example.com:22"`
+	Dir         string     `json:"dir" example:"/outgoing"`
+	Pattern     string     `json:"pattern" example:"*.csv"`
+	Interval    string     `json:"interval,omitempty" example:"15m0s"`
+	Schedule    string     `json:"schedule,omitempty" example:"business day 5 at 09:00 America/Bogota (CO)"`
+	LastRunAt   *time.Time `json:"lastRunAt,omitempty"`
+	NextRunAt   *time.Time `json:"nextRunAt,omitempty"`
+	LastError   string     `json:"lastError,omitempty" example:"failed to connect: dial tcp: i/o timeout"`
+	FilesStaged int64      `json:"filesStaged" example:"42"`
+}
+
+// MailboxStatus is the schedule and last outcome of a polled mailbox
+// @Description IMAP mailbox polled for emailed spreadsheets, with its schedule and the outcome of its last poll
+type MailboxStatus struct {
+	Name              string     `json:"name" example:"operator-submissions"`
+	Host              string     `json:"host" example:"imap.example.com:993"`
+	Folder            string     `json:"folder" example:"INBOX"`
+	Interval          string     `json:"interval,omitempty" example:"5m0s"`
+	Schedule          string     `json:"schedule,omitempty" example:"business day 1 from the end at 18:00 America/Bogota (CO)"`
+	Reviewers         int        `json:"reviewers" example:"2"`
+	LastRunAt         *time.Time `json:"lastRunAt,omitempty"`
+	NextRunAt         *time.Time `json:"nextRunAt,omitempty"`
+	LastError         string     `json:"lastError,omitempty" example:"failed to log in: NO [AUTHENTICATIONFAILED] Invalid credentials"`
+	MessagesRead      int64      `json:"messagesRead" example:"17"`
+	AttachmentsStaged int64      `json:"attachmentsStaged" example:"21"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CrosstabQuery selects the dimensions and aggregate of a cross-tab
+type CrosstabQuery struct {
+	Rows    string
+	Columns string
+	Value   string
+	// Metric is a metric expression (package metric); when set it replaces Value
+	Metric    string
+	StartDate *string
+	EndDate   *string
+	// AsOf restates the records to what they were at that moment
+	AsOf *time.Time
+}
+
+// Crosstab is a matrix of aggregated production, rows × columns
+// @Description Cross-tab of aggregated production (the energy matrix). cells[i][j] aggregates rowKeys[i] × columnKeys[j] and is null without records or when a metric divides by zero; totals use the same aggregate
+type Crosstab struct {
+	Rows         string               `json:"rows" example:"type"`
+	Columns      string               `json:"columns" example:"month"`
+	Value        string               `json:"value" example:"sum(productionMw)/sum(capacity)"`
+	RowKeys      []string             `json:"rowKeys" example:"Eólica,Solar"`
+	ColumnKeys   []string             `json:"columnKeys" example:"2025-08,2025-09"`
+	Cells        [][]*decimal.Decimal `json:"cells" swaggertype:"array,array,number"`
+	RowTotals    []*decimal.Decimal   `json:"rowTotals" swaggertype:"array,number"`
+	ColumnTotals []*decimal.Decimal   `json:"columnTotals" swaggertype:"array,number"`
+	Total        *decimal.Decimal     `json:"total" swaggertype:"number" example:"48250.75"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entities custom fields are defined for
+const (
+	CustomEntityType       = "type"
+	CustomEntityOperator   = "operator"
+	CustomEntityGenerator  = "generator"
+	CustomEntityProduction = "production"
+)
+
+// Value types of custom fields
+const (
+	CustomFieldString  = "string"
+	CustomFieldNumber  = "number"
+	CustomFieldBoolean = "boolean"
+	// CustomFieldDate values are YYYY-MM-DD strings
+	CustomFieldDate = "date"
+)
+
+// CustomAttributes are the values of the custom fields of a record, keyed by
+// field name. On updates they are merged into the stored values and a null
+// value removes the attribute.
+type CustomAttributes map[string]any
+
+// CustomField is a user-defined attribute of an entity
+// @Description Custom field registered for types, operators, generators or productions. min and max bound numbers and the length of strings; pattern (a regular expression) and options (allowed values) apply to strings
+type CustomField struct {
+	ID          uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440070"`
+	Entity      string    `json:"entity" example:"generator"`
+	Name        string    `json:"name" example:"gridNode"`
+	Type        string    `json:"type" example:"string"`
+	Required    bool      `json:"required" example:"false"`
+	Description string    `json:"description,omitempty" example:"Substation the generator is connected to"`
+	Pattern     string    `json:"pattern,omitempty" example:"^[A-Z]{3}-[0-9]+$"`
+	Min         *float64  `json:"min,omitempty" example:"0"`
+	Max         *float64  `json:"max,omitempty" example:"500"`
+	Options     []string  `json:"options,omitempty" example:"north,south"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// CreateCustomFieldRequest represents the request payload for registering a custom field
+// @Description Request body for registering a custom field; name is a letter followed by letters, digits or underscores
+type CreateCustomFieldRequest struct {
+	Entity      string   `json:"entity" binding:"required,oneof=type operator generator production" example:"generator"`
+	Name        string   `json:"name" binding:"required,max=50" example:"gridNode"`
+	Type        string   `json:"type" binding:"required,oneof=string number boolean date" example:"string"`
+	Required    bool     `json:"required" example:"false"`
+	Description string   `json:"description,omitempty" binding:"omitempty,max=200" example:"Substation the generator is connected to"`
+	Pattern     string   `json:"pattern,omitempty" binding:"omitempty,max=200" example:"^[A-Z]{3}-[0-9]+$"`
+	Min         *float64 `json:"min,omitempty" example:"0"`
+	Max         *float64 `json:"max,omitempty" example:"500"`
+	Options     []string `json:"options,omitempty" binding:"omitempty,dive,max=100" example:"north,south"`
+}
+
+// UpdateCustomFieldRequest represents the request payload for updating a custom field.
+// The entity, name and type of a field cannot change; the validation replaces the current one.
+// @Description Request body for updating the description and validation of a custom field
+type UpdateCustomFieldRequest struct {
+	Required    bool     `json:"required" example:"true"`
+	Description string   `json:"description,omitempty" binding:"omitempty,max=200" example:"Substation the generator is connected to"`
+	Pattern     string   `json:"pattern,omitempty" binding:"omitempty,max=200" example:"^[A-Z]{3}-[0-9]+$"`
+	Min         *float64 `json:"min,omitempty" example:"0"`
+	Max         *float64 `json:"max,omitempty" example:"500"`
+	Options     []string `json:"options,omitempty" binding:"omitempty,dive,max=100" example:"north,south"`
+}
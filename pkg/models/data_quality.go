@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Data quality checks
+const (
+	// QualityOrphanProduction finds productions of generators that do not exist
+	QualityOrphanProduction = "orphanProduction"
+	// QualityOrphanGrant finds operator grants of users that do not exist
+	QualityOrphanGrant = "orphanGrant"
+	// QualityDeletedType finds generators of deleted or merged types
+	QualityDeletedType = "generatorOfDeletedType"
+	// QualityBeforeCommissioning finds productions dated before the
+	// commissioning of their generator
+	QualityBeforeCommissioning = "productionBeforeCommissioning"
+	// QualityOverCapacity finds productions above the capacity of their generator
+	QualityOverCapacity = "productionOverCapacity"
+	// QualityUnusedType finds types without generators
+	QualityUnusedType = "typeWithoutGenerators"
+)
+
+// QualityChecks lists the data quality checks in the order they are reported
+var QualityChecks = []string{QualityOrphanProduction, QualityOrphanGrant, QualityDeletedType, QualityBeforeCommissioning, QualityOverCapacity, QualityUnusedType}
+
+// Entities data quality findings are about
+const (
+	QualityEntityType       = "type"
+	QualityEntityGenerator  = "generator"
+	QualityEntityProduction = "production"
+	QualityEntityUser       = "user"
+)
+
+// DataQualityFinding is a row a data quality check flagged
+// @Description An orphaned or inconsistent row: the check that flagged it, the entity and its ID, and what is wrong with it
+type DataQualityFinding struct {
+	Check    string    `json:"check" example:"productionOverCapacity"`
+	Entity   string    `json:"entity" example:"production"`
+	EntityID uuid.UUID `json:"entityId" example:"550e8400-e29b-41d4-a716-446655440002"`
+	// GeneratorID and Date locate production findings
+	GeneratorID *uuid.UUID `json:"generatorId,omitempty" example:"550e8400-e29b-41d4-a716-446655440001"`
+	Date        *string    `json:"date,omitempty" example:"2025-09-03"`
+	Detail      string     `json:"detail" example:"120.5 MW exceeds the capacity of 100.5 MW"`
+}
+
+// Key identifies a finding across scans
+func (f *DataQualityFinding) Key() string {
+	return f.Check + ":" + f.EntityID.String()
+}
+
+// DataQualityCheck is the outcome of one data quality check
+// @Description A data quality check and how many rows it flagged; findings lists at most limit of them
+type DataQualityCheck struct {
+	Check string `json:"check" example:"productionOverCapacity"`
+	Count int64  `json:"count" example:"3"`
+}
+
+// DataQualityReport is the outcome of a data quality scan
+// @Description Orphaned and inconsistent rows across types, generators, productions and grants, per check. truncated is set when a check flagged more rows than it lists
+type DataQualityReport struct {
+	Checks    []*DataQualityCheck   `json:"checks"`
+	Findings  []*DataQualityFinding `json:"findings"`
+	Truncated bool                  `json:"truncated" example:"false"`
+	ScannedAt time.Time             `json:"scannedAt"`
+}
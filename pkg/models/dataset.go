@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Dataset is a self-contained copy of the data of a date range, e.g. for
+// classroom or public use
+// @Description Dataset of the types, operators, plants, regions, generators and productions of a date range
+type Dataset struct {
+	GeneratedAt time.Time `json:"generatedAt"`
+	StartDate   *string   `json:"startDate,omitempty" example:"2025-01-01"`
+	EndDate     *string   `json:"endDate,omitempty" example:"2025-12-31"`
+	// Scrub is the policy applied to the dataset; absent when it was not scrubbed
+	Scrub       *ScrubPolicy  `json:"scrub,omitempty"`
+	Types       []*Type       `json:"types"`
+	Operators   []*Operator   `json:"operators"`
+	Plants      []*Plant      `json:"plants"`
+	Regions     []*Region     `json:"regions"`
+	Generators  []*Generator  `json:"generators"`
+	Productions []*Production `json:"productions"`
+}
+
+// ScrubPolicy describes what a scrubbed dataset leaves out
+// @Description Scrubbing applied to a dataset
+type ScrubPolicy struct {
+	// Operators is pseudonymize (names become "Operator 1", ...), drop or keep
+	Operators string `json:"operators" example:"pseudonymize"`
+	// CoordinateDecimals is the number of decimals coordinates are rounded
+	// to; -1 when they are removed
+	CoordinateDecimals int  `json:"coordinateDecimals" example:"1"`
+	RemoveSourceRefs   bool `json:"removeSourceRefs" example:"true"`
+	RemoveAttributes   bool `json:"removeCustomAttributes" example:"true"`
+}
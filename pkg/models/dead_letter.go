@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Dead letter status values
+const (
+	// DeadLetterPending rows are waiting to be fixed and resubmitted
+	DeadLetterPending = "pending"
+	// DeadLetterResubmitted rows were imported on a later attempt
+	DeadLetterResubmitted = "resubmitted"
+)
+
+// DeadLetter is an import row that was rejected, kept with its raw values
+// so it can be corrected and resubmitted
+// @Description Rejected import row with the values read from the file, why it was rejected and the outcome of its resubmissions
+type DeadLetter struct {
+	ID           uuid.UUID  `json:"id" example:"550e8400-e29b-41d4-a716-446655440090"`
+	JobID        uuid.UUID  `json:"jobId" example:"550e8400-e29b-41d4-a716-446655440050"`
+	Line         int64      `json:"line" example:"42"`
+	GeneratorID  string     `json:"generatorId" example:"550e8400-e29b-41d4-a716-446655440001"`
+	Date         string     `json:"date" example:"2025-09-31"`
+	ProductionMW string     `json:"productionMw" example:"1,250.5"`
+	Error        string     `json:"error" example:"invalid productionMw: must be a number greater than or equal to 0"`
+	Status       string     `json:"status" example:"pending"`
+	Attempts     int        `json:"attempts" example:"1"`
+	ProductionID *uuid.UUID `json:"productionId,omitempty" example:"550e8400-e29b-41d4-a716-446655440002"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+// DeadLetterFilter selects the dead letters of a listing
+type DeadLetterFilter struct {
+	JobID  *uuid.UUID
+	Status *string
+	Limit  int
+	Offset int
+}
+
+// UpdateDeadLetterRequest represents the request payload for correcting a dead letter
+// @Description Corrected values of a rejected import row; fields left out keep the values read from the file
+type UpdateDeadLetterRequest struct {
+	GeneratorID  *string `json:"generatorId,omitempty" binding:"omitempty,max=200" example:"550e8400-e29b-41d4-a716-446655440001"`
+	Date         *string `json:"date,omitempty" binding:"omitempty,max=200" example:"2025-09-30"`
+	ProductionMW *string `json:"productionMw,omitempty" binding:"omitempty,max=200" example:"1250.5"`
+}
+
+// DeadLetterResubmission is the outcome of resubmitting the pending dead letters
+// @Description Dead letters resubmitted in one request, each with its new status or error
+type DeadLetterResubmission struct {
+	Resubmitted int           `json:"resubmitted" example:"12"`
+	Failed      int           `json:"failed" example:"1"`
+	Items       []*DeadLetter `json:"items"`
+}
@@ -0,0 +1,8 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+func init() {
+	// Render energy and capacity values as JSON numbers instead of strings
+	decimal.MarshalJSONWithoutQuotes = true
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailTemplate is a version of the email sent for a kind of notification.
+// Subject and body are Go text/templates evaluated with the notification;
+// every edit adds a version, and the latest one is used.
+// @Description Version of a notification email; subject and body are Go templates over the notification data
+type EmailTemplate struct {
+	Key         string `json:"key" example:"freshness-alert"`
+	Description string `json:"description,omitempty" example:"Sent when production data becomes stale"`
+	// Version is 0 for the built-in template of a key that was never edited
+	Version   int        `json:"version" example:"3"`
+	Subject   string     `json:"subject" example:"Production data is {{.LagDays}} days old"`
+	Body      string     `json:"body" example:"{{range .Generators}}- {{.GeneratorID}}: {{.LagDays}} days\n{{end}}"`
+	Comment   string     `json:"comment,omitempty" example:"Shorter subject for mobile clients"`
+	UserID    *uuid.UUID `json:"userId,omitempty" example:"550e8400-e29b-41d4-a716-446655440060"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+}
+
+// EmailTemplateRequest represents the request payload for a new version of an email template
+// @Description New version of a notification email
+type EmailTemplateRequest struct {
+	Subject string `json:"subject" binding:"required,max=300" example:"Production data is {{.LagDays}} days old"`
+	Body    string `json:"body" binding:"required,max=20000" example:"{{range .Generators}}- {{.GeneratorID}}: {{.LagDays}} days\n{{end}}"`
+	Comment string `json:"comment,omitempty" binding:"max=200" example:"Shorter subject for mobile clients"`
+}
+
+// EmailTemplatePreviewRequest represents the request payload for previewing an email template
+// @Description Draft texts and data to render; the current template and sample data fill in what is left out
+type EmailTemplatePreviewRequest struct {
+	Subject *string `json:"subject,omitempty" binding:"omitempty,max=300"`
+	Body    *string `json:"body,omitempty" binding:"omitempty,max=20000"`
+	// Version renders a stored version instead of the current template
+	Version *int           `json:"version,omitempty" binding:"omitempty,gte=1" example:"2"`
+	Data    map[string]any `json:"data,omitempty" swaggertype:"object"`
+}
+
+// EmailPreview is a rendered email template
+// @Description Email as it would be sent, with the data it was rendered with
+type EmailPreview struct {
+	Key     string `json:"key" example:"freshness-alert"`
+	Subject string `json:"subject" example:"Production data is 4 days old"`
+	Body    string `json:"body"`
+	Data    any    `json:"data" swaggertype:"object"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ProjectionDailyProduction is the projection of production per day and generator type
+const ProjectionDailyProduction = "daily_production_summary"
+
+// DomainEvent is an entry of the append-only event log
+// @Description Change of a type, operator, generator or production record, with the row before (old) and after (new) it
+type DomainEvent struct {
+	Seq         int64           `json:"seq" example:"1042"`
+	Aggregate   string          `json:"aggregate" example:"production"`
+	AggregateID uuid.UUID       `json:"aggregateId" example:"550e8400-e29b-41d4-a716-446655440002"`
+	Event       string          `json:"event" example:"production.updated"`
+	Old         json.RawMessage `json:"old,omitempty" swaggertype:"object"`
+	New         json.RawMessage `json:"new,omitempty" swaggertype:"object"`
+	OccurredAt  time.Time       `json:"occurredAt"`
+}
+
+// DomainEventFilter narrows the event log; events come in log order after AfterSeq
+type DomainEventFilter struct {
+	Aggregate   *string
+	AggregateID *uuid.UUID
+	AfterSeq    int64
+	Limit       int
+}
+
+// Projection is the state of a summary table derived from the event log
+// @Description Projection with the last event it has processed and how far it is behind the log
+type Projection struct {
+	Name          string     `json:"name" example:"daily_production_summary"`
+	Rows          int64      `json:"rows" example:"540"`
+	LastSeq       int64      `json:"lastSeq" example:"1040"`
+	HeadSeq       int64      `json:"headSeq" example:"1042"`
+	PendingEvents int64      `json:"pendingEvents" example:"2"`
+	UpdatedAt     *time.Time `json:"updatedAt,omitempty"`
+	RebuiltAt     *time.Time `json:"rebuiltAt,omitempty"`
+}
+
+// DailyProductionSummary is a row of the daily production projection
+// @Description Production of one day for one generator type
+type DailyProductionSummary struct {
+	Date         string          `json:"date" example:"2025-09-03"`
+	TypeID       uuid.UUID       `json:"typeId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	TypeName     string          `json:"typeName" example:"Solar"`
+	IsRenewable  bool            `json:"isRenewable" example:"true"`
+	Records      int64           `json:"records" example:"12"`
+	ProductionMW decimal.Decimal `json:"productionMw" swaggertype:"number" example:"1024.5"`
+}
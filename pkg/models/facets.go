@@ -0,0 +1,43 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ProductionFacets summarizes the production records matching a filter
+// @Description Distinct values and counts of the productions matching the filter, for faceted filter UIs
+type ProductionFacets struct {
+	Total      int64             `json:"total" example:"8640"`
+	MinDate    *string           `json:"minDate" example:"2025-01-01"`
+	MaxDate    *string           `json:"maxDate" example:"2025-09-30"`
+	Generators []*GeneratorFacet `json:"generators"`
+	Types      []*TypeFacet      `json:"types"`
+	Sources    []*SourceFacet    `json:"sources"`
+}
+
+// GeneratorFacet is a generator with the number of matching records
+// @Description Generator present in the matching productions
+type GeneratorFacet struct {
+	ID           uuid.UUID       `json:"id" example:"550e8400-e29b-41d4-a716-446655440001"`
+	TypeName     string          `json:"typeName" example:"Solar"`
+	Capacity     decimal.Decimal `json:"capacity" swaggertype:"number" example:"150.5"`
+	OperatorName string          `json:"operatorName,omitempty" example:"Celsia"`
+	Count        int64           `json:"count" example:"240"`
+}
+
+// TypeFacet is a generator type with the number of matching records
+// @Description Generator type present in the matching productions
+type TypeFacet struct {
+	ID          uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name        string    `json:"name" example:"Solar"`
+	IsRenewable bool      `json:"isRenewable" example:"true"`
+	Count       int64     `json:"count" example:"1200"`
+}
+
+// SourceFacet is a provenance source with the number of matching records
+// @Description Provenance source present in the matching productions
+type SourceFacet struct {
+	Source string `json:"source" example:"import"`
+	Count  int64  `json:"count" example:"8000"`
+}
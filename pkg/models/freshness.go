@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GeneratorLatestProduction is the most recent production date of a generator
+// @Description Most recent production date of a generator (null when it never reported)
+type GeneratorLatestProduction struct {
+	GeneratorID  uuid.UUID `json:"generatorId" example:"550e8400-e29b-41d4-a716-446655440001"`
+	TypeName     string    `json:"typeName" example:"Solar"`
+	OperatorName string    `json:"operatorName,omitempty" example:"Celsia"`
+	LatestDate   *string   `json:"latestDate" example:"2025-09-30"`
+	CreatedOn    string    `json:"createdOn" example:"2025-01-01"`
+	// Cadence is the expected submission cadence (see the submission calendar)
+	Cadence string `json:"cadence" example:"daily"`
+}
+
+// GeneratorFreshness is how far behind today the data of a generator is
+// @Description Freshness of a generator: its latest production date and the days since. Generators that never reported count from their creation date; they are stale when the lag exceeds allowedLagDays (maxLagDays plus the days between submissions of their cadence)
+type GeneratorFreshness struct {
+	GeneratorLatestProduction
+	LagDays        int  `json:"lagDays" example:"1"`
+	AllowedLagDays int  `json:"allowedLagDays" example:"2"`
+	Stale          bool `json:"stale" example:"false"`
+}
+
+// Freshness reports how recent the production data is, overall and per generator
+// @Description Data freshness: the latest production date overall and per generator, with the lag in days against today. Stale means the lag exceeds maxLagDays. With a calendar (FRESHNESS_CALENDAR) lags count its business days only
+type Freshness struct {
+	Today      string `json:"today" example:"2025-10-02"`
+	MaxLagDays int    `json:"maxLagDays" example:"2"`
+	// Calendar is the holiday calendar lags are counted in business days of
+	Calendar string `json:"calendar,omitempty" example:"CO"`
+	// LatestDate is the most recent production date of any generator (null without data)
+	LatestDate      *string               `json:"latestDate" example:"2025-10-01"`
+	LagDays         *int                  `json:"lagDays" example:"1"`
+	Stale           bool                  `json:"stale" example:"false"`
+	GeneratorCount  int                   `json:"generatorCount" example:"42"`
+	StaleGenerators int                   `json:"staleGenerators" example:"3"`
+	Generators      []*GeneratorFreshness `json:"generators"`
+	CheckedAt       time.Time             `json:"checkedAt"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Holiday is a non-working day of a country, or of one region of it, managed
+// through the API on top of the calendars of HOLIDAY_CALENDARS_FILE
+// @Description Holiday of a country (ISO 3166-1 alpha-2), or only of a region of it when region is set; date is YYYY-MM-DD, or MM-DD for a holiday on the same date every year
+type Holiday struct {
+	ID      uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440090"`
+	Country string    `json:"country" example:"CO"`
+	// Region is the code of the region the holiday applies to, empty for the
+	// whole country
+	Region    string    `json:"region,omitempty" example:"ANT"`
+	Date      string    `json:"date" example:"07-20"`
+	Name      string    `json:"name,omitempty" example:"Independence Day"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// HolidayRequest represents the request payload for adding a holiday
+// @Description Request body for adding a holiday; date is YYYY-MM-DD for one day or MM-DD for every year
+type HolidayRequest struct {
+	Country string `json:"country" binding:"required,len=2,alpha" example:"CO"`
+	Region  string `json:"region,omitempty" binding:"omitempty,max=10,alphanum" example:"ANT"`
+	Date    string `json:"date" binding:"required" example:"07-20"`
+	Name    string `json:"name,omitempty" binding:"max=120" example:"Independence Day"`
+}
+
+// CalendarDay is a holiday of a resolved calendar
+// @Description Holiday within the range of a calendar; source is file for HOLIDAY_CALENDARS_FILE and api for the holidays of /admin/holidays
+type CalendarDay struct {
+	Date   string `json:"date" example:"2025-07-20"`
+	Name   string `json:"name,omitempty" example:"Independence Day"`
+	Source string `json:"source" example:"api"`
+}
+
+// BusinessCalendar is a calendar resolved over a date range
+// @Description Business days of a calendar over a date range: weekend days (0 = Sunday), the holidays in the range and the business days around it
+type BusinessCalendar struct {
+	Code                string         `json:"code" example:"CO-ANT"`
+	Weekend             []time.Weekday `json:"weekend" swaggertype:"array,integer" example:"0,6"`
+	StartDate           string         `json:"startDate" example:"2025-07-01"`
+	EndDate             string         `json:"endDate" example:"2025-07-31"`
+	Days                int            `json:"days" example:"31"`
+	BusinessDays        int            `json:"businessDays" example:"21"`
+	Holidays            []*CalendarDay `json:"holidays"`
+	PreviousBusinessDay string         `json:"previousBusinessDay" example:"2025-06-30"`
+	NextBusinessDay     string         `json:"nextBusinessDay" example:"2025-08-01"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Impersonation is a session in which an admin acts as a user, or as a user
+// of an operator, to debug what they can see and change. Every request made
+// with its token is recorded.
+// @Description Impersonation session: who acted as whom, why and until when
+type Impersonation struct {
+	ID      uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440080"`
+	ActorID uuid.UUID `json:"actorId" example:"550e8400-e29b-41d4-a716-446655440060"`
+	// UserID is the impersonated user; OperatorID the operator whose scoped
+	// operator user the admin acts as. Exactly one is set.
+	UserID     *uuid.UUID `json:"userId,omitempty" example:"550e8400-e29b-41d4-a716-446655440061"`
+	OperatorID *uuid.UUID `json:"operatorId,omitempty"`
+	Reason     string     `json:"reason" example:"Ticket 4211: user cannot see generators of EPM"`
+	StartedAt  time.Time  `json:"startedAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	EndedAt    *time.Time `json:"endedAt,omitempty"`
+	// Active is set while the session has neither ended nor expired
+	Active bool `json:"active" example:"true"`
+}
+
+// ImpersonationRequest represents the request payload for starting an impersonation session
+// @Description Whom to impersonate (userId or operatorId), why, and for how long
+type ImpersonationRequest struct {
+	UserID     *uuid.UUID `json:"userId,omitempty" binding:"required_without=OperatorID,excluded_with=OperatorID" example:"550e8400-e29b-41d4-a716-446655440061"`
+	OperatorID *uuid.UUID `json:"operatorId,omitempty" binding:"required_without=UserID,excluded_with=UserID"`
+	Reason     string     `json:"reason" binding:"required,max=500" example:"Ticket 4211: user cannot see generators of EPM"`
+	// DurationMinutes defaults to, and may not exceed, the longest session allowed
+	DurationMinutes int `json:"durationMinutes,omitempty" binding:"omitempty,min=1" example:"30"`
+}
+
+// ImpersonationResponse is a started impersonation session with its token
+// @Description Impersonation session and the access token to act with; responses to its requests carry X-Impersonation-Session
+type ImpersonationResponse struct {
+	Session     *Impersonation `json:"session"`
+	AccessToken string         `json:"accessToken"`
+	TokenType   string         `json:"tokenType" example:"Bearer"`
+	ExpiresAt   time.Time      `json:"expiresAt"`
+}
+
+// ImpersonationFilter selects the impersonation sessions of a listing
+type ImpersonationFilter struct {
+	ActorID *uuid.UUID
+	UserID  *uuid.UUID
+	Active  *bool
+	Limit   int
+	Offset  int
+}
+
+// ImpersonationEvent is a request made during an impersonation session
+// @Description Request made with the token of an impersonation session
+type ImpersonationEvent struct {
+	ID        int64     `json:"id" example:"1"`
+	SessionID uuid.UUID `json:"sessionId" example:"550e8400-e29b-41d4-a716-446655440080"`
+	Method    string    `json:"method" example:"GET"`
+	Path      string    `json:"path" example:"/api/v1/generators?operatorId=550e8400-e29b-41d4-a716-446655440010"`
+	Status    int       `json:"status" example:"200"`
+	CreatedAt time.Time `json:"createdAt"`
+}
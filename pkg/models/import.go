@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InitUploadRequest represents the request payload for starting a chunked upload
+// @Description Request body for starting a resumable chunked upload
+type InitUploadRequest struct {
+	FileName  string `json:"fileName" binding:"required,max=255" example:"productions-2025-09.csv"`
+	TotalSize int64  `json:"totalSize" binding:"required,gt=0" example:"104857600"`
+	ChunkSize int64  `json:"chunkSize" binding:"required,gt=0" example:"8388608"`
+}
+
+// PresignedUploadRequest represents the request payload for a direct-to-storage upload
+// @Description Request body for requesting a presigned upload URL
+type PresignedUploadRequest struct {
+	FileName string `json:"fileName" binding:"required,max=255" example:"productions-2025-09.csv"`
+}
+
+// PresignedUpload represents a presigned object storage upload
+// @Description Presigned URL the client uploads the file to, and the import job that will process it
+type PresignedUpload struct {
+	JobID     uuid.UUID `json:"jobId" example:"550e8400-e29b-41d4-a716-446655440010"`
+	Method    string    `json:"method" example:"PUT"`
+	UploadURL string    `json:"uploadUrl" example:"https://s3.amazonaws.com/tadb-imports/imports/550e8400-e29b-41d4-a716-446655440010/productions.csv?X-Amz-Algorithm=AWS4-HMAC-SHA256"`
+	Key       string    `json:"key" example:"imports/550e8400-e29b-41d4-a716-446655440010/productions.csv"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// ImportColumns names the header of each column of an import file; columns
+// left empty are found by their usual names (e.g. generator_id, fecha)
+type ImportColumns struct {
+	GeneratorID  string `json:"generatorId,omitempty" binding:"max=100" example:"Codigo planta"`
+	Date         string `json:"date,omitempty" binding:"max=100" example:"Fecha"`
+	ProductionMW string `json:"productionMw,omitempty" binding:"max=100" example:"Generacion (MW)"`
+}
+
+// ImportProfile is a saved column mapping for the files of a provider
+// @Description Column mapping and delimiter reused by the imports of a source, e.g. the monthly file of a market operator
+type ImportProfile struct {
+	ID          uuid.UUID     `json:"id" example:"550e8400-e29b-41d4-a716-446655440080"`
+	Name        string        `json:"name" example:"XM monthly generation"`
+	Source      string        `json:"source" example:"XM"`
+	Description string        `json:"description,omitempty" example:"Generacion real por planta, semicolon separated"`
+	Columns     ImportColumns `json:"columns"`
+	Delimiter   string        `json:"delimiter,omitempty" example:";"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	UpdatedAt   time.Time     `json:"updatedAt"`
+}
+
+// CreateImportProfileRequest represents the request payload for saving an import profile
+// @Description Request body for saving the column mapping of a source. delimiter defaults to the server's IMPORT_CSV_DELIMITER
+type CreateImportProfileRequest struct {
+	Name        string        `json:"name" binding:"required,max=80" example:"XM monthly generation"`
+	Source      string        `json:"source" binding:"required,max=80" example:"XM"`
+	Description string        `json:"description" binding:"max=500" example:"Generacion real por planta, semicolon separated"`
+	Columns     ImportColumns `json:"columns"`
+	Delimiter   string        `json:"delimiter" binding:"omitempty,len=1" example:";"`
+}
+
+// UpdateImportProfileRequest represents the request payload for replacing an import profile
+// @Description Request body for replacing an import profile
+type UpdateImportProfileRequest struct {
+	Name        string        `json:"name" binding:"required,max=80" example:"XM monthly generation"`
+	Source      string        `json:"source" binding:"required,max=80" example:"XM"`
+	Description string        `json:"description" binding:"max=500" example:"Generacion real por planta, semicolon separated"`
+	Columns     ImportColumns `json:"columns"`
+	Delimiter   string        `json:"delimiter" binding:"omitempty,len=1" example:";"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// ProductionEvent is a message of the live production feeds
+// @Description A production record as it was created or updated
+type ProductionEvent struct {
+	// ID increases with every event; a stream resumes after it with Last-Event-ID
+	ID         string      `json:"id" example:"1760500000000001"`
+	Type       string      `json:"type" example:"created" enums:"created,updated"`
+	Production *Production `json:"production"`
+	OccurredAt time.Time   `json:"occurredAt"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// LoginLockout is the failed-login state of a username
+// @Description Failed logins of a username and whether it is locked; usernames without account are tracked too
+type LoginLockout struct {
+	Username string `json:"username" example:"john_doe"`
+	// Failures are the failed logins in a row within the failure window
+	Failures int `json:"failures" example:"3"`
+	// Lockouts are the times the username was locked since its last
+	// successful login; each one doubles the next lock
+	Lockouts      int        `json:"lockouts" example:"1"`
+	LastFailureAt *time.Time `json:"lastFailureAt,omitempty"`
+	LastIP        string     `json:"lastIp,omitempty" example:"203.0.113.7"`
+	LockedUntil   *time.Time `json:"lockedUntil,omitempty"`
+	Locked        bool       `json:"locked" example:"true"`
+}
+
+// LoginLockoutFilter selects the usernames of a lockout listing
+type LoginLockoutFilter struct {
+	// Locked keeps the usernames locked now (true) or not locked (false)
+	Locked *bool
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// EnergyMix is the production of one day split by generator type
+// @Description Energy mix of a day from the running daily totals: production and share of each generator type, and of renewable and non-renewable types; shares are percentages of the day's total
+type EnergyMix struct {
+	Date                   string           `json:"date" example:"2025-09-03"`
+	Records                int64            `json:"records" example:"24"`
+	TotalProduction        decimal.Decimal  `json:"totalProduction" swaggertype:"number" example:"1250.5"`
+	RenewableProduction    decimal.Decimal  `json:"renewableProduction" swaggertype:"number" example:"850.3"`
+	NonRenewableProduction decimal.Decimal  `json:"nonRenewableProduction" swaggertype:"number" example:"400.2"`
+	RenewableShare         decimal.Decimal  `json:"renewableShare" swaggertype:"number" example:"68"`
+	NonRenewableShare      decimal.Decimal  `json:"nonRenewableShare" swaggertype:"number" example:"32"`
+	Types                  []*EnergyMixType `json:"types"`
+}
+
+// EnergyMixType is the production of one generator type in an energy mix
+// @Description Production of a generator type on the day; share is the percentage of the day's total
+type EnergyMixType struct {
+	TypeID       uuid.UUID       `json:"typeId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	TypeName     string          `json:"typeName" example:"Solar"`
+	IsRenewable  bool            `json:"isRenewable" example:"true"`
+	Records      int64           `json:"records" example:"12"`
+	ProductionMW decimal.Decimal `json:"productionMw" swaggertype:"number" example:"320.4"`
+	Share        decimal.Decimal `json:"share" swaggertype:"number" example:"25.62"`
+}
+
+// EnergyMixComparison compares the energy mix of a day with that of the
+// previous business day
+// @Description Energy mix of a day next to that of the previous business day of a calendar (weekends only without one), with the change in MW of the total and of each type; changePercent is null when the previous day had no production
+type EnergyMixComparison struct {
+	Calendar      string                 `json:"calendar,omitempty" example:"CO"`
+	Current       *EnergyMix             `json:"current"`
+	Previous      *EnergyMix             `json:"previous"`
+	Change        decimal.Decimal        `json:"change" swaggertype:"number" example:"-120.4"`
+	ChangePercent *decimal.Decimal       `json:"changePercent" swaggertype:"number" example:"-8.78"`
+	Types         []*EnergyMixTypeChange `json:"types"`
+}
+
+// EnergyMixTypeChange is the change of the production of a type between two days
+// @Description Production of a type on both days and its change in MW; types without production on one of the days count 0 there
+type EnergyMixTypeChange struct {
+	TypeID       uuid.UUID       `json:"typeId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	TypeName     string          `json:"typeName" example:"Solar"`
+	ProductionMW decimal.Decimal `json:"productionMw" swaggertype:"number" example:"420.5"`
+	PreviousMW   decimal.Decimal `json:"previousMw" swaggertype:"number" example:"398.1"`
+	Change       decimal.Decimal `json:"change" swaggertype:"number" example:"22.4"`
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Alert types users choose the delivery of
+const (
+	// AlertTypeFreshness is raised when production data becomes stale
+	AlertTypeFreshness = "freshness"
+	// AlertTypeSLO is raised when a route burns its error budget too fast
+	AlertTypeSLO = "slo"
+	// AlertTypeSecurity is raised to the owner of an account locked after
+	// too many failed logins
+	AlertTypeSecurity = "security"
+	// AlertTypeDataQuality is raised when the data quality scan finds new
+	// orphaned or inconsistent rows
+	AlertTypeDataQuality = "dataQuality"
+)
+
+// AlertTypes lists the alert types
+var AlertTypes = []string{AlertTypeFreshness, AlertTypeSLO, AlertTypeSecurity, AlertTypeDataQuality}
+
+// Notification deliveries
+const (
+	// DeliveryImmediate sends every alert as it is raised
+	DeliveryImmediate = "immediate"
+	// DeliveryDigest batches alerts into one email a day
+	DeliveryDigest = "digest"
+	// DeliveryMute drops the alerts
+	DeliveryMute = "mute"
+)
+
+// Notification channels
+const (
+	ChannelEmail = "email"
+	// ChannelInbox keeps alerts in the in-app inbox of the user
+	ChannelInbox = "inbox"
+)
+
+// NotificationPreference is how a user is told about an alert type
+// @Description Delivery and channels of an alert type for the current user
+type NotificationPreference struct {
+	AlertType string   `json:"alertType" example:"freshness"`
+	Delivery  string   `json:"delivery" example:"digest"`
+	Channels  []string `json:"channels" example:"email,inbox"`
+	// Default is set when the user never chose a delivery for the alert type
+	Default   bool       `json:"default" example:"false"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+// NotificationPreferenceRequest represents the request payload for choosing the delivery of an alert type
+// @Description Delivery of an alert type: immediate, digest (one email a day) or mute; channels default to email. The inbox gets every alert that is not muted at once
+type NotificationPreferenceRequest struct {
+	Delivery string   `json:"delivery" binding:"required,oneof=immediate digest mute" example:"digest"`
+	Channels []string `json:"channels,omitempty" binding:"omitempty,max=5,dive,oneof=email inbox" example:"email,inbox"`
+}
+
+// NotificationSubscriber is a user that may be told about an alert type.
+// Delivery and Channels are empty when the user never chose them.
+type NotificationSubscriber struct {
+	UserID   uuid.UUID
+	Email    string
+	Delivery string
+	Channels []string
+}
+
+// DigestItem is an alert waiting for the daily digest of a user, as
+// rendered when it was raised
+type DigestItem struct {
+	ID        int64     `json:"-"`
+	UserID    uuid.UUID `json:"-"`
+	AlertType string    `json:"alertType" example:"freshness"`
+	Subject   string    `json:"subject" example:"Production data is stale"`
+	Body      string    `json:"body"`
+	RaisedAt  time.Time `json:"raisedAt"`
+}
+
+// Notification is an alert in the in-app inbox of a user
+// @Description Alert in the inbox of the current user, as its email template rendered it
+type Notification struct {
+	ID        uuid.UUID  `json:"id" example:"550e8400-e29b-41d4-a716-446655440070"`
+	UserID    uuid.UUID  `json:"-"`
+	AlertType string     `json:"alertType" example:"freshness"`
+	Subject   string     `json:"subject" example:"Production data is stale"`
+	Body      string     `json:"body"`
+	Read      bool       `json:"read" example:"false"`
+	ReadAt    *time.Time `json:"readAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// NotificationFilter selects the inbox notifications of a user
+type NotificationFilter struct {
+	UserID    uuid.UUID
+	AlertType *string
+	// Unread keeps only the notifications that were not read
+	Unread bool
+	Limit  int
+	Offset int
+}
+
+// UnreadNotifications counts the unread notifications of a user
+// @Description Unread notifications of the current user, in total and by alert type
+type UnreadNotifications struct {
+	Unread      int            `json:"unread" example:"3"`
+	ByAlertType map[string]int `json:"byAlertType"`
+}
+
+// MarkNotificationsReadResult is the outcome of marking notifications read
+// @Description Number of notifications that were marked read
+type MarkNotificationsReadResult struct {
+	Marked int64 `json:"marked" example:"3"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Operator represents a company that owns or operates generators
+// @Description Company owning or operating energy generators
+type Operator struct {
+	ID               uuid.UUID        `json:"id" db:"id" example:"550e8400-e29b-41d4-a716-446655440020"`
+	Name             string           `json:"name" db:"name" example:"Celsia"`
+	Country          string           `json:"country,omitempty" db:"country" example:"CO"`
+	CustomAttributes CustomAttributes `json:"customAttributes,omitempty" swaggertype:"object"`
+	CreatedAt        time.Time        `json:"createdAt,omitempty" db:"created_at"`
+	UpdatedAt        time.Time        `json:"updatedAt,omitempty" db:"updated_at"`
+}
+
+// CreateOperatorRequest represents the request payload for creating an operator
+// @Description Request body for creating a new operator
+type CreateOperatorRequest struct {
+	Name             string           `json:"name" binding:"required,max=80" example:"Celsia"`
+	Country          string           `json:"country,omitempty" binding:"omitempty,len=2" example:"CO"`
+	CustomAttributes CustomAttributes `json:"customAttributes,omitempty" swaggertype:"object"`
+}
+
+// UpdateOperatorRequest represents the request payload for updating an operator
+// @Description Request body for updating an operator
+type UpdateOperatorRequest struct {
+	Name             *string          `json:"name,omitempty" binding:"omitempty,max=80" example:"Celsia"`
+	Country          *string          `json:"country,omitempty" binding:"omitempty,len=2" example:"CO"`
+	CustomAttributes CustomAttributes `json:"customAttributes,omitempty" swaggertype:"object"`
+}
+
+// OperatorMarketShare represents the share of capacity and production of an operator
+// @Description Market share of an operator (generators without operator are grouped as "Unassigned")
+type OperatorMarketShare struct {
+	OperatorID      *uuid.UUID      `json:"operatorId" example:"550e8400-e29b-41d4-a716-446655440020"`
+	OperatorName    string          `json:"operatorName" example:"Celsia"`
+	GeneratorCount  int64           `json:"generatorCount" example:"12"`
+	TotalCapacity   decimal.Decimal `json:"totalCapacity" swaggertype:"number" example:"1200.5"`
+	TotalProduction decimal.Decimal `json:"totalProduction" swaggertype:"number" example:"48250.75"`
+	ProductionShare decimal.Decimal `json:"productionShare" swaggertype:"number" example:"23.4"`
+	CapacityShare   decimal.Decimal `json:"capacityShare" swaggertype:"number" example:"19.8"`
+}
+
+// OperatorGrant gives a user write access to the generators of an operator
+// @Description Write access of a user to the generators of an operator
+type OperatorGrant struct {
+	UserID       uuid.UUID `json:"userId" example:"550e8400-e29b-41d4-a716-446655440030"`
+	OperatorID   uuid.UUID `json:"operatorId" example:"550e8400-e29b-41d4-a716-446655440020"`
+	OperatorName string    `json:"operatorName" example:"Celsia"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// GrantOperatorRequest represents the request payload for granting operator write access
+// @Description Request body for granting a user write access to an operator's generators
+type GrantOperatorRequest struct {
+	OperatorID uuid.UUID `json:"operatorId" binding:"required" example:"550e8400-e29b-41d4-a716-446655440020"`
+}
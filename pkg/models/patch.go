@@ -0,0 +1,17 @@
+package models
+
+// Fields is the set of members of the JSON body of a PATCH. An update request
+// that carries it is a partial update: only the members present are written,
+// and a member set to null clears an optional field. Requests of a PUT carry
+// none and keep their own defaults for the members left out.
+type Fields map[string]bool
+
+// Has reports whether the body had the member
+func (f Fields) Has(member string) bool {
+	return f[member]
+}
+
+// Partial reports whether the request is a PATCH
+func (f Fields) Partial() bool {
+	return f != nil
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Plant represents a facility grouping generators at one site, e.g. the
+// units of a hydro dam or the arrays of a solar park
+// @Description Energy plant (facility) grouping generators
+type Plant struct {
+	ID   uuid.UUID `json:"id" db:"id" example:"550e8400-e29b-41d4-a716-446655440040"`
+	Name string    `json:"name" db:"name" example:"Hidroituango"`
+	// Location is the place of the plant as written (municipality, department)
+	Location  string   `json:"location,omitempty" db:"location" example:"Ituango, Antioquia"`
+	Latitude  *float64 `json:"latitude,omitempty" db:"latitude" example:"7.1353"`
+	Longitude *float64 `json:"longitude,omitempty" db:"longitude" example:"-75.6474"`
+	// OwnerID is the operator owning the plant
+	OwnerID        *uuid.UUID      `json:"ownerId,omitempty" db:"owner_id" example:"550e8400-e29b-41d4-a716-446655440020"`
+	OwnerName      string          `json:"ownerName,omitempty" db:"owner_name" example:"EPM"`
+	GeneratorCount int64           `json:"generatorCount" example:"8"`
+	TotalCapacity  decimal.Decimal `json:"totalCapacity" swaggertype:"number" example:"2400"`
+	CreatedAt      time.Time       `json:"createdAt,omitempty" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updatedAt,omitempty" db:"updated_at"`
+}
+
+// PlantFilter represents the filters of the plant listing
+type PlantFilter struct {
+	OwnerID *uuid.UUID
+	Limit   int
+	Offset  int
+}
+
+// CreatePlantRequest represents the request payload for creating a plant
+// @Description Request body for creating a new plant
+type CreatePlantRequest struct {
+	Name      string     `json:"name" binding:"required,max=120" example:"Hidroituango"`
+	Location  string     `json:"location,omitempty" binding:"max=200" example:"Ituango, Antioquia"`
+	Latitude  *float64   `json:"latitude,omitempty" binding:"required_with=Longitude,omitempty,gte=-90,lte=90" example:"7.1353"`
+	Longitude *float64   `json:"longitude,omitempty" binding:"required_with=Latitude,omitempty,gte=-180,lte=180" example:"-75.6474"`
+	OwnerID   *uuid.UUID `json:"ownerId,omitempty" example:"550e8400-e29b-41d4-a716-446655440020"`
+}
+
+// UpdatePlantRequest represents the request payload for updating a plant
+// @Description Request body for updating a plant
+type UpdatePlantRequest struct {
+	Name      *string    `json:"name,omitempty" binding:"omitempty,max=120" example:"Hidroituango"`
+	Location  *string    `json:"location,omitempty" binding:"omitempty,max=200" example:"Ituango, Antioquia"`
+	Latitude  *float64   `json:"latitude,omitempty" binding:"required_with=Longitude,omitempty,gte=-90,lte=90" example:"7.1353"`
+	Longitude *float64   `json:"longitude,omitempty" binding:"required_with=Latitude,omitempty,gte=-180,lte=180" example:"-75.6474"`
+	OwnerID   *uuid.UUID `json:"ownerId,omitempty" example:"550e8400-e29b-41d4-a716-446655440020"`
+}
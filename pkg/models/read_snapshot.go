@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReadSnapshot pins the data listings read through it to the moment it was
+// opened, so the pages of a paginated walk are mutually consistent
+// @Description A repeatable-read snapshot of the database; pass its id as readSnapshot to the production listing until it expires
+type ReadSnapshot struct {
+	ID        uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
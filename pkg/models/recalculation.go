@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// RecalculationRequest represents the request payload for rescaling production records
+// @Description Records to change (generator, type, inclusive date range and source) and the formula new = productionMw * factor + offset
+type RecalculationRequest struct {
+	GeneratorID *uuid.UUID       `json:"generatorId,omitempty" example:"550e8400-e29b-41d4-a716-446655440001"`
+	TypeID      *uuid.UUID       `json:"typeId,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+	StartDate   string           `json:"startDate" binding:"required,datetime=2006-01-02" example:"2025-09-01"`
+	EndDate     string           `json:"endDate" binding:"required,datetime=2006-01-02" example:"2025-09-30"`
+	Source      *string          `json:"source,omitempty" binding:"omitempty,oneof=manual api import external telemetry" example:"import"`
+	Factor      *decimal.Decimal `json:"factor,omitempty" binding:"omitempty,gte=0" swaggertype:"number" example:"0.001"`
+	Offset      *decimal.Decimal `json:"offset,omitempty" swaggertype:"number" example:"0"`
+	Reason      string           `json:"reason" binding:"required,max=400" example:"Hydro values of September were loaded in kW"`
+}
+
+// Recalculation is a run of a recalculation, or its preview
+// @Description Recalculation with the number of records it changes and their total before and after. Previews list the first changes and are not kept
+type Recalculation struct {
+	ID               *uuid.UUID             `json:"id,omitempty" example:"550e8400-e29b-41d4-a716-446655440060"`
+	GeneratorID      *uuid.UUID             `json:"generatorId,omitempty" example:"550e8400-e29b-41d4-a716-446655440001"`
+	TypeID           *uuid.UUID             `json:"typeId,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+	StartDate        string                 `json:"startDate" example:"2025-09-01"`
+	EndDate          string                 `json:"endDate" example:"2025-09-30"`
+	Source           *string                `json:"source,omitempty" example:"import"`
+	Factor           decimal.Decimal        `json:"factor" swaggertype:"number" example:"0.001"`
+	Offset           decimal.Decimal        `json:"offset" swaggertype:"number" example:"0"`
+	Reason           string                 `json:"reason" example:"Hydro values of September were loaded in kW"`
+	UserID           *uuid.UUID             `json:"userId,omitempty" example:"550e8400-e29b-41d4-a716-446655440070"`
+	Applied          bool                   `json:"applied" example:"true"`
+	Records          int                    `json:"records" example:"240"`
+	PublishedRecords int                    `json:"publishedRecords" example:"0"`
+	PreviousTotalMW  decimal.Decimal        `json:"previousTotalMw" swaggertype:"number" example:"1830000"`
+	NewTotalMW       decimal.Decimal        `json:"newTotalMw" swaggertype:"number" example:"1830"`
+	Changes          []*RecalculationChange `json:"changes,omitempty"`
+	CreatedAt        *time.Time             `json:"createdAt,omitempty"`
+}
+
+// RecalculationChange is the change a recalculation makes to one production record
+// @Description Value of a production record before and after a recalculation
+type RecalculationChange struct {
+	ProductionID uuid.UUID       `json:"productionId" example:"550e8400-e29b-41d4-a716-446655440002"`
+	GeneratorID  uuid.UUID       `json:"generatorId" example:"550e8400-e29b-41d4-a716-446655440001"`
+	Date         string          `json:"date" example:"2025-09-03"`
+	PreviousMW   decimal.Decimal `json:"previousMw" swaggertype:"number" example:"85300"`
+	NewMW        decimal.Decimal `json:"newMw" swaggertype:"number" example:"85.3"`
+}
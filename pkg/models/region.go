@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Region represents a geographic region generators are assigned to, e.g. a
+// department, for regional energy mix reports
+// @Description Geographic region grouping generators; bbox is [minLng, minLat, maxLng, maxLat] as in GeoJSON
+type Region struct {
+	ID uuid.UUID `json:"id" db:"id" example:"550e8400-e29b-41d4-a716-446655440050"`
+	// Code is the short upper-case code of the region, e.g. a DANE code
+	Code string `json:"code" db:"code" example:"ANT"`
+	Name string `json:"name" db:"name" example:"Antioquia"`
+	// BBox is the bounding box of the region, in the order of the GeoJSON
+	// bbox member
+	BBox           []float64       `json:"bbox,omitempty" example:"-77.13,5.42,-73.88,8.88"`
+	GeneratorCount int64           `json:"generatorCount" example:"42"`
+	TotalCapacity  decimal.Decimal `json:"totalCapacity" swaggertype:"number" example:"6120.5"`
+	CreatedAt      time.Time       `json:"createdAt,omitempty" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updatedAt,omitempty" db:"updated_at"`
+}
+
+// RegionFilter represents the filters of the region listing
+type RegionFilter struct {
+	Limit  int
+	Offset int
+}
+
+// CreateRegionRequest represents the request payload for creating a region
+// @Description Request body for creating a new region; bbox is [minLng, minLat, maxLng, maxLat]
+type CreateRegionRequest struct {
+	Code string    `json:"code" binding:"required,max=10,alphanum" example:"ANT"`
+	Name string    `json:"name" binding:"required,max=120" example:"Antioquia"`
+	BBox []float64 `json:"bbox,omitempty" binding:"omitempty,len=4" example:"-77.13,5.42,-73.88,8.88"`
+}
+
+// UpdateRegionRequest represents the request payload for updating a region
+// @Description Request body for updating a region
+type UpdateRegionRequest struct {
+	Code *string   `json:"code,omitempty" binding:"omitempty,max=10,alphanum" example:"ANT"`
+	Name *string   `json:"name,omitempty" binding:"omitempty,max=120" example:"Antioquia"`
+	BBox []float64 `json:"bbox,omitempty" binding:"omitempty,len=4" example:"-77.13,5.42,-73.88,8.88"`
+}
+
+// RegionMix is the renewable and non-renewable production of a region over
+// a date range
+// @Description Production of the generators of a region with the renewable and non-renewable shares in percent; generators without region are reported as "Unassigned"
+type RegionMix struct {
+	RegionID               *uuid.UUID      `json:"regionId,omitempty" example:"550e8400-e29b-41d4-a716-446655440050"`
+	RegionCode             string          `json:"regionCode,omitempty" example:"ANT"`
+	RegionName             string          `json:"regionName" example:"Antioquia"`
+	GeneratorCount         int64           `json:"generatorCount" example:"42"`
+	Records                int64           `json:"records" example:"1260"`
+	TotalProduction        decimal.Decimal `json:"totalProduction" swaggertype:"number" example:"125050.5"`
+	RenewableProduction    decimal.Decimal `json:"renewableProduction" swaggertype:"number" example:"98040.2"`
+	NonRenewableProduction decimal.Decimal `json:"nonRenewableProduction" swaggertype:"number" example:"27010.3"`
+	RenewableShare         decimal.Decimal `json:"renewableShare" swaggertype:"number" example:"78.4"`
+	NonRenewableShare      decimal.Decimal `json:"nonRenewableShare" swaggertype:"number" example:"21.6"`
+}
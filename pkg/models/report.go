@@ -0,0 +1,135 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Report kinds
+const (
+	// ReportKindProductions lists the production records of the period
+	ReportKindProductions = "productions"
+	// ReportKindCrosstab is the energy matrix of the period (see GET /analytics/crosstab)
+	ReportKindCrosstab = "crosstab"
+)
+
+// Report formats
+const (
+	ReportFormatCSV = "csv"
+	// ReportFormatPDF is a formatted bulletin with key figures, charts and the report table
+	ReportFormatPDF = "pdf"
+)
+
+// Report run statuses
+const (
+	ReportRunRunning   = "running"
+	ReportRunSucceeded = "succeeded"
+	ReportRunFailed    = "failed"
+)
+
+// Report is a saved report definition, run on its schedule and delivered by email or webhook
+// @Description Saved report: what to compute, in which format, when and where to deliver it
+type Report struct {
+	ID         uuid.UUID      `json:"id" example:"550e8400-e29b-41d4-a716-446655440060"`
+	Name       string         `json:"name" example:"Monthly bulletin"`
+	Kind       string         `json:"kind" example:"crosstab"`
+	Format     string         `json:"format" example:"pdf"`
+	Filters    ReportFilters  `json:"filters"`
+	Schedule   ReportSchedule `json:"schedule"`
+	Delivery   ReportDelivery `json:"delivery"`
+	TemplateID *uuid.UUID     `json:"templateId,omitempty" example:"550e8400-e29b-41d4-a716-446655440070"`
+	Enabled    bool           `json:"enabled" example:"true"`
+	NextRunAt  *time.Time     `json:"nextRunAt,omitempty"`
+	LastRunAt  *time.Time     `json:"lastRunAt,omitempty"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
+}
+
+// ReportFilters selects the data of a report. Without startDate/endDate each
+// run covers the period before it (the previous day, week or month).
+// @Description Data selection of a report; rows/cols/value/metric apply to crosstab reports
+type ReportFilters struct {
+	GeneratorID *uuid.UUID `json:"generatorId,omitempty" example:"550e8400-e29b-41d4-a716-446655440001"`
+	Source      string     `json:"source,omitempty" binding:"omitempty,oneof=manual api import external telemetry" example:"external"`
+	StartDate   string     `json:"startDate,omitempty" binding:"omitempty,datetime=2006-01-02" example:"2025-09-01"`
+	EndDate     string     `json:"endDate,omitempty" binding:"omitempty,datetime=2006-01-02" example:"2025-09-30"`
+	Rows        string     `json:"rows,omitempty" example:"type"`
+	Cols        string     `json:"cols,omitempty" example:"day"`
+	Value       string     `json:"value,omitempty" example:"sum"`
+	Metric      string     `json:"metric,omitempty" example:"sum(productionMw)/sum(capacity)"`
+}
+
+// ReportSchedule says when a report runs; without frequency it only runs on demand
+// @Description Schedule of a report. day is the weekday (0 = Sunday) for weekly reports and the day of the month (1-28) for monthly ones. With a calendar (CO, or CO-ANT for a region) runs falling on weekends or holidays move to the next business day, and daily reports cover the days since the previous business day
+type ReportSchedule struct {
+	Frequency string `json:"frequency,omitempty" binding:"omitempty,oneof=daily weekly monthly" example:"monthly"`
+	Day       int    `json:"day,omitempty" binding:"gte=0,lte=28" example:"1"`
+	Hour      int    `json:"hour" binding:"gte=0,lte=23" example:"6"`
+	Timezone  string `json:"timezone,omitempty" example:"America/Bogota"`
+	Calendar  string `json:"calendar,omitempty" binding:"omitempty,max=13" example:"CO"`
+}
+
+// ReportDelivery says where the output of a run goes
+// @Description Recipients of a report; alertEmails are told about failed runs
+type ReportDelivery struct {
+	Emails      []string `json:"emails,omitempty" binding:"omitempty,dive,email" example:"bulletin@example.com"`
+	WebhookURL  string   `json:"webhookUrl,omitempty" binding:"omitempty,url" example:"https://hooks.example.com/reports"`
+	AlertEmails []string `json:"alertEmails,omitempty" binding:"omitempty,dive,email" example:"ops@example.com"`
+}
+
+// ReportRequest represents the request payload for creating or replacing a report
+// @Description Request body for creating or replacing a saved report
+type ReportRequest struct {
+	Name       string         `json:"name" binding:"required,max=120" example:"Monthly bulletin"`
+	Kind       string         `json:"kind" binding:"required,oneof=productions crosstab" example:"crosstab"`
+	Format     string         `json:"format" binding:"required,oneof=csv pdf" example:"pdf"`
+	Filters    ReportFilters  `json:"filters"`
+	Schedule   ReportSchedule `json:"schedule"`
+	Delivery   ReportDelivery `json:"delivery"`
+	TemplateID *uuid.UUID     `json:"templateId,omitempty" example:"550e8400-e29b-41d4-a716-446655440070"`
+	Enabled    *bool          `json:"enabled,omitempty" example:"true"`
+}
+
+// ReportRun records one execution of a report
+// @Description Execution of a report with its outcome
+type ReportRun struct {
+	ID          uuid.UUID  `json:"id" example:"550e8400-e29b-41d4-a716-446655440061"`
+	ReportID    uuid.UUID  `json:"reportId" example:"550e8400-e29b-41d4-a716-446655440060"`
+	Trigger     string     `json:"trigger" example:"schedule"`
+	Status      string     `json:"status" example:"succeeded"`
+	PeriodStart string     `json:"periodStart,omitempty" example:"2025-09-01"`
+	PeriodEnd   string     `json:"periodEnd,omitempty" example:"2025-09-30"`
+	Rows        int64      `json:"rows" example:"12"`
+	Bytes       int64      `json:"bytes" example:"2048"`
+	Error       string     `json:"error,omitempty" example:"smtp: connection refused"`
+	StartedAt   time.Time  `json:"startedAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+}
+
+// WebhookDelivery records one POST of a report to its webhook. The ID is
+// also the nonce the request was signed with.
+// @Description Webhook request of a report with its outcome; redeliveryOf is the delivery it repeats
+type WebhookDelivery struct {
+	ID           uuid.UUID  `json:"id" example:"550e8400-e29b-41d4-a716-446655440062"`
+	ReportID     uuid.UUID  `json:"reportId" example:"550e8400-e29b-41d4-a716-446655440060"`
+	RunID        *uuid.UUID `json:"runId,omitempty" example:"550e8400-e29b-41d4-a716-446655440061"`
+	RedeliveryOf *uuid.UUID `json:"redeliveryOf,omitempty" example:"550e8400-e29b-41d4-a716-446655440063"`
+	URL          string     `json:"url" example:"https://hooks.example.com/reports"`
+	PeriodStart  string     `json:"periodStart,omitempty" example:"2025-09-01"`
+	PeriodEnd    string     `json:"periodEnd,omitempty" example:"2025-09-30"`
+	StatusCode   *int       `json:"statusCode,omitempty" example:"200"`
+	Succeeded    bool       `json:"succeeded" example:"true"`
+	Error        string     `json:"error,omitempty" example:"unexpected status 502"`
+	Bytes        int64      `json:"bytes" example:"2048"`
+	DurationMs   int64      `json:"durationMs" example:"184"`
+	DeliveredAt  time.Time  `json:"deliveredAt"`
+}
+
+// WebhookSecret is the secret a report signs its webhook requests with
+// @Description Signing secret of a report webhook; it is only shown when rotated
+type WebhookSecret struct {
+	ReportID  uuid.UUID `json:"reportId" example:"550e8400-e29b-41d4-a716-446655440060"`
+	Secret    string    `json:"secret" example:"whsec_3f9a..."`
+	RotatedAt time.Time `json:"rotatedAt"`
+}
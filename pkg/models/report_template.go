@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Report template section types
+const (
+	// ReportSectionText is free text from the section body
+	ReportSectionText = "text"
+	// ReportSectionKeyFigures shows total, renewable and non-renewable production and the renewable share
+	ReportSectionKeyFigures = "keyFigures"
+	// ReportSectionDailyChart charts daily production stacked by renewable status
+	ReportSectionDailyChart = "dailyChart"
+	// ReportSectionOperatorChart charts the production of the largest operators
+	ReportSectionOperatorChart = "operatorChart"
+	// ReportSectionCrosstabChart charts the row totals of crosstab reports
+	ReportSectionCrosstabChart = "crosstabChart"
+	// ReportSectionTable is the report table
+	ReportSectionTable = "table"
+)
+
+// ReportTemplate is the layout of generated bulletins: branding, the sections
+// in order and the language of their texts. Every text is a Go text/template
+// evaluated with the report data.
+// @Description Layout of report bulletins and emails; texts are Go templates over the report data
+type ReportTemplate struct {
+	ID          uuid.UUID         `json:"id" example:"550e8400-e29b-41d4-a716-446655440070"`
+	Name        string            `json:"name" example:"Ministry bulletin"`
+	Description string            `json:"description,omitempty" example:"Spanish monthly bulletin with the ministry branding"`
+	Language    string            `json:"language" example:"es"`
+	Branding    ReportBranding    `json:"branding"`
+	Sections    []ReportSection   `json:"sections"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Subject     string            `json:"subject,omitempty" example:"{{.Report.Name}} ({{.Period}})"`
+	EmailBody   string            `json:"emailBody,omitempty" example:"Adjunto el boletín de {{.Period}}."`
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+}
+
+// ReportBranding is the look of a bulletin
+// @Description Branding of a bulletin; logo is a base64 PNG or JPEG image
+type ReportBranding struct {
+	Organization string `json:"organization,omitempty" example:"Ministerio de Minas y Energía"`
+	Title        string `json:"title,omitempty" example:"Boletín {{.Report.Name}}"`
+	Footer       string `json:"footer,omitempty" example:"{{.Organization}} · {{.Period}}"`
+	PrimaryColor string `json:"primaryColor,omitempty" binding:"omitempty,hexcolor" example:"#1F4E79"`
+	AccentColor  string `json:"accentColor,omitempty" binding:"omitempty,hexcolor" example:"#E6ECF4"`
+	Logo         string `json:"logo,omitempty" binding:"omitempty,base64" example:"iVBORw0KGgo="`
+}
+
+// ReportSection is one block of a bulletin. Without title the section gets
+// the default title of its type in the template language.
+// @Description Section of a bulletin; body is the text of text sections and the note under tables
+type ReportSection struct {
+	Type  string `json:"type" binding:"required,oneof=text keyFigures dailyChart operatorChart crosstabChart table" example:"text"`
+	Title string `json:"title,omitempty" example:"Resumen"`
+	Body  string `json:"body,omitempty" example:"La participación renovable fue {{.Figures.Share}}."`
+}
+
+// ReportTemplateRequest represents the request payload for creating or replacing a report template
+// @Description Request body for creating or replacing a report template; sections default to the standard bulletin
+type ReportTemplateRequest struct {
+	Name        string            `json:"name" binding:"required,max=120" example:"Ministry bulletin"`
+	Description string            `json:"description,omitempty" binding:"max=500" example:"Spanish monthly bulletin with the ministry branding"`
+	Language    string            `json:"language,omitempty" binding:"omitempty,oneof=en es" example:"es"`
+	Branding    ReportBranding    `json:"branding"`
+	Sections    []ReportSection   `json:"sections,omitempty" binding:"max=20,dive"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Subject     string            `json:"subject,omitempty" binding:"max=500" example:"{{.Report.Name}} ({{.Period}})"`
+	EmailBody   string            `json:"emailBody,omitempty" binding:"max=10000" example:"Adjunto el boletín de {{.Period}}."`
+}
@@ -0,0 +1,40 @@
+package models
+
+// DatabaseSchema describes the tables of the API schema and how they relate
+// @Description Data model introspected from information_schema: tables with their columns and the foreign keys between them
+type DatabaseSchema struct {
+	Schema        string                `json:"schema" example:"core"`
+	Tables        []*SchemaTable        `json:"tables"`
+	Relationships []*SchemaRelationship `json:"relationships"`
+}
+
+// SchemaTable is a table with its columns in definition order
+// @Description Table of the data model
+type SchemaTable struct {
+	Name       string          `json:"name" example:"generators"`
+	Comment    string          `json:"comment,omitempty" example:"Generation units and their installed capacity"`
+	PrimaryKey []string        `json:"primaryKey" example:"id"`
+	Columns    []*SchemaColumn `json:"columns"`
+}
+
+// SchemaColumn is a table column
+// @Description Column of a table; unique is set for single-column unique constraints
+type SchemaColumn struct {
+	Name     string  `json:"name" example:"capacity"`
+	Type     string  `json:"type" example:"numeric(14,4)"`
+	Nullable bool    `json:"nullable" example:"false"`
+	Default  *string `json:"default,omitempty" example:"now()"`
+	Unique   bool    `json:"unique,omitempty" example:"false"`
+	Comment  string  `json:"comment,omitempty" example:"Installed capacity in MW"`
+}
+
+// SchemaRelationship is a foreign key from columns of one table to another
+// @Description Foreign key; columns and referencedColumns pair up by position
+type SchemaRelationship struct {
+	Name              string   `json:"name" example:"fk_generator"`
+	Table             string   `json:"table" example:"productions"`
+	Columns           []string `json:"columns" example:"generator_id"`
+	ReferencedTable   string   `json:"referencedTable" example:"generators"`
+	ReferencedColumns []string `json:"referencedColumns" example:"id"`
+	OnDelete          string   `json:"onDelete" example:"CASCADE"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Snapshot is an immutable, published copy of one month of production data
+// @Description Published monthly snapshot; the hash covers every frozen record
+type Snapshot struct {
+	ID              uuid.UUID       `json:"id" example:"550e8400-e29b-41d4-a716-446655440040"`
+	Month           string          `json:"month" example:"2025-09"`
+	PublishedAt     time.Time       `json:"publishedAt"`
+	RecordCount     int64           `json:"recordCount" example:"8640"`
+	TotalProduction decimal.Decimal `json:"totalProduction" swaggertype:"number" example:"1250000.5"`
+	Hash            string          `json:"hash" example:"3a7bd3e2360a3d29eea436fcfb7e44c735d117c42d1c1835420b6b9942dd4f1b"`
+}
+
+// SnapshotProduction is a production record as frozen in a snapshot
+// @Description Production record frozen in a published snapshot
+type SnapshotProduction struct {
+	ProductionID uuid.UUID       `json:"productionId" example:"550e8400-e29b-41d4-a716-446655440002"`
+	GeneratorID  uuid.UUID       `json:"generatorId" example:"550e8400-e29b-41d4-a716-446655440001"`
+	Date         string          `json:"date" example:"2025-09-03"`
+	ProductionMW decimal.Decimal `json:"productionMw" swaggertype:"number" example:"85.3"`
+	Source       string          `json:"source" example:"external"`
+	SourceRef    string          `json:"sourceRef,omitempty" example:"XM bulletin 2025-09"`
+}
+
+// SnapshotDetail is a snapshot together with its frozen records
+// @Description Published snapshot with its frozen records
+type SnapshotDetail struct {
+	Snapshot
+	Productions []*SnapshotProduction `json:"productions"`
+}
+
+// SnapshotVerification compares the recorded hash with one recomputed from the frozen records
+// @Description Integrity check of a published snapshot
+type SnapshotVerification struct {
+	Month        string `json:"month" example:"2025-09"`
+	Hash         string `json:"hash" example:"3a7bd3e2360a3d29eea436fcfb7e44c735d117c42d1c1835420b6b9942dd4f1b"`
+	ComputedHash string `json:"computedHash" example:"3a7bd3e2360a3d29eea436fcfb7e44c735d117c42d1c1835420b6b9942dd4f1b"`
+	Valid        bool   `json:"valid" example:"true"`
+}
+
+// PublishSnapshotRequest represents the request payload for publishing a month
+// @Description Request body for publishing a month of production data
+type PublishSnapshotRequest struct {
+	Month string `json:"month" binding:"required" example:"2025-09"`
+}
+
+// ProductionCorrection records a change made to a production record after the fact
+// @Description Correction applied to a production record (the only way to change published months)
+type ProductionCorrection struct {
+	ID           uuid.UUID       `json:"id" example:"550e8400-e29b-41d4-a716-446655440050"`
+	ProductionID uuid.UUID       `json:"productionId" example:"550e8400-e29b-41d4-a716-446655440002"`
+	SnapshotID   *uuid.UUID      `json:"snapshotId,omitempty" example:"550e8400-e29b-41d4-a716-446655440040"`
+	PreviousMW   decimal.Decimal `json:"previousMw" swaggertype:"number" example:"85.3"`
+	CorrectedMW  decimal.Decimal `json:"correctedMw" swaggertype:"number" example:"83.1"`
+	Reason       string          `json:"reason" example:"Meter recalibration reported by the operator"`
+	CreatedAt    time.Time       `json:"createdAt"`
+}
+
+// CreateCorrectionRequest represents the request payload for correcting a production record
+// @Description Request body for correcting a production record
+type CreateCorrectionRequest struct {
+	ProductionMW *decimal.Decimal `json:"productionMw" binding:"required,gte=0" swaggertype:"number" example:"83.1"`
+	Reason       string           `json:"reason" binding:"required,max=500" example:"Meter recalibration reported by the operator"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Submission cadences
+const (
+	CadenceDaily   = "daily"
+	CadenceWeekly  = "weekly"
+	CadenceMonthly = "monthly"
+)
+
+// Submission calendar scopes
+const (
+	CadenceScopeType      = "type"
+	CadenceScopeGenerator = "generator"
+)
+
+// CadenceDays is the number of days between expected submissions of a cadence
+var CadenceDays = map[string]int{
+	CadenceDaily:   1,
+	CadenceWeekly:  7,
+	CadenceMonthly: 31,
+}
+
+// SubmissionCadence is the reporting cadence expected from the generators of
+// a type, or from one generator (which overrides its type)
+// @Description Expected reporting cadence of a type or a generator; generators without one report daily
+type SubmissionCadence struct {
+	Scope   string    `json:"scope" example:"type"`
+	ID      uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Name    string    `json:"name" example:"Solar"`
+	Cadence string    `json:"cadence" example:"weekly"`
+	// Generators is the number of generators following this entry
+	Generators int       `json:"generators" example:"12"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// SubmissionCadenceRequest represents the request payload for setting a submission cadence
+// @Description Request body for setting the expected reporting cadence of a type or generator
+type SubmissionCadenceRequest struct {
+	Cadence string `json:"cadence" binding:"required,oneof=daily weekly monthly" example:"weekly"`
+}
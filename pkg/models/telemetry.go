@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// How a telemetry reading updates the production of its day
+const (
+	// TelemetryModeAdd adds the reading to the day's production, for meters
+	// sending the energy of each interval
+	TelemetryModeAdd = "add"
+	// TelemetryModeSet replaces the day's production, for meters sending a
+	// running total of the day
+	TelemetryModeSet = "set"
+)
+
+// TelemetryReading is a meter message mapped to the production of a generator on a day
+type TelemetryReading struct {
+	GeneratorID uuid.UUID
+	Date        string
+	Value       decimal.Decimal
+	Mode        string
+	// Topic the message was published on; stored as the source reference
+	Topic string
+}
+
+// TelemetryStatus is the state of the telemetry bridge
+// @Description MQTT bridge connection and message counters since the server started; HTTP telemetry messages are counted too
+type TelemetryStatus struct {
+	Broker        string     `json:"broker,omitempty" example:"ssl://broker.example.com:8883"`
+	Connected     bool       `json:"connected" example:"true"`
+	Topics        []string   `json:"topics"`
+	Mappings      int        `json:"mappings" example:"2"`
+	Received      int64      `json:"received" example:"1440"`
+	Recorded      int64      `json:"recorded" example:"1436"`
+	Failed        int64      `json:"failed" example:"4"`
+	LastMessageAt *time.Time `json:"lastMessageAt,omitempty"`
+	LastError     string     `json:"lastError,omitempty" example:"plants/7/meter: no generator for device \"M-0042\""`
+	LastErrorAt   *time.Time `json:"lastErrorAt,omitempty"`
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Buckets of a time series; weeks start on Monday
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+	BucketYear  = "year"
+)
+
+// TimeSeriesBuckets lists the valid groupBy values of a time series
+var TimeSeriesBuckets = []string{BucketDay, BucketWeek, BucketMonth, BucketYear}
+
+// Metrics of a time series: one total series, a renewable and a
+// non-renewable series, or a series per generator type
+const (
+	TimeSeriesTotal     = "total"
+	TimeSeriesRenewable = "renewable"
+	TimeSeriesByType    = "byType"
+)
+
+// TimeSeriesMetrics lists the valid metric values of a time series
+var TimeSeriesMetrics = []string{TimeSeriesTotal, TimeSeriesRenewable, TimeSeriesByType}
+
+// TimeSeriesQuery selects the bucketing and series of a time series
+type TimeSeriesQuery struct {
+	GroupBy   string
+	Metric    string
+	StartDate *string
+	EndDate   *string
+	// AsOf restates the records to what they were at that moment
+	AsOf *time.Time
+}
+
+// TimeSeries is production summed per time bucket, ready for charting
+// @Description Production per bucket. buckets are the first days of the buckets, oldest first and without gaps; values[i] of every series is the production of buckets[i], 0 without records
+type TimeSeries struct {
+	GroupBy string            `json:"groupBy" example:"month"`
+	Metric  string            `json:"metric" example:"renewable"`
+	Buckets []string          `json:"buckets" example:"2025-08-01,2025-09-01"`
+	Series  []*TimeSeriesLine `json:"series"`
+}
+
+// TimeSeriesLine is one series of a time series
+// @Description Production of one series per bucket; key is total, renewable, nonRenewable or the type ID
+type TimeSeriesLine struct {
+	Key    string            `json:"key" example:"renewable"`
+	Name   string            `json:"name" example:"Renewable"`
+	TypeID *uuid.UUID        `json:"typeId,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Values []decimal.Decimal `json:"values" swaggertype:"array,number" example:"18250.5,20110.25"`
+	Total  decimal.Decimal   `json:"total" swaggertype:"number" example:"38360.75"`
+}
+
+// Smoothing of a capacity factor trend
+const (
+	SmoothingNone = "none"
+	// SmoothingSMA is the simple moving average of the last window periods
+	SmoothingSMA = "sma"
+	// SmoothingEMA is the exponential moving average with a span of window
+	// periods (alpha = 2 / (window + 1))
+	SmoothingEMA = "ema"
+)
+
+// Smoothings lists the valid smoothing values of a capacity factor trend
+var Smoothings = []string{SmoothingNone, SmoothingSMA, SmoothingEMA}
+
+// CapacityFactorQuery selects the buckets and smoothing of a capacity factor trend
+type CapacityFactorQuery struct {
+	GroupBy   string
+	Smoothing string
+	Window    int
+	StartDate *string
+	EndDate   *string
+}
+
+// CapacityFactorTrend is the capacity factor of a generator per period
+// @Description Capacity factor per period: the average production of the days with records as a percentage of the capacity, with its moving average when smoothing is set
+type CapacityFactorTrend struct {
+	GeneratorID uuid.UUID              `json:"generatorId" example:"550e8400-e29b-41d4-a716-446655440001"`
+	Capacity    decimal.Decimal        `json:"capacity" swaggertype:"number" example:"100.5"`
+	GroupBy     string                 `json:"groupBy" example:"month"`
+	Smoothing   string                 `json:"smoothing" example:"sma"`
+	Window      int                    `json:"window,omitempty" example:"3"`
+	Points      []*CapacityFactorPoint `json:"points"`
+}
+
+// CapacityFactorPoint is the capacity factor of one period
+// @Description Capacity factor of a period; null for periods without records (or a generator without capacity), and smoothed null until a value exists
+type CapacityFactorPoint struct {
+	Bucket         string           `json:"bucket" example:"2025-09-01"`
+	Days           int64            `json:"days" example:"30"`
+	Production     decimal.Decimal  `json:"production" swaggertype:"number" example:"2140.2"`
+	CapacityFactor *decimal.Decimal `json:"capacityFactor" swaggertype:"number" example:"70.98"`
+	Smoothed       *decimal.Decimal `json:"smoothed,omitempty" swaggertype:"number" example:"72.4"`
+}
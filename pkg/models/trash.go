@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Resources that go to the trash when deleted
+const (
+	TrashType           = "type"
+	TrashOperator       = "operator"
+	TrashGenerator      = "generator"
+	TrashProduction     = "production"
+	TrashReport         = "report"
+	TrashReportTemplate = "report_template"
+)
+
+// TrashItem is a deleted resource that can still be restored
+// @Description Deleted resource kept with the records its deletion cascaded to until it is purged
+type TrashItem struct {
+	ID         uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440070"`
+	Resource   string    `json:"resource" example:"generator"`
+	ResourceID uuid.UUID `json:"resourceId" example:"550e8400-e29b-41d4-a716-446655440001"`
+	Label      string    `json:"label" example:"Solar generator, 90.0000 MW"`
+	// Rows counts the resource and the records deleted with it
+	Rows      int       `json:"rows" example:"366"`
+	DeletedAt time.Time `json:"deletedAt"`
+	PurgeAt   time.Time `json:"purgeAt"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TwoFactor is the TOTP enrollment of a user. It is pending from enrollment
+// until a first code confirms it, and only enabled enrollments are asked for
+// on login.
+type TwoFactor struct {
+	UserID uuid.UUID
+	// Secret is the base32 TOTP key shared with the authenticator app
+	Secret    string
+	EnabledAt *time.Time
+	// LastStep is the time step of the last code accepted; codes of that
+	// step or earlier are not accepted again
+	LastStep        int64
+	BackupCodesLeft int
+	CreatedAt       time.Time
+}
+
+// Enabled reports whether the enrollment was confirmed
+func (t *TwoFactor) Enabled() bool {
+	return t != nil && t.EnabledAt != nil
+}
+
+// TwoFactorStatus is the two-factor authentication state of the current user
+// @Description Two-factor authentication of the current user; required is set when a role of the user needs it for writes
+type TwoFactorStatus struct {
+	Enabled         bool       `json:"enabled" example:"true"`
+	Pending         bool       `json:"pending" example:"false"`
+	EnabledAt       *time.Time `json:"enabledAt,omitempty"`
+	BackupCodesLeft int        `json:"backupCodesLeft" example:"10"`
+	Required        bool       `json:"required" example:"true"`
+}
+
+// TwoFactorEnrollment is a new TOTP secret to add to an authenticator app
+// @Description TOTP secret of a pending enrollment; render provisioningUri as a QR code or type secret into the app, then confirm with a code
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	ProvisioningURI string `json:"provisioningUri" example:"otpauth://totp/TADB:john_doe?algorithm=SHA1&digits=6&issuer=TADB&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	Issuer          string `json:"issuer" example:"TADB"`
+	Account         string `json:"account" example:"john_doe"`
+	Algorithm       string `json:"algorithm" example:"SHA1"`
+	Digits          int    `json:"digits" example:"6"`
+	Period          int    `json:"period" example:"30"`
+}
+
+// TwoFactorCodeRequest represents a request carrying a two-factor code
+// @Description A code of the authenticator app, or for some requests a backup code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required,max=20" example:"492039"`
+}
+
+// TwoFactorEnabled answers the confirmation of an enrollment
+// @Description Access token carrying the second factor, and the backup codes, shown only this once
+type TwoFactorEnabled struct {
+	TokenResponse
+	BackupCodes []string `json:"backupCodes" example:"k7dq-9m2x"`
+}
+
+// BackupCodes are new backup codes, shown only once
+// @Description Single-use backup codes replacing the previous ones
+type BackupCodes struct {
+	BackupCodes []string `json:"backupCodes" example:"k7dq-9m2x"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Languages types can be translated to
+const (
+	LanguageSpanish = "es"
+	LanguageEnglish = "en"
+)
+
+// Languages lists the supported languages in order of preference
+var Languages = []string{LanguageSpanish, LanguageEnglish}
+
+// TypeTranslation is the name and description of a type in a language
+// @Description Translated name and description of an energy generator type
+type TypeTranslation struct {
+	TypeID      uuid.UUID `json:"typeId" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Language    string    `json:"language" example:"en"`
+	Name        string    `json:"name" example:"Solar"`
+	Description string    `json:"description" example:"Solar photovoltaic panels"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// TypeTranslationRequest represents the request payload for setting a type translation
+// @Description Request body for setting the name and description of a type in a language
+type TypeTranslationRequest struct {
+	Name        string `json:"name" binding:"required,max=20" example:"Solar"`
+	Description string `json:"description" binding:"required,max=80" example:"Solar photovoltaic panels"`
+}
+
+// Localize replaces the name and description of t with tr; a nil tr leaves them
+func (t *Type) Localize(tr *TypeTranslation) {
+	if tr == nil {
+		return
+	}
+	t.Name, t.Description = tr.Name, tr.Description
+}
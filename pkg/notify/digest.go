@@ -0,0 +1,123 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/reports"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// Digest is the data of the alert-digest template: the alerts queued for a
+// user, oldest first
+type Digest struct {
+	Date  string               `json:"date"`
+	Items []*models.DigestItem `json:"items"`
+}
+
+// DigestCompiler batches the alerts queued for digest users into one email
+// a day per user
+type DigestCompiler struct {
+	repo      database.Repository
+	templates *Templates
+	mailer    reports.Mailer
+	cfg       *Config
+	now       func() time.Time
+}
+
+// NewDigestCompiler creates a new DigestCompiler
+func NewDigestCompiler(repo database.Repository, templates *Templates, mailer reports.Mailer, cfg *Config) *DigestCompiler {
+	return &DigestCompiler{repo: repo, templates: templates, mailer: mailer, cfg: cfg, now: time.Now}
+}
+
+// Compile emails every user with queued alerts a digest of them and returns
+// how many digests were sent. The alerts of a digest are dropped once it is
+// sent; the others wait for the next run.
+func (d *DigestCompiler) Compile(ctx context.Context) (int, error) {
+	items, err := d.repo.GetDigestItems(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var users []uuid.UUID
+	byUser := map[uuid.UUID][]*models.DigestItem{}
+	for _, item := range items {
+		if _, ok := byUser[item.UserID]; !ok {
+			users = append(users, item.UserID)
+		}
+		byUser[item.UserID] = append(byUser[item.UserID], item)
+	}
+
+	date := d.now().In(d.cfg.Location).Format(utils.DateLayout)
+	sent := 0
+	var errs []error
+	for _, userID := range users {
+		if err := d.send(ctx, userID, &Digest{Date: date, Items: byUser[userID]}); err != nil {
+			errs = append(errs, fmt.Errorf("digest of %s: %w", userID, err))
+			continue
+		}
+		sent++
+	}
+	return sent, errors.Join(errs...)
+}
+
+func (d *DigestCompiler) send(ctx context.Context, userID uuid.UUID, digest *Digest) error {
+	ids := make([]int64, len(digest.Items))
+	for i, item := range digest.Items {
+		ids[i] = item.ID
+	}
+	user, err := d.repo.GetUserByID(ctx, userID)
+	if err == sql.ErrNoRows {
+		// Deleted users take their queued alerts along; drop stragglers
+		return d.repo.DeleteDigestItems(ctx, ids)
+	}
+	if err != nil {
+		return err
+	}
+	subject, body, err := d.templates.Render(ctx, KeyAlertDigest, digest)
+	if err != nil {
+		return err
+	}
+	if err := d.mailer.Send(ctx, &reports.Message{To: []string{user.Email}, Subject: subject, Body: body}); err != nil {
+		return err
+	}
+	return d.repo.DeleteDigestItems(ctx, ids)
+}
+
+// next returns the first digest time after now
+func (d *DigestCompiler) next(now time.Time) time.Time {
+	local := now.In(d.cfg.Location)
+	run := time.Date(local.Year(), local.Month(), local.Day(), d.cfg.DigestHour, 0, 0, 0, d.cfg.Location)
+	if !run.After(local) {
+		run = run.AddDate(0, 0, 1)
+	}
+	return run
+}
+
+// Run compiles the digests every day at DigestHour until ctx is cancelled
+func (d *DigestCompiler) Run(ctx context.Context) {
+	if d.cfg.DigestHour < 0 {
+		return
+	}
+	for {
+		timer := time.NewTimer(d.next(d.now()).Sub(d.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			n, err := d.Compile(ctx)
+			if err != nil {
+				utils.LogError("notification digest", err)
+			}
+			if n > 0 {
+				utils.LogInfo(fmt.Sprintf("Sent %d notification digests", n))
+			}
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/freshness"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/quality"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/reports"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/slo"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// Config represents where alert emails are sent
+type Config struct {
+	// To are recipients of every alert email, besides the users that chose
+	// to receive them
+	To []string
+	// DefaultDelivery applies to users that never chose a delivery for an
+	// alert type; mute unless configured
+	DefaultDelivery string
+	// DigestHour is the hour of the day, in Location, the daily digest is
+	// sent at; negative disables digests
+	DigestHour int
+	Location   *time.Location
+	// RetentionDays is how long inbox notifications are kept, read or not
+	RetentionDays int
+	// PurgeInterval is how often expired inbox notifications are purged; 0
+	// disables purging
+	PurgeInterval time.Duration
+}
+
+// LoadConfig loads alert email configuration from environment variables
+func LoadConfig() *Config {
+	cfg := &Config{
+		DefaultDelivery: utils.GetEnv("NOTIFICATION_DEFAULT_DELIVERY", models.DeliveryMute),
+		DigestHour:      utils.GetEnvAsInt("NOTIFICATION_DIGEST_HOUR", 8),
+		Location:        time.UTC,
+		RetentionDays:   utils.GetEnvAsInt("NOTIFICATION_RETENTION_DAYS", 30),
+		PurgeInterval:   utils.GetEnvAsDuration("NOTIFICATION_PURGE_INTERVAL", time.Hour),
+	}
+	for _, addr := range strings.Split(utils.GetEnv("ALERT_EMAIL_TO", ""), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			cfg.To = append(cfg.To, addr)
+		}
+	}
+	switch cfg.DefaultDelivery {
+	case models.DeliveryImmediate, models.DeliveryDigest, models.DeliveryMute:
+	default:
+		utils.LogError("notify: NOTIFICATION_DEFAULT_DELIVERY", fmt.Errorf("unknown delivery %q, using mute", cfg.DefaultDelivery))
+		cfg.DefaultDelivery = models.DeliveryMute
+	}
+	if cfg.DigestHour > 23 {
+		cfg.DigestHour = 8
+	}
+	if cfg.RetentionDays <= 0 {
+		cfg.RetentionDays = 30
+	}
+	if name := utils.GetEnv("NOTIFICATION_DIGEST_TIMEZONE", ""); name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			cfg.Location = loc
+		} else {
+			utils.LogError("notify: NOTIFICATION_DIGEST_TIMEZONE", err)
+		}
+	}
+	return cfg
+}
+
+// Dispatcher delivers alerts to the configured recipients and to every user
+// as their notification preferences say: emailed at once, queued for the
+// daily digest or dropped. Users on the inbox channel get every alert that
+// is not muted in their inbox right away.
+type Dispatcher struct {
+	repo      database.Repository
+	templates *Templates
+	mailer    reports.Mailer
+	cfg       *Config
+}
+
+// NewDispatcher creates a new Dispatcher
+func NewDispatcher(repo database.Repository, templates *Templates, mailer reports.Mailer, cfg *Config) *Dispatcher {
+	return &Dispatcher{repo: repo, templates: templates, mailer: mailer, cfg: cfg}
+}
+
+// Default returns the preference of users that never chose one for an alert
+// type. Security alerts concern the account of the user, so they are sent at
+// once to its email and inbox unless muted.
+func (d *Dispatcher) Default(alertType string) *models.NotificationPreference {
+	if alertType == models.AlertTypeSecurity {
+		return &models.NotificationPreference{
+			AlertType: alertType,
+			Delivery:  models.DeliveryImmediate,
+			Channels:  []string{models.ChannelEmail, models.ChannelInbox},
+			Default:   true,
+		}
+	}
+	return &models.NotificationPreference{
+		AlertType: alertType,
+		Delivery:  d.cfg.DefaultDelivery,
+		Channels:  []string{models.ChannelEmail},
+		Default:   true,
+	}
+}
+
+// Preferences returns the preference of a user for every alert type, with
+// the default for the ones never chosen
+func (d *Dispatcher) Preferences(ctx context.Context, userID uuid.UUID) ([]*models.NotificationPreference, error) {
+	stored, err := d.repo.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	chosen := make(map[string]*models.NotificationPreference, len(stored))
+	for _, p := range stored {
+		chosen[p.AlertType] = p
+	}
+	list := make([]*models.NotificationPreference, 0, len(models.AlertTypes))
+	for _, alertType := range models.AlertTypes {
+		if p, ok := chosen[alertType]; ok {
+			list = append(list, p)
+			continue
+		}
+		list = append(list, d.Default(alertType))
+	}
+	return list, nil
+}
+
+// dispatch renders the email of a template key and delivers it
+func (d *Dispatcher) dispatch(ctx context.Context, alertType, key string, data any, raisedAt time.Time) error {
+	subject, body, err := d.templates.Render(ctx, key, data)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if len(d.cfg.To) > 0 {
+		if err := d.mailer.Send(ctx, &reports.Message{To: d.cfg.To, Subject: subject, Body: body}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	subscribers, err := d.repo.GetNotificationSubscribers(ctx, alertType)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for _, s := range subscribers {
+		errs = append(errs, d.deliver(ctx, s, alertType, subject, body, raisedAt)...)
+	}
+	return errors.Join(errs...)
+}
+
+// deliver delivers a rendered alert to a user as their preference says
+func (d *Dispatcher) deliver(ctx context.Context, s *models.NotificationSubscriber, alertType, subject, body string, raisedAt time.Time) []error {
+	delivery, channels := s.Delivery, s.Channels
+	if delivery == "" {
+		def := d.Default(alertType)
+		delivery, channels = def.Delivery, def.Channels
+	}
+	if delivery == models.DeliveryMute {
+		return nil
+	}
+	var errs []error
+	if hasChannel(channels, models.ChannelInbox) {
+		err := d.repo.CreateNotification(ctx, &models.Notification{
+			UserID:    s.UserID,
+			AlertType: alertType,
+			Subject:   subject,
+			Body:      body,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("notify %s: %w", s.Email, err))
+		}
+	}
+	if !hasChannel(channels, models.ChannelEmail) {
+		return errs
+	}
+	var err error
+	switch delivery {
+	case models.DeliveryImmediate:
+		err = d.mailer.Send(ctx, &reports.Message{To: []string{s.Email}, Subject: subject, Body: body})
+	case models.DeliveryDigest:
+		err = d.repo.QueueDigestItem(ctx, &models.DigestItem{
+			UserID:    s.UserID,
+			AlertType: alertType,
+			Subject:   subject,
+			Body:      body,
+			RaisedAt:  raisedAt,
+		})
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("notify %s: %w", s.Email, err))
+	}
+	return errs
+}
+
+func hasChannel(channels []string, channel string) bool {
+	for _, c := range channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// FreshnessNotifier logs freshness alerts and delivers them with the
+// freshness-alert template
+type FreshnessNotifier struct {
+	*Dispatcher
+}
+
+// NewFreshnessNotifier returns a FreshnessNotifier delivering through d
+func NewFreshnessNotifier(d *Dispatcher) freshness.Notifier {
+	return &FreshnessNotifier{d}
+}
+
+// Notify implements freshness.Notifier
+func (n *FreshnessNotifier) Notify(ctx context.Context, a freshness.Alert) error {
+	freshness.LogNotifier{}.Notify(ctx, a)
+	return n.dispatch(ctx, models.AlertTypeFreshness, KeyFreshnessAlert, &a, a.RaisedAt)
+}
+
+// SLONotifier logs SLO alerts and delivers them with the slo-alert template
+type SLONotifier struct {
+	*Dispatcher
+}
+
+// NewSLONotifier returns an SLONotifier delivering through d
+func NewSLONotifier(d *Dispatcher) slo.Notifier {
+	return &SLONotifier{d}
+}
+
+// Notify implements slo.Notifier
+func (n *SLONotifier) Notify(ctx context.Context, a slo.Alert) error {
+	slo.LogNotifier{}.Notify(ctx, a)
+	return n.dispatch(ctx, models.AlertTypeSLO, KeySLOAlert, &a, a.RaisedAt)
+}
+
+// DataQualityNotifier logs data quality alerts and delivers them with the
+// data-quality-alert template
+type DataQualityNotifier struct {
+	*Dispatcher
+}
+
+// NewDataQualityNotifier returns a DataQualityNotifier delivering through d
+func NewDataQualityNotifier(d *Dispatcher) quality.Notifier {
+	return &DataQualityNotifier{d}
+}
+
+// Notify implements quality.Notifier
+func (n *DataQualityNotifier) Notify(ctx context.Context, a quality.Alert) error {
+	quality.LogNotifier{}.Notify(ctx, a)
+	return n.dispatch(ctx, models.AlertTypeDataQuality, KeyDataQualityAlert, &a, a.RaisedAt)
+}
+
+// LockoutNotifier logs lockout alerts and delivers them with the
+// lockout-alert template to ALERT_EMAIL_TO and to the owner of the locked
+// account, as their preference for security alerts says
+type LockoutNotifier struct {
+	*Dispatcher
+}
+
+// NewLockoutNotifier returns a LockoutNotifier delivering through d
+func NewLockoutNotifier(d *Dispatcher) auth.Notifier {
+	return &LockoutNotifier{d}
+}
+
+// Notify implements auth.Notifier
+func (n *LockoutNotifier) Notify(ctx context.Context, a auth.LockoutAlert) error {
+	auth.LogNotifier{}.Notify(ctx, a)
+	subject, body, err := n.templates.Render(ctx, KeyLockoutAlert, &a)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if len(n.cfg.To) > 0 {
+		if err := n.mailer.Send(ctx, &reports.Message{To: n.cfg.To, Subject: subject, Body: body}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if a.UserID == nil {
+		return errors.Join(errs...)
+	}
+	user, err := n.repo.GetUserByID(ctx, *a.UserID)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	prefs, err := n.repo.GetNotificationPreferences(ctx, user.ID)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	owner := &models.NotificationSubscriber{UserID: user.ID, Email: user.Email}
+	for _, p := range prefs {
+		if p.AlertType == models.AlertTypeSecurity {
+			owner.Delivery, owner.Channels = p.Delivery, p.Channels
+		}
+	}
+	errs = append(errs, n.deliver(ctx, owner, models.AlertTypeSecurity, subject, body, a.RaisedAt)...)
+	return errors.Join(errs...)
+}
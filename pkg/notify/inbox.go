@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// InboxPurger deletes inbox notifications once they are past the retention period
+type InboxPurger struct {
+	repo database.Repository
+	cfg  *Config
+	now  func() time.Time
+}
+
+// NewInboxPurger creates a new InboxPurger
+func NewInboxPurger(repo database.Repository, cfg *Config) *InboxPurger {
+	return &InboxPurger{repo: repo, cfg: cfg, now: time.Now}
+}
+
+// Purge deletes the notifications past the retention period and returns how many there were
+func (p *InboxPurger) Purge(ctx context.Context) (int64, error) {
+	return p.repo.PurgeNotifications(ctx, p.now().AddDate(0, 0, -p.cfg.RetentionDays))
+}
+
+// Run purges expired notifications every PurgeInterval until ctx is cancelled
+func (p *InboxPurger) Run(ctx context.Context) {
+	if p.cfg.PurgeInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(p.cfg.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := p.Purge(ctx)
+			if err != nil {
+				utils.LogError("notification purge", err)
+				continue
+			}
+			if n > 0 {
+				utils.LogInfo(fmt.Sprintf("Purged %d expired notifications", n))
+			}
+		}
+	}
+}
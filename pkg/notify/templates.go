@@ -0,0 +1,345 @@
+// Package notify words and emails the alerts of the notification subsystem.
+// The emails come from versioned templates stored in the database, so their
+// wording can change without a deploy; keys that were never edited use the
+// built-in templates of this package.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/freshness"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/quality"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/slo"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUnknownTemplate is returned for a key no notification uses
+	ErrUnknownTemplate = errors.New("unknown email template")
+	// ErrInvalidTemplate is returned for templates that cannot be rendered
+	ErrInvalidTemplate = errors.New("invalid email template")
+)
+
+// Email template keys
+const (
+	// KeyFreshnessAlert is emailed when production data becomes stale
+	KeyFreshnessAlert = "freshness-alert"
+	// KeySLOAlert is emailed when a route burns its error budget too fast
+	KeySLOAlert = "slo-alert"
+	// KeyLockoutAlert is emailed when a username is locked after too many
+	// failed logins
+	KeyLockoutAlert = "lockout-alert"
+	// KeyDataQualityAlert is emailed when the data quality scan finds new
+	// orphaned or inconsistent rows
+	KeyDataQualityAlert = "data-quality-alert"
+	// KeyAlertDigest is emailed once a day to users that chose the digest
+	// delivery, with the alerts raised since the last one
+	KeyAlertDigest = "alert-digest"
+)
+
+// definition is a kind of notification email: its built-in texts, the type
+// of its data and sample data for previews
+type definition struct {
+	description string
+	subject     string
+	body        string
+	newData     func() any
+	sample      func() any
+}
+
+var definitions = map[string]*definition{
+	KeyAlertDigest: {
+		description: "Sent once a day to users that chose the digest delivery. Data: .Date, .Items (.AlertType, .Subject, .Body, .RaisedAt), each alert as its own template rendered it",
+		subject:     `Alert digest for {{.Date}}: {{len .Items}} alerts`,
+		body: `{{len .Items}} alerts were raised since the last digest.
+{{range .Items}}
+== {{.Subject}} ({{.RaisedAt.Format "2006-01-02 15:04 MST"}})
+{{.Body}}{{end}}`,
+		newData: func() any { return &Digest{} },
+		sample: func() any {
+			return &Digest{
+				Date: "2025-09-30",
+				Items: []*models.DigestItem{{
+					AlertType: models.AlertTypeFreshness,
+					Subject:   "Production data is stale",
+					Body:      "The latest production data is 4 days old.\n",
+					RaisedAt:  time.Date(2025, 9, 29, 8, 0, 0, 0, time.UTC),
+				}, {
+					AlertType: models.AlertTypeSLO,
+					Subject:   "SLO at risk for GET /api/v1/productions",
+					Body:      "GET /api/v1/productions is burning its error budget 3.00 times faster than allowed.\n",
+					RaisedAt:  time.Date(2025, 9, 29, 14, 30, 0, 0, time.UTC),
+				}},
+			}
+		},
+	},
+	KeyDataQualityAlert: {
+		description: "Sent when the data quality scan flags rows the previous scan did not. Data: .NewCount, .Findings (.Check, .Entity, .EntityID, .GeneratorID, .Date, .Detail; the first 50 new ones), .Checks (.Check, .Count), .RaisedAt",
+		subject:     `Data quality scan found {{.NewCount}} new issues`,
+		body: `The data quality scan found {{.NewCount}} new issues:
+{{range .Findings}}- {{.Check}}: {{.Entity}} {{.EntityID}}{{if .Date}} on {{.Date}}{{end}}: {{.Detail}}
+{{end}}{{if gt .NewCount (len .Findings)}}...and {{.NewCount}} in total.
+{{end}}
+Open issues per check:
+{{range .Checks}}- {{.Check}}: {{.Count}}
+{{end}}
+Raised at {{.RaisedAt.Format "2006-01-02 15:04 MST"}}
+`,
+		newData: func() any { return &quality.Alert{} },
+		sample: func() any {
+			generatorID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440001")
+			date := "2025-09-03"
+			return &quality.Alert{
+				NewCount: 1,
+				Findings: []*models.DataQualityFinding{{
+					Check:       models.QualityOverCapacity,
+					Entity:      models.QualityEntityProduction,
+					EntityID:    uuid.MustParse("550e8400-e29b-41d4-a716-446655440002"),
+					GeneratorID: &generatorID,
+					Date:        &date,
+					Detail:      "120.5 MW exceeds the capacity of 100.5 MW",
+				}},
+				Checks: []*models.DataQualityCheck{
+					{Check: models.QualityOverCapacity, Count: 1},
+					{Check: models.QualityUnusedType, Count: 2},
+				},
+				RaisedAt: time.Date(2025, 9, 30, 8, 0, 0, 0, time.UTC),
+			}
+		},
+	},
+	KeyFreshnessAlert: {
+		description: "Sent when production data, overall or of some generators, lags behind today by more than the allowed days. Data: .Overall, .LagDays, .Generators (.GeneratorID, .TypeName, .OperatorName, .LatestDate, .LagDays, .AllowedLagDays), .RaisedAt",
+		subject:     `{{if .Overall}}Production data is stale{{else}}{{len .Generators}} generators have stale data{{end}}`,
+		body: `{{if .Overall}}{{if .LagDays}}The latest production data is {{.LagDays}} days old.{{else}}There is no production data.{{end}}
+{{end}}{{if .Generators}}Generators with stale data:
+{{range .Generators}}- {{.GeneratorID}} ({{.TypeName}}): {{.LagDays}} days, {{.AllowedLagDays}} allowed
+{{end}}{{end}}
+Raised at {{.RaisedAt.Format "2006-01-02 15:04 MST"}}
+`,
+		newData: func() any { return &freshness.Alert{} },
+		sample: func() any {
+			lag, latest := 4, "2025-09-26"
+			return &freshness.Alert{
+				Overall: true,
+				LagDays: &lag,
+				Generators: []*models.GeneratorFreshness{{
+					GeneratorLatestProduction: models.GeneratorLatestProduction{
+						GeneratorID: uuid.MustParse("550e8400-e29b-41d4-a716-446655440001"),
+						TypeName:    "Solar",
+						LatestDate:  &latest,
+						CreatedOn:   "2025-01-01",
+						Cadence:     "daily",
+					},
+					LagDays:        4,
+					AllowedLagDays: 2,
+					Stale:          true,
+				}},
+				RaisedAt: time.Date(2025, 9, 30, 8, 0, 0, 0, time.UTC),
+			}
+		},
+	},
+	KeyLockoutAlert: {
+		description: "Sent to ALERT_EMAIL_TO and the owner of the account when a username is locked after too many failed logins. Data: .Username, .UserID (nil without account), .Failures, .Lockouts, .LastIP, .LockedUntil, .RaisedAt",
+		subject:     `Login of {{.Username}} locked after {{.Failures}} failed attempts`,
+		body: `{{.Failures}} logins to {{.Username}} failed in a row, the last one from {{.LastIP}}. Logins of {{.Username}} are refused until {{.LockedUntil.Format "2006-01-02 15:04 MST"}}.
+{{if gt .Lockouts 1}}
+The username was locked {{.Lockouts}} times since its last successful login.
+{{end}}{{if not .UserID}}
+No account has this username.
+{{end}}
+If these attempts were not yours, change your password after the lock ends or ask an admin to unlock it.
+
+Raised at {{.RaisedAt.Format "2006-01-02 15:04 MST"}}
+`,
+		newData: func() any { return &auth.LockoutAlert{} },
+		sample: func() any {
+			userID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440061")
+			return &auth.LockoutAlert{
+				Username:    "john_doe",
+				UserID:      &userID,
+				Failures:    5,
+				Lockouts:    1,
+				LastIP:      "203.0.113.7",
+				LockedUntil: time.Date(2025, 9, 30, 8, 15, 0, 0, time.UTC),
+				RaisedAt:    time.Date(2025, 9, 30, 8, 0, 0, 0, time.UTC),
+			}
+		},
+	},
+	KeySLOAlert: {
+		description: "Sent when a route burns its error budget faster than allowed. Data: .Route, .Status (.BurnRate, .ErrorBudgetRemaining, .Requests, .BadRequests, .SlowRequests, .ServerErrors, .AvgLatencyMs, .TargetLatencyMs, .Objective, .SLI), .RaisedAt",
+		subject:     `SLO at risk for {{.Route}}`,
+		body: `{{.Route}} is burning its error budget {{printf "%.2f" .Status.BurnRate}} times faster than allowed.
+
+{{.Status.BadRequests}} of {{.Status.Requests}} requests were bad ({{.Status.SlowRequests}} slower than {{.Status.TargetLatencyMs}} ms, {{.Status.ServerErrors}} server errors); {{printf "%.1f" (percent .Status.ErrorBudgetRemaining)}}% of the error budget is left.
+
+Raised at {{.RaisedAt.Format "2006-01-02 15:04 MST"}}
+`,
+		newData: func() any { return &slo.Alert{} },
+		sample: func() any {
+			return &slo.Alert{
+				Route: "GET /api/v1/productions",
+				Status: slo.RouteStatus{
+					Route:                "GET /api/v1/productions",
+					TargetLatencyMs:      500,
+					Objective:            0.99,
+					Requests:             1200,
+					BadRequests:          36,
+					SlowRequests:         30,
+					ServerErrors:         6,
+					AvgLatencyMs:         310,
+					SLI:                  0.97,
+					ErrorBudgetRemaining: -2,
+					BurnRate:             3,
+					AtRisk:               true,
+				},
+				RaisedAt: time.Date(2025, 9, 30, 8, 0, 0, 0, time.UTC),
+			}
+		},
+	},
+}
+
+// funcs are the functions templates may call besides the text/template builtins
+var funcs = template.FuncMap{
+	"percent": func(f float64) float64 { return f * 100 },
+	"join":    strings.Join,
+}
+
+// Keys lists the email template keys, sorted
+func Keys() []string {
+	keys := make([]string, 0, len(definitions))
+	for key := range definitions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Builtin returns the built-in template of a key, as version 0
+func Builtin(key string) (*models.EmailTemplate, error) {
+	def, ok := definitions[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, key)
+	}
+	return &models.EmailTemplate{Key: key, Description: def.description, Subject: def.subject, Body: def.body}, nil
+}
+
+// Render evaluates a subject and body with data
+func Render(subject, body string, data any) (string, string, error) {
+	s, err := execute("subject", subject, data)
+	if err != nil {
+		return "", "", err
+	}
+	b, err := execute("body", body, data)
+	if err != nil {
+		return "", "", err
+	}
+	// A subject is one line
+	return strings.Join(strings.Fields(s), " "), b, nil
+}
+
+func execute(name, text string, data any) (string, error) {
+	t, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrInvalidTemplate, name, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrInvalidTemplate, name, err)
+	}
+	return buf.String(), nil
+}
+
+// Validate checks that a subject and body render with the sample data of key
+func Validate(key, subject, body string) error {
+	def, ok := definitions[key]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownTemplate, key)
+	}
+	_, _, err := Render(subject, body, def.sample())
+	return err
+}
+
+// Data decodes the JSON form of the data of a key, e.g. a freshness alert
+// as the freshness endpoints show it; nil gives the sample data
+func Data(key string, raw map[string]any) (any, error) {
+	def, ok := definitions[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, key)
+	}
+	if raw == nil {
+		return def.sample(), nil
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: data: %v", ErrInvalidTemplate, err)
+	}
+	data := def.newData()
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(data); err != nil {
+		return nil, fmt.Errorf("%w: data: %v", ErrInvalidTemplate, err)
+	}
+	return data, nil
+}
+
+// Templates looks up the current version of email templates
+type Templates struct {
+	repo database.Repository
+}
+
+// NewTemplates creates a new Templates over the stored versions of repo
+func NewTemplates(repo database.Repository) *Templates {
+	return &Templates{repo: repo}
+}
+
+// Current returns the latest stored version of a key, or its built-in
+// template when it was never edited or templates cannot be stored
+func (t *Templates) Current(ctx context.Context, key string) (*models.EmailTemplate, error) {
+	builtin, err := Builtin(key)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := t.repo.GetEmailTemplate(ctx, key)
+	switch {
+	case err == nil:
+		stored.Description = builtin.Description
+		return stored, nil
+	case err == sql.ErrNoRows, errors.Is(err, database.ErrNotSupported):
+		return builtin, nil
+	}
+	return nil, err
+}
+
+// List returns the current template of every key, sorted by key
+func (t *Templates) List(ctx context.Context) ([]*models.EmailTemplate, error) {
+	list := make([]*models.EmailTemplate, 0, len(definitions))
+	for _, key := range Keys() {
+		current, err := t.Current(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, current)
+	}
+	return list, nil
+}
+
+// Render renders the current template of a key with data
+func (t *Templates) Render(ctx context.Context, key string, data any) (subject, body string, err error) {
+	current, err := t.Current(ctx, key)
+	if err != nil {
+		return "", "", err
+	}
+	return Render(current.Subject, current.Body, data)
+}
@@ -0,0 +1,102 @@
+package numeric
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/shopspring/decimal"
+)
+
+// RoundingMode selects how values are rounded to the configured precision
+type RoundingMode string
+
+const (
+	// RoundHalfEven rounds to the nearest value, ties to the even digit (banker's rounding)
+	RoundHalfEven RoundingMode = "half_even"
+	// RoundHalfUp rounds to the nearest value, ties away from zero
+	RoundHalfUp RoundingMode = "half_up"
+	// RoundDown truncates towards zero
+	RoundDown RoundingMode = "down"
+	// RoundUp rounds away from zero
+	RoundUp RoundingMode = "up"
+	// RoundNone keeps values as stored
+	RoundNone RoundingMode = "none"
+)
+
+// Policy represents the decimal precision applied to energy and capacity values
+type Policy struct {
+	Decimals int32
+	Mode     RoundingMode
+}
+
+var (
+	mu      sync.RWMutex
+	current = Policy{Decimals: 3, Mode: RoundHalfEven}
+)
+
+// LoadPolicy loads the rounding policy from environment variables
+func LoadPolicy() Policy {
+	policy := Policy{
+		Decimals: int32(utils.GetEnvAsInt("NUMBER_DECIMALS", 3)),
+		Mode:     RoundingMode(strings.ToLower(utils.GetEnv("NUMBER_ROUNDING", string(RoundHalfEven)))),
+	}
+	if policy.Decimals < 0 {
+		policy.Mode = RoundNone
+	}
+	switch policy.Mode {
+	case RoundHalfEven, RoundHalfUp, RoundDown, RoundUp, RoundNone:
+	default:
+		policy.Mode = RoundHalfEven
+	}
+	return policy
+}
+
+// SetPolicy sets the process wide rounding policy
+func SetPolicy(p Policy) {
+	mu.Lock()
+	current = p
+	mu.Unlock()
+}
+
+// CurrentPolicy returns the process wide rounding policy
+func CurrentPolicy() Policy {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// RoundDecimal rounds d according to the policy
+func (p Policy) RoundDecimal(d decimal.Decimal) decimal.Decimal {
+	switch p.Mode {
+	case RoundHalfEven:
+		return d.RoundBank(p.Decimals)
+	case RoundHalfUp:
+		return d.Round(p.Decimals)
+	case RoundDown:
+		return d.Truncate(p.Decimals)
+	case RoundUp:
+		return d.RoundUp(p.Decimals)
+	default:
+		return d
+	}
+}
+
+// Round rounds f according to the policy. The value goes through an exact
+// decimal representation so results do not depend on binary float artifacts.
+func (p Policy) Round(f float64) float64 {
+	if p.Mode == RoundNone {
+		return f
+	}
+	return p.RoundDecimal(decimal.NewFromFloat(f)).InexactFloat64()
+}
+
+// Round rounds f with the process wide policy
+func Round(f float64) float64 {
+	return CurrentPolicy().Round(f)
+}
+
+// RoundDecimal rounds d with the process wide policy
+func RoundDecimal(d decimal.Decimal) decimal.Decimal {
+	return CurrentPolicy().RoundDecimal(d)
+}
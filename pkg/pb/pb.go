@@ -0,0 +1,143 @@
+// Package pb encodes responses as the protobuf messages of tadb.proto, for
+// consumers that poll large listings and series. Messages are written with
+// protowire following the proto3 rules: fields at their zero value are left
+// out and repeated messages are length-delimited.
+package pb
+
+import (
+	"math"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// MIMEType is the media type of protobuf responses
+const MIMEType = "application/x-protobuf"
+
+// ProductionList encodes a tadb.v1.ProductionList
+func ProductionList(list []*models.Production) []byte {
+	var b []byte
+	for _, p := range list {
+		b = appendMessage(b, 1, production(p))
+	}
+	return b
+}
+
+func production(p *models.Production) []byte {
+	var b []byte
+	b = appendUUID(b, 1, p.ID)
+	b = appendUUID(b, 2, p.GeneratorID)
+	b = appendDecimal(b, 3, p.GeneratorCapacity)
+	b = appendString(b, 4, p.TypeName)
+	b = appendBool(b, 5, p.IsRenewable)
+	b = appendString(b, 6, p.Date)
+	b = appendDecimal(b, 7, p.ProductionMW)
+	b = appendString(b, 8, p.Source)
+	b = appendString(b, 9, p.SourceRef)
+	b = appendString(b, 10, p.Signature)
+	if p.SignatureValid != nil {
+		// optional: written even when false
+		b = protowire.AppendTag(b, 11, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(*p.SignatureValid))
+	}
+	b = appendTime(b, 12, p.CreatedAt)
+	b = appendTime(b, 13, p.UpdatedAt)
+	b = appendOptionalDecimal(b, 14, p.CapacityFactor)
+	b = appendOptionalDecimal(b, 15, p.Utilization)
+	return b
+}
+
+// TotalProductionSeries encodes a tadb.v1.TotalProductionSeries
+func TotalProductionSeries(list []*models.TotalProductionByDate) []byte {
+	var b []byte
+	for _, t := range list {
+		var m []byte
+		m = appendString(m, 1, t.Date)
+		m = appendDecimal(m, 2, t.TotalProduction)
+		m = appendDecimal(m, 3, t.RenewableProduction)
+		m = appendDecimal(m, 4, t.NonRenewableProduction)
+		b = appendMessage(b, 1, m)
+	}
+	return b
+}
+
+// DailyProductionSummaryList encodes a tadb.v1.DailyProductionSummaryList
+func DailyProductionSummaryList(list []*models.DailyProductionSummary) []byte {
+	var b []byte
+	for _, s := range list {
+		var m []byte
+		m = appendString(m, 1, s.Date)
+		m = appendUUID(m, 2, s.TypeID)
+		m = appendString(m, 3, s.TypeName)
+		m = appendBool(m, 4, s.IsRenewable)
+		if s.Records != 0 {
+			m = protowire.AppendTag(m, 5, protowire.VarintType)
+			m = protowire.AppendVarint(m, uint64(s.Records))
+		}
+		m = appendDecimal(m, 6, s.ProductionMW)
+		b = appendMessage(b, 1, m)
+	}
+	return b
+}
+
+// appendMessage writes an embedded message; empty messages are still
+// written so repeated fields keep their length
+func appendMessage(b []byte, num protowire.Number, m []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendUUID(b []byte, num protowire.Number, id uuid.UUID) []byte {
+	if id == uuid.Nil {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, id[:])
+}
+
+func appendBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, 1)
+}
+
+// appendDecimal writes a decimal as a double; values are already rounded
+// to the configured decimals, which a double holds exactly enough
+func appendDecimal(b []byte, num protowire.Number, d decimal.Decimal) []byte {
+	if d.IsZero() {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(d.InexactFloat64()))
+}
+
+// appendOptionalDecimal writes an optional double; set values are written even when zero
+func appendOptionalDecimal(b []byte, num protowire.Number, d *decimal.Decimal) []byte {
+	if d == nil {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(d.InexactFloat64()))
+}
+
+// appendTime writes a time as Unix milliseconds
+func appendTime(b []byte, num protowire.Number, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(t.UnixMilli()))
+}
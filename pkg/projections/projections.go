@@ -0,0 +1,54 @@
+// Package projections keeps the summary tables derived from the domain event
+// log up to date in the background.
+package projections
+
+import (
+	"context"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// Config represents the projection settings
+type Config struct {
+	// Interval is how often the projections catch up with the event log; 0 disables it
+	Interval time.Duration
+}
+
+// LoadConfig loads projection configuration from environment variables
+func LoadConfig() *Config {
+	return &Config{
+		Interval: utils.GetEnvAsDuration("PROJECTIONS_INTERVAL", 10*time.Second),
+	}
+}
+
+// Projector applies new events of the log to the projections
+type Projector struct {
+	repo database.Repository
+	cfg  *Config
+}
+
+// NewProjector creates a new Projector
+func NewProjector(repo database.Repository, cfg *Config) *Projector {
+	return &Projector{repo: repo, cfg: cfg}
+}
+
+// Run catches the projections up every Interval until ctx is cancelled
+func (p *Projector) Run(ctx context.Context) {
+	if p.cfg.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.repo.CatchUpProjections(ctx); err != nil {
+				utils.LogError("projections catch-up", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package quality scans the data for orphaned and inconsistent rows across
+// types, generators, productions and grants, and alerts when new ones appear.
+package quality
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// maxAlertFindings bounds the findings listed in an alert; the counts cover
+// all of them
+const maxAlertFindings = 50
+
+// Config represents the background data quality scan
+type Config struct {
+	// Interval is how often the data is scanned for new findings; 0 disables
+	// the scan and its alerts
+	Interval time.Duration
+	// ScanLimit is how many findings of each check the background scan
+	// remembers; findings beyond it are only counted
+	ScanLimit int
+}
+
+// LoadConfig loads data quality configuration from environment variables
+func LoadConfig() *Config {
+	cfg := &Config{
+		Interval:  utils.GetEnvAsDuration("DATA_QUALITY_INTERVAL", 6*time.Hour),
+		ScanLimit: utils.GetEnvAsInt("DATA_QUALITY_SCAN_LIMIT", 1000),
+	}
+	if cfg.ScanLimit <= 0 {
+		cfg.ScanLimit = 1000
+	}
+	return cfg
+}
+
+// Alert is emitted when a scan flags rows the previous scan did not
+type Alert struct {
+	// NewCount is how many findings are new; Findings lists the first of them
+	NewCount int                          `json:"newCount"`
+	Findings []*models.DataQualityFinding `json:"findings"`
+	// Checks are the counts of every check, new findings or not
+	Checks   []*models.DataQualityCheck `json:"checks"`
+	RaisedAt time.Time                  `json:"raisedAt"`
+}
+
+// Notifier delivers data quality alerts
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// LogNotifier writes alerts to the server log
+type LogNotifier struct{}
+
+// Notify implements Notifier
+func (LogNotifier) Notify(_ context.Context, a Alert) error {
+	counts := make([]string, 0, len(a.Checks))
+	for _, c := range a.Checks {
+		if c.Count > 0 {
+			counts = append(counts, fmt.Sprintf("%s: %d", c.Check, c.Count))
+		}
+	}
+	utils.LogInfo(fmt.Sprintf("Data quality scan found %d new issues (%s)", a.NewCount, strings.Join(counts, ", ")))
+	return nil
+}
+
+// Monitor scans the data quality on demand and in the background
+type Monitor struct {
+	repo     database.Repository
+	cfg      *Config
+	notifier Notifier
+	now      func() time.Time
+
+	mu sync.Mutex
+	// seen are the keys of the findings of the last background scan; nil
+	// before the first
+	seen map[string]bool
+}
+
+// NewMonitor creates a new Monitor; alerts are delivered to notifier
+func NewMonitor(repo database.Repository, cfg *Config, notifier Notifier) *Monitor {
+	if notifier == nil {
+		notifier = LogNotifier{}
+	}
+	return &Monitor{repo: repo, cfg: cfg, notifier: notifier, now: time.Now}
+}
+
+// Report scans the data with checks (all when empty), listing at most limit
+// findings of each
+func (m *Monitor) Report(ctx context.Context, checks []string, limit int) (*models.DataQualityReport, error) {
+	report, err := m.repo.ScanDataQuality(ctx, checks, limit)
+	if err != nil {
+		return nil, err
+	}
+	report.ScannedAt = m.now().UTC()
+	return report, nil
+}
+
+// Run scans every Interval and notifies the findings that were not there
+// in the previous scan, until ctx is cancelled. The first scan reports every
+// finding, so after a restart the open findings are alerted once more.
+func (m *Monitor) Run(ctx context.Context) {
+	if m.cfg.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.checkAlerts(ctx)
+		}
+	}
+}
+
+func (m *Monitor) checkAlerts(ctx context.Context) {
+	report, err := m.Report(ctx, nil, m.cfg.ScanLimit)
+	if err != nil {
+		utils.LogError("data quality scan", err)
+		return
+	}
+
+	alert := Alert{Findings: []*models.DataQualityFinding{}, Checks: report.Checks, RaisedAt: report.ScannedAt}
+	seen := make(map[string]bool, len(report.Findings))
+	m.mu.Lock()
+	for _, f := range report.Findings {
+		key := f.Key()
+		seen[key] = true
+		if m.seen[key] {
+			continue
+		}
+		alert.NewCount++
+		if len(alert.Findings) < maxAlertFindings {
+			alert.Findings = append(alert.Findings, f)
+		}
+	}
+	// Findings fixed since are forgotten, so they alert again if they return
+	m.seen = seen
+	m.mu.Unlock()
+
+	if alert.NewCount == 0 {
+		return
+	}
+	if err := m.notifier.Notify(ctx, alert); err != nil {
+		utils.LogError("data quality alert", err)
+	}
+}
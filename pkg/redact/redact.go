@@ -0,0 +1,337 @@
+// Package redact hides fields of the records in responses from roles that
+// should not see them, e.g. operator details from public readers, as set by
+// a policy file. Responses rendered by the negotiating render layer pass
+// through Apply; those shaped other than the records, such as GeoJSON and
+// CSV exports, drop what Hidden reports, and caches shared between callers
+// key their entries by Audience.
+package redact
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/auth"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// Public is the role of requests without an access token
+const Public = "public"
+
+// resources are the records a policy can redact, by the name rules use
+var resources = map[string]reflect.Type{
+	"type":       reflect.TypeOf(models.Type{}),
+	"operator":   reflect.TypeOf(models.Operator{}),
+	"generator":  reflect.TypeOf(models.Generator{}),
+	"plant":      reflect.TypeOf(models.Plant{}),
+	"region":     reflect.TypeOf(models.Region{}),
+	"production": reflect.TypeOf(models.Production{}),
+}
+
+// Config represents the redaction policy
+type Config struct {
+	// File is the policy; without it every role sees every field
+	File string
+}
+
+// LoadConfig loads the redaction settings from environment variables
+func LoadConfig() *Config {
+	return &Config{
+		File: utils.GetEnv("REDACTION_POLICY_FILE", ""),
+	}
+}
+
+// Rule hides fields of a resource from roles. Fields are JSON names; a
+// dotted name reaches into an object, e.g. customAttributes.costPerMwh.
+type Rule struct {
+	Roles    []string `json:"roles"`
+	Resource string   `json:"resource"`
+	Fields   []string `json:"fields"`
+}
+
+// policyFile is the layout of REDACTION_POLICY_FILE
+type policyFile struct {
+	Rules []*Rule `json:"rules"`
+}
+
+// Policy holds the fields hidden from each role, by record type
+type Policy struct {
+	hidden map[string]map[reflect.Type][]string
+	// audiences caches the fields hidden from a set of roles
+	audiences sync.Map
+}
+
+// LoadPolicy reads and checks a policy file; no file name gives no policy
+func LoadPolicy(file string) (*Policy, error) {
+	if file == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction policy: %w", err)
+	}
+	var f policyFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction policy: %w", err)
+	}
+	p := &Policy{hidden: map[string]map[reflect.Type][]string{}}
+	for i, rule := range f.Rules {
+		t, ok := resources[rule.Resource]
+		if !ok {
+			return nil, fmt.Errorf("rule %d: unknown resource %q", i, rule.Resource)
+		}
+		if len(rule.Roles) == 0 || len(rule.Fields) == 0 {
+			return nil, fmt.Errorf("rule %d: roles and fields are required", i)
+		}
+		names := jsonNames(t)
+		for _, field := range rule.Fields {
+			top, _, _ := strings.Cut(field, ".")
+			if !names[top] {
+				return nil, fmt.Errorf("rule %d: %s has no field %q", i, rule.Resource, top)
+			}
+		}
+		for _, role := range rule.Roles {
+			if role != Public && !slices.Contains(auth.Roles, role) {
+				return nil, fmt.Errorf("rule %d: unknown role %q", i, role)
+			}
+			if p.hidden[role] == nil {
+				p.hidden[role] = map[reflect.Type][]string{}
+			}
+			p.hidden[role][t] = append(p.hidden[role][t], rule.Fields...)
+		}
+	}
+	return p, nil
+}
+
+var (
+	mu      sync.RWMutex
+	current *Policy
+)
+
+// SetPolicy sets the process wide policy; nil redacts nothing
+func SetPolicy(p *Policy) {
+	mu.Lock()
+	current = p
+	mu.Unlock()
+}
+
+func currentPolicy() *Policy {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// roles returns the sorted roles of the request of ctx, Public without a principal
+func roles(ctx context.Context) []string {
+	p, ok := auth.PrincipalFrom(ctx)
+	if !ok || len(p.Roles) == 0 {
+		return []string{Public}
+	}
+	r := slices.Clone(p.Roles)
+	sort.Strings(r)
+	return r
+}
+
+// Audience identifies the responses the request of ctx gets, for caches
+// keyed by more than the URL; empty when no policy is set, as every request
+// then gets the same
+func Audience(ctx context.Context) string {
+	if currentPolicy() == nil {
+		return ""
+	}
+	return strings.Join(roles(ctx), ",")
+}
+
+// Enabled reports whether a policy is set, so responses vary by the token
+func Enabled() bool {
+	return currentPolicy() != nil
+}
+
+// hiddenFrom returns the fields hidden from a request with roles: those
+// every one of its roles hides, since each role adds what it may see
+func (p *Policy) hiddenFrom(roles []string) map[reflect.Type][]string {
+	key := strings.Join(roles, ",")
+	if cached, ok := p.audiences.Load(key); ok {
+		return cached.(map[reflect.Type][]string)
+	}
+	hidden := map[reflect.Type][]string{}
+	for t, fields := range p.hidden[roles[0]] {
+		for _, field := range fields {
+			all := true
+			for _, role := range roles[1:] {
+				all = all && slices.Contains(p.hidden[role][t], field)
+			}
+			if all {
+				hidden[t] = append(hidden[t], field)
+			}
+		}
+	}
+	p.audiences.Store(key, hidden)
+	return hidden
+}
+
+// Apply returns v as it renders to the request of ctx: v itself when it
+// has nothing hidden from it (false), or the JSON tree of v without the
+// hidden fields (true)
+func Apply(ctx context.Context, v any) (any, bool) {
+	p := currentPolicy()
+	if p == nil {
+		return v, false
+	}
+	hidden := p.hiddenFrom(roles(ctx))
+	if len(hidden) == 0 {
+		return v, false
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		// Rendering v fails the same way
+		return v, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return v, false
+	}
+	w := &walker{hidden: hidden}
+	w.walk(reflect.ValueOf(v), tree)
+	if !w.removed {
+		return v, false
+	}
+	return tree, true
+}
+
+// Hidden reports whether field, a JSON name, of record is hidden from the
+// request of ctx, for responses shaped other than the record, e.g. GeoJSON
+func Hidden(ctx context.Context, record any, field string) bool {
+	p := currentPolicy()
+	if p == nil {
+		return false
+	}
+	t := reflect.TypeOf(record)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return slices.Contains(p.hiddenFrom(roles(ctx))[t], field)
+}
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// walker drops the hidden fields of the records in a JSON tree
+type walker struct {
+	hidden  map[reflect.Type][]string
+	removed bool
+}
+
+// walk follows v and its JSON tree node together, so the objects of the
+// tree are known by their Go type
+func (w *walker) walk(v reflect.Value, node any) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Type().Implements(marshalerType) || reflect.PointerTo(v.Type()).Implements(marshalerType) {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return
+		}
+		for _, field := range w.hidden[v.Type()] {
+			w.removed = remove(obj, strings.Split(field, ".")) || w.removed
+		}
+		w.walkFields(v, obj)
+	case reflect.Slice, reflect.Array:
+		list, ok := node.([]any)
+		if !ok {
+			return
+		}
+		for i := 0; i < v.Len() && i < len(list); i++ {
+			w.walk(v.Index(i), list[i])
+		}
+	case reflect.Map:
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			w.walk(iter.Value(), obj[fmt.Sprint(iter.Key().Interface())])
+		}
+	}
+}
+
+// walkFields walks the fields of struct v, with those of embedded structs
+// promoted as encoding/json does
+func (w *walker) walkFields(v reflect.Value, obj map[string]any) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if f.Anonymous && name == "" {
+			for fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					break
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				w.walkFields(fv, obj)
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		w.walk(fv, obj[name])
+	}
+}
+
+// remove deletes the member at path from obj and reports whether it was there
+func remove(obj map[string]any, path []string) bool {
+	if len(path) == 1 {
+		_, ok := obj[path[0]]
+		delete(obj, path[0])
+		return ok
+	}
+	inner, ok := obj[path[0]].(map[string]any)
+	return ok && remove(inner, path[1:])
+}
+
+// jsonNames returns the JSON names of the fields of struct t
+func jsonNames(t reflect.Type) map[string]bool {
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names[name] = true
+	}
+	return names
+}
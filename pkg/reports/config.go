@@ -0,0 +1,58 @@
+package reports
+
+import (
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// Config represents the scheduler and delivery settings of saved reports
+type Config struct {
+	PollInterval time.Duration
+	MaxRows      int
+	RunHistory   int
+	AlertEmails  []string
+	// WebhookTimeout overrides OUTBOUND_TIMEOUT for webhook requests when set
+	WebhookTimeout time.Duration
+	// Language is the language of reports without a template
+	Language string
+	SMTP     SMTPConfig
+}
+
+// SMTPConfig is the mail server used to email reports; without Host messages are only logged
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// LoadConfig loads report configuration from environment variables
+func LoadConfig() *Config {
+	cfg := &Config{
+		PollInterval:   utils.GetEnvAsDuration("REPORTS_POLL_INTERVAL", time.Minute),
+		MaxRows:        utils.GetEnvAsInt("REPORTS_MAX_ROWS", 1_000_000),
+		RunHistory:     utils.GetEnvAsInt("REPORTS_RUN_HISTORY", 50),
+		AlertEmails:    utils.GetEnvAsList("REPORTS_ALERT_EMAILS", nil),
+		WebhookTimeout: utils.GetEnvAsDuration("REPORTS_WEBHOOK_TIMEOUT", 0),
+		Language:       utils.GetEnv("REPORTS_LANGUAGE", "en"),
+		SMTP: SMTPConfig{
+			Host:     utils.GetEnv("SMTP_HOST", ""),
+			Port:     utils.GetEnvAsInt("SMTP_PORT", 587),
+			Username: utils.GetEnv("SMTP_USERNAME", ""),
+			Password: utils.GetEnv("SMTP_PASSWORD", ""),
+			From:     utils.GetEnv("SMTP_FROM", "reports@tadb.local"),
+		},
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Minute
+	}
+	if cfg.RunHistory <= 0 {
+		cfg.RunHistory = 50
+	}
+	if _, ok := languages[cfg.Language]; !ok {
+		cfg.Language = "en"
+	}
+	return cfg
+}
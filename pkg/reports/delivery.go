@@ -0,0 +1,155 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/webhook"
+)
+
+// Message is an email, optionally with a report attached
+type Message struct {
+	To         []string
+	Subject    string
+	Body       string
+	Attachment *Output
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// NewMailer returns an SMTP mailer, or a LogMailer when no SMTP host is configured
+func NewMailer(cfg SMTPConfig) Mailer {
+	if cfg.Host == "" {
+		return LogMailer{}
+	}
+	return &SMTPMailer{cfg: cfg}
+}
+
+// LogMailer writes emails to the server log instead of sending them
+type LogMailer struct{}
+
+// Send implements Mailer
+func (LogMailer) Send(_ context.Context, msg *Message) error {
+	attachment := ""
+	if msg.Attachment != nil {
+		attachment = fmt.Sprintf(" with %s (%d bytes)", msg.Attachment.Filename, len(msg.Attachment.Body))
+	}
+	utils.LogInfo(fmt.Sprintf("Email to %s: %s%s (SMTP_HOST not set, not sent)", strings.Join(msg.To, ", "), msg.Subject, attachment))
+	return nil
+}
+
+// SMTPMailer sends emails through an SMTP server, with STARTTLS when offered
+type SMTPMailer struct {
+	cfg SMTPConfig
+}
+
+// Send implements Mailer
+func (m *SMTPMailer) Send(_ context.Context, msg *Message) error {
+	body, err := buildMessage(m.cfg.From, msg)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+	addr := m.cfg.Host + ":" + strconv.Itoa(m.cfg.Port)
+	if err := smtp.SendMail(addr, auth, m.cfg.From, msg.To, body); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// buildMessage encodes msg as a MIME email with the attachment in base64
+func buildMessage(from string, msg *Message) ([]byte, error) {
+	var buf bytes.Buffer
+	header := textproto.MIMEHeader{}
+	header.Set("From", from)
+	header.Set("To", strings.Join(msg.To, ", "))
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("MIME-Version", "1.0")
+
+	mw := multipart.NewWriter(&buf)
+	header.Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	var head bytes.Buffer
+	for _, key := range []string{"From", "To", "Subject", "Date", "MIME-Version", "Content-Type"} {
+		fmt.Fprintf(&head, "%s: %s\r\n", key, header.Get(key))
+	}
+	head.WriteString("\r\n")
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write([]byte(msg.Body)); err != nil {
+		return nil, err
+	}
+	if a := msg.Attachment; a != nil {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(a.Body)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return append(head.Bytes(), buf.Bytes()...), nil
+}
+
+// postWebhook sends the report file as the body of a POST request to d.URL,
+// signed with secret and the delivery ID as nonce, and fills in the outcome of d
+func postWebhook(ctx context.Context, client *http.Client, secret string, d *models.WebhookDelivery, out *Output) error {
+	d.Bytes = int64(len(out.Body))
+	d.DeliveredAt = time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(out.Body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", out.ContentType)
+	req.Header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": out.Filename}))
+	req.Header.Set("X-Report-ID", d.ReportID.String())
+	if d.RunID != nil {
+		req.Header.Set("X-Report-Run-ID", d.RunID.String())
+	}
+	req.Header.Set("X-Report-Period", d.PeriodStart+"/"+d.PeriodEnd)
+	webhook.Sign(req.Header, secret, d.ID.String(), out.Body, d.DeliveredAt)
+
+	resp, err := client.Do(req)
+	d.DurationMs = time.Since(d.DeliveredAt).Milliseconds()
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	resp.Body.Close()
+	d.StatusCode = &resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	d.Succeeded = true
+	return nil
+}
@@ -0,0 +1,538 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	// pdfMaxTableRows caps the table of a PDF; the CSV format has every row
+	pdfMaxTableRows = 500
+	// pdfMaxBars caps the bars of the horizontal bar charts
+	pdfMaxBars = 10
+	pdfMargin  = 15.0
+)
+
+// Chart colours; bars and header fills follow the template branding
+var (
+	colorRenewable    = [3]int{46, 139, 87}
+	colorNonRenewable = [3]int{128, 128, 128}
+	colorBar          = [3]int{52, 101, 164}
+	colorMuted        = [3]int{110, 110, 110}
+	colorHeader       = [3]int{230, 236, 244}
+)
+
+// renderPDF lays out a report as a bulletin made of the sections of its
+// template: key figures, charts of the period, free text and the report table
+func renderPDF(ctx context.Context, repo database.Repository, l *layout, td *templateData, data *dataset, daily []*models.TotalProductionByDate) ([]byte, error) {
+	var share []*models.OperatorMarketShare
+	if l.has(models.ReportSectionOperatorChart) {
+		var err error
+		if share, err = repo.GetMarketShareByOperator(ctx, optional(td.Start), optional(td.End), nil); err != nil {
+			return nil, err
+		}
+	}
+	title, err := execute(l.title, td)
+	if err != nil {
+		return nil, err
+	}
+	footer, err := execute(l.footer, td)
+	if err != nil {
+		return nil, err
+	}
+
+	d := newDocument(l, title, footer)
+	d.heading(title, l.labels["period"]+": "+td.Period+"   ·   "+l.labels["generated"]+" "+td.Generated)
+	for _, s := range l.sections {
+		// The default crosstab title refers to the crosstab, so it is only evaluated when there is one
+		if s.kind == models.ReportSectionCrosstabChart && td.Crosstab == nil {
+			continue
+		}
+		heading, err := execute(s.title, td)
+		if err != nil {
+			return nil, err
+		}
+		body, err := execute(s.body, td)
+		if err != nil {
+			return nil, err
+		}
+		switch s.kind {
+		case models.ReportSectionText:
+			d.section(heading)
+			d.paragraph(body)
+		case models.ReportSectionKeyFigures:
+			d.section(heading)
+			d.keyFigures(td.Figures)
+		case models.ReportSectionDailyChart:
+			d.dailyChart(heading, daily)
+		case models.ReportSectionOperatorChart:
+			d.operatorChart(heading, share)
+		case models.ReportSectionCrosstabChart:
+			d.crosstabChart(heading, td.Crosstab)
+		case models.ReportSectionTable:
+			d.section(heading)
+			d.table(pdfTable(td.Report.Kind, data.records), body)
+			d.annotations(l.labels["annotations"], data.annotations)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := d.pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render pdf: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// document wraps the PDF being built with the text encoder for the core fonts
+// and the layout it follows
+type document struct {
+	pdf *fpdf.Fpdf
+	tr  func(string) string
+	l   *layout
+}
+
+func newDocument(l *layout, title, footer string) *document {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.SetTitle(title, true)
+	pdf.SetAuthor(l.organization, true)
+	pdf.SetCreator("TADB Energy Matrix API", true)
+	pdf.AliasNbPages("")
+	d := &document{pdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor(""), l: l}
+	if l.logo != nil {
+		pdf.RegisterImageOptionsReader("logo", fpdf.ImageOptions{ImageType: l.logoType}, bytes.NewReader(l.logo))
+	}
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-10)
+		pdf.SetFont("Helvetica", "", 7)
+		d.textColor(colorMuted)
+		pdf.CellFormat(0, 4, d.tr(footer), "", 0, "L", false, 0, "")
+		pdf.SetX(pdfMargin)
+		pdf.CellFormat(0, 4, fmt.Sprintf("%s %d/{nb}", d.tr(l.labels["page"]), pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+	return d
+}
+
+func (d *document) width() float64 {
+	w, _ := d.pdf.GetPageSize()
+	return w - 2*pdfMargin
+}
+
+// ensure starts a new page unless h millimetres fit on the current one
+func (d *document) ensure(h float64) {
+	_, pageH := d.pdf.GetPageSize()
+	if d.pdf.GetY()+h > pageH-pdfMargin {
+		d.pdf.AddPage()
+	}
+}
+
+func (d *document) fill(c [3]int)      { d.pdf.SetFillColor(c[0], c[1], c[2]) }
+func (d *document) textColor(c [3]int) { d.pdf.SetTextColor(c[0], c[1], c[2]) }
+
+// heading prints the organization, title and subtitle, with the logo at the top right
+func (d *document) heading(title, subtitle string) {
+	if d.l.logo != nil {
+		const logoH = 14.0
+		w, _ := d.pdf.GetPageSize()
+		info := d.pdf.GetImageInfo("logo")
+		if info != nil && info.Height() > 0 {
+			logoW := math.Min(50, logoH*info.Width()/info.Height())
+			d.pdf.ImageOptions("logo", w-pdfMargin-logoW, pdfMargin, logoW, logoH, false, fpdf.ImageOptions{ImageType: d.l.logoType}, 0, "")
+		}
+	}
+	if d.l.organization != "" {
+		d.pdf.SetFont("Helvetica", "B", 9)
+		d.textColor(colorMuted)
+		d.pdf.CellFormat(0, 5, d.tr(strings.ToUpper(d.l.organization)), "", 1, "L", false, 0, "")
+	}
+	d.pdf.SetFont("Helvetica", "B", 18)
+	d.textColor(d.l.primary)
+	d.pdf.CellFormat(0, 9, d.tr(title), "", 1, "L", false, 0, "")
+	d.pdf.SetFont("Helvetica", "", 9)
+	d.textColor(colorMuted)
+	d.pdf.CellFormat(0, 5, d.tr(subtitle), "", 1, "L", false, 0, "")
+	d.pdf.Ln(4)
+}
+
+// section starts a section; untitled sections only get some spacing
+func (d *document) section(title string) {
+	d.ensure(20)
+	if title == "" {
+		d.pdf.Ln(2)
+		return
+	}
+	d.pdf.Ln(2)
+	d.pdf.SetFont("Helvetica", "B", 12)
+	d.textColor([3]int{0, 0, 0})
+	d.pdf.CellFormat(0, 7, d.tr(title), "", 1, "L", false, 0, "")
+	d.pdf.Ln(1)
+}
+
+// paragraph prints free text, keeping its line breaks
+func (d *document) paragraph(text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	d.pdf.SetFont("Helvetica", "", 9)
+	d.textColor([3]int{0, 0, 0})
+	d.pdf.MultiCell(0, 4.5, d.tr(strings.TrimSpace(text)), "", "L", false)
+	d.pdf.Ln(2)
+}
+
+// keyFigures shows total, renewable and non-renewable production and the renewable share
+func (d *document) keyFigures(f figures) {
+	cards := [][2]string{
+		{d.l.labels["figureTotal"], f.Total.String()},
+		{d.l.labels["figureRenewable"], f.Renewable.String()},
+		{d.l.labels["figureNonRenewable"], f.NonRenewable.String()},
+		{d.l.labels["figureShare"], f.Share},
+	}
+
+	w := d.width() / float64(len(cards))
+	d.ensure(24)
+	x, y := d.pdf.GetXY()
+	d.fill(d.l.accent)
+	for i, f := range cards {
+		d.pdf.Rect(x+float64(i)*w, y, w-2, 18, "F")
+		d.pdf.SetXY(x+float64(i)*w+2, y+2)
+		d.pdf.SetFont("Helvetica", "", 8)
+		d.textColor(colorMuted)
+		d.pdf.CellFormat(w-6, 4, d.tr(f[0]), "", 2, "L", false, 0, "")
+		d.pdf.SetFont("Helvetica", "B", 13)
+		d.textColor([3]int{0, 0, 0})
+		d.pdf.CellFormat(w-6, 8, d.tr(f[1]), "", 0, "L", false, 0, "")
+	}
+	d.pdf.SetXY(x, y+24)
+}
+
+// dailyChart draws daily production as bars stacked by renewable status
+func (d *document) dailyChart(title string, daily []*models.TotalProductionByDate) {
+	if len(daily) == 0 {
+		return
+	}
+	// Rows come newest first
+	labels := make([]string, len(daily))
+	renewable := make([]float64, len(daily))
+	nonRenewable := make([]float64, len(daily))
+	for i, row := range daily {
+		j := len(daily) - 1 - i
+		labels[j] = row.Date
+		renewable[j] = row.RenewableProduction.InexactFloat64()
+		nonRenewable[j] = row.NonRenewableProduction.InexactFloat64()
+	}
+	d.section(title)
+	d.stackedBars(labels, [][]float64{renewable, nonRenewable},
+		[]string{d.l.labels["renewable"], d.l.labels["nonRenewable"]}, [][3]int{colorRenewable, colorNonRenewable})
+}
+
+// operatorChart draws the production of the largest operators
+func (d *document) operatorChart(title string, share []*models.OperatorMarketShare) {
+	var labels []string
+	var values []float64
+	for _, s := range share {
+		if s.TotalProduction.IsZero() {
+			continue
+		}
+		labels = append(labels, s.OperatorName)
+		values = append(values, s.TotalProduction.InexactFloat64())
+	}
+	if len(labels) == 0 {
+		return
+	}
+	d.section(title)
+	d.horizontalBars(labels, values)
+}
+
+// crosstabChart draws the row totals of a crosstab
+func (d *document) crosstabChart(title string, ct *models.Crosstab) {
+	var labels []string
+	var values []float64
+	for i, key := range ct.RowKeys {
+		if v := ct.RowTotals[i]; v != nil {
+			labels = append(labels, key)
+			values = append(values, v.InexactFloat64())
+		}
+	}
+	if len(labels) == 0 {
+		return
+	}
+	d.section(title)
+	d.horizontalBars(labels, values)
+}
+
+// stackedBars draws one vertical bar per label with the series stacked on top of each other
+func (d *document) stackedBars(labels []string, series [][]float64, legend []string, colors [][3]int) {
+	const h, axisW, labelH = 55.0, 16.0, 8.0
+	d.ensure(h + labelH + 8)
+	x0, y0 := d.pdf.GetXY()
+	areaX, areaW, bottom := x0+axisW, d.width()-axisW, y0+h
+
+	totals := make([]float64, len(labels))
+	for _, s := range series {
+		for i, v := range s {
+			totals[i] += v
+		}
+	}
+	top := niceMax(maxOf(totals))
+	d.axis(areaX, areaW, y0, bottom, top)
+
+	slot := areaW / float64(len(labels))
+	barW := slot * 0.7
+	every := int(math.Ceil(float64(len(labels)) / 15))
+	d.pdf.SetFont("Helvetica", "", 6)
+	d.textColor(colorMuted)
+	for i, label := range labels {
+		x := areaX + float64(i)*slot + (slot-barW)/2
+		y := bottom
+		for k, s := range series {
+			bh := s[i] / top * h
+			if bh <= 0 {
+				continue
+			}
+			d.fill(colors[k])
+			d.pdf.Rect(x, y-bh, barW, bh, "F")
+			y -= bh
+		}
+		if i%every == 0 {
+			d.pdf.SetXY(areaX+float64(i)*slot-5, bottom+1)
+			d.pdf.CellFormat(slot+10, 3, d.tr(shortDate(label)), "", 0, "C", false, 0, "")
+		}
+	}
+
+	// Legend
+	d.pdf.SetXY(areaX, bottom+labelH-3)
+	d.pdf.SetFont("Helvetica", "", 7)
+	for k, name := range legend {
+		x, y := d.pdf.GetXY()
+		d.fill(colors[k])
+		d.pdf.Rect(x, y+0.8, 3, 3, "F")
+		d.pdf.SetX(x + 4)
+		d.pdf.CellFormat(d.pdf.GetStringWidth(name)+6, 4.5, d.tr(name), "", 0, "L", false, 0, "")
+	}
+	d.pdf.SetXY(x0, bottom+labelH+4)
+}
+
+// horizontalBars draws the largest values as horizontal bars with their value
+func (d *document) horizontalBars(labels []string, values []float64) {
+	idx := make([]int, len(values))
+	for i := range idx {
+		idx[i] = i
+	}
+	// Largest first, stable for equal values
+	for i := 1; i < len(idx); i++ {
+		for j := i; j > 0 && values[idx[j]] > values[idx[j-1]]; j-- {
+			idx[j], idx[j-1] = idx[j-1], idx[j]
+		}
+	}
+	if len(idx) > pdfMaxBars {
+		idx = idx[:pdfMaxBars]
+	}
+
+	const rowH, labelW, valueW = 6.0, 42.0, 24.0
+	d.ensure(float64(len(idx))*rowH + 4)
+	x0, y := d.pdf.GetXY()
+	barMax := d.width() - labelW - valueW
+	top := maxOf(values)
+	d.pdf.SetFont("Helvetica", "", 8)
+	for _, i := range idx {
+		d.textColor([3]int{0, 0, 0})
+		d.pdf.SetXY(x0, y)
+		d.pdf.CellFormat(labelW, rowH, d.tr(truncate(labels[i], 28)), "", 0, "L", false, 0, "")
+		w := 0.0
+		if top > 0 && values[i] > 0 {
+			w = values[i] / top * barMax
+		}
+		d.fill(d.l.primary)
+		d.pdf.Rect(x0+labelW, y+1, w, rowH-2, "F")
+		d.pdf.SetXY(x0+labelW+w+1, y)
+		d.textColor(colorMuted)
+		d.pdf.CellFormat(valueW, rowH, formatValue(values[i]), "", 0, "L", false, 0, "")
+		y += rowH
+	}
+	d.pdf.SetXY(x0, y+4)
+}
+
+// axis draws the value axis with gridlines from 0 to top
+func (d *document) axis(x, w, yTop, yBottom, top float64) {
+	const ticks = 4
+	d.pdf.SetFont("Helvetica", "", 6)
+	d.textColor(colorMuted)
+	d.pdf.SetLineWidth(0.1)
+	d.pdf.SetDrawColor(210, 210, 210)
+	for t := 0; t <= ticks; t++ {
+		y := yBottom - (yBottom-yTop)*float64(t)/ticks
+		d.pdf.Line(x, y, x+w, y)
+		d.pdf.SetXY(x-16, y-1.5)
+		d.pdf.CellFormat(15, 3, formatValue(top*float64(t)/ticks), "", 0, "R", false, 0, "")
+	}
+	d.pdf.SetDrawColor(0, 0, 0)
+}
+
+// table prints records (header first) in blocks of columns that fit the page
+// width, repeating the first column in every block
+func (d *document) table(records [][]string, note string) {
+	if len(records) == 0 {
+		return
+	}
+	const rowH, firstW, minW = 5.0, 38.0, 16.0
+	header, body := records[0], records[1:]
+	perBlock := int((d.width() - firstW) / minW)
+	d.pdf.SetFont("Helvetica", "", 7)
+	for from := 1; from < len(header) || from == 1; from += perBlock {
+		to := from + perBlock
+		if to > len(header) {
+			to = len(header)
+		}
+		colW := minW
+		if n := to - from; n > 0 {
+			colW = math.Min(40, (d.width()-firstW)/float64(n))
+		}
+		row := func(cells []string, fill bool) {
+			d.ensure(rowH)
+			d.pdf.CellFormat(firstW, rowH, d.tr(truncate(cells[0], 26)), "B", 0, "L", fill, 0, "")
+			for i := from; i < to; i++ {
+				d.pdf.CellFormat(colW, rowH, d.tr(truncate(cells[i], int(colW/1.6))), "B", 0, "R", fill, 0, "")
+			}
+			d.pdf.Ln(-1)
+		}
+		d.fill(d.l.accent)
+		d.pdf.SetFont("Helvetica", "B", 7)
+		d.textColor([3]int{0, 0, 0})
+		row(header, true)
+		d.pdf.SetFont("Helvetica", "", 7)
+		for _, cells := range body {
+			row(cells, false)
+		}
+		d.pdf.Ln(3)
+		if to >= len(header) {
+			break
+		}
+	}
+	if note = strings.TrimSpace(note); note != "" {
+		d.pdf.SetFont("Helvetica", "I", 7)
+		d.textColor(colorMuted)
+		d.pdf.CellFormat(0, 4, d.tr(note), "", 1, "L", false, 0, "")
+	}
+}
+
+// annotations lists the notes on the data of the report below its table
+func (d *document) annotations(title string, list []*models.Annotation) {
+	if len(list) == 0 {
+		return
+	}
+	d.pdf.SetFont("Helvetica", "B", 8)
+	d.textColor([3]int{0, 0, 0})
+	d.ensure(5)
+	d.pdf.CellFormat(0, 5, d.tr(title), "", 1, "L", false, 0, "")
+	d.pdf.SetFont("Helvetica", "", 7)
+	for _, a := range list {
+		var when string
+		switch {
+		case a.ProductionID != nil:
+			when = a.ProductionID.String()[:8]
+		case *a.StartDate == *a.EndDate:
+			when = *a.StartDate
+		default:
+			when = *a.StartDate + " - " + *a.EndDate
+		}
+		if a.GeneratorID != nil {
+			when += " · " + a.GeneratorID.String()[:8]
+		}
+		d.ensure(4)
+		d.pdf.MultiCell(0, 4, d.tr(when+": "+a.Note), "", "L", false)
+	}
+	d.pdf.Ln(2)
+}
+
+// pdfTable picks the columns shown in the PDF and caps the rows; production
+// IDs are left out and generator IDs shortened so the table fits the page
+func pdfTable(kind string, records [][]string) [][]string {
+	if kind == models.ReportKindProductions {
+		// id, generatorId, typeName, date, productionMw, source, sourceRef, annotations
+		cols := []int{3, 2, 1, 4, 5}
+		picked := make([][]string, len(records))
+		for i, r := range records {
+			row := make([]string, len(cols))
+			for j, c := range cols {
+				row[j] = r[c]
+			}
+			if i > 0 {
+				row[2] = truncate(row[2], 8)
+			}
+			picked[i] = row
+		}
+		records = picked
+	}
+	if len(records) > pdfMaxTableRows+1 {
+		records = records[:pdfMaxTableRows+1]
+	}
+	return records
+}
+
+func maxOf(values []float64) float64 {
+	top := 0.0
+	for _, v := range values {
+		top = math.Max(top, v)
+	}
+	return top
+}
+
+// niceMax rounds top up to 1, 2, 2.5 or 5 times a power of ten for readable axis ticks
+func niceMax(top float64) float64 {
+	if top <= 0 {
+		return 1
+	}
+	pow := math.Pow(10, math.Floor(math.Log10(top)))
+	for _, m := range []float64{1, 2, 2.5, 5, 10} {
+		if top <= m*pow {
+			return m * pow
+		}
+	}
+	return 10 * pow
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.Abs(v) >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case math.Abs(v) >= 1e4:
+		return fmt.Sprintf("%.1fk", v/1e3)
+	case v == math.Trunc(v):
+		return fmt.Sprintf("%.0f", v)
+	}
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.3f", v), "0"), ".")
+}
+
+// shortDate drops the year of YYYY-MM-DD labels
+func shortDate(s string) string {
+	if len(s) == len("2006-01-02") && s[4] == '-' {
+		return s[5:]
+	}
+	return s
+}
+
+func truncate(s string, n int) string {
+	if n < 1 {
+		n = 1
+	}
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
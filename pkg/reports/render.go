@@ -0,0 +1,234 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/shopspring/decimal"
+)
+
+// Output is a rendered report file
+type Output struct {
+	Filename    string
+	ContentType string
+	Body        []byte
+	// Rows is the number of data rows in the report
+	Rows int64
+	// Subject and Text are the email of the report, from its template
+	Subject string
+	Text    string
+}
+
+// dataset is the data of a report, as a table and (for crosstabs) the matrix it came from
+type dataset struct {
+	// records is the report table, header first
+	records  [][]string
+	rows     int64
+	crosstab *models.Crosstab
+	// annotations are the notes on the production data of the report, in date order
+	annotations []*models.Annotation
+}
+
+// Render computes a report over the inclusive date range start..end (empty
+// bounds are open) and encodes it in the report format, laid out by the
+// report template
+func Render(ctx context.Context, repo database.Repository, rep *models.Report, start, end string, cfg *Config) (*Output, error) {
+	l, err := loadLayout(ctx, repo, rep, cfg.Language)
+	if err != nil {
+		return nil, err
+	}
+
+	var data *dataset
+	switch rep.Kind {
+	case models.ReportKindProductions:
+		data, err = productionData(ctx, repo, rep.Filters, start, end, cfg.MaxRows)
+	case models.ReportKindCrosstab:
+		data, err = crosstabData(ctx, repo, rep.Filters, start, end)
+	default:
+		return nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidReport, rep.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	daily, err := repo.GetTotalProductionByDate(ctx, optional(start), optional(end), nil)
+	if err != nil {
+		return nil, err
+	}
+	td := l.data(rep, start, end, data.rows)
+	td.Figures = keyFigures(daily)
+	td.Crosstab = data.crosstab
+	rows := len(data.records) - 1
+	td.Table = tableInfo{Rows: rows, Shown: rows}
+
+	out := &Output{Rows: data.rows}
+	switch rep.Format {
+	case models.ReportFormatPDF:
+		if rows > pdfMaxTableRows {
+			td.Table = tableInfo{Rows: rows, Shown: pdfMaxTableRows, Truncated: true}
+		}
+		if out.Body, err = renderPDF(ctx, repo, l, td, data, daily); err != nil {
+			return nil, err
+		}
+		out.Filename = filename(rep, start, end) + ".pdf"
+		out.ContentType = "application/pdf"
+	case models.ReportFormatCSV:
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		if err := w.WriteAll(data.records); err != nil {
+			return nil, fmt.Errorf("failed to write csv: %w", err)
+		}
+		out.Body = buf.Bytes()
+		out.Filename = filename(rep, start, end) + ".csv"
+		out.ContentType = "text/csv; charset=utf-8"
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidReport, rep.Format)
+	}
+
+	if out.Subject, err = execute(l.subject, td); err != nil {
+		return nil, err
+	}
+	if out.Text, err = execute(l.body, td); err != nil {
+		return nil, err
+	}
+	out.Subject = strings.Join(strings.Fields(out.Subject), " ")
+	return out, nil
+}
+
+// loadLayout compiles the template of a report; reports without one, or
+// whose template was deleted, use the standard bulletin
+func loadLayout(ctx context.Context, repo database.Repository, rep *models.Report, defaultLanguage string) (*layout, error) {
+	var t *models.ReportTemplate
+	if rep.TemplateID != nil {
+		var err error
+		t, err = repo.GetReportTemplateByID(ctx, *rep.TemplateID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+	return compileTemplate(t, defaultLanguage)
+}
+
+// keyFigures sums the daily totals of the period
+func keyFigures(daily []*models.TotalProductionByDate) figures {
+	var f figures
+	for _, row := range daily {
+		f.Total = f.Total.Add(row.TotalProduction)
+		f.Renewable = f.Renewable.Add(row.RenewableProduction)
+		f.NonRenewable = f.NonRenewable.Add(row.NonRenewableProduction)
+	}
+	f.Share = "-"
+	if !f.Total.IsZero() {
+		f.Share = f.Renewable.Div(f.Total).Mul(decimal.NewFromInt(100)).StringFixed(1) + " %"
+	}
+	return f
+}
+
+var unsafeFilename = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// filename names the output after the report and its period, e.g. monthly-bulletin_2025-09-01_2025-09-30
+func filename(rep *models.Report, start, end string) string {
+	name := strings.Trim(unsafeFilename.ReplaceAllString(strings.ToLower(rep.Name), "-"), "-")
+	if name == "" {
+		name = "report"
+	}
+	if start != "" || end != "" {
+		name += "_" + start + "_" + end
+	}
+	return name
+}
+
+func productionData(ctx context.Context, repo database.Repository, f models.ReportFilters, start, end string, maxRows int) (*dataset, error) {
+	filter := &models.ProductionFilter{GeneratorID: f.GeneratorID}
+	if start != "" {
+		filter.StartDate = &start
+	}
+	if end != "" {
+		filter.EndDate = &end
+	}
+	if f.Source != "" {
+		filter.Source = &f.Source
+	}
+	if maxRows > 0 {
+		filter.Limit = maxRows + 1
+	}
+	list, err := repo.GetAllProductions(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if maxRows > 0 && len(list) > maxRows {
+		return nil, fmt.Errorf("report has more than %d rows; narrow its filters", maxRows)
+	}
+
+	annotations, err := repo.GetAnnotations(ctx, &models.AnnotationFilter{GeneratorID: f.GeneratorID, StartDate: filter.StartDate, EndDate: filter.EndDate})
+	if err != nil {
+		return nil, err
+	}
+
+	records := [][]string{{"id", "generatorId", "typeName", "date", "productionMw", "source", "sourceRef", "annotations"}}
+	for _, p := range list {
+		records = append(records, []string{
+			p.ID.String(), p.GeneratorID.String(), p.TypeName, p.Date, p.ProductionMW.String(), p.Source, p.SourceRef,
+			annotationText(annotations, p),
+		})
+	}
+	return &dataset{records: records, rows: int64(len(list)), annotations: annotations}, nil
+}
+
+// annotationText joins the notes that apply to a production record
+func annotationText(annotations []*models.Annotation, p *models.Production) string {
+	var notes []string
+	for _, a := range annotations {
+		if a.AppliesTo(p) {
+			notes = append(notes, a.Note)
+		}
+	}
+	return strings.Join(notes, " | ")
+}
+
+// crosstabData lays the matrix out with the column keys as header, a total
+// per row in the last column and a totals row at the bottom
+func crosstabData(ctx context.Context, repo database.Repository, f models.ReportFilters, start, end string) (*dataset, error) {
+	f = crosstabFilters(f)
+	q := &models.CrosstabQuery{Rows: f.Rows, Columns: f.Cols, Value: f.Value, Metric: f.Metric}
+	if start != "" {
+		q.StartDate = &start
+	}
+	if end != "" {
+		q.EndDate = &end
+	}
+	ct, err := repo.GetCrosstab(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append([]string{ct.Rows + " / " + ct.Columns}, ct.ColumnKeys...)
+	records := [][]string{append(header, "Total")}
+	for i, key := range ct.RowKeys {
+		record := []string{key}
+		for _, v := range ct.Cells[i] {
+			record = append(record, cellText(v))
+		}
+		records = append(records, append(record, cellText(ct.RowTotals[i])))
+	}
+	totals := []string{"Total"}
+	for _, v := range ct.ColumnTotals {
+		totals = append(totals, cellText(v))
+	}
+	records = append(records, append(totals, cellText(ct.Total)))
+	return &dataset{records: records, rows: int64(len(ct.RowKeys)), crosstab: ct}, nil
+}
+
+func cellText(v *decimal.Decimal) string {
+	if v == nil {
+		return ""
+	}
+	return v.String()
+}
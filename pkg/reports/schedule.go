@@ -0,0 +1,210 @@
+package reports
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	// Embedded zone database so schedule timezones resolve in minimal containers
+	_ "time/tzdata"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/calendar"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/metric"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+)
+
+// Schedule frequencies
+const (
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+)
+
+// ErrInvalidReport is returned for report definitions that cannot be run
+var ErrInvalidReport = errors.New("invalid report")
+
+// Validate checks the parts of a report definition that binding tags cannot:
+// the schedule day, timezone and calendar code and the crosstab dimensions;
+// whether the calendar exists is checked when it is resolved
+func Validate(req *models.ReportRequest) error {
+	s := req.Schedule
+	if _, err := location(s); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidReport, s.Timezone)
+	}
+	if s.Calendar != "" {
+		if _, _, err := calendar.ParseCode(s.Calendar); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidReport, err)
+		}
+	}
+	switch s.Frequency {
+	case FrequencyWeekly:
+		if s.Day > 6 {
+			return fmt.Errorf("%w: day must be a weekday from 0 (Sunday) to 6 for weekly reports", ErrInvalidReport)
+		}
+	case FrequencyMonthly:
+		if s.Day < 1 {
+			return fmt.Errorf("%w: day must be a day of the month from 1 to 28 for monthly reports", ErrInvalidReport)
+		}
+	}
+	if f := req.Filters; f.StartDate != "" && f.EndDate != "" && f.StartDate > f.EndDate {
+		return fmt.Errorf("%w: startDate is after endDate", ErrInvalidReport)
+	}
+
+	if req.Kind == models.ReportKindCrosstab {
+		f := crosstabFilters(req.Filters)
+		if !contains(database.CrosstabDimensions(), f.Rows) || !contains(database.CrosstabDimensions(), f.Cols) {
+			return fmt.Errorf("%w: rows and cols must be one of %s", ErrInvalidReport, strings.Join(database.CrosstabDimensions(), ", "))
+		}
+		if f.Rows == f.Cols {
+			return fmt.Errorf("%w: rows and cols must be different dimensions", ErrInvalidReport)
+		}
+		if f.Metric != "" {
+			if _, err := metric.Parse(f.Metric); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidReport, err)
+			}
+		} else if !contains(database.CrosstabValues(), f.Value) {
+			return fmt.Errorf("%w: value must be one of %s", ErrInvalidReport, strings.Join(database.CrosstabValues(), ", "))
+		}
+	}
+	return nil
+}
+
+// crosstabFilters fills in the crosstab defaults of the analytics endpoint
+func crosstabFilters(f models.ReportFilters) models.ReportFilters {
+	if f.Rows == "" {
+		f.Rows = "type"
+	}
+	if f.Cols == "" {
+		f.Cols = "day"
+	}
+	if f.Value == "" {
+		f.Value = "sum"
+	}
+	return f
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func location(s models.ReportSchedule) (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(s.Timezone)
+}
+
+// NextRun returns the first scheduled time strictly after after, or nil for
+// reports that only run on demand. With cal, the calendar of the schedule,
+// runs on non-business days move to the next business day at the same hour.
+func NextRun(s models.ReportSchedule, after time.Time, cal *calendar.Calendar) *time.Time {
+	if s.Frequency == "" {
+		return nil
+	}
+	loc, err := location(s)
+	if err != nil {
+		return nil
+	}
+	if cal == nil {
+		return nominalRun(s, after, loc)
+	}
+	// Moving runs keeps their order, so the first moved run after after is
+	// found from the runs due a month before it on
+	from := after.AddDate(0, -1, 0)
+	for i := 0; i < 400; i++ {
+		nominal := nominalRun(s, from, loc)
+		if nominal == nil {
+			return nil
+		}
+		t := nominal.In(loc)
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		for !cal.IsBusinessDay(day) {
+			day = day.AddDate(0, 0, 1)
+		}
+		next := time.Date(day.Year(), day.Month(), day.Day(), s.Hour, 0, 0, 0, loc).UTC()
+		if next.After(after) {
+			return &next
+		}
+		from = *nominal
+	}
+	return nil
+}
+
+// nominalRun returns the first time of the schedule strictly after after,
+// regardless of business days
+func nominalRun(s models.ReportSchedule, after time.Time, loc *time.Location) *time.Time {
+	t := after.In(loc)
+	var next time.Time
+	switch s.Frequency {
+	case FrequencyDaily:
+		next = time.Date(t.Year(), t.Month(), t.Day(), s.Hour, 0, 0, 0, loc)
+		if !next.After(t) {
+			next = next.AddDate(0, 0, 1)
+		}
+	case FrequencyWeekly:
+		next = time.Date(t.Year(), t.Month(), t.Day(), s.Hour, 0, 0, 0, loc)
+		next = next.AddDate(0, 0, (s.Day-int(t.Weekday())+7)%7)
+		if !next.After(t) {
+			next = next.AddDate(0, 0, 7)
+		}
+	case FrequencyMonthly:
+		next = time.Date(t.Year(), t.Month(), s.Day, s.Hour, 0, 0, 0, loc)
+		if !next.After(t) {
+			next = next.AddDate(0, 1, 0)
+		}
+	default:
+		return nil
+	}
+	next = next.UTC()
+	return &next
+}
+
+// Period returns the inclusive date range a run at time at covers: the fixed
+// range of the filters when set, otherwise the period before the run (the
+// previous day, the previous 7 days or the previous calendar month). On-demand
+// reports without range cover all data. With cal, the calendar of the
+// schedule, daily runs cover the days since the previous business day and
+// moved weekly and monthly runs the period before the day they were due.
+func Period(rep *models.Report, at time.Time, cal *calendar.Calendar) (start, end string) {
+	if rep.Filters.StartDate != "" || rep.Filters.EndDate != "" {
+		return rep.Filters.StartDate, rep.Filters.EndDate
+	}
+	loc, err := location(rep.Schedule)
+	if err != nil {
+		loc = time.UTC
+	}
+	t := at.In(loc)
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	const layout = "2006-01-02"
+	if cal != nil {
+		switch s := rep.Schedule; s.Frequency {
+		case FrequencyDaily:
+			return cal.PreviousBusinessDay(today).Format(layout), today.AddDate(0, 0, -1).Format(layout)
+		case FrequencyWeekly:
+			due := today.AddDate(0, 0, -((int(today.Weekday()) - s.Day + 7) % 7))
+			return due.AddDate(0, 0, -7).Format(layout), due.AddDate(0, 0, -1).Format(layout)
+		case FrequencyMonthly:
+			if t.Day() < s.Day {
+				t = t.AddDate(0, 0, -t.Day())
+			}
+		}
+	}
+	switch rep.Schedule.Frequency {
+	case FrequencyDaily:
+		day := today.AddDate(0, 0, -1)
+		return day.Format(layout), day.Format(layout)
+	case FrequencyWeekly:
+		return today.AddDate(0, 0, -7).Format(layout), today.AddDate(0, 0, -1).Format(layout)
+	case FrequencyMonthly:
+		first := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return first.AddDate(0, -1, 0).Format(layout), first.AddDate(0, 0, -1).Format(layout)
+	}
+	return "", ""
+}
@@ -0,0 +1,240 @@
+package reports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/calendar"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpclient"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// Run triggers
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// Scheduler runs saved reports when they are due and delivers their output
+type Scheduler struct {
+	repo      database.Repository
+	cfg       *Config
+	calendars *calendar.Service
+	mailer    Mailer
+	client    *http.Client
+}
+
+// NewScheduler creates a report scheduler resolving schedule calendars with
+// calendars; a nil mailer sends through the configured SMTP server
+func NewScheduler(repo database.Repository, cfg *Config, calendars *calendar.Service, mailer Mailer) *Scheduler {
+	if mailer == nil {
+		mailer = NewMailer(cfg.SMTP)
+	}
+	return &Scheduler{
+		repo:      repo,
+		cfg:       cfg,
+		calendars: calendars,
+		mailer:    mailer,
+		client:    httpclient.New(cfg.WebhookTimeout),
+	}
+}
+
+// Config returns the scheduler configuration
+func (s *Scheduler) Config() *Config {
+	return s.cfg
+}
+
+// Calendar resolves the calendar of a schedule, nil when it has none
+func (s *Scheduler) Calendar(ctx context.Context, schedule models.ReportSchedule) (*calendar.Calendar, error) {
+	if schedule.Calendar == "" {
+		return nil, nil
+	}
+	return s.calendars.Calendar(ctx, schedule.Calendar)
+}
+
+// Run checks for due reports every poll interval until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.runDue(ctx, now)
+		}
+	}
+}
+
+// runDue executes every due report once. Runs missed while the server was
+// down collapse into a single run; the claim keeps other instances from
+// running the same report.
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	due, err := s.repo.GetDueReports(ctx, now)
+	if err != nil {
+		utils.LogError("reports: list due reports", err)
+		return
+	}
+	for _, rep := range due {
+		at := *rep.NextRunAt
+		cal, err := s.Calendar(ctx, rep.Schedule)
+		if err != nil {
+			// Keep the report running on its days, weekends and holidays included
+			utils.LogError("reports: calendar of "+rep.ID.String(), err)
+		}
+		claimed, err := s.repo.ClaimReportRun(ctx, rep.ID, at, NextRun(rep.Schedule, now, cal))
+		if err != nil {
+			utils.LogError("reports: claim "+rep.ID.String(), err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if _, err := s.Execute(ctx, rep, TriggerSchedule, at); err != nil {
+			utils.LogError("reports: run "+rep.ID.String(), err)
+		}
+	}
+}
+
+// Execute renders a report as of time at, delivers it and records the run.
+// Failed runs are reported to the alert addresses of the report (or
+// REPORTS_ALERT_EMAILS); the run is returned together with its error.
+func (s *Scheduler) Execute(ctx context.Context, rep *models.Report, trigger string, at time.Time) (*models.ReportRun, error) {
+	cal, err := s.Calendar(ctx, rep.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	start, end := Period(rep, at, cal)
+	run := &models.ReportRun{
+		ID:          uuid.New(),
+		ReportID:    rep.ID,
+		Trigger:     trigger,
+		Status:      models.ReportRunRunning,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		StartedAt:   time.Now(),
+	}
+	if err := s.repo.CreateReportRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	out, err := Render(ctx, s.repo, rep, start, end, s.cfg)
+	if err == nil {
+		run.Rows = out.Rows
+		run.Bytes = int64(len(out.Body))
+		err = s.deliver(ctx, rep, run, out)
+	}
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.Status = models.ReportRunSucceeded
+	if err != nil {
+		run.Status = models.ReportRunFailed
+		run.Error = err.Error()
+		s.alert(ctx, rep, run)
+	}
+	if ferr := s.repo.FinishReportRun(ctx, run); ferr != nil {
+		utils.LogError("reports: record run "+run.ID.String(), ferr)
+	}
+	return run, err
+}
+
+// deliver sends the output to every target, trying all of them before
+// reporting the ones that failed
+func (s *Scheduler) deliver(ctx context.Context, rep *models.Report, run *models.ReportRun, out *Output) error {
+	var errs []error
+	if len(rep.Delivery.Emails) > 0 {
+		msg := &Message{
+			To:         rep.Delivery.Emails,
+			Subject:    out.Subject,
+			Body:       out.Text,
+			Attachment: out,
+		}
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("email: %w", err))
+		}
+	}
+	if rep.Delivery.WebhookURL != "" {
+		if _, err := s.sendWebhook(ctx, rep, &run.ID, run.PeriodStart, run.PeriodEnd, out, nil); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ErrNoWebhook is returned when redelivering to a report without webhook URL
+var ErrNoWebhook = errors.New("report has no webhook URL")
+
+// Redeliver renders the period of a past delivery again and posts it to the
+// current webhook URL of the report as a new, freshly signed delivery. The
+// delivery is returned together with its error.
+func (s *Scheduler) Redeliver(ctx context.Context, rep *models.Report, original *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	if rep.Delivery.WebhookURL == "" {
+		return nil, ErrNoWebhook
+	}
+	out, err := Render(ctx, s.repo, rep, original.PeriodStart, original.PeriodEnd, s.cfg)
+	if err != nil {
+		return nil, err
+	}
+	return s.sendWebhook(ctx, rep, original.RunID, original.PeriodStart, original.PeriodEnd, out, &original.ID)
+}
+
+// sendWebhook posts out to the webhook of rep and logs the delivery
+func (s *Scheduler) sendWebhook(ctx context.Context, rep *models.Report, runID *uuid.UUID, start, end string, out *Output, redeliveryOf *uuid.UUID) (*models.WebhookDelivery, error) {
+	secret, err := s.repo.GetReportWebhookSecret(ctx, rep.ID)
+	if err != nil {
+		return nil, err
+	}
+	d := &models.WebhookDelivery{
+		ID:           uuid.New(),
+		ReportID:     rep.ID,
+		RunID:        runID,
+		RedeliveryOf: redeliveryOf,
+		URL:          rep.Delivery.WebhookURL,
+		PeriodStart:  start,
+		PeriodEnd:    end,
+	}
+	err = postWebhook(ctx, s.client, secret, d, out)
+	if err != nil {
+		d.Error = err.Error()
+	}
+	if lerr := s.repo.CreateWebhookDelivery(ctx, d); lerr != nil {
+		utils.LogError("reports: record webhook delivery "+d.ID.String(), lerr)
+	}
+	return d, err
+}
+
+// alert tells the alert recipients that a run failed; it only logs when there are none
+func (s *Scheduler) alert(ctx context.Context, rep *models.Report, run *models.ReportRun) {
+	utils.LogInfo(fmt.Sprintf("Report %q (%s) failed: %s", rep.Name, rep.ID, run.Error))
+	to := rep.Delivery.AlertEmails
+	if len(to) == 0 {
+		to = s.cfg.AlertEmails
+	}
+	if len(to) == 0 {
+		return
+	}
+	msg := &Message{
+		To:      to,
+		Subject: "Report failed: " + rep.Name,
+		Body: fmt.Sprintf("The %s run of report %q (%s) for %s failed at %s:\n\n%s\n",
+			run.Trigger, rep.Name, rep.ID, period(run), run.StartedAt.UTC().Format(time.RFC3339), run.Error),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		utils.LogError("reports: failure alert for "+rep.ID.String(), err)
+	}
+}
+
+func period(run *models.ReportRun) string {
+	if run.PeriodStart == "" && run.PeriodEnd == "" {
+		return "all data"
+	}
+	return strings.TrimSpace(run.PeriodStart + " to " + run.PeriodEnd)
+}
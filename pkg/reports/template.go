@@ -0,0 +1,382 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/shopspring/decimal"
+)
+
+// ErrInvalidTemplate is returned for report templates that cannot be rendered
+var ErrInvalidTemplate = errors.New("invalid report template")
+
+// maxLogoBytes caps the decoded logo of a template
+const maxLogoBytes = 512 << 10
+
+// languages are the built-in texts of each template language. Labels are
+// plain words used inside charts and headings; the other texts are the
+// default templates of the language.
+var languages = map[string]*language{
+	"en": {
+		labels: map[string]string{
+			"allData":            "all data",
+			"from":               "from",
+			"until":              "until",
+			"to":                 "to",
+			"period":             "Period",
+			"generated":          "Generated",
+			"rows":               "Rows",
+			"page":               "Page",
+			"by":                 "by",
+			"total":              "Total",
+			"renewable":          "Renewable",
+			"nonRenewable":       "Non-renewable",
+			"figureTotal":        "Total production (MW)",
+			"figureRenewable":    "Renewable (MW)",
+			"figureNonRenewable": "Non-renewable (MW)",
+			"figureShare":        "Renewable share",
+			"annotations":        "Annotations",
+		},
+		titles: map[string]string{
+			models.ReportSectionDailyChart:    "Daily production (MW)",
+			models.ReportSectionOperatorChart: "Production by operator (MW)",
+			models.ReportSectionTable:         "Data",
+		},
+		tableNote: `{{if .Table.Truncated}}Showing {{.Table.Shown}} of {{.Table.Rows}} rows; the CSV format has all of them.{{end}}`,
+	},
+	"es": {
+		labels: map[string]string{
+			"allData":            "todos los datos",
+			"from":               "desde",
+			"until":              "hasta",
+			"to":                 "a",
+			"period":             "Periodo",
+			"generated":          "Generado",
+			"rows":               "Filas",
+			"page":               "Página",
+			"by":                 "por",
+			"total":              "Total",
+			"renewable":          "Renovable",
+			"nonRenewable":       "No renovable",
+			"figureTotal":        "Producción total (MW)",
+			"figureRenewable":    "Renovable (MW)",
+			"figureNonRenewable": "No renovable (MW)",
+			"figureShare":        "Participación renovable",
+			"annotations":        "Anotaciones",
+		},
+		titles: map[string]string{
+			models.ReportSectionDailyChart:    "Producción diaria (MW)",
+			models.ReportSectionOperatorChart: "Producción por operador (MW)",
+			models.ReportSectionTable:         "Datos",
+		},
+		tableNote: `{{if .Table.Truncated}}Se muestran {{.Table.Shown}} de {{.Table.Rows}} filas; el formato CSV las tiene todas.{{end}}`,
+	},
+}
+
+type language struct {
+	labels    map[string]string
+	titles    map[string]string
+	tableNote string
+}
+
+// Defaults shared by every language
+const (
+	defaultTitle        = `{{.Report.Name}}`
+	defaultFooter       = `{{.Report.Name}}`
+	defaultSubject      = `{{.Report.Name}}{{if or .Start .End}} ({{.Period}}){{end}}`
+	defaultEmailBody    = "{{.Report.Name}}\n\n{{label \"period\"}}: {{.Period}}\n{{label \"rows\"}}: {{.Rows}}\n"
+	defaultCrosstabName = `{{.Crosstab.Value}} {{label "by"}} {{.Crosstab.Rows}}`
+)
+
+// defaultSections is the standard bulletin
+var defaultSections = []models.ReportSection{
+	{Type: models.ReportSectionKeyFigures},
+	{Type: models.ReportSectionDailyChart},
+	{Type: models.ReportSectionOperatorChart},
+	{Type: models.ReportSectionCrosstabChart},
+	{Type: models.ReportSectionTable},
+}
+
+// Languages lists the built-in template languages
+func Languages() []string {
+	return sortedKeys(languages)
+}
+
+// Labels lists the label keys a template may override
+func Labels() []string {
+	return sortedKeys(languages["en"].labels)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// layout is a compiled report template
+type layout struct {
+	labels        map[string]string
+	organization  string
+	primary       [3]int
+	accent        [3]int
+	logo          []byte
+	logoType      string
+	title, footer *template.Template
+	subject, body *template.Template
+	sections      []section
+}
+
+type section struct {
+	kind        string
+	title, body *template.Template
+}
+
+// templateData is what the texts of a template are evaluated with
+type templateData struct {
+	Report       *models.Report
+	Organization string
+	// Period is the covered range in words; Start and End are its bounds (empty when open)
+	Period     string
+	Start, End string
+	Generated  string
+	Rows       int64
+	Figures    figures
+	Crosstab   *models.Crosstab
+	Table      tableInfo
+}
+
+// figures are the key figures of the period
+type figures struct {
+	Total, Renewable, NonRenewable decimal.Decimal
+	// Share is the renewable share as a percentage, "-" without production
+	Share string
+}
+
+type tableInfo struct {
+	Rows, Shown int
+	Truncated   bool
+}
+
+// compileTemplate turns a stored template (nil for the standard bulletin) into a layout
+func compileTemplate(t *models.ReportTemplate, defaultLanguage string) (*layout, error) {
+	if t == nil {
+		t = &models.ReportTemplate{Language: defaultLanguage}
+	}
+	lang, ok := languages[t.Language]
+	if !ok {
+		lang = languages["en"]
+	}
+
+	l := &layout{
+		labels:       make(map[string]string, len(lang.labels)),
+		organization: t.Branding.Organization,
+		primary:      colorBar,
+		accent:       colorHeader,
+	}
+	for k, v := range lang.labels {
+		l.labels[k] = v
+	}
+	for k, v := range t.Labels {
+		if _, ok := l.labels[k]; !ok {
+			return nil, fmt.Errorf("%w: unknown label %q (labels: %s)", ErrInvalidTemplate, k, strings.Join(Labels(), ", "))
+		}
+		l.labels[k] = v
+	}
+
+	var err error
+	if t.Branding.PrimaryColor != "" {
+		if l.primary, err = parseColor(t.Branding.PrimaryColor); err != nil {
+			return nil, err
+		}
+	}
+	if t.Branding.AccentColor != "" {
+		if l.accent, err = parseColor(t.Branding.AccentColor); err != nil {
+			return nil, err
+		}
+	}
+	if t.Branding.Logo != "" {
+		if l.logo, l.logoType, err = decodeLogo(t.Branding.Logo); err != nil {
+			return nil, err
+		}
+	}
+
+	parse := func(name, text, fallback string) (*template.Template, error) {
+		if text == "" {
+			text = fallback
+		}
+		tpl, err := template.New(name).Funcs(template.FuncMap{"label": l.label}).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+		}
+		return tpl, nil
+	}
+	if l.title, err = parse("title", t.Branding.Title, defaultTitle); err != nil {
+		return nil, err
+	}
+	if l.footer, err = parse("footer", t.Branding.Footer, defaultFooter); err != nil {
+		return nil, err
+	}
+	if l.subject, err = parse("subject", t.Subject, defaultSubject); err != nil {
+		return nil, err
+	}
+	if l.body, err = parse("emailBody", t.EmailBody, defaultEmailBody); err != nil {
+		return nil, err
+	}
+
+	sections := t.Sections
+	if len(sections) == 0 {
+		sections = defaultSections
+	}
+	for i, s := range sections {
+		title := lang.titles[s.Type]
+		if s.Type == models.ReportSectionCrosstabChart {
+			title = defaultCrosstabName
+		}
+		body := ""
+		if s.Type == models.ReportSectionTable {
+			body = lang.tableNote
+		}
+		sec := section{kind: s.Type}
+		if sec.title, err = parse(fmt.Sprintf("sections[%d].title", i), s.Title, title); err != nil {
+			return nil, err
+		}
+		if sec.body, err = parse(fmt.Sprintf("sections[%d].body", i), s.Body, body); err != nil {
+			return nil, err
+		}
+		l.sections = append(l.sections, sec)
+	}
+	return l, nil
+}
+
+// label is the template function returning a label of the layout language
+func (l *layout) label(key string) (string, error) {
+	v, ok := l.labels[key]
+	if !ok {
+		return "", fmt.Errorf("unknown label %q", key)
+	}
+	return v, nil
+}
+
+// has reports whether the layout has a section of the given kind
+func (l *layout) has(kind string) bool {
+	for _, s := range l.sections {
+		if s.kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// period describes the inclusive range start..end in the layout language
+func (l *layout) period(start, end string) string {
+	switch {
+	case start == "" && end == "":
+		return l.labels["allData"]
+	case start == "":
+		return l.labels["until"] + " " + end
+	case end == "":
+		return l.labels["from"] + " " + start
+	case start == end:
+		return start
+	}
+	return start + " " + l.labels["to"] + " " + end
+}
+
+func (l *layout) data(rep *models.Report, start, end string, rows int64) *templateData {
+	return &templateData{
+		Report:       rep,
+		Organization: l.organization,
+		Period:       l.period(start, end),
+		Start:        start,
+		End:          end,
+		Generated:    time.Now().UTC().Format("2006-01-02 15:04 MST"),
+		Rows:         rows,
+		Figures:      figures{Share: "-"},
+	}
+}
+
+func execute(tpl *template.Template, data *templateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render report template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// ValidateTemplate checks that a template compiles and that its texts
+// render against sample data of a crosstab report
+func ValidateTemplate(req *models.ReportTemplateRequest) error {
+	l, err := compileTemplate(&models.ReportTemplate{
+		Language:  req.Language,
+		Branding:  req.Branding,
+		Sections:  req.Sections,
+		Labels:    req.Labels,
+		Subject:   req.Subject,
+		EmailBody: req.EmailBody,
+	}, "en")
+	if err != nil {
+		return err
+	}
+
+	rep := &models.Report{Name: "Sample report", Kind: models.ReportKindCrosstab, Format: models.ReportFormatPDF}
+	data := l.data(rep, "2025-09-01", "2025-09-30", 3)
+	data.Figures = figures{Total: decimal.NewFromInt(100), Renewable: decimal.NewFromInt(70), NonRenewable: decimal.NewFromInt(30), Share: "70.0 %"}
+	data.Crosstab = &models.Crosstab{Rows: "type", Columns: "day", Value: "sum"}
+	data.Table = tableInfo{Rows: pdfMaxTableRows + 1, Shown: pdfMaxTableRows, Truncated: true}
+	texts := []*template.Template{l.title, l.footer, l.subject, l.body}
+	for _, s := range l.sections {
+		texts = append(texts, s.title, s.body)
+	}
+	for _, tpl := range texts {
+		if err := tpl.Execute(&bytes.Buffer{}, data); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+		}
+	}
+	return nil
+}
+
+// parseColor reads #RGB, #RGBA, #RRGGBB and #RRGGBBAA colours, ignoring alpha
+func parseColor(s string) ([3]int, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 || len(hex) == 4 {
+		hex = strings.Repeat(hex[0:1], 2) + strings.Repeat(hex[1:2], 2) + strings.Repeat(hex[2:3], 2)
+	}
+	if len(hex) == 8 {
+		hex = hex[:6]
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return [3]int{}, fmt.Errorf("%w: invalid colour %q", ErrInvalidTemplate, s)
+	}
+	return [3]int{int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff)}, nil
+}
+
+// decodeLogo decodes a base64 logo and returns it with its fpdf image type
+func decodeLogo(s string) ([]byte, string, error) {
+	logo, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: logo is not base64", ErrInvalidTemplate)
+	}
+	if len(logo) > maxLogoBytes {
+		return nil, "", fmt.Errorf("%w: logo is larger than %d KB", ErrInvalidTemplate, maxLogoBytes>>10)
+	}
+	switch http.DetectContentType(logo) {
+	case "image/png":
+		return logo, "PNG", nil
+	case "image/jpeg":
+		return logo, "JPG", nil
+	}
+	return nil, "", fmt.Errorf("%w: logo must be a PNG or JPEG image", ErrInvalidTemplate)
+}
@@ -0,0 +1,184 @@
+// Package scrub strips identifying details from exported datasets so they can
+// be shared, e.g. with a class or the public, according to a configurable
+// policy.
+package scrub
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// Operator policies
+const (
+	// OperatorsPseudonymize replaces operator names with "Operator 1", ...
+	OperatorsPseudonymize = "pseudonymize"
+	// OperatorsDrop removes operators and the links of generators to them
+	OperatorsDrop = "drop"
+	// OperatorsKeep leaves operators as they are
+	OperatorsKeep = "keep"
+)
+
+// Config represents the scrubbing policy
+type Config struct {
+	Operators string
+	// CoordinateDecimals is the number of decimals coordinates are rounded
+	// to (1 is about 11 km); negative removes them
+	CoordinateDecimals int
+	RemoveSourceRefs   bool
+	RemoveAttributes   bool
+}
+
+// LoadConfig loads the scrubbing policy from environment variables
+func LoadConfig() *Config {
+	cfg := &Config{
+		Operators:          strings.ToLower(utils.GetEnv("SCRUB_OPERATORS", OperatorsPseudonymize)),
+		CoordinateDecimals: utils.GetEnvAsInt("SCRUB_COORDINATE_DECIMALS", 1),
+		RemoveSourceRefs:   utils.GetEnvAsBool("SCRUB_SOURCE_REFS", true),
+		RemoveAttributes:   utils.GetEnvAsBool("SCRUB_CUSTOM_ATTRIBUTES", true),
+	}
+	if cfg.Operators != OperatorsDrop && cfg.Operators != OperatorsKeep {
+		cfg.Operators = OperatorsPseudonymize
+	}
+	if cfg.CoordinateDecimals < 0 {
+		cfg.CoordinateDecimals = -1
+	}
+	return cfg
+}
+
+// Policy describes the configuration in the dataset it is applied to
+func (cfg *Config) Policy() *models.ScrubPolicy {
+	return &models.ScrubPolicy{
+		Operators:          cfg.Operators,
+		CoordinateDecimals: cfg.CoordinateDecimals,
+		RemoveSourceRefs:   cfg.RemoveSourceRefs,
+		RemoveAttributes:   cfg.RemoveAttributes,
+	}
+}
+
+// Apply scrubs ds in place. Generators, plants, productions and, unless kept,
+// operators get new IDs, drawn anew for every dataset, so that rows cannot be
+// looked up in the API to find what was scrubbed; types and regions keep
+// theirs. Unless operators are kept, plants are renamed "Plant 1", ... and
+// lose their location, as the name of a facility gives its owner away.
+// Production signatures are removed, since they no longer match the rows.
+func Apply(cfg *Config, ds *models.Dataset) {
+	ds.Scrub = cfg.Policy()
+
+	// Pseudonyms are numbered in a random order, and the listings sorted by
+	// them, so neither gives away the alphabetical order of the real names
+	operators := map[uuid.UUID]uuid.UUID{}
+	names := map[uuid.UUID]string{}
+	switch cfg.Operators {
+	case OperatorsDrop:
+		ds.Operators = []*models.Operator{}
+	case OperatorsPseudonymize:
+		sorted := make([]*models.Operator, len(ds.Operators))
+		for i, n := range rand.Perm(len(ds.Operators)) {
+			o := ds.Operators[i]
+			operators[o.ID] = uuid.New()
+			o.ID = operators[o.ID]
+			o.Name = fmt.Sprintf("Operator %d", n+1)
+			names[o.ID] = o.Name
+			sorted[n] = o
+		}
+		ds.Operators = sorted
+	}
+	operator := func(id *uuid.UUID) (*uuid.UUID, string, bool) {
+		if id == nil || cfg.Operators == OperatorsKeep {
+			return id, "", false
+		}
+		if next, ok := operators[*id]; ok {
+			return &next, names[next], true
+		}
+		return nil, "", true
+	}
+
+	plants := map[uuid.UUID]uuid.UUID{}
+	sorted := make([]*models.Plant, len(ds.Plants))
+	for i, n := range rand.Perm(len(ds.Plants)) {
+		p := ds.Plants[i]
+		sorted[n] = p
+		plants[p.ID] = uuid.New()
+		p.ID = plants[p.ID]
+		if id, name, changed := operator(p.OwnerID); changed {
+			p.OwnerID, p.OwnerName = id, name
+		}
+		if cfg.Operators != OperatorsKeep {
+			p.Name = fmt.Sprintf("Plant %d", n+1)
+			p.Location = ""
+		}
+		p.Latitude, p.Longitude = cfg.coordinates(p.Latitude, p.Longitude)
+	}
+	if cfg.Operators != OperatorsKeep {
+		ds.Plants = sorted
+	}
+	plantNames := map[uuid.UUID]string{}
+	for _, p := range ds.Plants {
+		plantNames[p.ID] = p.Name
+	}
+
+	generators := map[uuid.UUID]uuid.UUID{}
+	for _, g := range ds.Generators {
+		generators[g.ID] = uuid.New()
+		g.ID = generators[g.ID]
+		if id, name, changed := operator(g.OperatorID); changed {
+			g.OperatorID, g.OperatorName = id, name
+		}
+		if g.PlantID != nil {
+			if next, ok := plants[*g.PlantID]; ok {
+				g.PlantID, g.PlantName = &next, plantNames[next]
+			} else {
+				g.PlantID, g.PlantName = nil, ""
+			}
+		}
+		g.Latitude, g.Longitude = cfg.coordinates(g.Latitude, g.Longitude)
+		if cfg.RemoveAttributes {
+			g.CustomAttributes = nil
+		}
+	}
+	// Listed in the order of the new IDs, not that of the API
+	slices.SortFunc(ds.Generators, func(a, b *models.Generator) int {
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+
+	for _, p := range ds.Productions {
+		p.ID = uuid.New()
+		p.GeneratorID = generators[p.GeneratorID]
+		p.Signature, p.SignatureValid = "", nil
+		if cfg.RemoveSourceRefs {
+			p.SourceRef = ""
+		}
+		if cfg.RemoveAttributes {
+			p.CustomAttributes = nil
+		}
+	}
+	slices.SortFunc(ds.Productions, func(a, b *models.Production) int {
+		return cmp.Or(strings.Compare(a.Date, b.Date), strings.Compare(a.GeneratorID.String(), b.GeneratorID.String()))
+	})
+	if cfg.RemoveAttributes {
+		for _, t := range ds.Types {
+			t.CustomAttributes = nil
+		}
+		for _, o := range ds.Operators {
+			o.CustomAttributes = nil
+		}
+	}
+}
+
+// coordinates rounds a location to the configured precision or removes it
+func (cfg *Config) coordinates(lat, lng *float64) (*float64, *float64) {
+	if lat == nil || lng == nil || cfg.CoordinateDecimals < 0 {
+		return nil, nil
+	}
+	scale := math.Pow(10, float64(cfg.CoordinateDecimals))
+	rlat, rlng := math.Round(*lat*scale)/scale, math.Round(*lng*scale)/scale
+	return &rlat, &rlng
+}
@@ -0,0 +1,335 @@
+package slo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// bucketCount is the number of buckets the rolling window is split into
+const bucketCount = 60
+
+// Target is the objective of a route: the share of requests (Objective) that
+// must answer without server error within Latency
+type Target struct {
+	Latency   time.Duration
+	Objective float64
+}
+
+// Config represents the SLO configuration
+type Config struct {
+	Default Target
+	// Routes overrides the default per "METHOD /path" (gin route pattern)
+	Routes map[string]Target
+	Window time.Duration
+	// AlertBurnRate is the burn rate at which a route is at risk (1 spends the budget exactly over the window)
+	AlertBurnRate float64
+	// AlertMinRequests avoids alerting on a handful of requests
+	AlertMinRequests int64
+	AlertInterval    time.Duration
+	AlertCooldown    time.Duration
+}
+
+// LoadConfig loads SLO configuration from environment variables.
+// SLO_ROUTES overrides targets per route, e.g.
+// "GET /api/v1/productions=300ms@0.995,POST /api/v1/imports/productions=30s".
+func LoadConfig() *Config {
+	cfg := &Config{
+		Default: Target{
+			Latency:   utils.GetEnvAsDuration("SLO_LATENCY_TARGET", 500*time.Millisecond),
+			Objective: getEnvAsFloat("SLO_OBJECTIVE", 0.99),
+		},
+		Routes:           map[string]Target{},
+		Window:           utils.GetEnvAsDuration("SLO_WINDOW", time.Hour),
+		AlertBurnRate:    getEnvAsFloat("SLO_ALERT_BURN_RATE", 2),
+		AlertMinRequests: utils.GetEnvAsInt64("SLO_ALERT_MIN_REQUESTS", 100),
+		AlertInterval:    utils.GetEnvAsDuration("SLO_ALERT_INTERVAL", time.Minute),
+		AlertCooldown:    utils.GetEnvAsDuration("SLO_ALERT_COOLDOWN", 30*time.Minute),
+	}
+	for _, item := range utils.GetEnvAsList("SLO_ROUTES", nil) {
+		route, spec, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		target := cfg.Default
+		latency, objective, hasObjective := strings.Cut(spec, "@")
+		if d, err := time.ParseDuration(strings.TrimSpace(latency)); err == nil {
+			target.Latency = d
+		}
+		if hasObjective {
+			if o, err := strconv.ParseFloat(strings.TrimSpace(objective), 64); err == nil && o > 0 && o < 1 {
+				target.Objective = o
+			}
+		}
+		cfg.Routes[strings.Join(strings.Fields(route), " ")] = target
+	}
+	if cfg.Default.Objective <= 0 || cfg.Default.Objective >= 1 {
+		cfg.Default.Objective = 0.99
+	}
+	return cfg
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if f, err := strconv.ParseFloat(utils.GetEnv(key, ""), 64); err == nil {
+		return f
+	}
+	return defaultValue
+}
+
+// Alert is emitted when a route burns its error budget too fast
+type Alert struct {
+	Route    string      `json:"route"`
+	Status   RouteStatus `json:"status"`
+	RaisedAt time.Time   `json:"raisedAt"`
+}
+
+// Notifier delivers SLO alerts
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// LogNotifier writes alerts to the server log
+type LogNotifier struct{}
+
+// Notify implements Notifier
+func (LogNotifier) Notify(_ context.Context, a Alert) error {
+	utils.LogInfo(fmt.Sprintf("SLO at risk for %s: burn rate %.2f, %.1f%% of error budget left (%d/%d bad requests)",
+		a.Route, a.Status.BurnRate, a.Status.ErrorBudgetRemaining*100, a.Status.BadRequests, a.Status.Requests))
+	return nil
+}
+
+// RouteStatus is the current state of a route over the rolling window
+// @Description SLO state of a route over the rolling window
+type RouteStatus struct {
+	Route           string  `json:"route" example:"GET /api/v1/productions"`
+	TargetLatencyMs int64   `json:"targetLatencyMs" example:"500"`
+	Objective       float64 `json:"objective" example:"0.99"`
+	Requests        int64   `json:"requests" example:"1200"`
+	BadRequests     int64   `json:"badRequests" example:"6"`
+	SlowRequests    int64   `json:"slowRequests" example:"4"`
+	ServerErrors    int64   `json:"serverErrors" example:"2"`
+	AvgLatencyMs    float64 `json:"avgLatencyMs" example:"87.5"`
+	// SLI is the share of good requests
+	SLI float64 `json:"sli" example:"0.995"`
+	// ErrorBudgetRemaining is the unspent share of the window's error budget (negative when exceeded)
+	ErrorBudgetRemaining float64 `json:"errorBudgetRemaining" example:"0.5"`
+	// BurnRate is the bad-request rate relative to the allowed rate
+	BurnRate float64 `json:"burnRate" example:"0.5"`
+	AtRisk   bool    `json:"atRisk" example:"false"`
+}
+
+// Report is the state of every tracked route
+// @Description SLO report over the rolling window
+type Report struct {
+	WindowSeconds int64         `json:"windowSeconds" example:"3600"`
+	AlertBurnRate float64       `json:"alertBurnRate" example:"2"`
+	Routes        []RouteStatus `json:"routes"`
+}
+
+type bucket struct {
+	epoch     int64
+	requests  int64
+	slow      int64
+	errors    int64
+	bad       int64
+	latencyNs int64
+}
+
+type routeStats struct {
+	buckets [bucketCount]bucket
+}
+
+// Tracker measures per-route latency and errors against the SLO targets
+type Tracker struct {
+	cfg      *Config
+	notifier Notifier
+	now      func() time.Time
+
+	mu      sync.Mutex
+	routes  map[string]*routeStats
+	alerted map[string]time.Time
+}
+
+// NewTracker creates a new Tracker; alerts are delivered to notifier
+func NewTracker(cfg *Config, notifier Notifier) *Tracker {
+	if notifier == nil {
+		notifier = LogNotifier{}
+	}
+	return &Tracker{
+		cfg:      cfg,
+		notifier: notifier,
+		now:      time.Now,
+		routes:   map[string]*routeStats{},
+		alerted:  map[string]time.Time{},
+	}
+}
+
+// Middleware records the latency and status of every request under its route pattern
+func (t *Tracker) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		path := c.FullPath()
+		if path == "" {
+			// Unmatched routes would otherwise create one series per URL
+			return
+		}
+		t.Record(c.Request.Method+" "+path, c.Writer.Status(), time.Since(start))
+	}
+}
+
+func (t *Tracker) target(route string) Target {
+	if target, ok := t.cfg.Routes[route]; ok {
+		return target
+	}
+	return t.cfg.Default
+}
+
+func (t *Tracker) bucketSize() time.Duration {
+	size := t.cfg.Window / bucketCount
+	if size <= 0 {
+		size = time.Second
+	}
+	return size
+}
+
+// Record adds one request to the statistics of route
+func (t *Tracker) Record(route string, status int, latency time.Duration) {
+	target := t.target(route)
+	epoch := t.now().UnixNano() / int64(t.bucketSize())
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rs, ok := t.routes[route]
+	if !ok {
+		rs = &routeStats{}
+		t.routes[route] = rs
+	}
+	b := &rs.buckets[epoch%bucketCount]
+	if b.epoch != epoch {
+		*b = bucket{epoch: epoch}
+	}
+	b.requests++
+	b.latencyNs += int64(latency)
+	slow := latency > target.Latency
+	failed := status >= 500
+	if slow {
+		b.slow++
+	}
+	if failed {
+		b.errors++
+	}
+	if slow || failed {
+		b.bad++
+	}
+}
+
+// Report returns the state of every route over the rolling window
+func (t *Tracker) Report() *Report {
+	current := t.now().UnixNano() / int64(t.bucketSize())
+
+	t.mu.Lock()
+	statuses := make([]RouteStatus, 0, len(t.routes))
+	for route, rs := range t.routes {
+		var sum bucket
+		for _, b := range rs.buckets {
+			if b.epoch > current-bucketCount && b.epoch <= current {
+				sum.requests += b.requests
+				sum.slow += b.slow
+				sum.errors += b.errors
+				sum.bad += b.bad
+				sum.latencyNs += b.latencyNs
+			}
+		}
+		if sum.requests == 0 {
+			continue
+		}
+		statuses = append(statuses, t.status(route, sum))
+	}
+	t.mu.Unlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].BurnRate != statuses[j].BurnRate {
+			return statuses[i].BurnRate > statuses[j].BurnRate
+		}
+		return statuses[i].Route < statuses[j].Route
+	})
+	return &Report{
+		WindowSeconds: int64(t.cfg.Window / time.Second),
+		AlertBurnRate: t.cfg.AlertBurnRate,
+		Routes:        statuses,
+	}
+}
+
+// round keeps 4 decimals so reports are not cluttered with float noise
+func round(f float64) float64 {
+	return math.Round(f*1e4) / 1e4
+}
+
+func (t *Tracker) status(route string, sum bucket) RouteStatus {
+	target := t.target(route)
+	badRatio := float64(sum.bad) / float64(sum.requests)
+	allowed := 1 - target.Objective
+	s := RouteStatus{
+		Route:                route,
+		TargetLatencyMs:      target.Latency.Milliseconds(),
+		Objective:            target.Objective,
+		Requests:             sum.requests,
+		BadRequests:          sum.bad,
+		SlowRequests:         sum.slow,
+		ServerErrors:         sum.errors,
+		AvgLatencyMs:         round(float64(sum.latencyNs) / float64(sum.requests) / float64(time.Millisecond)),
+		SLI:                  round(1 - badRatio),
+		BurnRate:             round(badRatio / allowed),
+		ErrorBudgetRemaining: round(1 - badRatio/allowed),
+	}
+	s.AtRisk = s.Requests >= t.cfg.AlertMinRequests && s.BurnRate >= t.cfg.AlertBurnRate
+	return s
+}
+
+// Run evaluates the routes every AlertInterval and notifies those at risk,
+// at most once per AlertCooldown each, until ctx is cancelled
+func (t *Tracker) Run(ctx context.Context) {
+	if t.cfg.AlertInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(t.cfg.AlertInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.checkAlerts(ctx)
+		}
+	}
+}
+
+func (t *Tracker) checkAlerts(ctx context.Context) {
+	now := t.now()
+	for _, s := range t.Report().Routes {
+		if !s.AtRisk {
+			continue
+		}
+		t.mu.Lock()
+		last, seen := t.alerted[s.Route]
+		if seen && now.Sub(last) < t.cfg.AlertCooldown {
+			t.mu.Unlock()
+			continue
+		}
+		t.alerted[s.Route] = now
+		t.mu.Unlock()
+
+		if err := t.notifier.Notify(ctx, Alert{Route: s.Route, Status: s, RaisedAt: now}); err != nil {
+			utils.LogError("slo alert "+s.Route, err)
+		}
+	}
+}
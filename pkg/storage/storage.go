@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpclient"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// ErrNotConfigured is returned when object storage settings are missing
+var ErrNotConfigured = errors.New("object storage is not configured")
+
+// ErrObjectNotFound is returned when the requested object does not exist
+var ErrObjectNotFound = errors.New("object not found")
+
+// Config represents S3-compatible object storage configuration
+type Config struct {
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool
+	URLTTL    time.Duration
+}
+
+// LoadConfig loads object storage configuration from environment variables
+func LoadConfig() *Config {
+	return &Config{
+		Endpoint:  strings.TrimRight(utils.GetEnv("STORAGE_ENDPOINT", "https://s3.amazonaws.com"), "/"),
+		Bucket:    utils.GetEnv("STORAGE_BUCKET", ""),
+		Region:    utils.GetEnv("STORAGE_REGION", "us-east-1"),
+		AccessKey: utils.GetEnv("STORAGE_ACCESS_KEY", ""),
+		SecretKey: utils.GetEnv("STORAGE_SECRET_KEY", ""),
+		PathStyle: utils.GetEnvAsBool("STORAGE_PATH_STYLE", true),
+		URLTTL:    utils.GetEnvAsDuration("STORAGE_URL_TTL", 15*time.Minute),
+	}
+}
+
+// Enabled reports whether enough settings are present to talk to the storage
+func (c *Config) Enabled() bool {
+	return c.Bucket != "" && c.AccessKey != "" && c.SecretKey != ""
+}
+
+// Client issues presigned URLs (AWS Signature V4) for an S3-compatible bucket
+type Client struct {
+	cfg  *Config
+	http *http.Client
+}
+
+// NewClient creates a new storage client
+func NewClient(cfg *Config) *Client {
+	return &Client{
+		cfg: cfg,
+		// Objects are streamed, so only the connect and header timeouts apply
+		http: httpclient.New(httpclient.NoTimeout),
+	}
+}
+
+// Enabled reports whether the client is configured
+func (c *Client) Enabled() bool {
+	return c != nil && c.cfg.Enabled()
+}
+
+// URLTTL returns the default lifetime of presigned URLs
+func (c *Client) URLTTL() time.Duration {
+	return c.cfg.URLTTL
+}
+
+// PresignPut returns a URL the client can PUT the object to directly
+func (c *Client) PresignPut(key string, ttl time.Duration) (string, error) {
+	return c.presign(http.MethodPut, key, ttl, time.Now())
+}
+
+// PresignGet returns a URL the object can be downloaded from
+func (c *Client) PresignGet(key string, ttl time.Duration) (string, error) {
+	return c.presign(http.MethodGet, key, ttl, time.Now())
+}
+
+// Open streams an object from the bucket
+func (c *Client) Open(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	resp, err := c.do(ctx, http.MethodGet, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// Put uploads an object to the bucket, replacing any object with the key
+func (c *Client) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	signed, err := c.presign(http.MethodPut, key, c.cfg.URLTTL, time.Now())
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, signed, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build storage request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("storage request failed: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("storage request failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// Delete removes an object from the bucket
+func (c *Client) Delete(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, key string) (*http.Response, error) {
+	signed, err := c.presign(method, key, c.cfg.URLTTL, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, signed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build storage request: %w", err)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("storage request failed: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrObjectNotFound
+	}
+	if resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("storage request failed: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *Client) presign(method, key string, ttl time.Duration, now time.Time) (string, error) {
+	if !c.Enabled() {
+		return "", ErrNotConfigured
+	}
+
+	endpoint, err := url.Parse(c.cfg.Endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid STORAGE_ENDPOINT: %w", err)
+	}
+
+	host := endpoint.Host
+	path := "/" + encodePath(key)
+	if c.cfg.PathStyle {
+		path = "/" + encodePath(c.cfg.Bucket) + path
+	} else {
+		host = c.cfg.Bucket + "." + host
+	}
+
+	now = now.UTC()
+	date := now.Format("20060102")
+	amzDate := now.Format("20060102T150405Z")
+	scope := date + "/" + c.cfg.Region + "/s3/aws4_request"
+
+	query := map[string]string{
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    c.cfg.AccessKey + "/" + scope,
+		"X-Amz-Date":          amzDate,
+		"X-Amz-Expires":       fmt.Sprintf("%d", int(ttl.Seconds())),
+		"X-Amz-SignedHeaders": "host",
+	}
+	canonicalQuery := canonicalQueryString(query)
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		path,
+		canonicalQuery,
+		"host:" + host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(hashed[:]),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+c.cfg.SecretKey), date)
+	signingKey = hmacSHA256(signingKey, c.cfg.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	return fmt.Sprintf("%s://%s%s?%s&X-Amz-Signature=%s", endpoint.Scheme, host, path, canonicalQuery, signature), nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func canonicalQueryString(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, uriEncode(k)+"="+uriEncode(params[k]))
+	}
+	return strings.Join(parts, "&")
+}
+
+func encodePath(path string) string {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, s := range segments {
+		segments[i] = uriEncode(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// uriEncode encodes a string as required by AWS Signature V4
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z') || (ch >= '0' && ch <= '9') ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' {
+			b.WriteByte(ch)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", ch)
+	}
+	return b.String()
+}
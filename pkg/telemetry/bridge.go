@@ -0,0 +1,195 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// Bridge maps meter messages to production records
+type Bridge struct {
+	repo     database.Repository
+	cfg      *Config
+	mappings []*Mapping
+	now      func() time.Time
+
+	mu     sync.Mutex
+	status models.TelemetryStatus
+}
+
+// NewBridge creates a new Bridge with the mappings of cfg.MappingFile
+func NewBridge(repo database.Repository, cfg *Config) (*Bridge, error) {
+	b := &Bridge{repo: repo, cfg: cfg, now: time.Now}
+	if cfg.MappingFile != "" {
+		mappings, err := LoadMappings(cfg.MappingFile)
+		if err != nil {
+			return nil, err
+		}
+		b.mappings = mappings
+	}
+	if cfg.Broker != "" && len(b.mappings) == 0 {
+		return nil, errors.New("MQTT_BROKER is set but MQTT_MAPPING_FILE has no mappings")
+	}
+	b.status.Broker = cfg.Broker
+	b.status.Mappings = len(b.mappings)
+	b.status.Topics = b.topics()
+	return b, nil
+}
+
+// topics returns the distinct topic filters of the mappings
+func (b *Bridge) topics() []string {
+	seen := map[string]bool{}
+	topics := []string{}
+	for _, m := range b.mappings {
+		if !seen[m.Topic] {
+			seen[m.Topic] = true
+			topics = append(topics, m.Topic)
+		}
+	}
+	return topics
+}
+
+// Status returns the connection state and message counters
+func (b *Bridge) Status() *models.TelemetryStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.status
+	s.Topics = append([]string(nil), b.status.Topics...)
+	return &s
+}
+
+// Handle records a message of topic with the first mapping matching it
+func (b *Bridge) Handle(ctx context.Context, topic string, payload []byte) (*models.Production, error) {
+	now := b.now()
+	b.mu.Lock()
+	b.status.Received++
+	b.status.LastMessageAt = &now
+	b.mu.Unlock()
+
+	pr, err := b.record(ctx, topic, payload, now)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		b.status.Failed++
+		b.status.LastError = topic + ": " + err.Error()
+		b.status.LastErrorAt = &now
+		return nil, err
+	}
+	b.status.Recorded++
+	return pr, nil
+}
+
+func (b *Bridge) record(ctx context.Context, topic string, payload []byte, now time.Time) (*models.Production, error) {
+	for _, m := range b.mappings {
+		if !m.Matches(topic) {
+			continue
+		}
+		reading, err := m.Reading(topic, payload, now)
+		if err != nil {
+			return nil, err
+		}
+		return b.repo.RecordTelemetry(ctx, reading)
+	}
+	return nil, ErrNoMapping
+}
+
+func (b *Bridge) setConnected(connected bool) {
+	b.mu.Lock()
+	b.status.Connected = connected
+	b.mu.Unlock()
+}
+
+// Run subscribes to the topics of the mappings and records their messages
+// until ctx is cancelled, reconnecting after ReconnectDelay when the
+// connection drops; it returns at once when no broker is configured
+func (b *Bridge) Run(ctx context.Context) {
+	if b.cfg.Broker == "" {
+		return
+	}
+	for {
+		err := b.session(ctx)
+		b.setConnected(false)
+		if ctx.Err() != nil {
+			return
+		}
+		utils.LogError("telemetry: MQTT connection", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.cfg.ReconnectDelay):
+		}
+	}
+}
+
+// session connects, subscribes and reads messages until the connection fails
+func (b *Bridge) session(ctx context.Context) error {
+	conn, err := dialBroker(ctx, b.cfg.Broker, b.cfg.KeepAlive)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.disconnect()
+		case <-done:
+			conn.conn.Close()
+		}
+	}()
+
+	if err := conn.connect(b.cfg); err != nil {
+		return err
+	}
+	if err := conn.subscribe(b.status.Topics, b.cfg.QoS, b.cfg.KeepAlive); err != nil {
+		return err
+	}
+	b.setConnected(true)
+	utils.LogInfo(fmt.Sprintf("telemetry: subscribed to %d topics on %s", len(b.status.Topics), b.cfg.Broker))
+
+	// Pings keep the connection alive when no message is published for a while
+	go func() {
+		ticker := time.NewTicker(b.cfg.KeepAlive / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := conn.ping(); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		conn.conn.SetReadDeadline(time.Now().Add(b.cfg.KeepAlive * 3 / 2))
+		kind, flags, body, err := conn.read()
+		if err != nil {
+			return err
+		}
+		if kind != packetPublish {
+			continue
+		}
+		msg, err := parsePublish(flags, body)
+		if err != nil {
+			return err
+		}
+		if _, err := b.Handle(ctx, msg.topic, msg.payload); err != nil {
+			utils.LogError("telemetry: "+msg.topic, err)
+		}
+		// Failed messages are acknowledged too: redelivery would fail the same way
+		if msg.qos > 0 {
+			if err := conn.puback(msg.packetID); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package telemetry records the production pushed by plant meters. Meter
+// messages arrive from an MQTT broker the bridge subscribes to, or from the
+// POST /telemetry endpoint, and are mapped to production records by the
+// mappings file.
+package telemetry
+
+import (
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// Config represents the MQTT bridge settings
+type Config struct {
+	// Broker is the broker URL: tcp:// or mqtt:// for plain connections and
+	// ssl://, tls:// or mqtts:// for TLS; empty disables the MQTT subscriber
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	// QoS is the subscription quality of service, 0 or 1
+	QoS byte
+	// MappingFile is the JSON file mapping topics and devices to generators
+	MappingFile    string
+	KeepAlive      time.Duration
+	ReconnectDelay time.Duration
+}
+
+// LoadConfig loads telemetry configuration from environment variables
+func LoadConfig() *Config {
+	cfg := &Config{
+		Broker:         utils.GetEnv("MQTT_BROKER", ""),
+		ClientID:       utils.GetEnv("MQTT_CLIENT_ID", "tadb-api"),
+		Username:       utils.GetEnv("MQTT_USERNAME", ""),
+		Password:       utils.GetEnv("MQTT_PASSWORD", ""),
+		QoS:            1,
+		MappingFile:    utils.GetEnv("MQTT_MAPPING_FILE", ""),
+		KeepAlive:      utils.GetEnvAsDuration("MQTT_KEEPALIVE", time.Minute),
+		ReconnectDelay: utils.GetEnvAsDuration("MQTT_RECONNECT_DELAY", 10*time.Second),
+	}
+	if utils.GetEnvAsInt("MQTT_QOS", 1) == 0 {
+		cfg.QoS = 0
+	}
+	if cfg.KeepAlive < time.Second || cfg.KeepAlive > 18*time.Hour {
+		cfg.KeepAlive = time.Minute
+	}
+	if cfg.ReconnectDelay <= 0 {
+		cfg.ReconnectDelay = 10 * time.Second
+	}
+	return cfg
+}
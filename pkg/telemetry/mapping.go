@@ -0,0 +1,277 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	// Embedded zone database so mapping timezones resolve in minimal containers
+	_ "time/tzdata"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/models"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// ErrNoMapping is returned for messages on a topic no mapping matches
+	ErrNoMapping = errors.New("no telemetry mapping matches the topic")
+	// ErrInvalidMessage is returned for messages a mapping cannot read
+	ErrInvalidMessage = errors.New("invalid telemetry message")
+)
+
+// Mapping maps the messages of a topic filter to production records. The
+// generator is fixed, or taken from a topic level or a payload field holding
+// a device serial looked up in Devices (or a generator id when Devices is empty).
+type Mapping struct {
+	// Topic is an MQTT topic filter; + matches one level and # the rest
+	Topic       string     `json:"topic"`
+	GeneratorID *uuid.UUID `json:"generatorId,omitempty"`
+	// GeneratorTopicLevel is the 0-based topic level holding the device
+	GeneratorTopicLevel *int `json:"generatorTopicLevel,omitempty"`
+	// GeneratorField is the payload field holding the device
+	GeneratorField string               `json:"generatorField,omitempty"`
+	Devices        map[string]uuid.UUID `json:"devices,omitempty"`
+	// ValueField is the payload field of the reading; nested fields are dotted
+	ValueField string `json:"valueField"`
+	// Scale converts the reading to the unit of production_mw, default 1
+	Scale *decimal.Decimal `json:"scale,omitempty"`
+	// TimestampField holds the reading time as RFC 3339 or Unix seconds or
+	// milliseconds; without it the time of arrival is used
+	TimestampField string `json:"timestampField,omitempty"`
+	// Timezone is the zone the production day is taken in, default UTC
+	Timezone string `json:"timezone,omitempty"`
+	// Mode is add (interval readings) or set (running daily totals), default add
+	Mode string `json:"mode,omitempty"`
+
+	location *time.Location
+}
+
+// mappingFile is the layout of MQTT_MAPPING_FILE
+type mappingFile struct {
+	Mappings []*Mapping `json:"mappings"`
+}
+
+// LoadMappings reads and checks the mappings file
+func LoadMappings(path string) ([]*Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read telemetry mappings: %w", err)
+	}
+	var f mappingFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to parse telemetry mappings: %w", err)
+	}
+	for i, m := range f.Mappings {
+		if err := m.check(); err != nil {
+			return nil, fmt.Errorf("telemetry mapping %d (%s): %w", i, m.Topic, err)
+		}
+	}
+	return f.Mappings, nil
+}
+
+func (m *Mapping) check() error {
+	if err := checkFilter(m.Topic); err != nil {
+		return err
+	}
+	sources := 0
+	if m.GeneratorID != nil {
+		sources++
+	}
+	if m.GeneratorTopicLevel != nil {
+		if *m.GeneratorTopicLevel < 0 {
+			return errors.New("generatorTopicLevel cannot be negative")
+		}
+		sources++
+	}
+	if m.GeneratorField != "" {
+		sources++
+	}
+	if sources != 1 {
+		return errors.New("exactly one of generatorId, generatorTopicLevel and generatorField is required")
+	}
+	if m.ValueField == "" {
+		return errors.New("valueField is required")
+	}
+	switch m.Mode {
+	case "":
+		m.Mode = models.TelemetryModeAdd
+	case models.TelemetryModeAdd, models.TelemetryModeSet:
+	default:
+		return fmt.Errorf("mode must be %s or %s", models.TelemetryModeAdd, models.TelemetryModeSet)
+	}
+	m.location = time.UTC
+	if m.Timezone != "" {
+		loc, err := time.LoadLocation(m.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone: %w", err)
+		}
+		m.location = loc
+	}
+	return nil
+}
+
+// checkFilter validates an MQTT topic filter
+func checkFilter(filter string) error {
+	if filter == "" {
+		return errors.New("topic is required")
+	}
+	levels := strings.Split(filter, "/")
+	for i, l := range levels {
+		if strings.Contains(l, "#") && (l != "#" || i != len(levels)-1) {
+			return errors.New("# must be the whole last level of the topic")
+		}
+		if strings.Contains(l, "+") && l != "+" {
+			return errors.New("+ must be a whole topic level")
+		}
+	}
+	return nil
+}
+
+// Matches reports whether topic matches the mapping's filter
+func (m *Mapping) Matches(topic string) bool {
+	filter := strings.Split(m.Topic, "/")
+	levels := strings.Split(topic, "/")
+	for i, f := range filter {
+		if f == "#" {
+			return true
+		}
+		if i >= len(levels) || (f != "+" && f != levels[i]) {
+			return false
+		}
+	}
+	return len(filter) == len(levels)
+}
+
+// Reading maps a message of topic received at now to a production reading
+func (m *Mapping) Reading(topic string, payload []byte, now time.Time) (*models.TelemetryReading, error) {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	var msg any
+	if err := dec.Decode(&msg); err != nil {
+		return nil, fmt.Errorf("%w: payload is not JSON: %v", ErrInvalidMessage, err)
+	}
+
+	generatorID, err := m.generator(topic, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, ok := field(msg, m.ValueField)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidMessage, m.ValueField)
+	}
+	value, err := decimal.NewFromString(scalar(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not a number", ErrInvalidMessage, m.ValueField)
+	}
+	if m.Scale != nil {
+		value = value.Mul(*m.Scale)
+	}
+
+	at := now
+	if m.TimestampField != "" {
+		raw, ok := field(msg, m.TimestampField)
+		if !ok {
+			return nil, fmt.Errorf("%w: missing %s", ErrInvalidMessage, m.TimestampField)
+		}
+		if at, err = timestamp(raw); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidMessage, m.TimestampField, err)
+		}
+	}
+
+	return &models.TelemetryReading{
+		GeneratorID: generatorID,
+		Date:        at.In(m.location).Format("2006-01-02"),
+		Value:       value,
+		Mode:        m.Mode,
+		Topic:       topic,
+	}, nil
+}
+
+// generator resolves the generator of a message
+func (m *Mapping) generator(topic string, msg any) (uuid.UUID, error) {
+	if m.GeneratorID != nil {
+		return *m.GeneratorID, nil
+	}
+	var device string
+	if m.GeneratorTopicLevel != nil {
+		levels := strings.Split(topic, "/")
+		if *m.GeneratorTopicLevel >= len(levels) {
+			return uuid.Nil, fmt.Errorf("%w: topic has no level %d", ErrInvalidMessage, *m.GeneratorTopicLevel)
+		}
+		device = levels[*m.GeneratorTopicLevel]
+	} else {
+		raw, ok := field(msg, m.GeneratorField)
+		if !ok {
+			return uuid.Nil, fmt.Errorf("%w: missing %s", ErrInvalidMessage, m.GeneratorField)
+		}
+		device = scalar(raw)
+	}
+
+	if len(m.Devices) > 0 {
+		id, ok := m.Devices[device]
+		if !ok {
+			return uuid.Nil, fmt.Errorf("%w: no generator for device %q", ErrInvalidMessage, device)
+		}
+		return id, nil
+	}
+	id, err := uuid.Parse(device)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: device %q is not a generator id", ErrInvalidMessage, device)
+	}
+	return id, nil
+}
+
+// field returns the value at a dotted path of a decoded JSON object
+func field(msg any, path string) (any, bool) {
+	v := msg
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = obj[key]; !ok || v == nil {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// scalar renders a decoded JSON number or string as text
+func scalar(v any) string {
+	switch v := v.(type) {
+	case json.Number:
+		return v.String()
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
+
+// timestamp parses RFC 3339 text or Unix seconds; numbers past year 5000
+// in seconds are taken as milliseconds
+func timestamp(v any) (time.Time, error) {
+	if s, ok := v.(string); ok {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, nil
+		}
+	}
+	n, err := strconv.ParseFloat(scalar(v), 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return time.Time{}, errors.New("not an RFC 3339 time or Unix timestamp")
+	}
+	if n > 1e11 {
+		return time.UnixMilli(int64(n)), nil
+	}
+	sec, frac := math.Modf(n)
+	return time.Unix(int64(sec), int64(frac*1e9)), nil
+}
@@ -0,0 +1,261 @@
+package telemetry
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/httpclient"
+)
+
+// MQTT 3.1.1 control packet types, in the high nibble of the fixed header
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+	maxPacketSize     = 1 << 20
+	subscribePacketID = 1
+)
+
+// connackErrors are the CONNACK return codes refusing the connection
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// mqttConn is a client connection to the broker; only the subset of MQTT
+// 3.1.1 a subscriber needs is implemented
+type mqttConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	// wmu serializes writes of the reader loop and the pinger
+	wmu sync.Mutex
+}
+
+// dialBroker opens a connection to the broker URL
+func dialBroker(ctx context.Context, broker string, timeout time.Duration) (*mqttConn, error) {
+	u, err := url.Parse(broker)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MQTT_BROKER: %w", err)
+	}
+	useTLS := false
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		useTLS, port = true, "8883"
+	default:
+		return nil, fmt.Errorf("invalid MQTT_BROKER: unsupported scheme %q", u.Scheme)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	if useTLS {
+		cfg := httpclient.TLSConfig()
+		cfg.ServerName = u.Hostname()
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: cfg}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to broker: %w", err)
+	}
+	return &mqttConn{conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+// connect sends CONNECT with a clean session and waits for CONNACK
+func (c *mqttConn) connect(cfg *Config) error {
+	var flags byte = 0x02
+	if cfg.Username != "" {
+		flags |= 0x80
+		if cfg.Password != "" {
+			flags |= 0x40
+		}
+	}
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(cfg.KeepAlive/time.Second))
+	body = appendString(body, cfg.ClientID)
+	if cfg.Username != "" {
+		body = appendString(body, cfg.Username)
+		if cfg.Password != "" {
+			body = appendString(body, cfg.Password)
+		}
+	}
+	if err := c.write(packetConnect<<4, body); err != nil {
+		return err
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(cfg.KeepAlive))
+	kind, _, resp, err := c.read()
+	if err != nil {
+		return fmt.Errorf("failed to read CONNACK: %w", err)
+	}
+	if kind != packetConnack || len(resp) != 2 {
+		return fmt.Errorf("expected CONNACK, got packet type %d", kind)
+	}
+	if code := resp[1]; code != 0 {
+		msg, ok := connackErrors[code]
+		if !ok {
+			msg = fmt.Sprintf("return code %d", code)
+		}
+		return fmt.Errorf("broker refused the connection: %s", msg)
+	}
+	return nil
+}
+
+// subscribe subscribes to filters and waits for SUBACK
+func (c *mqttConn) subscribe(filters []string, qos byte, timeout time.Duration) error {
+	body := binary.BigEndian.AppendUint16(nil, subscribePacketID)
+	for _, f := range filters {
+		body = appendString(body, f)
+		body = append(body, qos)
+	}
+	if err := c.write(packetSubscribe<<4|0x02, body); err != nil {
+		return err
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(timeout))
+	kind, _, resp, err := c.read()
+	if err != nil {
+		return fmt.Errorf("failed to read SUBACK: %w", err)
+	}
+	if kind != packetSuback || len(resp) != 2+len(filters) {
+		return fmt.Errorf("expected SUBACK, got packet type %d", kind)
+	}
+	for i, code := range resp[2:] {
+		if code == 0x80 {
+			return fmt.Errorf("broker refused the subscription to %q", filters[i])
+		}
+	}
+	return nil
+}
+
+// publish is a received PUBLISH packet
+type publish struct {
+	topic    string
+	qos      byte
+	packetID uint16
+	payload  []byte
+}
+
+// parsePublish decodes the body of a PUBLISH packet with the given flags
+func parsePublish(flags byte, body []byte) (*publish, error) {
+	p := &publish{qos: (flags >> 1) & 0x03}
+	if len(body) < 2 {
+		return nil, errors.New("malformed PUBLISH")
+	}
+	n := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+n {
+		return nil, errors.New("malformed PUBLISH")
+	}
+	p.topic, body = string(body[2:2+n]), body[2+n:]
+	if p.qos > 0 {
+		if len(body) < 2 {
+			return nil, errors.New("malformed PUBLISH")
+		}
+		p.packetID, body = binary.BigEndian.Uint16(body), body[2:]
+	}
+	p.payload = body
+	return p, nil
+}
+
+func (c *mqttConn) puback(id uint16) error {
+	return c.write(packetPuback<<4, binary.BigEndian.AppendUint16(nil, id))
+}
+
+func (c *mqttConn) ping() error {
+	return c.write(packetPingreq<<4, nil)
+}
+
+// disconnect sends DISCONNECT and closes the connection
+func (c *mqttConn) disconnect() {
+	c.write(packetDisconnect<<4, nil)
+	c.conn.Close()
+}
+
+// write sends a packet with the given fixed header byte
+func (c *mqttConn) write(header byte, body []byte) error {
+	pkt := append([]byte{header}, remainingLength(len(body))...)
+	pkt = append(pkt, body...)
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	if _, err := c.conn.Write(pkt); err != nil {
+		return fmt.Errorf("failed to write to broker: %w", err)
+	}
+	return nil
+}
+
+// read reads the next packet and returns its type, flags and body
+func (c *mqttConn) read() (kind, flags byte, body []byte, err error) {
+	header, err := c.r.ReadByte()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	n, mult := 0, 1
+	for i := 0; ; i++ {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		n += int(b&0x7f) * mult
+		if b&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, 0, nil, errors.New("malformed remaining length")
+		}
+		mult *= 128
+	}
+	if n > maxPacketSize {
+		return 0, 0, nil, fmt.Errorf("packet of %d bytes exceeds the %d byte limit", n, maxPacketSize)
+	}
+	body = make([]byte, n)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return 0, 0, nil, err
+	}
+	return header >> 4, header & 0x0f, body, nil
+}
+
+// remainingLength encodes the variable length of a packet body
+func remainingLength(n int) []byte {
+	var b []byte
+	for {
+		d := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			d |= 0x80
+		}
+		b = append(b, d)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
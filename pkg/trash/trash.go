@@ -0,0 +1,83 @@
+// Package trash purges deleted resources from the trash once they are past
+// the retention period.
+package trash
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/database"
+	"github.com/02loveslollipop/api_matriz_enegertica_tadb/pkg/utils"
+)
+
+// Config represents the trash retention settings
+type Config struct {
+	// RetentionDays is how long deleted resources can be restored
+	RetentionDays int
+	// PurgeInterval is how often expired items are purged; 0 disables purging
+	PurgeInterval time.Duration
+}
+
+// LoadConfig loads trash configuration from environment variables
+func LoadConfig() *Config {
+	cfg := &Config{
+		RetentionDays: utils.GetEnvAsInt("TRASH_RETENTION_DAYS", 30),
+		PurgeInterval: utils.GetEnvAsDuration("TRASH_PURGE_INTERVAL", time.Hour),
+	}
+	if cfg.RetentionDays < 0 {
+		cfg.RetentionDays = 30
+	}
+	return cfg
+}
+
+// Purger deletes expired trash items for good
+type Purger struct {
+	repo database.Repository
+	cfg  *Config
+	now  func() time.Time
+}
+
+// NewPurger creates a new Purger
+func NewPurger(repo database.Repository, cfg *Config) *Purger {
+	return &Purger{repo: repo, cfg: cfg, now: time.Now}
+}
+
+// Config returns the purger configuration
+func (p *Purger) Config() *Config {
+	return p.cfg
+}
+
+// PurgeAt returns when an item deleted at deletedAt is purged
+func (p *Purger) PurgeAt(deletedAt time.Time) time.Time {
+	return deletedAt.AddDate(0, 0, p.cfg.RetentionDays)
+}
+
+// Purge deletes the items past the retention period and returns how many there were
+func (p *Purger) Purge(ctx context.Context) (int64, error) {
+	return p.repo.PurgeTrash(ctx, p.now().AddDate(0, 0, -p.cfg.RetentionDays))
+}
+
+// Run purges expired items every PurgeInterval until ctx is cancelled
+func (p *Purger) Run(ctx context.Context) {
+	if p.cfg.PurgeInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(p.cfg.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := p.Purge(ctx)
+			if err != nil {
+				utils.LogError("trash purge", err)
+				continue
+			}
+			if n > 0 {
+				utils.LogInfo(fmt.Sprintf("Purged %d expired trash items", n))
+			}
+		}
+	}
+}
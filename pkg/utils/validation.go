@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/shopspring/decimal"
+)
+
+// RegisterValidators registers custom types and rules on Gin's validator
+func RegisterValidators() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	// Let numeric rules such as gt=0 and gte=0 work on decimal fields
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if d, ok := field.Interface().(decimal.Decimal); ok {
+			return d.InexactFloat64()
+		}
+		return nil
+	}, decimal.Decimal{})
+
+	// isodate: the string is an ISO-8601 calendar date (YYYY-MM-DD)
+	v.RegisterValidation("isodate", func(fl validator.FieldLevel) bool {
+		_, err := ParseDate(fl.Field().String())
+		return err == nil
+	})
+	// notfuture=Field: the date is not after today (UTC) unless the bool
+	// field named by the parameter is true; dates that do not parse are left
+	// to isodate
+	v.RegisterValidation("notfuture", func(fl validator.FieldLevel) bool {
+		if allow := reflect.Indirect(fl.Parent()).FieldByName(fl.Param()); allow.Kind() == reflect.Bool && allow.Bool() {
+			return true
+		}
+		date, err := ParseDate(fl.Field().String())
+		return err != nil || !IsFutureDate(date)
+	})
+}
+
+// DateLayout is the ISO-8601 format of dates in request bodies
+const DateLayout = "2006-01-02"
+
+// ErrInvalidDate is returned for a date that is not YYYY-MM-DD
+var ErrInvalidDate = errors.New("must be a date (YYYY-MM-DD)")
+
+// ParseDate parses an ISO-8601 calendar date (YYYY-MM-DD); other layouts such
+// as 03/09/2025 and impossible dates such as 2025-02-30 are rejected
+func ParseDate(s string) (time.Time, error) {
+	date, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return time.Time{}, ErrInvalidDate
+	}
+	return date, nil
+}
+
+// IsFutureDate reports whether a date parsed by ParseDate is after today in UTC
+func IsFutureDate(date time.Time) bool {
+	return date.Format(DateLayout) > time.Now().UTC().Format(DateLayout)
+}
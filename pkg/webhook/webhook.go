@@ -0,0 +1,141 @@
+// Package webhook signs outgoing webhook requests and verifies them on the
+// receiving side. A request carries the time it was signed and a unique
+// nonce; the signature is an HMAC-SHA256, keyed with the secret of the
+// endpoint, over "<timestamp>.<nonce>.<body>". Receivers reject requests
+// signed too long ago and nonces they have already seen, so a captured
+// delivery cannot be replayed.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers of a signed request
+const (
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderNonce     = "X-Webhook-Nonce"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// signatureVersion prefixes signatures so the scheme can change without
+// breaking receivers; several comma-separated signatures may be sent
+const signatureVersion = "v1="
+
+// DefaultTolerance is how old a signature may be when verified
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	// ErrMissingSignature is returned for requests without the signature headers
+	ErrMissingSignature = errors.New("webhook signature headers missing")
+	// ErrInvalidSignature is returned when no signature matches the secret
+	ErrInvalidSignature = errors.New("webhook signature does not match")
+	// ErrExpired is returned for signatures older (or newer) than the tolerance
+	ErrExpired = errors.New("webhook timestamp outside the tolerance")
+	// ErrReplayed is returned for nonces that were already accepted
+	ErrReplayed = errors.New("webhook nonce already used")
+)
+
+// NewSecret returns a random signing secret
+func NewSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("webhook: no randomness: " + err.Error())
+	}
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+// Signature computes the signature of body for the given timestamp and nonce
+func Signature(secret string, timestamp int64, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write([]byte(nonce))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sign sets the timestamp, nonce and signature headers of a request carrying body
+func Sign(h http.Header, secret, nonce string, body []byte, now time.Time) {
+	ts := now.Unix()
+	h.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	h.Set(HeaderNonce, nonce)
+	h.Set(HeaderSignature, signatureVersion+Signature(secret, ts, nonce, body))
+}
+
+// Verifier checks signed requests for one secret and remembers the nonces
+// it accepted for as long as their timestamp is within the tolerance
+type Verifier struct {
+	secret    string
+	tolerance time.Duration
+	now       func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewVerifier creates a Verifier; a tolerance <= 0 uses DefaultTolerance
+func NewVerifier(secret string, tolerance time.Duration) *Verifier {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	return &Verifier{
+		secret:    secret,
+		tolerance: tolerance,
+		now:       time.Now,
+		seen:      map[string]time.Time{},
+	}
+}
+
+// Verify checks the signature headers h of a request with body and records
+// its nonce. Only requests that pass every check consume their nonce.
+func (v *Verifier) Verify(h http.Header, body []byte) error {
+	tsHeader, nonce, sigs := h.Get(HeaderTimestamp), h.Get(HeaderNonce), h.Get(HeaderSignature)
+	if tsHeader == "" || nonce == "" || sigs == "" {
+		return ErrMissingSignature
+	}
+	ts, err := strconv.ParseInt(tsHeader, 10, 64)
+	if err != nil {
+		return ErrMissingSignature
+	}
+	now := v.now()
+	signed := time.Unix(ts, 0)
+	if d := now.Sub(signed); d > v.tolerance || d < -v.tolerance {
+		return ErrExpired
+	}
+
+	expected := []byte(Signature(v.secret, ts, nonce, body))
+	valid := false
+	for _, sig := range strings.Split(sigs, ",") {
+		sig = strings.TrimSpace(sig)
+		if strings.HasPrefix(sig, signatureVersion) && hmac.Equal([]byte(sig[len(signatureVersion):]), expected) {
+			valid = true
+		}
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for n, expires := range v.seen {
+		if now.After(expires) {
+			delete(v.seen, n)
+		}
+	}
+	if _, ok := v.seen[nonce]; ok {
+		return ErrReplayed
+	}
+	// Past this moment the timestamp check rejects the request on its own
+	v.seen[nonce] = signed.Add(v.tolerance)
+	return nil
+}
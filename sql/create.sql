@@ -4,36 +4,609 @@ CREATE SCHEMA IF NOT EXISTS core;
 
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
+DROP TABLE core.holidays;
+DROP TABLE core.user_backup_codes;
+DROP TABLE core.user_two_factor;
+DROP TABLE core.login_lockouts;
+DROP TABLE core.audit_log;
+DROP TABLE core.impersonation_events;
+DROP TABLE core.impersonations;
+DROP TABLE core.notifications;
+DROP TABLE core.notification_digest_items;
+DROP TABLE core.notification_preferences;
+DROP TABLE core.email_templates;
+DROP TABLE core.import_dead_letters;
+DROP TABLE core.import_profiles;
+DROP TABLE core.recalculations;
+DROP TABLE core.custom_fields;
+DROP TABLE core.annotations;
+DROP TABLE core.attachment_object_deletions;
+DROP TABLE core.attachments;
+DROP TABLE core.connector_files;
+DROP TABLE core.daily_totals;
+DROP TABLE core.trash;
+DROP TABLE core.projection_checkpoints;
+DROP TABLE core.daily_production_summary;
+DROP TABLE core.domain_events;
+DROP TABLE core.webhook_deliveries;
+DROP TABLE core.submission_calendar;
+DROP TABLE core.report_runs;
+DROP TABLE core.reports;
+DROP TABLE core.report_templates;
+DROP TABLE core.production_corrections;
+DROP TABLE core.snapshot_productions;
+DROP TABLE core.published_snapshots;
+DROP TABLE core.type_translations;
+DROP TABLE core.type_aliases;
 DROP TABLE core.production;
 DROP TABLE core.generator;
+DROP TABLE core.plants;
+DROP TABLE core.regions;
+DROP TABLE core.user_operator_grants;
+DROP TABLE core.users;
+DROP TABLE core.operators;
 DROP TABLE core.type;
 
 CREATE TABLE core.type(
     id  UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     name varchar(20) UNIQUE NOT NULL,
     description varchar(80) NOT NULL,
-    isRenuevable bool NOT NULL
+    isRenuevable bool NOT NULL,
+    technology_code varchar(20),
+    deleted_at timestamptz,
+    merged_into UUID REFERENCES core.type(id),
+    -- Values of custom fields (sql/migrations/024_custom_attributes.sql)
+    custom_attributes jsonb NOT NULL DEFAULT '{}',
+    -- Raised by a trigger on every update (sql/migrations/041_row_versions.sql)
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE core.type_aliases(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    alias varchar(20) NOT NULL,
+    type_id UUID NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT fk_alias_type
+        FOREIGN KEY (type_id)
+        REFERENCES core.type(id)
+        ON DELETE CASCADE
+);
+
+-- Name and description of a type per language (sql/migrations/019_type_translations.sql)
+CREATE TABLE core.type_translations(
+    type_id UUID NOT NULL REFERENCES core.type(id) ON DELETE CASCADE,
+    language varchar(2) NOT NULL CHECK (language IN ('es', 'en')),
+    name varchar(20) NOT NULL,
+    description varchar(80) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (type_id, language)
+);
+
+CREATE TABLE core.operators(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name varchar(80) UNIQUE NOT NULL,
+    country char(2),
+    custom_attributes jsonb NOT NULL DEFAULT '{}'
+);
+
+CREATE TABLE core.user_operator_grants(
+    user_id UUID NOT NULL,
+    operator_id UUID NOT NULL REFERENCES core.operators(id) ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, operator_id)
+);
+
+-- Facilities grouping generators (sql/migrations/035_plants.sql)
+CREATE TABLE core.plants(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name varchar(120) UNIQUE NOT NULL,
+    location varchar(200) NOT NULL DEFAULT '',
+    latitude double precision CHECK (latitude BETWEEN -90 AND 90),
+    longitude double precision CHECK (longitude BETWEEN -180 AND 180),
+    CHECK ((latitude IS NULL) = (longitude IS NULL)),
+    owner_id UUID REFERENCES core.operators(id) ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_plants_owner_id ON core.plants (owner_id);
+
+-- Geographic regions generators belong to (sql/migrations/037_regions.sql)
+CREATE TABLE core.regions(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    code varchar(10) UNIQUE NOT NULL,
+    name varchar(120) UNIQUE NOT NULL,
+    min_lng double precision,
+    min_lat double precision,
+    max_lng double precision,
+    max_lat double precision,
+    CHECK ((min_lng IS NULL AND min_lat IS NULL AND max_lng IS NULL AND max_lat IS NULL)
+        OR (min_lng BETWEEN -180 AND max_lng AND max_lng <= 180
+            AND min_lat BETWEEN -90 AND max_lat AND max_lat <= 90)),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
 );
 
 CREATE TABLE core.generator(
     id  UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     type UUID NOT NULL,
-    capacity FLOAT NOT NULL,
+    capacity NUMERIC(14,4) NOT NULL,
+    operator_id UUID REFERENCES core.operators(id) ON DELETE SET NULL,
+    plant_id UUID REFERENCES core.plants(id) ON DELETE SET NULL,
+    region_id UUID REFERENCES core.regions(id) ON DELETE SET NULL,
+    -- WGS 84 location (sql/migrations/018_generator_location.sql)
+    latitude double precision CHECK (latitude BETWEEN -90 AND 90),
+    longitude double precision CHECK (longitude BETWEEN -180 AND 180),
+    CHECK ((latitude IS NULL) = (longitude IS NULL)),
+    -- Day the generator entered service (sql/migrations/039_data_quality.sql)
+    commissioned_on date,
+    custom_attributes jsonb NOT NULL DEFAULT '{}',
+    -- Raised by a trigger on every update (sql/migrations/041_row_versions.sql)
+    version integer NOT NULL DEFAULT 1,
+    -- Deletes of types in use are rejected (sql/migrations/026_restrict_deletes.sql)
     CONSTRAINT fk_type
         FOREIGN KEY (type)
         REFERENCES core.type(id)
-        ON DELETE CASCADE
 );
 
 CREATE TABLE core.production(
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     generator_id UUID NOT NULL,
     date DATE NOT NULL,
-    production_mw DECIMAL NOT NULL,
+    production_mw NUMERIC(14,4) NOT NULL,
+    source varchar(20) NOT NULL DEFAULT 'api'
+        CHECK (source IN ('manual', 'api', 'import', 'external', 'telemetry')),
+    source_ref varchar(120),
+    signature char(64),
+    custom_attributes jsonb NOT NULL DEFAULT '{}',
+    -- Raised by a trigger on every update but re-signing (sql/migrations/041_row_versions.sql)
+    version integer NOT NULL DEFAULT 1,
     CONSTRAINT fk_generator
         FOREIGN KEY (generator_id)
-        REFERENCES core.generator(id)
-        ON DELETE CASCADE,
+        REFERENCES core.generator(id),
     CONSTRAINT uk_generator_date
         UNIQUE(generator_id,date)
 );
+
+-- Published months; triggers protecting them are in sql/migrations/007_published_snapshots.sql
+CREATE TABLE core.published_snapshots(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    month DATE UNIQUE NOT NULL,
+    published_at timestamptz NOT NULL DEFAULT now(),
+    record_count bigint NOT NULL,
+    total_production NUMERIC(18,4) NOT NULL,
+    hash varchar(64) NOT NULL
+);
+
+CREATE TABLE core.snapshot_productions(
+    snapshot_id UUID NOT NULL REFERENCES core.published_snapshots(id),
+    production_id UUID NOT NULL,
+    generator_id UUID NOT NULL,
+    date DATE NOT NULL,
+    production_mw NUMERIC(14,4) NOT NULL,
+    source varchar(20) NOT NULL,
+    source_ref varchar(120),
+    PRIMARY KEY (snapshot_id, production_id)
+);
+
+CREATE TABLE core.production_corrections(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    production_id UUID NOT NULL REFERENCES core.production(id) ON DELETE CASCADE,
+    snapshot_id UUID REFERENCES core.published_snapshots(id),
+    previous_mw NUMERIC(14,4) NOT NULL,
+    corrected_mw NUMERIC(14,4) NOT NULL,
+    reason varchar(500) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Report templates (sql/migrations/009_report_templates.sql)
+CREATE TABLE core.report_templates(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name varchar(120) NOT NULL,
+    description varchar(500) NOT NULL DEFAULT '',
+    language varchar(10) NOT NULL DEFAULT 'en',
+    branding jsonb NOT NULL DEFAULT '{}',
+    sections jsonb NOT NULL DEFAULT '[]',
+    labels jsonb NOT NULL DEFAULT '{}',
+    subject text NOT NULL DEFAULT '',
+    email_body text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Saved and scheduled reports (sql/migrations/008_reports.sql)
+CREATE TABLE core.reports(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name varchar(120) NOT NULL,
+    kind varchar(20) NOT NULL CHECK (kind IN ('productions', 'crosstab')),
+    format varchar(10) NOT NULL,
+    filters jsonb NOT NULL DEFAULT '{}',
+    schedule jsonb NOT NULL DEFAULT '{}',
+    delivery jsonb NOT NULL DEFAULT '{}',
+    template_id UUID REFERENCES core.report_templates(id) ON DELETE SET NULL,
+    webhook_secret varchar(80) NOT NULL,
+    enabled bool NOT NULL DEFAULT true,
+    next_run_at timestamptz,
+    last_run_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE core.report_runs(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    report_id UUID NOT NULL REFERENCES core.reports(id) ON DELETE CASCADE,
+    trigger varchar(20) NOT NULL CHECK (trigger IN ('schedule', 'manual')),
+    status varchar(20) NOT NULL CHECK (status IN ('running', 'succeeded', 'failed')),
+    period_start DATE,
+    period_end DATE,
+    row_count bigint NOT NULL DEFAULT 0,
+    byte_count bigint NOT NULL DEFAULT 0,
+    error text,
+    started_at timestamptz NOT NULL DEFAULT now(),
+    finished_at timestamptz
+);
+
+-- Expected submission cadence per type or generator (sql/migrations/010_submission_calendar.sql)
+CREATE TABLE core.submission_calendar(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    type_id UUID UNIQUE REFERENCES core.type(id) ON DELETE CASCADE,
+    generator_id UUID UNIQUE REFERENCES core.generator(id) ON DELETE CASCADE,
+    cadence varchar(10) NOT NULL CHECK (cadence IN ('daily', 'weekly', 'monthly')),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CHECK ((type_id IS NULL) <> (generator_id IS NULL))
+);
+
+-- Webhook delivery log (sql/migrations/011_webhook_deliveries.sql)
+CREATE TABLE core.webhook_deliveries(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    report_id UUID NOT NULL REFERENCES core.reports(id) ON DELETE CASCADE,
+    run_id UUID REFERENCES core.report_runs(id) ON DELETE SET NULL,
+    redelivery_of UUID REFERENCES core.webhook_deliveries(id) ON DELETE SET NULL,
+    url text NOT NULL,
+    period_start DATE,
+    period_end DATE,
+    status_code integer,
+    succeeded bool NOT NULL,
+    error text,
+    byte_count bigint NOT NULL DEFAULT 0,
+    duration_ms bigint NOT NULL DEFAULT 0,
+    delivered_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Append-only domain event log and its projections; the triggers
+-- filling the log are in sql/migrations/012_event_log.sql
+CREATE TABLE core.domain_events(
+    seq bigserial PRIMARY KEY,
+    aggregate varchar(20) NOT NULL,
+    aggregate_id UUID NOT NULL,
+    event varchar(40) NOT NULL,
+    old_state jsonb,
+    new_state jsonb,
+    txid xid8 NOT NULL DEFAULT pg_current_xact_id(),
+    occurred_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE core.daily_production_summary(
+    date DATE NOT NULL,
+    type_id UUID NOT NULL,
+    records bigint NOT NULL,
+    production_mw NUMERIC(18,4) NOT NULL,
+    PRIMARY KEY (date, type_id)
+);
+
+CREATE TABLE core.projection_checkpoints(
+    name varchar(40) PRIMARY KEY,
+    last_txid xid8 NOT NULL DEFAULT '0',
+    last_seq bigint NOT NULL DEFAULT 0,
+    updated_at timestamptz,
+    rebuilt_at timestamptz
+);
+
+-- Deleted resources awaiting purge (sql/migrations/013_trash.sql)
+CREATE TABLE core.trash(
+    id UUID PRIMARY KEY,
+    resource varchar(30) NOT NULL,
+    resource_id UUID NOT NULL,
+    label varchar(200) NOT NULL,
+    row_count integer NOT NULL,
+    payload jsonb NOT NULL,
+    deleted_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Running production totals per day and type; the triggers keeping
+-- them current are in sql/migrations/014_daily_totals.sql
+CREATE TABLE core.daily_totals(
+    date DATE NOT NULL,
+    type_id UUID NOT NULL,
+    records bigint NOT NULL,
+    production_mw NUMERIC(18,4) NOT NULL,
+    PRIMARY KEY (date, type_id)
+);
+
+-- Files pulled from partner SFTP feeds and mailboxes (sql/migrations/016_connector_files.sql, 017_connector_files_connector.sql)
+CREATE TABLE core.connector_files(
+    id UUID PRIMARY KEY,
+    connector varchar(20) NOT NULL DEFAULT 'sftp' CHECK (connector IN ('sftp', 'email')),
+    source varchar(80) NOT NULL,
+    remote_path text NOT NULL,
+    size bigint NOT NULL,
+    modified_at timestamptz NOT NULL,
+    sha256 char(64) NOT NULL,
+    status varchar(20) NOT NULL CHECK (status IN ('staged', 'duplicate')),
+    staged_as text,
+    fetched_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_connector_files_path ON core.connector_files (source, remote_path, size, modified_at);
+CREATE INDEX idx_connector_files_sha256 ON core.connector_files (source, sha256);
+CREATE INDEX idx_connector_files_fetched ON core.connector_files (fetched_at DESC);
+CREATE INDEX idx_connector_files_connector ON core.connector_files (connector, fetched_at DESC);
+
+-- Documents attached to types and generators; the trigger queueing the
+-- objects of deleted attachments is in sql/migrations/020_attachments.sql
+CREATE TABLE core.attachments(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    type_id UUID REFERENCES core.type(id) ON DELETE CASCADE,
+    generator_id UUID REFERENCES core.generator(id) ON DELETE CASCADE,
+    file_name varchar(255) NOT NULL,
+    content_type varchar(100) NOT NULL,
+    size_bytes bigint NOT NULL CHECK (size_bytes >= 0),
+    sha256 char(64) NOT NULL,
+    storage_key text,
+    content bytea,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CHECK ((type_id IS NULL) <> (generator_id IS NULL)),
+    CHECK ((storage_key IS NULL) <> (content IS NULL))
+);
+
+CREATE TABLE core.attachment_object_deletions(
+    storage_key text PRIMARY KEY,
+    queued_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Notes on production records and date ranges (sql/migrations/021_annotations.sql)
+CREATE TABLE core.annotations(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    production_id UUID REFERENCES core.production(id) ON DELETE CASCADE,
+    generator_id UUID REFERENCES core.generator(id) ON DELETE CASCADE,
+    start_date date,
+    end_date date,
+    note varchar(1000) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    CHECK (
+        (production_id IS NOT NULL AND generator_id IS NULL AND start_date IS NULL AND end_date IS NULL)
+        OR (production_id IS NULL AND start_date IS NOT NULL AND end_date >= start_date)
+    )
+);
+
+CREATE INDEX idx_annotations_production ON core.annotations (production_id) WHERE production_id IS NOT NULL;
+CREATE INDEX idx_annotations_range ON core.annotations (start_date, end_date) WHERE production_id IS NULL;
+
+-- User accounts (sql/migrations/022_users.sql)
+CREATE TABLE core.users(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    username varchar(50) NOT NULL,
+    email varchar(254) NOT NULL,
+    password_hash text NOT NULL,
+    roles text[] NOT NULL DEFAULT '{viewer}'
+        CONSTRAINT users_roles_check CHECK (cardinality(roles) > 0 AND roles <@ ARRAY['admin', 'operator', 'viewer']),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX idx_users_username ON core.users (lower(username));
+CREATE UNIQUE INDEX idx_users_email ON core.users (lower(email));
+
+-- Custom field registry (sql/migrations/024_custom_attributes.sql)
+CREATE TABLE core.custom_fields(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    entity varchar(20) NOT NULL CHECK (entity IN ('type', 'operator', 'generator', 'production')),
+    name varchar(50) NOT NULL CHECK (name ~ '^[A-Za-z][A-Za-z0-9_]*$'),
+    type varchar(10) NOT NULL CHECK (type IN ('string', 'number', 'boolean', 'date')),
+    required bool NOT NULL DEFAULT false,
+    description varchar(200),
+    pattern varchar(200),
+    min_value double precision,
+    max_value double precision,
+    options text[],
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (entity, name)
+);
+
+CREATE INDEX idx_generators_custom_attributes ON core.generator USING GIN (custom_attributes jsonb_path_ops);
+CREATE INDEX idx_generators_plant_id ON core.generator (plant_id);
+CREATE INDEX idx_generators_region_id ON core.generator (region_id);
+CREATE INDEX idx_productions_custom_attributes ON core.production USING GIN (custom_attributes jsonb_path_ops);
+
+-- Production recalculations (sql/migrations/025_recalculations.sql)
+CREATE TABLE core.recalculations(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    generator_id UUID,
+    type_id UUID,
+    start_date date NOT NULL,
+    end_date date NOT NULL,
+    source varchar(20),
+    factor NUMERIC(18,8) NOT NULL,
+    offset_mw NUMERIC(14,4) NOT NULL,
+    reason varchar(400) NOT NULL,
+    user_id UUID REFERENCES core.users(id) ON DELETE SET NULL,
+    records int NOT NULL,
+    published_records int NOT NULL,
+    previous_total_mw NUMERIC(18,4) NOT NULL,
+    new_total_mw NUMERIC(18,4) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_recalculations_created_at ON core.recalculations (created_at DESC);
+
+-- Column mappings of import files per source (sql/migrations/027_import_profiles.sql)
+CREATE TABLE core.import_profiles(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name varchar(80) NOT NULL UNIQUE,
+    source varchar(80) NOT NULL,
+    description varchar(500),
+    columns jsonb NOT NULL DEFAULT '{}',
+    delimiter varchar(1),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_import_profiles_source ON core.import_profiles (source);
+
+-- Rejected import rows kept for correction (sql/migrations/028_import_dead_letters.sql)
+CREATE TABLE core.import_dead_letters(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    job_id UUID NOT NULL,
+    line bigint NOT NULL,
+    generator_id varchar(200) NOT NULL DEFAULT '',
+    date varchar(200) NOT NULL DEFAULT '',
+    production_mw varchar(200) NOT NULL DEFAULT '',
+    error text NOT NULL,
+    status varchar(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'resubmitted')),
+    attempts int NOT NULL DEFAULT 0,
+    production_id UUID REFERENCES core.production(id) ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_import_dead_letters_job ON core.import_dead_letters (job_id, line);
+CREATE INDEX idx_import_dead_letters_status ON core.import_dead_letters (status, created_at);
+
+-- Versioned notification email templates (sql/migrations/029_email_templates.sql)
+CREATE TABLE core.email_templates(
+    key varchar(60) NOT NULL,
+    version int NOT NULL CHECK (version > 0),
+    subject varchar(300) NOT NULL,
+    body text NOT NULL,
+    comment varchar(200),
+    user_id UUID REFERENCES core.users(id) ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (key, version)
+);
+
+-- Notification preferences and digests (sql/migrations/030_notification_preferences.sql)
+CREATE TABLE core.notification_preferences(
+    user_id UUID NOT NULL REFERENCES core.users(id) ON DELETE CASCADE,
+    alert_type varchar(40) NOT NULL,
+    delivery varchar(20) NOT NULL
+        CHECK (delivery IN ('immediate', 'digest', 'mute')),
+    channels text[] NOT NULL DEFAULT '{email}',
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, alert_type)
+);
+
+CREATE TABLE core.notification_digest_items(
+    id bigserial PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES core.users(id) ON DELETE CASCADE,
+    alert_type varchar(40) NOT NULL,
+    subject varchar(300) NOT NULL,
+    body text NOT NULL,
+    raised_at timestamptz NOT NULL
+);
+
+CREATE INDEX idx_notification_digest_items_user ON core.notification_digest_items (user_id, raised_at);
+
+-- In-app notification inbox (sql/migrations/031_notifications.sql)
+CREATE TABLE core.notifications(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES core.users(id) ON DELETE CASCADE,
+    alert_type varchar(40) NOT NULL,
+    subject varchar(300) NOT NULL,
+    body text NOT NULL,
+    read_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_notifications_user ON core.notifications (user_id, created_at DESC);
+CREATE INDEX idx_notifications_unread ON core.notifications (user_id, alert_type) WHERE read_at IS NULL;
+CREATE INDEX idx_notifications_created ON core.notifications (created_at);
+
+-- Impersonation sessions and their requests (sql/migrations/032_impersonations.sql)
+CREATE TABLE core.impersonations(
+    id UUID PRIMARY KEY,
+    actor_id UUID NOT NULL REFERENCES core.users(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES core.users(id) ON DELETE CASCADE,
+    operator_id UUID REFERENCES core.operators(id) ON DELETE CASCADE,
+    reason varchar(500) NOT NULL,
+    started_at timestamptz NOT NULL DEFAULT now(),
+    expires_at timestamptz NOT NULL,
+    ended_at timestamptz,
+    CHECK ((user_id IS NULL) <> (operator_id IS NULL))
+);
+
+CREATE INDEX idx_impersonations_started ON core.impersonations (started_at DESC);
+
+CREATE TABLE core.impersonation_events(
+    id bigserial PRIMARY KEY,
+    session_id UUID NOT NULL REFERENCES core.impersonations(id) ON DELETE CASCADE,
+    method varchar(10) NOT NULL,
+    path text NOT NULL,
+    status int NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_impersonation_events_session ON core.impersonation_events (session_id, id);
+
+-- Audit log of mutations (sql/migrations/033_audit_log.sql)
+CREATE TABLE core.audit_log(
+    id bigserial PRIMARY KEY,
+    entity_type varchar(40) NOT NULL,
+    entity_id UUID NOT NULL,
+    action varchar(10) NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    before jsonb,
+    after jsonb,
+    user_id UUID,
+    impersonator_id UUID,
+    -- Address the change was requested from (sql/migrations/038_audit_client_ip.sql)
+    client_ip varchar(45),
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_audit_log_entity ON core.audit_log (entity_type, entity_id, id);
+CREATE INDEX idx_audit_log_created ON core.audit_log (created_at);
+CREATE INDEX idx_audit_log_user ON core.audit_log (user_id, created_at);
+
+-- Failed logins and lockouts per username (sql/migrations/034_login_lockouts.sql)
+CREATE TABLE core.login_lockouts(
+    username varchar(50) PRIMARY KEY,
+    failures integer NOT NULL DEFAULT 0,
+    lockouts integer NOT NULL DEFAULT 0,
+    last_failure_at timestamptz,
+    last_ip varchar(45) NOT NULL DEFAULT '',
+    locked_until timestamptz
+);
+
+CREATE INDEX idx_login_lockouts_locked ON core.login_lockouts (locked_until) WHERE locked_until IS NOT NULL;
+
+-- TOTP enrollments and backup codes of users (sql/migrations/036_two_factor.sql)
+CREATE TABLE core.user_two_factor(
+    user_id UUID PRIMARY KEY REFERENCES core.users(id) ON DELETE CASCADE,
+    secret varchar(64) NOT NULL,
+    enabled_at timestamptz,
+    last_step bigint NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE core.user_backup_codes(
+    user_id UUID NOT NULL REFERENCES core.user_two_factor(user_id) ON DELETE CASCADE,
+    code_hash char(64) NOT NULL,
+    used_at timestamptz,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+-- Holidays managed through the API, per country and region (sql/migrations/040_holidays.sql)
+CREATE TABLE core.holidays(
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    country char(2) NOT NULL,
+    region varchar(10) NOT NULL DEFAULT '',
+    date varchar(10) NOT NULL,
+    name varchar(120) NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT uq_holidays_day UNIQUE (country, region, date),
+    CONSTRAINT chk_holidays_date CHECK (date ~ '^([0-9]{4}-)?[0-9]{2}-[0-9]{2}$')
+);
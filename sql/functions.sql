@@ -44,7 +44,7 @@ RETURNS TABLE(
     id uuid,
     type_id uuid,
     type_name varchar(20),
-    capacity float
+    capacity numeric
 )
 LANGUAGE plpgsql
 AS $$
@@ -66,7 +66,7 @@ RETURNS TABLE(
     type_name varchar(20),
     type_description varchar(80),
     isrenuevable boolean,
-    capacity float
+    capacity numeric
 )
 LANGUAGE plpgsql
 AS $$
@@ -87,7 +87,7 @@ RETURNS TABLE(
     type_name varchar(20),
     type_description varchar(80),
     isrenuevable boolean,
-    capacity float
+    capacity numeric
 )
 LANGUAGE plpgsql
 AS $$
@@ -159,7 +159,7 @@ CREATE OR REPLACE FUNCTION core.get_all_productions()
 RETURNS TABLE(
     id uuid,
     generator_id uuid,
-    generator_capacity float,
+    generator_capacity numeric,
     type_name varchar(20),
     isrenuevable boolean,
     date date,
@@ -182,7 +182,7 @@ CREATE OR REPLACE FUNCTION core.get_production_by_id(p_production_id uuid)
 RETURNS TABLE(
     id uuid,
     generator_id uuid,
-    generator_capacity float,
+    generator_capacity numeric,
     type_name varchar(20),
     isrenuevable boolean,
     date date,
@@ -245,7 +245,7 @@ CREATE OR REPLACE FUNCTION core.get_generator_efficiency(
 RETURNS TABLE(
     generator_id uuid,
     type_name varchar(20),
-    capacity float,
+    capacity numeric,
     total_production decimal,
     avg_daily_production decimal,
     efficiency_percentage decimal
@@ -281,7 +281,7 @@ CREATE OR REPLACE FUNCTION core.get_renewable_summary(
 )
 RETURNS TABLE(
     energy_type varchar(20),
-    total_capacity float,
+    total_capacity numeric,
     generator_count bigint,
     total_production decimal,
     avg_production decimal,
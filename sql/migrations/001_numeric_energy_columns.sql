@@ -0,0 +1,15 @@
+-- =====================================================
+-- Store capacity and production as exact NUMERIC values
+-- =====================================================
+-- Summing FLOAT values over years of daily data accumulates visible
+-- rounding error in totals. NUMERIC keeps aggregates exact.
+
+BEGIN;
+
+ALTER TABLE core.generators
+    ALTER COLUMN capacity TYPE NUMERIC(14,4) USING capacity::numeric(14,4);
+
+ALTER TABLE core.productions
+    ALTER COLUMN production_mw TYPE NUMERIC(14,4) USING production_mw::numeric(14,4);
+
+COMMIT;
@@ -0,0 +1,28 @@
+-- =====================================================
+-- Canonical technology code on generator types
+-- =====================================================
+-- Maps free-form type names to the codes served by
+-- GET /api/v1/catalog/technologies so datasets can be compared.
+
+BEGIN;
+
+ALTER TABLE core.types
+    ADD COLUMN IF NOT EXISTS technology_code VARCHAR(20);
+
+-- Backfill well-known names; remaining types keep a NULL code
+UPDATE core.types
+SET technology_code = CASE
+        WHEN lower(name) IN ('solar', 'solar fotovoltaica', 'fotovoltaica', 'pv') THEN 'SOLAR'
+        WHEN lower(name) IN ('eólica', 'eolica', 'wind', 'viento') THEN 'WIND'
+        WHEN lower(name) IN ('hidráulica', 'hidraulica', 'hidroeléctrica', 'hidroelectrica', 'hydro') THEN 'HYDRO'
+        WHEN lower(name) IN ('biomasa', 'biomass', 'biogas') THEN 'BIOMASS'
+        WHEN lower(name) IN ('geotérmica', 'geotermica', 'geothermal') THEN 'GEOTHERMAL'
+        WHEN lower(name) IN ('térmica', 'termica', 'thermal') THEN 'THERMAL'
+        WHEN lower(name) IN ('carbón', 'carbon', 'coal') THEN 'COAL'
+        WHEN lower(name) IN ('gas', 'gas natural', 'natural gas') THEN 'GAS'
+        WHEN lower(name) IN ('diesel', 'combustibles líquidos', 'combustibles liquidos', 'oil') THEN 'OIL'
+        WHEN lower(name) = 'nuclear' THEN 'NUCLEAR'
+    END
+WHERE technology_code IS NULL;
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- =====================================================
+-- Merge duplicate types (aliases + soft delete)
+-- =====================================================
+-- POST /api/v1/types/:id/merge-into/:targetId moves the generators
+-- of a duplicate type, keeps its name as an alias of the target and
+-- soft-deletes it instead of dropping the row.
+
+BEGIN;
+
+ALTER TABLE core.types
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS merged_into UUID REFERENCES core.types(id);
+
+CREATE TABLE IF NOT EXISTS core.type_aliases (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    alias VARCHAR(20) NOT NULL,
+    type_id UUID NOT NULL REFERENCES core.types(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_type_aliases_type_id ON core.type_aliases(type_id);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_type_aliases_alias ON core.type_aliases(lower(alias), type_id);
+
+COMMIT;
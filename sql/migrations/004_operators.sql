@@ -0,0 +1,22 @@
+-- =====================================================
+-- Generator operators (companies)
+-- =====================================================
+-- Links each generator to the company that owns or operates it,
+-- used by GET /api/v1/analytics/market-share.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.operators (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(80) UNIQUE NOT NULL,
+    country CHAR(2),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE core.generators
+    ADD COLUMN IF NOT EXISTS operator_id UUID REFERENCES core.operators(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_generators_operator_id ON core.generators(operator_id);
+
+COMMIT;
@@ -0,0 +1,16 @@
+-- =====================================================
+-- Per-operator write permissions
+-- =====================================================
+-- A user with grants may only write generators (and their
+-- productions) of the granted operators.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.user_operator_grants (
+    user_id UUID NOT NULL,
+    operator_id UUID NOT NULL REFERENCES core.operators(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, operator_id)
+);
+
+COMMIT;
@@ -0,0 +1,21 @@
+-- =====================================================
+-- Provenance of production records
+-- =====================================================
+-- Records where each value came from (manual edit, API client,
+-- import job or external system) and an optional HMAC signature
+-- so auditors can tell official data from corrections.
+
+BEGIN;
+
+ALTER TABLE core.productions
+    ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'api',
+    ADD COLUMN IF NOT EXISTS source_ref VARCHAR(120),
+    ADD COLUMN IF NOT EXISTS signature CHAR(64);
+
+ALTER TABLE core.productions
+    ADD CONSTRAINT chk_productions_source
+    CHECK (source IN ('manual', 'api', 'import', 'external'));
+
+CREATE INDEX IF NOT EXISTS idx_productions_source ON core.productions(source);
+
+COMMIT;
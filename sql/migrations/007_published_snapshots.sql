@@ -0,0 +1,97 @@
+-- =====================================================
+-- Immutable published snapshots of monthly data
+-- =====================================================
+-- Publishing a month copies its production records into
+-- snapshot_productions and records a SHA-256 hash of the copy.
+-- Afterwards triggers reject plain writes to productions of that
+-- month; the correction workflow enables them per transaction
+-- (tadb.allow_published_edit) and logs every change in
+-- production_corrections. Snapshots themselves never change.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.published_snapshots (
+    id UUID PRIMARY KEY,
+    month DATE NOT NULL UNIQUE CHECK (month = date_trunc('month', month)::date),
+    published_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    record_count BIGINT NOT NULL,
+    total_production NUMERIC(18,4) NOT NULL,
+    hash VARCHAR(64) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS core.snapshot_productions (
+    snapshot_id UUID NOT NULL REFERENCES core.published_snapshots(id),
+    production_id UUID NOT NULL,
+    generator_id UUID NOT NULL,
+    date DATE NOT NULL,
+    production_mw NUMERIC(14,4) NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    source_ref VARCHAR(120),
+    PRIMARY KEY (snapshot_id, production_id)
+);
+
+CREATE TABLE IF NOT EXISTS core.production_corrections (
+    id UUID PRIMARY KEY,
+    production_id UUID NOT NULL REFERENCES core.productions(id) ON DELETE CASCADE,
+    snapshot_id UUID REFERENCES core.published_snapshots(id),
+    previous_mw NUMERIC(14,4) NOT NULL,
+    corrected_mw NUMERIC(14,4) NOT NULL,
+    reason VARCHAR(500) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_production_corrections_production ON core.production_corrections(production_id);
+
+-- Rejects writes to productions of published months (SQLSTATE TP001)
+CREATE OR REPLACE FUNCTION core.protect_published_productions()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    IF current_setting('tadb.allow_published_edit', true) = 'on' THEN
+        RETURN COALESCE(NEW, OLD);
+    END IF;
+    IF TG_OP <> 'INSERT' THEN
+        IF EXISTS (SELECT 1 FROM core.published_snapshots WHERE month = date_trunc('month', OLD.date)::date) THEN
+            RAISE EXCEPTION 'month % is published', to_char(OLD.date, 'YYYY-MM') USING ERRCODE = 'TP001';
+        END IF;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        IF EXISTS (SELECT 1 FROM core.published_snapshots WHERE month = date_trunc('month', NEW.date)::date) THEN
+            RAISE EXCEPTION 'month % is published', to_char(NEW.date, 'YYYY-MM') USING ERRCODE = 'TP001';
+        END IF;
+    END IF;
+    RETURN COALESCE(NEW, OLD);
+END;
+$$;
+
+DROP TRIGGER IF EXISTS trg_protect_published_productions ON core.productions;
+CREATE TRIGGER trg_protect_published_productions
+    BEFORE INSERT OR UPDATE OR DELETE ON core.productions
+    FOR EACH ROW EXECUTE FUNCTION core.protect_published_productions();
+
+-- Snapshots are append-only; the only update allowed is recording the
+-- hash of a snapshot being published (hash still empty)
+CREATE OR REPLACE FUNCTION core.protect_snapshots()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND TG_TABLE_NAME = 'published_snapshots' AND OLD.hash = '' THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'published snapshots are immutable' USING ERRCODE = 'TP001';
+END;
+$$;
+
+DROP TRIGGER IF EXISTS trg_protect_published_snapshots ON core.published_snapshots;
+CREATE TRIGGER trg_protect_published_snapshots
+    BEFORE UPDATE OR DELETE ON core.published_snapshots
+    FOR EACH ROW EXECUTE FUNCTION core.protect_snapshots();
+
+DROP TRIGGER IF EXISTS trg_protect_snapshot_productions ON core.snapshot_productions;
+CREATE TRIGGER trg_protect_snapshot_productions
+    BEFORE UPDATE OR DELETE ON core.snapshot_productions
+    FOR EACH ROW EXECUTE FUNCTION core.protect_snapshots();
+
+COMMIT;
@@ -0,0 +1,45 @@
+-- =====================================================
+-- Saved and scheduled reports
+-- =====================================================
+-- A report stores its data selection, output format, schedule and
+-- delivery targets (JSONB, shaped like models.ReportFilters,
+-- ReportSchedule and ReportDelivery). The scheduler picks reports
+-- whose next_run_at has passed, claims them by moving next_run_at
+-- forward and records every execution in report_runs.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.reports (
+    id UUID PRIMARY KEY,
+    name VARCHAR(120) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('productions', 'crosstab')),
+    format VARCHAR(10) NOT NULL,
+    filters JSONB NOT NULL DEFAULT '{}',
+    schedule JSONB NOT NULL DEFAULT '{}',
+    delivery JSONB NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    next_run_at TIMESTAMPTZ,
+    last_run_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reports_next_run_at ON core.reports (next_run_at) WHERE enabled;
+
+CREATE TABLE IF NOT EXISTS core.report_runs (
+    id UUID PRIMARY KEY,
+    report_id UUID NOT NULL REFERENCES core.reports(id) ON DELETE CASCADE,
+    trigger VARCHAR(20) NOT NULL CHECK (trigger IN ('schedule', 'manual')),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('running', 'succeeded', 'failed')),
+    period_start DATE,
+    period_end DATE,
+    row_count BIGINT NOT NULL DEFAULT 0,
+    byte_count BIGINT NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_runs_report ON core.report_runs (report_id, started_at DESC);
+
+COMMIT;
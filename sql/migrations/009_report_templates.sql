@@ -0,0 +1,30 @@
+-- =====================================================
+-- Report templates
+-- =====================================================
+-- A template is the layout of generated bulletins: branding, the
+-- sections in order and the language of their texts (JSONB, shaped
+-- like models.ReportBranding, []ReportSection and the label
+-- overrides). Texts are Go templates evaluated when a report runs.
+-- Reports whose template is deleted fall back to the standard
+-- bulletin.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.report_templates (
+    id UUID PRIMARY KEY,
+    name VARCHAR(120) NOT NULL,
+    description VARCHAR(500) NOT NULL DEFAULT '',
+    language VARCHAR(10) NOT NULL DEFAULT 'en',
+    branding JSONB NOT NULL DEFAULT '{}',
+    sections JSONB NOT NULL DEFAULT '[]',
+    labels JSONB NOT NULL DEFAULT '{}',
+    subject TEXT NOT NULL DEFAULT '',
+    email_body TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE core.reports
+    ADD COLUMN IF NOT EXISTS template_id UUID REFERENCES core.report_templates(id) ON DELETE SET NULL;
+
+COMMIT;
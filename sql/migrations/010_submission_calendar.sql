@@ -0,0 +1,22 @@
+-- =====================================================
+-- Expected submission calendar
+-- =====================================================
+-- Reporting cadence expected from generators (daily, weekly or
+-- monthly), set per type and overridden per generator. Generators
+-- without an entry are expected to report daily. Used by
+-- GET /api/v1/analytics/freshness so plants that report weekly or
+-- monthly are not flagged as stale between submissions.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.submission_calendar (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    type_id UUID UNIQUE REFERENCES core.types(id) ON DELETE CASCADE,
+    generator_id UUID UNIQUE REFERENCES core.generators(id) ON DELETE CASCADE,
+    cadence VARCHAR(10) NOT NULL CHECK (cadence IN ('daily', 'weekly', 'monthly')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((type_id IS NULL) <> (generator_id IS NULL))
+);
+
+COMMIT;
@@ -0,0 +1,38 @@
+-- =====================================================
+-- Signed webhook deliveries
+-- =====================================================
+-- Every report gets a secret to sign its webhook requests with
+-- (HMAC-SHA256, see pkg/webhook); existing reports get a random
+-- one. Each POST to a webhook is logged in webhook_deliveries with
+-- its outcome; the delivery id is also the nonce of the request.
+-- Redeliveries point to the delivery they repeat.
+
+BEGIN;
+
+ALTER TABLE core.reports ADD COLUMN IF NOT EXISTS webhook_secret VARCHAR(80);
+
+UPDATE core.reports
+SET webhook_secret = 'whsec_' || replace(gen_random_uuid()::text || gen_random_uuid()::text, '-', '')
+WHERE webhook_secret IS NULL;
+
+ALTER TABLE core.reports ALTER COLUMN webhook_secret SET NOT NULL;
+
+CREATE TABLE IF NOT EXISTS core.webhook_deliveries (
+    id UUID PRIMARY KEY,
+    report_id UUID NOT NULL REFERENCES core.reports(id) ON DELETE CASCADE,
+    run_id UUID REFERENCES core.report_runs(id) ON DELETE SET NULL,
+    redelivery_of UUID REFERENCES core.webhook_deliveries(id) ON DELETE SET NULL,
+    url TEXT NOT NULL,
+    period_start DATE,
+    period_end DATE,
+    status_code INTEGER,
+    succeeded BOOLEAN NOT NULL,
+    error TEXT,
+    byte_count BIGINT NOT NULL DEFAULT 0,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    delivered_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_report ON core.webhook_deliveries (report_id, delivered_at DESC);
+
+COMMIT;
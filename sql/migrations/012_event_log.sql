@@ -0,0 +1,125 @@
+-- =====================================================
+-- Append-only domain event log and rebuildable projections
+-- =====================================================
+-- Triggers on types, operators, generators and productions append
+-- every create, update and delete to domain_events with the row
+-- before and after the change, in the writing transaction. Events
+-- can only be appended. Existing rows are logged once as created
+-- so the log can rebuild everything from the start.
+-- Projections are summary tables derived from the log. The API
+-- keeps them current from the events of finished transactions
+-- (projection_checkpoints.last_txid is the snapshot xmin they are
+-- complete up to); `tadb rebuild-projections` recomputes them from
+-- the log alone after an aggregation bug.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.domain_events (
+    seq BIGSERIAL PRIMARY KEY,
+    aggregate VARCHAR(20) NOT NULL,
+    aggregate_id UUID NOT NULL,
+    event VARCHAR(40) NOT NULL,
+    old_state JSONB,
+    new_state JSONB,
+    txid XID8 NOT NULL DEFAULT pg_current_xact_id(),
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_domain_events_aggregate ON core.domain_events (aggregate, aggregate_id, seq);
+CREATE INDEX IF NOT EXISTS idx_domain_events_txid ON core.domain_events (txid);
+
+-- Logs the change of a row as <aggregate>.created/updated/deleted; the aggregate name is the trigger argument
+CREATE OR REPLACE FUNCTION core.record_domain_event()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    INSERT INTO core.domain_events (aggregate, aggregate_id, event, old_state, new_state)
+    VALUES (
+        TG_ARGV[0],
+        CASE WHEN TG_OP = 'DELETE' THEN OLD.id ELSE NEW.id END,
+        TG_ARGV[0] || '.' || CASE TG_OP WHEN 'INSERT' THEN 'created' WHEN 'UPDATE' THEN 'updated' ELSE 'deleted' END,
+        CASE WHEN TG_OP <> 'INSERT' THEN to_jsonb(OLD) END,
+        CASE WHEN TG_OP <> 'DELETE' THEN to_jsonb(NEW) END
+    );
+    RETURN NULL;
+END;
+$$;
+
+-- The log is append-only
+CREATE OR REPLACE FUNCTION core.protect_domain_events()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    RAISE EXCEPTION 'domain events are append-only' USING ERRCODE = 'TP002';
+END;
+$$;
+
+DROP TRIGGER IF EXISTS trg_protect_domain_events ON core.domain_events;
+CREATE TRIGGER trg_protect_domain_events
+    BEFORE UPDATE OR DELETE ON core.domain_events
+    FOR EACH ROW EXECUTE FUNCTION core.protect_domain_events();
+
+DROP TRIGGER IF EXISTS trg_protect_domain_events_truncate ON core.domain_events;
+CREATE TRIGGER trg_protect_domain_events_truncate
+    BEFORE TRUNCATE ON core.domain_events
+    FOR EACH STATEMENT EXECUTE FUNCTION core.protect_domain_events();
+
+-- Existing data, logged once in dependency order
+INSERT INTO core.domain_events (aggregate, aggregate_id, event, new_state, occurred_at)
+SELECT aggregate, id, aggregate || '.created', state, created_at
+FROM (
+    SELECT 1 AS step, 'type' AS aggregate, t.id, to_jsonb(t) AS state, t.created_at FROM core.types t
+    UNION ALL
+    SELECT 2, 'operator', o.id, to_jsonb(o), o.created_at FROM core.operators o
+    UNION ALL
+    SELECT 3, 'generator', g.id, to_jsonb(g), g.created_at FROM core.generators g
+    UNION ALL
+    SELECT 4, 'production', p.id, to_jsonb(p), p.created_at FROM core.productions p
+) existing
+WHERE NOT EXISTS (SELECT 1 FROM core.domain_events)
+ORDER BY step, created_at, id;
+
+DROP TRIGGER IF EXISTS trg_domain_events_types ON core.types;
+CREATE TRIGGER trg_domain_events_types
+    AFTER INSERT OR UPDATE OR DELETE ON core.types
+    FOR EACH ROW EXECUTE FUNCTION core.record_domain_event('type');
+
+DROP TRIGGER IF EXISTS trg_domain_events_operators ON core.operators;
+CREATE TRIGGER trg_domain_events_operators
+    AFTER INSERT OR UPDATE OR DELETE ON core.operators
+    FOR EACH ROW EXECUTE FUNCTION core.record_domain_event('operator');
+
+DROP TRIGGER IF EXISTS trg_domain_events_generators ON core.generators;
+CREATE TRIGGER trg_domain_events_generators
+    AFTER INSERT OR DELETE OR UPDATE OF type, capacity, operator_id ON core.generators
+    FOR EACH ROW EXECUTE FUNCTION core.record_domain_event('generator');
+
+-- Re-signing a record only updates its signature and is not an event
+DROP TRIGGER IF EXISTS trg_domain_events_productions ON core.productions;
+CREATE TRIGGER trg_domain_events_productions
+    AFTER INSERT OR DELETE OR UPDATE OF generator_id, date, production_mw, source, source_ref ON core.productions
+    FOR EACH ROW EXECUTE FUNCTION core.record_domain_event('production');
+
+-- Projection: production per day and generator type
+CREATE TABLE IF NOT EXISTS core.daily_production_summary (
+    date DATE NOT NULL,
+    type_id UUID NOT NULL,
+    records BIGINT NOT NULL,
+    production_mw NUMERIC(18,4) NOT NULL,
+    PRIMARY KEY (date, type_id)
+);
+
+CREATE TABLE IF NOT EXISTS core.projection_checkpoints (
+    name VARCHAR(40) PRIMARY KEY,
+    last_txid XID8 NOT NULL DEFAULT '0',
+    last_seq BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ,
+    rebuilt_at TIMESTAMPTZ
+);
+
+INSERT INTO core.projection_checkpoints (name) VALUES ('daily_production_summary')
+ON CONFLICT (name) DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,25 @@
+-- =====================================================
+-- Trash for deleted resources
+-- =====================================================
+-- Deleting a type, operator, generator, production, report or
+-- report template moves it to the trash: the row and every row its
+-- deletion cascades to are kept in payload as JSON, keyed by table,
+-- before they are deleted. Restoring re-inserts them. Items are
+-- purged for good once they are older than the retention period.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.trash (
+    id UUID PRIMARY KEY,
+    resource VARCHAR(30) NOT NULL,
+    resource_id UUID NOT NULL,
+    label VARCHAR(200) NOT NULL,
+    row_count INTEGER NOT NULL,
+    payload JSONB NOT NULL,
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_trash_deleted_at ON core.trash(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_trash_resource ON core.trash(resource, resource_id);
+
+COMMIT;
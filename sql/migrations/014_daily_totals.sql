@@ -0,0 +1,113 @@
+-- =====================================================
+-- Running daily production totals per generator type
+-- =====================================================
+-- daily_totals holds the record count and production of every day
+-- and generator type. Triggers keep it current in the transaction
+-- of each write with ON CONFLICT DO UPDATE increments, which lock
+-- only the row of that day and type, so concurrent writers queue
+-- on it instead of losing updates. /analytics/mix reads a day from
+-- here instead of aggregating its productions.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.daily_totals (
+    date DATE NOT NULL,
+    type_id UUID NOT NULL,
+    records BIGINT NOT NULL,
+    production_mw NUMERIC(18,4) NOT NULL,
+    PRIMARY KEY (date, type_id)
+);
+
+-- Adds records and production to the totals of a day and type
+CREATE OR REPLACE FUNCTION core.add_daily_total(d DATE, t UUID, n BIGINT, mw NUMERIC)
+RETURNS void
+LANGUAGE sql
+AS $$
+    INSERT INTO core.daily_totals (date, type_id, records, production_mw)
+    VALUES (d, t, n, mw)
+    ON CONFLICT (date, type_id) DO UPDATE
+    SET records = core.daily_totals.records + EXCLUDED.records,
+        production_mw = core.daily_totals.production_mw + EXCLUDED.production_mw;
+$$;
+
+-- Applies a production write to the totals. Productions deleted along with
+-- their generator find no generator: the generator trigger has taken them out.
+CREATE OR REPLACE FUNCTION core.count_production()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    t UUID;
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        SELECT type INTO t FROM core.generators WHERE id = OLD.generator_id;
+        IF FOUND THEN
+            PERFORM core.add_daily_total(OLD.date, t, -1, -OLD.production_mw);
+        END IF;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        SELECT type INTO t FROM core.generators WHERE id = NEW.generator_id;
+        PERFORM core.add_daily_total(NEW.date, t, 1, NEW.production_mw);
+    END IF;
+    RETURN NULL;
+END;
+$$;
+
+-- Moves the productions of a generator to its new type, or takes them out
+-- before the generator and its productions are deleted
+CREATE OR REPLACE FUNCTION core.count_generator_productions()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    INSERT INTO core.daily_totals (date, type_id, records, production_mw)
+    SELECT p.date, OLD.type, -COUNT(*), -SUM(p.production_mw)
+    FROM core.productions p
+    WHERE p.generator_id = OLD.id
+    GROUP BY p.date
+    ON CONFLICT (date, type_id) DO UPDATE
+    SET records = core.daily_totals.records + EXCLUDED.records,
+        production_mw = core.daily_totals.production_mw + EXCLUDED.production_mw;
+
+    IF TG_OP = 'UPDATE' THEN
+        INSERT INTO core.daily_totals (date, type_id, records, production_mw)
+        SELECT p.date, NEW.type, COUNT(*), SUM(p.production_mw)
+        FROM core.productions p
+        WHERE p.generator_id = NEW.id
+        GROUP BY p.date
+        ON CONFLICT (date, type_id) DO UPDATE
+        SET records = core.daily_totals.records + EXCLUDED.records,
+            production_mw = core.daily_totals.production_mw + EXCLUDED.production_mw;
+        RETURN NEW;
+    END IF;
+    RETURN OLD;
+END;
+$$;
+
+-- No writes while the totals are filled from the existing data
+LOCK TABLE core.productions, core.generators IN SHARE MODE;
+
+DELETE FROM core.daily_totals;
+INSERT INTO core.daily_totals (date, type_id, records, production_mw)
+SELECT p.date, g.type, COUNT(*), SUM(p.production_mw)
+FROM core.productions p
+JOIN core.generators g ON p.generator_id = g.id
+GROUP BY p.date, g.type;
+
+DROP TRIGGER IF EXISTS trg_count_production ON core.productions;
+CREATE TRIGGER trg_count_production
+    AFTER INSERT OR DELETE OR UPDATE OF generator_id, date, production_mw ON core.productions
+    FOR EACH ROW EXECUTE FUNCTION core.count_production();
+
+DROP TRIGGER IF EXISTS trg_count_generator_type ON core.generators;
+CREATE TRIGGER trg_count_generator_type
+    AFTER UPDATE OF type ON core.generators
+    FOR EACH ROW WHEN (OLD.type IS DISTINCT FROM NEW.type)
+    EXECUTE FUNCTION core.count_generator_productions();
+
+DROP TRIGGER IF EXISTS trg_count_generator_delete ON core.generators;
+CREATE TRIGGER trg_count_generator_delete
+    BEFORE DELETE ON core.generators
+    FOR EACH ROW EXECUTE FUNCTION core.count_generator_productions();
+
+COMMIT;
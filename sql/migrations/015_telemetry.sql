@@ -0,0 +1,15 @@
+-- =====================================================
+-- Production records pushed by plant meters
+-- =====================================================
+-- Telemetry messages received from the MQTT bridge or POSTed to
+-- /telemetry are mapped to the production of a generator on a day
+-- and stored with source 'telemetry' and the topic as source_ref.
+
+BEGIN;
+
+ALTER TABLE core.productions DROP CONSTRAINT IF EXISTS chk_productions_source;
+ALTER TABLE core.productions
+    ADD CONSTRAINT chk_productions_source
+    CHECK (source IN ('manual', 'api', 'import', 'external', 'telemetry'));
+
+COMMIT;
@@ -0,0 +1,28 @@
+-- =====================================================
+-- Files pulled from partner feeds
+-- =====================================================
+-- The SFTP connector records every remote file it downloads with
+-- its SHA-256, so files are not fetched again while unchanged and
+-- files re-published under another name or date are not imported
+-- twice. Staged files were passed on to the drop folder; duplicates
+-- matched the checksum of a file staged before.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.connector_files (
+    id UUID PRIMARY KEY,
+    source VARCHAR(80) NOT NULL,
+    remote_path TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    modified_at TIMESTAMPTZ NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('staged', 'duplicate')),
+    staged_as TEXT,
+    fetched_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_connector_files_path ON core.connector_files (source, remote_path, size, modified_at);
+CREATE INDEX IF NOT EXISTS idx_connector_files_sha256 ON core.connector_files (source, sha256);
+CREATE INDEX IF NOT EXISTS idx_connector_files_fetched ON core.connector_files (fetched_at DESC);
+
+COMMIT;
@@ -0,0 +1,18 @@
+-- =====================================================
+-- Connector of partner feed files
+-- =====================================================
+-- Attachments of emailed spreadsheets are tracked in
+-- connector_files next to the SFTP downloads. The connector
+-- column tells them apart; for attachments remote_path is the
+-- message ID and the file name, and modified_at the time the
+-- message was received.
+
+BEGIN;
+
+ALTER TABLE core.connector_files
+    ADD COLUMN IF NOT EXISTS connector VARCHAR(20) NOT NULL DEFAULT 'sftp'
+        CHECK (connector IN ('sftp', 'email'));
+
+CREATE INDEX IF NOT EXISTS idx_connector_files_connector ON core.connector_files (connector, fetched_at DESC);
+
+COMMIT;
@@ -0,0 +1,23 @@
+-- =====================================================
+-- Generator location
+-- =====================================================
+-- WGS 84 coordinates of each generator, used by the near and
+-- bbox filters of GET /api/v1/generators and by
+-- GET /api/v1/generators.geojson. Distances are computed with
+-- the haversine formula, so PostGIS is not required; the index
+-- serves the bounding box filters.
+
+BEGIN;
+
+ALTER TABLE core.generators
+    ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180);
+
+ALTER TABLE core.generators DROP CONSTRAINT IF EXISTS chk_generators_location;
+ALTER TABLE core.generators
+    ADD CONSTRAINT chk_generators_location CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+CREATE INDEX IF NOT EXISTS idx_generators_location ON core.generators (latitude, longitude)
+    WHERE latitude IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,23 @@
+-- =====================================================
+-- Type translations
+-- =====================================================
+-- Name and description of a type in Spanish or English. The
+-- type endpoints answer in the language of the Accept-Language
+-- header, falling back to the texts of core.types when a type
+-- has no translation to it.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.type_translations (
+    type_id UUID NOT NULL REFERENCES core.types(id) ON DELETE CASCADE,
+    language VARCHAR(2) NOT NULL CHECK (language IN ('es', 'en')),
+    name VARCHAR(20) NOT NULL,
+    description VARCHAR(80) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (type_id, language)
+);
+
+CREATE INDEX IF NOT EXISTS idx_type_translations_language ON core.type_translations (language);
+
+COMMIT;
@@ -0,0 +1,57 @@
+-- =====================================================
+-- Attachments of types and generators
+-- =====================================================
+-- Documents (permits, datasheets, bulletin PDFs) attached to a
+-- type or a generator. The content is kept in object storage
+-- when it is configured (storage_key) and in the row otherwise
+-- (content). Attachments are deleted with their type or
+-- generator; the trigger queues the objects they leave behind in
+-- attachment_object_deletions, which the API removes from the
+-- bucket in the background.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.attachments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    type_id UUID REFERENCES core.types(id) ON DELETE CASCADE,
+    generator_id UUID REFERENCES core.generators(id) ON DELETE CASCADE,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes >= 0),
+    sha256 CHAR(64) NOT NULL,
+    storage_key TEXT,
+    content BYTEA,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((type_id IS NULL) <> (generator_id IS NULL)),
+    CHECK ((storage_key IS NULL) <> (content IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_type ON core.attachments (type_id) WHERE type_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_attachments_generator ON core.attachments (generator_id) WHERE generator_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS core.attachment_object_deletions (
+    storage_key TEXT PRIMARY KEY,
+    queued_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Queues the object of a deleted attachment for removal from the bucket
+CREATE OR REPLACE FUNCTION core.queue_attachment_object()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    IF OLD.storage_key IS NOT NULL THEN
+        INSERT INTO core.attachment_object_deletions (storage_key)
+        VALUES (OLD.storage_key)
+        ON CONFLICT (storage_key) DO NOTHING;
+    END IF;
+    RETURN NULL;
+END;
+$$;
+
+DROP TRIGGER IF EXISTS trg_attachments_queue_object ON core.attachments;
+CREATE TRIGGER trg_attachments_queue_object
+    AFTER DELETE ON core.attachments
+    FOR EACH ROW EXECUTE FUNCTION core.queue_attachment_object();
+
+COMMIT;
@@ -0,0 +1,30 @@
+-- =====================================================
+-- Annotations on production data
+-- =====================================================
+-- Notes of analysts on one production record (production_id) or
+-- on an inclusive date range, of one generator (generator_id) or
+-- of the whole fleet (neither). They are shown with the records
+-- they apply to, in report exports and next to the corrections of
+-- a record, and go to the trash with their record or generator.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.annotations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    production_id UUID REFERENCES core.productions(id) ON DELETE CASCADE,
+    generator_id UUID REFERENCES core.generators(id) ON DELETE CASCADE,
+    start_date DATE,
+    end_date DATE,
+    note VARCHAR(1000) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (
+        (production_id IS NOT NULL AND generator_id IS NULL AND start_date IS NULL AND end_date IS NULL)
+        OR (production_id IS NULL AND start_date IS NOT NULL AND end_date >= start_date)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_annotations_production ON core.annotations (production_id) WHERE production_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_annotations_range ON core.annotations (start_date, end_date) WHERE production_id IS NULL;
+
+COMMIT;
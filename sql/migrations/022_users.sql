@@ -0,0 +1,23 @@
+-- =====================================================
+-- User accounts
+-- =====================================================
+-- Users sign in with their username and password and get a
+-- signed access token for the API. Passwords are stored as
+-- bcrypt hashes. Usernames and emails are unique regardless
+-- of case. Operator grants (005) refer to these users.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.users (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    username VARCHAR(50) NOT NULL,
+    email VARCHAR(254) NOT NULL,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON core.users (lower(username));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON core.users (lower(email));
+
+COMMIT;
@@ -0,0 +1,28 @@
+-- =====================================================
+-- User roles
+-- =====================================================
+-- admin and operator users write types, generators and
+-- productions; viewers only read. Admins manage accounts.
+-- Accounts created before roles existed keep write access:
+-- the oldest becomes admin and the rest operators. The
+-- column is added without a default first, so only rows
+-- that predate it are NULL: running the migration again
+-- leaves the roles alone.
+
+BEGIN;
+
+ALTER TABLE core.users ADD COLUMN IF NOT EXISTS roles TEXT[];
+
+UPDATE core.users SET roles = '{admin}'
+WHERE roles IS NULL
+  AND id = (SELECT id FROM core.users ORDER BY created_at, id LIMIT 1);
+UPDATE core.users SET roles = '{operator}' WHERE roles IS NULL;
+
+ALTER TABLE core.users ALTER COLUMN roles SET DEFAULT '{viewer}';
+ALTER TABLE core.users ALTER COLUMN roles SET NOT NULL;
+
+ALTER TABLE core.users DROP CONSTRAINT IF EXISTS users_roles_check;
+ALTER TABLE core.users ADD CONSTRAINT users_roles_check
+    CHECK (cardinality(roles) > 0 AND roles <@ ARRAY['admin', 'operator', 'viewer']);
+
+COMMIT;
@@ -0,0 +1,37 @@
+-- =====================================================
+-- Custom fields
+-- =====================================================
+-- Deployments extend types, operators, generators and
+-- productions with their own attributes without schema
+-- changes. custom_fields registers each field (name, value
+-- type and validation) per entity; the values are kept in the
+-- custom_attributes object of the rows, keyed by field name.
+-- GIN indexes serve the equality filters of the listings.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.custom_fields (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    entity VARCHAR(20) NOT NULL CHECK (entity IN ('type', 'operator', 'generator', 'production')),
+    name VARCHAR(50) NOT NULL CHECK (name ~ '^[A-Za-z][A-Za-z0-9_]*$'),
+    type VARCHAR(10) NOT NULL CHECK (type IN ('string', 'number', 'boolean', 'date')),
+    required BOOLEAN NOT NULL DEFAULT false,
+    description VARCHAR(200),
+    pattern VARCHAR(200),
+    min_value DOUBLE PRECISION,
+    max_value DOUBLE PRECISION,
+    options TEXT[],
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (entity, name)
+);
+
+ALTER TABLE core.types ADD COLUMN IF NOT EXISTS custom_attributes JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE core.operators ADD COLUMN IF NOT EXISTS custom_attributes JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE core.generators ADD COLUMN IF NOT EXISTS custom_attributes JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE core.productions ADD COLUMN IF NOT EXISTS custom_attributes JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_generators_custom_attributes ON core.generators USING GIN (custom_attributes jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS idx_productions_custom_attributes ON core.productions USING GIN (custom_attributes jsonb_path_ops);
+
+COMMIT;
@@ -0,0 +1,32 @@
+-- =====================================================
+-- Recalculations
+-- =====================================================
+-- Admins rescale a filtered set of production records in
+-- one go (new = old * factor + offset), e.g. to fix values
+-- loaded in kW instead of MW. Each run is kept here with
+-- its filter, formula, author and totals; every record it
+-- changed also gets a production_corrections entry.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.recalculations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    generator_id UUID,
+    type_id UUID,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    source VARCHAR(20),
+    factor NUMERIC(18,8) NOT NULL,
+    offset_mw NUMERIC(14,4) NOT NULL,
+    reason VARCHAR(400) NOT NULL,
+    user_id UUID REFERENCES core.users(id) ON DELETE SET NULL,
+    records INT NOT NULL,
+    published_records INT NOT NULL,
+    previous_total_mw NUMERIC(18,4) NOT NULL,
+    new_total_mw NUMERIC(18,4) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_recalculations_created_at ON core.recalculations (created_at DESC);
+
+COMMIT;
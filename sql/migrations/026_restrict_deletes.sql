@@ -0,0 +1,32 @@
+-- =====================================================
+-- Restrict deletes of types and generators in use
+-- =====================================================
+-- Deleting a type used to delete its generators and their
+-- productions silently, and a generator its productions.
+-- The foreign keys now reject those deletes (23503), which
+-- the API answers with 409; DELETE ?cascade=true removes
+-- the dependent rows first, explicitly.
+
+BEGIN;
+
+DO $$
+DECLARE
+    c record;
+BEGIN
+    FOR c IN
+        SELECT conrelid::regclass AS tbl, conname
+        FROM pg_constraint
+        WHERE contype = 'f'
+          AND ((conrelid = 'core.generators'::regclass AND confrelid = 'core.types'::regclass)
+            OR (conrelid = 'core.productions'::regclass AND confrelid = 'core.generators'::regclass))
+    LOOP
+        EXECUTE format('ALTER TABLE %s DROP CONSTRAINT %I', c.tbl, c.conname);
+    END LOOP;
+END $$;
+
+ALTER TABLE core.generators
+    ADD CONSTRAINT fk_type FOREIGN KEY (type) REFERENCES core.types(id);
+ALTER TABLE core.productions
+    ADD CONSTRAINT fk_generator FOREIGN KEY (generator_id) REFERENCES core.generators(id);
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- =====================================================
+-- Import mapping profiles
+-- =====================================================
+-- Providers send their files with their own headers and
+-- delimiter. A profile saves the header of each column
+-- and the delimiter once per source, and imports name the
+-- profile instead of rewriting the file every month.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.import_profiles (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(80) NOT NULL UNIQUE,
+    source VARCHAR(80) NOT NULL,
+    description VARCHAR(500),
+    columns JSONB NOT NULL DEFAULT '{}',
+    delimiter VARCHAR(1),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_profiles_source ON core.import_profiles (source);
+
+COMMIT;
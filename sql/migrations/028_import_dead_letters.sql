@@ -0,0 +1,30 @@
+-- =====================================================
+-- Dead letters of imports
+-- =====================================================
+-- Rows rejected by an import used to be reported only in
+-- the job summary, which keeps the first errors. Every
+-- rejected row is now kept with the values read from the
+-- file so it can be corrected and resubmitted.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.import_dead_letters (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    job_id UUID NOT NULL,
+    line BIGINT NOT NULL,
+    generator_id VARCHAR(200) NOT NULL DEFAULT '',
+    date VARCHAR(200) NOT NULL DEFAULT '',
+    production_mw VARCHAR(200) NOT NULL DEFAULT '',
+    error TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'resubmitted')),
+    attempts INT NOT NULL DEFAULT 0,
+    production_id UUID REFERENCES core.productions(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_dead_letters_job ON core.import_dead_letters (job_id, line);
+CREATE INDEX IF NOT EXISTS idx_import_dead_letters_status ON core.import_dead_letters (status, created_at);
+
+COMMIT;
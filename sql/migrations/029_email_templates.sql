@@ -0,0 +1,24 @@
+-- =====================================================
+-- Email templates
+-- =====================================================
+-- Subject and body of the notification emails (freshness
+-- and SLO alerts), as Go templates. Every edit adds a
+-- version with its author and the latest one is used, so
+-- the wording changes without a deploy and earlier
+-- versions can be restored. Keys without versions use
+-- the built-in templates.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.email_templates (
+    key VARCHAR(60) NOT NULL,
+    version INT NOT NULL CHECK (version > 0),
+    subject VARCHAR(300) NOT NULL,
+    body TEXT NOT NULL,
+    comment VARCHAR(200),
+    user_id UUID REFERENCES core.users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (key, version)
+);
+
+COMMIT;
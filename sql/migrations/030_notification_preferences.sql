@@ -0,0 +1,33 @@
+-- =====================================================
+-- Notification preferences and digests
+-- =====================================================
+-- How each user is told about every alert type: at once,
+-- in a daily digest or not at all, and on which channels.
+-- Alerts for digest users wait in the digest items until
+-- the daily digest email takes them.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.notification_preferences (
+    user_id UUID NOT NULL REFERENCES core.users(id) ON DELETE CASCADE,
+    alert_type VARCHAR(40) NOT NULL,
+    delivery VARCHAR(20) NOT NULL
+        CHECK (delivery IN ('immediate', 'digest', 'mute')),
+    channels TEXT[] NOT NULL DEFAULT '{email}',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, alert_type)
+);
+
+CREATE TABLE IF NOT EXISTS core.notification_digest_items (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES core.users(id) ON DELETE CASCADE,
+    alert_type VARCHAR(40) NOT NULL,
+    subject VARCHAR(300) NOT NULL,
+    body TEXT NOT NULL,
+    raised_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_digest_items_user
+    ON core.notification_digest_items (user_id, raised_at);
+
+COMMIT;
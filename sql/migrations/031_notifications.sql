@@ -0,0 +1,27 @@
+-- =====================================================
+-- Notification inbox
+-- =====================================================
+-- Alerts kept for users that chose the inbox channel, so
+-- the admin UI can show them without email. Notifications
+-- are purged once past the retention period, read or not.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.notifications (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES core.users(id) ON DELETE CASCADE,
+    alert_type VARCHAR(40) NOT NULL,
+    subject VARCHAR(300) NOT NULL,
+    body TEXT NOT NULL,
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user
+    ON core.notifications (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread
+    ON core.notifications (user_id, alert_type) WHERE read_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_notifications_created
+    ON core.notifications (created_at);
+
+COMMIT;
@@ -0,0 +1,36 @@
+-- =====================================================
+-- Impersonation sessions
+-- =====================================================
+-- Admins act as a user, or as a user of an operator, to
+-- debug permission and visibility issues. Sessions record
+-- who impersonated whom and why, and every request made
+-- with the session token is kept as an event.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.impersonations (
+    id UUID PRIMARY KEY,
+    actor_id UUID NOT NULL REFERENCES core.users(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES core.users(id) ON DELETE CASCADE,
+    operator_id UUID REFERENCES core.operators(id) ON DELETE CASCADE,
+    reason VARCHAR(500) NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    ended_at TIMESTAMPTZ,
+    CHECK ((user_id IS NULL) <> (operator_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonations_started ON core.impersonations (started_at DESC);
+
+CREATE TABLE IF NOT EXISTS core.impersonation_events (
+    id BIGSERIAL PRIMARY KEY,
+    session_id UUID NOT NULL REFERENCES core.impersonations(id) ON DELETE CASCADE,
+    method VARCHAR(10) NOT NULL,
+    path TEXT NOT NULL,
+    status INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_impersonation_events_session ON core.impersonation_events (session_id, id);
+
+COMMIT;
@@ -0,0 +1,28 @@
+-- =====================================================
+-- Audit log
+-- =====================================================
+-- Every create, update and delete of the audited entities
+-- (types, operators, generators, productions, annotations,
+-- custom fields, import profiles, reports, report
+-- templates and user roles and grants) with the entity
+-- before and after the change and who made it.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    entity_type VARCHAR(40) NOT NULL,
+    entity_id UUID NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    before JSONB,
+    after JSONB,
+    user_id UUID,
+    impersonator_id UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON core.audit_log (entity_type, entity_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON core.audit_log (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_user ON core.audit_log (user_id, created_at);
+
+COMMIT;
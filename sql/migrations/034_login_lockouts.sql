@@ -0,0 +1,23 @@
+-- =====================================================
+-- Login lockouts
+-- =====================================================
+-- Failed logins per username (lower case, whether an
+-- account exists or not). Failures in a row within the
+-- failure window lock the username for a while; every
+-- lockout before the next successful login doubles the
+-- next one. Admins can unlock usernames.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.login_lockouts (
+    username VARCHAR(50) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    lockouts INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ,
+    last_ip VARCHAR(45) NOT NULL DEFAULT '',
+    locked_until TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_lockouts_locked ON core.login_lockouts (locked_until) WHERE locked_until IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,32 @@
+-- =====================================================
+-- Plants (facilities)
+-- =====================================================
+-- Groups generators at one site, e.g. the units of a
+-- hydro dam, under a plant with its location and the
+-- operator owning it. Generators belong to at most one
+-- plant; deleting a plant keeps its generators without
+-- plant. Used by /api/v1/plants and the plantId filter
+-- of the generator and production listings.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.plants (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(120) UNIQUE NOT NULL,
+    location VARCHAR(200) NOT NULL DEFAULT '',
+    latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    owner_id UUID REFERENCES core.operators(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT chk_plants_location CHECK ((latitude IS NULL) = (longitude IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_plants_owner_id ON core.plants(owner_id);
+
+ALTER TABLE core.generators
+    ADD COLUMN IF NOT EXISTS plant_id UUID REFERENCES core.plants(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_generators_plant_id ON core.generators(plant_id);
+
+COMMIT;
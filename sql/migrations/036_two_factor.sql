@@ -0,0 +1,27 @@
+-- =====================================================
+-- Two-factor authentication
+-- =====================================================
+-- TOTP enrollments of users. An enrollment is pending
+-- (enabled_at NULL) until a first code confirms it;
+-- last_step is the time step of the last code accepted,
+-- so codes are not accepted twice. Backup codes are
+-- stored as SHA-256 hashes and used once each.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.user_two_factor (
+    user_id UUID PRIMARY KEY REFERENCES core.users(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    enabled_at TIMESTAMPTZ,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS core.user_backup_codes (
+    user_id UUID NOT NULL REFERENCES core.user_two_factor(user_id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+COMMIT;
@@ -0,0 +1,35 @@
+-- =====================================================
+-- Regions
+-- =====================================================
+-- Geographic regions, e.g. departments, with a short
+-- code and an optional bounding box. Generators belong
+-- to at most one region; deleting a region keeps its
+-- generators without region. Used by /api/v1/regions,
+-- the regionId filter of the generator listing and
+-- /api/v1/analytics/mix-by-region.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.regions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    code VARCHAR(10) UNIQUE NOT NULL,
+    name VARCHAR(120) UNIQUE NOT NULL,
+    min_lng DOUBLE PRECISION,
+    min_lat DOUBLE PRECISION,
+    max_lng DOUBLE PRECISION,
+    max_lat DOUBLE PRECISION,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT chk_regions_bbox CHECK (
+        (min_lng IS NULL AND min_lat IS NULL AND max_lng IS NULL AND max_lat IS NULL)
+        OR (min_lng BETWEEN -180 AND max_lng AND max_lng <= 180
+            AND min_lat BETWEEN -90 AND max_lat AND max_lat <= 90)
+    )
+);
+
+ALTER TABLE core.generators
+    ADD COLUMN IF NOT EXISTS region_id UUID REFERENCES core.regions(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_generators_region_id ON core.generators(region_id);
+
+COMMIT;
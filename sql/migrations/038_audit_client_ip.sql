@@ -0,0 +1,14 @@
+-- =====================================================
+-- Client IP of audit entries
+-- =====================================================
+-- The address each change was requested from, as resolved
+-- through TRUSTED_PROXIES: the peer itself unless it is a
+-- trusted proxy, then the last untrusted address of
+-- X-Forwarded-For. NULL for entries written before this
+-- migration and for background writes.
+
+BEGIN;
+
+ALTER TABLE core.audit_log ADD COLUMN IF NOT EXISTS client_ip VARCHAR(45);
+
+COMMIT;
@@ -0,0 +1,14 @@
+-- =====================================================
+-- Data quality
+-- =====================================================
+-- Commissioning date of generators: the day they entered
+-- service. Optional; the data quality scan of
+-- /api/v1/admin/data-quality flags productions dated
+-- before it.
+
+BEGIN;
+
+ALTER TABLE core.generators
+    ADD COLUMN IF NOT EXISTS commissioned_on DATE;
+
+COMMIT;
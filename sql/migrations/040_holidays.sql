@@ -0,0 +1,24 @@
+-- =====================================================
+-- Holidays
+-- =====================================================
+-- Holidays managed through /api/v1/admin/holidays, on
+-- top of the calendars of HOLIDAY_CALENDARS_FILE. A
+-- holiday belongs to a country (ISO 3166-1 alpha-2)
+-- and, when region is set, only to that region of it
+-- (calendar CO-ANT). date is YYYY-MM-DD, or MM-DD for
+-- a holiday on the same date every year.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS core.holidays (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    country CHAR(2) NOT NULL,
+    region VARCHAR(10) NOT NULL DEFAULT '',
+    date VARCHAR(10) NOT NULL,
+    name VARCHAR(120) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT uq_holidays_day UNIQUE (country, region, date),
+    CONSTRAINT chk_holidays_date CHECK (date ~ '^([0-9]{4}-)?[0-9]{2}-[0-9]{2}$')
+);
+
+COMMIT;
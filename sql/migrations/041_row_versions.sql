@@ -0,0 +1,43 @@
+-- =====================================================
+-- Row versions for optimistic concurrency
+-- =====================================================
+-- Types, generators and production records get a version
+-- that triggers raise on every update. GETs send it as the
+-- ETag, and PUT and PATCH with If-Match: "<version>" only
+-- apply while the row still has it (412 otherwise), so two
+-- clients editing the same record no longer overwrite each
+-- other silently. Re-signing a production record (only the
+-- signature changes) keeps its version.
+
+BEGIN;
+
+ALTER TABLE core.types ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE core.generators ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE core.productions ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+CREATE OR REPLACE FUNCTION core.bump_row_version()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    NEW.version := OLD.version + 1;
+    RETURN NEW;
+END;
+$$;
+
+DROP TRIGGER IF EXISTS trg_types_version ON core.types;
+CREATE TRIGGER trg_types_version
+    BEFORE UPDATE ON core.types
+    FOR EACH ROW EXECUTE FUNCTION core.bump_row_version();
+
+DROP TRIGGER IF EXISTS trg_generators_version ON core.generators;
+CREATE TRIGGER trg_generators_version
+    BEFORE UPDATE ON core.generators
+    FOR EACH ROW EXECUTE FUNCTION core.bump_row_version();
+
+DROP TRIGGER IF EXISTS trg_productions_version ON core.productions;
+CREATE TRIGGER trg_productions_version
+    BEFORE UPDATE OF generator_id, date, production_mw, source, source_ref, custom_attributes ON core.productions
+    FOR EACH ROW EXECUTE FUNCTION core.bump_row_version();
+
+COMMIT;
@@ -0,0 +1,15 @@
+-- =====================================================
+-- Audit log without user PII
+-- =====================================================
+-- Users are audited with their roles only; remove the
+-- email and username of the user entries written before.
+
+BEGIN;
+
+UPDATE core.audit_log
+SET before = before - 'email' - 'username',
+    after = after - 'email' - 'username'
+WHERE entity_type = 'user'
+  AND (before ?| ARRAY['email', 'username'] OR after ?| ARRAY['email', 'username']);
+
+COMMIT;
@@ -110,7 +110,7 @@ $$;
 -- Insert generator procedure
 CREATE OR REPLACE PROCEDURE core.insert_generator(
     p_generator_type uuid,
-    p_generator_capacity float
+    p_generator_capacity numeric
 )
 LANGUAGE plpgsql
 as $$
@@ -145,7 +145,7 @@ $$;
 CREATE OR REPLACE PROCEDURE core.update_generator(
     p_generator_id uuid,
     p_generator_type uuid,
-    p_generator_capacity float
+    p_generator_capacity numeric
 )
 LANGUAGE plpgsql
 as $$
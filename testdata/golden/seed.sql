@@ -0,0 +1,35 @@
+-- Fixed dataset for the golden analytics suite (go run ./cmd/golden).
+-- Values are chosen to exercise NUMERIC sums and half-even rounding to 3 decimals.
+
+INSERT INTO types (id, name, description, isrenuevable, created_at, updated_at) VALUES
+    ('11111111-1111-1111-1111-111111111111', 'Solar',   'Paneles solares fotovoltaicos', true,  '2025-01-01T00:00:00Z', '2025-01-01T00:00:00Z'),
+    ('22222222-2222-2222-2222-222222222222', 'Eólica',  'Aerogeneradores',               true,  '2025-01-01T00:00:00Z', '2025-01-01T00:00:00Z'),
+    ('33333333-3333-3333-3333-333333333333', 'Térmica', 'Plantas a carbón y gas',        false, '2025-01-01T00:00:00Z', '2025-01-01T00:00:00Z');
+
+INSERT INTO operators (id, name, country, created_at, updated_at) VALUES
+    ('cccccccc-0000-0000-0000-000000000001', 'Celsia', 'CO', '2025-01-01T00:00:00Z', '2025-01-01T00:00:00Z'),
+    ('cccccccc-0000-0000-0000-000000000002', 'EPM',    'CO', '2025-01-01T00:00:00Z', '2025-01-01T00:00:00Z');
+
+-- Generator 2 has no operator and is reported as "Unassigned"
+INSERT INTO generators (id, type, capacity, operator_id, created_at, updated_at) VALUES
+    ('aaaaaaaa-0000-0000-0000-000000000001', '11111111-1111-1111-1111-111111111111', 100,   'cccccccc-0000-0000-0000-000000000001', '2025-01-01T00:00:00Z', '2025-01-01T00:00:00Z'),
+    ('aaaaaaaa-0000-0000-0000-000000000002', '11111111-1111-1111-1111-111111111111', 50.5,  NULL,                                   '2025-01-01T00:00:00Z', '2025-01-01T00:00:00Z'),
+    ('aaaaaaaa-0000-0000-0000-000000000003', '22222222-2222-2222-2222-222222222222', 80,    'cccccccc-0000-0000-0000-000000000001', '2025-01-01T00:00:00Z', '2025-01-01T00:00:00Z'),
+    ('aaaaaaaa-0000-0000-0000-000000000004', '33333333-3333-3333-3333-333333333333', 200,   'cccccccc-0000-0000-0000-000000000002', '2025-01-01T00:00:00Z', '2025-01-01T00:00:00Z');
+
+INSERT INTO productions (id, generator_id, date, production_mw, created_at, updated_at) VALUES
+    ('bbbbbbbb-0000-0000-0000-000000000001', 'aaaaaaaa-0000-0000-0000-000000000001', '2025-09-01', 60.125,   '2025-09-02T00:00:00Z', '2025-09-02T00:00:00Z'),
+    ('bbbbbbbb-0000-0000-0000-000000000002', 'aaaaaaaa-0000-0000-0000-000000000002', '2025-09-01', 30.5,     '2025-09-02T00:00:00Z', '2025-09-02T00:00:00Z'),
+    ('bbbbbbbb-0000-0000-0000-000000000003', 'aaaaaaaa-0000-0000-0000-000000000003', '2025-09-01', 40,       '2025-09-02T00:00:00Z', '2025-09-02T00:00:00Z'),
+    ('bbbbbbbb-0000-0000-0000-000000000004', 'aaaaaaaa-0000-0000-0000-000000000004', '2025-09-01', 150.25,   '2025-09-02T00:00:00Z', '2025-09-02T00:00:00Z'),
+    ('bbbbbbbb-0000-0000-0000-000000000005', 'aaaaaaaa-0000-0000-0000-000000000001', '2025-09-02', 70,       '2025-09-03T00:00:00Z', '2025-09-03T00:00:00Z'),
+    ('bbbbbbbb-0000-0000-0000-000000000006', 'aaaaaaaa-0000-0000-0000-000000000002', '2025-09-02', 25.25,    '2025-09-03T00:00:00Z', '2025-09-03T00:00:00Z'),
+    ('bbbbbbbb-0000-0000-0000-000000000007', 'aaaaaaaa-0000-0000-0000-000000000003', '2025-09-02', 55.3333,  '2025-09-03T00:00:00Z', '2025-09-03T00:00:00Z'),
+    ('bbbbbbbb-0000-0000-0000-000000000008', 'aaaaaaaa-0000-0000-0000-000000000004', '2025-09-02', 180,      '2025-09-03T00:00:00Z', '2025-09-03T00:00:00Z'),
+    ('bbbbbbbb-0000-0000-0000-000000000009', 'aaaaaaaa-0000-0000-0000-000000000001', '2025-09-03', 0,        '2025-09-04T00:00:00Z', '2025-09-04T00:00:00Z'),
+    ('bbbbbbbb-0000-0000-0000-000000000010', 'aaaaaaaa-0000-0000-0000-000000000003', '2025-09-03', 20.0005,  '2025-09-04T00:00:00Z', '2025-09-04T00:00:00Z'),
+    ('bbbbbbbb-0000-0000-0000-000000000011', 'aaaaaaaa-0000-0000-0000-000000000004', '2025-09-03', 199.9995, '2025-09-04T00:00:00Z', '2025-09-04T00:00:00Z');
+
+-- Corrected after 2025-09-03 12:00, so total_production_as_of still sees 65
+INSERT INTO production_corrections (id, production_id, previous_mw, corrected_mw, reason, created_at) VALUES
+    ('dddddddd-0000-0000-0000-000000000001', 'bbbbbbbb-0000-0000-0000-000000000005', 65, 70, 'Meter reading fixed', '2025-09-10T00:00:00Z');